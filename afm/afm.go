@@ -0,0 +1,324 @@
+// Package afm implements a parser for Adobe Font Metrics files (.afm),
+// as described in the Adobe Technical Note #5004.
+//
+// AFM files provide the metrics of PostScript fonts, and are the only
+// source of metrics (including kerning) for the standard 14 PDF fonts.
+// The parsed Metrics may be attached to Type1 or CFF faces.
+package afm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CharMetric stores the metrics for one character.
+type CharMetric struct {
+	Code int    // character code in the font encoding, or -1 if not encoded
+	Name string // glyph name
+
+	WX, WY float64 // advance vector
+
+	BBox [4]float64 // llx, lly, urx, ury
+
+	// Ligatures maps a successor glyph name to the ligature glyph name.
+	Ligatures map[string]string
+}
+
+// KernPair is a kerning adjustment between two glyphs, identified by their names.
+type KernPair struct {
+	Left, Right string
+	X, Y        float64
+}
+
+// Metrics is the content of an AFM file.
+type Metrics struct {
+	FontName       string
+	FullName       string
+	FamilyName     string
+	Weight         string
+	Version        string
+	Notice         string
+	EncodingScheme string
+	CharacterSet   string
+
+	ItalicAngle  float64
+	IsFixedPitch bool
+
+	FontBBox [4]float64 // llx, lly, urx, ury
+
+	UnderlinePosition  float64
+	UnderlineThickness float64
+
+	CapHeight float64
+	XHeight   float64
+	Ascender  float64
+	Descender float64
+	StdHW     float64
+	StdVW     float64
+
+	CharMetrics []CharMetric
+	KernPairs   []KernPair
+
+	byName map[string]int        // index into CharMetrics
+	kerns  map[[2]string]float64 // horizontal kerning
+}
+
+// Parse reads an AFM file.
+// Unknown keys are ignored, as recommended by the specification.
+func Parse(r io.Reader) (*Metrics, error) {
+	var (
+		out        Metrics
+		section    string // the current StartXXX section
+		sawStart   bool
+		scanner    = bufio.NewScanner(r)
+		lineNumber int
+		sections   = map[string]bool{
+			"CharMetrics": true, "KernPairs": true, "KernPairs0": true, "KernPairs1": true,
+			"Composites": true, "TrackKern": true,
+		}
+	)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, value := splitKeyValue(line)
+		if !sawStart {
+			if key != "StartFontMetrics" {
+				return nil, errors.New("invalid AFM file: missing StartFontMetrics")
+			}
+			sawStart = true
+			continue
+		}
+
+		if strings.HasPrefix(key, "Start") && sections[key[len("Start"):]] {
+			section = key[len("Start"):]
+			continue
+		}
+		if strings.HasPrefix(key, "End") && sections[key[len("End"):]] {
+			section = ""
+			continue
+		}
+
+		var err error
+		switch section {
+		case "CharMetrics":
+			err = out.parseCharMetric(line)
+		case "KernPairs", "KernPairs0":
+			err = out.parseKernPair(key, value)
+		case "KernPairs1", "Composites", "TrackKern":
+			// vertical kerning, composites and track kerning are not supported
+		default:
+			err = out.parseGlobal(key, value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid AFM file at line %d: %s", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !sawStart {
+		return nil, errors.New("invalid AFM file: missing StartFontMetrics")
+	}
+
+	out.buildIndex()
+	return &out, nil
+}
+
+func splitKeyValue(line string) (key, value string) {
+	if i := strings.IndexAny(line, " \t"); i != -1 {
+		return line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return line, ""
+}
+
+func parseNumbers(value string, n int) ([]float64, error) {
+	fields := strings.Fields(value)
+	if len(fields) < n {
+		return nil, fmt.Errorf("expected %d numbers, got %q", n, value)
+	}
+	out := make([]float64, n)
+	for i := range out {
+		var err error
+		out[i], err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func parseNumber(value string) (float64, error) {
+	n, err := parseNumbers(value, 1)
+	if err != nil {
+		return 0, err
+	}
+	return n[0], nil
+}
+
+func (m *Metrics) parseGlobal(key, value string) (err error) {
+	switch key {
+	case "FontName":
+		m.FontName = value
+	case "FullName":
+		m.FullName = value
+	case "FamilyName":
+		m.FamilyName = value
+	case "Weight":
+		m.Weight = value
+	case "Version":
+		m.Version = value
+	case "Notice":
+		m.Notice = value
+	case "EncodingScheme":
+		m.EncodingScheme = value
+	case "CharacterSet":
+		m.CharacterSet = value
+	case "ItalicAngle":
+		m.ItalicAngle, err = parseNumber(value)
+	case "IsFixedPitch":
+		m.IsFixedPitch = value == "true"
+	case "FontBBox":
+		var bbox []float64
+		bbox, err = parseNumbers(value, 4)
+		if err == nil {
+			copy(m.FontBBox[:], bbox)
+		}
+	case "UnderlinePosition":
+		m.UnderlinePosition, err = parseNumber(value)
+	case "UnderlineThickness":
+		m.UnderlineThickness, err = parseNumber(value)
+	case "CapHeight":
+		m.CapHeight, err = parseNumber(value)
+	case "XHeight":
+		m.XHeight, err = parseNumber(value)
+	case "Ascender":
+		m.Ascender, err = parseNumber(value)
+	case "Descender":
+		m.Descender, err = parseNumber(value)
+	case "StdHW":
+		m.StdHW, err = parseNumber(value)
+	case "StdVW":
+		m.StdVW, err = parseNumber(value)
+	}
+	return err
+}
+
+// parseCharMetric parses a line such as
+//
+//	C 32 ; WX 250 ; N space ; B 0 0 0 0 ;
+func (m *Metrics) parseCharMetric(line string) error {
+	cm := CharMetric{Code: -1}
+	for _, item := range strings.Split(line, ";") {
+		key, value := splitKeyValue(strings.TrimSpace(item))
+		var err error
+		switch key {
+		case "C":
+			var c float64
+			c, err = parseNumber(value)
+			cm.Code = int(c)
+		case "CH":
+			var c int64
+			c, err = strconv.ParseInt(strings.Trim(value, "<>"), 16, 32)
+			cm.Code = int(c)
+		case "WX", "W0X":
+			cm.WX, err = parseNumber(value)
+		case "WY", "W0Y":
+			cm.WY, err = parseNumber(value)
+		case "W", "W0":
+			var w []float64
+			w, err = parseNumbers(value, 2)
+			if err == nil {
+				cm.WX, cm.WY = w[0], w[1]
+			}
+		case "N":
+			cm.Name = value
+		case "B":
+			var bbox []float64
+			bbox, err = parseNumbers(value, 4)
+			if err == nil {
+				copy(cm.BBox[:], bbox)
+			}
+		case "L":
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				return fmt.Errorf("invalid ligature %q", value)
+			}
+			if cm.Ligatures == nil {
+				cm.Ligatures = make(map[string]string)
+			}
+			cm.Ligatures[fields[0]] = fields[1]
+		}
+		if err != nil {
+			return err
+		}
+	}
+	m.CharMetrics = append(m.CharMetrics, cm)
+	return nil
+}
+
+// parseKernPair parses a line such as
+//
+//	KPX A y -40
+func (m *Metrics) parseKernPair(key, value string) error {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return fmt.Errorf("invalid kern pair %q", value)
+	}
+	kp := KernPair{Left: fields[0], Right: fields[1]}
+	var err error
+	switch key {
+	case "KPX":
+		kp.X, err = strconv.ParseFloat(fields[2], 64)
+	case "KPY":
+		kp.Y, err = strconv.ParseFloat(fields[2], 64)
+	case "KP":
+		var v []float64
+		v, err = parseNumbers(strings.Join(fields[2:], " "), 2)
+		if err == nil {
+			kp.X, kp.Y = v[0], v[1]
+		}
+	default: // KPH (hex names) is not supported
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m.KernPairs = append(m.KernPairs, kp)
+	return nil
+}
+
+func (m *Metrics) buildIndex() {
+	m.byName = make(map[string]int, len(m.CharMetrics))
+	for i, cm := range m.CharMetrics {
+		if _, has := m.byName[cm.Name]; !has {
+			m.byName[cm.Name] = i
+		}
+	}
+	m.kerns = make(map[[2]string]float64, len(m.KernPairs))
+	for _, kp := range m.KernPairs {
+		m.kerns[[2]string{kp.Left, kp.Right}] = kp.X
+	}
+}
+
+// CharMetric returns the metrics for the glyph `name`,
+// or false if not found.
+func (m *Metrics) CharMetric(name string) (CharMetric, bool) {
+	i, ok := m.byName[name]
+	if !ok {
+		return CharMetric{}, false
+	}
+	return m.CharMetrics[i], true
+}
+
+// Kerning returns the horizontal kerning adjustment to apply
+// between the glyphs `left` and `right`, or 0.
+func (m *Metrics) Kerning(left, right string) float64 {
+	return m.kerns[[2]string{left, right}]
+}
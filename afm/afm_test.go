@@ -0,0 +1,113 @@
+package afm
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/Times-Bold.afm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	metrics, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.FontName != "Times-Bold" || metrics.FamilyName != "Times" || metrics.Weight != "Bold" ||
+		metrics.EncodingScheme != "AdobeStandardEncoding" || metrics.IsFixedPitch {
+		t.Errorf("unexpected font info %v", metrics)
+	}
+	if metrics.FontBBox != [4]float64{-168, -218, 1000, 935} || metrics.CapHeight != 676 || metrics.XHeight != 461 ||
+		metrics.Ascender != 683 || metrics.Descender != -217 || metrics.UnderlinePosition != -100 || metrics.StdVW != 139 {
+		t.Errorf("unexpected font metrics %v", metrics)
+	}
+	if len(metrics.CharMetrics) != 315 || len(metrics.KernPairs) != 2242 {
+		t.Errorf("expected 315 characters and 2242 kern pairs, got %d and %d", len(metrics.CharMetrics), len(metrics.KernPairs))
+	}
+
+	a, ok := metrics.CharMetric("A")
+	if !ok || a.Code != 65 || a.WX != 722 || a.BBox != [4]float64{9, 0, 689, 690} {
+		t.Errorf("unexpected metrics for 'A': %v", a)
+	}
+	if eacute, _ := metrics.CharMetric("eacute"); eacute.Code != -1 || eacute.WX != 444 {
+		t.Errorf("unexpected metrics for 'eacute': %v", eacute)
+	}
+	if cm, _ := metrics.CharMetric("f"); cm.Ligatures["i"] != "fi" || cm.Ligatures["l"] != "fl" {
+		t.Errorf("unexpected ligatures for 'f': %v", cm.Ligatures)
+	}
+	if _, ok := metrics.CharMetric("missing"); ok {
+		t.Error("unexpected metrics for a missing glyph")
+	}
+
+	if kern := metrics.Kerning("A", "T"); kern != -95 {
+		t.Errorf("expected kerning -95 for 'A' 'T', got %g", kern)
+	}
+	if kern := metrics.Kerning("T", "Z"); kern != 0 {
+		t.Errorf("expected no kerning for 'T' 'Z', got %g", kern)
+	}
+}
+
+func TestParseVariants(t *testing.T) {
+	const input = `StartFontMetrics 2.0
+FontName Test
+Comment unknown keys are ignored
+Unknown 1 2 3
+StartCharMetrics 3
+CH <41> ; W 500 10 ; N A ;
+C 66 ; W0X 600 ; W0Y 20 ; N B ;
+C 67 ; WX 700 ; N C ;
+EndCharMetrics
+StartKernPairs 3
+KP A B -10 5
+KPY B C 8
+KPH <41> <42> -20
+EndKernPairs
+StartKernPairs1 1
+KPX A C -30
+EndKernPairs1
+EndFontMetrics
+`
+	metrics, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := metrics.CharMetric("A"); a.Code != 0x41 || a.WX != 500 || a.WY != 10 {
+		t.Errorf("unexpected metrics for 'A': %v", a)
+	}
+	if b, _ := metrics.CharMetric("B"); b.WX != 600 || b.WY != 20 {
+		t.Errorf("unexpected metrics for 'B': %v", b)
+	}
+	// hex names and vertical kerning are not supported
+	expected := []KernPair{{"A", "B", -10, 5}, {"B", "C", 0, 8}}
+	if len(metrics.KernPairs) != len(expected) {
+		t.Fatalf("expected kern pairs %v, got %v", expected, metrics.KernPairs)
+	}
+	for i, kp := range expected {
+		if metrics.KernPairs[i] != kp {
+			t.Errorf("expected kern pair %v, got %v", kp, metrics.KernPairs[i])
+		}
+	}
+	if kern := metrics.Kerning("A", "C"); kern != 0 {
+		t.Errorf("unexpected vertical kerning %g", kern)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"FontName Test\nStartFontMetrics 4.1\n",
+		"StartFontMetrics 4.1\nFontBBox 0 0 1\n",
+		"StartFontMetrics 4.1\nCapHeight abc\n",
+		"StartFontMetrics 4.1\nStartCharMetrics 1\nC 65 ; WX abc ; N A ;\n",
+		"StartFontMetrics 4.1\nStartCharMetrics 1\nC 102 ; WX 300 ; N f ; L i ;\n",
+		"StartFontMetrics 4.1\nStartKernPairs 1\nKPX A -10\n",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
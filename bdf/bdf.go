@@ -0,0 +1,329 @@
+// Package bdf provides support for the Glyph Bitmap Distribution Format (.bdf),
+// the textual exchange format of bitmap fonts.
+//
+// The metrics of bitmap fonts are expressed in pixels.
+// See the Adobe Technical Note #5005 for the format.
+package bdf
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/benoitkugler/textlayout/fonts/glyphsnames"
+	"github.com/go-text/font"
+)
+
+var (
+	_ font.Face          = (*Face)(nil)
+	_ font.GlyphExtenter = (*Face)(nil)
+	_ font.GlyphCounter  = (*Face)(nil)
+)
+
+// GID is used to identify glyphs in a font.
+type GID = font.GID
+
+// Property is an entry of the font properties,
+// which is either a string (atom) or an integer.
+type Property struct {
+	Atom   string // only valid if IsAtom is true
+	Value  int32  // only valid if IsAtom is false
+	IsAtom bool
+}
+
+// BBox is a bounding box, in pixels, whose lower left
+// corner is at (XOffset, YOffset) from the origin.
+type BBox struct {
+	Width, Height    int
+	XOffset, YOffset int
+}
+
+// Size is the point size of the font, and the resolution
+// of the device it has been designed for.
+type Size struct {
+	PointSize   float64
+	XResolution int
+	YResolution int
+}
+
+// Glyph stores the information found between STARTCHAR and ENDCHAR.
+type Glyph struct {
+	Name string
+	// Encoding is the code of the glyph in the font charset,
+	// or -1 for unencoded glyphs.
+	Encoding int32
+
+	ScalableWidth [2]float64 // SWIDTH, in 1/1000 of the point size
+	DeviceWidth   [2]int     // DWIDTH, in pixels
+
+	BBox BBox // BBX
+
+	// Bitmap stores BBox.Height rows of (BBox.Width + 7) / 8 bytes,
+	// from top to bottom, with the leftmost pixel in the most significant bit.
+	Bitmap []byte
+}
+
+// Bitmap is the monochrome image of a glyph, stored row by row,
+// from top to bottom. The leftmost pixel of a row is the most significant bit
+// of its first byte, and each row is padded to a whole number of bytes.
+type Bitmap struct {
+	Width, Height int
+	Stride        int // number of bytes per row
+	Data          []byte
+
+	// Left and Top are the position of the top-left pixel,
+	// relative to the glyph origin, with Top increasing upwards.
+	Left, Top int
+}
+
+// At returns true if the pixel at column `x` and row `y` is set.
+func (b Bitmap) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return false
+	}
+	return b.Data[y*b.Stride+x/8]&(0x80>>(x%8)) != 0
+}
+
+// Face is a font parsed from a BDF file.
+// The glyph indices are the order of the glyphs in the file.
+type Face struct {
+	Version  string // STARTFONT
+	FontName string // FONT, usually a XLFD name
+	Size     Size
+	BBox     BBox // FONTBOUNDINGBOX
+
+	// Properties stores the entries between STARTPROPERTIES and ENDPROPERTIES,
+	// such as FAMILY_NAME, PIXEL_SIZE, CHARSET_REGISTRY or CHARSET_ENCODING.
+	Properties map[string]Property
+
+	// Ascent and Descent are the logical extents of the font,
+	// above and below the baseline, in pixels.
+	// They default to the font bounding box when
+	// FONT_ASCENT and FONT_DESCENT are missing.
+	Ascent, Descent int32
+
+	Glyphs []Glyph
+
+	codes map[int32]GID
+	cmap  map[rune]GID
+}
+
+// ParseFont reads a BDF font.
+func ParseFont(file font.Resource) (*Face, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil { // file might have been used before
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses the content of a BDF file.
+func Parse(data []byte) (*Face, error) {
+	p := parser{lines: strings.Split(string(data), "\n")}
+	out, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Glyphs) == 0 {
+		return nil, errors.New("invalid BDF font: no glyphs")
+	}
+
+	out.Ascent, out.Descent = int32(out.BBox.Height+out.BBox.YOffset), int32(-out.BBox.YOffset)
+	if p, ok := out.Properties["FONT_ASCENT"]; ok && !p.IsAtom {
+		out.Ascent = p.Value
+	}
+	if p, ok := out.Properties["FONT_DESCENT"]; ok && !p.IsAtom {
+		out.Descent = p.Value
+	}
+
+	out.buildIndex()
+	return out, nil
+}
+
+// isUnicode returns true if the character codes of the font
+// are Unicode code points, as indicated by its charset properties.
+func (f *Face) isUnicode() bool {
+	registry := strings.ToLower(f.Properties["CHARSET_REGISTRY"].Atom)
+	encoding := f.Properties["CHARSET_ENCODING"].Atom
+	switch registry {
+	case "iso10646":
+		return true
+	case "iso8859":
+		return encoding == "1"
+	case "iso646.1991":
+		return encoding == "IRV" // ASCII
+	}
+	return false
+}
+
+// buildIndex builds the lookup tables by code and by rune.
+// The codes of the encoding are used as runes if the font
+// uses an Unicode compatible charset, the glyph names otherwise.
+func (f *Face) buildIndex() {
+	f.codes = make(map[int32]GID, len(f.Glyphs))
+	for i, g := range f.Glyphs {
+		if g.Encoding < 0 {
+			continue
+		}
+		if _, has := f.codes[g.Encoding]; !has {
+			f.codes[g.Encoding] = GID(i)
+		}
+	}
+
+	f.cmap = make(map[rune]GID, len(f.Glyphs))
+	if f.isUnicode() {
+		for code, gid := range f.codes {
+			f.cmap[rune(code)] = gid
+		}
+		return
+	}
+	for i, g := range f.Glyphs {
+		if r, ok := glyphsnames.GlyphToRune(g.Name); ok {
+			if _, has := f.cmap[r]; !has {
+				f.cmap[r] = GID(i)
+			}
+		}
+	}
+}
+
+// NumGlyphs returns the number of glyphs in the font.
+func (f *Face) NumGlyphs() int { return len(f.Glyphs) }
+
+// GlyphCount implements font.GlyphCounter, and is the same as NumGlyphs.
+func (f *Face) GlyphCount() int { return f.NumGlyphs() }
+
+// Upem returns the PIXEL_SIZE property, or the pixel size deduced
+// from the SIZE line if it is missing, or the font height.
+// The metrics of the font are expressed in pixels,
+// so that dividing them by Upem gives values relative to the em.
+func (f *Face) Upem() uint16 {
+	if p, ok := f.Properties["PIXEL_SIZE"]; ok && !p.IsAtom && p.Value > 0 {
+		return uint16(p.Value)
+	}
+	if size := uint16(f.Size.PointSize*float64(f.Size.YResolution)/72 + 0.5); size != 0 {
+		return size
+	}
+	return uint16(f.Ascent + f.Descent)
+}
+
+// NominalGlyph returns the glyph for the given rune.
+// The character codes of the font are used if its charset is
+// compatible with Unicode (ISO10646, ISO8859-1 or ASCII), and
+// the glyph names are interpreted otherwise.
+func (f *Face) NominalGlyph(r rune) (GID, bool) {
+	gid, ok := f.cmap[r]
+	return gid, ok
+}
+
+// GlyphForCode returns the glyph used for the character code `code`,
+// according to the font encoding, whose charset is given by the properties.
+func (f *Face) GlyphForCode(code int32) (GID, bool) {
+	gid, ok := f.codes[code]
+	return gid, ok
+}
+
+// DefaultGlyph returns the glyph to use for the codes not
+// supported by the font, as given by the DEFAULT_CHAR property,
+// or 0 if the font does not specify it.
+func (f *Face) DefaultGlyph() GID {
+	if p, ok := f.Properties["DEFAULT_CHAR"]; ok && !p.IsAtom {
+		if gid, ok := f.codes[p.Value]; ok {
+			return gid
+		}
+	}
+	return 0
+}
+
+// GlyphName returns the name of the given glyph, or an empty
+// string if the glyph is invalid.
+func (f *Face) GlyphName(gid GID) string {
+	if int(gid) >= len(f.Glyphs) {
+		return ""
+	}
+	return f.Glyphs[gid].Name
+}
+
+// HorizontalAdvance returns the advance of the glyph, in pixels,
+// or 0 if the glyph is invalid.
+func (f *Face) HorizontalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.Glyphs) {
+		return 0
+	}
+	return float32(f.Glyphs[gid].DeviceWidth[0])
+}
+
+// VerticalAdvance returns the opposite of the font height (its ascent
+// plus its descent), since vertical metrics are not supported,
+// or 0 if the glyph is invalid.
+func (f *Face) VerticalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.Glyphs) {
+		return 0
+	}
+	return -float32(f.Ascent + f.Descent)
+}
+
+// FontHExtents returns the ascent and descent of the font,
+// in pixels, with no line gap.
+func (f *Face) FontHExtents() (font.FontExtents, bool) {
+	return font.FontExtents{Ascender: float32(f.Ascent), Descender: -float32(f.Descent)}, true
+}
+
+// LineMetric returns the underline and strikethrough metrics, in pixels, given by the
+// UNDERLINE_POSITION, UNDERLINE_THICKNESS, STRIKEOUT_ASCENT and STRIKEOUT_DESCENT
+// properties, or false if the required properties are missing.
+func (f *Face) LineMetric(metric font.LineMetric) (float32, bool) {
+	value := func(name string) (float32, bool) {
+		p, ok := f.Properties[name]
+		return float32(p.Value), ok && !p.IsAtom
+	}
+	switch metric {
+	case font.UnderlinePosition: // XLFD values are positive below the baseline
+		pos, ok := value("UNDERLINE_POSITION")
+		return -pos, ok
+	case font.UnderlineThickness:
+		return value("UNDERLINE_THICKNESS")
+	case font.StrikethroughPosition:
+		return value("STRIKEOUT_ASCENT")
+	case font.StrikethroughThickness:
+		ascent, ok1 := value("STRIKEOUT_ASCENT")
+		descent, ok2 := value("STRIKEOUT_DESCENT")
+		return ascent + descent, ok1 && ok2
+	default:
+		return 0, false
+	}
+}
+
+// GlyphExtents returns the extents of the bounding box of the glyph,
+// in pixels, or false if the glyph is invalid.
+func (f *Face) GlyphExtents(gid GID) (font.GlyphExtents, bool) {
+	if int(gid) >= len(f.Glyphs) {
+		return font.GlyphExtents{}, false
+	}
+	b := f.Glyphs[gid].BBox
+	return font.GlyphExtents{
+		XBearing: float32(b.XOffset),
+		YBearing: float32(b.YOffset + b.Height),
+		Width:    float32(b.Width),
+		Height:   -float32(b.Height),
+	}, true
+}
+
+// GlyphBitmap returns the image of the glyph, or false if the glyph is invalid.
+func (f *Face) GlyphBitmap(gid GID) (Bitmap, bool) {
+	if int(gid) >= len(f.Glyphs) {
+		return Bitmap{}, false
+	}
+	g := f.Glyphs[gid]
+	return Bitmap{
+		Width:  g.BBox.Width,
+		Height: g.BBox.Height,
+		Stride: (g.BBox.Width + 7) / 8,
+		Data:   g.Bitmap,
+		Left:   g.BBox.XOffset,
+		Top:    g.BBox.YOffset + g.BBox.Height,
+	}, true
+}
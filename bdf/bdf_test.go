@@ -0,0 +1,194 @@
+package bdf
+
+import (
+	"strings"
+	"testing"
+)
+
+// sample is the example font of the BDF specification.
+const sample = `STARTFONT 2.1
+COMMENT This is a sample font in 2.1 format.
+FONT -Adobe-Helvetica-Bold-R-Normal--24-240-75-75-P-65-ISO8859-1
+SIZE 24 75 75
+FONTBOUNDINGBOX 9 24 -2 -6
+STARTPROPERTIES 19
+FOUNDRY "Adobe"
+FAMILY "Helvetica"
+WEIGHT_NAME "Bold"
+SLANT "R"
+SETWIDTH_NAME "Normal"
+ADD_STYLE_NAME ""
+PIXEL_SIZE 24
+POINT_SIZE 240
+RESOLUTION_X 75
+RESOLUTION_Y 75
+SPACING "P"
+AVERAGE_WIDTH 65
+CHARSET_REGISTRY "ISO8859"
+CHARSET_ENCODING "1"
+MIN_SPACE 4
+FONT_ASCENT 21
+FONT_DESCENT 7
+COPYRIGHT "Copyright (c) 1987 Adobe Systems, Inc."
+NOTICE "Helvetica is a registered trademark of Linotype Inc."
+ENDPROPERTIES
+CHARS 2
+STARTCHAR j
+ENCODING 106
+SWIDTH 355 0
+DWIDTH 8 0
+BBX 9 22 -2 -6
+BITMAP
+0380
+0380
+0380
+0380
+0000
+0700
+0700
+0700
+0700
+0E00
+0E00
+0E00
+0E00
+0E00
+1C00
+1C00
+1C00
+1C00
+3C00
+7800
+F000
+E000
+ENDCHAR
+STARTCHAR quoteright
+ENCODING 39
+SWIDTH 223 0
+DWIDTH 5 0
+BBX 4 6 2 12
+ATTRIBUTES 01C0
+BITMAP
+70
+70
+70
+60
+E0
+C0
+ENDCHAR
+ENDFONT
+`
+
+func TestParse(t *testing.T) {
+	face, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if face.Version != "2.1" || face.FontName != "-Adobe-Helvetica-Bold-R-Normal--24-240-75-75-P-65-ISO8859-1" {
+		t.Errorf("unexpected font %s %s", face.Version, face.FontName)
+	}
+	if face.Size != (Size{24, 75, 75}) || face.BBox != (BBox{9, 24, -2, -6}) {
+		t.Errorf("unexpected size %v and bounding box %v", face.Size, face.BBox)
+	}
+	if len(face.Properties) != 19 {
+		t.Errorf("expected 19 properties, got %d", len(face.Properties))
+	}
+	if p := face.Properties["FAMILY"]; !p.IsAtom || p.Atom != "Helvetica" {
+		t.Errorf("unexpected FAMILY property %v", p)
+	}
+	if p := face.Properties["ADD_STYLE_NAME"]; !p.IsAtom || p.Atom != "" {
+		t.Errorf("unexpected ADD_STYLE_NAME property %v", p)
+	}
+	if p := face.Properties["MIN_SPACE"]; p.IsAtom || p.Value != 4 {
+		t.Errorf("unexpected MIN_SPACE property %v", p)
+	}
+	if face.Ascent != 21 || face.Descent != 7 || face.Upem() != 24 {
+		t.Errorf("unexpected metrics %d %d %d", face.Ascent, face.Descent, face.Upem())
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	face, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if face.NumGlyphs() != 2 {
+		t.Fatalf("expected 2 glyphs, got %d", face.NumGlyphs())
+	}
+
+	// the ISO8859-1 codes are Unicode code points
+	j, ok := face.NominalGlyph('j')
+	if !ok || face.GlyphName(j) != "j" {
+		t.Fatalf("invalid glyph %d for 'j'", j)
+	}
+	if quote, ok := face.NominalGlyph('\''); !ok || face.GlyphName(quote) != "quoteright" {
+		t.Errorf("invalid glyph %d for 'quoteright'", quote)
+	}
+	if code, _ := face.GlyphForCode(106); code != j {
+		t.Errorf("expected glyph %d for code 106, got %d", j, code)
+	}
+
+	g := face.Glyphs[j]
+	if g.Encoding != 106 || g.ScalableWidth != [2]float64{355, 0} || g.DeviceWidth != [2]int{8, 0} {
+		t.Errorf("unexpected glyph %v", g)
+	}
+	if advance := face.HorizontalAdvance(j); advance != 8 {
+		t.Errorf("expected advance 8, got %g", advance)
+	}
+	if extents, _ := face.GlyphExtents(j); extents.XBearing != -2 || extents.YBearing != 16 || extents.Width != 9 || extents.Height != -22 {
+		t.Errorf("unexpected extents %v", extents)
+	}
+
+	bitmap, ok := face.GlyphBitmap(j)
+	if !ok || bitmap.Width != 9 || bitmap.Height != 22 || bitmap.Stride != 2 || bitmap.Left != -2 || bitmap.Top != 16 {
+		t.Fatalf("unexpected bitmap %v", bitmap)
+	}
+	for y, row := range map[int]string{0: "......###", 4: ".........", 5: ".....###.", 21: "###......"} {
+		for x, c := range row {
+			if bitmap.At(x, y) != (c == '#') {
+				t.Errorf("invalid pixel (%d, %d)", x, y)
+			}
+		}
+	}
+	if _, ok := face.GlyphBitmap(2); ok {
+		t.Error("expected no bitmap for an invalid glyph")
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	// without FONT_ASCENT and FONT_DESCENT, the font bounding box is used
+	input := strings.Replace(strings.Replace(sample, "FONT_ASCENT 21\n", "", 1), "FONT_DESCENT 7\n", "", 1)
+	// the glyph values default to the font-wide ones
+	input = strings.Replace(input, "SWIDTH 223 0\nDWIDTH 5 0\n", "", 1)
+	input = strings.Replace(input, "CHARS 2\n", "SWIDTH 500 0\nDWIDTH 12 0\nCHARS 2\n", 1)
+	face, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if face.Ascent != 18 || face.Descent != 6 {
+		t.Errorf("expected the ascent and descent of the bounding box, got %d %d", face.Ascent, face.Descent)
+	}
+	g := face.Glyphs[1]
+	if g.ScalableWidth != [2]float64{500, 0} || g.DeviceWidth != [2]int{12, 0} {
+		t.Errorf("unexpected default values %v", g)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"FONT test\n",
+		"STARTFONT 2.1\nENDFONT\n", // no glyphs
+		strings.Replace(sample, "ENDPROPERTIES\n", "", 1) + "\n",
+		strings.Replace(sample, "SIZE 24 75 75", "SIZE 24 75", 1),
+		strings.Replace(sample, "FONTBOUNDINGBOX 9 24 -2 -6", "FONTBOUNDINGBOX 9 24 -2 x", 1),
+		strings.Replace(sample, "ENCODING 106", "ENCODING j", 1),
+		strings.Replace(sample, "0E00\n", "0EZZ\n", 1),
+		strings.Replace(sample, "C0\nENDCHAR", "ENDCHAR", 1),
+		sample[:strings.LastIndex(sample, "ENDCHAR")],
+	} {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package bdf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser is a cursor over the lines of the file
+type parser struct {
+	lines []string
+	pos   int // index of the next line
+}
+
+// next returns the keyword and the arguments of the next meaningful line,
+// or an empty keyword at the end of the file.
+func (p *parser) next() (keyword string, args string) {
+	for p.pos < len(p.lines) {
+		line := strings.TrimSpace(p.lines[p.pos])
+		p.pos++
+		if line == "" {
+			continue
+		}
+		keyword, args = line, ""
+		if i := strings.IndexAny(line, " \t"); i != -1 {
+			keyword, args = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if keyword == "COMMENT" {
+			continue
+		}
+		return keyword, args
+	}
+	return "", ""
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid BDF font (line %d): %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// ints parses the `n` integers of `args`.
+func (p *parser) ints(keyword, args string, n int) ([]int, error) {
+	fields := strings.Fields(args)
+	if len(fields) < n {
+		return nil, p.errorf("expected %d values for %s", n, keyword)
+	}
+	out := make([]int, n)
+	for i := range out {
+		v, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return nil, p.errorf("invalid value for %s: %s", keyword, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (p *parser) bbox(keyword, args string) (BBox, error) {
+	v, err := p.ints(keyword, args, 4)
+	if err != nil {
+		return BBox{}, err
+	}
+	return BBox{Width: v[0], Height: v[1], XOffset: v[2], YOffset: v[3]}, nil
+}
+
+func (p *parser) parse() (*Face, error) {
+	keyword, args := p.next()
+	if keyword != "STARTFONT" {
+		return nil, p.errorf("expected STARTFONT, got %q", keyword)
+	}
+	out := &Face{Version: args, Properties: map[string]Property{}}
+
+	// font-wide values, used as defaults for the glyphs
+	var (
+		sWidth [2]float64
+		dWidth [2]int
+	)
+	for {
+		keyword, args = p.next()
+		var err error
+		switch keyword {
+		case "", "ENDFONT":
+			return out, nil
+		case "FONT":
+			out.FontName = args
+		case "SIZE":
+			fields := strings.Fields(args)
+			if len(fields) < 3 {
+				return nil, p.errorf("expected 3 values for SIZE")
+			}
+			out.Size.PointSize, err = strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, p.errorf("invalid point size: %s", err)
+			}
+			var res []int
+			if res, err = p.ints(keyword, strings.Join(fields[1:], " "), 2); err != nil {
+				return nil, err
+			}
+			out.Size.XResolution, out.Size.YResolution = res[0], res[1]
+		case "FONTBOUNDINGBOX":
+			out.BBox, err = p.bbox(keyword, args)
+		case "STARTPROPERTIES":
+			err = p.parseProperties(out.Properties)
+		case "SWIDTH":
+			sWidth, err = p.scalableWidth(args)
+		case "DWIDTH":
+			var v []int
+			v, err = p.ints(keyword, args, 2)
+			if err == nil {
+				dWidth = [2]int{v[0], v[1]}
+			}
+		case "CHARS":
+			if n, err := strconv.Atoi(args); err == nil && 0 < n && n < 1<<16 {
+				out.Glyphs = make([]Glyph, 0, n)
+			}
+		case "STARTCHAR":
+			g := Glyph{Name: args, Encoding: -1, ScalableWidth: sWidth, DeviceWidth: dWidth, BBox: out.BBox}
+			if err = p.parseGlyph(&g); err != nil {
+				return nil, err
+			}
+			out.Glyphs = append(out.Glyphs, g)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseProperties reads the property lines until ENDPROPERTIES.
+// String values are quoted, with quotes escaped by doubling them.
+func (p *parser) parseProperties(props map[string]Property) error {
+	for {
+		name, value := p.next()
+		switch name {
+		case "":
+			return p.errorf("missing ENDPROPERTIES")
+		case "ENDPROPERTIES":
+			return nil
+		}
+		if strings.HasPrefix(value, `"`) {
+			value = strings.TrimSuffix(value[1:], `"`)
+			props[name] = Property{Atom: strings.ReplaceAll(value, `""`, `"`), IsAtom: true}
+		} else if v, err := strconv.ParseInt(value, 10, 32); err == nil {
+			props[name] = Property{Value: int32(v)}
+		} else { // be lenient with unquoted strings
+			props[name] = Property{Atom: value, IsAtom: true}
+		}
+	}
+}
+
+func (p *parser) scalableWidth(args string) ([2]float64, error) {
+	var out [2]float64
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return out, p.errorf("expected 2 values for SWIDTH")
+	}
+	for i := range out {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return out, p.errorf("invalid value for SWIDTH: %s", err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// parseGlyph reads the glyph lines until ENDCHAR.
+func (p *parser) parseGlyph(g *Glyph) error {
+	for {
+		keyword, args := p.next()
+		var err error
+		switch keyword {
+		case "", "ENDFONT":
+			return p.errorf("missing ENDCHAR for glyph %s", g.Name)
+		case "ENDCHAR":
+			return nil
+		case "ENCODING":
+			var v []int
+			if v, err = p.ints(keyword, args, 1); err == nil {
+				g.Encoding = int32(v[0])
+			}
+		case "SWIDTH":
+			g.ScalableWidth, err = p.scalableWidth(args)
+		case "DWIDTH":
+			var v []int
+			if v, err = p.ints(keyword, args, 2); err == nil {
+				g.DeviceWidth = [2]int{v[0], v[1]}
+			}
+		case "BBX":
+			g.BBox, err = p.bbox(keyword, args)
+		case "BITMAP":
+			err = p.parseBitmap(g)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseBitmap reads one hexadecimal line for each row of the glyph.
+func (p *parser) parseBitmap(g *Glyph) error {
+	if g.BBox.Width < 0 || g.BBox.Height < 0 {
+		return p.errorf("invalid bounding box for glyph %s", g.Name)
+	}
+	stride := (g.BBox.Width + 7) / 8
+	g.Bitmap = make([]byte, stride*g.BBox.Height)
+	for y := 0; y < g.BBox.Height; y++ {
+		if p.pos >= len(p.lines) {
+			return p.errorf("missing bitmap rows for glyph %s", g.Name)
+		}
+		row := strings.TrimSpace(p.lines[p.pos])
+		p.pos++
+		if len(row) < 2*stride {
+			return p.errorf("invalid bitmap row %q for glyph %s", row, g.Name)
+		}
+		if _, err := hex.Decode(g.Bitmap[y*stride:(y+1)*stride], []byte(row[:2*stride])); err != nil {
+			return p.errorf("invalid bitmap row %q for glyph %s", row, g.Name)
+		}
+	}
+	return nil
+}
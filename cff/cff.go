@@ -0,0 +1,478 @@
+// Package cff provides support for Compact Font Format fonts,
+// either standalone (as embedded in PDF files), or
+// found in the 'CFF ' table of OpenType fonts.
+// The 'CFF2' table of variable fonts is also supported, see ParseCFF2.
+//
+// See the Adobe Technical Note #5176 for the format, and #5177
+// for the Type2 charstrings interpreted to provide the glyph metrics.
+package cff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/benoitkugler/textlayout/fonts/glyphsnames"
+	"github.com/go-text/font"
+	"github.com/go-text/font/afm"
+)
+
+var (
+	_ font.Face          = (*Face)(nil)
+	_ font.GlyphExtenter = (*Face)(nil)
+	_ font.GlyphCounter  = (*Face)(nil)
+	_ font.GlyphOutliner = (*Face)(nil)
+)
+
+// GID is used to identify glyphs in a font.
+type GID = font.GID
+
+var errUnsupportedVersion = errors.New("unsupported CFF version")
+
+// FontInfo stores the informative entries of the Top DICT.
+type FontInfo struct {
+	Version    string
+	Notice     string
+	Copyright  string
+	FullName   string
+	FamilyName string
+	Weight     string
+
+	ItalicAngle  float64
+	IsFixedPitch bool
+
+	UnderlinePosition  float64
+	UnderlineThickness float64
+}
+
+// ROS is the Registry-Ordering-Supplement of CID-keyed fonts.
+type ROS struct {
+	Registry   string
+	Ordering   string
+	Supplement int
+}
+
+// Face is a font parsed from a CFF file.
+type Face struct {
+	FontName string
+	Info     FontInfo
+
+	// FontMatrix maps glyph space to text space,
+	// and is usually [0.001 0 0 0.001 0 0]
+	FontMatrix [6]float64
+	FontBBox   [4]float64 // xMin, yMin, xMax, yMax
+
+	// Charstrings stores the Type2 charstrings, indexed by glyph.
+	Charstrings [][]byte
+
+	// Charset stores the glyph names, indexed by glyph.
+	// It is nil for CID-keyed fonts, see CIDs.
+	Charset []string
+
+	// CIDs stores the CID of each glyph for CID-keyed fonts,
+	// and is nil otherwise.
+	CIDs []uint16
+	ROS  ROS // only valid for CID-keyed fonts
+
+	// Encoding maps character codes to glyph names.
+	// Unused codes are mapped to an empty string.
+	// It is empty for CID-keyed fonts.
+	Encoding [256]string
+
+	globalSubrs [][]byte
+	fonts       []privateDict // one per font dict (only one for non CID-keyed fonts)
+	fdSelect    []uint16      // index into fonts, for CID-keyed fonts and CFF2
+
+	isCFF2   bool
+	varStore variationStore // CFF2 only
+	scalars  [][]float64    // for each item variation data, see SetVariations
+
+	nameToGID map[string]GID
+	cmap      map[rune]GID
+
+	metrics *afm.Metrics // optional, see AttachMetrics
+}
+
+type privateDict struct {
+	subrs        [][]byte
+	defaultWidth float64
+	nominalWidth float64
+	vsindex      int // CFF2 only
+}
+
+// ParseFont reads a standalone CFF file.
+// Although CFF allows several fonts in one file, embedded CFF files
+// shall consist of exactly one font, so that only the first one is returned.
+func ParseFont(file font.Resource) (*Face, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil { // file might have been used before
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses the CFF data, which may be the content of a 'CFF ' table.
+// Only the first font is returned.
+// The returned Face references `data`, which must not be modified.
+func Parse(data []byte) (*Face, error) {
+	if len(data) < 4 {
+		return nil, errEOF
+	}
+	if data[0] != 1 {
+		return nil, errUnsupportedVersion
+	}
+	p := parser{data: data, pos: int(data[2])} // header size
+	names, err := p.index(false)
+	if err != nil {
+		return nil, err
+	}
+	topDicts, err := p.index(false)
+	if err != nil {
+		return nil, err
+	}
+	strs, err := p.index(false)
+	if err != nil {
+		return nil, err
+	}
+	out := Face{}
+	out.globalSubrs, err = p.index(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 || len(topDicts) == 0 {
+		return nil, errors.New("invalid CFF font: no font")
+	}
+	out.FontName = string(names[0])
+
+	top, err := parseDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	}
+	if err = out.parseTopDict(&p, top, stringIndex(strs)); err != nil {
+		return nil, err
+	}
+
+	out.buildIndex()
+	return &out, nil
+}
+
+func (f *Face) parseTopDict(p *parser, top dict, strs stringIndex) error {
+	if t := top.int(opCharstringType, 2); t != 2 {
+		return fmt.Errorf("unsupported CFF charstring type %d", t)
+	}
+
+	getString := func(op dictOp) string {
+		if v := top[op]; len(v) != 0 {
+			return strs.get(uint16(v[0]))
+		}
+		return ""
+	}
+	f.Info = FontInfo{
+		Version:            getString(opVersion),
+		Notice:             getString(opNotice),
+		Copyright:          getString(opCopyright),
+		FullName:           getString(opFullName),
+		FamilyName:         getString(opFamilyName),
+		Weight:             getString(opWeight),
+		ItalicAngle:        top.number(opItalicAngle, 0),
+		IsFixedPitch:       top.number(opIsFixedPitch, 0) != 0,
+		UnderlinePosition:  top.number(opUnderlinePosition, -100),
+		UnderlineThickness: top.number(opUnderlineThickness, 50),
+	}
+	f.FontMatrix = [6]float64{0.001, 0, 0, 0.001, 0, 0}
+	if m := top[opFontMatrix]; len(m) == 6 {
+		copy(f.FontMatrix[:], m)
+	}
+	if m := top[opFontBBox]; len(m) == 4 {
+		copy(f.FontBBox[:], m)
+	}
+
+	// charstrings
+	var err error
+	if err = p.seek(top.int(opCharStrings, -1)); err != nil {
+		return errors.New("invalid CFF font: missing CharStrings")
+	}
+	f.Charstrings, err = p.index(false)
+	if err != nil {
+		return err
+	}
+	if len(f.Charstrings) == 0 {
+		return errors.New("invalid CFF font: missing CharStrings")
+	}
+
+	charset, err := p.parseCharset(top.int(opCharset, 0), len(f.Charstrings))
+	if err != nil {
+		return err
+	}
+
+	if ros := top[opROS]; len(ros) == 3 { // CID-keyed font
+		f.ROS = ROS{Registry: strs.get(uint16(ros[0])), Ordering: strs.get(uint16(ros[1])), Supplement: int(ros[2])}
+		f.CIDs = charset
+		return f.parseCIDFonts(p, top)
+	}
+
+	f.Charset = make([]string, len(charset))
+	for i, sid := range charset {
+		f.Charset[i] = strs.get(sid)
+	}
+	f.Encoding, err = p.parseEncoding(top.int(opEncoding, 0), charset, strs)
+	if err != nil {
+		return err
+	}
+	pd, err := p.parsePrivateDict(top[opPrivate], false)
+	if err != nil {
+		return err
+	}
+	f.fonts = []privateDict{pd}
+	return nil
+}
+
+// parseCIDFonts reads the FDArray and FDSelect of CID-keyed fonts.
+func (f *Face) parseCIDFonts(p *parser, top dict) error {
+	if err := p.seek(top.int(opFDArray, -1)); err != nil {
+		return errors.New("invalid CFF CID font: missing FDArray")
+	}
+	fdArray, err := p.index(false)
+	if err != nil {
+		return err
+	}
+	f.fonts = make([]privateDict, len(fdArray))
+	for i, fd := range fdArray {
+		fontDict, err := parseDict(fd)
+		if err != nil {
+			return err
+		}
+		f.fonts[i], err = p.parsePrivateDict(fontDict[opPrivate], false)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.fdSelect, err = p.parseFDSelect(top.int(opFDSelect, -1), len(f.Charstrings))
+	if err != nil {
+		return err
+	}
+	for _, fd := range f.fdSelect {
+		if int(fd) >= len(f.fonts) {
+			return fmt.Errorf("invalid CFF FDSelect index %d", fd)
+		}
+	}
+	return nil
+}
+
+// parsePrivateDict reads the Private DICT, given the (size, offset) operands,
+// and its local subroutines.
+func (p *parser) parsePrivateDict(sizeOffset []float64, isCFF2 bool) (privateDict, error) {
+	if len(sizeOffset) != 2 {
+		return privateDict{}, errors.New("invalid CFF font: missing Private DICT")
+	}
+	size, offset := int(sizeOffset[0]), int(sizeOffset[1])
+	if size < 0 || offset < 0 || offset+size > len(p.data) {
+		return privateDict{}, errors.New("invalid CFF Private DICT offset or size")
+	}
+	private, err := parseDict(p.data[offset : offset+size])
+	if err != nil {
+		return privateDict{}, err
+	}
+	out := privateDict{
+		defaultWidth: private.number(opDefaultWidthX, 0),
+		nominalWidth: private.number(opNominalWidthX, 0),
+		vsindex:      private.int(opVsindex, 0),
+	}
+	if subrs := private.int(opSubrs, 0); subrs != 0 { // offset relative to the Private DICT
+		if err = p.seek(offset + subrs); err != nil {
+			return out, err
+		}
+		out.subrs, err = p.index(isCFF2)
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func (f *Face) context(gid GID) charstringContext {
+	pd := f.fonts[0]
+	if f.fdSelect != nil {
+		pd = f.fonts[f.fdSelect[gid]]
+	}
+	return charstringContext{
+		localSubrs:   pd.subrs,
+		globalSubrs:  f.globalSubrs,
+		defaultWidth: pd.defaultWidth,
+		nominalWidth: pd.nominalWidth,
+		vsindex:      pd.vsindex,
+	}
+}
+
+// buildIndex builds the lookup tables by glyph name and by rune.
+func (f *Face) buildIndex() {
+	f.nameToGID = make(map[string]GID, len(f.Charset))
+	for i, name := range f.Charset {
+		if _, has := f.nameToGID[name]; !has {
+			f.nameToGID[name] = GID(i)
+		}
+	}
+
+	f.cmap = make(map[rune]GID, len(f.Charset))
+	addRune := func(name string) {
+		gid, ok := f.nameToGID[name]
+		if !ok || gid == 0 {
+			return
+		}
+		if r, ok := glyphsnames.GlyphToRune(name); ok {
+			if _, has := f.cmap[r]; !has {
+				f.cmap[r] = gid
+			}
+		}
+	}
+	// give priority to the glyphs referenced by the encoding
+	for _, name := range f.Encoding {
+		addRune(name)
+	}
+	for _, name := range f.Charset {
+		addRune(name)
+	}
+}
+
+// IsCIDKeyed returns true for CID-keyed fonts, which have
+// no glyph names.
+func (f *Face) IsCIDKeyed() bool { return f.CIDs != nil }
+
+// NumGlyphs returns the number of glyphs in the font.
+func (f *Face) NumGlyphs() int { return len(f.Charstrings) }
+
+// GlyphCount implements font.GlyphCounter, and is the same as NumGlyphs.
+func (f *Face) GlyphCount() int { return f.NumGlyphs() }
+
+// Upem returns the units per em, deduced from the font matrix.
+func (f *Face) Upem() uint16 {
+	if f.FontMatrix[0] <= 0 {
+		return 1000
+	}
+	return uint16(1/f.FontMatrix[0] + 0.5)
+}
+
+// NominalGlyph returns the glyph for the given rune, using the
+// Adobe Glyph List to interpret the glyph names.
+// It always returns false for CID-keyed fonts, which require an external CMap.
+func (f *Face) NominalGlyph(r rune) (GID, bool) {
+	gid, ok := f.cmap[r]
+	return gid, ok
+}
+
+// GlyphName returns the name of the given glyph, or an empty
+// string if the glyph is invalid or if the font is CID-keyed.
+func (f *Face) GlyphName(gid GID) string {
+	if int(gid) >= len(f.Charset) {
+		return ""
+	}
+	return f.Charset[gid]
+}
+
+// GlyphForCode returns the glyph used for the character code `code`,
+// according to the font built-in encoding.
+func (f *Face) GlyphForCode(code byte) (GID, bool) {
+	if f.Encoding[code] == "" {
+		return 0, false
+	}
+	gid, ok := f.nameToGID[f.Encoding[code]]
+	return gid, ok
+}
+
+// AttachMetrics uses the given AFM metrics to provide kerning,
+// and advances for the glyphs whose charstrings are invalid.
+// Passing nil removes the metrics.
+func (f *Face) AttachMetrics(metrics *afm.Metrics) { f.metrics = metrics }
+
+// HorizontalAdvance returns the advance of the glyph, in font units,
+// or 0 if the glyph is invalid.
+// If the advance can't be computed from the charstring, the attached AFM metrics are used.
+func (f *Face) HorizontalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.Charstrings) {
+		return 0
+	}
+	m, err := f.runCharstring(gid, true)
+	if err != nil {
+		if f.metrics != nil {
+			if cm, ok := f.metrics.CharMetric(f.GlyphName(gid)); ok {
+				return float32(cm.WX)
+			}
+		}
+		return 0
+	}
+	return float32(m.advance)
+}
+
+// VerticalAdvance returns the opposite of the units per em, since
+// the font has no vertical metrics, or 0 if the glyph is invalid.
+func (f *Face) VerticalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.Charstrings) {
+		return 0
+	}
+	return -float32(f.Upem())
+}
+
+// GlyphExtents returns the bounding box of the glyph outline, in font units,
+// or false if the glyph is invalid. Since the control points of the curves
+// are included, the box may be larger than the exact bounds.
+func (f *Face) GlyphExtents(gid GID) (font.GlyphExtents, bool) {
+	g, err := f.runCharstring(gid, false)
+	if err != nil {
+		return font.GlyphExtents{}, false
+	}
+	return g.extents(), true
+}
+
+// GlyphOutline returns the outline of the glyph, made of cubic Bézier curves,
+// or false if the glyph is invalid.
+func (f *Face) GlyphOutline(gid GID) (font.GlyphOutline, bool) {
+	g, err := f.runCharstring(gid, false)
+	if err != nil {
+		return font.GlyphOutline{}, false
+	}
+	return font.GlyphOutline{Segments: g.segments}, true
+}
+
+// FontHExtents returns the ascender and descender of the attached AFM metrics,
+// or of the font bounding box, and a line gap such that the line height is
+// at least 1.2 times the units per em. It returns false if the font has no bounding box.
+func (f *Face) FontHExtents() (font.FontExtents, bool) {
+	ascender, descender := f.FontBBox[3], f.FontBBox[1]
+	if f.metrics != nil && (f.metrics.Ascender != 0 || f.metrics.Descender != 0) {
+		ascender, descender = f.metrics.Ascender, f.metrics.Descender
+	} else if f.FontBBox == [4]float64{} {
+		return font.FontExtents{}, false
+	}
+	out := font.FontExtents{Ascender: float32(ascender), Descender: float32(descender)}
+	if height := 1.2 * float32(f.Upem()); height > out.Ascender-out.Descender {
+		out.LineGap = height - (out.Ascender - out.Descender)
+	}
+	return out, true
+}
+
+// LineMetric returns the underline position and thickness
+// of the font information, and false for the other metrics.
+func (f *Face) LineMetric(metric font.LineMetric) (float32, bool) {
+	switch metric {
+	case font.UnderlinePosition:
+		return float32(f.Info.UnderlinePosition), true
+	case font.UnderlineThickness:
+		return float32(f.Info.UnderlineThickness), true
+	default:
+		return 0, false
+	}
+}
+
+// Kerning returns the horizontal kerning adjustment (in font units) between
+// the glyphs `left` and `right`, as provided by the attached AFM metrics, or 0.
+func (f *Face) Kerning(left, right GID) float32 {
+	if f.metrics == nil {
+		return 0
+	}
+	return float32(f.metrics.Kerning(f.GlyphName(left), f.GlyphName(right)))
+}
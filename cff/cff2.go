@@ -0,0 +1,227 @@
+package cff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ParseCFF2 parses the content of a 'CFF2' table, used by
+// variable OpenType fonts with PostScript outlines.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/cff2
+//
+// CFF2 fonts have no names, encoding or advances : this information
+// is provided by the other tables of the OpenType font.
+// The returned Face references `data`, which must not be modified.
+func ParseCFF2(data []byte) (*Face, error) {
+	if len(data) < 5 {
+		return nil, errEOF
+	}
+	if data[0] != 2 {
+		return nil, errUnsupportedVersion
+	}
+	headerSize, topDictLength := int(data[2]), int(binary.BigEndian.Uint16(data[3:]))
+	if headerSize+topDictLength > len(data) {
+		return nil, errEOF
+	}
+	top, err := parseDict(data[headerSize : headerSize+topDictLength])
+	if err != nil {
+		return nil, err
+	}
+
+	out := Face{isCFF2: true}
+	p := parser{data: data, pos: headerSize + topDictLength}
+	out.globalSubrs, err = p.index(true)
+	if err != nil {
+		return nil, err
+	}
+
+	out.FontMatrix = [6]float64{0.001, 0, 0, 0.001, 0, 0}
+	if m := top[opFontMatrix]; len(m) == 6 {
+		copy(out.FontMatrix[:], m)
+	}
+
+	if err = p.seek(top.int(opCharStrings, -1)); err != nil {
+		return nil, errors.New("invalid CFF2 font: missing CharStrings")
+	}
+	out.Charstrings, err = p.index(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Charstrings) == 0 {
+		return nil, errors.New("invalid CFF2 font: missing CharStrings")
+	}
+
+	if vstore := top.int(opVstore, 0); vstore != 0 {
+		out.varStore, err = parseVariationStore(data, vstore)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.seek(top.int(opFDArray, -1)); err != nil {
+		return nil, errors.New("invalid CFF2 font: missing FDArray")
+	}
+	fdArray, err := p.index(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(fdArray) == 0 {
+		return nil, errors.New("invalid CFF2 font: empty FDArray")
+	}
+	out.fonts = make([]privateDict, len(fdArray))
+	for i, fd := range fdArray {
+		fontDict, err := parseDict(fd)
+		if err != nil {
+			return nil, err
+		}
+		out.fonts[i], err = p.parsePrivateDict(fontDict[opPrivate], true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fdSelect := top.int(opFDSelect, 0); fdSelect != 0 {
+		out.fdSelect, err = p.parseFDSelect(fdSelect, len(out.Charstrings))
+		if err != nil {
+			return nil, err
+		}
+		for _, fd := range out.fdSelect {
+			if int(fd) >= len(out.fonts) {
+				return nil, fmt.Errorf("invalid CFF2 FDSelect index %d", fd)
+			}
+		}
+	}
+
+	out.buildIndex()
+	return &out, nil
+}
+
+// variationStore is the subset of an Item Variation Store
+// used by CFF2 : the deltas are stored in the charstrings.
+type variationStore struct {
+	regions []region
+	// for each Item Variation Data, the indices into regions
+	regionIndices [][]uint16
+}
+
+// region stores one range for each axis
+type region []regionAxis
+
+type regionAxis struct {
+	start, peak, end float64
+}
+
+// parseVariationStore reads the variation store found at `offset`,
+// prefixed by its length.
+func parseVariationStore(data []byte, offset int) (variationStore, error) {
+	var out variationStore
+	if offset+2 > len(data) {
+		return out, errEOF
+	}
+	data = data[offset+2:] // skip the length
+	if len(data) < 8 {
+		return out, errors.New("invalid CFF2 variation store (EOF)")
+	}
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return out, fmt.Errorf("unsupported CFF2 variation store format %d", format)
+	}
+	regionsOffset := binary.BigEndian.Uint32(data[2:])
+	count := int(binary.BigEndian.Uint16(data[6:]))
+	if 8+4*count > len(data) || uint64(regionsOffset)+4 > uint64(len(data)) {
+		return out, errors.New("invalid CFF2 variation store (EOF)")
+	}
+
+	regions := data[regionsOffset:]
+	axisCount, regionCount := int(binary.BigEndian.Uint16(regions)), int(binary.BigEndian.Uint16(regions[2:]))
+	if 4+6*axisCount*regionCount > len(regions) {
+		return out, errors.New("invalid CFF2 variation region list (EOF)")
+	}
+	out.regions = make([]region, regionCount)
+	for i := range out.regions {
+		out.regions[i] = make(region, axisCount)
+		for j := range out.regions[i] {
+			r := regions[4+6*(i*axisCount+j):]
+			out.regions[i][j] = regionAxis{
+				start: f2dot14(r),
+				peak:  f2dot14(r[2:]),
+				end:   f2dot14(r[4:]),
+			}
+		}
+	}
+
+	out.regionIndices = make([][]uint16, count)
+	for i := range out.regionIndices {
+		ivdOffset := binary.BigEndian.Uint32(data[8+4*i:])
+		if uint64(ivdOffset)+6 > uint64(len(data)) {
+			return out, errors.New("invalid CFF2 item variation data (EOF)")
+		}
+		ivd := data[ivdOffset:]
+		indexCount := int(binary.BigEndian.Uint16(ivd[4:]))
+		if 6+2*indexCount > len(ivd) {
+			return out, errors.New("invalid CFF2 item variation data (EOF)")
+		}
+		indices := make([]uint16, indexCount)
+		for j := range indices {
+			indices[j] = binary.BigEndian.Uint16(ivd[6+2*j:])
+			if int(indices[j]) >= regionCount {
+				return out, fmt.Errorf("invalid CFF2 variation region index %d", indices[j])
+			}
+		}
+		out.regionIndices[i] = indices
+	}
+	return out, nil
+}
+
+func f2dot14(b []byte) float64 { return float64(int16(binary.BigEndian.Uint16(b))) / (1 << 14) }
+
+// scalar computes the contribution of the region for the given normalized coordinates.
+func (r region) scalar(coords []float32) float64 {
+	v := 1.
+	for i, axis := range r {
+		var coord float64
+		if i < len(coords) {
+			coord = float64(coords[i])
+		}
+		switch {
+		case axis.start > axis.peak || axis.peak > axis.end,
+			axis.start < 0 && axis.end > 0 && axis.peak != 0,
+			axis.peak == 0 || coord == axis.peak:
+			// the axis does not participate
+			continue
+		case coord <= axis.start || coord >= axis.end:
+			return 0
+		case coord < axis.peak:
+			v *= (coord - axis.start) / (axis.peak - axis.start)
+		default:
+			v *= (axis.end - coord) / (axis.end - axis.peak)
+		}
+	}
+	return v
+}
+
+// SetVariations sets the normalized variation coordinates (in [-1, 1]) used
+// to interpret the blend operators of CFF2 charstrings.
+// It has no effect on CFF fonts, and passing nil selects the default instance.
+func (f *Face) SetVariations(coords []float32) {
+	f.scalars = make([][]float64, len(f.varStore.regionIndices))
+	for i, indices := range f.varStore.regionIndices {
+		s := make([]float64, len(indices))
+		for j, index := range indices {
+			s[j] = f.varStore.regions[index].scalar(coords)
+		}
+		f.scalars[i] = s
+	}
+}
+
+// regionScalars returns the scalars of the regions used by the
+// item variation data `vsindex`, for the current coordinates.
+func (f *Face) regionScalars(vsindex int) ([]float64, error) {
+	if vsindex < 0 || vsindex >= len(f.varStore.regionIndices) {
+		return nil, fmt.Errorf("invalid CFF2 vsindex %d", vsindex)
+	}
+	if f.scalars == nil { // default instance
+		return make([]float64, len(f.varStore.regionIndices[vsindex])), nil
+	}
+	return f.scalars[vsindex], nil
+}
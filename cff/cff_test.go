@@ -0,0 +1,159 @@
+package cff
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"testing"
+
+	"github.com/go-text/font"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+func loadCFF(t *testing.T, file string) *Face {
+	t.Helper()
+	data, err := td.Files.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := Parse(data)
+	if err != nil {
+		t.Fatalf("%s: %s", file, err)
+	}
+	return face
+}
+
+func TestParseCorpus(t *testing.T) {
+	files, err := fs.Glob(td.Files, "cff/*.cff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("missing CFF files")
+	}
+	for _, file := range files {
+		face := loadCFF(t, file)
+		if face.NumGlyphs() == 0 {
+			t.Errorf("%s: no glyphs", file)
+		}
+		if !face.IsCIDKeyed() && (len(face.Charset) != face.NumGlyphs() || face.GlyphName(0) != ".notdef") {
+			t.Errorf("%s: invalid charset", file)
+		}
+		for gid := GID(0); int(gid) < face.NumGlyphs(); gid++ {
+			outline, ok1 := face.GlyphOutline(gid)
+			extents, ok2 := face.GlyphExtents(gid)
+			if !ok1 || !ok2 {
+				t.Errorf("%s: glyph %d: invalid charstring", file, gid)
+				continue
+			}
+			if len(outline.Segments) == 0 {
+				continue
+			}
+			// the extents include the control points
+			const tolerance = 0.01
+			tight := outline.TightExtents()
+			if tight.XBearing < extents.XBearing-tolerance || tight.YBearing > extents.YBearing+tolerance ||
+				tight.XBearing+tight.Width > extents.XBearing+extents.Width+tolerance ||
+				tight.YBearing+tight.Height < extents.YBearing+extents.Height-tolerance {
+				t.Errorf("%s: glyph %d: outline extents %v not enclosed by %v", file, gid, tight, extents)
+			}
+		}
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	data, err := td.Files.ReadFile("cff/NimbusSans-Bold.cff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, length := range []int{0, 3, 10, 100, 1000, len(data) / 2} {
+		if _, err := Parse(data[:length]); err == nil {
+			t.Errorf("expected an error for %d bytes", length)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	face := loadCFF(t, "cff/NimbusSans-Bold.cff")
+	if face.FontName != "NimbusSans-Bold" || face.Info.Weight != "Bold" || face.Upem() != 1000 {
+		t.Errorf("unexpected face info %s %v", face.FontName, face.Info)
+	}
+	gid, ok := face.GlyphForCode('A')
+	if !ok || face.GlyphName(gid) != "A" {
+		t.Fatalf("code 'A' mapped to glyph %d (%q)", gid, face.GlyphName(gid))
+	}
+	if nominal, _ := face.NominalGlyph('A'); nominal != gid {
+		t.Errorf("expected glyph %d for rune 'A', got %d", gid, nominal)
+	}
+	if advance := face.HorizontalAdvance(gid); advance != 722 {
+		t.Errorf("expected advance 722 for 'A', got %g", advance)
+	}
+}
+
+func TestCIDKeyed(t *testing.T) {
+	face := loadCFF(t, "cff/AdobeMingStd-Light-Identity-H.cff")
+	if !face.IsCIDKeyed() || len(face.CIDs) != face.NumGlyphs() {
+		t.Fatal("expected a CID-keyed font")
+	}
+	if expected := (ROS{"Adobe", "CNS1", 7}); face.ROS != expected {
+		t.Errorf("expected ROS %v, got %v", expected, face.ROS)
+	}
+	if _, ok := face.NominalGlyph('A'); ok || face.GlyphName(1) != "" {
+		t.Error("CID-keyed fonts have no glyph names")
+	}
+}
+
+// sfntTable returns the content of the table `tag` of an OpenType face.
+func sfntTable(data []byte, tag string) []byte {
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := data[12+16*i:]
+		if string(record[:4]) == tag {
+			offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+			return data[offset : offset+length]
+		}
+	}
+	return nil
+}
+
+func TestParseCFF2(t *testing.T) {
+	data, err := td.Files.ReadFile("common/NotoSansCJKjp-VF.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := ParseCFF2(sfntTable(data, "CFF2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if face.NumGlyphs() == 0 || face.Upem() != 1000 {
+		t.Fatalf("unexpected face: %d glyphs, %d units per em", face.NumGlyphs(), face.Upem())
+	}
+
+	const numGlyphs = 1000
+	var defaults [numGlyphs]font.GlyphExtents
+	for gid := GID(0); gid < numGlyphs; gid++ {
+		var ok bool
+		if defaults[gid], ok = face.GlyphExtents(gid); !ok {
+			t.Fatalf("glyph %d: invalid charstring", gid)
+		}
+	}
+	face.SetVariations([]float32{1})
+	changed := 0
+	for gid := GID(0); gid < numGlyphs; gid++ {
+		extents, ok := face.GlyphExtents(gid)
+		if !ok {
+			t.Fatalf("glyph %d: invalid charstring for the bold instance", gid)
+		}
+		if extents != defaults[gid] {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Error("the variations have no effect")
+	}
+	// back to the default instance
+	face.SetVariations(nil)
+	if extents, _ := face.GlyphExtents(1); extents != defaults[1] {
+		t.Errorf("expected default extents %v, got %v", defaults[1], extents)
+	}
+}
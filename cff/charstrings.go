@@ -0,0 +1,650 @@
+package cff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-text/font"
+)
+
+// This file implements an interpreter for Type2 charstrings,
+// as defined in the Adobe Technical Note #5177.
+
+const (
+	maxStackSize     = 48
+	maxCFF2StackSize = 513
+	maxSubrsDepth    = 10
+	transientSize    = 32
+)
+
+type point struct{ x, y float64 }
+
+func (p point) add(q point) point { return point{p.x + q.x, p.y + q.y} }
+
+func (p point) toSegmentPoint() font.SegmentPoint {
+	return font.SegmentPoint{X: float32(p.x), Y: float32(p.y)}
+}
+
+// glyphData is the result of the interpretation of a charstring
+type glyphData struct {
+	advance  float64
+	segments []font.Segment
+}
+
+// charstringContext stores the data needed to interpret the
+// charstrings of one glyph.
+type charstringContext struct {
+	localSubrs, globalSubrs [][]byte
+	defaultWidth            float64
+	nominalWidth            float64
+	vsindex                 int // CFF2 only, may be changed by the charstring
+}
+
+// extents returns the bounding box of the points of the outline,
+// including the control points of the curves.
+// Move points not followed by a line or a curve are ignored.
+func (g glyphData) extents() font.GlyphExtents {
+	xMin, yMin := float32(math.Inf(+1)), float32(math.Inf(+1))
+	xMax, yMax := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for i, s := range g.segments {
+		args := s.ArgsSlice()
+		if s.Op == font.SegmentOpMoveTo && (i+1 == len(g.segments) || g.segments[i+1].Op == font.SegmentOpMoveTo) {
+			args = nil
+		}
+		for _, p := range args {
+			if p.X < xMin {
+				xMin = p.X
+			}
+			if p.X > xMax {
+				xMax = p.X
+			}
+			if p.Y < yMin {
+				yMin = p.Y
+			}
+			if p.Y > yMax {
+				yMax = p.Y
+			}
+		}
+	}
+	if xMin > xMax { // no outline
+		return font.GlyphExtents{}
+	}
+	return font.GlyphExtents{
+		XBearing: xMin,
+		YBearing: yMax,
+		Width:    xMax - xMin,
+		Height:   yMin - yMax,
+	}
+}
+
+type interpreter struct {
+	face *Face
+	ctx  charstringContext
+
+	stack     []float64
+	transient [transientSize]float64
+
+	glyph   glyphData
+	current point
+	offset  point // used to position seac accents
+
+	numStems int
+	widthSet bool
+
+	depth     int
+	seac      bool // nested charstrings may not use seac
+	widthOnly bool
+	done      bool
+}
+
+// runCharstring interprets the charstring of the given glyph. If `widthOnly` is true,
+// the execution stops as soon as the advance is known.
+func (f *Face) runCharstring(gid GID, widthOnly bool) (glyphData, error) {
+	if int(gid) >= len(f.Charstrings) {
+		return glyphData{}, fmt.Errorf("invalid glyph index %d", gid)
+	}
+	ip := f.newInterpreter(gid)
+	ip.widthOnly = widthOnly
+	err := ip.run(f.Charstrings[gid])
+	return ip.glyph, err
+}
+
+func (f *Face) newInterpreter(gid GID) interpreter {
+	maxStack := maxStackSize
+	if f.isCFF2 {
+		maxStack = maxCFF2StackSize
+	}
+	return interpreter{face: f, ctx: f.context(gid), stack: make([]float64, 0, maxStack)}
+}
+
+func subrsBias(subrs [][]byte) int {
+	switch n := len(subrs); {
+	case n < 1240:
+		return 107
+	case n < 33900:
+		return 1131
+	default:
+		return 32768
+	}
+}
+
+func (ip *interpreter) push(v float64) error {
+	if len(ip.stack) >= cap(ip.stack) {
+		return errors.New("invalid Type2 charstring (stack overflow)")
+	}
+	ip.stack = append(ip.stack, v)
+	return nil
+}
+
+func (ip *interpreter) pop() (float64, error) {
+	if len(ip.stack) == 0 {
+		return 0, errors.New("invalid Type2 charstring (stack underflow)")
+	}
+	v := ip.stack[len(ip.stack)-1]
+	ip.stack = ip.stack[:len(ip.stack)-1]
+	return v, nil
+}
+
+func (ip *interpreter) moveTo(p point) {
+	ip.current = p
+	ip.glyph.segments = append(ip.glyph.segments, font.Segment{Op: font.SegmentOpMoveTo, Args: [3]font.SegmentPoint{p.toSegmentPoint()}})
+}
+
+func (ip *interpreter) lineTo(d point) {
+	ip.current = ip.current.add(d)
+	ip.glyph.segments = append(ip.glyph.segments, font.Segment{Op: font.SegmentOpLineTo, Args: [3]font.SegmentPoint{ip.current.toSegmentPoint()}})
+}
+
+// cubeTo adds a curve using three relative displacements.
+func (ip *interpreter) cubeTo(d1, d2, d3 point) {
+	p1 := ip.current.add(d1)
+	p2 := p1.add(d2)
+	p3 := p2.add(d3)
+	ip.current = p3
+	ip.glyph.segments = append(ip.glyph.segments, font.Segment{Op: font.SegmentOpCubeTo, Args: [3]font.SegmentPoint{p1.toSegmentPoint(), p2.toSegmentPoint(), p3.toSegmentPoint()}})
+}
+
+// readWidth handles the optional width argument of the first
+// stack clearing operator, expecting `n` arguments (or a multiple of n if `pairs` is true)
+func (ip *interpreter) readWidth(n int, pairs bool) {
+	if ip.widthSet {
+		return
+	}
+	ip.widthSet = true
+	ip.glyph.advance = ip.ctx.defaultWidth
+	hasWidth := len(ip.stack) > n
+	if pairs {
+		hasWidth = len(ip.stack)%2 == 1
+	}
+	if hasWidth && !ip.face.isCFF2 { // no width in CFF2 charstrings
+		ip.glyph.advance = ip.ctx.nominalWidth + ip.stack[0]
+		ip.stack = append(ip.stack[:0], ip.stack[1:]...)
+	}
+	if ip.widthOnly {
+		ip.done = true
+	}
+}
+
+func (ip *interpreter) run(code []byte) error {
+	if ip.depth > maxSubrsDepth {
+		return errors.New("invalid Type2 charstring (subroutines nesting limit reached)")
+	}
+	ip.depth++
+	defer func() { ip.depth-- }()
+
+	for pos := 0; pos < len(code) && !ip.done; {
+		b := int(code[pos])
+		pos++
+
+		// numbers
+		switch {
+		case b == 28:
+			if pos+2 > len(code) {
+				return errors.New("invalid Type2 charstring (EOF)")
+			}
+			v := int16(binary.BigEndian.Uint16(code[pos:]))
+			pos += 2
+			if err := ip.push(float64(v)); err != nil {
+				return err
+			}
+			continue
+		case b >= 32 && b <= 246:
+			if err := ip.push(float64(b - 139)); err != nil {
+				return err
+			}
+			continue
+		case b >= 247 && b <= 254:
+			if pos >= len(code) {
+				return errors.New("invalid Type2 charstring (EOF)")
+			}
+			w := int(code[pos])
+			pos++
+			v := (b-247)*256 + w + 108
+			if b >= 251 {
+				v = -(b-251)*256 - w - 108
+			}
+			if err := ip.push(float64(v)); err != nil {
+				return err
+			}
+			continue
+		case b == 255: // 16.16 fixed
+			if pos+4 > len(code) {
+				return errors.New("invalid Type2 charstring (EOF)")
+			}
+			v := int32(binary.BigEndian.Uint32(code[pos:]))
+			pos += 4
+			if err := ip.push(float64(v) / (1 << 16)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// operators
+		switch b {
+		case 12:
+			if pos >= len(code) {
+				return errors.New("invalid Type2 charstring (EOF)")
+			}
+			b = 256 + int(code[pos])
+			pos++
+		case 19, 20: // hintmask, cntrmask : skip the mask bytes
+			// the stack may contain implicit vstem hints
+			ip.readWidth(0, true)
+			ip.numStems += len(ip.stack) / 2
+			ip.stack = ip.stack[:0]
+			pos += (ip.numStems + 7) / 8
+			continue
+		case 11: // return
+			return nil
+		}
+
+		if err := ip.operator(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ip *interpreter) operator(op int) error {
+	a := ip.stack
+	switch op {
+	case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+		ip.readWidth(0, true)
+		ip.numStems += len(ip.stack) / 2
+	case 21: // rmoveto
+		ip.readWidth(2, false)
+		a = ip.stack
+		if len(a) < 2 {
+			return errors.New("invalid Type2 charstring (missing arguments for rmoveto)")
+		}
+		ip.moveTo(ip.current.add(point{a[0], a[1]}).add(ip.offsetOnce()))
+	case 22: // hmoveto
+		ip.readWidth(1, false)
+		a = ip.stack
+		if len(a) < 1 {
+			return errors.New("invalid Type2 charstring (missing arguments for hmoveto)")
+		}
+		ip.moveTo(ip.current.add(point{a[0], 0}).add(ip.offsetOnce()))
+	case 4: // vmoveto
+		ip.readWidth(1, false)
+		a = ip.stack
+		if len(a) < 1 {
+			return errors.New("invalid Type2 charstring (missing arguments for vmoveto)")
+		}
+		ip.moveTo(ip.current.add(point{0, a[0]}).add(ip.offsetOnce()))
+	case 5: // rlineto
+		for i := 0; i+2 <= len(a); i += 2 {
+			ip.lineTo(point{a[i], a[i+1]})
+		}
+	case 6, 7: // hlineto, vlineto
+		horizontal := op == 6
+		for _, d := range a {
+			if horizontal {
+				ip.lineTo(point{d, 0})
+			} else {
+				ip.lineTo(point{0, d})
+			}
+			horizontal = !horizontal
+		}
+	case 8: // rrcurveto
+		for i := 0; i+6 <= len(a); i += 6 {
+			ip.cubeTo(point{a[i], a[i+1]}, point{a[i+2], a[i+3]}, point{a[i+4], a[i+5]})
+		}
+	case 24: // rcurveline
+		if len(a) < 8 {
+			return errors.New("invalid Type2 charstring (missing arguments for rcurveline)")
+		}
+		i := 0
+		for ; i+8 <= len(a); i += 6 {
+			ip.cubeTo(point{a[i], a[i+1]}, point{a[i+2], a[i+3]}, point{a[i+4], a[i+5]})
+		}
+		ip.lineTo(point{a[i], a[i+1]})
+	case 25: // rlinecurve
+		if len(a) < 8 {
+			return errors.New("invalid Type2 charstring (missing arguments for rlinecurve)")
+		}
+		i := 0
+		for ; i+8 <= len(a); i += 2 {
+			ip.lineTo(point{a[i], a[i+1]})
+		}
+		ip.cubeTo(point{a[i], a[i+1]}, point{a[i+2], a[i+3]}, point{a[i+4], a[i+5]})
+	case 26: // vvcurveto
+		var dx1 float64
+		if len(a)%2 == 1 {
+			dx1, a = a[0], a[1:]
+		}
+		for i := 0; i+4 <= len(a); i += 4 {
+			ip.cubeTo(point{dx1, a[i]}, point{a[i+1], a[i+2]}, point{0, a[i+3]})
+			dx1 = 0
+		}
+	case 27: // hhcurveto
+		var dy1 float64
+		if len(a)%2 == 1 {
+			dy1, a = a[0], a[1:]
+		}
+		for i := 0; i+4 <= len(a); i += 4 {
+			ip.cubeTo(point{a[i], dy1}, point{a[i+1], a[i+2]}, point{a[i+3], 0})
+			dy1 = 0
+		}
+	case 30, 31: // vhcurveto, hvcurveto
+		horizontal := op == 31
+		for i := 0; i+4 <= len(a); i += 4 {
+			var last float64
+			if i+5 == len(a) {
+				last = a[i+4]
+			}
+			if horizontal {
+				ip.cubeTo(point{a[i], 0}, point{a[i+1], a[i+2]}, point{last, a[i+3]})
+			} else {
+				ip.cubeTo(point{0, a[i]}, point{a[i+1], a[i+2]}, point{a[i+3], last})
+			}
+			horizontal = !horizontal
+		}
+	case 10, 29: // callsubr, callgsubr
+		index, err := ip.pop()
+		if err != nil {
+			return err
+		}
+		subrs := ip.ctx.localSubrs
+		if op == 29 {
+			subrs = ip.ctx.globalSubrs
+		}
+		i := int(index) + subrsBias(subrs)
+		if i < 0 || i >= len(subrs) {
+			return fmt.Errorf("invalid Type2 charstring (subroutine %d out of range)", i)
+		}
+		return ip.run(subrs[i]) // the arguments are kept on the stack
+	case 14: // endchar
+		n := len(ip.stack)
+		if n == 1 || n == 5 { // the first argument is the width
+			n--
+		}
+		ip.readWidth(n, false)
+		ip.done = true
+		if len(ip.stack) >= 4 {
+			a = ip.stack[len(ip.stack)-4:]
+			return ip.doSeac(a[0], a[1], a[2], a[3])
+		}
+	case 15: // vsindex (CFF2)
+		index, err := ip.pop()
+		if err != nil {
+			return err
+		}
+		ip.ctx.vsindex = int(index)
+	case 16: // blend (CFF2)
+		return ip.blend()
+	case 256 + 35: // flex
+		if len(a) < 13 {
+			return errors.New("invalid Type2 charstring (missing arguments for flex)")
+		}
+		ip.cubeTo(point{a[0], a[1]}, point{a[2], a[3]}, point{a[4], a[5]})
+		ip.cubeTo(point{a[6], a[7]}, point{a[8], a[9]}, point{a[10], a[11]})
+	case 256 + 34: // hflex
+		if len(a) < 7 {
+			return errors.New("invalid Type2 charstring (missing arguments for hflex)")
+		}
+		ip.cubeTo(point{a[0], 0}, point{a[1], a[2]}, point{a[3], 0})
+		ip.cubeTo(point{a[4], 0}, point{a[5], -a[2]}, point{a[6], 0})
+	case 256 + 36: // hflex1
+		if len(a) < 9 {
+			return errors.New("invalid Type2 charstring (missing arguments for hflex1)")
+		}
+		ip.cubeTo(point{a[0], a[1]}, point{a[2], a[3]}, point{a[4], 0})
+		ip.cubeTo(point{a[5], 0}, point{a[6], a[7]}, point{a[8], -(a[1] + a[3] + a[7])})
+	case 256 + 37: // flex1
+		if len(a) < 11 {
+			return errors.New("invalid Type2 charstring (missing arguments for flex1)")
+		}
+		var d point
+		for i := 0; i < 10; i += 2 {
+			d = d.add(point{a[i], a[i+1]})
+		}
+		last := point{a[10], -d.y}
+		if math.Abs(d.x) <= math.Abs(d.y) {
+			last = point{-d.x, a[10]}
+		}
+		ip.cubeTo(point{a[0], a[1]}, point{a[2], a[3]}, point{a[4], a[5]})
+		ip.cubeTo(point{a[6], a[7]}, point{a[8], a[9]}, last)
+	default:
+		if op >= 256 {
+			return ip.arithmetic(op - 256)
+		}
+		// unknown operators are ignored
+	}
+	ip.stack = ip.stack[:0]
+	return nil
+}
+
+// blend applies the variation deltas to the default values, replacing
+// v1 ... vn d11 ... d1k ... dn1 ... dnk n by the n blended values.
+func (ip *interpreter) blend() error {
+	if !ip.face.isCFF2 {
+		return errors.New("invalid Type2 charstring (blend operator in CFF font)")
+	}
+	n, err := ip.pop()
+	if err != nil {
+		return err
+	}
+	scalars, err := ip.face.regionScalars(ip.ctx.vsindex)
+	if err != nil {
+		return err
+	}
+	count, k := int(n), len(scalars)
+	if count < 0 || count*(k+1) > len(ip.stack) {
+		return errors.New("invalid Type2 charstring (missing arguments for blend)")
+	}
+	args := ip.stack[len(ip.stack)-count*(k+1):]
+	deltas := args[count:]
+	for i := range args[:count] {
+		for j, scalar := range scalars {
+			args[i] += scalar * deltas[i*k+j]
+		}
+	}
+	ip.stack = ip.stack[:len(ip.stack)-count*k]
+	return nil
+}
+
+// offsetOnce returns the seac accent offset for the first moveto
+func (ip *interpreter) offsetOnce() point {
+	o := ip.offset
+	ip.offset = point{}
+	return o
+}
+
+// arithmetic implements the (deprecated) arithmetic and storage operators,
+// which do not clear the stack.
+func (ip *interpreter) arithmetic(op int) error {
+	var (
+		n   = len(ip.stack)
+		err = fmt.Errorf("invalid Type2 charstring (missing arguments for operator 12 %d)", op)
+	)
+	switch op {
+	case 9, 14, 26, 18, 27: // abs, neg, sqrt, drop, dup
+		if n < 1 {
+			return err
+		}
+		v := &ip.stack[n-1]
+		switch op {
+		case 9:
+			*v = math.Abs(*v)
+		case 14:
+			*v = -*v
+		case 26:
+			*v = math.Sqrt(math.Abs(*v))
+		case 18:
+			ip.stack = ip.stack[:n-1]
+		case 27:
+			return ip.push(*v)
+		}
+	case 3, 4, 10, 11, 12, 15, 24, 28: // and, or, add, sub, div, eq, mul, exch
+		if n < 2 {
+			return err
+		}
+		x, y := ip.stack[n-2], ip.stack[n-1]
+		var r float64
+		switch op {
+		case 3:
+			r = boolToFloat(x != 0 && y != 0)
+		case 4:
+			r = boolToFloat(x != 0 || y != 0)
+		case 10:
+			r = x + y
+		case 11:
+			r = x - y
+		case 12:
+			if y == 0 {
+				return errors.New("invalid Type2 charstring (division by zero)")
+			}
+			r = x / y
+		case 15:
+			r = boolToFloat(x == y)
+		case 24:
+			r = x * y
+		case 28:
+			ip.stack[n-2], ip.stack[n-1] = y, x
+			return nil
+		}
+		ip.stack = append(ip.stack[:n-2], r)
+	case 5: // not
+		if n < 1 {
+			return err
+		}
+		ip.stack[n-1] = boolToFloat(ip.stack[n-1] == 0)
+	case 22: // ifelse
+		if n < 4 {
+			return err
+		}
+		s1, s2, v1, v2 := ip.stack[n-4], ip.stack[n-3], ip.stack[n-2], ip.stack[n-1]
+		if v1 > v2 {
+			s1 = s2
+		}
+		ip.stack = append(ip.stack[:n-4], s1)
+	case 23: // random : use a deterministic value in ]0, 1]
+		return ip.push(0.5)
+	case 20: // put
+		if n < 2 {
+			return err
+		}
+		i := int(ip.stack[n-1])
+		if i < 0 || i >= transientSize {
+			return errors.New("invalid Type2 charstring (invalid transient index)")
+		}
+		ip.transient[i] = ip.stack[n-2]
+		ip.stack = ip.stack[:n-2]
+	case 21: // get
+		if n < 1 {
+			return err
+		}
+		i := int(ip.stack[n-1])
+		if i < 0 || i >= transientSize {
+			return errors.New("invalid Type2 charstring (invalid transient index)")
+		}
+		ip.stack[n-1] = ip.transient[i]
+	case 29: // index
+		if n < 1 {
+			return err
+		}
+		i := int(ip.stack[n-1])
+		if i < 0 {
+			i = 0
+		}
+		if i >= n-1 {
+			return errors.New("invalid Type2 charstring (invalid index)")
+		}
+		ip.stack[n-1] = ip.stack[n-2-i]
+	case 30: // roll
+		if n < 2 {
+			return err
+		}
+		count, shift := int(ip.stack[n-2]), int(ip.stack[n-1])
+		ip.stack = ip.stack[:n-2]
+		if count < 0 || count > len(ip.stack) {
+			return errors.New("invalid Type2 charstring (invalid roll)")
+		}
+		if count == 0 {
+			return nil
+		}
+		elems := ip.stack[len(ip.stack)-count:]
+		shift = ((shift % count) + count) % count
+		rolled := append(append([]float64(nil), elems[count-shift:]...), elems[:count-shift]...)
+		copy(elems, rolled)
+	default:
+		// hints (dotsection) and unknown operators are ignored
+		ip.stack = ip.stack[:0]
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// doSeac builds an accented character from two glyphs
+// of the standard encoding, as for the Type1 seac operator.
+func (ip *interpreter) doSeac(adx, ady, bchar, achar float64) error {
+	if ip.seac {
+		return errors.New("invalid Type2 charstring (nested seac)")
+	}
+	if ip.widthOnly {
+		return nil
+	}
+
+	base, err := ip.standardGlyph(bchar)
+	if err != nil {
+		return err
+	}
+	accent, err := ip.standardGlyph(achar)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range [2]struct {
+		gid    GID
+		offset point
+	}{{base, point{}}, {accent, point{adx, ady}}} {
+		sub := ip.face.newInterpreter(g.gid)
+		sub.seac, sub.offset = true, g.offset
+		if err = sub.run(ip.face.Charstrings[g.gid]); err != nil {
+			return err
+		}
+		ip.glyph.segments = append(ip.glyph.segments, sub.glyph.segments...)
+	}
+	return nil
+}
+
+func (ip *interpreter) standardGlyph(code float64) (GID, error) {
+	if code < 0 || code > 255 {
+		return 0, errors.New("invalid Type2 charstring (invalid seac code)")
+	}
+	name := standardEncoding[int(code)]
+	gid, ok := ip.face.nameToGID[name]
+	if name == "" || !ok {
+		return 0, fmt.Errorf("invalid Type2 charstring (missing seac glyph %s)", name)
+	}
+	return gid, nil
+}
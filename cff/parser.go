@@ -0,0 +1,486 @@
+package cff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errEOF = errors.New("invalid CFF font (EOF)")
+
+// parser is a cursor over the CFF data
+type parser struct {
+	data []byte
+	pos  int
+}
+
+func (p *parser) u8() (byte, error) {
+	if p.pos >= len(p.data) {
+		return 0, errEOF
+	}
+	p.pos++
+	return p.data[p.pos-1], nil
+}
+
+func (p *parser) u16() (uint16, error) {
+	if p.pos+2 > len(p.data) {
+		return 0, errEOF
+	}
+	p.pos += 2
+	return binary.BigEndian.Uint16(p.data[p.pos-2:]), nil
+}
+
+func (p *parser) u32() (uint32, error) {
+	if p.pos+4 > len(p.data) {
+		return 0, errEOF
+	}
+	p.pos += 4
+	return binary.BigEndian.Uint32(p.data[p.pos-4:]), nil
+}
+
+// offset reads an offset of size `offSize` (1 to 4)
+func (p *parser) offset(offSize int) (uint32, error) {
+	if p.pos+offSize > len(p.data) {
+		return 0, errEOF
+	}
+	var v uint32
+	for _, b := range p.data[p.pos : p.pos+offSize] {
+		v = v<<8 | uint32(b)
+	}
+	p.pos += offSize
+	return v, nil
+}
+
+func (p *parser) seek(offset int) error {
+	if offset < 0 || offset > len(p.data) {
+		return fmt.Errorf("invalid CFF offset %d", offset)
+	}
+	p.pos = offset
+	return nil
+}
+
+// index reads an INDEX structure, whose count is stored on
+// 2 bytes for CFF and 4 bytes for CFF2.
+// The returned slices point into the font data.
+func (p *parser) index(isCFF2 bool) ([][]byte, error) {
+	var (
+		count uint32
+		err   error
+	)
+	if isCFF2 {
+		count, err = p.u32()
+	} else {
+		var c uint16
+		c, err = p.u16()
+		count = uint32(c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	offSize, err := p.u8()
+	if err != nil {
+		return nil, err
+	}
+	if offSize < 1 || offSize > 4 {
+		return nil, fmt.Errorf("invalid CFF INDEX offset size %d", offSize)
+	}
+	if uint64(count+1)*uint64(offSize) > uint64(len(p.data)-p.pos) {
+		return nil, errEOF
+	}
+	offsets := make([]uint32, count+1)
+	for i := range offsets {
+		offsets[i], _ = p.offset(int(offSize))
+	}
+	// offsets are relative to the byte preceding the data
+	base := p.pos - 1
+	out := make([][]byte, count)
+	for i := range out {
+		start, end := uint64(base)+uint64(offsets[i]), uint64(base)+uint64(offsets[i+1])
+		if offsets[i] == 0 || start > end || end > uint64(len(p.data)) {
+			return nil, errors.New("invalid CFF INDEX offsets")
+		}
+		out[i] = p.data[start:end]
+	}
+	p.pos = int(uint64(base) + uint64(offsets[count]))
+	return out, nil
+}
+
+// dictOp identifies a DICT operator. Two bytes operators (12 x)
+// are stored as 1200 + x
+type dictOp uint16
+
+const (
+	opVersion            dictOp = 0
+	opNotice             dictOp = 1
+	opFullName           dictOp = 2
+	opFamilyName         dictOp = 3
+	opWeight             dictOp = 4
+	opFontBBox           dictOp = 5
+	opCharset            dictOp = 15
+	opEncoding           dictOp = 16
+	opCharStrings        dictOp = 17
+	opPrivate            dictOp = 18
+	opSubrs              dictOp = 19
+	opDefaultWidthX      dictOp = 20
+	opNominalWidthX      dictOp = 21
+	opVsindex            dictOp = 22 // CFF2 only
+	opBlend              dictOp = 23 // CFF2 only
+	opVstore             dictOp = 24 // CFF2 only
+	opCopyright          dictOp = 1200
+	opIsFixedPitch       dictOp = 1201
+	opItalicAngle        dictOp = 1202
+	opUnderlinePosition  dictOp = 1203
+	opUnderlineThickness dictOp = 1204
+	opCharstringType     dictOp = 1206
+	opFontMatrix         dictOp = 1207
+	opROS                dictOp = 1230
+	opCIDCount           dictOp = 1234
+	opFDArray            dictOp = 1236
+	opFDSelect           dictOp = 1237
+	opFontName           dictOp = 1238
+)
+
+// dict maps the operators to their operands
+type dict map[dictOp][]float64
+
+// int returns the first operand of `op`, or `def`
+func (d dict) int(op dictOp, def int) int {
+	if v := d[op]; len(v) != 0 {
+		return int(v[0])
+	}
+	return def
+}
+
+func (d dict) number(op dictOp, def float64) float64 {
+	if v := d[op]; len(v) != 0 {
+		return v[0]
+	}
+	return def
+}
+
+// parseDict decodes a DICT structure. Unknown operators are
+// kept, and their meaning is left to the caller.
+// The CFF2 blend operator is treated as a regular operator, so that the
+// (variable) values it applies to are dropped : they are only used for hinting.
+func parseDict(data []byte) (dict, error) {
+	out := dict{}
+	var operands []float64
+	for i := 0; i < len(data); {
+		b := data[i]
+		i++
+		switch {
+		case b <= 27: // operators (22 to 24 are only used in CFF2)
+			op := dictOp(b)
+			if b == 12 {
+				if i >= len(data) {
+					return nil, errors.New("invalid CFF DICT operator (EOF)")
+				}
+				op = 1200 + dictOp(data[i])
+				i++
+			}
+			out[op] = operands
+			operands = nil
+		case b == 28:
+			if i+2 > len(data) {
+				return nil, errors.New("invalid CFF DICT operand (EOF)")
+			}
+			operands = append(operands, float64(int16(binary.BigEndian.Uint16(data[i:]))))
+			i += 2
+		case b == 29:
+			if i+4 > len(data) {
+				return nil, errors.New("invalid CFF DICT operand (EOF)")
+			}
+			operands = append(operands, float64(int32(binary.BigEndian.Uint32(data[i:]))))
+			i += 4
+		case b == 30:
+			v, n, err := parseReal(data[i:])
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, v)
+			i += n
+		case 32 <= b && b <= 246:
+			operands = append(operands, float64(int(b)-139))
+		case 247 <= b && b <= 254:
+			if i >= len(data) {
+				return nil, errors.New("invalid CFF DICT operand (EOF)")
+			}
+			w := int(data[i])
+			i++
+			if b <= 250 {
+				operands = append(operands, float64((int(b)-247)*256+w+108))
+			} else {
+				operands = append(operands, float64(-(int(b)-251)*256-w-108))
+			}
+		default:
+			return nil, fmt.Errorf("invalid CFF DICT operand %d", b)
+		}
+	}
+	return out, nil
+}
+
+// parseReal decodes the nibbles of a real number, returning
+// the number of bytes read.
+func parseReal(data []byte) (float64, int, error) {
+	var s []byte
+	for i, b := range data {
+		for _, nibble := range [2]byte{b >> 4, b & 0xF} {
+			switch {
+			case nibble <= 9:
+				s = append(s, '0'+nibble)
+			case nibble == 0xa:
+				s = append(s, '.')
+			case nibble == 0xb:
+				s = append(s, 'E')
+			case nibble == 0xc:
+				s = append(s, 'E', '-')
+			case nibble == 0xe:
+				s = append(s, '-')
+			case nibble == 0xf:
+				if len(s) == 0 {
+					return 0, i + 1, nil
+				}
+				v, err := strconv.ParseFloat(string(s), 64)
+				if err != nil {
+					return 0, 0, fmt.Errorf("invalid CFF real number: %s", err)
+				}
+				return v, i + 1, nil
+			default:
+				return 0, 0, errors.New("invalid CFF real number")
+			}
+		}
+	}
+	return 0, 0, errors.New("invalid CFF real number (EOF)")
+}
+
+// parseCharset returns the SIDs (or CIDs) of each glyph
+func (p *parser) parseCharset(offset, numGlyphs int) ([]uint16, error) {
+	switch offset {
+	case 0: // ISOAdobe
+		return predefinedCharset(charsetISOAdobe[:], numGlyphs), nil
+	case 1: // Expert
+		return predefinedCharset(charsetExpert[:], numGlyphs), nil
+	case 2: // ExpertSubset
+		return predefinedCharset(charsetExpertSubset[:], numGlyphs), nil
+	}
+
+	if err := p.seek(offset); err != nil {
+		return nil, err
+	}
+	format, err := p.u8()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uint16, 1, numGlyphs) // .notdef is omitted
+	switch format {
+	case 0:
+		for len(out) < numGlyphs {
+			sid, err := p.u16()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sid)
+		}
+	case 1, 2:
+		for len(out) < numGlyphs {
+			first, err := p.u16()
+			if err != nil {
+				return nil, err
+			}
+			var nLeft uint16
+			if format == 1 {
+				var n byte
+				n, err = p.u8()
+				nLeft = uint16(n)
+			} else {
+				nLeft, err = p.u16()
+			}
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i <= int(nLeft) && len(out) < numGlyphs; i++ {
+				out = append(out, first+uint16(i))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid CFF charset format %d", format)
+	}
+	return out, nil
+}
+
+func predefinedCharset(sids []uint16, numGlyphs int) []uint16 {
+	if numGlyphs < len(sids) {
+		sids = sids[:numGlyphs]
+	}
+	return append([]uint16(nil), sids...)
+}
+
+// parseEncoding returns the glyph names for each code.
+func (p *parser) parseEncoding(offset int, charset []uint16, strs stringIndex) ([256]string, error) {
+	switch offset {
+	case 0:
+		return standardEncoding, nil
+	case 1:
+		return expertEncoding, nil
+	}
+
+	var out [256]string
+	if err := p.seek(offset); err != nil {
+		return out, err
+	}
+	format, err := p.u8()
+	if err != nil {
+		return out, err
+	}
+	setCode := func(code byte, gid int) {
+		if gid < len(charset) {
+			out[code] = strs.get(charset[gid])
+		}
+	}
+	switch format & 0x7f {
+	case 0:
+		nCodes, err := p.u8()
+		if err != nil {
+			return out, err
+		}
+		for gid := 1; gid <= int(nCodes); gid++ {
+			code, err := p.u8()
+			if err != nil {
+				return out, err
+			}
+			setCode(code, gid)
+		}
+	case 1:
+		nRanges, err := p.u8()
+		if err != nil {
+			return out, err
+		}
+		gid := 1
+		for i := 0; i < int(nRanges); i++ {
+			first, err := p.u8()
+			if err != nil {
+				return out, err
+			}
+			nLeft, err := p.u8()
+			if err != nil {
+				return out, err
+			}
+			for c := int(first); c <= int(first)+int(nLeft) && c < 256; c++ {
+				setCode(byte(c), gid)
+				gid++
+			}
+		}
+	default:
+		return out, fmt.Errorf("invalid CFF encoding format %d", format)
+	}
+
+	if format&0x80 != 0 { // supplements
+		nSups, err := p.u8()
+		if err != nil {
+			return out, err
+		}
+		for i := 0; i < int(nSups); i++ {
+			code, err := p.u8()
+			if err != nil {
+				return out, err
+			}
+			sid, err := p.u16()
+			if err != nil {
+				return out, err
+			}
+			out[code] = strs.get(sid)
+		}
+	}
+	return out, nil
+}
+
+// parseFDSelect returns the font dict index of each glyph
+func (p *parser) parseFDSelect(offset, numGlyphs int) ([]uint16, error) {
+	if err := p.seek(offset); err != nil {
+		return nil, err
+	}
+	format, err := p.u8()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uint16, numGlyphs)
+	switch format {
+	case 0:
+		if p.pos+numGlyphs > len(p.data) {
+			return nil, errEOF
+		}
+		for i, fd := range p.data[p.pos : p.pos+numGlyphs] {
+			out[i] = uint16(fd)
+		}
+	case 3, 4: // format 4 is only used in CFF2
+		var nRanges uint32
+		if format == 3 {
+			n, err := p.u16()
+			nRanges = uint32(n)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			nRanges, err = p.u32()
+			if err != nil {
+				return nil, err
+			}
+		}
+		readGID := func() (uint32, error) {
+			if format == 3 {
+				v, err := p.u16()
+				return uint32(v), err
+			}
+			return p.u32()
+		}
+		first, err := readGID()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < nRanges; i++ {
+			var fd uint16
+			if format == 3 {
+				var b byte
+				b, err = p.u8()
+				fd = uint16(b)
+			} else {
+				fd, err = p.u16()
+			}
+			if err != nil {
+				return nil, err
+			}
+			next, err := readGID()
+			if err != nil {
+				return nil, err
+			}
+			if first > next {
+				return nil, errors.New("invalid CFF FDSelect range")
+			}
+			for gid := first; gid < next && gid < uint32(numGlyphs); gid++ {
+				out[gid] = fd
+			}
+			first = next
+		}
+	default:
+		return nil, fmt.Errorf("invalid CFF FDSelect format %d", format)
+	}
+	return out, nil
+}
+
+// stringIndex stores the custom strings, referenced by SIDs starting at 391
+type stringIndex [][]byte
+
+func (s stringIndex) get(sid uint16) string {
+	if int(sid) < len(stdStrings) {
+		return stdStrings[sid]
+	}
+	sid -= uint16(len(stdStrings))
+	if int(sid) < len(s) {
+		return string(s[sid])
+	}
+	return ""
+}
@@ -7,8 +7,80 @@ var _ Face = (fonts.Face)(nil)
 
 type GID = fonts.GID
 
+// Resource is a combination of io.Reader, io.Seeker and io.ReaderAt,
+// satisfied for instance by *os.File or *bytes.Reader.
+type Resource = fonts.Resource
+
 type Face interface {
+	// Upem returns the units per em of the font, that is the number of font units
+	// in one em. Bitmap fonts, whose metrics are in pixels, return their pixel size.
+	Upem() uint16
+
 	// NominalGlyph returns the glyph identifier used to represent the given rune,
 	// or false the rune is not supported by the font.
 	NominalGlyph(r rune) (GID, bool)
+
+	// HorizontalAdvance returns the horizontal advance of the glyph, in font units,
+	// or 0 if the glyph is invalid.
+	HorizontalAdvance(gid GID) float32
+
+	// VerticalAdvance returns the vertical advance of the glyph, in font units,
+	// or 0 if the glyph is invalid. Since the y axis grows up, it is negative
+	// for top to bottom text.
+	// For fonts without vertical metrics, a default value is returned.
+	VerticalAdvance(gid GID) float32
+
+	// FontHExtents returns the extents of the font for horizontal text,
+	// that is its ascender, descender and line gap, in font units,
+	// or false if they are not available.
+	FontHExtents() (FontExtents, bool)
+
+	// LineMetric returns the metric identified by `metric`, in font units,
+	// or false if the font does not provide it.
+	// For variable fonts, the value is adjusted to the current variation coordinates.
+	LineMetric(metric LineMetric) (float32, bool)
+}
+
+// FontExtents exposes font wide extent values, measured in font units.
+// Note that typically ascender is positive and descender negative in coordinate systems that grow up.
+type FontExtents = fonts.FontExtents
+
+// LineMetric identifies one metric about the font.
+// Some formats only support a subset of the metrics defined by the constants.
+type LineMetric = fonts.LineMetric
+
+const (
+	// Distance above the baseline of the top of the underline.
+	// Since most fonts have underline positions beneath the baseline, this value is typically negative.
+	UnderlinePosition = fonts.UnderlinePosition
+	// Suggested thickness to draw for the underline.
+	UnderlineThickness = fonts.UnderlineThickness
+	// Distance above the baseline of the top of the strikethrough.
+	StrikethroughPosition = fonts.StrikethroughPosition
+	// Suggested thickness to draw for the strikethrough.
+	StrikethroughThickness = fonts.StrikethroughThickness
+
+	SuperscriptEmYSize   = fonts.SuperscriptEmYSize
+	SuperscriptEmXOffset = fonts.SuperscriptEmXOffset
+	SubscriptEmYSize     = fonts.SubscriptEmYSize
+	SubscriptEmYOffset   = fonts.SubscriptEmYOffset
+	SubscriptEmXOffset   = fonts.SubscriptEmXOffset
+)
+
+// GlyphCounter is implemented by the faces providing their number of glyphs.
+// Valid glyph identifiers are in [0, GlyphCount()).
+type GlyphCounter interface {
+	GlyphCount() int
+}
+
+// GlyphExtents exposes extent values, measured in font units.
+// Note that height is negative in coordinate systems that grow up.
+type GlyphExtents = fonts.GlyphExtents
+
+// GlyphExtenter is implemented by the faces providing
+// the extents of their glyphs.
+type GlyphExtenter interface {
+	// GlyphExtents returns the extents of the given glyph, in font units,
+	// or false if the glyph is invalid or its extents are not available.
+	GlyphExtents(gid GID) (GlyphExtents, bool)
 }
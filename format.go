@@ -0,0 +1,202 @@
+package font
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// Format identifies the kind of a font file.
+type Format uint8
+
+const (
+	Unknown    Format = iota // unrecognized or unreadable file
+	TrueType                 // sfnt font with TrueType outlines (.ttf)
+	OpenType                 // sfnt font with CFF outlines (.otf)
+	Collection               // TrueType or OpenType collection (.ttc, .otc)
+	WOFF                     // Web Open Font Format 1.0 (.woff)
+	WOFF2                    // Web Open Font Format 2.0 (.woff2), possibly wrapping a collection
+	EOT                      // Embedded OpenType (.eot)
+	Type1                    // PostScript Type1 font, binary (.pfb) or ASCII (.pfa)
+	PCF                      // X11 Portable Compiled Format bitmap font (.pcf, .pcf.gz)
+	BDF                      // Glyph Bitmap Distribution Format bitmap font (.bdf)
+	CFF                      // bare Compact Font Format font, as embedded in PDF files
+	SfntType1                // sfnt font wrapping PostScript Type1 outlines ('typ1' version, old Mac OS)
+)
+
+func (f Format) String() string {
+	switch f {
+	case TrueType:
+		return "TrueType"
+	case OpenType:
+		return "OpenType"
+	case Collection:
+		return "Collection"
+	case WOFF:
+		return "WOFF"
+	case WOFF2:
+		return "WOFF2"
+	case EOT:
+		return "EOT"
+	case Type1:
+		return "Type1"
+	case PCF:
+		return "PCF"
+	case BDF:
+		return "BDF"
+	case CFF:
+		return "CFF"
+	case SfntType1:
+		return "SfntType1"
+	default:
+		return "Unknown"
+	}
+}
+
+// DetectFormat inspects the first bytes of `file` to find its format,
+// without parsing the whole font. It also returns the number of faces
+// stored in the file, which is only greater than 1 for collections, and
+// 0 for Unknown (which is also returned when `file` can't be read).
+func DetectFormat(file io.ReaderAt) (Format, int) {
+	var header [48]byte
+	n, _ := file.ReadAt(header[:], 0)
+	data := header[:n]
+	if len(data) < 4 {
+		return Unknown, 0
+	}
+
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+		return TrueType, 1
+	case "typ1":
+		return SfntType1, 1
+	case "OTTO":
+		return OpenType, 1
+	case "ttcf":
+		if len(data) < 12 {
+			return Unknown, 0
+		}
+		return Collection, int(binary.BigEndian.Uint32(data[8:]))
+	case "wOFF":
+		return WOFF, 1
+	case "wOF2":
+		if len(data) < 48 {
+			return Unknown, 0
+		}
+		if string(data[4:8]) != "ttcf" {
+			return WOFF2, 1
+		}
+		return WOFF2, woff2NumFonts(file, int(binary.BigEndian.Uint16(data[12:])))
+	case "\x01fcp":
+		return PCF, 1
+	case "STAR":
+		if bytes.HasPrefix(data, []byte("STARTFONT")) {
+			return BDF, 1
+		}
+	}
+
+	switch {
+	case data[0] == 0x80 && data[1] == 1, // PFB segment header
+		bytes.HasPrefix(data, []byte("%!PS-AdobeFont")), bytes.HasPrefix(data, []byte("%!FontType1")):
+		return Type1, 1
+	case data[0] == 0x1f && data[1] == 0x8b: // gzip
+		r, err := gzip.NewReader(io.NewSectionReader(file, 0, 1<<62))
+		if err != nil {
+			return Unknown, 0
+		}
+		var magic [4]byte
+		if _, err = io.ReadFull(r, magic[:]); err == nil && string(magic[:]) == "\x01fcp" {
+			return PCF, 1
+		}
+	case isEOT(data):
+		return EOT, 1
+	case data[0] == 1 && data[1] == 0 && data[2] >= 4 && 1 <= data[3] && data[3] <= 4: // CFF header
+		return CFF, 1
+	}
+	return Unknown, 0
+}
+
+// isEOT checks the magic number and the version of an EOT header
+func isEOT(header []byte) bool {
+	if len(header) < 36 || binary.LittleEndian.Uint16(header[34:]) != 0x504C {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(header[8:]) {
+	case 0x00010000, 0x00020001, 0x00020002:
+		return true
+	}
+	return false
+}
+
+// woff2NumFonts skips the table directory of a WOFF2 collection
+// to read the number of fonts in its collection directory.
+func woff2NumFonts(file io.ReaderAt, numTables int) int {
+	// each table entry takes at most 1 + 4 + 5 + 5 bytes
+	buf := make([]byte, numTables*15+7)
+	n, _ := file.ReadAt(buf, 48)
+	buf = buf[:n]
+
+	pos := 0
+	readBase128 := func() bool {
+		for i := 0; i < 5; i++ {
+			if pos >= len(buf) {
+				return false
+			}
+			b := buf[pos]
+			pos++
+			if b&0x80 == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < numTables; i++ {
+		if pos >= len(buf) {
+			return 0
+		}
+		flags := buf[pos]
+		pos++
+		if flags&0x3f == 0x3f { // arbitrary tag
+			pos += 4
+		}
+		if !readBase128() { // origLength
+			return 0
+		}
+		// the transform version 3 is the null transform for 'glyf' and 'loca',
+		// and the version 0 for the other tables
+		tag := flags & 0x3f
+		transformed := (flags >> 6) != 0
+		if tag == 10 || tag == 11 {
+			transformed = (flags >> 6) == 0
+		}
+		if transformed && !readBase128() { // transformLength
+			return 0
+		}
+	}
+
+	// version (uint32) followed by numFonts (255UInt16)
+	pos += 4
+	if pos >= len(buf) {
+		return 0
+	}
+	switch code := buf[pos]; code {
+	case 253:
+		if pos+3 > len(buf) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(buf[pos+1:]))
+	case 254:
+		if pos+2 > len(buf) {
+			return 0
+		}
+		return int(buf[pos+1]) + 253*2
+	case 255:
+		if pos+2 > len(buf) {
+			return 0
+		}
+		return int(buf[pos+1]) + 253
+	default:
+		return int(code)
+	}
+}
@@ -0,0 +1,99 @@
+package font
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+func TestDetectFormatCorpus(t *testing.T) {
+	expected := map[string]Format{
+		".ttf": TrueType, ".otb": TrueType, ".otf": OpenType, ".ttc": Collection,
+		".woff": WOFF, ".cff": CFF, ".dfont": Unknown, // Mac resource forks are not supported
+	}
+	exceptions := map[string]Format{
+		"toys/NamesCFF.ttf": OpenType,
+	}
+	numFaces := map[string]int{
+		"collections/NotoSansCJK-Bold.ttc": 10,
+		"collections/msgothic.ttc":         3,
+		"toys/3cmaps.ttc":                  2,
+	}
+	err := fs.WalkDir(td.Files, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "table" || d.Name() == "tables") { // raw tables
+			return fs.SkipDir
+		}
+		format, ok := expected[strings.ToLower(path.Ext(file))]
+		if d.IsDir() || !ok {
+			return nil
+		}
+		if exception, ok := exceptions[file]; ok {
+			format = exception
+		}
+		expectedFaces := 1
+		if format == Collection {
+			expectedFaces = numFaces[file]
+		} else if format == Unknown {
+			expectedFaces = 0
+		}
+
+		data, err := td.Files.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if got, n := DetectFormat(bytes.NewReader(data)); got != format || n != expectedFaces {
+			t.Errorf("%s: expected %s (%d faces), got %s (%d faces)", file, format, expectedFaces, got, n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	readFile := func(file string) []byte {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	eot := make([]byte, 82)
+	binary.LittleEndian.PutUint32(eot[8:], 0x00020001)
+	binary.LittleEndian.PutUint16(eot[34:], 0x504C)
+
+	for _, test := range []struct {
+		name     string
+		data     []byte
+		format   Format
+		numFaces int
+	}{
+		{"WOFF2", readFile("opentype/woff2/testdata/roundtrip-hmtx-lsb-001.woff2"), WOFF2, 1},
+		{"WOFF2 collection", readFile("opentype/woff2/testdata/roundtrip-collection-order-001.woff2"), WOFF2, 3},
+		{"PCF", readFile("pcf/testdata/8x16.pcf.gz"), PCF, 1},
+		{"BDF", []byte("STARTFONT 2.1\nFONT test\n"), BDF, 1},
+		{"PFA", []byte("%!PS-AdobeFont-1.0: Test 001.000\n"), Type1, 1},
+		{"PFA", []byte("%!FontType1-1.0: Test 001.000\n"), Type1, 1},
+		{"PFB", []byte{0x80, 1, 0, 0, 0, 0, '%', '!'}, Type1, 1},
+		{"EOT", eot, EOT, 1},
+		{"sfnt Type1", []byte("typ1\x00\x01\x00\x00"), SfntType1, 1},
+		{"empty", nil, Unknown, 0},
+		{"text", []byte("this is not a font"), Unknown, 0},
+		{"invalid gzip", []byte{0x1f, 0x8b, 0, 0, 0}, Unknown, 0},
+		{"truncated collection", []byte("ttcf\x00\x01"), Unknown, 0},
+	} {
+		if format, n := DetectFormat(bytes.NewReader(test.data)); format != test.format || n != test.numFaces {
+			t.Errorf("%s: expected %s (%d faces), got %s (%d faces)", test.name, test.format, test.numFaces, format, n)
+		}
+	}
+}
@@ -1,5 +1,9 @@
 module github.com/go-text/font
 
-go 1.15
+go 1.16
 
-require github.com/benoitkugler/textlayout v0.0.3
+require (
+	github.com/andybalholm/brotli v1.0.4
+	github.com/benoitkugler/textlayout v0.0.3
+	github.com/go-text/typesetting-utils v0.0.0-20250618110550-c820a94c77b8
+)
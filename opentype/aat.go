@@ -0,0 +1,418 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// parseAATLookup parses a lookup table of the AAT tables, mapping glyphs to
+// 16-bit values, which are returned as the classes of a ClassDef.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6Tables.html
+func parseAATLookup(data []byte, offset uint32, numGlyphs int) (ClassDef, error) {
+	if int64(offset)+2 > int64(len(data)) {
+		return nil, errors.New("invalid lookup table (EOF)")
+	}
+	table := data[offset:]
+	switch format := binary.BigEndian.Uint16(table); format {
+	case 0: // simple array
+		if len(table) < 2+2*numGlyphs {
+			return nil, errors.New("invalid lookup table format 0 (EOF)")
+		}
+		out := classDefArray{classes: make([]uint16, numGlyphs)}
+		for i := range out.classes {
+			out.classes[i] = binary.BigEndian.Uint16(table[2+2*i:])
+		}
+		return out, nil
+	case 2, 4, 6: // binary searched segments or single glyphs
+		units, unitSize, err := parseAATBinSearch(table[2:])
+		if err != nil {
+			return nil, err
+		}
+		minSize := 6 // segments
+		if format == 6 {
+			minSize = 4 // single glyphs
+		}
+		if unitSize < minSize {
+			return nil, fmt.Errorf("invalid lookup table format %d unit size %d", format, unitSize)
+		}
+		var out classDefRanges
+		for _, unit := range units {
+			switch format {
+			case 2:
+				last, first := GID(binary.BigEndian.Uint16(unit)), GID(binary.BigEndian.Uint16(unit[2:]))
+				if first > last {
+					return nil, errors.New("invalid lookup table format 2 segment")
+				}
+				out = append(out, classRange{start: first, end: last, class: binary.BigEndian.Uint16(unit[4:])})
+			case 4: // the segment value is the offset of an array of values
+				last, first := GID(binary.BigEndian.Uint16(unit)), GID(binary.BigEndian.Uint16(unit[2:]))
+				valuesOffset := int(binary.BigEndian.Uint16(unit[4:]))
+				if first > last {
+					return nil, errors.New("invalid lookup table format 4 segment")
+				}
+				if len(table) < valuesOffset+2*(int(last-first)+1) {
+					return nil, errors.New("invalid lookup table format 4 (EOF)")
+				}
+				for gid := first; ; gid++ {
+					value := binary.BigEndian.Uint16(table[valuesOffset+2*int(gid-first):])
+					out = append(out, classRange{start: gid, end: gid, class: value})
+					if gid == last {
+						break
+					}
+				}
+			case 6:
+				gid := GID(binary.BigEndian.Uint16(unit))
+				out = append(out, classRange{start: gid, end: gid, class: binary.BigEndian.Uint16(unit[2:])})
+			}
+		}
+		for i := 1; i < len(out); i++ {
+			if out[i].start <= out[i-1].end {
+				return nil, errors.New("invalid lookup table: unsorted entries")
+			}
+		}
+		return out, nil
+	case 8: // trimmed array
+		if len(table) < 6 {
+			return nil, errors.New("invalid lookup table format 8 (EOF)")
+		}
+		out := classDefArray{startGlyph: GID(binary.BigEndian.Uint16(table[2:]))}
+		count := int(binary.BigEndian.Uint16(table[4:]))
+		if len(table) < 6+2*count {
+			return nil, errors.New("invalid lookup table format 8 (EOF)")
+		}
+		out.classes = make([]uint16, count)
+		for i := range out.classes {
+			out.classes[i] = binary.BigEndian.Uint16(table[6+2*i:])
+		}
+		return out, nil
+	case 10: // extended trimmed array, only supported for 1 or 2 bytes values
+		if len(table) < 8 {
+			return nil, errors.New("invalid lookup table format 10 (EOF)")
+		}
+		unitSize := int(binary.BigEndian.Uint16(table[2:]))
+		out := classDefArray{startGlyph: GID(binary.BigEndian.Uint16(table[4:]))}
+		count := int(binary.BigEndian.Uint16(table[6:]))
+		if unitSize != 1 && unitSize != 2 {
+			return nil, fmt.Errorf("unsupported lookup table format 10 unit size %d", unitSize)
+		}
+		if len(table) < 8+unitSize*count {
+			return nil, errors.New("invalid lookup table format 10 (EOF)")
+		}
+		out.classes = make([]uint16, count)
+		for i := range out.classes {
+			if unitSize == 1 {
+				out.classes[i] = uint16(table[8+i])
+			} else {
+				out.classes[i] = binary.BigEndian.Uint16(table[8+2*i:])
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported lookup table format %d", format)
+	}
+}
+
+// aatLongLookup is a lookup table of the AAT tables with 32-bit values,
+// stored as sorted glyph ranges.
+type aatLongLookup []aatLongRange
+
+type aatLongRange struct {
+	start, end GID
+	value      uint32
+}
+
+// value returns the value of the glyph, or false if it is not covered.
+func (l aatLongLookup) value(gid GID) (uint32, bool) {
+	i := sort.Search(len(l), func(i int) bool { return l[i].end >= gid })
+	if i < len(l) && l[i].start <= gid {
+		return l[i].value, true
+	}
+	return 0, false
+}
+
+// parseAATLongLookup is the same as parseAATLookup,
+// for the lookup tables mapping glyphs to 32-bit values.
+func parseAATLongLookup(data []byte, offset uint32, numGlyphs int) (aatLongLookup, error) {
+	if int64(offset)+2 > int64(len(data)) {
+		return nil, errors.New("invalid lookup table (EOF)")
+	}
+	table := data[offset:]
+	var out aatLongLookup
+	switch format := binary.BigEndian.Uint16(table); format {
+	case 0: // simple array
+		if len(table) < 2+4*numGlyphs {
+			return nil, errors.New("invalid lookup table format 0 (EOF)")
+		}
+		out = make(aatLongLookup, numGlyphs)
+		for i := range out {
+			out[i] = aatLongRange{start: GID(i), end: GID(i), value: binary.BigEndian.Uint32(table[2+4*i:])}
+		}
+	case 2, 4, 6: // binary searched segments or single glyphs
+		units, unitSize, err := parseAATBinSearch(table[2:])
+		if err != nil {
+			return nil, err
+		}
+		minSize := 8 // segments
+		if format != 2 {
+			minSize = 6 // segments with an offset, or single glyphs
+		}
+		if unitSize < minSize {
+			return nil, fmt.Errorf("invalid lookup table format %d unit size %d", format, unitSize)
+		}
+		for _, unit := range units {
+			switch format {
+			case 2:
+				last, first := GID(binary.BigEndian.Uint16(unit)), GID(binary.BigEndian.Uint16(unit[2:]))
+				if first > last {
+					return nil, errors.New("invalid lookup table format 2 segment")
+				}
+				out = append(out, aatLongRange{start: first, end: last, value: binary.BigEndian.Uint32(unit[4:])})
+			case 4: // the segment value is the offset of an array of values
+				last, first := GID(binary.BigEndian.Uint16(unit)), GID(binary.BigEndian.Uint16(unit[2:]))
+				valuesOffset := int(binary.BigEndian.Uint16(unit[4:]))
+				if first > last {
+					return nil, errors.New("invalid lookup table format 4 segment")
+				}
+				if len(table) < valuesOffset+4*(int(last-first)+1) {
+					return nil, errors.New("invalid lookup table format 4 (EOF)")
+				}
+				for gid := first; ; gid++ {
+					value := binary.BigEndian.Uint32(table[valuesOffset+4*int(gid-first):])
+					out = append(out, aatLongRange{start: gid, end: gid, value: value})
+					if gid == last {
+						break
+					}
+				}
+			case 6:
+				gid := GID(binary.BigEndian.Uint16(unit))
+				out = append(out, aatLongRange{start: gid, end: gid, value: binary.BigEndian.Uint32(unit[2:])})
+			}
+		}
+		for i := 1; i < len(out); i++ {
+			if out[i].start <= out[i-1].end {
+				return nil, errors.New("invalid lookup table: unsorted entries")
+			}
+		}
+	case 10: // extended trimmed array
+		if len(table) < 8 {
+			return nil, errors.New("invalid lookup table format 10 (EOF)")
+		}
+		if unitSize := binary.BigEndian.Uint16(table[2:]); unitSize != 4 {
+			return nil, fmt.Errorf("unsupported lookup table format 10 unit size %d", unitSize)
+		}
+		start := GID(binary.BigEndian.Uint16(table[4:]))
+		count := int(binary.BigEndian.Uint16(table[6:]))
+		if len(table) < 8+4*count {
+			return nil, errors.New("invalid lookup table format 10 (EOF)")
+		}
+		out = make(aatLongLookup, count)
+		for i := range out {
+			gid := start + GID(i)
+			out[i] = aatLongRange{start: gid, end: gid, value: binary.BigEndian.Uint32(table[8+4*i:])}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported lookup table format %d", format)
+	}
+	return out, nil
+}
+
+// parseAATBinSearch parses the binary search header and returns the units
+// following it, without the optional terminating 0xFFFF unit.
+func parseAATBinSearch(data []byte) (units [][]byte, unitSize int, err error) {
+	const headerSize = 10
+	if len(data) < headerSize {
+		return nil, 0, errors.New("invalid binary search header (EOF)")
+	}
+	unitSize = int(binary.BigEndian.Uint16(data))
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if unitSize < 2 {
+		return nil, 0, fmt.Errorf("invalid binary search unit size %d", unitSize)
+	}
+	if len(data) < headerSize+unitSize*count {
+		return nil, 0, errors.New("invalid binary search table (EOF)")
+	}
+	units = make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		unit := data[headerSize+unitSize*i : headerSize+unitSize*(i+1)]
+		if binary.BigEndian.Uint16(unit) == 0xFFFF {
+			break
+		}
+		units = append(units, unit)
+	}
+	return units, unitSize, nil
+}
+
+// AATStateTable is a finite state machine of the AAT tables,
+// processing the glyphs of a run according to their classes.
+// For each glyph, the entry of the current state and of the class of the glyph
+// gives the next state, and the actions to perform.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6Tables.html
+type AATStateTable struct {
+	// Classes assigns the classes of the glyphs, the glyphs not
+	// covered being in the class AATClassOutOfBounds.
+	Classes    ClassDef
+	NumClasses int
+	// States are the indices, in Entries, of the entries of the
+	// states, indexed by state then by class.
+	States  [][]uint16
+	Entries []AATStateEntry
+}
+
+// The classes predefined by the AAT state tables, the
+// classes of the glyphs starting at 4.
+const (
+	AATClassEndOfText    = 0
+	AATClassOutOfBounds  = 1
+	AATClassDeletedGlyph = 2
+	AATClassEndOfLine    = 3
+)
+
+// The states predefined by the AAT state tables.
+const (
+	AATStateStartOfText = 0
+	AATStateStartOfLine = 1
+)
+
+// AATStateEntry is a transition of an AAT state table.
+type AATStateEntry struct {
+	NewState uint16 // index in AATStateTable.States
+	Flags    uint16 // table specific
+	// Data are the table specific values following the flags
+	// (such as the indices of the actions), if any.
+	Data [2]uint16
+}
+
+// Class returns the class of the glyph, 0xFFFF identifying the deleted glyphs.
+func (st *AATStateTable) Class(gid GID) uint16 {
+	if gid == 0xFFFF {
+		return AATClassDeletedGlyph
+	}
+	if class, ok := st.Classes.Class(gid); ok {
+		return class
+	}
+	return AATClassOutOfBounds
+}
+
+// Entry returns the entry of the state `state` for the class `class`,
+// treating invalid classes as AATClassOutOfBounds, and invalid states
+// as AATStateStartOfText.
+func (st *AATStateTable) Entry(state, class uint16) AATStateEntry {
+	if int(state) >= len(st.States) {
+		state = AATStateStartOfText
+	}
+	row := st.States[state]
+	if int(class) >= len(row) {
+		class = AATClassOutOfBounds
+	}
+	if index := row[class]; int(index) < len(st.Entries) {
+		return st.Entries[index]
+	}
+	return AATStateEntry{}
+}
+
+// parseAATStateTable parses the state table starting at `data`, which is an extended
+// state table (used by 'morx' and 'kerx') if `extended` is true, or the original
+// state table (used by 'kern' and 'mort') otherwise. Each entry is followed by
+// `entryData` (at most 2) 16-bit values.
+// The number of states and entries are not given by the tables, and are deduced
+// from the states reachable from the start of text (AATStateStartOfText).
+func parseAATStateTable(data []byte, extended bool, entryData int, numGlyphs int) (AATStateTable, error) {
+	errTruncated := errors.New("invalid state table (EOF)")
+	var (
+		out                                    AATStateTable
+		statesOffset, entriesOffset, entrySize int
+		err                                    error
+	)
+	if extended {
+		if len(data) < 16 {
+			return out, errTruncated
+		}
+		out.NumClasses = int(binary.BigEndian.Uint32(data))
+		if out.Classes, err = parseAATLookup(data, binary.BigEndian.Uint32(data[4:]), numGlyphs); err != nil {
+			return out, err
+		}
+		statesOffset, entriesOffset = int(binary.BigEndian.Uint32(data[8:])), int(binary.BigEndian.Uint32(data[12:]))
+		entrySize = 2 // uint16 entry indices
+	} else {
+		if len(data) < 8 {
+			return out, errTruncated
+		}
+		out.NumClasses = int(binary.BigEndian.Uint16(data))
+		classesOffset := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < classesOffset+4 {
+			return out, errTruncated
+		}
+		classes := classDefArray{startGlyph: GID(binary.BigEndian.Uint16(data[classesOffset:]))}
+		count := int(binary.BigEndian.Uint16(data[classesOffset+2:]))
+		if len(data) < classesOffset+4+count {
+			return out, errTruncated
+		}
+		classes.classes = make([]uint16, count)
+		for i, class := range data[classesOffset+4 : classesOffset+4+count] {
+			classes.classes[i] = uint16(class)
+		}
+		out.Classes = classes
+		statesOffset, entriesOffset = int(binary.BigEndian.Uint16(data[4:])), int(binary.BigEndian.Uint16(data[6:]))
+		entrySize = 1 // uint8 entry indices
+	}
+	if out.NumClasses < 4 || out.NumClasses > 0xFFFF {
+		return out, fmt.Errorf("invalid number of classes %d", out.NumClasses)
+	}
+	if statesOffset > len(data) || entriesOffset > len(data) {
+		return out, errTruncated
+	}
+
+	// parse the rows of the states, then the entries they use,
+	// until no new state is referenced
+	rowSize := entrySize * out.NumClasses
+	entryLength := 4 + 2*entryData
+	for numStates, numEntries := 1, 0; ; {
+		for len(out.States) < numStates {
+			start := statesOffset + rowSize*len(out.States)
+			if len(data) < start+rowSize {
+				return out, errTruncated
+			}
+			row := make([]uint16, out.NumClasses)
+			for i := range row {
+				if extended {
+					row[i] = binary.BigEndian.Uint16(data[start+2*i:])
+				} else {
+					row[i] = uint16(data[start+i])
+				}
+				if int(row[i]) >= numEntries {
+					numEntries = int(row[i]) + 1
+				}
+			}
+			out.States = append(out.States, row)
+		}
+		for len(out.Entries) < numEntries {
+			start := entriesOffset + entryLength*len(out.Entries)
+			if len(data) < start+entryLength {
+				return out, errTruncated
+			}
+			entry := AATStateEntry{
+				NewState: binary.BigEndian.Uint16(data[start:]),
+				Flags:    binary.BigEndian.Uint16(data[start+2:]),
+			}
+			for i := 0; i < entryData; i++ {
+				entry.Data[i] = binary.BigEndian.Uint16(data[start+4+2*i:])
+			}
+			if !extended { // convert the offset of the state to an index
+				offset := int(entry.NewState) - statesOffset
+				if offset < 0 || offset%rowSize != 0 {
+					return out, fmt.Errorf("invalid state offset %d", entry.NewState)
+				}
+				entry.NewState = uint16(offset / rowSize)
+			}
+			if int(entry.NewState) >= numStates {
+				numStates = int(entry.NewState) + 1
+			}
+			out.Entries = append(out.Entries, entry)
+		}
+		if len(out.States) == numStates {
+			return out, nil
+		}
+	}
+}
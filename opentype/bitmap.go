@@ -0,0 +1,356 @@
+package opentype
+
+import "sync"
+
+// ImageFormat identifies the encoding of the data of a GlyphImage.
+type ImageFormat uint8
+
+const (
+	ImageUnknown ImageFormat = iota // an unsupported encoding
+	ImagePNG                        // a PNG file
+	ImageJPEG                       // a JPEG file
+	ImageTIFF                       // a TIFF file
+	ImageRaw                        // an uncompressed Bitmap
+)
+
+// BitmapStrike describes a set of glyph images designed for a given size.
+type BitmapStrike struct {
+	// Table is the table defining the strike, one of 'sbix', 'CBLC', 'EBLC' or 'bloc'.
+	Table Tag
+	PPEM  uint16 // the size, in pixels per em
+	// PPI is the pixel density of the design, in pixels per inch,
+	// which is only provided by the 'sbix' table, and zero otherwise.
+	PPI uint16
+	// BitDepth is the number of bits per pixel, which is 1, 2, 4 or 8 for
+	// the 'EBDT' table, 32 for the 'CBDT' table, and zero for the 'sbix' table.
+	BitDepth uint8
+}
+
+// GlyphImage is the image of a glyph, for a given strike.
+type GlyphImage struct {
+	Format ImageFormat
+	// Data is the content of the image file, which must not be modified.
+	Data []byte
+	// OriginX and OriginY are the position of the bottom left corner of the image,
+	// relative to the glyph origin, in pixels of the strike, with the y axis growing up.
+	OriginX, OriginY int16
+}
+
+// BitmapMetrics are the metrics of a glyph image of the 'CBDT' or 'EBDT' table, in pixels.
+// The bearings are the position of the top left corner of the image, relative
+// to the horizontal or vertical origin, with the y axis growing up.
+type BitmapMetrics struct {
+	Width, Height              int16
+	HoriBearingX, HoriBearingY int16
+	HoriAdvance                int16
+	VertBearingX, VertBearingY int16
+	VertAdvance                int16
+}
+
+// Bitmap is an uncompressed glyph image of the 'EBDT' table, stored row by row,
+// from top to bottom. Each pixel uses BitDepth bits, the leftmost pixel of a row
+// being in the most significant bits of its first byte, and each row is padded
+// to a whole number of bytes. The pixels are gray levels, where zero is
+// transparent and 1<<BitDepth - 1 is opaque.
+type Bitmap struct {
+	Width, Height int
+	BitDepth      int
+	Stride        int    // number of bytes per row
+	Data          []byte // must not be modified
+	Metrics       BitmapMetrics
+}
+
+// At returns the value of the pixel at column `x` and row `y`,
+// or zero if it is out of the image.
+func (b Bitmap) At(x, y int) uint8 {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return 0
+	}
+	return b.pixel(x, y)
+}
+
+func (b Bitmap) pixel(x, y int) uint8 {
+	bit := x * b.BitDepth
+	shift := 8 - b.BitDepth - bit%8
+	return (b.Data[y*b.Stride+bit/8] >> shift) & (1<<b.BitDepth - 1)
+}
+
+// setPixel ignores the pixels out of the image.
+func (b Bitmap) setPixel(x, y int, v uint8) {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return
+	}
+	bit := x * b.BitDepth
+	shift := 8 - b.BitDepth - bit%8
+	mask := uint8(1<<b.BitDepth-1) << shift
+	index := y*b.Stride + bit/8
+	b.Data[index] = b.Data[index]&^mask | v<<shift&mask
+}
+
+// bitmaps stores the bitmap glyphs tables, loaded on first use.
+type bitmaps struct {
+	once sync.Once
+
+	sbix *tableSbix // optional
+
+	cblc tableBitmapLocation // optional
+	cbdt []byte              // required by cblc
+
+	eblc    tableBitmapLocation // optional, from 'EBLC' or 'bloc'
+	ebdt    []byte              // required by eblc
+	eblcTag Tag                 // tagEBLC or tagBloc
+}
+
+// loadBitmaps parses the bitmap tables on first use, reporting the
+// invalid tables in the warnings.
+func (face *Face) loadBitmaps() *bitmaps {
+	b := &face.bitmaps
+	b.once.Do(func() {
+		if data, err := face.GetRawTable(tagSbix); err == nil {
+			if sbix, err := parseTableSbix(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagSbix, err))
+			} else {
+				b.sbix = &sbix
+			}
+		}
+		if data, err := face.GetRawTable(tagCBDT); err == nil {
+			if cblc, err := face.GetRawTable(tagCBLC); err == nil {
+				if b.cblc, err = parseTableBitmapLocation(cblc); err != nil {
+					face.warnings.addError(face.tableError(tagCBLC, err))
+				} else {
+					b.cbdt = data
+				}
+			}
+		}
+		for _, tags := range [...][2]Tag{{tagEBLC, tagEBDT}, {tagBloc, tagBdat}} {
+			data, err := face.GetRawTable(tags[1])
+			if err != nil {
+				continue
+			}
+			if eblc, err := face.GetRawTable(tags[0]); err == nil {
+				if b.eblc, err = parseTableBitmapLocation(eblc); err != nil {
+					face.warnings.addError(face.tableError(tags[0], err))
+				} else {
+					b.ebdt, b.eblcTag = data, tags[0]
+				}
+				break
+			}
+		}
+	})
+	return b
+}
+
+// SbixStrikes returns the strikes of the 'sbix' table, in font order,
+// or nil if the table is missing.
+func (face *Face) SbixStrikes() []BitmapStrike {
+	sbix := face.loadBitmaps().sbix
+	if sbix == nil {
+		return nil
+	}
+	out := make([]BitmapStrike, len(sbix.strikes))
+	for i, s := range sbix.strikes {
+		out[i] = BitmapStrike{Table: tagSbix, PPEM: s.ppem, PPI: s.ppi}
+	}
+	return out
+}
+
+// SbixDrawsOutlines returns true if the 'sbix' table requests
+// the outlines of the glyphs to be drawn on top of their images.
+func (face *Face) SbixDrawsOutlines() bool {
+	sbix := face.loadBitmaps().sbix
+	return sbix != nil && sbix.drawOutlines
+}
+
+// SbixGlyphImage returns the image of the glyph in the first strike of the 'sbix'
+// table whose size is `ppem`, with the 'dupe' records resolved.
+// It returns false if there is no such strike, or if the glyph has no image
+// in the strike. The 'mask' records are not supported and reported as ImageUnknown.
+func (face *Face) SbixGlyphImage(gid GID, ppem uint16) (GlyphImage, bool) {
+	sbix := face.loadBitmaps().sbix
+	if sbix == nil {
+		return GlyphImage{}, false
+	}
+	for i := range sbix.strikes {
+		s := &sbix.strikes[i]
+		if s.ppem != ppem {
+			continue
+		}
+		x, y, graphicType, data, ok := s.glyphData(gid)
+		if !ok {
+			return GlyphImage{}, false
+		}
+		return GlyphImage{Format: sbixImageFormat(graphicType), Data: data, OriginX: x, OriginY: y}, true
+	}
+	return GlyphImage{}, false
+}
+
+func sbixImageFormat(graphicType Tag) ImageFormat {
+	switch graphicType {
+	case tagPNG:
+		return ImagePNG
+	case tagJPEG:
+		return ImageJPEG
+	case tagTIFF:
+		return ImageTIFF
+	default:
+		return ImageUnknown
+	}
+}
+
+// CBDTStrikes returns the strikes of the 'CBLC' table, in font order,
+// or nil if the 'CBLC' or 'CBDT' table is missing.
+func (face *Face) CBDTStrikes() []BitmapStrike {
+	cblc := face.loadBitmaps().cblc
+	if cblc == nil {
+		return nil
+	}
+	out := make([]BitmapStrike, len(cblc))
+	for i, s := range cblc {
+		out[i] = BitmapStrike{Table: tagCBLC, PPEM: s.ppemY, BitDepth: s.bitDepth}
+	}
+	return out
+}
+
+// CBDTGlyphImage returns the PNG image of the glyph, and its metrics, in the first
+// strike of the 'CBLC' table whose size is `ppem`.
+// It returns false if there is no such strike, or if the glyph has no
+// (valid) image in the strike.
+func (face *Face) CBDTGlyphImage(gid GID, ppem uint16) (GlyphImage, BitmapMetrics, bool) {
+	b := face.loadBitmaps()
+	for i := range b.cblc {
+		s := &b.cblc[i]
+		if s.ppemY != ppem {
+			continue
+		}
+		st, offset, length, ok := s.glyphImage(gid)
+		if !ok || uint64(offset)+uint64(length) > uint64(len(b.cbdt)) {
+			return GlyphImage{}, BitmapMetrics{}, false
+		}
+		data, m, err := cbdtGlyph(b.cbdt[offset:offset+length], st, s.flags)
+		if err != nil {
+			return GlyphImage{}, BitmapMetrics{}, false
+		}
+		metrics := m.export()
+		return GlyphImage{
+			Format:  ImagePNG,
+			Data:    data,
+			OriginX: metrics.HoriBearingX,
+			OriginY: metrics.HoriBearingY - metrics.Height,
+		}, metrics, true
+	}
+	return GlyphImage{}, BitmapMetrics{}, false
+}
+
+func (m bitmapMetrics) export() BitmapMetrics {
+	return BitmapMetrics{
+		Width:        int16(m.width),
+		Height:       int16(m.height),
+		HoriBearingX: int16(m.horiBearingX),
+		HoriBearingY: int16(m.horiBearingY),
+		HoriAdvance:  int16(m.horiAdvance),
+		VertBearingX: int16(m.vertBearingX),
+		VertBearingY: int16(m.vertBearingY),
+		VertAdvance:  int16(m.vertAdvance),
+	}
+}
+
+// EBDTStrikes returns the strikes of the 'EBLC' table, or of the Apple 'bloc' table,
+// in font order, or nil if the location or image data table is missing.
+func (face *Face) EBDTStrikes() []BitmapStrike {
+	b := face.loadBitmaps()
+	if b.eblc == nil {
+		return nil
+	}
+	out := make([]BitmapStrike, len(b.eblc))
+	for i, s := range b.eblc {
+		out[i] = BitmapStrike{Table: b.eblcTag, PPEM: s.ppemY, BitDepth: s.bitDepth}
+	}
+	return out
+}
+
+// EBDTGlyphBitmap returns the bitmap of the glyph in the first strike of the
+// 'EBLC' (or 'bloc') table whose size is `ppem`, with the composite bitmaps resolved.
+// It returns false if there is no such strike, or if the glyph has no
+// (valid) bitmap in the strike.
+func (face *Face) EBDTGlyphBitmap(gid GID, ppem uint16) (Bitmap, bool) {
+	b := face.loadBitmaps()
+	for i := range b.eblc {
+		s := &b.eblc[i]
+		if s.ppemY != ppem {
+			continue
+		}
+		out, err := s.ebdtBitmap(b.ebdt, gid, 0)
+		return out, err == nil
+	}
+	return Bitmap{}, false
+}
+
+// GlyphBitmap is the image of a glyph, found in the 'sbix', 'CBDT' or 'EBDT' table.
+type GlyphBitmap struct {
+	// GlyphImage stores the image: the formats ImagePNG, ImageJPEG, ImageTIFF
+	// and ImageUnknown are used by the 'sbix' and 'CBDT' tables, and ImageRaw
+	// by the 'EBDT' table, in which case Data is Bitmap.Data.
+	GlyphImage
+	// Bitmap is only valid for the ImageRaw format.
+	Bitmap Bitmap
+	// Strike is the strike providing the image, whose size may differ from
+	// the requested one : the image should then be scaled by ppem / Strike.PPEM.
+	Strike BitmapStrike
+}
+
+// BitmapStrikes returns the strikes of all the bitmap tables of the font,
+// in the order 'sbix', 'CBLC', then 'EBLC' (or 'bloc').
+func (face *Face) BitmapStrikes() []BitmapStrike {
+	out := face.SbixStrikes()
+	out = append(out, face.CBDTStrikes()...)
+	return append(out, face.EBDTStrikes()...)
+}
+
+// BestBitmapStrike returns the strike to use to draw glyphs at size `ppem` : the
+// smallest strike not smaller than `ppem`, or the largest one if all of them are smaller.
+// When several strikes have the same size, the first one returned by BitmapStrikes is used,
+// so that color images are preferred over monochrome bitmaps.
+// It returns false if the font has no bitmap strike.
+func (face *Face) BestBitmapStrike(ppem uint16) (BitmapStrike, bool) {
+	strikes := face.BitmapStrikes()
+	if len(strikes) == 0 {
+		return BitmapStrike{}, false
+	}
+	best := strikes[0]
+	for _, s := range strikes[1:] {
+		if (ppem > best.PPEM && s.PPEM > best.PPEM) || (ppem <= s.PPEM && s.PPEM < best.PPEM) {
+			best = s
+		}
+	}
+	return best, true
+}
+
+// GlyphBitmap returns the image of the glyph in the strike selected by
+// BestBitmapStrike(ppem), whatever the table defining it.
+// It returns false if the font has no bitmap strike, or if the glyph
+// has no (valid) image in the selected strike.
+func (face *Face) GlyphBitmap(gid GID, ppem uint16) (GlyphBitmap, bool) {
+	strike, ok := face.BestBitmapStrike(ppem)
+	if !ok {
+		return GlyphBitmap{}, false
+	}
+	out := GlyphBitmap{Strike: strike}
+	switch strike.Table {
+	case tagSbix:
+		out.GlyphImage, ok = face.SbixGlyphImage(gid, strike.PPEM)
+	case tagCBLC:
+		out.GlyphImage, _, ok = face.CBDTGlyphImage(gid, strike.PPEM)
+	default:
+		out.Bitmap, ok = face.EBDTGlyphBitmap(gid, strike.PPEM)
+		out.GlyphImage = GlyphImage{
+			Format:  ImageRaw,
+			Data:    out.Bitmap.Data,
+			OriginX: out.Bitmap.Metrics.HoriBearingX,
+			OriginY: out.Bitmap.Metrics.HoriBearingY - out.Bitmap.Metrics.Height,
+		}
+	}
+	if !ok {
+		return GlyphBitmap{}, false
+	}
+	return out, true
+}
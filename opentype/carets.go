@@ -0,0 +1,163 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// tableLcar stores the ligature caret positions of the AAT 'lcar' table.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6lcar.html
+type tableLcar struct {
+	isPointIndex bool     // format 1, the values are contour points
+	lookup       ClassDef // offsets of the caret values, from the start of the table
+	data         []byte   // the table content
+}
+
+func parseTableLcar(data []byte, numGlyphs int) (tableLcar, error) {
+	if len(data) < 6 {
+		return tableLcar{}, errEOF
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 {
+		return tableLcar{}, fmt.Errorf("unsupported version %x", version)
+	}
+	var (
+		out tableLcar
+		err error
+	)
+	switch format := binary.BigEndian.Uint16(data[4:]); format {
+	case 0:
+	case 1:
+		out.isPointIndex = true
+	default:
+		return out, fmt.Errorf("unsupported format %d", format)
+	}
+	out.lookup, err = parseAATLookup(data, 6, numGlyphs)
+	out.data = data
+	return out, err
+}
+
+// carets returns the caret values of the glyph.
+func (t *tableLcar) carets(gid GID) ([]int16, bool) {
+	offset, ok := t.lookup.Class(gid)
+	if !ok || len(t.data) < int(offset)+2 {
+		return nil, false
+	}
+	values := t.data[offset:]
+	count := int(binary.BigEndian.Uint16(values))
+	if len(values) < 2+2*count {
+		return nil, false
+	}
+	out := make([]int16, count)
+	for i := range out {
+		out[i] = int16(binary.BigEndian.Uint16(values[2+2*i:]))
+	}
+	return out, true
+}
+
+// lcar is the lazily parsed 'lcar' table.
+type lcar struct {
+	once  sync.Once
+	table *tableLcar // nil if missing or invalid
+}
+
+func (face *Face) loadLcar() *tableLcar {
+	face.lcar.once.Do(func() {
+		if data, err := face.GetRawTable(tagLcar); err == nil {
+			if table, err := parseTableLcar(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagLcar, err))
+			} else {
+				face.lcar.table = &table
+			}
+		}
+	})
+	return face.lcar.table
+}
+
+// LigatureCarets returns the positions of the carets inside the ligature `gid`,
+// in font units, along the x axis (or the y axis if `vertical` is true).
+// They are given by the 'GDEF' table, or by the AAT 'lcar' table, and adjusted
+// by the variations of variable fonts (see SetVariations).
+// When `ppem` is not zero, the hinting adjustments for this size (expressed in pixels)
+// are also applied.
+// Carets defined by a contour point are only supported for TrueType outlines,
+// and are set to 0 otherwise.
+// It returns nil if the glyph is not a ligature, or the font has no caret information.
+func (face *Face) LigatureCarets(gid GID, vertical bool, ppem uint16) []float32 {
+	if gdef, ok := face.GDEF(); ok && gdef.ligCarets.coverage != nil {
+		index, ok := gdef.ligCarets.coverage.Index(gid)
+		if !ok || index >= len(gdef.ligCarets.carets) {
+			return nil
+		}
+		carets := gdef.ligCarets.carets[index]
+		out := make([]float32, len(carets))
+		for i, caret := range carets {
+			switch caret.format {
+			case 2:
+				out[i] = face.pointCoordinate(gid, caret.pointIndex, vertical)
+			case 3:
+				out[i] = float32(caret.coordinate) + face.deviceDelta(&gdef.store, caret.device, ppem)
+			default:
+				out[i] = float32(caret.coordinate)
+			}
+		}
+		return out
+	}
+
+	if lcar := face.loadLcar(); lcar != nil {
+		values, ok := lcar.carets(gid)
+		if !ok {
+			return nil
+		}
+		out := make([]float32, len(values))
+		for i, v := range values {
+			if lcar.isPointIndex {
+				out[i] = face.pointCoordinate(gid, uint16(v), vertical)
+			} else {
+				out[i] = float32(v)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// deviceDelta returns the adjustment given by the device table, in font units :
+// the variation delta for variable fonts, or the hinting adjustment
+// for the size `ppem`, if not zero. The variation deltas are stored in
+// `store`, which may be nil.
+func (face *Face) deviceDelta(store *itemVariationStore, device deviceTable, ppem uint16) float32 {
+	if device.isVariation {
+		if !face.isVariable() || store == nil {
+			return 0
+		}
+		return store.delta(device.outer, device.inner, face.coords)
+	}
+	if ppem == 0 {
+		return 0
+	}
+	return float32(device.pixelDelta(ppem)) * float32(face.Upem()) / float32(ppem)
+}
+
+// pointCoordinate returns the x (or y) coordinate of the contour point
+// `index` of the TrueType glyph, or 0 if it is not found.
+func (face *Face) pointCoordinate(gid GID, index uint16, vertical bool) float32 {
+	p, _ := face.contourPoint(gid, index)
+	if vertical {
+		return p.y
+	}
+	return p.x
+}
+
+// contourPoint returns the contour point `index` of the TrueType glyph,
+// shifted as the outline (see GlyphOutline), or false if it is not found.
+func (face *Face) contourPoint(gid GID, index uint16) (contourPoint, bool) {
+	if int(gid) >= face.NumGlyphs || face.loadOutlines() != nil || face.outlines.cff != nil {
+		return contourPoint{}, false
+	}
+	points, err := face.glyphPoints(gid)
+	if err != nil || int(index) >= len(points.points) {
+		return contourPoint{}, false
+	}
+	return points.points[index], true
+}
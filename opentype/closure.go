@@ -0,0 +1,315 @@
+package opentype
+
+import "sort"
+
+// FeatureLookups returns the sorted indices of the lookups of the features
+// whose tag is in `features`, or of all the features if `features` is nil.
+// The features substituted by FeatureVariations, whatever the variation
+// coordinates, and the required features of the language systems are included.
+func (l *Layout) FeatureLookups(features []Tag) []uint16 {
+	selected := func(tag Tag) bool {
+		if features == nil {
+			return true
+		}
+		for _, t := range features {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}
+	required := map[uint16]bool{}
+	for _, script := range l.Scripts {
+		if script.DefaultLanguage != nil && script.DefaultLanguage.RequiredFeature != NoRequiredFeature {
+			required[script.DefaultLanguage.RequiredFeature] = true
+		}
+		for _, lang := range script.Languages {
+			if lang.RequiredFeature != NoRequiredFeature {
+				required[lang.RequiredFeature] = true
+			}
+		}
+	}
+
+	lookups := map[uint16]bool{}
+	for i, feature := range l.Features {
+		if selected(feature.Tag) || required[uint16(i)] {
+			for _, index := range feature.LookupIndices {
+				lookups[index] = true
+			}
+		}
+	}
+	for _, variation := range l.FeatureVariations {
+		for _, subst := range variation.Substitutions {
+			if selected(subst.Feature.Tag) || required[subst.FeatureIndex] {
+				for _, index := range subst.Feature.LookupIndices {
+					lookups[index] = true
+				}
+			}
+		}
+	}
+
+	out := make([]uint16, 0, len(lookups))
+	for index := range lookups {
+		out = append(out, index)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Closure returns the sorted glyphs reachable from `glyphs` (which are included)
+// by the substitutions of the features `features`, or of all the features if
+// `features` is nil (see Layout.FeatureLookups).
+//
+// The lookups referenced by the contextual subtables are followed, and applied
+// to all the reachable glyphs as soon as the context may be matched by them,
+// so that the closure may contain more glyphs than actually produced by the
+// shaping of some text. This is the expected behavior when subsetting a font,
+// where missing glyphs would break the layout.
+func (t *TableGSUB) Closure(glyphs []GID, features []Tag) []GID {
+	c := gsubClosure{
+		lookups: t.Lookups,
+		active:  make([]bool, len(t.Lookups)),
+		glyphs:  make(map[GID]bool, len(glyphs)),
+	}
+	for _, gid := range glyphs {
+		c.glyphs[gid] = true
+	}
+	for _, index := range t.FeatureLookups(features) {
+		c.activate(index)
+	}
+	for c.changed = true; c.changed; {
+		c.changed = false
+		c.sorted = c.sorted[:0]
+		for gid := range c.glyphs {
+			c.sorted = append(c.sorted, gid)
+		}
+		sort.Slice(c.sorted, func(i, j int) bool { return c.sorted[i] < c.sorted[j] })
+		for i, lookup := range c.lookups {
+			if !c.active[i] {
+				continue
+			}
+			for _, subtable := range lookup.Subtables {
+				c.closeSubtable(subtable)
+			}
+		}
+	}
+	return c.sorted
+}
+
+// gsubClosure stores the state of TableGSUB.Closure, which
+// processes the active lookups until no glyph or lookup is added.
+type gsubClosure struct {
+	lookups []GSUBLookup
+	active  []bool // lookups reachable from the features
+	glyphs  map[GID]bool
+	sorted  []GID // the glyphs at the start of the current pass
+	changed bool
+}
+
+func (c *gsubClosure) add(gid GID) {
+	if !c.glyphs[gid] {
+		c.glyphs[gid] = true
+		c.changed = true
+	}
+}
+
+func (c *gsubClosure) activate(index uint16) {
+	if int(index) < len(c.active) && !c.active[index] {
+		c.active[index] = true
+		c.changed = true
+	}
+}
+
+func (c *gsubClosure) activateAll(lookups []SequenceLookup) {
+	for _, lookup := range lookups {
+		c.activate(lookup.LookupIndex)
+	}
+}
+
+// covered calls `fn` for the glyphs of the closure covered by `coverage`.
+func (c *gsubClosure) covered(coverage Coverage, fn func(gid GID, index int)) {
+	for _, gid := range c.sorted {
+		if index, ok := coverage.Index(gid); ok {
+			fn(gid, index)
+		}
+	}
+}
+
+func (c *gsubClosure) intersects(coverage Coverage) bool {
+	for _, gid := range c.sorted {
+		if _, ok := coverage.Index(gid); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *gsubClosure) intersectsAll(coverages []Coverage) bool {
+	for _, coverage := range coverages {
+		if !c.intersects(coverage) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAll returns true if all the glyphs are in the closure.
+func (c *gsubClosure) containsAll(glyphs []uint16) bool {
+	for _, gid := range glyphs {
+		if !c.glyphs[GID(gid)] {
+			return false
+		}
+	}
+	return true
+}
+
+// classes returns the classes of the glyphs of the closure.
+func (c *gsubClosure) classes(classDef ClassDef) map[uint16]bool {
+	out := map[uint16]bool{}
+	for _, gid := range c.sorted {
+		class, _ := classDef.Class(gid) // 0 if not assigned
+		out[class] = true
+	}
+	return out
+}
+
+func containsAllClasses(classes map[uint16]bool, sequence []uint16) bool {
+	for _, class := range sequence {
+		if !classes[class] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *gsubClosure) closeSubtable(subtable GSUBSubtable) {
+	switch subtable := subtable.(type) {
+	case SingleSubst:
+		c.covered(subtable.Coverage, func(gid GID, _ int) {
+			if substitute, ok := subtable.Substitute(gid); ok {
+				c.add(substitute)
+			}
+		})
+	case MultipleSubst:
+		c.closeSequences(subtable.Coverage, subtable.Sequences)
+	case AlternateSubst:
+		c.closeSequences(subtable.Coverage, subtable.Alternates)
+	case LigatureSubst:
+		c.covered(subtable.Coverage, func(_ GID, index int) {
+			if index >= len(subtable.LigatureSets) {
+				return
+			}
+		ligatures:
+			for _, ligature := range subtable.LigatureSets[index] {
+				for _, component := range ligature.Components {
+					if !c.glyphs[component] {
+						continue ligatures
+					}
+				}
+				c.add(ligature.Glyph)
+			}
+		})
+	case ContextualSubst:
+		c.closeSequenceContext(subtable.SequenceContext)
+	case ChainedContextualSubst:
+		c.closeChainedSequenceContext(subtable.ChainedSequenceContext)
+	case ReverseChainedSingleSubst:
+		if !c.intersectsAll(subtable.BacktrackCoverages) || !c.intersectsAll(subtable.LookaheadCoverages) {
+			return
+		}
+		c.covered(subtable.Coverage, func(_ GID, index int) {
+			if index < len(subtable.Substitutes) {
+				c.add(subtable.Substitutes[index])
+			}
+		})
+	}
+}
+
+func (c *gsubClosure) closeSequences(coverage Coverage, sequences [][]GID) {
+	c.covered(coverage, func(_ GID, index int) {
+		if index < len(sequences) {
+			for _, gid := range sequences[index] {
+				c.add(gid)
+			}
+		}
+	})
+}
+
+func (c *gsubClosure) closeSequenceContext(context SequenceContext) {
+	switch context.Format {
+	case 1:
+		c.covered(context.Coverage, func(_ GID, index int) {
+			if index >= len(context.RuleSets) {
+				return
+			}
+			for _, rule := range context.RuleSets[index] {
+				if c.containsAll(rule.Input) {
+					c.activateAll(rule.Lookups)
+				}
+			}
+		})
+	case 2:
+		classes := c.classes(context.ClassDef)
+		for class := range c.firstClasses(context.Coverage, context.ClassDef) {
+			if int(class) >= len(context.RuleSets) {
+				continue
+			}
+			for _, rule := range context.RuleSets[class] {
+				if containsAllClasses(classes, rule.Input) {
+					c.activateAll(rule.Lookups)
+				}
+			}
+		}
+	case 3:
+		if c.intersectsAll(context.Coverages) {
+			c.activateAll(context.Lookups)
+		}
+	}
+}
+
+func (c *gsubClosure) closeChainedSequenceContext(context ChainedSequenceContext) {
+	switch context.Format {
+	case 1:
+		c.covered(context.Coverage, func(_ GID, index int) {
+			if index >= len(context.RuleSets) {
+				return
+			}
+			for _, rule := range context.RuleSets[index] {
+				if c.containsAll(rule.Backtrack) && c.containsAll(rule.Input) && c.containsAll(rule.Lookahead) {
+					c.activateAll(rule.Lookups)
+				}
+			}
+		})
+	case 2:
+		backtrack := c.classes(context.BacktrackClassDef)
+		input := c.classes(context.InputClassDef)
+		lookahead := c.classes(context.LookaheadClassDef)
+		for class := range c.firstClasses(context.Coverage, context.InputClassDef) {
+			if int(class) >= len(context.RuleSets) {
+				continue
+			}
+			for _, rule := range context.RuleSets[class] {
+				if containsAllClasses(backtrack, rule.Backtrack) && containsAllClasses(input, rule.Input) &&
+					containsAllClasses(lookahead, rule.Lookahead) {
+					c.activateAll(rule.Lookups)
+				}
+			}
+		}
+	case 3:
+		if c.intersectsAll(context.BacktrackCoverages) && c.intersectsAll(context.InputCoverages) &&
+			c.intersectsAll(context.LookaheadCoverages) {
+			c.activateAll(context.Lookups)
+		}
+	}
+}
+
+// firstClasses returns the classes of the glyphs of the
+// closure covered by `coverage`.
+func (c *gsubClosure) firstClasses(coverage Coverage, classDef ClassDef) map[uint16]bool {
+	out := map[uint16]bool{}
+	c.covered(coverage, func(gid GID, _ int) {
+		class, _ := classDef.Class(gid)
+		out[class] = true
+	})
+	return out
+}
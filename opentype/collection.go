@@ -0,0 +1,138 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-text/font"
+)
+
+const maxNumFonts = 1024 // security implementation limit
+
+// Collection is a set of faces stored in one file,
+// such as TrueType and OpenType collections (.ttc, .otc),
+// or Mac resource forks (.dfont).
+// The faces are only parsed when requested, using Face.
+type Collection struct {
+	members []member
+
+	release func() error // memory mapping, see OpenCollection
+}
+
+// member locates one face of a collection
+type member struct {
+	src    source // table offsets are relative to the start of src
+	offset uint32 // offset of the table directory in src
+}
+
+// ParseCollection reads a font collection file : .ttc, .otc, WOFF2 files wrapping a collection,
+// and Mac resource forks (.dfont files or raw resource forks) containing 'sfnt' resources.
+// Regular font files are also accepted, and are treated as a collection with one face,
+// so that this function may be used as a general entry point.
+func ParseCollection(file font.Resource) (*Collection, error) {
+	data, err := readSfnt(file)
+	if err != nil {
+		return nil, err
+	}
+	return newCollection(memorySource(data))
+}
+
+// LoadCollection is the same as ParseCollection, but only reads the headers
+// up front, fetching the tables from `file` when needed (see LoadFont).
+// `size` is the size of the file, in bytes.
+// Resource forks and web fonts are entirely loaded in memory.
+func LoadCollection(file io.ReaderAt, size int64) (*Collection, error) {
+	src, err := fileSource(file, size)
+	if err != nil {
+		return nil, err
+	}
+	return newCollection(src)
+}
+
+func newCollection(src source) (*Collection, error) {
+	signature, err := src.read(0, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	var out Collection
+	switch magic := Tag(binary.BigEndian.Uint32(signature)); magic {
+	case TypeTrueType, TypeOpenType, TypePostScript1, TypeAppleTrueType:
+		out.members = []member{{src: src}}
+	case TypeCollection:
+		offsets, err := parseTTCHeader(src)
+		if err != nil {
+			return nil, err
+		}
+		out.members = make([]member, len(offsets))
+		for i, o := range offsets {
+			out.members[i] = member{src: src, offset: o}
+		}
+	default:
+		data := src.data
+		if src.file != nil {
+			if data, err = readAll(src.file, int64(src.size)); err != nil {
+				return nil, err
+			}
+		}
+		if magic != dfontResourceDataOffset && !isResourceFork(data) {
+			return nil, fmt.Errorf("%s (%s)", errUnsupportedFormat, magic)
+		}
+		resources, err := parseResourceFork(data)
+		if err != nil {
+			return nil, err
+		}
+		out.members = make([]member, len(resources))
+		for i, res := range resources {
+			out.members[i] = member{src: memorySource(res)}
+		}
+	}
+
+	return &out, nil
+}
+
+// NumFaces returns the number of faces in the collection.
+func (c *Collection) NumFaces() int { return len(c.members) }
+
+// Face parses the face at `index`, which must be in [0, NumFaces()[.
+func (c *Collection) Face(index int) (*Face, error) {
+	return c.FaceWithOptions(index, ParseOptions{})
+}
+
+func (c *Collection) checkIndex(index int) error {
+	if index < 0 || index >= len(c.members) {
+		return fmt.Errorf("invalid face index %d for collection of %d faces", index, len(c.members))
+	}
+	return nil
+}
+
+// parseTTCHeader returns the offsets of each font.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/otff#ttc-header
+func parseTTCHeader(src source) ([]uint32, error) {
+	const headerSize = 12
+	data, err := src.read(0, headerSize)
+	if err != nil {
+		return nil, errors.New("invalid font collection header (EOF)")
+	}
+	// skip versions
+	numFonts := binary.BigEndian.Uint32(data[8:])
+	if numFonts == 0 {
+		return nil, errors.New("empty font collection")
+	}
+	if numFonts > maxNumFonts {
+		return nil, fmt.Errorf("number of fonts (%d) in collection exceed implementation limit (%d)",
+			numFonts, maxNumFonts)
+	}
+	data, err = src.read(0, headerSize+4*numFonts)
+	if err != nil {
+		return nil, errors.New("invalid font collection header (EOF)")
+	}
+
+	offsets := make([]uint32, numFonts)
+	for i := range offsets {
+		offsets[i] = binary.BigEndian.Uint32(data[headerSize+4*i:])
+	}
+	return offsets, nil
+}
@@ -0,0 +1,304 @@
+package opentype
+
+import (
+	"image/color"
+	"sync"
+)
+
+// ForegroundPaletteIndex is the palette index of the layers
+// which are drawn with the text foreground color.
+const ForegroundPaletteIndex = 0xFFFF
+
+// ColorLayer is a layer of a color glyph, drawn
+// with a color of the current palette.
+type ColorLayer struct {
+	Glyph GID // the glyph providing the outline of the layer
+	// PaletteIndex is the index of the color in the palette,
+	// or ForegroundPaletteIndex.
+	PaletteIndex uint16
+}
+
+// colorTables stores the color glyphs tables, loaded on first use.
+type colorTables struct {
+	once sync.Once
+
+	colr *tableCOLR // optional
+	cpal *tableCPAL // optional
+	svg  *tableSVG  // optional
+}
+
+// loadColor parses the color tables on first use, reporting the
+// invalid tables in the warnings.
+func (face *Face) loadColor() *colorTables {
+	c := &face.color
+	c.once.Do(func() {
+		if data, err := face.GetRawTable(tagCOLR); err == nil {
+			if colr, err := parseTableCOLR(data); err != nil {
+				face.warnings.addError(face.tableError(tagCOLR, err))
+			} else {
+				c.colr = &colr
+			}
+		}
+		if data, err := face.GetRawTable(tagCPAL); err == nil {
+			if cpal, err := parseTableCPAL(data); err != nil {
+				face.warnings.addError(face.tableError(tagCPAL, err))
+			} else {
+				c.cpal = &cpal
+			}
+		}
+		if data, err := face.GetRawTable(tagSVG); err == nil {
+			if svg, err := parseTableSVG(data); err != nil {
+				face.warnings.addError(face.tableError(tagSVG, err))
+			} else {
+				c.svg = &svg
+			}
+		}
+	})
+	return c
+}
+
+// ColorGlyphLayers returns the layers of the color glyph `gid`, defined by
+// the 'COLR' table (version 0), in drawing order, from bottom to top.
+// It returns nil if the glyph is not a layered color glyph : in particular,
+// the glyphs defined by the version 1 of the table are only
+// supported by ColorGlyphPaint.
+// The returned slice must not be modified.
+func (face *Face) ColorGlyphLayers(gid GID) []ColorLayer {
+	colr := face.loadColor().colr
+	if colr == nil {
+		return nil
+	}
+	return colr.glyphLayers(gid)
+}
+
+// ColorGlyphPaint returns the root of the paint graph of the color glyph `gid`,
+// defined by the 'COLR' table, with the variations of variable fonts applied
+// (see SetVariations). The layers of the glyphs defined by the version 0 of the table
+// are converted to an equivalent graph, as a PaintLayers
+// of PaintGlyph filled with a PaintSolid.
+// It returns nil if the glyph is not a color glyph, and an error if
+// its paint graph is invalid : in particular, graphs with cycles are rejected.
+func (face *Face) ColorGlyphPaint(gid GID) (Paint, error) {
+	colr := face.loadColor().colr
+	if colr == nil {
+		return nil, nil
+	}
+	if offset, ok := colr.basePaint(gid); ok {
+		b := paintBuilder{colr: colr}
+		if face.isVariable() {
+			b.coords = face.coords
+		}
+		out, err := b.paint(offset)
+		if err != nil {
+			return nil, face.tableError(tagCOLR, err)
+		}
+		return out, nil
+	}
+	layers := colr.glyphLayers(gid)
+	if layers == nil {
+		return nil, nil
+	}
+	out := PaintLayers{Layers: make([]Paint, len(layers))}
+	for i, layer := range layers {
+		out.Layers[i] = PaintGlyph{Glyph: layer.Glyph, Paint: PaintSolid{PaletteIndex: layer.PaletteIndex, Alpha: 1}}
+	}
+	return out, nil
+}
+
+// ColorGlyphClipBox returns the box outside of which the drawing of the
+// color glyph must be clipped, given by the 'COLR' table (version 1), or false
+// if the glyph has no clip box.
+func (face *Face) ColorGlyphClipBox(gid GID) (ClipBox, bool) {
+	colr := face.loadColor().colr
+	if colr == nil {
+		return ClipBox{}, false
+	}
+	var coords []float32
+	if face.isVariable() {
+		coords = face.coords
+	}
+	return colr.clipBox(gid, coords)
+}
+
+// PaletteColors returns the colors of the palette at index `palette`,
+// given by the 'CPAL' table, or nil if the palette does not exist.
+// The first palette is the default one.
+// The returned slice must not be modified.
+func (face *Face) PaletteColors(palette int) []color.NRGBA {
+	cpal := face.loadColor().cpal
+	if cpal == nil || palette < 0 || palette >= len(cpal.palettes) {
+		return nil
+	}
+	return cpal.palettes[palette]
+}
+
+// PaletteFlags describes the intended usage of a palette.
+type PaletteFlags uint32
+
+const (
+	// PaletteUsableWithLightBackground indicates that the palette
+	// is appropriate for a light background.
+	PaletteUsableWithLightBackground PaletteFlags = 1 << iota
+	// PaletteUsableWithDarkBackground indicates that the palette
+	// is appropriate for a dark background.
+	PaletteUsableWithDarkBackground
+)
+
+// noLabel is the name ID of the palettes and entries without label.
+const noLabel = 0xFFFF
+
+// Palette is a color palette of the 'CPAL' table.
+type Palette struct {
+	// Colors are the entries of the palette, non premultiplied.
+	// All the palettes of a font have the same number of entries.
+	Colors []color.NRGBA
+	// Flags is only provided by the version 1 of the table,
+	// and is zero otherwise.
+	Flags PaletteFlags
+	// Label is the ID of the name of the palette, in the 'name' table,
+	// or 0xFFFF if the palette has no label.
+	Label uint16
+}
+
+// NumPalettes returns the number of palettes of the 'CPAL' table,
+// which is zero if the table is missing.
+func (face *Face) NumPalettes() int {
+	cpal := face.loadColor().cpal
+	if cpal == nil {
+		return 0
+	}
+	return len(cpal.palettes)
+}
+
+// Palettes returns the palettes of the 'CPAL' table, the first one being the default.
+// The user interfaces should only offer the palettes matching the background
+// (see PaletteFlags), if specified.
+// The returned colors must not be modified.
+func (face *Face) Palettes() []Palette {
+	cpal := face.loadColor().cpal
+	if cpal == nil {
+		return nil
+	}
+	out := make([]Palette, len(cpal.palettes))
+	for i, colors := range cpal.palettes {
+		out[i] = Palette{Colors: colors, Label: noLabel}
+		if cpal.types != nil {
+			out[i].Flags = cpal.types[i]
+		}
+		if cpal.labels != nil {
+			out[i].Label = cpal.labels[i]
+		}
+	}
+	return out
+}
+
+// PaletteEntryLabel returns the ID of the name describing the palette entry at index `entry`
+// (such as "Outline" or "Fill"), in the 'name' table, or false if the entry has no label.
+func (face *Face) PaletteEntryLabel(entry int) (uint16, bool) {
+	cpal := face.loadColor().cpal
+	if cpal == nil || entry < 0 || entry >= len(cpal.entryLabels) || cpal.entryLabels[entry] == noLabel {
+		return 0, false
+	}
+	return cpal.entryLabels[entry], true
+}
+
+// SVGDocument is a document of the 'SVG ' table, which may define several glyphs.
+// The glyph `gid` is the element whose id is "glyph<gid>", such as "glyph42",
+// drawn in a coordinate system where the origin is the glyph origin, one unit is
+// one font unit, and the y axis grows down.
+type SVGDocument struct {
+	// Data is the decompressed content of the document.
+	Data []byte
+	// FirstGlyph and LastGlyph are the range of the glyphs sharing the document.
+	FirstGlyph, LastGlyph GID
+}
+
+// GlyphSVG returns the SVG document defining the glyph `gid`, after
+// decompressing it if needed. It returns false if the glyph is not defined
+// by the 'SVG ' table, or if its document could not be decompressed.
+// The returned data must not be modified.
+func (face *Face) GlyphSVG(gid GID) (SVGDocument, bool) {
+	svg := face.loadColor().svg
+	if svg == nil {
+		return SVGDocument{}, false
+	}
+	record, ok := svg.document(gid)
+	if !ok {
+		return SVGDocument{}, false
+	}
+	data, err := decompressSVG(svg.data[record.offset : record.offset+record.length])
+	if err != nil {
+		return SVGDocument{}, false
+	}
+	return SVGDocument{Data: data, FirstGlyph: record.firstGlyph, LastGlyph: record.lastGlyph}, true
+}
+
+// ColorFormats is a set of color glyph technologies.
+type ColorFormats uint8
+
+const (
+	ColorCOLRv0 ColorFormats = 1 << iota // layered glyphs of the 'COLR' table
+	ColorCOLRv1                          // paint graphs of the 'COLR' table
+	ColorSVG                             // documents of the 'SVG ' table
+	ColorSbix                            // images of the 'sbix' table
+	ColorCBDT                            // images of the 'CBDT' table
+)
+
+// ColorFormats returns the color technologies used by the font, which are
+// only reported if the tables define at least one glyph.
+// The monochrome or grayscale bitmaps of the 'EBDT' table are not included.
+func (face *Face) ColorFormats() ColorFormats {
+	var out ColorFormats
+	c := face.loadColor()
+	if c.colr != nil && len(c.colr.baseGlyphs) != 0 {
+		out |= ColorCOLRv0
+	}
+	if c.colr != nil && len(c.colr.basePaints) != 0 {
+		out |= ColorCOLRv1
+	}
+	if c.svg != nil && len(c.svg.documents) != 0 {
+		out |= ColorSVG
+	}
+	b := face.loadBitmaps()
+	if b.sbix != nil && len(b.sbix.strikes) != 0 {
+		out |= ColorSbix
+	}
+	if len(b.cblc) != 0 {
+		out |= ColorCBDT
+	}
+	return out
+}
+
+// HasColorGlyphs returns true if the font uses one of the color technologies
+// (see ColorFormats).
+func (face *Face) HasColorGlyphs() bool { return face.ColorFormats() != 0 }
+
+// IsColorGlyph returns true if the glyph is defined by one of the color tables
+// (see ColorFormats), for at least one size in the case of bitmaps.
+func (face *Face) IsColorGlyph(gid GID) bool {
+	c := face.loadColor()
+	if c.colr != nil {
+		if _, ok := c.colr.basePaint(gid); ok || len(c.colr.glyphLayers(gid)) != 0 {
+			return true
+		}
+	}
+	if c.svg != nil {
+		if _, ok := c.svg.document(gid); ok {
+			return true
+		}
+	}
+	b := face.loadBitmaps()
+	if b.sbix != nil {
+		for i := range b.sbix.strikes {
+			if _, _, _, _, ok := b.sbix.strikes[i].glyphData(gid); ok {
+				return true
+			}
+		}
+	}
+	for i := range b.cblc {
+		if _, _, _, ok := b.cblc[i].glyphImage(gid); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// dfontResourceDataOffset is the usual resource data offset,
+// found at the start of .dfont files.
+const dfontResourceDataOffset = 0x00000100
+
+var errInvalidDfont = errors.New("invalid dfont")
+
+// isResourceFork checks the consistency of the resource fork header,
+// since resource forks have no signature.
+func isResourceFork(data []byte) bool {
+	if len(data) < 16 {
+		return false
+	}
+	dataOffset := uint64(binary.BigEndian.Uint32(data))
+	mapOffset := uint64(binary.BigEndian.Uint32(data[4:]))
+	dataLength := uint64(binary.BigEndian.Uint32(data[8:]))
+	mapLength := uint64(binary.BigEndian.Uint32(data[12:]))
+	return dataOffset >= 16 && mapLength >= 28 &&
+		dataOffset+dataLength <= uint64(len(data)) &&
+		mapOffset+mapLength <= uint64(len(data))
+}
+
+// parseResourceFork parses a Macintosh resource fork (such as .dfont files) as per
+// https://github.com/kreativekorp/ksfl/wiki/Macintosh-Resource-File-Format
+// and returns the content of each 'sfnt' resource.
+func parseResourceFork(data []byte) ([][]byte, error) {
+	if !isResourceFork(data) {
+		return nil, errInvalidDfont
+	}
+	dataOffset := binary.BigEndian.Uint32(data)
+	mapOffset := binary.BigEndian.Uint32(data[4:])
+	mapLength := binary.BigEndian.Uint32(data[12:])
+	resourceMap := data[mapOffset : mapOffset+mapLength]
+
+	typeListOffset := uint32(binary.BigEndian.Uint16(resourceMap[24:]))
+	if typeListOffset < 28 || typeListOffset+2 > mapLength {
+		return nil, errInvalidDfont
+	}
+	typeList := resourceMap[typeListOffset:]
+	typeCount := int(binary.BigEndian.Uint16(typeList)) + 1 // the number of types, minus one
+	if typeCount == 0x10000 {
+		return nil, errInvalidDfont
+	}
+
+	const tSize = 8
+	if len(typeList) < 2+tSize*typeCount {
+		return nil, errInvalidDfont
+	}
+	var (
+		numFonts           int
+		resourceListOffset uint32
+	)
+	for i := 0; i < typeCount; i++ {
+		entry := typeList[2+tSize*i:]
+		if binary.BigEndian.Uint32(entry) != 0x73666e74 { // "sfnt"
+			continue
+		}
+		numFonts = int(binary.BigEndian.Uint16(entry[4:])) + 1 // the number of resources of this type, minus one
+		resourceListOffset = uint32(binary.BigEndian.Uint16(entry[6:]))
+		break
+	}
+	if numFonts == 0 {
+		return nil, errors.New("invalid dfont: no 'sfnt' resource")
+	}
+	if numFonts > maxNumFonts {
+		return nil, fmt.Errorf("number of fonts (%d) in collection exceed implementation limit (%d)",
+			numFonts, maxNumFonts)
+	}
+
+	const rSize = 12
+	if uint64(resourceListOffset)+rSize*uint64(numFonts) > uint64(len(typeList)) {
+		return nil, errInvalidDfont
+	}
+	references := typeList[resourceListOffset:]
+
+	out := make([][]byte, numFonts)
+	for i := range out {
+		// Offsets are relative to the resource data start, not the file start.
+		// A particular resource's data also starts with a 4-byte length.
+		o := uint64(dataOffset) + uint64(0xffffff&binary.BigEndian.Uint32(references[rSize*i+4:]))
+		if o+4 > uint64(len(data)) {
+			return nil, errInvalidDfont
+		}
+		length := uint64(binary.BigEndian.Uint32(data[o:]))
+		if o+4+length > uint64(len(data)) {
+			return nil, errInvalidDfont
+		}
+		out[i] = data[o+4 : o+4+length]
+	}
+
+	return out, nil
+}
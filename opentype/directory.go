@@ -0,0 +1,67 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// TableRecord describes a table of the font, as stored in the table directory.
+type TableRecord struct {
+	Tag      Tag
+	Offset   uint32 // start of the table in the file
+	Length   uint32 // length of the table, in bytes
+	Checksum uint32 // checksum stored in the table directory
+
+	// ChecksumValid is true if Checksum matches the content of the table.
+	ChecksumValid bool
+
+	actualChecksum uint32
+}
+
+// ListTables returns the tables of the font (excluding the ones skipped by
+// the ParseOptions), sorted by offset, checking their checksums.
+// Since the checksums require to read every table, using
+// this method on faces built with LoadFont reads the whole file.
+func (face *Face) ListTables() ([]TableRecord, error) {
+	out := make([]TableRecord, 0, len(face.tables))
+	for tag, sec := range face.tables {
+		out = append(out, TableRecord{Tag: tag, Offset: sec.offset, Length: sec.length, Checksum: sec.checksum})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Offset != out[j].Offset {
+			return out[i].Offset < out[j].Offset
+		}
+		return out[i].Tag < out[j].Tag
+	})
+
+	for i, table := range out {
+		data, err := face.src.read(table.Offset, table.Length)
+		if err != nil {
+			return nil, err
+		}
+		sum := tableChecksum(data)
+		if (table.Tag == tagHead || table.Tag == tagBhed) && len(data) >= 12 {
+			// the checksum of 'head' (and 'bhed') is computed with checkSumAdjustment set to 0
+			sum -= binary.BigEndian.Uint32(data[8:])
+		}
+		out[i].actualChecksum = sum
+		out[i].ChecksumValid = sum == table.Checksum
+	}
+	return out, nil
+}
+
+// tableChecksum returns the sum of the table, as uint32 values,
+// the last one being padded with zeros.
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for len(data) >= 4 {
+		sum += binary.BigEndian.Uint32(data)
+		data = data[4:]
+	}
+	if len(data) != 0 {
+		var last [4]byte
+		copy(last[:], data)
+		sum += binary.BigEndian.Uint32(last[:])
+	}
+	return sum
+}
@@ -0,0 +1,183 @@
+// Package eot implements a decoder for Embedded OpenType files (.eot), as
+// described in https://www.w3.org/Submission/EOT/.
+//
+// Plain and XOR obfuscated payloads are supported, as well as payloads
+// compressed with zlib by some legacy tools. Fonts compressed with MicroType Express
+// are detected but not decoded : Decode returns ErrMicroTypeExpress.
+package eot
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"unicode/utf16"
+)
+
+const (
+	magicNumber   = 0x504C
+	minHeaderSize = 82 // up to the FamilyNameSize field
+
+	// flags
+	flagSubset       = 0x00000001
+	flagTTCompressed = 0x00000004
+	flagXOREncrypted = 0x10000000
+
+	xorKey = 0x50
+)
+
+// ErrMicroTypeExpress is returned when the font data is compressed
+// with MicroType Express, which is not supported.
+var ErrMicroTypeExpress = errors.New("unsupported EOT MicroType Express compression")
+
+var errEOF = errors.New("invalid EOT file (EOF)")
+
+// Header stores the metadata found in an EOT file.
+type Header struct {
+	Version uint32
+	Flags   uint32
+
+	Panose  [10]byte
+	Charset byte
+	Italic  bool
+	Weight  uint32
+	FsType  uint16
+
+	FamilyName  string
+	StyleName   string
+	VersionName string
+	FullName    string
+	RootString  string // only for version 0x00020001 and later
+}
+
+// IsSubset returns true if the embedded font is a subset of the original one.
+func (h Header) IsSubset() bool { return h.Flags&flagSubset != 0 }
+
+// IsEOT returns true if `data` looks like an EOT file,
+// by checking its magic number and sizes.
+func IsEOT(data []byte) bool {
+	if len(data) < minHeaderSize {
+		return false
+	}
+	eotSize := binary.LittleEndian.Uint32(data)
+	fontDataSize := binary.LittleEndian.Uint32(data[4:])
+	return binary.LittleEndian.Uint16(data[34:]) == magicNumber &&
+		uint64(eotSize) <= uint64(len(data)) && fontDataSize <= eotSize
+}
+
+// Decode strips the EOT header, returning the metadata and
+// the embedded OpenType font file.
+func Decode(data []byte) (Header, []byte, error) {
+	header, err := parseHeader(data)
+	if err != nil {
+		return header, nil, err
+	}
+
+	// the font data is always stored at the end of the EOT structure
+	eotSize := binary.LittleEndian.Uint32(data)
+	fontDataSize := binary.LittleEndian.Uint32(data[4:])
+	fontData := append([]byte(nil), data[eotSize-fontDataSize:eotSize]...)
+
+	if header.Flags&flagXOREncrypted != 0 {
+		for i := range fontData {
+			fontData[i] ^= xorKey
+		}
+	}
+
+	if header.Flags&flagTTCompressed != 0 {
+		return header, nil, ErrMicroTypeExpress
+	}
+
+	if isZlib(fontData) {
+		r, err := zlib.NewReader(bytes.NewReader(fontData))
+		if err != nil {
+			return header, nil, fmt.Errorf("invalid EOT compressed data: %s", err)
+		}
+		defer r.Close()
+		fontData, err = ioutil.ReadAll(r)
+		if err != nil {
+			return header, nil, fmt.Errorf("invalid EOT compressed data: %s", err)
+		}
+	}
+
+	return header, fontData, nil
+}
+
+// isZlib detects a zlib stream, which can't be confused
+// with the start of a sfnt file.
+func isZlib(data []byte) bool {
+	return len(data) >= 2 && data[0]&0x0F == 8 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0
+}
+
+func parseHeader(data []byte) (Header, error) {
+	if !IsEOT(data) {
+		return Header{}, errors.New("invalid EOT header")
+	}
+
+	var out Header
+	out.Version = binary.LittleEndian.Uint32(data[8:])
+	out.Flags = binary.LittleEndian.Uint32(data[12:])
+	copy(out.Panose[:], data[16:26])
+	out.Charset = data[26]
+	out.Italic = data[27] == 1
+	out.Weight = binary.LittleEndian.Uint32(data[28:])
+	out.FsType = binary.LittleEndian.Uint16(data[32:])
+
+	switch out.Version {
+	case 0x00010000, 0x00020001, 0x00020002:
+	default:
+		return out, fmt.Errorf("unsupported EOT version %x", out.Version)
+	}
+
+	// the variable length names follow the fixed size fields,
+	// which end with a padding
+	pos := minHeaderSize - 2
+	var err error
+	readName := func() (string, error) {
+		if pos+4 > len(data) {
+			return "", errEOF
+		}
+		pos += 2 // padding
+		size := int(binary.LittleEndian.Uint16(data[pos:]))
+		pos += 2
+		if pos+size > len(data) {
+			return "", errEOF
+		}
+		s := decodeUTF16LE(data[pos : pos+size])
+		pos += size
+		return s, nil
+	}
+	if out.FamilyName, err = readName(); err != nil {
+		return out, err
+	}
+	if out.StyleName, err = readName(); err != nil {
+		return out, err
+	}
+	if out.VersionName, err = readName(); err != nil {
+		return out, err
+	}
+	if out.FullName, err = readName(); err != nil {
+		return out, err
+	}
+	if out.Version >= 0x00020001 {
+		if out.RootString, err = readName(); err != nil {
+			return out, err
+		}
+	}
+
+	return out, nil
+}
+
+func decodeUTF16LE(b []byte) string {
+	chars := make([]uint16, len(b)/2)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	// names may be null terminated
+	for len(chars) != 0 && chars[len(chars)-1] == 0 {
+		chars = chars[:len(chars)-1]
+	}
+	return string(utf16.Decode(chars))
+}
@@ -0,0 +1,150 @@
+package eot
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+// encode builds an EOT file wrapping `fontData`, which is
+// stored as it is : the caller is responsible for its encoding.
+func encode(version, flags uint32, names []string, fontData []byte) []byte {
+	out := make([]byte, minHeaderSize-2)
+	binary.LittleEndian.PutUint32(out[8:], version)
+	binary.LittleEndian.PutUint32(out[12:], flags)
+	copy(out[16:26], []byte{2, 11, 6, 3, 5, 4, 5, 2, 2, 4}) // panose
+	out[26] = 1                                             // charset
+	out[27] = 1                                             // italic
+	binary.LittleEndian.PutUint32(out[28:], 700)
+	binary.LittleEndian.PutUint16(out[32:], 8) // fsType
+	binary.LittleEndian.PutUint16(out[34:], magicNumber)
+	for _, name := range names {
+		chars := utf16.Encode([]rune(name))
+		out = append(out, 0, 0) // padding
+		out = append(out, byte(2*len(chars)), byte(2*len(chars)>>8))
+		for _, c := range chars {
+			out = append(out, byte(c), byte(c>>8))
+		}
+	}
+	if version == 0x00020002 {
+		// root string checksum, EUDC code page, padding, signature size, EUDC flags, EUDC font size
+		out = append(out, make([]byte, 4+4+2+2+4+4)...)
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(fontData)))
+	out = append(out, fontData...)
+	binary.LittleEndian.PutUint32(out, uint32(len(out)))
+	return out
+}
+
+func readFont(t *testing.T) []byte {
+	t.Helper()
+	data, err := td.Files.ReadFile("common/Roboto-BoldItalic.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	font := readFont(t)
+	names := []string{"Roboto", "Bold Italic", "Version 2.137", "Roboto Bold Italic", "http://example.com"}
+
+	xored := append([]byte(nil), font...)
+	for i := range xored {
+		xored[i] ^= xorKey
+	}
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	if _, err := w.Write(font); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		version  uint32
+		flags    uint32
+		fontData []byte
+	}{
+		{0x00010000, 0, font},
+		{0x00020001, flagSubset, font},
+		{0x00020002, 0, font},
+		{0x00020001, flagXOREncrypted, xored},
+		{0x00020001, 0, compressed.Bytes()},
+	} {
+		numNames := 5
+		if test.version == 0x00010000 {
+			numNames = 4 // no root string
+		}
+		data := encode(test.version, test.flags, names[:numNames], test.fontData)
+		if !IsEOT(data) {
+			t.Fatalf("version %x, flags %x: expected an EOT file", test.version, test.flags)
+		}
+		header, decoded, err := Decode(data)
+		if err != nil {
+			t.Fatalf("version %x, flags %x: %s", test.version, test.flags, err)
+		}
+		if !bytes.Equal(decoded, font) {
+			t.Errorf("version %x, flags %x: invalid font data", test.version, test.flags)
+		}
+
+		if header.Version != test.version || header.IsSubset() != (test.flags&flagSubset != 0) {
+			t.Errorf("unexpected header %v", header)
+		}
+		if header.Weight != 700 || !header.Italic || header.FsType != 8 || header.Charset != 1 || header.Panose[0] != 2 {
+			t.Errorf("version %x: unexpected header %v", test.version, header)
+		}
+		got := []string{header.FamilyName, header.StyleName, header.VersionName, header.FullName, header.RootString}
+		for i, name := range names[:numNames] {
+			if got[i] != name {
+				t.Errorf("version %x: name %d: expected %q, got %q", test.version, i, name, got[i])
+			}
+		}
+		if numNames == 4 && header.RootString != "" {
+			t.Errorf("unexpected root string %q", header.RootString)
+		}
+	}
+}
+
+func TestDecodeMicroTypeExpress(t *testing.T) {
+	data := encode(0x00020001, flagTTCompressed, []string{"", "", "", "", ""}, []byte{1, 2, 3, 4})
+	if _, _, err := Decode(data); err != ErrMicroTypeExpress {
+		t.Errorf("expected %s, got %v", ErrMicroTypeExpress, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	font := readFont(t)
+	if IsEOT(font) {
+		t.Error("TrueType font detected as EOT")
+	}
+
+	data := encode(0x00020001, 0, []string{"Roboto", "Bold Italic", "", "", ""}, font)
+	for _, length := range []int{0, 10, minHeaderSize - 1, len(data) - 1} {
+		if _, _, err := Decode(data[:length]); err == nil {
+			t.Errorf("expected an error for %d bytes", length)
+		}
+	}
+
+	version := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(version[8:], 0x00030000)
+	if _, _, err := Decode(version); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+
+	// the name sizes overflow the header
+	names := encode(0x00020001, 0, []string{"Roboto"}, nil)
+	if _, _, err := Decode(names); err == nil {
+		t.Error("expected an error for truncated names")
+	}
+
+	compressed := encode(0x00020001, 0, []string{"", "", "", "", ""}, []byte{0x78, 0x9C, 1, 2, 3})
+	if _, _, err := Decode(compressed); err == nil {
+		t.Error("expected an error for invalid compressed data")
+	}
+}
@@ -0,0 +1,108 @@
+package opentype
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableError is returned when a table is missing or invalid.
+// Use errors.As to inspect it.
+type TableError struct {
+	Tag Tag
+	// Offset is the start of the table in the file (not the position
+	// of the invalid data in the table), or 0 for missing tables.
+	Offset uint32
+	// Err describes what is invalid. It is returned by Unwrap,
+	// so that it may be inspected with errors.Is and errors.As.
+	Err error
+}
+
+func (e *TableError) Error() string {
+	if e.Offset == 0 {
+		return fmt.Sprintf("table '%s': %s", e.Tag, e.Err)
+	}
+	return fmt.Sprintf("invalid '%s' table at offset %d: %s", e.Tag, e.Offset, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *TableError) Unwrap() error { return e.Err }
+
+// tableError returns a TableError for the table `tag`,
+// whose cause is `err`.
+func (face *Face) tableError(tag Tag, err error) error {
+	return &TableError{Tag: tag, Offset: face.tables[tag].offset, Err: err}
+}
+
+// missingTable returns a TableError for the required table `tag`.
+func missingTable(tag Tag) error {
+	return &TableError{Tag: tag, Err: errors.New("missing required table")}
+}
+
+var errEOF = errors.New("EOF")
+
+// warnings accumulates the non fatal issues found while parsing.
+// It may be used concurrently, since the tables are loaded on demand.
+type warnings struct {
+	mu      sync.Mutex
+	list    []string
+	errs    []error // the structural errors, see addError
+	loading int     // number of warnings found while loading the face, see endLoading
+}
+
+func (w *warnings) add(format string, args ...interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.list = append(w.list, fmt.Sprintf(format, args...))
+}
+
+// addError reports a structural error, which is tolerated by ignoring
+// the invalid data, but rejected by the strict mode.
+func (w *warnings) addError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.list = append(w.list, err.Error()+": ignored")
+	w.errs = append(w.errs, err)
+}
+
+// firstError returns the first error reported by addError, or nil.
+func (w *warnings) firstError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.errs) == 0 {
+		return nil
+	}
+	return w.errs[0]
+}
+
+// endLoading records the warnings found while loading the face,
+// before any table parsed on demand.
+func (w *warnings) endLoading() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.loading = len(w.list)
+}
+
+// copy returns a copy of all the warnings.
+func (w *warnings) copy() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.list...)
+}
+
+// copyLoading copies the warnings found while loading the face into `dst`.
+func (w *warnings) copyLoading(dst *warnings) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dst.list = append([]string(nil), w.list[:w.loading]...)
+	dst.loading = w.loading
+}
+
+// Warnings returns a description of the issues which were
+// tolerated while parsing the face, such as ignored duplicate tables
+// or unsupported subtable formats, or suspicious values.
+// Most tables are parsed on first use, so that their issues are
+// only reported after the methods using them have been called; the strict
+// mode (see ParseOptions.Strict) parses them while loading the face.
+// The returned slice is a copy, which may be modified by the caller.
+func (face *Face) Warnings() []string { return face.warnings.copy() }
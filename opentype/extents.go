@@ -0,0 +1,104 @@
+package opentype
+
+import "github.com/go-text/font"
+
+// ExtentsPolicy selects the tables providing the ascender,
+// descender and line gap of a font, see FontExtents.
+type ExtentsPolicy uint8
+
+const (
+	// ExtentsDefault uses the typographic metrics of the 'OS/2' table if
+	// its USE_TYPO_METRICS flag is set, and the 'hhea' metrics otherwise.
+	// It is the behavior of HarfBuzz, and of most browsers on macOS and Linux.
+	ExtentsDefault ExtentsPolicy = iota
+	// ExtentsHhea uses the 'hhea' metrics.
+	ExtentsHhea
+	// ExtentsWin uses the usWinAscent and usWinDescent values of the 'OS/2' table,
+	// with the external leading computed by Windows GDI as line gap.
+	// Browsers on Windows use it unless the USE_TYPO_METRICS flag is set.
+	ExtentsWin
+	// ExtentsTypo uses the typographic metrics of the 'OS/2' table,
+	// regardless of the USE_TYPO_METRICS flag.
+	ExtentsTypo
+)
+
+// FontExtents returns the ascender, descender and line gap of the font, selected
+// by `policy` and adjusted by the 'MVAR' table for variable fonts.
+// The descender is always negative.
+// If the table required by `policy` is missing, the other one is used,
+// and false is returned if the font has neither 'OS/2' nor 'hhea' table.
+func (face *Face) FontExtents(policy ExtentsPolicy) (font.FontExtents, bool) {
+	m := face.loadMetrics()
+	switch {
+	case m.os2 == nil:
+		policy = ExtentsHhea
+	case policy == ExtentsDefault && m.os2.FsSelection&SelectionUseTypoMetrics != 0,
+		policy == ExtentsHhea && m.hhea == nil:
+		policy = ExtentsTypo
+	case policy == ExtentsDefault:
+		policy = ExtentsHhea
+	}
+
+	var out font.FontExtents
+	switch policy {
+	case ExtentsTypo:
+		out = font.FontExtents{
+			Ascender:  float32(m.os2.TypoAscender) + face.metricDelta(tagHorizontalAscender),
+			Descender: float32(m.os2.TypoDescender) + face.metricDelta(tagHorizontalDescender),
+			LineGap:   float32(m.os2.TypoLineGap) + face.metricDelta(tagHorizontalLineGap),
+		}
+	case ExtentsWin:
+		out = font.FontExtents{
+			Ascender:  float32(m.os2.WinAscent) + face.metricDelta(tagClippingAscent),
+			Descender: float32(m.os2.WinDescent) + face.metricDelta(tagClippingDescent),
+		}
+		if m.hhea != nil {
+			// external leading, as defined by GDI
+			hheaHeight := float32(m.hhea.ascender) - float32(m.hhea.descender)
+			leading := float32(m.hhea.lineGap) - (abs(out.Ascender) + abs(out.Descender) - hheaHeight)
+			if leading > 0 {
+				out.LineGap = leading
+			}
+		}
+	default: // ExtentsHhea
+		if m.hhea == nil {
+			return font.FontExtents{}, false
+		}
+		out = font.FontExtents{
+			Ascender:  float32(m.hhea.ascender) + face.metricDelta(tagHorizontalAscender),
+			Descender: float32(m.hhea.descender) + face.metricDelta(tagHorizontalDescender),
+			LineGap:   float32(m.hhea.lineGap) + face.metricDelta(tagHorizontalLineGap),
+		}
+	}
+	out.Ascender, out.Descender = abs(out.Ascender), -abs(out.Descender)
+	return out, true
+}
+
+// FontHExtents implements font.Face, using ExtentsDefault (see FontExtents).
+func (face *Face) FontHExtents() (font.FontExtents, bool) {
+	return face.FontExtents(ExtentsDefault)
+}
+
+// FontVExtents returns the extents of the font for vertical text, given by
+// the 'vhea' table, and adjusted by the 'MVAR' table for variable fonts :
+// the ascender is the distance from the center line to the right of the
+// line, and the descender, which is negative, to its left.
+// It returns false if the font has no 'vhea' table.
+func (face *Face) FontVExtents() (font.FontExtents, bool) {
+	vhea := face.loadMetrics().vhea
+	if vhea == nil {
+		return font.FontExtents{}, false
+	}
+	return font.FontExtents{
+		Ascender:  float32(vhea.ascender) + face.metricDelta(tagVerticalAscender),
+		Descender: float32(vhea.descender) + face.metricDelta(tagVerticalDescender),
+		LineGap:   float32(vhea.lineGap) + face.metricDelta(tagVerticalLineGap),
+	}, true
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
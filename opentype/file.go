@@ -0,0 +1,114 @@
+package opentype
+
+import (
+	"os"
+)
+
+// mapFile memory-maps the file at `path`, or reads it in memory on
+// platforms without memory mapping. Web fonts are decompressed, and
+// their mapping released right away.
+// The returned function releases the mapping, and is nil if there is none.
+func mapFile(path string) (src source, release func() error, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return source{}, nil, err
+	}
+	defer file.Close() // the mapping stays valid after closing the file
+
+	info, err := file.Stat()
+	if err != nil {
+		return source{}, nil, err
+	}
+	size := info.Size()
+	if size < 4 || int64(int(size)) != size {
+		return source{}, nil, errUnsupportedFormat
+	}
+	data, err := mmapFile(file, int(size))
+	if err != nil {
+		return source{}, nil, err
+	}
+
+	sfnt, err := unwrapWebFont(data)
+	if err != nil || len(sfnt) < 4 {
+		munmap(data)
+		if err == nil {
+			err = errUnsupportedFormat
+		}
+		return source{}, nil, err
+	}
+	if &sfnt[0] != &data[0] { // decompressed in the Go heap
+		return memorySource(sfnt), nil, munmap(data)
+	}
+	return memorySource(data), func() error { return munmap(data) }, nil
+}
+
+// OpenFile is the same as ParseFont, but memory-maps the file at `path`
+// instead of copying it in memory, which is useful for large fonts.
+// On platforms not supporting memory mapping, the file is read instead.
+//
+// The returned Face must be released with Close when no longer used : it is then
+// invalid, as well as all the slices returned by its methods, such as GetRawTable.
+func OpenFile(path string) (*Face, error) {
+	src, release, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = checkSignature(src.data, "OpenCollection")
+	var face *Face
+	if err == nil {
+		face, err = parseFace(src, 0, ParseOptions{})
+	}
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+	face.release = release
+	return face, nil
+}
+
+// OpenCollection is the same as ParseCollection, but memory-maps the file at `path`,
+// similarly to OpenFile.
+//
+// The returned Collection must be released with Close when no longer used : it is then
+// invalid, as well as all the faces it returned.
+func OpenCollection(path string) (*Collection, error) {
+	src, release, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCollection(src)
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+	c.release = release
+	return c, nil
+}
+
+// Close releases the memory mapping of faces created with OpenFile,
+// or closes the file of faces created with LoadFromFS,
+// and is a no-op for other faces.
+func (face *Face) Close() error {
+	if face.release == nil {
+		return nil
+	}
+	err := face.release()
+	face.release = nil
+	return err
+}
+
+// Close releases the memory mapping of collections created with OpenCollection,
+// or closes the file of collections created with LoadCollectionFromFS,
+// and is a no-op for other collections.
+func (c *Collection) Close() error {
+	if c.release == nil {
+		return nil
+	}
+	err := c.release()
+	c.release = nil
+	return err
+}
@@ -0,0 +1,105 @@
+package opentype
+
+import (
+	"io"
+	"io/fs"
+)
+
+// Parse is the same as ParseFont, but reads the font from `data`,
+// which is used without copy (except for web fonts, which are decompressed).
+// This is convenient for fonts embedded with go:embed.
+// `data` must not be modified while the Face is used.
+func Parse(data []byte) (*Face, error) {
+	data, err := sfntData(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSignature(data, "ParseCollectionBytes"); err != nil {
+		return nil, err
+	}
+	return parseFace(memorySource(data), 0, ParseOptions{})
+}
+
+// ParseCollectionBytes is the same as ParseCollection, but reads the collection
+// from `data`, which is used without copy (see Parse).
+func ParseCollectionBytes(data []byte) (*Collection, error) {
+	data, err := sfntData(data)
+	if err != nil {
+		return nil, err
+	}
+	return newCollection(memorySource(data))
+}
+
+// LoadFromFS is the same as ParseFont, for the file at `path` in `fsys`,
+// such as a embed.FS.
+// If the file implements io.ReaderAt (which is the case for embed.FS and os.DirFS),
+// the font is loaded as with LoadFont, and the file stays open until
+// the returned Face is released with Close. Otherwise, the file is read and closed.
+func LoadFromFS(fsys fs.FS, path string) (*Face, error) {
+	var face *Face
+	release, err := loadFromFS(fsys, path,
+		func(file io.ReaderAt, size int64) (err error) {
+			face, err = loadFont(file, size, "LoadCollectionFromFS")
+			return err
+		},
+		func(data []byte) (err error) {
+			face, err = Parse(data)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+	face.release = release
+	return face, nil
+}
+
+// LoadCollectionFromFS is the same as LoadFromFS, for collections : see
+// ParseCollection and LoadCollection. The returned Collection should be
+// released with Close.
+func LoadCollectionFromFS(fsys fs.FS, path string) (*Collection, error) {
+	var c *Collection
+	release, err := loadFromFS(fsys, path,
+		func(file io.ReaderAt, size int64) (err error) {
+			c, err = LoadCollection(file, size)
+			return err
+		},
+		func(data []byte) (err error) {
+			c, err = ParseCollectionBytes(data)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+	c.release = release
+	return c, nil
+}
+
+// loadFromFS opens `path` and calls `load` with the file if it supports
+// random access, or `parse` with its content.
+// The file is only kept open if `load` succeeds : the returned function
+// then closes it, and is nil otherwise.
+func loadFromFS(fsys fs.FS, path string, load func(file io.ReaderAt, size int64) error, parse func(data []byte) error) (func() error, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if ra, ok := file.(io.ReaderAt); ok && info.Mode().IsRegular() {
+		if err = load(ra, info.Size()); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file.Close, nil
+	}
+
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	return nil, parse(data)
+}
@@ -0,0 +1,61 @@
+package opentype
+
+import "sync"
+
+// glyphNames stores the glyph names of the 'post' table,
+// loaded on first use.
+type glyphNames struct {
+	once sync.Once
+	post tablePost
+
+	byNameOnce sync.Once
+	byName     map[string]GID // built on demand by GlyphFromName
+}
+
+func (face *Face) loadGlyphNames() *glyphNames {
+	names := &face.glyphNames
+	names.once.Do(func() {
+		data, err := face.GetRawTable(tagPost)
+		if err != nil {
+			return
+		}
+		names.post, err = parseTablePost(data, face.NumGlyphs)
+		if err != nil {
+			face.warnings.addError(face.tableError(tagPost, err))
+		}
+	})
+	return names
+}
+
+// GlyphName returns the name of the glyph, given by the 'post' table or
+// by the CFF charset, or an empty string if the glyph is invalid or has no name.
+func (face *Face) GlyphName(gid GID) string {
+	if int(gid) >= face.NumGlyphs {
+		return ""
+	}
+	if names := face.loadGlyphNames().post.names; int(gid) < len(names) && names[gid] != "" {
+		return names[gid]
+	}
+	if face.loadOutlines() == nil && face.outlines.cff != nil {
+		return face.outlines.cff.GlyphName(gid)
+	}
+	return ""
+}
+
+// GlyphFromName returns the glyph with the given name (as returned by GlyphName),
+// or false if no glyph has this name. If several glyphs share the
+// same name, the first one is returned.
+func (face *Face) GlyphFromName(name string) (GID, bool) {
+	names := face.loadGlyphNames()
+	names.byNameOnce.Do(func() {
+		names.byName = make(map[string]GID, face.NumGlyphs)
+		// iterate backward so that the first glyph wins
+		for gid := face.NumGlyphs - 1; gid >= 0; gid-- {
+			if name := face.GlyphName(GID(gid)); name != "" {
+				names.byName[name] = GID(gid)
+			}
+		}
+	})
+	gid, ok := names.byName[name]
+	return gid, ok
+}
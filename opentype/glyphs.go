@@ -0,0 +1,257 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/go-text/font"
+	"github.com/go-text/font/cff"
+)
+
+// outlines gives access to the glyph outlines, stored
+// either in the 'glyf' table or in a 'CFF ' or 'CFF2' table.
+type outlines struct {
+	once sync.Once
+	err  error
+
+	glyf tableSection
+	loca []uint32 // offsets into glyf, for NumGlyphs + 1 glyphs at most
+
+	gvar *tableGvar // optional
+
+	cff *cff.Face
+}
+
+// loadOutlines parses the tables required to access the glyphs outlines,
+// on first use only.
+func (face *Face) loadOutlines() error {
+	face.outlines.once.Do(func() {
+		face.outlines.err = face.parseOutlines()
+	})
+	return face.outlines.err
+}
+
+func (face *Face) parseOutlines() error {
+	glyf, hasGlyf := face.tables[tagGlyf]
+	if loca, err := face.GetRawTable(tagLoca); err == nil && hasGlyf {
+		face.outlines.glyf = glyf
+		face.outlines.loca, err = parseTableLoca(loca, face.NumGlyphs, face.Head.IndexToLocFormat)
+		if err != nil {
+			return face.tableError(tagLoca, err)
+		}
+		if data, err := face.GetRawTable(tagGvar); err == nil {
+			if gvar, err := parseTableGvar(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagGvar, err))
+			} else {
+				face.outlines.gvar = &gvar
+			}
+		}
+		return nil
+	}
+	if data, err := face.GetRawTable(tagCFF2); err == nil {
+		face.outlines.cff, err = cff.ParseCFF2(data)
+		if err != nil {
+			return face.tableError(tagCFF2, err)
+		}
+		face.outlines.cff.SetVariations(face.coords)
+		return nil
+	}
+	if data, err := face.GetRawTable(tagCFF); err == nil {
+		face.outlines.cff, err = cff.Parse(data)
+		if err != nil {
+			return face.tableError(tagCFF, err)
+		}
+		return nil
+	}
+	return errors.New("no glyph outlines")
+}
+
+// parseTableLoca returns the offsets of the glyphs in the 'glyf' table,
+// which are truncated if the table is too short.
+func parseTableLoca(data []byte, numGlyphs int, indexToLocFormat int16) ([]uint32, error) {
+	var entrySize int
+	switch indexToLocFormat {
+	case 0:
+		entrySize = 2
+	case 1:
+		entrySize = 4
+	default:
+		return nil, errors.New("invalid index to loc format")
+	}
+	n := numGlyphs + 1
+	if len(data)/entrySize < n {
+		n = len(data) / entrySize
+	}
+	out := make([]uint32, n)
+	for i := range out {
+		if entrySize == 2 {
+			out[i] = 2 * uint32(binary.BigEndian.Uint16(data[2*i:]))
+		} else {
+			out[i] = binary.BigEndian.Uint32(data[4*i:])
+		}
+	}
+	return out, nil
+}
+
+// glyphData returns the content of the glyph in the 'glyf' table,
+// which is empty for glyphs without outline.
+func (face *Face) glyphData(gid GID) ([]byte, bool) {
+	loca := face.outlines.loca
+	if int(gid)+1 >= len(loca) {
+		return nil, false
+	}
+	start, end := loca[gid], loca[gid+1]
+	if start > end || end > face.outlines.glyf.length {
+		return nil, false
+	}
+	data, err := face.src.read(face.outlines.glyf.offset+start, end-start)
+	return data, err == nil
+}
+
+// GlyphExtents returns the extents of the glyph, in font units, given by the
+// bounding box stored in the 'glyf' table, or computed from the CFF charstring.
+// As rasterizers do, the left side bearing of TrueType glyphs is taken from
+// the 'hmtx' table, and may differ from the minimum x of the bounding box.
+// For variable fonts, the extents are computed from the varied outlines.
+// It returns false if the glyph is invalid, or the font has no outlines.
+func (face *Face) GlyphExtents(gid GID) (font.GlyphExtents, bool) {
+	if int(gid) >= face.NumGlyphs || face.loadOutlines() != nil {
+		return font.GlyphExtents{}, false
+	}
+	if face.outlines.cff != nil {
+		return face.outlines.cff.GlyphExtents(gid)
+	}
+	if face.gvarTable() != nil {
+		return face.variedGlyfExtents(gid)
+	}
+
+	data, ok := face.glyphData(gid)
+	if !ok {
+		return font.GlyphExtents{}, false
+	}
+	if len(data) == 0 { // no outline
+		return font.GlyphExtents{}, true
+	}
+	if len(data) < 10 {
+		return font.GlyphExtents{}, false
+	}
+	xMin := int16(binary.BigEndian.Uint16(data[2:]))
+	yMin := int16(binary.BigEndian.Uint16(data[4:]))
+	xMax := int16(binary.BigEndian.Uint16(data[6:]))
+	yMax := int16(binary.BigEndian.Uint16(data[8:]))
+	lsb := xMin
+	if sb, ok := face.loadMetrics().hmtx.sideBearing(gid); ok {
+		lsb = sb
+	}
+	return font.GlyphExtents{
+		XBearing: float32(lsb),
+		YBearing: float32(yMax),
+		Width:    float32(xMax) - float32(xMin),
+		Height:   float32(yMin) - float32(yMax),
+	}, true
+}
+
+// variedGlyfExtents computes the extents of the glyph from its varied points, the
+// left side bearing being given by the varied horizontal origin.
+func (face *Face) variedGlyfExtents(gid GID) (font.GlyphExtents, bool) {
+	var points glyfPoints
+	phantoms, err := face.appendGlyfPoints(gid, 0, &points)
+	if err != nil {
+		return font.GlyphExtents{}, false
+	}
+	if len(points.points) == 0 {
+		return font.GlyphExtents{}, true
+	}
+	xMin, yMin := points.points[0].x, points.points[0].y
+	xMax, yMax := xMin, yMin
+	for _, p := range points.points[1:] {
+		xMin, xMax = min32(xMin, p.x), max32(xMax, p.x)
+		yMin, yMax = min32(yMin, p.y), max32(yMax, p.y)
+	}
+	return font.GlyphExtents{
+		XBearing: xMin - phantoms[0].x,
+		YBearing: yMax,
+		Width:    xMax - xMin,
+		Height:   yMin - yMax,
+	}, true
+}
+
+// GlyphOutline returns the outline of the glyph, made of quadratic Bézier curves
+// for TrueType outlines (with the composite glyphs resolved), and of cubic Bézier
+// curves for CFF outlines.
+// Hinting instructions are ignored, and the variations of variable fonts
+// are applied (see SetVariations).
+// As for GlyphExtents, TrueType outlines are shifted so that their left side
+// bearing is the one of the 'hmtx' table.
+// It returns false if the glyph is invalid, or the font has no outlines.
+func (face *Face) GlyphOutline(gid GID) (font.GlyphOutline, bool) {
+	if int(gid) >= face.NumGlyphs || face.loadOutlines() != nil {
+		return font.GlyphOutline{}, false
+	}
+	if face.outlines.cff != nil {
+		return face.outlines.cff.GlyphOutline(gid)
+	}
+
+	points, err := face.glyphPoints(gid)
+	if err != nil {
+		return font.GlyphOutline{}, false
+	}
+	var out font.GlyphOutline
+	start := 0
+	for _, end := range points.ends {
+		if end < start || end >= len(points.points) {
+			return font.GlyphOutline{}, false
+		}
+		out.Segments = appendContour(out.Segments, points.points[start:end+1])
+		start = end + 1
+	}
+	return out, true
+}
+
+// appendContour converts a TrueType contour to segments,
+// adding the implicit on curve points between two off curve points.
+func appendContour(segments []font.Segment, contour []contourPoint) []font.Segment {
+	if len(contour) == 0 {
+		return segments
+	}
+	mid := func(p, q contourPoint) font.SegmentPoint {
+		return font.SegmentPoint{X: (p.x + q.x) / 2, Y: (p.y + q.y) / 2}
+	}
+	toPoint := func(p contourPoint) font.SegmentPoint { return font.SegmentPoint{X: p.x, Y: p.y} }
+
+	first, last := contour[0], contour[len(contour)-1]
+	var start font.SegmentPoint
+	switch {
+	case first.onCurve:
+		start, contour = toPoint(first), contour[1:]
+	case last.onCurve:
+		start, contour = toPoint(last), contour[:len(contour)-1]
+	default:
+		start = mid(last, first)
+	}
+	segments = append(segments, font.Segment{Op: font.SegmentOpMoveTo, Args: [3]font.SegmentPoint{start}})
+
+	var (
+		control    contourPoint
+		hasControl bool
+	)
+	for _, p := range contour {
+		switch {
+		case p.onCurve && hasControl:
+			segments = append(segments, font.Segment{Op: font.SegmentOpQuadTo, Args: [3]font.SegmentPoint{toPoint(control), toPoint(p)}})
+			hasControl = false
+		case p.onCurve:
+			segments = append(segments, font.Segment{Op: font.SegmentOpLineTo, Args: [3]font.SegmentPoint{toPoint(p)}})
+		case hasControl:
+			segments = append(segments, font.Segment{Op: font.SegmentOpQuadTo, Args: [3]font.SegmentPoint{toPoint(control), mid(control, p)}})
+			control = p
+		default:
+			control, hasControl = p, true
+		}
+	}
+	if hasControl { // close the contour
+		segments = append(segments, font.Segment{Op: font.SegmentOpQuadTo, Args: [3]font.SegmentPoint{toPoint(control), start}})
+	}
+	return segments
+}
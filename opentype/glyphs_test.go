@@ -0,0 +1,60 @@
+package opentype
+
+import (
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+// checkExtents checks that the exact extents of the outlines are
+// enclosed by the extents of the glyphs, within one font unit, since
+// the composite glyphs may be scaled.
+func checkExtents(t *testing.T, name string, face *Face, maxGlyphs int) {
+	const tolerance = 1
+	for gid := GID(0); int(gid) < face.GlyphCount() && int(gid) < maxGlyphs; gid++ {
+		outline, ok1 := face.GlyphOutline(gid)
+		extents, ok2 := face.GlyphExtents(gid)
+		if !ok1 || !ok2 {
+			t.Errorf("%s: glyph %d: missing outline or extents", name, gid)
+			continue
+		}
+		if len(outline.Segments) == 0 {
+			continue
+		}
+		tight := outline.TightExtents()
+		if tight.XBearing < extents.XBearing-tolerance || tight.YBearing > extents.YBearing+tolerance ||
+			tight.XBearing+tight.Width > extents.XBearing+extents.Width+tolerance ||
+			tight.YBearing+tight.Height < extents.YBearing+extents.Height-tolerance {
+			t.Errorf("%s: glyph %d: outline extents %v not enclosed by %v", name, gid, tight, extents)
+		}
+	}
+}
+
+func TestGlyphExtents(t *testing.T) {
+	for _, file := range td.WithGlyphs {
+		checkExtents(t, file.Path, loadFace(t, file.Path), file.GlyphNumber)
+	}
+	// CFF fonts
+	checkExtents(t, "Raleway", loadFace(t, "common/Raleway-v4020-Regular.otf"), 1000)
+	cjk := loadFace(t, "common/NotoSansCJKjp-VF.otf")
+	cjk.SetVariations(cjk.NormalizeCoordinates([]float32{650}))
+	checkExtents(t, "NotoSansCJKjp 650", cjk, 1000)
+	// variable TrueType font
+	face := loadFace(t, "common/Commissioner-VF.ttf")
+	checkExtents(t, "Commissioner 650", face.WithVariations([]Variation{{MustNewTag("wght"), 650}}), face.GlyphCount())
+}
+
+func TestGlyphExtentsSideBearing(t *testing.T) {
+	// as rasterizers do, the outlines are shifted by the 'hmtx' side bearing
+	face := loadFace(t, "common/Roboto-BoldItalic.ttf")
+	for gid := GID(0); int(gid) < face.GlyphCount(); gid++ {
+		extents, _ := face.GlyphExtents(gid)
+		lsb, ok := face.LeftSideBearing(gid)
+		if !ok {
+			t.Fatalf("glyph %d: missing side bearing", gid)
+		}
+		if extents.Width != 0 && extents.XBearing != lsb {
+			t.Errorf("glyph %d: expected side bearing %g, got %g", gid, lsb, extents.XBearing)
+		}
+	}
+}
@@ -0,0 +1,504 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// This file builds static fonts from the instances of variable fonts.
+
+var (
+	tagHdmx = MustNewTag("hdmx")
+	tagLTSH = MustNewTag("LTSH")
+	tagVDMX = MustNewTag("VDMX")
+	tagDSIG = MustNewTag("DSIG")
+	tagWght = MustNewTag("wght")
+	tagWdth = MustNewTag("wdth")
+	tagSlnt = MustNewTag("slnt")
+)
+
+// droppedInstanceTables are the tables removed from the static instances :
+// the variation tables, and the tables invalidated by the new glyphs.
+var droppedInstanceTables = map[Tag]bool{
+	tagFvar: true, tagAvar: true, tagGvar: true, tagCvar: true,
+	tagHVAR: true, tagVVAR: true, tagMVAR: true,
+	tagHdmx: true, tagLTSH: true, tagVDMX: true, tagDSIG: true,
+}
+
+// Instance returns the binary content of a static font (without variations), which
+// is the instance of the variable font at the coordinates set by SetVariations :
+//   - the 'gvar' variations (or the 'CFF2' blends) are applied to the outlines,
+//     whose coordinates are rounded ; the CFF2 hints and subroutines are dropped
+//   - the varied metrics are written in the 'hmtx', 'vmtx', 'hhea', 'vhea', 'VORG',
+//     'OS/2' and 'post' tables, and the varied control values in the 'cvt ' table
+//   - the names of the instance (family, subfamily, full and PostScript names),
+//     built as VariationPostScriptName does, are written in the 'name' table
+//   - the variation tables ('fvar', 'avar', 'gvar', 'cvar', 'HVAR', 'VVAR', 'MVAR'),
+//     and the 'hdmx', 'LTSH', 'VDMX' and 'DSIG' tables are removed.
+//
+// The other tables are copied unchanged, so that the variations of the layout
+// ('GDEF', 'GPOS' and 'GSUB') and color ('COLR') tables are not applied : the
+// static font uses the values of the default instance.
+// It returns an error if the font is not variable, or if its outlines are invalid.
+func (face *Face) Instance() ([]byte, error) {
+	fvar, ok := face.Fvar()
+	if !ok {
+		return nil, errors.New("not a variable font")
+	}
+	if err := face.loadOutlines(); err != nil {
+		return nil, err
+	}
+	tables := make(map[Tag][]byte, len(face.tables))
+	for tag := range face.tables {
+		if droppedInstanceTables[tag] {
+			continue
+		}
+		data, err := face.GetRawTable(tag)
+		if err != nil {
+			return nil, face.tableError(tag, err)
+		}
+		tables[tag] = append([]byte(nil), data...)
+	}
+
+	m := face.loadMetrics()
+	hMetrics := make([]longMetric, face.NumGlyphs)
+	vMetrics := make([]longMetric, face.NumGlyphs)
+	for gid := range hMetrics {
+		hMetrics[gid].advance = uint16(roundFUnit(face.HorizontalAdvance(GID(gid))))
+		vMetrics[gid].advance = uint16(roundFUnit(-face.VerticalAdvance(GID(gid))))
+	}
+
+	var (
+		bounds  []glyphBounds
+		version uint32 = 0x00010000
+	)
+	if face.outlines.cff != nil {
+		data, cffBounds, err := face.instanceCFF2()
+		if err != nil {
+			return nil, face.tableError(tagCFF2, err)
+		}
+		tables[tagCFF2], bounds, version = data, cffBounds, 0x4F54544F // OTTO
+		for gid := range hMetrics {
+			if gid < len(bounds) {
+				hMetrics[gid].sideBearing = bounds[gid].xMin
+			}
+			if tsb, ok := face.TopSideBearing(GID(gid)); ok {
+				vMetrics[gid].sideBearing = roundFUnit(tsb)
+			}
+		}
+	} else {
+		glyf, err := face.instanceGlyf()
+		if err != nil {
+			return nil, face.tableError(tagGlyf, err)
+		}
+		tables[tagGlyf], tables[tagLoca], bounds = glyf.glyf, glyf.loca, glyf.bounds
+		for gid, phantoms := range glyf.phantoms {
+			b := bounds[gid]
+			hMetrics[gid].sideBearing = b.xMin - int16(phantoms[0].x)
+			vMetrics[gid].sideBearing = int16(phantoms[2].y) - b.yMax
+		}
+		if head := tables[tagHead]; len(head) >= 54 {
+			binary.BigEndian.PutUint16(head[50:], 0)
+			if glyf.longLoca {
+				binary.BigEndian.PutUint16(head[50:], 1)
+			}
+		}
+	}
+
+	if _, ok := tables[tagHmtx]; ok && tables[tagHhea] != nil {
+		tables[tagHmtx] = face.instanceMetrics(tables[tagHhea], hMetrics, bounds, false)
+	}
+	if _, ok := tables[tagVmtx]; ok && tables[tagVhea] != nil {
+		tables[tagVmtx] = face.instanceMetrics(tables[tagVhea], vMetrics, bounds, true)
+	}
+	if tables[tagVORG] != nil && m.vorg != nil {
+		tables[tagVORG] = face.instanceVORG()
+	}
+	if head := tables[tagHead]; len(head) >= 54 {
+		instanceHead(head, bounds)
+	}
+	if os2 := tables[tagOS2]; len(os2) >= 78 {
+		face.instanceOS2(os2, fvar, hMetrics)
+	}
+	if post := tables[tagPost]; len(post) >= 12 {
+		face.instancePost(post, fvar)
+	}
+	if _, ok := tables[tagCvt]; ok {
+		values, _ := face.ControlValues()
+		cvt := make([]byte, 2*len(values))
+		for i, v := range values {
+			binary.BigEndian.PutUint16(cvt[2*i:], uint16(roundFUnit(v)))
+		}
+		tables[tagCvt] = cvt
+	}
+	if names, ok := face.NameTable(); ok {
+		tables[tagName] = face.instanceNames(names, fvar)
+	}
+	return writeSfnt(version, tables), nil
+}
+
+// instanceMetrics returns the 'hmtx' (or 'vmtx') table storing `metrics`,
+// and updates its header `header` accordingly, applying the 'MVAR' variations.
+func (face *Face) instanceMetrics(header []byte, metrics []longMetric, bounds []glyphBounds, vertical bool) []byte {
+	// the advance of the last long metric is used for the following glyphs
+	numMetrics := len(metrics)
+	for numMetrics > 1 && metrics[numMetrics-2].advance == metrics[numMetrics-1].advance {
+		numMetrics--
+	}
+	out := make([]byte, 0, 4*numMetrics+2*(len(metrics)-numMetrics))
+	for i, metric := range metrics {
+		if i < numMetrics {
+			out = appendUint16(out, metric.advance)
+		}
+		out = appendUint16(out, uint16(metric.sideBearing))
+	}
+	if len(header) < 36 {
+		return out
+	}
+
+	var maxAdvance uint16
+	minLeading, minTrailing, maxExtent := int16(math.MaxInt16), int16(math.MaxInt16), int16(math.MinInt16)
+	for gid, metric := range metrics {
+		if metric.advance > maxAdvance {
+			maxAdvance = metric.advance
+		}
+		if gid >= len(bounds) || bounds[gid].empty {
+			continue
+		}
+		size := bounds[gid].xMax - bounds[gid].xMin
+		if vertical {
+			size = bounds[gid].yMax - bounds[gid].yMin
+		}
+		leading, extent := metric.sideBearing, metric.sideBearing+size
+		minLeading, minTrailing = minInt16(minLeading, leading), minInt16(minTrailing, int16(metric.advance)-extent)
+		maxExtent = maxInt16(maxExtent, extent)
+	}
+	if maxExtent == math.MinInt16 { // no outlines
+		minLeading, minTrailing, maxExtent = 0, 0, 0
+	}
+
+	tags := [6]Tag{tagHorizontalAscender, tagHorizontalDescender, tagHorizontalLineGap, tagHorizontalCaretRise, tagHorizontalCaretRun, tagHorizontalCaretOff}
+	if vertical {
+		tags = [6]Tag{tagVerticalAscender, tagVerticalDescender, tagVerticalLineGap, tagVerticalCaretRise, tagVerticalCaretRun, tagVerticalCaretOff}
+	}
+	for i, offset := range [6]int{4, 6, 8, 18, 20, 22} {
+		face.varyInt16(header[offset:], tags[i])
+	}
+	binary.BigEndian.PutUint16(header[10:], maxAdvance)
+	binary.BigEndian.PutUint16(header[12:], uint16(minLeading))
+	binary.BigEndian.PutUint16(header[14:], uint16(minTrailing))
+	binary.BigEndian.PutUint16(header[16:], uint16(maxExtent))
+	binary.BigEndian.PutUint16(header[34:], uint16(numMetrics))
+	return out
+}
+
+// varyInt16 adds the 'MVAR' variation of `tag` to the FWORD stored in `data`.
+func (face *Face) varyInt16(data []byte, tag Tag) {
+	v := float32(int16(binary.BigEndian.Uint16(data)))
+	binary.BigEndian.PutUint16(data, uint16(roundFUnit(v+face.metricDelta(tag))))
+}
+
+// varyUint16 is the same as varyInt16, for an UFWORD.
+func (face *Face) varyUint16(data []byte, tag Tag) {
+	v := float64(binary.BigEndian.Uint16(data)) + float64(face.metricDelta(tag))
+	binary.BigEndian.PutUint16(data, uint16(math.Max(0, math.Min(math.MaxUint16, math.Floor(v+0.5)))))
+}
+
+// instanceVORG returns a 'VORG' table storing the varied vertical origins,
+// using the most frequent one as default.
+func (face *Face) instanceVORG() []byte {
+	origins := make([]int16, face.NumGlyphs)
+	counts := map[int16]int{}
+	for gid := range origins {
+		_, y, _ := face.VerticalOrigin(GID(gid))
+		origins[gid] = roundFUnit(y)
+		counts[origins[gid]]++
+	}
+	var defaultOrigin int16
+	for origin, count := range counts {
+		if count > counts[defaultOrigin] || count == counts[defaultOrigin] && origin < defaultOrigin {
+			defaultOrigin = origin
+		}
+	}
+	out := []byte{0, 1, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(out[4:], uint16(defaultOrigin))
+	var count uint16
+	for gid, origin := range origins {
+		if origin != defaultOrigin {
+			out = appendUint16(appendUint16(out, uint16(gid)), uint16(origin))
+			count++
+		}
+	}
+	binary.BigEndian.PutUint16(out[6:], count)
+	return out
+}
+
+// instanceHead updates the bounding box of the font, and clears its checksum adjustment.
+func instanceHead(head []byte, bounds []glyphBounds) {
+	fontBounds := glyphBounds{empty: true}
+	for _, b := range bounds {
+		switch {
+		case b.empty:
+		case fontBounds.empty:
+			fontBounds = b
+		default:
+			fontBounds.xMin, fontBounds.yMin = minInt16(fontBounds.xMin, b.xMin), minInt16(fontBounds.yMin, b.yMin)
+			fontBounds.xMax, fontBounds.yMax = maxInt16(fontBounds.xMax, b.xMax), maxInt16(fontBounds.yMax, b.yMax)
+		}
+	}
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[36:], uint16(fontBounds.xMin))
+	binary.BigEndian.PutUint16(head[38:], uint16(fontBounds.yMin))
+	binary.BigEndian.PutUint16(head[40:], uint16(fontBounds.xMax))
+	binary.BigEndian.PutUint16(head[42:], uint16(fontBounds.yMax))
+}
+
+// widthClasses are the widths, in percent of the normal width,
+// of the width classes of the 'OS/2' table.
+var widthClasses = [9]float32{50, 62.5, 75, 87.5, 100, 112.5, 125, 150, 200}
+
+// instanceOS2 applies the 'MVAR' variations to the 'OS/2' table, whose weight and width
+// classes are set from the 'wght' and 'wdth' axes, and average width recomputed.
+func (face *Face) instanceOS2(os2 []byte, fvar *TableFvar, metrics []longMetric) {
+	for _, field := range [...]struct {
+		offset int
+		tag    Tag
+	}{
+		{10, tagSubscriptXSize}, {12, tagSubscriptYSize}, {14, tagSubscriptXOffset}, {16, tagSubscriptYOffset},
+		{18, tagSuperscriptXSize}, {20, tagSuperscriptYSize}, {22, tagSuperscriptXOffset}, {24, tagSuperscriptYOffset},
+		{26, tagStrikeoutSize}, {28, tagStrikeoutOffset},
+		{68, tagHorizontalAscender}, {70, tagHorizontalDescender}, {72, tagHorizontalLineGap},
+		{86, tagXHeight}, {88, tagCapHeight},
+	} {
+		if field.offset+2 <= len(os2) {
+			face.varyInt16(os2[field.offset:], field.tag)
+		}
+	}
+	face.varyUint16(os2[74:], tagClippingAscent)
+	face.varyUint16(os2[76:], tagClippingDescent)
+
+	user := face.DenormalizeCoordinates(face.coords)
+	if i, ok := fvar.FindAxis(tagWght); ok {
+		weight := math.Max(1, math.Min(1000, math.Round(float64(user[i]))))
+		binary.BigEndian.PutUint16(os2[4:], uint16(weight))
+	}
+	if i, ok := fvar.FindAxis(tagWdth); ok {
+		class := 0
+		for c, width := range widthClasses {
+			if abs(width-user[i]) < abs(widthClasses[class]-user[i]) {
+				class = c
+			}
+		}
+		binary.BigEndian.PutUint16(os2[6:], uint16(class+1))
+	}
+
+	if version := binary.BigEndian.Uint16(os2); version >= 3 {
+		// average of the non zero advances
+		var sum, count int
+		for _, metric := range metrics {
+			if metric.advance != 0 {
+				sum += int(metric.advance)
+				count++
+			}
+		}
+		if count != 0 {
+			binary.BigEndian.PutUint16(os2[2:], uint16((sum+count/2)/count))
+		}
+	}
+}
+
+// instancePost applies the 'MVAR' variations to the underline metrics
+// of the 'post' table, and sets its italic angle from the 'slnt' axis.
+func (face *Face) instancePost(post []byte, fvar *TableFvar) {
+	if i, ok := fvar.FindAxis(tagSlnt); ok {
+		user := face.DenormalizeCoordinates(face.coords)
+		binary.BigEndian.PutUint32(post[4:], uint32(int32(math.Round(float64(user[i])*(1<<16)))))
+	}
+	face.varyInt16(post[8:], tagUnderlineOffset)
+	face.varyInt16(post[10:], tagUnderlineSize)
+}
+
+// ribbiStyles are the subfamilies of the style linking groups.
+var ribbiStyles = map[string]bool{"Regular": true, "Italic": true, "Bold": true, "Bold Italic": true}
+
+// instanceNames returns the 'name' table of the instance, replacing the family,
+// subfamily, unique, full and PostScript names by English Windows names,
+// and removing the variations PostScript name prefix.
+// As for VariationPostScriptName, the subfamily is the one of the matching named
+// instance, or the style name given by the 'STAT' table, or a description of
+// the coordinates, such as "700wght 75wdth".
+// The subfamilies outside of the style linking groups are kept in the typographic
+// names, the legacy family and subfamily being "<family> <subfamily>" and "Regular",
+// or "<family> <subfamily without Italic>" and "Italic" for the italic styles.
+func (face *Face) instanceNames(names *TableName, fvar *TableFvar) []byte {
+	subfamily := face.instanceStyle(fvar, face.currentNamedInstance(fvar))
+	if subfamily == "" {
+		subfamily = strings.Join(face.axisLabels(fvar), " ")
+	}
+	if subfamily == "" { // the default instance
+		var ok bool
+		if subfamily, ok = face.Name(NameTypographicSubfamily); !ok {
+			subfamily, _ = face.Name(NameSubfamily)
+		}
+	}
+	if subfamily == "" {
+		subfamily = "Regular"
+	}
+	family, ok := face.Name(NameTypographicFamily)
+	if !ok {
+		family, _ = face.Name(NameFamily)
+	}
+
+	updated := map[NameID]string{
+		NameFamily:           family,
+		NameSubfamily:        subfamily,
+		NameFull:             family + " " + subfamily,
+		NamePostScript:       face.VariationPostScriptName(),
+		NameUniqueIdentifier: face.VariationPostScriptName(),
+	}
+	if !ribbiStyles[subfamily] {
+		legacy := "Regular"
+		words := strings.Fields(subfamily)
+		for i, word := range words {
+			if word == "Italic" {
+				legacy = "Italic"
+				words = append(words[:i], words[i+1:]...)
+				break
+			}
+		}
+		updated[NameFamily], updated[NameSubfamily] = strings.Join(append([]string{family}, words...), " "), legacy
+		updated[NameTypographicFamily], updated[NameTypographicSubfamily] = family, subfamily
+	}
+	records := make([]NameRecord, 0, len(names.Records)+len(updated))
+	for _, r := range names.Records {
+		switch r.Name {
+		case NameFamily, NameSubfamily, NameUniqueIdentifier, NameFull, NamePostScript,
+			NameTypographicFamily, NameTypographicSubfamily, NameVariationsPostScriptPrefix:
+			continue
+		}
+		records = append(records, r)
+	}
+	for name, value := range updated {
+		records = append(records, NameRecord{
+			Platform: PlatformMicrosoft,
+			Encoding: PEMicrosoftUnicodeCs,
+			Language: LanguageMicrosoftEnglish,
+			Name:     name,
+			Value:    encodeUTF16BE(value),
+		})
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		ri, rj := records[i], records[j]
+		if ri.Platform != rj.Platform {
+			return ri.Platform < rj.Platform
+		}
+		if ri.Encoding != rj.Encoding {
+			return ri.Encoding < rj.Encoding
+		}
+		if ri.Language != rj.Language {
+			return ri.Language < rj.Language
+		}
+		return ri.Name < rj.Name
+	})
+	return TableName{Records: records, LanguageTags: names.LanguageTags}.encode()
+}
+
+func encodeUTF16BE(s string) []byte {
+	chars := utf16.Encode([]rune(s))
+	out := make([]byte, 2*len(chars))
+	for i, c := range chars {
+		binary.BigEndian.PutUint16(out[2*i:], c)
+	}
+	return out
+}
+
+// encode returns the binary content of the 'name' table.
+func (t TableName) encode() []byte {
+	var version uint16
+	headerSize := 6 + 12*len(t.Records)
+	if len(t.LanguageTags) != 0 {
+		version = 1
+		headerSize += 2 + 4*len(t.LanguageTags)
+	}
+	out := make([]byte, 6, headerSize)
+	binary.BigEndian.PutUint16(out, version)
+	binary.BigEndian.PutUint16(out[2:], uint16(len(t.Records)))
+	binary.BigEndian.PutUint16(out[4:], uint16(headerSize))
+	var storage []byte
+	appendString := func(value []byte) {
+		out = appendUint16(appendUint16(out, uint16(len(value))), uint16(len(storage)))
+		storage = append(storage, value...)
+	}
+	for _, r := range t.Records {
+		out = appendUint16(appendUint16(out, uint16(r.Platform)), uint16(r.Encoding))
+		out = appendUint16(appendUint16(out, uint16(r.Language)), uint16(r.Name))
+		appendString(r.Value)
+	}
+	if version == 1 {
+		out = appendUint16(out, uint16(len(t.LanguageTags)))
+		for _, tag := range t.LanguageTags {
+			appendString(encodeUTF16BE(tag))
+		}
+	}
+	return append(out, storage...)
+}
+
+// writeSfnt returns a font file storing `tables`, whose
+// 'head' checksum adjustment must be zero.
+func writeSfnt(version uint32, tables map[Tag][]byte) []byte {
+	tags := make([]Tag, 0, len(tables))
+	size := 12 + 16*len(tables)
+	for tag, data := range tables {
+		tags = append(tags, tag)
+		size += (len(data) + 3) &^ 3
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= len(tags) {
+		searchRange *= 2
+		entrySelector++
+	}
+	out := make([]byte, 12+16*len(tags), size)
+	binary.BigEndian.PutUint32(out, version)
+	binary.BigEndian.PutUint16(out[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(out[6:], uint16(16*searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(16*(len(tags)-searchRange)))
+	headOffset := -1
+	for i, tag := range tags {
+		data := tables[tag]
+		record := out[12+16*i:]
+		binary.BigEndian.PutUint32(record, uint32(tag))
+		binary.BigEndian.PutUint32(record[4:], checksum(data))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(data)))
+		if tag == tagHead {
+			headOffset = len(out)
+		}
+		out = append(out, data...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	if headOffset >= 0 && len(tables[tagHead]) >= 12 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-checksum(out))
+	}
+	return out
+}
+
+// checksum returns the checksum of a table, padded with zeros.
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for len(data) >= 4 {
+		sum += binary.BigEndian.Uint32(data)
+		data = data[4:]
+	}
+	var last [4]byte
+	copy(last[:], data)
+	return sum + binary.BigEndian.Uint32(last[:])
+}
@@ -0,0 +1,164 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/go-text/font"
+)
+
+// This file writes the 'CFF2' table of the static instances.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cff2
+
+// charstring and DICT operators
+const (
+	csRLineTo   = 5
+	csRRCurveTo = 8
+	csRMoveTo   = 21
+
+	dictCharStrings = 17
+	dictPrivate     = 18
+	dictEscape      = 12
+	dictFDArray     = 36 // escaped
+)
+
+// instanceCFF2 returns a 'CFF2' table whose charstrings draw the varied
+// outlines, without variations, hints nor subroutines, and the bounds of the glyphs.
+// The points of the outlines are rounded.
+func (face *Face) instanceCFF2() ([]byte, []glyphBounds, error) {
+	cff := face.outlines.cff
+	charstrings := make([][]byte, cff.NumGlyphs())
+	bounds := make([]glyphBounds, len(charstrings))
+	for gid := range charstrings {
+		outline, ok := cff.GlyphOutline(GID(gid))
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid glyph %d", gid)
+		}
+		charstrings[gid], bounds[gid] = encodeCharstring(outline.Segments)
+		if extents, ok := cff.GlyphExtents(GID(gid)); ok && !bounds[gid].empty {
+			// the bounding box of the curves, not of their control points
+			bounds[gid] = glyphBounds{
+				xMin: roundFUnit(extents.XBearing),
+				yMin: roundFUnit(extents.YBearing + extents.Height),
+				xMax: roundFUnit(extents.XBearing + extents.Width),
+				yMax: roundFUnit(extents.YBearing),
+			}
+		}
+	}
+
+	// the offsets of the DICTs are always encoded on 5 bytes,
+	// so that the layout does not depend on their values
+	const (
+		headerSize    = 5
+		topDictSize   = 5 + 1 + 5 + 2
+		emptyIndex    = 4
+		fontDictSize  = 5 + 5 + 1
+		charstringsAt = headerSize + topDictSize + emptyIndex
+	)
+	charstringsIndex := appendIndex(nil, charstrings)
+	fdArrayAt := charstringsAt + len(charstringsIndex)
+	privateAt := fdArrayAt + len(appendIndex(nil, [][]byte{make([]byte, fontDictSize)}))
+
+	out := []byte{2, 0, headerSize, 0, topDictSize}
+	out = appendDictInt(out, int32(charstringsAt))
+	out = append(out, dictCharStrings)
+	out = appendDictInt(out, int32(fdArrayAt))
+	out = append(out, dictEscape, dictFDArray)
+	out = append(out, 0, 0, 0, 0) // global subroutines
+	out = append(out, charstringsIndex...)
+	fontDict := appendDictInt(appendDictInt(nil, 0), int32(privateAt)) // empty Private DICT
+	fontDict = append(fontDict, dictPrivate)
+	out = appendIndex(out, [][]byte{fontDict})
+	return out, bounds, nil
+}
+
+// encodeCharstring returns the charstring drawing `segments`, with
+// rounded coordinates, and the bounds of its points.
+func encodeCharstring(segments []font.Segment) ([]byte, glyphBounds) {
+	var (
+		out        []byte
+		bounds     = glyphBounds{empty: true}
+		currentX   int16
+		currentY   int16
+		appendArgs = func(args []font.SegmentPoint) {
+			for _, arg := range args {
+				x, y := roundFUnit(arg.X), roundFUnit(arg.Y)
+				out = appendCharstringInt(appendCharstringInt(out, int32(x)-int32(currentX)), int32(y)-int32(currentY))
+				currentX, currentY = x, y
+				if bounds.empty {
+					bounds = glyphBounds{xMin: x, yMin: y, xMax: x, yMax: y}
+					continue
+				}
+				bounds.xMin, bounds.xMax = minInt16(bounds.xMin, x), maxInt16(bounds.xMax, x)
+				bounds.yMin, bounds.yMax = minInt16(bounds.yMin, y), maxInt16(bounds.yMax, y)
+			}
+		}
+	)
+	for _, seg := range segments {
+		switch seg.Op {
+		case font.SegmentOpMoveTo:
+			appendArgs(seg.Args[:1])
+			out = append(out, csRMoveTo)
+		case font.SegmentOpLineTo:
+			appendArgs(seg.Args[:1])
+			out = append(out, csRLineTo)
+		case font.SegmentOpCubeTo:
+			appendArgs(seg.Args[:3])
+			out = append(out, csRRCurveTo)
+		}
+	}
+	return out, bounds
+}
+
+// appendCharstringInt appends the charstring encoding of `v`.
+func appendCharstringInt(b []byte, v int32) []byte {
+	switch {
+	case -107 <= v && v <= 107:
+		return append(b, byte(v+139))
+	case 108 <= v && v <= 1131:
+		v -= 108
+		return append(b, byte(v>>8+247), byte(v))
+	case -1131 <= v && v <= -108:
+		v = -v - 108
+		return append(b, byte(v>>8+251), byte(v))
+	case math.MinInt16 <= v && v <= math.MaxInt16:
+		return append(b, 28, byte(v>>8), byte(v))
+	default: // 16.16 fixed number
+		return append(b, 255, byte(v>>8), byte(v), 0, 0)
+	}
+}
+
+// appendDictInt appends the 5 bytes DICT encoding of `v`.
+func appendDictInt(b []byte, v int32) []byte {
+	return append(b, 29, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendIndex appends the CFF2 INDEX storing `objects`.
+func appendIndex(b []byte, objects [][]byte) []byte {
+	b = append(b, byte(len(objects)>>24), byte(len(objects)>>16), byte(len(objects)>>8), byte(len(objects)))
+	if len(objects) == 0 {
+		return b
+	}
+	end := 1
+	for _, object := range objects {
+		end += len(object)
+	}
+	offSize := 1
+	for ; offSize < 4 && end>>(8*offSize) != 0; offSize++ {
+	}
+	b = append(b, byte(offSize))
+	offset := 1
+	var buf [4]byte
+	for i := 0; i <= len(objects); i++ {
+		binary.BigEndian.PutUint32(buf[:], uint32(offset))
+		b = append(b, buf[4-offSize:]...)
+		if i < len(objects) {
+			offset += len(objects[i])
+		}
+	}
+	for _, object := range objects {
+		b = append(b, object...)
+	}
+	return b
+}
@@ -0,0 +1,299 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// This file writes the 'glyf' and 'loca' tables of the static instances.
+
+// glyphBounds is the bounding box of a glyph, in font units.
+type glyphBounds struct {
+	xMin, yMin, xMax, yMax int16
+	empty                  bool // the glyph has no outline
+}
+
+// instancedGlyf stores the varied glyphs of a TrueType font.
+type instancedGlyf struct {
+	glyf, loca []byte
+	longLoca   bool
+	bounds     []glyphBounds
+	phantoms   [][phantomCount]contourPoint // rounded
+}
+
+// roundFUnit rounds `v` to the nearest integer, clamped to the int16 range.
+func roundFUnit(v float32) int16 {
+	r := math.Floor(float64(v) + 0.5)
+	switch {
+	case r > math.MaxInt16:
+		return math.MaxInt16
+	case r < math.MinInt16:
+		return math.MinInt16
+	}
+	return int16(r)
+}
+
+// instanceGlyf applies the variations to the glyphs of the 'glyf' table, rounding
+// the varied points and component offsets, and keeping the hinting instructions.
+func (face *Face) instanceGlyf() (instancedGlyf, error) {
+	out := instancedGlyf{
+		bounds:   make([]glyphBounds, face.NumGlyphs),
+		phantoms: make([][phantomCount]contourPoint, face.NumGlyphs),
+	}
+	offsets := make([]uint32, face.NumGlyphs+1)
+	for gid := range out.bounds {
+		glyph, err := face.instanceGlyph(GID(gid), &out)
+		if err != nil {
+			return out, err
+		}
+		out.glyf = append(out.glyf, glyph...)
+		if len(out.glyf)%2 != 0 {
+			out.glyf = append(out.glyf, 0)
+		}
+		offsets[gid+1] = uint32(len(out.glyf))
+	}
+
+	out.longLoca = len(out.glyf) > 2*math.MaxUint16
+	if out.longLoca {
+		out.loca = make([]byte, 4*len(offsets))
+		for i, offset := range offsets {
+			binary.BigEndian.PutUint32(out.loca[4*i:], offset)
+		}
+	} else {
+		out.loca = make([]byte, 2*len(offsets))
+		for i, offset := range offsets {
+			binary.BigEndian.PutUint16(out.loca[2*i:], uint16(offset/2))
+		}
+	}
+	return out, nil
+}
+
+func roundPhantoms(phantoms [phantomCount]contourPoint) [phantomCount]contourPoint {
+	for i, p := range phantoms {
+		phantoms[i].x, phantoms[i].y = float32(roundFUnit(p.x)), float32(roundFUnit(p.y))
+	}
+	return phantoms
+}
+
+// instanceGlyph returns the varied description of the glyph,
+// and stores its bounds and phantom points in `out`.
+func (face *Face) instanceGlyph(gid GID, out *instancedGlyf) ([]byte, error) {
+	data, ok := face.glyphData(gid)
+	if !ok {
+		return nil, fmt.Errorf("invalid glyph %d", gid)
+	}
+	if len(data) == 0 { // no outline
+		phantoms := face.phantomPoints(gid, 0, 0)
+		face.varyGlyfPoints(gid, nil, phantoms[:], nil)
+		out.bounds[gid] = glyphBounds{empty: true}
+		out.phantoms[gid] = roundPhantoms(phantoms)
+		return nil, nil
+	}
+	if len(data) < 10 {
+		return nil, fmt.Errorf("invalid glyph %d (EOF)", gid)
+	}
+	xMin, yMax := int16(binary.BigEndian.Uint16(data[2:])), int16(binary.BigEndian.Uint16(data[8:]))
+	phantoms := face.phantomPoints(gid, xMin, yMax)
+	numContours := int(int16(binary.BigEndian.Uint16(data)))
+	if numContours < 0 {
+		glyph, err := face.instanceCompositeGlyph(gid, data, &phantoms, out)
+		out.phantoms[gid] = roundPhantoms(phantoms)
+		return glyph, err
+	}
+
+	var points glyfPoints
+	if err := appendSimpleGlyph(data[10:], numContours, &points); err != nil {
+		return nil, err
+	}
+	face.varyGlyfPoints(gid, points.points, phantoms[:], points.ends)
+	out.phantoms[gid] = roundPhantoms(phantoms)
+
+	// the lengths have been checked by appendSimpleGlyph
+	instructionsStart := 10 + 2*numContours + 2
+	instructions := data[instructionsStart : instructionsStart+int(binary.BigEndian.Uint16(data[instructionsStart-2:]))]
+	var overlap byte
+	if len(points.points) != 0 {
+		overlap = data[instructionsStart+len(instructions)] & flagOverlapSimple
+	}
+	glyph, bounds := encodeSimpleGlyph(points, instructions, overlap)
+	out.bounds[gid] = bounds
+	return glyph, nil
+}
+
+// flagOverlapSimple is the flag of the first point
+// of the simple glyphs with overlapping contours.
+const flagOverlapSimple = 0x40
+
+// encodeSimpleGlyph returns the description of the simple glyph, of points
+// `glyph`, which are rounded, and its bounding box.
+// `overlap` is added to the flag of the first point.
+func encodeSimpleGlyph(glyph glyfPoints, instructions []byte, overlap byte) ([]byte, glyphBounds) {
+	bounds := glyphBounds{empty: true}
+	xs, ys := make([]int16, len(glyph.points)), make([]int16, len(glyph.points))
+	for i, p := range glyph.points {
+		xs[i], ys[i] = roundFUnit(p.x), roundFUnit(p.y)
+		if i == 0 {
+			bounds = glyphBounds{xMin: xs[i], yMin: ys[i], xMax: xs[i], yMax: ys[i]}
+			continue
+		}
+		bounds.xMin, bounds.xMax = minInt16(bounds.xMin, xs[i]), maxInt16(bounds.xMax, xs[i])
+		bounds.yMin, bounds.yMax = minInt16(bounds.yMin, ys[i]), maxInt16(bounds.yMax, ys[i])
+	}
+
+	out := make([]byte, 10, 10+2*len(glyph.ends)+2+len(instructions)+5*len(xs))
+	binary.BigEndian.PutUint16(out, uint16(len(glyph.ends)))
+	binary.BigEndian.PutUint16(out[2:], uint16(bounds.xMin))
+	binary.BigEndian.PutUint16(out[4:], uint16(bounds.yMin))
+	binary.BigEndian.PutUint16(out[6:], uint16(bounds.xMax))
+	binary.BigEndian.PutUint16(out[8:], uint16(bounds.yMax))
+	for _, end := range glyph.ends {
+		out = appendUint16(out, uint16(end))
+	}
+	out = appendUint16(out, uint16(len(instructions)))
+	out = append(out, instructions...)
+
+	flags := make([]byte, len(xs))
+	var xData, yData []byte
+	encode := func(data []byte, delta int16, short, sameOrPositive byte) ([]byte, byte) {
+		switch {
+		case delta == 0:
+			return data, sameOrPositive
+		case -255 <= delta && delta < 0:
+			return append(data, byte(-delta)), short
+		case 0 < delta && delta <= 255:
+			return append(data, byte(delta)), short | sameOrPositive
+		default:
+			return appendUint16(data, uint16(delta)), 0
+		}
+	}
+	var prevX, prevY int16
+	for i := range xs {
+		var xFlag, yFlag byte
+		xData, xFlag = encode(xData, xs[i]-prevX, flagXShort, flagXSameOrPositive)
+		yData, yFlag = encode(yData, ys[i]-prevY, flagYShort, flagYSameOrPositive)
+		flags[i] = xFlag | yFlag
+		if glyph.points[i].onCurve {
+			flags[i] |= flagOnCurve
+		}
+		prevX, prevY = xs[i], ys[i]
+	}
+	if len(flags) != 0 {
+		flags[0] |= overlap
+	}
+
+	// compress the repeated flags
+	for i := 0; i < len(flags); {
+		repeat := 0
+		for i+1+repeat < len(flags) && flags[i+1+repeat] == flags[i] && repeat < 255 {
+			repeat++
+		}
+		if repeat > 1 {
+			out = append(out, flags[i]|flagRepeat, byte(repeat))
+			i += 1 + repeat
+		} else {
+			out = append(out, flags[i])
+			i++
+		}
+	}
+	out = append(append(out, xData...), yData...)
+	return out, bounds
+}
+
+// instanceCompositeGlyph returns the description of the composite glyph, whose
+// component offsets are varied and rounded, and stores its bounds in `out`.
+func (face *Face) instanceCompositeGlyph(gid GID, data []byte, phantoms *[phantomCount]contourPoint, out *instancedGlyf) ([]byte, error) {
+	components, rest, err := parseGlyfComponents(data[10:])
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]contourPoint, len(components))
+	for i, comp := range components {
+		if comp.flags&flagArgsAreXYValues != 0 {
+			offsets[i] = contourPoint{x: float32(comp.arg1), y: float32(comp.arg2)}
+		}
+	}
+	scratch := *phantoms
+	face.varyGlyfPoints(gid, offsets, scratch[:], nil)
+
+	// the bounds are the ones of the resolved, varied points, and the
+	// phantom points may be the ones of a component (see flagUseMyMetrics)
+	var points glyfPoints
+	if *phantoms, err = face.appendGlyfPoints(gid, 0, &points); err != nil {
+		return nil, err
+	}
+	bounds := glyphBounds{empty: true}
+	for i, p := range points.points {
+		x, y := roundFUnit(p.x), roundFUnit(p.y)
+		if i == 0 {
+			bounds = glyphBounds{xMin: x, yMin: y, xMax: x, yMax: y}
+			continue
+		}
+		bounds.xMin, bounds.xMax = minInt16(bounds.xMin, x), maxInt16(bounds.xMax, x)
+		bounds.yMin, bounds.yMax = minInt16(bounds.yMin, y), maxInt16(bounds.yMax, y)
+	}
+	out.bounds[gid] = bounds
+
+	glyph := make([]byte, 10, len(data))
+	binary.BigEndian.PutUint16(glyph, 0xFFFF)
+	binary.BigEndian.PutUint16(glyph[2:], uint16(bounds.xMin))
+	binary.BigEndian.PutUint16(glyph[4:], uint16(bounds.yMin))
+	binary.BigEndian.PutUint16(glyph[6:], uint16(bounds.xMax))
+	binary.BigEndian.PutUint16(glyph[8:], uint16(bounds.yMax))
+	for i, comp := range components {
+		flags := comp.flags &^ flagArg1And2AreWords
+		arg1, arg2 := comp.arg1, comp.arg2
+		words := arg1 > math.MaxUint8 || arg2 > math.MaxUint8
+		if comp.flags&flagArgsAreXYValues != 0 {
+			arg1, arg2 = int32(roundFUnit(offsets[i].x)), int32(roundFUnit(offsets[i].y))
+			words = arg1 < math.MinInt8 || arg1 > math.MaxInt8 || arg2 < math.MinInt8 || arg2 > math.MaxInt8
+		}
+		if words {
+			flags |= flagArg1And2AreWords
+		}
+		glyph = appendUint16(glyph, flags)
+		glyph = appendUint16(glyph, uint16(comp.gid))
+		if words {
+			glyph = appendUint16(appendUint16(glyph, uint16(arg1)), uint16(arg2))
+		} else {
+			glyph = append(glyph, byte(arg1), byte(arg2))
+		}
+		switch {
+		case flags&flagWeHaveAScale != 0:
+			glyph = appendF2dot14(glyph, comp.a)
+		case flags&flagWeHaveAnXAndYScale != 0:
+			glyph = appendF2dot14(appendF2dot14(glyph, comp.a), comp.d)
+		case flags&flagWeHaveATwoByTwo != 0:
+			glyph = appendF2dot14(appendF2dot14(glyph, comp.a), comp.b)
+			glyph = appendF2dot14(appendF2dot14(glyph, comp.c), comp.d)
+		}
+	}
+	if last := components[len(components)-1]; last.flags&flagWeHaveInstructions != 0 {
+		if len(rest) < 2 || len(rest) < 2+int(binary.BigEndian.Uint16(rest)) {
+			return nil, errors.New("invalid composite glyph instructions (EOF)")
+		}
+		glyph = append(glyph, rest[:2+int(binary.BigEndian.Uint16(rest))]...)
+	}
+	return glyph, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte { return append(b, byte(v>>8), byte(v)) }
+
+func appendF2dot14(b []byte, v float32) []byte {
+	return appendUint16(b, uint16(int16(math.Round(float64(v)*(1<<14)))))
+}
+
+func minInt16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,259 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// LookupFlag qualifies the glyphs processed by a lookup of the 'GSUB' and 'GPOS' tables.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#lookup-table
+type LookupFlag uint16
+
+const (
+	// RightToLeft is only used by cursive attachment lookups,
+	// and indicates that the last glyph of the sequence is on the baseline.
+	RightToLeft LookupFlag = 1 << iota
+	// IgnoreBaseGlyphs skips the base glyphs (see GlyphClassBase).
+	IgnoreBaseGlyphs
+	// IgnoreLigatures skips the ligatures (see GlyphClassLigature).
+	IgnoreLigatures
+	// IgnoreMarks skips all the marks (see GlyphClassMark).
+	IgnoreMarks
+	// UseMarkFilteringSet skips the marks not belonging to the mark
+	// glyph set given by the lookup (see TableGDEF.MarkGlyphSets).
+	UseMarkFilteringSet
+
+	// MarkAttachmentType is a mask whose value, if not zero, skips the marks
+	// whose attachment class (see TableGDEF.MarkAttachClassDef) is different.
+	MarkAttachmentType LookupFlag = 0xFF00
+)
+
+// MarkAttachClass returns the mark attachment class selected by the flag,
+// or 0 if the flag does not filter the marks by attachment class.
+func (f LookupFlag) MarkAttachClass() uint16 { return uint16(f&MarkAttachmentType) >> 8 }
+
+// Coverage specifies the glyphs affected by a layout subtable,
+// associating each of them to an index.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#coverage-table
+type Coverage interface {
+	// Index returns the coverage index of the glyph, or false if
+	// the glyph is not covered.
+	Index(gid GID) (int, bool)
+	// Len returns the number of covered glyphs.
+	Len() int
+}
+
+// ClassDef assigns a class to glyphs, with all glyphs not assigned
+// to a class falling into class 0.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#class-definition-table
+type ClassDef interface {
+	// Class returns the class of the glyph, or false if
+	// the glyph is not assigned to a class.
+	Class(gid GID) (uint16, bool)
+}
+
+// coverageList is a coverage of format 1, listing the sorted glyphs.
+type coverageList []GID
+
+// coverageRange is a range of glyphs of a coverage of format 2.
+type coverageRange struct {
+	start, end GID
+	startIndex int // coverage index of `start`
+}
+
+type coverageRanges []coverageRange
+
+func parseCoverage(data []byte, offset uint32) (Coverage, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return nil, errors.New("invalid coverage table (EOF)")
+	}
+	data = data[offset:]
+	format := binary.BigEndian.Uint16(data)
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	switch format {
+	case 1:
+		if len(data) < 4+2*count {
+			return nil, errors.New("invalid coverage table (EOF)")
+		}
+		out := make(coverageList, count)
+		for i := range out {
+			out[i] = GID(binary.BigEndian.Uint16(data[4+2*i:]))
+		}
+		return out, nil
+	case 2:
+		if len(data) < 4+6*count {
+			return nil, errors.New("invalid coverage table (EOF)")
+		}
+		out := make(coverageRanges, count)
+		for i := range out {
+			record := data[4+6*i:]
+			out[i] = coverageRange{
+				start:      GID(binary.BigEndian.Uint16(record)),
+				end:        GID(binary.BigEndian.Uint16(record[2:])),
+				startIndex: int(binary.BigEndian.Uint16(record[4:])),
+			}
+			if out[i].start > out[i].end {
+				return nil, fmt.Errorf("invalid coverage range (%d > %d)", out[i].start, out[i].end)
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported coverage format %d", format)
+	}
+}
+
+func (cl coverageList) Index(gid GID) (int, bool) {
+	i := sort.Search(len(cl), func(i int) bool { return cl[i] >= gid })
+	if i < len(cl) && cl[i] == gid {
+		return i, true
+	}
+	return 0, false
+}
+
+func (cl coverageList) Len() int { return len(cl) }
+
+func (cr coverageRanges) Index(gid GID) (int, bool) {
+	i := sort.Search(len(cr), func(i int) bool { return cr[i].end >= gid })
+	if i < len(cr) && cr[i].start <= gid {
+		return cr[i].startIndex + int(gid-cr[i].start), true
+	}
+	return 0, false
+}
+
+func (cr coverageRanges) Len() int {
+	out := 0
+	for _, r := range cr {
+		out += int(r.end-r.start) + 1
+	}
+	return out
+}
+
+// classDefArray is a class definition of format 1, storing
+// the classes of consecutive glyphs.
+type classDefArray struct {
+	startGlyph GID
+	classes    []uint16
+}
+
+// classRange is a range of glyphs of a class definition of format 2.
+type classRange struct {
+	start, end GID
+	class      uint16
+}
+
+type classDefRanges []classRange
+
+func parseClassDef(data []byte, offset uint32) (ClassDef, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return nil, errors.New("invalid class definition table (EOF)")
+	}
+	data = data[offset:]
+	switch format := binary.BigEndian.Uint16(data); format {
+	case 1:
+		if len(data) < 6 {
+			return nil, errors.New("invalid class definition table (EOF)")
+		}
+		out := classDefArray{startGlyph: GID(binary.BigEndian.Uint16(data[2:]))}
+		count := int(binary.BigEndian.Uint16(data[4:]))
+		if len(data) < 6+2*count {
+			return nil, errors.New("invalid class definition table (EOF)")
+		}
+		out.classes = make([]uint16, count)
+		for i := range out.classes {
+			out.classes[i] = binary.BigEndian.Uint16(data[6+2*i:])
+		}
+		return out, nil
+	case 2:
+		count := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < 4+6*count {
+			return nil, errors.New("invalid class definition table (EOF)")
+		}
+		out := make(classDefRanges, count)
+		for i := range out {
+			record := data[4+6*i:]
+			out[i] = classRange{
+				start: GID(binary.BigEndian.Uint16(record)),
+				end:   GID(binary.BigEndian.Uint16(record[2:])),
+				class: binary.BigEndian.Uint16(record[4:]),
+			}
+			if out[i].start > out[i].end {
+				return nil, fmt.Errorf("invalid class definition range (%d > %d)", out[i].start, out[i].end)
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported class definition format %d", format)
+	}
+}
+
+func (c classDefArray) Class(gid GID) (uint16, bool) {
+	if gid < c.startGlyph || int(gid-c.startGlyph) >= len(c.classes) {
+		return 0, false
+	}
+	return c.classes[gid-c.startGlyph], true
+}
+
+func (c classDefRanges) Class(gid GID) (uint16, bool) {
+	i := sort.Search(len(c), func(i int) bool { return c[i].end >= gid })
+	if i < len(c) && c[i].start <= gid {
+		return c[i].class, true
+	}
+	return 0, false
+}
+
+// deviceTable stores the adjustments of a layout value, either in pixels
+// for a range of sizes (hinting), or as an index into the item variation
+// store of the 'GDEF' table (variable fonts).
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#device-and-variationindex-tables
+type deviceTable struct {
+	startSize uint16
+	deltas    []int8 // in pixels, for the sizes from startSize
+
+	isVariation  bool
+	outer, inner uint16 // valid if isVariation is true
+}
+
+func parseDeviceTable(data []byte, offset uint32) (deviceTable, error) {
+	if int64(offset)+6 > int64(len(data)) {
+		return deviceTable{}, errors.New("invalid device table (EOF)")
+	}
+	data = data[offset:]
+	first, last := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	format := binary.BigEndian.Uint16(data[4:])
+	if format == 0x8000 {
+		return deviceTable{isVariation: true, outer: first, inner: last}, nil
+	}
+	if format < 1 || format > 3 {
+		return deviceTable{}, fmt.Errorf("unsupported device table format %d", format)
+	}
+	if first > last {
+		return deviceTable{}, nil // no adjustment
+	}
+	bits := uint(1) << format // 2, 4 or 8 bits per value
+	count := int(last-first) + 1
+	perWord := 16 / int(bits)
+	if len(data) < 6+2*((count+perWord-1)/perWord) {
+		return deviceTable{}, errors.New("invalid device table (EOF)")
+	}
+	out := deviceTable{startSize: first, deltas: make([]int8, count)}
+	for i := range out.deltas {
+		word := binary.BigEndian.Uint16(data[6+2*(i/perWord):])
+		shift := 16 - bits*uint(i%perWord+1)
+		v := int16(word>>shift) & (1<<bits - 1)
+		if v >= 1<<(bits-1) { // sign extension
+			v -= 1 << bits
+		}
+		out.deltas[i] = int8(v)
+	}
+	return out, nil
+}
+
+// pixelDelta returns the hinting adjustment for the size `ppem`, in pixels.
+func (d deviceTable) pixelDelta(ppem uint16) int {
+	if ppem < d.startSize || int(ppem-d.startSize) >= len(d.deltas) {
+		return 0
+	}
+	return int(d.deltas[ppem-d.startSize])
+}
@@ -0,0 +1,481 @@
+package opentype
+
+import (
+	"sync"
+
+	"github.com/go-text/font"
+)
+
+// metrics stores the horizontal and vertical metrics of the glyphs,
+// and the font wide metrics, loaded on first use.
+type metrics struct {
+	once sync.Once
+
+	hhea, vhea *tableHhea // optional
+	hmtx, vmtx tableHmtx  // empty if missing
+	hvar, vvar *tableHVAR // optional
+	vorg       *tableVORG // optional
+
+	os2  *TableOS2  // optional
+	mvar *tableMVAR // optional
+}
+
+// loadMetrics parses the metrics tables on first use, reporting the
+// invalid tables in the warnings.
+func (face *Face) loadMetrics() *metrics {
+	m := &face.metrics
+	m.once.Do(func() {
+		var err error
+		if m.hhea, m.hmtx, err = face.parseHmtx(tagHhea, tagHmtx); err != nil && face.HasTable(tagHmtx) {
+			face.warnings.addError(err)
+		}
+		if m.vhea, m.vmtx, err = face.parseHmtx(tagVhea, tagVmtx); err != nil && face.HasTable(tagVmtx) {
+			face.warnings.addError(err)
+		}
+		m.hvar = face.parseHVAR(tagHVAR)
+		m.vvar = face.parseHVAR(tagVVAR)
+		if data, err := face.GetRawTable(tagVORG); err == nil {
+			if vorg, err := parseTableVORG(data); err != nil {
+				face.warnings.addError(face.tableError(tagVORG, err))
+			} else {
+				m.vorg = &vorg
+			}
+		}
+
+		if data, err := face.GetRawTable(tagOS2); err == nil {
+			if os2, err := parseTableOS2(data); err != nil {
+				face.warnings.addError(face.tableError(tagOS2, err))
+			} else {
+				m.os2 = &os2
+			}
+		}
+		if data, err := face.GetRawTable(tagMVAR); err == nil {
+			if mvar, err := parseTableMVAR(data); err != nil {
+				face.warnings.addError(face.tableError(tagMVAR, err))
+			} else {
+				m.mvar = &mvar
+			}
+		}
+	})
+	return m
+}
+
+// parseHmtx parses the 'hhea' and 'hmtx' tables, or the 'vhea' and 'vmtx' tables.
+// The header is returned even if the metrics table is missing.
+func (face *Face) parseHmtx(headerTag, metricsTag Tag) (*tableHhea, tableHmtx, error) {
+	data, err := face.GetRawTable(headerTag)
+	if err != nil {
+		return nil, tableHmtx{}, missingTable(headerTag)
+	}
+	header, err := parseTableHhea(data)
+	if err != nil {
+		return nil, tableHmtx{}, face.tableError(headerTag, err)
+	}
+	data, err = face.GetRawTable(metricsTag)
+	if err != nil {
+		return &header, tableHmtx{}, missingTable(metricsTag)
+	}
+	return &header, parseTableHmtx(data, header.numMetrics, face.NumGlyphs), nil
+}
+
+func (face *Face) parseHVAR(tag Tag) *tableHVAR {
+	data, err := face.GetRawTable(tag)
+	if err != nil {
+		return nil
+	}
+	out, err := parseTableHVAR(data, tag == tagVVAR)
+	if err != nil {
+		face.warnings.addError(face.tableError(tag, err))
+		return nil
+	}
+	return &out
+}
+
+// HorizontalAdvance returns the horizontal advance of the glyph, in font units,
+// given by the 'hmtx' table, and adjusted by the 'HVAR' table for variable fonts
+// (see SetVariations). For variable TrueType fonts without 'HVAR' table,
+// the advance is computed from the phantom points of the varied outline.
+// The varied advances are clamped to 0.
+// If the font has no 'hmtx' table, half the units per em is returned.
+// It returns 0 for invalid glyphs.
+func (face *Face) HorizontalAdvance(gid GID) float32 {
+	return face.advance(gid, false)
+}
+
+// VerticalAdvance is the same as HorizontalAdvance, for the 'vmtx' and 'VVAR' tables.
+// The returned value is negative, since the y axis grows up: fonts
+// without 'vmtx' table return the opposite of the units per em, or
+// of the font height if SetSynthesizedVerticalMetrics is enabled.
+func (face *Face) VerticalAdvance(gid GID) float32 {
+	if face.synthesizeVertical && int(gid) < face.NumGlyphs && len(face.loadMetrics().vmtx.metrics) == 0 {
+		extents, _ := face.FontHExtents()
+		return extents.Descender - extents.Ascender
+	}
+	return -face.advance(gid, true)
+}
+
+// SetSynthesizedVerticalMetrics enables or disables the synthesis of the vertical
+// metrics of fonts without 'vmtx' table, as done by the common shapers :
+// the vertical advance is the height of the font (its ascender minus its descender,
+// see FontHExtents), and the vertical origin is centered horizontally, at the
+// height of the ascender. It is disabled by default.
+func (face *Face) SetSynthesizedVerticalMetrics(enabled bool) {
+	face.synthesizeVertical = enabled
+}
+
+func (face *Face) advance(gid GID, vertical bool) float32 {
+	if int(gid) >= face.NumGlyphs {
+		return 0
+	}
+	m := face.loadMetrics()
+	table, variations := m.hmtx, m.hvar
+	if vertical {
+		table, variations = m.vmtx, m.vvar
+	}
+	if len(table.metrics) == 0 {
+		if vertical {
+			return float32(face.Upem())
+		}
+		return float32(face.Upem() / 2)
+	}
+	advance := float32(table.advance(gid))
+	if !face.isVariable() {
+		return advance
+	}
+	if variations != nil {
+		advance += variations.advanceDelta(gid, face.coords)
+	} else if phantoms, ok := face.variedPhantoms(gid); ok {
+		if vertical {
+			advance = phantoms[2].y - phantoms[3].y
+		} else {
+			advance = phantoms[1].x - phantoms[0].x
+		}
+	}
+	if advance < 0 { // varied advances are clamped
+		return 0
+	}
+	return advance
+}
+
+// variedPhantoms returns the phantom points of the glyph, with the
+// variations of the 'gvar' table applied, or false if the outlines
+// are not varied by a 'gvar' table.
+func (face *Face) variedPhantoms(gid GID) ([phantomCount]contourPoint, bool) {
+	if face.loadOutlines() != nil || face.gvarTable() == nil {
+		return [phantomCount]contourPoint{}, false
+	}
+	var points glyfPoints
+	phantoms, err := face.appendGlyfPoints(gid, 0, &points)
+	return phantoms, err == nil
+}
+
+// LeftSideBearing returns the distance between the horizontal origin of the glyph
+// and the left of its bounding box, in font units, given by the 'hmtx' table and
+// adjusted by the 'HVAR' table for variable fonts (see SetVariations).
+// For variable TrueType fonts whose 'HVAR' table provides no side bearings,
+// it is computed from the varied outline.
+// It returns false if the glyph is invalid or the font has no 'hmtx' table.
+func (face *Face) LeftSideBearing(gid GID) (float32, bool) {
+	if int(gid) >= face.NumGlyphs {
+		return 0, false
+	}
+	m := face.loadMetrics()
+	lsb, ok := m.hmtx.sideBearing(gid)
+	if !ok {
+		return 0, false
+	}
+	if !face.isVariable() {
+		return float32(lsb), true
+	}
+	if m.hvar != nil && m.hvar.hasSideBearings {
+		return float32(lsb) + m.hvar.sideBearingDelta(gid, face.coords), true
+	}
+	if face.loadOutlines() == nil && face.gvarTable() != nil {
+		if extents, ok := face.variedGlyfExtents(gid); ok {
+			return extents.XBearing, true
+		}
+	}
+	return float32(lsb), true
+}
+
+// TopSideBearing returns the distance between the vertical origin of the glyph
+// and the top of its bounding box, in font units, given by the 'vmtx' table and
+// adjusted by the 'VVAR' table for variable fonts (see SetVariations).
+// For variable TrueType fonts whose 'VVAR' table provides no side bearings,
+// it is computed from the phantom points of the varied outline.
+// It returns false if the glyph is invalid or the font has no 'vmtx' table.
+func (face *Face) TopSideBearing(gid GID) (float32, bool) {
+	if int(gid) >= face.NumGlyphs {
+		return 0, false
+	}
+	m := face.loadMetrics()
+	tsb, ok := m.vmtx.sideBearing(gid)
+	if !ok {
+		return 0, false
+	}
+	if !face.isVariable() {
+		return float32(tsb), true
+	}
+	if m.vvar != nil && m.vvar.hasSideBearings {
+		return float32(tsb) + m.vvar.sideBearingDelta(gid, face.coords), true
+	}
+	if phantoms, ok := face.variedPhantoms(gid); ok {
+		if extents, ok := face.variedGlyfExtents(gid); ok {
+			return phantoms[2].y - extents.YBearing, true
+		}
+	}
+	return float32(tsb), true
+}
+
+// VerticalOrigin returns the position of the origin used in vertical layout,
+// relative to the horizontal origin of the glyph, in font units.
+// The x coordinate is half the horizontal advance, and the y coordinate
+// is given by the 'VORG' table if present (adjusted by the 'VVAR' table
+// for variable fonts), or is the top of the glyph plus its top side bearing,
+// both following the variations of variable fonts (see TopSideBearing).
+// It returns false if the glyph is invalid or the font has no vertical metrics,
+// unless SetSynthesizedVerticalMetrics is enabled, in which case the ascender
+// of the font is used.
+func (face *Face) VerticalOrigin(gid GID) (x, y float32, ok bool) {
+	if int(gid) >= face.NumGlyphs {
+		return 0, 0, false
+	}
+	m := face.loadMetrics()
+	x = face.HorizontalAdvance(gid) / 2
+	if m.vorg != nil {
+		y = float32(m.vorg.origin(gid))
+		if m.vvar != nil && face.isVariable() {
+			y += m.vvar.originDelta(gid, face.coords)
+		}
+		return x, y, true
+	}
+	if tsb, ok := face.TopSideBearing(gid); ok {
+		if extents, ok := face.GlyphExtents(gid); ok {
+			return x, extents.YBearing + tsb, true
+		}
+	}
+	if !face.synthesizeVertical {
+		return 0, 0, false
+	}
+	extents, _ := face.FontHExtents()
+	return x, extents.Ascender, true
+}
+
+// MetricDelta returns the variation of the font wide metric identified by
+// the 'MVAR' value tag `tag` (such as 'hasc' for the horizontal ascender),
+// for the coordinates set by SetVariations.
+// It returns 0 if the font has no 'MVAR' table, or no variations for `tag`.
+func (face *Face) MetricDelta(tag Tag) float32 { return face.metricDelta(tag) }
+
+// metricDelta returns the variation of the font wide metric `tag`,
+// given by the 'MVAR' table.
+func (face *Face) metricDelta(tag Tag) float32 {
+	m := face.loadMetrics()
+	if m.mvar == nil || !face.isVariable() {
+		return 0
+	}
+	return m.mvar.delta(tag, face.coords)
+}
+
+// MVAR value tags
+var (
+	tagHorizontalAscender  = MustNewTag("hasc")
+	tagHorizontalDescender = MustNewTag("hdsc")
+	tagHorizontalLineGap   = MustNewTag("hlgp")
+	tagVerticalAscender    = MustNewTag("vasc")
+	tagVerticalDescender   = MustNewTag("vdsc")
+	tagVerticalLineGap     = MustNewTag("vlgp")
+	tagHorizontalCaretRise = MustNewTag("hcrs")
+	tagHorizontalCaretRun  = MustNewTag("hcrn")
+	tagHorizontalCaretOff  = MustNewTag("hcof")
+	tagVerticalCaretRise   = MustNewTag("vcrs")
+	tagVerticalCaretRun    = MustNewTag("vcrn")
+	tagVerticalCaretOff    = MustNewTag("vcof")
+	tagClippingAscent      = MustNewTag("hcla")
+	tagClippingDescent     = MustNewTag("hcld")
+	tagStrikeoutSize       = MustNewTag("strs")
+	tagStrikeoutOffset     = MustNewTag("stro")
+	tagUnderlineSize       = MustNewTag("unds")
+	tagUnderlineOffset     = MustNewTag("undo")
+	tagSuperscriptXSize    = MustNewTag("spxs")
+	tagSuperscriptYSize    = MustNewTag("spys")
+	tagSuperscriptXOffset  = MustNewTag("spxo")
+	tagSuperscriptYOffset  = MustNewTag("spyo")
+	tagSubscriptXSize      = MustNewTag("sbxs")
+	tagSubscriptYSize      = MustNewTag("sbys")
+	tagSubscriptYOffset    = MustNewTag("sbyo")
+	tagSubscriptXOffset    = MustNewTag("sbxo")
+	tagCapHeight           = MustNewTag("cpht")
+	tagXHeight             = MustNewTag("xhgt")
+)
+
+// LineMetric implements font.Face. The underline metrics are given by the 'post' table,
+// and the other ones by the 'OS/2' table, adjusted by the 'MVAR' table for variable fonts.
+// It returns false if the required table is missing.
+func (face *Face) LineMetric(metric font.LineMetric) (float32, bool) {
+	if metric == font.UnderlinePosition || metric == font.UnderlineThickness {
+		post := face.loadGlyphNames().post
+		if post.version == 0 { // missing or invalid table
+			return 0, false
+		}
+		if metric == font.UnderlinePosition {
+			return float32(post.underlinePosition) + face.metricDelta(tagUnderlineOffset), true
+		}
+		return float32(post.underlineThickness) + face.metricDelta(tagUnderlineSize), true
+	}
+
+	os2 := face.loadMetrics().os2
+	if os2 == nil {
+		return 0, false
+	}
+	var (
+		value int16
+		tag   Tag
+	)
+	switch metric {
+	case font.StrikethroughPosition:
+		value, tag = os2.StrikeoutPosition, tagStrikeoutOffset
+	case font.StrikethroughThickness:
+		value, tag = os2.StrikeoutSize, tagStrikeoutSize
+	case font.SuperscriptEmYSize:
+		value, tag = os2.SuperscriptYSize, tagSuperscriptYSize
+	case font.SuperscriptEmXOffset:
+		value, tag = os2.SuperscriptXOffset, tagSuperscriptXOffset
+	case font.SubscriptEmYSize:
+		value, tag = os2.SubscriptYSize, tagSubscriptYSize
+	case font.SubscriptEmYOffset:
+		value, tag = os2.SubscriptYOffset, tagSubscriptYOffset
+	case font.SubscriptEmXOffset:
+		value, tag = os2.SubscriptXOffset, tagSubscriptXOffset
+	default:
+		return 0, false
+	}
+	return float32(value) + face.metricDelta(tag), true
+}
+
+// ScriptMetrics describes the recommended size and position of the
+// subscripts (or superscripts), in font units.
+type ScriptMetrics struct {
+	XSize, YSize     float32
+	XOffset, YOffset float32 // the y offset of the subscripts is positive below the baseline
+}
+
+// Subscript returns the subscript metrics given by the 'OS/2' table,
+// adjusted by the 'MVAR' table for variable fonts, or false if the table is missing.
+func (face *Face) Subscript() (ScriptMetrics, bool) {
+	os2 := face.loadMetrics().os2
+	if os2 == nil {
+		return ScriptMetrics{}, false
+	}
+	return ScriptMetrics{
+		XSize:   float32(os2.SubscriptXSize) + face.metricDelta(tagSubscriptXSize),
+		YSize:   float32(os2.SubscriptYSize) + face.metricDelta(tagSubscriptYSize),
+		XOffset: float32(os2.SubscriptXOffset) + face.metricDelta(tagSubscriptXOffset),
+		YOffset: float32(os2.SubscriptYOffset) + face.metricDelta(tagSubscriptYOffset),
+	}, true
+}
+
+// Superscript is the same as Subscript, for the superscripts.
+func (face *Face) Superscript() (ScriptMetrics, bool) {
+	os2 := face.loadMetrics().os2
+	if os2 == nil {
+		return ScriptMetrics{}, false
+	}
+	return ScriptMetrics{
+		XSize:   float32(os2.SuperscriptXSize) + face.metricDelta(tagSuperscriptXSize),
+		YSize:   float32(os2.SuperscriptYSize) + face.metricDelta(tagSuperscriptYSize),
+		XOffset: float32(os2.SuperscriptXOffset) + face.metricDelta(tagSuperscriptXOffset),
+		YOffset: float32(os2.SuperscriptYOffset) + face.metricDelta(tagSuperscriptYOffset),
+	}, true
+}
+
+// Caret describes the slope of the caret, which is vertical (Rise = 1, Run = 0)
+// for upright fonts, and the offset to apply to its position, in font units.
+type Caret struct {
+	Rise, Run float32
+	Offset    float32
+}
+
+// Caret returns the caret given by the 'hhea' table (or the 'vhea' table
+// if `vertical` is true), adjusted by the 'MVAR' table for variable fonts,
+// or false if the table is missing.
+func (face *Face) Caret(vertical bool) (Caret, bool) {
+	m := face.loadMetrics()
+	header, rise, run, offset := m.hhea, tagHorizontalCaretRise, tagHorizontalCaretRun, tagHorizontalCaretOff
+	if vertical {
+		header, rise, run, offset = m.vhea, tagVerticalCaretRise, tagVerticalCaretRun, tagVerticalCaretOff
+	}
+	if header == nil {
+		return Caret{}, false
+	}
+	return Caret{
+		Rise:   float32(header.caretSlopeRise) + face.metricDelta(rise),
+		Run:    float32(header.caretSlopeRun) + face.metricDelta(run),
+		Offset: float32(header.caretOffset) + face.metricDelta(offset),
+	}, true
+}
+
+// DecorationMetrics describes a text decoration line, in font units.
+type DecorationMetrics struct {
+	// Position is the distance above the baseline of the top of the line,
+	// which is typically negative for underlines.
+	Position float32
+	// Thickness is the suggested thickness of the line.
+	Thickness float32
+}
+
+// Underline returns the underline metrics given by the 'post' table,
+// adjusted by the 'MVAR' table for variable fonts, or false if the table is missing.
+func (face *Face) Underline() (DecorationMetrics, bool) {
+	return face.decorationMetrics(font.UnderlinePosition, font.UnderlineThickness)
+}
+
+// Strikeout returns the strikeout metrics given by the 'OS/2' table,
+// adjusted by the 'MVAR' table for variable fonts, or false if the table is missing.
+func (face *Face) Strikeout() (DecorationMetrics, bool) {
+	return face.decorationMetrics(font.StrikethroughPosition, font.StrikethroughThickness)
+}
+
+func (face *Face) decorationMetrics(position, thickness font.LineMetric) (DecorationMetrics, bool) {
+	var (
+		out DecorationMetrics
+		ok  bool
+	)
+	out.Position, ok = face.LineMetric(position)
+	out.Thickness, _ = face.LineMetric(thickness)
+	return out, ok
+}
+
+// CapHeight returns the height of the capital letters above the baseline, in font units,
+// given by the 'OS/2' table (version 2 and later) and adjusted by the 'MVAR' table
+// for variable fonts.
+// If the value is missing, the top of the 'H' glyph is used instead, and false
+// is returned if the font does not support 'H'.
+func (face *Face) CapHeight() (float32, bool) {
+	return face.letterHeight(tagCapHeight, 'H')
+}
+
+// XHeight is the same as CapHeight, for the height of the lowercase letters,
+// using the 'x' glyph as fallback.
+func (face *Face) XHeight() (float32, bool) {
+	return face.letterHeight(tagXHeight, 'x')
+}
+
+func (face *Face) letterHeight(tag Tag, letter rune) (float32, bool) {
+	if os2 := face.loadMetrics().os2; os2 != nil {
+		value := os2.CapHeight
+		if tag == tagXHeight {
+			value = os2.XHeight
+		}
+		if value != 0 {
+			return float32(value) + face.metricDelta(tag), true
+		}
+	}
+	// measure the glyph
+	gid, ok := face.NominalGlyph(letter)
+	if !ok {
+		return 0, false
+	}
+	extents, ok := face.GlyphExtents(gid)
+	return extents.YBearing, ok
+}
@@ -0,0 +1,19 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package opentype
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile is not supported on this platform : the
+// file is read in memory instead.
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	_, err := io.ReadFull(file, data)
+	return data, err
+}
+
+func munmap([]byte) error { return nil }
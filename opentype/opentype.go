@@ -0,0 +1,434 @@
+// Package opentype provides support for OpenType and TrueType font files
+// (.ttf, .otf), as well as the web containers wrapping them.
+// Collections (.ttc, .otc) and Mac resource forks (.dfont) are
+// supported by ParseCollection.
+//
+// The tables are parsed on demand : only the few tables required to
+// build a Face ('head', 'maxp' and 'cmap') are loaded by ParseFont.
+// Large files may also be opened with LoadFont and LoadCollection, which
+// keep a io.ReaderAt and only read the table directory and these tables up front.
+//
+// Fonts embedded in the binary with go:embed may be loaded with
+// Parse and ParseCollectionBytes, or LoadFromFS and LoadCollectionFromFS.
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/go-text/font"
+	"github.com/go-text/font/opentype/eot"
+	"github.com/go-text/font/opentype/woff"
+	"github.com/go-text/font/opentype/woff2"
+)
+
+var (
+	_ font.Face          = (*Face)(nil)
+	_ font.GlyphExtenter = (*Face)(nil)
+	_ font.GlyphCounter  = (*Face)(nil)
+	_ font.GlyphOutliner = (*Face)(nil)
+)
+
+var (
+	errMissingTable = errors.New("missing table")
+
+	errUnsupportedFormat = errors.New("unsupported font format")
+)
+
+// Face is a font face loaded from an OpenType file.
+// The underlying table data is kept in memory (or read on demand, see LoadFont)
+// so that tables may be parsed lazily.
+// A Face may be used by several goroutines, except for the Set methods
+// (such as SetVariations), which must not be called concurrently with
+// the other methods.
+type Face struct {
+	src    source
+	tables map[Tag]tableSection // header only, contents is processed on demand
+
+	cmap         Cmap
+	cmapVar      UnicodeVariations
+	coverageOnce sync.Once
+	coverage     *font.RuneSet // built on demand
+
+	Head TableHead
+
+	// NumGlyphs exposes the number of glyph indexes present in the font.
+	NumGlyphs int
+
+	// Type represents the kind of glyphs in this font.
+	// It is one of TypeTrueType, TypeTrueTypeApple, TypePostScript1, TypeOpenType
+	Type Tag
+
+	outlines outlines // loaded on demand
+	metrics  metrics  // loaded on demand
+
+	glyphNames glyphNames  // loaded on demand
+	names      names       // loaded on demand
+	fvar       fvar        // loaded on demand
+	avar       avar        // loaded on demand
+	stat       stat        // loaded on demand
+	meta       meta        // loaded on demand
+	cvt        cvt         // loaded on demand
+	gdef       gdef        // loaded on demand
+	gsub       gsub        // loaded on demand
+	gpos       gpos        // loaded on demand
+	base       base        // loaded on demand
+	jstf       jstf        // loaded on demand
+	math       mathTable   // loaded on demand
+	kern       kern        // loaded on demand
+	kerx       kerx        // loaded on demand
+	morx       morx        // loaded on demand
+	trak       trak        // loaded on demand
+	feat       feat        // loaded on demand
+	ankr       ankr        // loaded on demand
+	bsln       bsln        // loaded on demand
+	opbd       opbd        // loaded on demand
+	prop       prop        // loaded on demand
+	zapf       zapf        // loaded on demand
+	lcar       lcar        // loaded on demand
+	color      colorTables // loaded on demand
+	bitmaps    bitmaps     // loaded on demand
+
+	coords []float32 // normalized variation coordinates, see SetVariations
+
+	synthesizeVertical bool // see SetSynthesizedVerticalMetrics
+
+	repairs  []string // see ParseOptions.Repair
+	warnings warnings
+
+	release func() error // memory mapping, see OpenFile
+}
+
+// tableSection represents a table within the font file.
+type tableSection struct {
+	offset   uint32 // Offset into the file this table starts.
+	length   uint32 // Length of this table within the file.
+	checksum uint32 // Checksum stored in the table directory.
+}
+
+// ParseFont reads an OpenType (.otf) or TrueType (.ttf) file and returns a Face.
+// WOFF and WOFF2 files are also accepted : their content is decompressed
+// (and the transformed tables are reconstructed) before parsing.
+// So are EOT files, as long as they are not compressed with MicroType Express.
+// Font collections are rejected : see ParseCollection.
+func ParseFont(file font.Resource) (*Face, error) {
+	return ParseFontWithOptions(file, ParseOptions{})
+}
+
+// LoadFont is the same as ParseFont, but only reads the table directory
+// and the tables required to build the Face up front : the other tables are
+// fetched from `file` when needed, so that `file` must stay valid (and unchanged)
+// while the Face is used. `size` is the size of the file, in bytes.
+// Web fonts must be decompressed, and are thus entirely loaded in memory.
+func LoadFont(file io.ReaderAt, size int64) (*Face, error) {
+	return loadFont(file, size, "LoadCollection")
+}
+
+func loadFont(file io.ReaderAt, size int64, collectionLoader string) (*Face, error) {
+	src, err := fileSource(file, size)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := src.read(0, 4)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSignature(signature, collectionLoader); err != nil {
+		return nil, err
+	}
+	return parseFace(src, 0, ParseOptions{})
+}
+
+// checkSignature returns an error if `signature` is not the one of a single font,
+// suggesting to use the `collectionLoader` function for collections.
+func checkSignature(signature []byte, collectionLoader string) error {
+	switch magic := Tag(binary.BigEndian.Uint32(signature)); magic {
+	case TypeTrueType, TypeOpenType, TypePostScript1, TypeAppleTrueType:
+		return nil
+	case TypeCollection, dfontResourceDataOffset:
+		return fmt.Errorf("unexpected font collection (use %s instead)", collectionLoader)
+	default:
+		return fmt.Errorf("%s (%s)", errUnsupportedFormat, magic)
+	}
+}
+
+// readSfnt reads the whole content of `file`, unwrapping web fonts,
+// and checks that at least a signature is present.
+func readSfnt(file font.Resource) ([]byte, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil { // file might have been used before
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return sfntData(data)
+}
+
+// sfntData unwraps web fonts, and checks that at least a signature is present.
+func sfntData(data []byte) ([]byte, error) {
+	data, err := unwrapWebFont(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, errUnsupportedFormat
+	}
+	return data, nil
+}
+
+// unwrapWebFont returns the sfnt content of WOFF, WOFF2 and EOT files,
+// or `data` for other formats.
+func unwrapWebFont(data []byte) ([]byte, error) {
+	switch {
+	case woff.IsWOFF(data):
+		return woff.Decode(data)
+	case woff2.IsWOFF2(data):
+		return woff2.Decode(data)
+	case eot.IsEOT(data):
+		_, sfnt, err := eot.Decode(data)
+		return sfnt, err
+	default:
+		return data, nil
+	}
+}
+
+// parseFace parses the table directory found at `offset`,
+// and loads the tables required for all fonts.
+// Table offsets are relative to the start of `src`.
+func parseFace(src source, offset uint32, opts ParseOptions) (*Face, error) {
+	if opts.Strict && opts.Repair {
+		return nil, errors.New("incompatible Strict and Repair options")
+	}
+	face := &Face{src: src}
+	var err error
+	if face.tables, face.Type, err = parseTableDirectory(src, offset, opts, &face.warnings); err != nil {
+		return nil, err
+	}
+	defer face.warnings.endLoading()
+
+	if opts.Repair {
+		if err = face.repair(opts); err != nil {
+			return nil, err
+		}
+		return face, nil
+	}
+
+	if err = face.loadNumGlyphs(); err != nil {
+		return nil, err
+	}
+	if err = face.loadHeadTable(); err != nil {
+		return nil, err
+	}
+	if !opts.OnlyMetadata {
+		if err = face.loadCmapTable(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Strict {
+		if err = face.validate(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return face, nil
+}
+
+const (
+	otfHeaderLength      = 12
+	directoryEntryLength = 16
+)
+
+// parseTableDirectory reads the offset table and the table records,
+// ignoring the tables skipped by `opts`.
+// In repair mode, tables exceeding the file are kept, and fixed by repairDirectory.
+func parseTableDirectory(src source, offset uint32, opts ParseOptions, w *warnings) (map[Tag]tableSection, Tag, error) {
+	header, err := src.read(offset, otfHeaderLength)
+	if err != nil {
+		return nil, 0, errors.New("invalid OpenType header (EOF)")
+	}
+	magic := Tag(binary.BigEndian.Uint32(header))
+	numTables := int(binary.BigEndian.Uint16(header[4:]))
+	header, err = src.read(offset, otfHeaderLength+directoryEntryLength*uint32(numTables))
+	if err != nil {
+		return nil, 0, errors.New("invalid OpenType table directory (EOF)")
+	}
+
+	tables := make(map[Tag]tableSection, numTables)
+	for i := 0; i < numTables; i++ {
+		entry := header[otfHeaderLength+directoryEntryLength*i:]
+		tag := Tag(binary.BigEndian.Uint32(entry))
+		sec := tableSection{
+			checksum: binary.BigEndian.Uint32(entry[4:]),
+			offset:   binary.BigEndian.Uint32(entry[8:]),
+			length:   binary.BigEndian.Uint32(entry[12:]),
+		}
+		if _, found := tables[tag]; found && opts.Strict {
+			return nil, 0, &TableError{Tag: tag, Offset: sec.offset, Err: errors.New("duplicate table")}
+		}
+		if _, found := tables[tag]; found {
+			// ignore duplicate tables – the first one wins
+			w.add("duplicate table '%s' at offset %d: ignored", tag, sec.offset)
+			continue
+		}
+		if opts.skip(tag) {
+			continue
+		}
+		if uint64(sec.offset)+uint64(sec.length) > src.size && !opts.Repair {
+			return nil, 0, &TableError{Tag: tag, Offset: sec.offset, Err: fmt.Errorf("length %d exceeds the end of the file", sec.length)}
+		}
+		tables[tag] = sec
+	}
+
+	return tables, magic, nil
+}
+
+// HasTable returns `true` if the font has the given table.
+func (face *Face) HasTable(tag Tag) bool {
+	_, has := face.tables[tag]
+	return has
+}
+
+// GetRawTable returns the binary content of the given table,
+// or an error if not found.
+// For faces built with LoadFont, the table is read from the file.
+// The returned slice must not be modified.
+func (face *Face) GetRawTable(tag Tag) ([]byte, error) {
+	s, found := face.tables[tag]
+	if !found {
+		return nil, errMissingTable
+	}
+	return face.src.read(s.offset, s.length)
+}
+
+// Table returns the binary content of the table `tag`, which
+// may be any table, including the ones not supported by this package.
+// It returns false if the table is not present (or skipped by the ParseOptions),
+// or can't be read from the file.
+// The returned slice must not be modified.
+func (face *Face) Table(tag Tag) ([]byte, bool) {
+	data, err := face.GetRawTable(tag)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// loadNumGlyphs parses the 'maxp' table to find the number of glyphs in the font.
+func (face *Face) loadNumGlyphs() error {
+	buf, err := face.GetRawTable(tagMaxp)
+	if err != nil {
+		return missingTable(tagMaxp)
+	}
+
+	face.NumGlyphs, err = parseTableMaxp(buf)
+	if err != nil {
+		return face.tableError(tagMaxp, err)
+	}
+	if face.NumGlyphs == 0 {
+		face.warnings.add("'maxp' table: no glyphs")
+	}
+	return nil
+}
+
+// loads the table corresponding to the 'head' tag.
+// if a 'bhed' Apple table is present, it replaces the 'head' one
+func (face *Face) loadHeadTable() error {
+	tag := tagBhed
+	buf, err := face.GetRawTable(tagBhed)
+	if err != nil {
+		tag = tagHead
+		buf, err = face.GetRawTable(tagHead)
+		if err != nil {
+			return missingTable(tagHead)
+		}
+	}
+
+	face.Head, err = parseTableHead(buf)
+	if err != nil {
+		return face.tableError(tag, err)
+	}
+	if upem := face.Head.UnitsPerEm; upem < 16 || upem > 16384 {
+		face.warnings.add("'%s' table: invalid units per em %d", tag, upem)
+	}
+	return nil
+}
+
+func (face *Face) loadCmapTable() error {
+	buf, err := face.GetRawTable(tagCmap)
+	if err != nil {
+		return missingTable(tagCmap)
+	}
+
+	cmaps, err := face.parseTableCmap(buf)
+	if err != nil {
+		return face.tableError(tagCmap, err)
+	}
+	face.cmap, _ = cmaps.BestEncoding()
+	face.cmapVar = cmaps.Variations
+	return nil
+}
+
+// Upem returns the units per em of the font.
+func (face *Face) Upem() uint16 { return face.Head.UnitsPerEm }
+
+// GlyphCount implements font.GlyphCounter, returning NumGlyphs.
+func (face *Face) GlyphCount() int { return face.NumGlyphs }
+
+// NominalGlyph implements font.Face, using the best available cmap subtable.
+func (face *Face) NominalGlyph(r rune) (GID, bool) {
+	if face.cmap == nil {
+		return 0, false
+	}
+	return face.cmap.Lookup(r)
+}
+
+// Coverage returns the set of the runes supported by the font,
+// that is the ones for which NominalGlyph returns true.
+// The set is computed on first use.
+func (face *Face) Coverage() *font.RuneSet {
+	face.coverageOnce.Do(func() {
+		var runes []rune
+		if face.cmap != nil {
+			for it := face.cmap.Iter(); it.Next(); {
+				r, _ := it.Char()
+				runes = append(runes, r)
+			}
+		}
+		face.coverage = font.NewRuneSet(runes...)
+	})
+	return face.coverage
+}
+
+// SupportsText returns true if all the runes of `s`, except default
+// ignorable code points (see font.IsDefaultIgnorable), are supported
+// by the font, or false and the first unsupported rune.
+func (face *Face) SupportsText(s string) (bool, rune) {
+	for _, r := range s {
+		if _, ok := face.NominalGlyph(r); !ok && !font.IsDefaultIgnorable(r) {
+			return false, r
+		}
+	}
+	return true, 0
+}
+
+// VariationGlyph returns the glyph for the Unicode variation sequence made of
+// `r` followed by the variation selector `selector`, as defined
+// by the cmap format 14 subtable.
+// Sequences rendered with the default glyph of `r` are resolved with NominalGlyph.
+// It returns false if the sequence is not supported by the font.
+func (face *Face) VariationGlyph(r, selector rune) (GID, bool) {
+	gid, status := face.cmapVar.Lookup(r, selector)
+	switch status {
+	case UVSDefault:
+		return face.NominalGlyph(r)
+	case UVSSpecific:
+		return gid, true
+	default:
+		return 0, false
+	}
+}
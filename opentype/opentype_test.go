@@ -0,0 +1,193 @@
+package opentype
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+// corpusFonts returns the paths of the font files of the test corpus,
+// skipping the standalone tables and the bare CFF fonts.
+func corpusFonts(t *testing.T) []string {
+	var out []string
+	err := fs.WalkDir(td.Files, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "table" || d.Name() == "tables" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		switch path.Ext(file) {
+		case ".ttf", ".otf", ".ttc", ".otb", ".woff", ".dfont":
+			out = append(out, file)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// loadFace loads the font at `file` in the test corpus.
+func loadFace(t *testing.T, file string) *Face {
+	t.Helper()
+	face, err := LoadFromFS(td.Files, file)
+	if err != nil {
+		t.Fatalf("%s: %s", file, err)
+	}
+	t.Cleanup(func() { face.Close() })
+	return face
+}
+
+// loadAll loads every lazy table and glyph data of `face`.
+func loadAll(face *Face) {
+	face.Ankr()
+	face.Avar()
+	face.BASE()
+	face.Bsln()
+	face.Feat()
+	face.Fvar()
+	face.GDEF()
+	face.GPOS()
+	face.GSUB()
+	face.JSTF()
+	face.Kern()
+	face.Kerx()
+	face.MATH()
+	face.Meta()
+	face.Morx()
+	face.NameTable()
+	face.Opbd()
+	face.Prop()
+	face.STAT()
+	face.Trak()
+	face.Zapf()
+	face.Coverage()
+	face.ColorFormats()
+	face.BitmapStrikes()
+	face.Palettes()
+	face.ControlValues()
+	face.VariationPostScriptName()
+	for gid := GID(0); int(gid) < face.GlyphCount(); gid++ {
+		face.GlyphName(gid)
+		face.GlyphOutline(gid)
+		face.GlyphExtents(gid)
+		face.HorizontalAdvance(gid)
+		face.VerticalAdvance(gid)
+		face.LigatureCarets(gid, false, 0)
+		face.ColorGlyphPaint(gid)
+		face.GlyphSVG(gid)
+	}
+}
+
+// knownWarnings are the issues reported for the corpus fonts.
+var knownWarnings = map[string]string{
+	"common/OldaniaADFStd-Bold.otf": "duplicate table 'DSIG' at offset 40268: ignored",
+}
+
+// strictErrors are extracts of the errors returned by the
+// strict mode for the invalid corpus fonts.
+var strictErrors = map[string]string{
+	"common/OldaniaADFStd-Bold.otf": "'DSIG' table at offset 40268: duplicate table",
+	"collections/Courier.dfont":     "invalid checksum",
+	// these bitmap fonts have no horizontal metrics, or only one 'loca' entry
+	"collections/Gacha_9.dfont": "table 'hhea': missing required table",
+	"bitmap/IBM3161-bitmap.otb": "glyph 0: invalid glyph 0",
+}
+
+func TestParseCorpus(t *testing.T) {
+	for _, file := range corpusFonts(t) {
+		collection, err := LoadCollectionFromFS(td.Files, file)
+		if err != nil {
+			t.Errorf("%s: %s", file, err)
+			continue
+		}
+		for i := 0; i < collection.NumFaces(); i++ {
+			face, err := collection.Face(i)
+			if err != nil {
+				t.Errorf("%s (face %d): %s", file, i, err)
+				continue
+			}
+			loadAll(face)
+			warnings := face.Warnings()
+			if expected, ok := knownWarnings[file]; ok {
+				if len(warnings) != 1 || warnings[0] != expected {
+					t.Errorf("%s (face %d): expected warning %q, got %q", file, i, expected, warnings)
+				}
+			} else if len(warnings) != 0 {
+				t.Errorf("%s (face %d): unexpected warnings %q", file, i, warnings)
+			}
+
+			repaired, err := collection.FaceWithOptions(i, ParseOptions{Repair: true})
+			if err != nil {
+				t.Errorf("%s (face %d): %s", file, i, err)
+			} else if repairs := repaired.Repairs(); len(repairs) != 0 {
+				t.Errorf("%s (face %d): unexpected repairs %q", file, i, repairs)
+			}
+
+			_, err = collection.FaceWithOptions(i, ParseOptions{Strict: true})
+			if expected, ok := strictErrors[file]; ok {
+				if err == nil || !strings.Contains(err.Error(), expected) {
+					t.Errorf("%s (face %d): expected strict mode error %q, got %v", file, i, expected, err)
+				}
+			} else if err != nil {
+				t.Errorf("%s (face %d): unexpected strict mode error %s", file, i, err)
+			}
+		}
+	}
+}
+
+func TestGlyphCount(t *testing.T) {
+	for _, file := range td.WithGlyphs {
+		if got := loadFace(t, file.Path).GlyphCount(); got != file.GlyphNumber {
+			t.Errorf("%s: expected %d glyphs, got %d", file.Path, file.GlyphNumber, got)
+		}
+	}
+}
+
+func TestLayoutTables(t *testing.T) {
+	for _, file := range td.WithOTLayout {
+		face := loadFace(t, file)
+		gsub, hasGSUB := face.GSUB()
+		gpos, hasGPOS := face.GPOS()
+		if !hasGSUB || !hasGPOS {
+			t.Fatalf("%s: missing layout tables", file)
+		}
+		if len(gsub.Lookups) == 0 || len(gpos.Lookups) == 0 {
+			t.Errorf("%s: empty layout tables", file)
+		}
+	}
+}
+
+func TestBitmapStrikes(t *testing.T) {
+	for _, file := range td.WithSbix {
+		if got := len(loadFace(t, file.Path).SbixStrikes()); got != file.StrikesNumber {
+			t.Errorf("%s: expected %d 'sbix' strikes, got %d", file.Path, file.StrikesNumber, got)
+		}
+	}
+	for _, file := range td.WithCBLC {
+		face := loadFace(t, file.Path)
+		strikes := face.CBDTStrikes()
+		if len(strikes) != file.StrikesNumber {
+			t.Errorf("%s: expected %d 'CBLC' strikes, got %d", file.Path, file.StrikesNumber, len(strikes))
+			continue
+		}
+		for gid := file.GlyphRange[0]; gid <= file.GlyphRange[1]; gid++ {
+			if _, _, ok := face.CBDTGlyphImage(GID(gid), strikes[0].PPEM); !ok {
+				t.Errorf("%s: missing bitmap for glyph %d", file.Path, gid)
+			}
+		}
+	}
+	for _, file := range td.WithEBLC {
+		if got := len(loadFace(t, file.Path).EBDTStrikes()); got != file.StrikesNumber {
+			t.Errorf("%s: expected %d 'EBLC' strikes, got %d", file.Path, file.StrikesNumber, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package opentype
+
+import "github.com/go-text/font"
+
+// ParseOptions selects which groups of tables are loaded,
+// so that applications only interested in some of them (for instance,
+// to index the names and coverage of many fonts) don't pay for the others.
+// Skipped tables are ignored, as if they were not present in the font :
+// HasTable returns false and GetRawTable fails for them.
+// The zero value loads every table.
+type ParseOptions struct {
+	// SkipLayout ignores the OpenType and AAT layout tables
+	// (GDEF, GSUB, GPOS, BASE, JSTF, MATH, morx, mort, kerx, kern, ...).
+	SkipLayout bool
+
+	// SkipBitmaps ignores the embedded bitmap tables
+	// (EBDT, EBLC, EBSC, CBDT, CBLC, sbix, bdat, bloc).
+	SkipBitmaps bool
+
+	// OnlyMetadata only keeps the tables describing the font
+	// (head, maxp, name, OS/2, post, fvar, STAT and meta).
+	// In particular, the 'cmap' table is not loaded, so that no rune
+	// is supported by the returned Face. It implies the other options.
+	OnlyMetadata bool
+
+	// Strict rejects the fonts with structural errors, which are
+	// otherwise tolerated when they don't prevent using the font.
+	// The following checks are performed, on the tables kept by the options :
+	//   - duplicate or overlapping tables, and invalid table checksums
+	//   - missing 'hhea', 'hmtx', 'name', 'post' or 'OS/2' tables
+	//     (the latter is optional for Apple fonts)
+	//   - invalid values in the 'head', 'maxp' and 'cmap' tables,
+	//     such as glyph indices out of range in the 'cmap' table
+	//   - invalid number of metrics in the 'hhea' and 'vhea' tables, and
+	//     truncated 'hmtx' and 'vmtx' tables
+	//   - every table otherwise parsed on demand by the Face methods is parsed
+	//     up front, and rejected if it would be ignored, or reported in the
+	//     warnings as ignored data (see Face.Warnings)
+	//   - the outline of every glyph is decoded, from the 'glyf', 'CFF ' or 'CFF2' table
+	// Unsupported formats and versions in optional subtables, which are ignored
+	// in any case, are not considered as errors: the 'cmap' subtables, for instance.
+	// Since these checks require to read every table, this option
+	// defeats the purpose of LoadFont and OpenFile for large fonts.
+	Strict bool
+
+	// Repair recovers from the common corruptions which are otherwise fatal
+	// (or silently tolerated), by clamping or replacing the invalid values :
+	// truncated tables, invalid 'head' values, missing or wrong number of glyphs
+	// (compared to the 'loca' table), invalid 'cmap' table, and 'cmap' entries
+	// with glyphs out of range.
+	// The repairs performed are reported by Face.Repairs.
+	// It can't be combined with Strict.
+	Repair bool
+}
+
+var (
+	layoutTables = tagSet("GDEF", "GSUB", "GPOS", "BASE", "JSTF", "MATH",
+		"morx", "mort", "kerx", "kern", "trak", "feat", "ankr", "bsln", "lcar", "opbd", "prop", "just")
+	bitmapTables   = tagSet("EBDT", "EBLC", "EBSC", "CBDT", "CBLC", "sbix", "bdat", "bloc")
+	metadataTables = tagSet("head", "bhed", "maxp", "name", "OS/2", "post", "fvar", "STAT", "meta")
+)
+
+func tagSet(tags ...string) map[Tag]bool {
+	out := make(map[Tag]bool, len(tags))
+	for _, tag := range tags {
+		out[MustNewTag(tag)] = true
+	}
+	return out
+}
+
+// skip returns true if the table `tag` should be ignored.
+func (opts ParseOptions) skip(tag Tag) bool {
+	switch {
+	case opts.OnlyMetadata:
+		return !metadataTables[tag]
+	case opts.SkipLayout && layoutTables[tag], opts.SkipBitmaps && bitmapTables[tag]:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseFontWithOptions is the same as ParseFont, but only
+// loads the tables selected by `opts`.
+func ParseFontWithOptions(file font.Resource, opts ParseOptions) (*Face, error) {
+	data, err := readSfnt(file)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSignature(data, "ParseCollection"); err != nil {
+		return nil, err
+	}
+	return parseFace(memorySource(data), 0, opts)
+}
+
+// FaceWithOptions is the same as Face, but only loads the tables selected by `opts`.
+// Since regular font files are also accepted by LoadCollection and OpenCollection,
+// this method also permits to select the tables of lazily loaded or memory mapped fonts.
+func (c *Collection) FaceWithOptions(index int, opts ParseOptions) (*Face, error) {
+	if err := c.checkIndex(index); err != nil {
+		return nil, err
+	}
+	m := c.members[index]
+	return parseFace(m.src, m.offset, opts)
+}
@@ -0,0 +1,208 @@
+package opentype
+
+import (
+	"errors"
+	"math"
+
+	"github.com/go-text/font"
+)
+
+// Paint is a node of the paint graph of a color glyph (see Face.ColorGlyphPaint).
+// It is one of PaintLayers, PaintSolid, PaintLinearGradient, PaintRadialGradient,
+// PaintSweepGradient, PaintGlyph, PaintColrGlyph, PaintTransform or PaintComposite.
+// The coordinates are expressed in font units, with the y axis growing up,
+// and the variations of variable fonts are applied (see SetVariations).
+type Paint interface {
+	isPaint()
+}
+
+func (PaintLayers) isPaint()         {}
+func (PaintSolid) isPaint()          {}
+func (PaintLinearGradient) isPaint() {}
+func (PaintRadialGradient) isPaint() {}
+func (PaintSweepGradient) isPaint()  {}
+func (PaintGlyph) isPaint()          {}
+func (PaintColrGlyph) isPaint()      {}
+func (PaintTransform) isPaint()      {}
+func (PaintComposite) isPaint()      {}
+
+// PaintLayers draws its layers in order, from bottom to top,
+// each layer being composed with the previous ones using the source over mode.
+type PaintLayers struct {
+	Layers []Paint
+}
+
+// PaintSolid fills the current shape with a color of the palette.
+type PaintSolid struct {
+	// PaletteIndex is the index of the color in the palette,
+	// or ForegroundPaletteIndex.
+	PaletteIndex uint16
+	// Alpha multiplies the alpha component of the color, in [0, 1].
+	Alpha float32
+}
+
+// Extend specifies how a gradient is drawn outside of its color line.
+type Extend uint8
+
+const (
+	ExtendPad     Extend = iota // use the nearest color stop
+	ExtendRepeat                // repeat the color line
+	ExtendReflect               // mirror the color line
+)
+
+// ColorStop is a color at a given position of a ColorLine.
+type ColorStop struct {
+	Offset       float32 // position on the color line
+	PaletteIndex uint16  // index in the palette, or ForegroundPaletteIndex
+	Alpha        float32 // multiplies the alpha component of the color
+}
+
+// ColorLine defines the colors of a gradient.
+type ColorLine struct {
+	Extend Extend
+	Stops  []ColorStop
+}
+
+// PaintLinearGradient fills the current shape with a linear gradient.
+// The color line starts at P0 and ends at P1, and the gradient is drawn
+// perpendicularly to the line P0P2.
+type PaintLinearGradient struct {
+	ColorLine  ColorLine
+	P0, P1, P2 font.SegmentPoint
+}
+
+// PaintRadialGradient fills the current shape with a gradient between
+// the circle of center C0 and radius R0, and the circle of center C1 and radius R1.
+type PaintRadialGradient struct {
+	ColorLine ColorLine
+	C0, C1    font.SegmentPoint
+	R0, R1    float32
+}
+
+// PaintSweepGradient fills the current shape with a conic gradient around Center,
+// between the two angles, in counter-clockwise degrees.
+type PaintSweepGradient struct {
+	ColorLine            ColorLine
+	Center               font.SegmentPoint
+	StartAngle, EndAngle float32
+}
+
+// PaintGlyph restricts the drawing of Paint to the outline of the glyph.
+type PaintGlyph struct {
+	Glyph GID
+	Paint Paint
+}
+
+// PaintColrGlyph draws the paint graph of another color glyph,
+// which is resolved and stored in Paint.
+type PaintColrGlyph struct {
+	Glyph GID
+	Paint Paint
+}
+
+// Transform is an affine transformation, mapping (x, y) to
+// (XX*x + XY*y + DX, YX*x + YY*y + DY).
+type Transform struct {
+	XX, YX, XY, YY, DX, DY float32
+}
+
+// Multiply returns the transformation applying `u`, then `t`.
+func (t Transform) Multiply(u Transform) Transform {
+	return Transform{
+		XX: t.XX*u.XX + t.XY*u.YX,
+		YX: t.YX*u.XX + t.YY*u.YX,
+		XY: t.XX*u.XY + t.XY*u.YY,
+		YY: t.YX*u.XY + t.YY*u.YY,
+		DX: t.XX*u.DX + t.XY*u.DY + t.DX,
+		DY: t.YX*u.DX + t.YY*u.DY + t.DY,
+	}
+}
+
+// PaintTransform draws Paint with the transformation applied.
+// The translation, scale, rotation and skew paints of the font
+// are all converted to a PaintTransform.
+type PaintTransform struct {
+	Transform Transform
+	Paint     Paint
+}
+
+// CompositeMode is the blending mode of a PaintComposite.
+type CompositeMode uint8
+
+// The composite modes, as defined by the W3C Compositing and Blending specification.
+const (
+	CompositeClear CompositeMode = iota
+	CompositeSrc
+	CompositeDest
+	CompositeSrcOver
+	CompositeDestOver
+	CompositeSrcIn
+	CompositeDestIn
+	CompositeSrcOut
+	CompositeDestOut
+	CompositeSrcAtop
+	CompositeDestAtop
+	CompositeXor
+	CompositePlus
+	CompositeScreen
+	CompositeOverlay
+	CompositeDarken
+	CompositeLighten
+	CompositeColorDodge
+	CompositeColorBurn
+	CompositeHardLight
+	CompositeSoftLight
+	CompositeDifference
+	CompositeExclusion
+	CompositeMultiply
+	CompositeHue
+	CompositeSaturation
+	CompositeColor
+	CompositeLuminosity
+)
+
+// PaintComposite draws Source over Backdrop using Mode.
+type PaintComposite struct {
+	Source, Backdrop Paint
+	Mode             CompositeMode
+}
+
+// ClipBox is a box, in font units, outside of which the
+// drawing of a color glyph is clipped.
+type ClipBox struct {
+	XMin, YMin, XMax, YMax float32
+}
+
+var (
+	errPaintCycle = errors.New("invalid paint graph: cycle detected")
+	errPaintLimit = errors.New("invalid paint graph: too many paints")
+)
+
+const (
+	maxPaintDepth = 64    // nesting limit of the paint graph
+	maxPaintCount = 10000 // limit on the total number of paints of a glyph
+)
+
+func translation(dx, dy float32) Transform { return Transform{XX: 1, YY: 1, DX: dx, DY: dy} }
+
+// aroundCenter returns `t` applied around the point (cx, cy) instead of the origin.
+func aroundCenter(t Transform, cx, cy float32) Transform {
+	return translation(cx, cy).Multiply(t).Multiply(translation(-cx, -cy))
+}
+
+// rotation returns the rotation by `angle`, in counter-clockwise half turns.
+func rotation(angle float32) Transform {
+	sin, cos := math.Sincos(float64(angle) * math.Pi)
+	return Transform{XX: float32(cos), YX: float32(sin), XY: float32(-sin), YY: float32(cos)}
+}
+
+// skew returns the skew transformation with the given angles,
+// in counter-clockwise half turns.
+func skew(xAngle, yAngle float32) Transform {
+	return Transform{
+		XX: 1,
+		YX: float32(math.Tan(float64(yAngle) * math.Pi)),
+		XY: float32(math.Tan(-float64(xAngle) * math.Pi)),
+		YY: 1,
+	}
+}
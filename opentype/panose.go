@@ -0,0 +1,85 @@
+package opentype
+
+// Panose is the PANOSE classification of the font, made of 10 digits
+// whose meaning depends on the family kind (the first digit).
+// For all the digits, the value 0 means "any" and 1 means "no fit".
+// https://monotype.github.io/panose/
+type Panose [10]byte
+
+// PanoseFamilyKind is the first digit of the PANOSE classification.
+type PanoseFamilyKind uint8
+
+const (
+	PanoseAny              PanoseFamilyKind = iota // any family kind
+	PanoseNoFit                                    // the font does not fit the classification
+	PanoseLatinText                                // text and display fonts
+	PanoseLatinHandWritten                         // script fonts
+	PanoseLatinDecorative                          // decorative fonts
+	PanoseLatinSymbol                              // pictorial fonts
+)
+
+// FamilyKind returns the family kind of the classification.
+func (p Panose) FamilyKind() PanoseFamilyKind { return PanoseFamilyKind(p[0]) }
+
+// IsSerif returns true for Latin text fonts whose serif style is one
+// of the serif styles (cove to triangle).
+func (p Panose) IsSerif() bool {
+	return p.FamilyKind() == PanoseLatinText && 2 <= p[1] && p[1] <= 10
+}
+
+// IsSansSerif returns true for Latin text fonts whose serif style is one
+// of the sans serif styles (normal, obtuse or perpendicular sans).
+func (p Panose) IsSansSerif() bool {
+	return p.FamilyKind() == PanoseLatinText && 11 <= p[1] && p[1] <= 13
+}
+
+// IsMonospacedByPanose returns true if the classification describes a monospaced
+// font, that is a Latin text font with a monospaced proportion, or
+// a Latin hand written or symbol font with a monospaced spacing.
+func (p Panose) IsMonospacedByPanose() bool {
+	switch p.FamilyKind() {
+	case PanoseLatinText:
+		return p[3] == 9
+	case PanoseLatinHandWritten, PanoseLatinSymbol:
+		return p[3] == 3
+	}
+	return false
+}
+
+// Weight returns the weight digit of Latin text fonts, from 2 (very light) to
+// 11 (extra black), or 0 for the other family kinds.
+func (p Panose) Weight() uint8 {
+	if p.FamilyKind() != PanoseLatinText {
+		return 0
+	}
+	return p[2]
+}
+
+// Distance returns the PANOSE distance between the classifications,
+// which is the sum of the squared differences of their digits.
+// The digits set to "any" in one of the classifications are ignored,
+// and -1 is returned if the family kinds are different.
+func (p Panose) Distance(other Panose) int {
+	if p[0] != other[0] && p[0] != 0 && other[0] != 0 {
+		return -1
+	}
+	distance := 0
+	for i := 1; i < len(p); i++ {
+		if p[i] == 0 || other[i] == 0 {
+			continue
+		}
+		d := int(p[i]) - int(other[i])
+		distance += d * d
+	}
+	return distance
+}
+
+// Panose returns the PANOSE classification given by the 'OS/2' table,
+// or false if the font has no 'OS/2' table.
+func (face *Face) Panose() (Panose, bool) {
+	os2, ok := face.OS2()
+	if !ok {
+		return Panose{}, false
+	}
+	return os2.Panose, true
+}
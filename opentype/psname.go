@@ -0,0 +1,161 @@
+package opentype
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxPostScriptNameLength is the maximum length of the
+// generated PostScript names.
+const maxPostScriptNameLength = 127
+
+// VariationPostScriptName returns the PostScript name of the instance selected
+// by SetVariations, following the Adobe Technical Note #5902:
+//   - for the default instance, the PostScript name of the font is returned
+//   - the named instances providing a PostScript name use it
+//   - the other named instances use the family prefix (the name 25, or else
+//     the typographic family name), followed by "-" and their subfamily name
+//   - the arbitrary instances whose coordinates are all described by the axis
+//     values of the 'STAT' table use the family prefix, followed by "-" and the
+//     names of these values, such as "-SemiBoldCondensed" (see StyleName)
+//   - the other instances use the family prefix, followed by "_<value><tag>"
+//     for each axis whose value is not the default one, such as "_700wght".
+//
+// Only the characters [A-Za-z0-9] of the names are kept. As specified by the note
+// ("last resort" names), the generated names longer than 127 characters are replaced
+// by the family prefix, followed by "-", a hash of the full name and "...".
+// The hash is the 32-bit FNV-1a hash, written with 8 uppercase hexadecimal digits.
+// It returns an empty string if the required names are missing.
+func (face *Face) VariationPostScriptName() string {
+	fvar, ok := face.Fvar()
+	if !ok || !face.isVariable() {
+		name, _ := face.Name(NamePostScript)
+		return name
+	}
+
+	instance := face.currentNamedInstance(fvar)
+	if instance != nil && instance.PostScript != noNameID {
+		if name, ok := face.Name(instance.PostScript); ok {
+			return name
+		}
+	}
+
+	prefix, ok := face.Name(NameVariationsPostScriptPrefix)
+	if !ok {
+		if prefix, ok = face.Name(NameTypographicFamily); !ok {
+			prefix, _ = face.Name(NameFamily)
+		}
+	}
+	prefix = postScriptCharacters(prefix)
+	if prefix == "" {
+		return ""
+	}
+
+	var name strings.Builder
+	name.WriteString(prefix)
+	if subfamily := postScriptCharacters(face.instanceStyle(fvar, instance)); subfamily != "" {
+		name.WriteByte('-')
+		name.WriteString(subfamily)
+	} else {
+		for _, label := range face.axisLabels(fvar) {
+			name.WriteByte('_')
+			name.WriteString(label)
+		}
+	}
+
+	if name.Len() <= maxPostScriptNameLength {
+		return name.String()
+	}
+	// last resort : "<prefix>-<hash>..."
+	hash := fnv.New32a()
+	hash.Write([]byte(name.String()))
+	suffix := fmt.Sprintf("-%08X...", hash.Sum32())
+	if len(prefix) > maxPostScriptNameLength-len(suffix) {
+		prefix = prefix[:maxPostScriptNameLength-len(suffix)]
+	}
+	return prefix + suffix
+}
+
+// currentNamedInstance returns the named instance whose coordinates
+// are the ones set by SetVariations, or nil.
+func (face *Face) currentNamedInstance(fvar *TableFvar) *VarInstance {
+	if match, ok := face.MatchNamedInstance(face.coords); ok && match.Exact {
+		return &fvar.Instances[match.Index]
+	}
+	return nil
+}
+
+// instanceStyle returns the style of the instance selected by SetVariations, given by
+// the subfamily name of the matching named instance `instance` (which may be nil),
+// or else by the axis values of the 'STAT' table, or an empty string.
+func (face *Face) instanceStyle(fvar *TableFvar, instance *VarInstance) string {
+	if instance != nil {
+		name, _ := face.Name(instance.Subfamily)
+		return name
+	}
+	name, _ := face.instanceStyleName(true)
+	return name
+}
+
+// axisLabels returns "<value><tag>" for each axis whose value
+// is not the default one, such as "700wght".
+func (face *Face) axisLabels(fvar *TableFvar) []string {
+	var out []string
+	for i, axis := range fvar.Axes {
+		if i >= len(face.coords) {
+			break
+		}
+		value := face.snappedUserCoordinate(fvar, i)
+		if value == axis.Default {
+			continue
+		}
+		out = append(out, formatAxisValue(value)+strings.TrimRight(axis.Tag.String(), " _"))
+	}
+	return out
+}
+
+// snappedUserCoordinate returns the user space coordinate of the axis at index `axis`,
+// as the shortest decimal value, with at most 5 fractional digits, whose normalized form
+// is the coordinate set by SetVariations. This removes the noise of the inverse of
+// the normalization, so that the value set with WithVariations(650) gives 650.
+func (face *Face) snappedUserCoordinate(fvar *TableFvar, axis int) float32 {
+	coord := face.currentCoordinate(fvar, axis)
+	value := face.userCoordinate(fvar, axis, coord)
+	for digits := 0; digits <= 5; digits++ {
+		scale := math.Pow10(digits)
+		snapped := float32(math.Round(float64(value)*scale) / scale)
+		if face.normalizedCoordinate(fvar, axis, snapped) == coord {
+			return snapped
+		}
+	}
+	return value
+}
+
+// postScriptCharacters removes the characters of `s` other than [A-Za-z0-9].
+func postScriptCharacters(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// formatAxisValue formats `v` with its shortest representation if it has at
+// most 5 fractional digits, or else with 5 fractional digits, after rounding it to
+// the 16.16 fixed point precision.
+func formatAxisValue(v float32) string {
+	s := strconv.FormatFloat(float64(v), 'f', -1, 32)
+	if dot := strings.IndexByte(s, '.'); dot != -1 && len(s)-dot-1 > 5 {
+		fixed := math.Round(float64(v) * (1 << 16))
+		s = strconv.FormatFloat(fixed/(1<<16), 'f', 5, 64)
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package opentype
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariationPostScriptName(t *testing.T) {
+	wght, slnt := MustNewTag("wght"), MustNewTag("slnt")
+	for _, test := range []struct {
+		file       string
+		variations []Variation
+		expected   string
+	}{
+		{"common/Commissioner-VF.ttf", nil, "Commissioner-Thin"},
+		{"common/Commissioner-VF.ttf", []Variation{{wght, 600}}, "Commissioner-SemiBold"},
+		{"common/Commissioner-VF.ttf", []Variation{{wght, 650}}, "Commissioner_650wght"},
+		{"common/Commissioner-VF.ttf", []Variation{{wght, 550}, {slnt, -5}}, "Commissioner_550wght_-5slnt"},
+		{"common/Selawik-VF.ttf", []Variation{{wght, 333}}, "SelawikVariationstest_333wght"},
+		{"common/Selawik-VF.ttf", []Variation{{wght, 650}}, "SelawikVariationstest_650wght"},
+		{"common/SourceSans-VF.ttf", []Variation{{wght, 612.5}}, "SourceSansRoman_612.5wght"},
+	} {
+		face := loadFace(t, test.file).WithVariations(test.variations)
+		if got := face.VariationPostScriptName(); got != test.expected {
+			t.Errorf("%s %v: expected %q, got %q", test.file, test.variations, test.expected, got)
+		}
+	}
+}
+
+func TestNamedInstancesPostScriptName(t *testing.T) {
+	face := loadFace(t, "common/Commissioner-VF.ttf")
+	fvar, _ := face.Fvar()
+	for i, instance := range fvar.Instances {
+		named, subfamily, ok := face.NamedInstance(i)
+		if !ok {
+			t.Fatalf("missing named instance %d", i)
+		}
+		// the font does not provide PostScript names for its instances
+		if instance.PostScript != noNameID {
+			t.Fatalf("unexpected PostScript name for instance %d", i)
+		}
+		expected := "Commissioner-" + strings.ReplaceAll(subfamily, " ", "")
+		if got := named.VariationPostScriptName(); got != expected {
+			t.Errorf("instance %d: expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestVariationPostScriptNameLastResort(t *testing.T) {
+	face := loadFace(t, "toys/Var1.ttf")
+	fvar, _ := face.Fvar()
+	var variations []Variation
+	for _, axis := range fvar.Axes {
+		variations = append(variations, Variation{axis.Tag, axis.Minimum + (axis.Maximum-axis.Minimum)/3})
+	}
+	// the full name is longer than 127 characters
+	const expected = "AmstelvarAlpha-CABBBF3B..."
+	if got := face.WithVariations(variations).VariationPostScriptName(); got != expected {
+		t.Errorf("expected last resort name %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,191 @@
+package opentype
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Repairs returns a description of the corruptions fixed while loading
+// the face, when ParseOptions.Repair is used.
+func (face *Face) Repairs() []string { return face.repairs }
+
+func (face *Face) repaired(format string, args ...interface{}) {
+	face.repairs = append(face.repairs, fmt.Sprintf(format, args...))
+}
+
+// repair loads the tables required for all fonts, as parseFace does,
+// but replaces the invalid values instead of failing.
+// It only fails if the 'maxp' table is invalid and can't be replaced by the 'loca' table.
+func (face *Face) repair(opts ParseOptions) error {
+	face.repairDirectory()
+	face.repairHead()
+	if err := face.repairNumGlyphs(); err != nil {
+		return err
+	}
+	if !opts.OnlyMetadata {
+		face.repairCmap()
+	}
+	return nil
+}
+
+// repairDirectory truncates the tables exceeding the file,
+// and drops the ones starting after its end.
+func (face *Face) repairDirectory() {
+	tags := make([]Tag, 0, len(face.tables))
+	for tag := range face.tables {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return face.tables[tags[i]].offset < face.tables[tags[j]].offset })
+	for _, tag := range tags {
+		sec := face.tables[tag]
+		if uint64(sec.offset)+uint64(sec.length) <= face.src.size {
+			continue
+		}
+		if uint64(sec.offset) >= face.src.size {
+			delete(face.tables, tag)
+			face.repaired("table '%s' starts after the end of the file: ignored", tag)
+			continue
+		}
+		length := uint32(face.src.size - uint64(sec.offset))
+		face.repaired("table '%s' truncated from %d to %d bytes", tag, sec.length, length)
+		sec.length = length
+		face.tables[tag] = sec
+	}
+}
+
+const defaultUpem = 1000
+
+// repairHead replaces a missing or invalid 'head' table by default values.
+// An invalid 'loca' format is handled by repairNumGlyphs.
+func (face *Face) repairHead() {
+	if err := face.loadHeadTable(); err != nil {
+		face.Head = TableHead{UnitsPerEm: defaultUpem, IndexToLocFormat: -1}
+		face.repaired("%s: using default values", err)
+		return
+	}
+	if upem := face.Head.UnitsPerEm; upem < 16 || upem > 16384 {
+		face.Head.UnitsPerEm = defaultUpem
+		face.repaired("invalid units per em %d in 'head' table: using %d", upem, defaultUpem)
+	}
+}
+
+// repairNumGlyphs checks the number of glyphs against the 'loca' table,
+// if any, which is also used when the 'maxp' table is missing or invalid.
+func (face *Face) repairNumGlyphs() error {
+	err := face.loadNumGlyphs()
+
+	var (
+		hasLoca    bool
+		locaGlyphs int
+	)
+	// bitmap only fonts may have an empty 'glyf' table, with a dummy 'loca' table
+	if loca, errLoca := face.GetRawTable(tagLoca); errLoca == nil && face.HasTable(tagGlyf) && face.tables[tagGlyf].length != 0 {
+		entrySize := 2
+		switch format := face.Head.IndexToLocFormat; format {
+		case 0:
+		case 1:
+			entrySize = 4
+		default:
+			// use the format matching the number of glyphs, if known
+			if (err == nil && len(loca) >= 4*(face.NumGlyphs+1)) || (err != nil && len(loca)%4 == 0) {
+				entrySize = 4
+			}
+			face.Head.IndexToLocFormat = int16(entrySize / 4)
+			if format != -1 { // not already reported
+				face.repaired("invalid index to loc format %d in 'head' table: using %d", format, entrySize/4)
+			}
+		}
+		hasLoca = true
+		if len(loca) >= entrySize { // an empty 'loca' table has no glyph
+			locaGlyphs = len(loca)/entrySize - 1
+		}
+	}
+
+	switch {
+	case err != nil && locaGlyphs > 0:
+		face.NumGlyphs = locaGlyphs
+		face.repaired("%s: using %d glyphs from the 'loca' table", err, locaGlyphs)
+	case err != nil:
+		return err
+	case face.NumGlyphs == 0 && locaGlyphs > 0:
+		face.NumGlyphs = locaGlyphs
+		face.repaired("no glyphs in 'maxp' table: using %d glyphs from the 'loca' table", locaGlyphs)
+	case hasLoca && face.NumGlyphs > locaGlyphs:
+		face.repaired("%d glyphs in 'maxp' table, but only %d in the 'loca' table: keeping %d", face.NumGlyphs, locaGlyphs, locaGlyphs)
+		face.NumGlyphs = locaGlyphs
+	}
+	return nil
+}
+
+// repairCmap ignores an invalid 'cmap' table, and
+// the entries mapping to glyphs out of range.
+func (face *Face) repairCmap() {
+	if err := face.loadCmapTable(); err != nil {
+		face.repaired("%s: ignored", err)
+		return
+	}
+	face.repairCmapVariations()
+	if face.cmap == nil {
+		return
+	}
+	invalid := 0
+	for it := face.cmap.Iter(); it.Next(); {
+		if _, gid := it.Char(); int(gid) >= face.NumGlyphs {
+			invalid++
+		}
+	}
+	if invalid != 0 {
+		face.cmap = validGlyphsCmap{Cmap: face.cmap, numGlyphs: face.NumGlyphs}
+		face.repaired("%d 'cmap' entries with glyphs out of range: ignored", invalid)
+	}
+}
+
+// repairCmapVariations removes the variation sequences mapped to glyphs out of range.
+func (face *Face) repairCmapVariations() {
+	invalid := 0
+	for i, vs := range face.cmapVar {
+		valid := vs.NonDefaultUVS[:0]
+		for _, m := range vs.NonDefaultUVS {
+			if int(m.Glyph) < face.NumGlyphs {
+				valid = append(valid, m)
+			}
+		}
+		invalid += len(vs.NonDefaultUVS) - len(valid)
+		face.cmapVar[i].NonDefaultUVS = valid
+	}
+	if invalid != 0 {
+		face.repaired("%d 'cmap' variation sequences with glyphs out of range: ignored", invalid)
+	}
+}
+
+// validGlyphsCmap hides the entries mapping to glyphs out of range.
+type validGlyphsCmap struct {
+	Cmap
+	numGlyphs int
+}
+
+func (c validGlyphsCmap) Lookup(r rune) (GID, bool) {
+	gid, ok := c.Cmap.Lookup(r)
+	if !ok || int(gid) >= c.numGlyphs {
+		return 0, false
+	}
+	return gid, true
+}
+
+func (c validGlyphsCmap) Iter() CmapIter {
+	return &validGlyphsIter{CmapIter: c.Cmap.Iter(), numGlyphs: c.numGlyphs}
+}
+
+type validGlyphsIter struct {
+	CmapIter
+	numGlyphs int
+}
+
+func (it *validGlyphsIter) Next() bool {
+	for it.CmapIter.Next() {
+		if _, gid := it.Char(); int(gid) < it.numGlyphs {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// source provides the content of a font file, either
+// stored in memory, or read on demand from a io.ReaderAt.
+type source struct {
+	data []byte      // used if file is nil
+	file io.ReaderAt // optional
+	size uint64      // size of the file
+}
+
+func memorySource(data []byte) source { return source{data: data, size: uint64(len(data))} }
+
+// read returns the `length` bytes starting at `offset`.
+// For in memory sources, the returned slice points into the data and must not be modified.
+func (s source) read(offset, length uint32) ([]byte, error) {
+	if uint64(offset)+uint64(length) > s.size {
+		return nil, fmt.Errorf("invalid offset or length (%d, %d) (EOF)", offset, length)
+	}
+	if s.file == nil {
+		return s.data[offset : offset+length], nil
+	}
+	out := make([]byte, length)
+	if _, err := s.file.ReadAt(out, int64(offset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return out, nil
+}
+
+// readAll loads the whole content of the file in memory
+func readAll(file io.ReaderAt, size int64) ([]byte, error) {
+	return ioutil.ReadAll(io.NewSectionReader(file, 0, size))
+}
+
+// fileSource returns a source reading from `file` on demand, unless
+// the file is a web font, which is then decompressed in memory.
+func fileSource(file io.ReaderAt, size int64) (source, error) {
+	if size < 4 {
+		return source{}, errUnsupportedFormat
+	}
+	var header [36]byte
+	n, err := file.ReadAt(header[:], 0)
+	if n < 4 {
+		return source{}, err
+	}
+	signature := Tag(binary.BigEndian.Uint32(header[:]))
+	// EOT files start with their size, and store a magic number at offset 34
+	isEOT := n == len(header) && binary.LittleEndian.Uint32(header[:]) == uint32(size) &&
+		binary.LittleEndian.Uint16(header[34:]) == 0x504C
+	if signature != SignatureWOFF && signature != SignatureWOFF2 && !isEOT {
+		return source{file: file, size: uint64(size)}, nil
+	}
+
+	data, err := readAll(file, size)
+	if err != nil {
+		return source{}, err
+	}
+	data, err = unwrapWebFont(data)
+	if err != nil {
+		return source{}, err
+	}
+	if len(data) < 4 {
+		return source{}, errUnsupportedFormat
+	}
+	return memorySource(data), nil
+}
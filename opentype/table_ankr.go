@@ -0,0 +1,84 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableAnkr stores the anchor points of the glyphs of the AAT fonts,
+// which are used to attach the glyphs (see KerxAnchorPointActions).
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6ankr.html
+type TableAnkr struct {
+	glyphs ClassDef // the offsets of the anchors of the glyphs in data
+	data   []byte   // the glyph data table
+}
+
+// AnchorPoint is an anchor point of a glyph, in font units.
+type AnchorPoint struct {
+	X, Y int16
+}
+
+// AnchorPoints returns the anchor points of the glyph,
+// or nil if it has none (or if they are invalid).
+func (t *TableAnkr) AnchorPoints(gid GID) []AnchorPoint {
+	offset, ok := t.glyphs.Class(gid)
+	if !ok || len(t.data) < int(offset)+4 {
+		return nil
+	}
+	count := int64(binary.BigEndian.Uint32(t.data[offset:]))
+	if int64(len(t.data)) < int64(offset)+4+4*count {
+		return nil
+	}
+	out := make([]AnchorPoint, count)
+	for i := range out {
+		point := t.data[int(offset)+4+4*i:]
+		out[i] = AnchorPoint{X: int16(binary.BigEndian.Uint16(point)), Y: int16(binary.BigEndian.Uint16(point[2:]))}
+	}
+	return out
+}
+
+func parseTableAnkr(data []byte, numGlyphs int) (TableAnkr, error) {
+	if len(data) < 12 {
+		return TableAnkr{}, errEOF
+	}
+	if version := binary.BigEndian.Uint16(data); version != 0 {
+		return TableAnkr{}, fmt.Errorf("unsupported version %d", version)
+	}
+	var (
+		out TableAnkr
+		err error
+	)
+	if out.glyphs, err = parseAATLookup(data, binary.BigEndian.Uint32(data[4:]), numGlyphs); err != nil {
+		return out, err
+	}
+	offset := binary.BigEndian.Uint32(data[8:])
+	if int64(offset) > int64(len(data)) {
+		return out, errors.New("invalid anchor points (EOF)")
+	}
+	out.data = data[offset:]
+	return out, nil
+}
+
+// ankr is the lazily parsed 'ankr' table.
+type ankr struct {
+	once  sync.Once
+	table *TableAnkr // nil if missing or invalid
+}
+
+// Ankr returns the anchor point table, parsed on first use,
+// or false if the font has no 'ankr' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Ankr() (*TableAnkr, bool) {
+	face.ankr.once.Do(func() {
+		if data, err := face.GetRawTable(tagAnkr); err == nil {
+			if table, err := parseTableAnkr(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagAnkr, err))
+			} else {
+				face.ankr.table = &table
+			}
+		}
+	})
+	return face.ankr.table, face.ankr.table != nil
+}
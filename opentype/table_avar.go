@@ -0,0 +1,141 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// TableAvar modifies the default normalization of the variation
+// coordinates, with one piecewise linear mapping per axis.
+// Only the segment maps are supported : the extensions of the version 2
+// are ignored.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/avar
+type TableAvar struct {
+	// AxisSegmentMaps are the mappings of the axes, in the order of the 'fvar' table.
+	// An empty mapping is the identity.
+	AxisSegmentMaps [][]AxisValueMap
+}
+
+// AxisValueMap maps a normalized coordinate to its modified value.
+type AxisValueMap struct {
+	From, To float32 // in [-1, 1]
+}
+
+func parseTableAvar(data []byte) (TableAvar, error) {
+	if len(data) < 8 {
+		return TableAvar{}, errEOF
+	}
+	if major := binary.BigEndian.Uint16(data); major != 1 && major != 2 {
+		return TableAvar{}, errors.New("unsupported version")
+	}
+	count := int(binary.BigEndian.Uint16(data[6:]))
+	out := TableAvar{AxisSegmentMaps: make([][]AxisValueMap, count)}
+	data = data[8:]
+	for i := range out.AxisSegmentMaps {
+		if len(data) < 2 {
+			return TableAvar{}, errors.New("invalid segment map (EOF)")
+		}
+		mapCount := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+4*mapCount {
+			return TableAvar{}, errors.New("invalid segment map (EOF)")
+		}
+		maps := make([]AxisValueMap, mapCount)
+		for j := range maps {
+			maps[j] = AxisValueMap{
+				From: f2dot14(binary.BigEndian.Uint16(data[2+4*j:])),
+				To:   f2dot14(binary.BigEndian.Uint16(data[4+4*j:])),
+			}
+		}
+		out.AxisSegmentMaps[i] = maps
+		data = data[2+4*mapCount:]
+	}
+	return out, nil
+}
+
+// mapSegments applies the piecewise linear mapping `maps` to `coord`,
+// or its inverse if `inverse` is true.
+// The coordinates outside of the mapping are shifted by the nearest
+// segment end.
+func mapSegments(maps []AxisValueMap, coord float32, inverse bool) float32 {
+	from := func(m AxisValueMap) float32 { return m.From }
+	to := func(m AxisValueMap) float32 { return m.To }
+	if inverse {
+		from, to = to, from
+	}
+	n := len(maps)
+	switch {
+	case n == 0:
+		return coord
+	case n == 1 || coord <= from(maps[0]):
+		return coord - from(maps[0]) + to(maps[0])
+	case coord >= from(maps[n-1]):
+		return coord - from(maps[n-1]) + to(maps[n-1])
+	}
+	i := 1
+	for i < n-1 && coord > from(maps[i]) {
+		i++
+	}
+	start, end := maps[i-1], maps[i]
+	if coord == from(end) {
+		return to(end)
+	}
+	if from(end) == from(start) {
+		return to(start)
+	}
+	return to(start) + (to(end)-to(start))*(coord-from(start))/(from(end)-from(start))
+}
+
+// avar stores the 'avar' table, loaded on first use.
+type avar struct {
+	once  sync.Once
+	table *TableAvar // nil if missing or invalid
+}
+
+// Avar returns the axis variations table, parsed on first use,
+// or false if the font has no 'avar' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Avar() (*TableAvar, bool) {
+	face.avar.once.Do(func() {
+		if data, err := face.GetRawTable(tagAvar); err == nil {
+			if table, err := parseTableAvar(data); err != nil {
+				face.warnings.addError(face.tableError(tagAvar, err))
+			} else {
+				face.avar.table = &table
+			}
+		}
+	})
+	return face.avar.table, face.avar.table != nil
+}
+
+// normalizedCoordinate returns the normalized value, in [-1, 1],
+// of the user space coordinate `coord` on the axis at index `axis`
+// of the 'fvar' table, applying the 'avar' mapping.
+// As in the font tables, the values are rounded to the precision of the F2Dot14 format.
+func (face *Face) normalizedCoordinate(fvar *TableFvar, axis int, coord float32) float32 {
+	coord = roundF2dot14(fvar.Axes[axis].normalizedCoordinate(coord))
+	if avar, ok := face.Avar(); ok && axis < len(avar.AxisSegmentMaps) {
+		coord = roundF2dot14(mapSegments(avar.AxisSegmentMaps[axis], coord, false))
+	}
+	return coord
+}
+
+// userCoordinate is the inverse of normalizedCoordinate.
+func (face *Face) userCoordinate(fvar *TableFvar, axis int, coord float32) float32 {
+	if avar, ok := face.Avar(); ok && axis < len(avar.AxisSegmentMaps) {
+		coord = mapSegments(avar.AxisSegmentMaps[axis], coord, true)
+	}
+	return fvar.Axes[axis].userCoordinate(coord)
+}
+
+// currentCoordinate returns the coordinate set by SetVariations on the axis at index `axis`
+// of the 'fvar' table, rounded to the F2Dot14 precision and limited to the design space
+// of the axis : for instance, a negative coordinate is the default one on an axis
+// whose minimum is its default value.
+func (face *Face) currentCoordinate(fvar *TableFvar, axis int) float32 {
+	var coord float32
+	if axis < len(face.coords) {
+		coord = face.coords[axis]
+	}
+	return face.normalizedCoordinate(fvar, axis, face.userCoordinate(fvar, axis, roundF2dot14(coord)))
+}
@@ -0,0 +1,384 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TableBASE stores the positions of the baselines of the scripts,
+// used to align the glyphs of different scripts on a line.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/base
+type TableBASE struct {
+	// Horizontal and Vertical are the baselines of the horizontal and
+	// vertical layouts, or nil if not provided.
+	Horizontal, Vertical *BaseAxis
+
+	store itemVariationStore // version 1.1 and later
+}
+
+var (
+	// BaselineRoman is the baseline of most alphabetic scripts.
+	BaselineRoman = MustNewTag("romn")
+	// BaselineHanging is the hanging baseline of the Indic scripts.
+	BaselineHanging = MustNewTag("hang")
+	// BaselineIdeographicBottom is the bottom of the ideographic em-box.
+	BaselineIdeographicBottom = MustNewTag("ideo")
+	// BaselineIdeographicTop is the top of the ideographic em-box.
+	BaselineIdeographicTop = MustNewTag("idtp")
+	// BaselineIdeographicFaceBottom is the bottom of the ideographic character face.
+	BaselineIdeographicFaceBottom = MustNewTag("icfb")
+	// BaselineIdeographicFaceTop is the top of the ideographic character face.
+	BaselineIdeographicFaceTop = MustNewTag("icft")
+	// BaselineMath is the baseline around which mathematical characters are centered.
+	BaselineMath = MustNewTag("math")
+)
+
+// BaseAxis stores the baselines of a layout direction.
+type BaseAxis struct {
+	// BaselineTags are the baselines (BaselineRoman, ...) defined
+	// for the scripts, sorted by tag.
+	BaselineTags []Tag
+	// Scripts are sorted by tag.
+	Scripts []BaseScript
+}
+
+// BaseScript stores the baselines of a script, and the extent
+// of its glyphs.
+type BaseScript struct {
+	Tag Tag
+	// DefaultBaseline is the index, in BaseAxis.BaselineTags,
+	// of the baseline used by the script.
+	DefaultBaseline uint16
+	// Baselines are the positions of the baselines of BaseAxis.BaselineTags,
+	// in the same order, or nil if the script does not provide them.
+	Baselines []BaseCoord
+	// DefaultMinMax is the extent of the glyphs for the languages
+	// not listed in Languages, or nil if not provided.
+	DefaultMinMax *MinMax
+	// Languages are sorted by tag.
+	Languages []BaseLangSys
+}
+
+// BaseLangSys is the extent of the glyphs of a language.
+type BaseLangSys struct {
+	Tag    Tag
+	MinMax MinMax
+}
+
+// MinMax is the extent of the glyphs of a script or a language,
+// possibly modified by some features. The coordinates are nil if missing.
+type MinMax struct {
+	Min, Max *BaseCoord
+	Features []FeatureMinMax
+}
+
+// FeatureMinMax is the extent of the glyphs when the feature Tag is applied.
+type FeatureMinMax struct {
+	Tag      Tag
+	Min, Max *BaseCoord
+}
+
+// BaseCoord is a position along the axis perpendicular to the
+// layout direction, in font units, given by a coordinate (format 1),
+// possibly replaced by the position of a contour point of a glyph (format 2),
+// or adjusted by a device table (format 3). See Face.ResolveBaseCoord.
+type BaseCoord struct {
+	Format         uint16
+	Coordinate     int16
+	ReferenceGlyph GID    // format 2
+	ContourPoint   uint16 // format 2
+
+	device deviceTable // format 3
+}
+
+func parseTableBASE(data []byte) (TableBASE, error) {
+	if len(data) < 8 {
+		return TableBASE{}, errEOF
+	}
+	major, minor := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	if major != 1 {
+		return TableBASE{}, fmt.Errorf("unsupported version %d.%d", major, minor)
+	}
+	var out TableBASE
+	for i, axis := range [2]**BaseAxis{&out.Horizontal, &out.Vertical} {
+		if offset := binary.BigEndian.Uint16(data[4+2*i:]); offset != 0 {
+			a, err := parseBaseAxis(data, uint32(offset))
+			if err != nil {
+				return out, err
+			}
+			*axis = &a
+		}
+	}
+	if minor >= 1 && len(data) >= 12 {
+		if offset := binary.BigEndian.Uint32(data[8:]); offset != 0 {
+			var err error
+			if out.store, err = parseItemVariationStore(data, offset); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseBaseAxis(data []byte, offset uint32) (BaseAxis, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return BaseAxis{}, errors.New("invalid axis table (EOF)")
+	}
+	data = data[offset:]
+	var out BaseAxis
+	if tagsOffset := int(binary.BigEndian.Uint16(data)); tagsOffset != 0 {
+		if len(data) < tagsOffset+2 {
+			return out, errors.New("invalid baseline tag list (EOF)")
+		}
+		count := int(binary.BigEndian.Uint16(data[tagsOffset:]))
+		if len(data) < tagsOffset+2+4*count {
+			return out, errors.New("invalid baseline tag list (EOF)")
+		}
+		out.BaselineTags = make([]Tag, count)
+		for i := range out.BaselineTags {
+			out.BaselineTags[i] = Tag(binary.BigEndian.Uint32(data[tagsOffset+2+4*i:]))
+		}
+	}
+
+	scriptsOffset := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < scriptsOffset+2 {
+		return out, errors.New("invalid base script list (EOF)")
+	}
+	scripts := data[scriptsOffset:]
+	count := int(binary.BigEndian.Uint16(scripts))
+	if len(scripts) < 2+6*count {
+		return out, errors.New("invalid base script list (EOF)")
+	}
+	out.Scripts = make([]BaseScript, count)
+	for i := range out.Scripts {
+		record := scripts[2+6*i:]
+		var err error
+		if out.Scripts[i], err = parseBaseScript(scripts, uint32(binary.BigEndian.Uint16(record[4:])), len(out.BaselineTags)); err != nil {
+			return out, err
+		}
+		out.Scripts[i].Tag = Tag(binary.BigEndian.Uint32(record))
+	}
+	return out, nil
+}
+
+func parseBaseScript(data []byte, offset uint32, numBaselines int) (BaseScript, error) {
+	if int64(offset)+6 > int64(len(data)) {
+		return BaseScript{}, errors.New("invalid base script table (EOF)")
+	}
+	data = data[offset:]
+	var (
+		out BaseScript
+		err error
+	)
+	if valuesOffset := int(binary.BigEndian.Uint16(data)); valuesOffset != 0 {
+		if len(data) < valuesOffset+4 {
+			return out, errors.New("invalid base values table (EOF)")
+		}
+		values := data[valuesOffset:]
+		out.DefaultBaseline = binary.BigEndian.Uint16(values)
+		count := int(binary.BigEndian.Uint16(values[2:]))
+		if count != numBaselines {
+			return out, fmt.Errorf("invalid number of baselines (%d != %d)", count, numBaselines)
+		}
+		if int(out.DefaultBaseline) >= count {
+			return out, fmt.Errorf("invalid default baseline index (%d >= %d)", out.DefaultBaseline, count)
+		}
+		if len(values) < 4+2*count {
+			return out, errors.New("invalid base values table (EOF)")
+		}
+		out.Baselines = make([]BaseCoord, count)
+		for i := range out.Baselines {
+			if out.Baselines[i], err = parseBaseCoord(values, uint32(binary.BigEndian.Uint16(values[4+2*i:]))); err != nil {
+				return out, err
+			}
+		}
+	}
+	if minMaxOffset := binary.BigEndian.Uint16(data[2:]); minMaxOffset != 0 {
+		minMax, err := parseMinMax(data, uint32(minMaxOffset))
+		if err != nil {
+			return out, err
+		}
+		out.DefaultMinMax = &minMax
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+6*count {
+		return out, errors.New("invalid base script table (EOF)")
+	}
+	out.Languages = make([]BaseLangSys, count)
+	for i := range out.Languages {
+		record := data[6+6*i:]
+		out.Languages[i].Tag = Tag(binary.BigEndian.Uint32(record))
+		if out.Languages[i].MinMax, err = parseMinMax(data, uint32(binary.BigEndian.Uint16(record[4:]))); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseMinMax(data []byte, offset uint32) (MinMax, error) {
+	if int64(offset)+6 > int64(len(data)) {
+		return MinMax{}, errors.New("invalid min max table (EOF)")
+	}
+	data = data[offset:]
+	var (
+		out MinMax
+		err error
+	)
+	if out.Min, err = parseOptionalBaseCoord(data, binary.BigEndian.Uint16(data)); err != nil {
+		return out, err
+	}
+	if out.Max, err = parseOptionalBaseCoord(data, binary.BigEndian.Uint16(data[2:])); err != nil {
+		return out, err
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+8*count {
+		return out, errors.New("invalid min max table (EOF)")
+	}
+	out.Features = make([]FeatureMinMax, count)
+	for i := range out.Features {
+		record := data[6+8*i:]
+		out.Features[i].Tag = Tag(binary.BigEndian.Uint32(record))
+		if out.Features[i].Min, err = parseOptionalBaseCoord(data, binary.BigEndian.Uint16(record[4:])); err != nil {
+			return out, err
+		}
+		if out.Features[i].Max, err = parseOptionalBaseCoord(data, binary.BigEndian.Uint16(record[6:])); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// parseOptionalBaseCoord is like parseBaseCoord, but returns nil for null offsets.
+func parseOptionalBaseCoord(data []byte, offset uint16) (*BaseCoord, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	coord, err := parseBaseCoord(data, uint32(offset))
+	return &coord, err
+}
+
+func parseBaseCoord(data []byte, offset uint32) (BaseCoord, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return BaseCoord{}, errors.New("invalid base coordinate (EOF)")
+	}
+	data = data[offset:]
+	out := BaseCoord{
+		Format:     binary.BigEndian.Uint16(data),
+		Coordinate: int16(binary.BigEndian.Uint16(data[2:])),
+	}
+	switch out.Format {
+	case 1:
+	case 2:
+		if len(data) < 8 {
+			return out, errors.New("invalid base coordinate (EOF)")
+		}
+		out.ReferenceGlyph = GID(binary.BigEndian.Uint16(data[4:]))
+		out.ContourPoint = binary.BigEndian.Uint16(data[6:])
+	case 3:
+		if len(data) < 6 {
+			return out, errors.New("invalid base coordinate (EOF)")
+		}
+		if deviceOffset := binary.BigEndian.Uint16(data[4:]); deviceOffset != 0 {
+			var err error
+			if out.device, err = parseDeviceTable(data, uint32(deviceOffset)); err != nil {
+				return out, err
+			}
+		}
+	default:
+		return out, fmt.Errorf("unsupported base coordinate format %d", out.Format)
+	}
+	return out, nil
+}
+
+// FindScript returns the baselines of the script `tag`, or false if not found.
+func (a *BaseAxis) FindScript(tag Tag) (*BaseScript, bool) {
+	i := sort.Search(len(a.Scripts), func(i int) bool { return a.Scripts[i].Tag >= tag })
+	if i < len(a.Scripts) && a.Scripts[i].Tag == tag {
+		return &a.Scripts[i], true
+	}
+	return nil, false
+}
+
+// base is the lazily parsed 'BASE' table.
+type base struct {
+	once  sync.Once
+	table *TableBASE // nil if missing or invalid
+}
+
+// BASE returns the baseline table, parsed on first use,
+// or false if the font has no 'BASE' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) BASE() (*TableBASE, bool) {
+	face.base.once.Do(func() {
+		if data, err := face.GetRawTable(tagBASE); err == nil {
+			if table, err := parseTableBASE(data); err != nil {
+				face.warnings.addError(face.tableError(tagBASE, err))
+			} else {
+				face.base.table = &table
+			}
+		}
+	})
+	return face.base.table, face.base.table != nil
+}
+
+// ResolveBaseCoord returns the position given by the coordinate of the
+// 'BASE' table, in font units, along the y axis for the horizontal
+// layout (or along the x axis if `vertical` is true). The coordinates of
+// format 2 use the position of their contour point, which is only supported
+// for TrueType outlines, and fall back to their coordinate otherwise.
+// The coordinates of format 3 are adjusted by the variations of variable
+// fonts (see SetVariations), and, when `ppem` is not zero, by the hinting
+// adjustments for this size.
+func (face *Face) ResolveBaseCoord(c BaseCoord, vertical bool, ppem uint16) float32 {
+	switch c.Format {
+	case 2:
+		if p, ok := face.contourPoint(c.ReferenceGlyph, c.ContourPoint); ok {
+			if vertical {
+				return p.x
+			}
+			return p.y
+		}
+	case 3:
+		var store *itemVariationStore
+		if table, ok := face.BASE(); ok {
+			store = &table.store
+		}
+		return float32(c.Coordinate) + face.deviceDelta(store, c.device, ppem)
+	}
+	return float32(c.Coordinate)
+}
+
+// Baseline returns the position of the baseline `baseline` (BaselineRoman, ...)
+// of the script `script`, in font units, for the horizontal layout (or the
+// vertical layout if `vertical` is true), adjusted by the variations of variable
+// fonts (see ResolveBaseCoord). The baselines of DefaultScript are used
+// if the script is not found. It returns false if the font has no
+// 'BASE' table, or if the baseline is not defined for the script.
+func (face *Face) Baseline(script, baseline Tag, vertical bool) (float32, bool) {
+	table, ok := face.BASE()
+	if !ok {
+		return 0, false
+	}
+	axis := table.Horizontal
+	if vertical {
+		axis = table.Vertical
+	}
+	if axis == nil {
+		return 0, false
+	}
+	s, ok := axis.FindScript(script)
+	if !ok {
+		if s, ok = axis.FindScript(DefaultScript); !ok {
+			return 0, false
+		}
+	}
+	for i, tag := range axis.BaselineTags {
+		if tag == baseline && i < len(s.Baselines) {
+			return face.ResolveBaseCoord(s.Baselines[i], vertical, 0), true
+		}
+	}
+	return 0, false
+}
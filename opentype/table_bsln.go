@@ -0,0 +1,148 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableBsln stores the baselines of the AAT fonts, which are used to align
+// the glyphs of different scripts, such as ideographic and Latin glyphs.
+// The positions of the baselines are given by deltas (formats 0 and 1) or by
+// the control points of a standard glyph (formats 2 and 3), and the
+// baselines of the glyphs are given by their class (formats 1 and 3).
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6bsln.html
+type TableBsln struct {
+	Format uint16
+	// DefaultBaseline is the baseline class of the glyphs
+	// not assigned to another class (see BaselineClass).
+	DefaultBaseline uint16
+	// Deltas are the positions of the baselines, in font units, relative
+	// to the default baseline, indexed by baseline class (formats 0 and 1).
+	Deltas [32]int16
+	// StandardGlyph is the glyph whose control points give
+	// the positions of the baselines (formats 2 and 3).
+	StandardGlyph GID
+	// ControlPoints are the control points giving the positions of the
+	// baselines, indexed by baseline class, or 0xFFFF for the undefined
+	// baselines (formats 2 and 3).
+	ControlPoints [32]uint16
+
+	classes ClassDef // nil for formats 0 and 2
+}
+
+// The baseline classes predefined by the 'bsln' table.
+const (
+	BslnRoman = iota
+	BslnIdeographicCentered
+	BslnIdeographicLow
+	BslnHanging
+	BslnMath
+)
+
+// BaselineClass returns the baseline class of the glyph.
+func (t *TableBsln) BaselineClass(gid GID) uint16 {
+	if t.classes != nil {
+		if class, ok := t.classes.Class(gid); ok {
+			return class
+		}
+	}
+	return t.DefaultBaseline
+}
+
+func parseTableBsln(data []byte, numGlyphs int) (TableBsln, error) {
+	if len(data) < 8 {
+		return TableBsln{}, errEOF
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 {
+		return TableBsln{}, fmt.Errorf("unsupported version %x", version)
+	}
+	out := TableBsln{Format: binary.BigEndian.Uint16(data[4:]), DefaultBaseline: binary.BigEndian.Uint16(data[6:])}
+	if out.DefaultBaseline >= 32 {
+		return out, fmt.Errorf("invalid default baseline %d", out.DefaultBaseline)
+	}
+	parts := data[8:]
+	switch out.Format {
+	case 0, 1:
+		if len(parts) < 64 {
+			return out, errors.New("invalid baseline deltas (EOF)")
+		}
+		for i := range out.Deltas {
+			out.Deltas[i] = int16(binary.BigEndian.Uint16(parts[2*i:]))
+		}
+		parts = parts[64:]
+	case 2, 3:
+		if len(parts) < 66 {
+			return out, errors.New("invalid baseline control points (EOF)")
+		}
+		out.StandardGlyph = GID(binary.BigEndian.Uint16(parts))
+		for i := range out.ControlPoints {
+			out.ControlPoints[i] = binary.BigEndian.Uint16(parts[2+2*i:])
+		}
+		parts = parts[66:]
+	default:
+		return out, fmt.Errorf("unsupported format %d", out.Format)
+	}
+	if out.Format == 1 || out.Format == 3 {
+		var err error
+		if out.classes, err = parseAATLookup(parts, 0, numGlyphs); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// bsln is the lazily parsed 'bsln' table.
+type bsln struct {
+	once  sync.Once
+	table *TableBsln // nil if missing or invalid
+}
+
+// Bsln returns the baseline table, parsed on first use,
+// or false if the font has no 'bsln' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Bsln() (*TableBsln, bool) {
+	face.bsln.once.Do(func() {
+		if data, err := face.GetRawTable(tagBsln); err == nil {
+			if table, err := parseTableBsln(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagBsln, err))
+			} else {
+				face.bsln.table = &table
+			}
+		}
+	})
+	return face.bsln.table, face.bsln.table != nil
+}
+
+// BaselineDelta returns the position of the baseline `class`, in font
+// units, relative to the default baseline of the 'bsln' table, for horizontal
+// text. For formats 2 and 3, it is given by the control point of the standard
+// glyph, which is only supported for TrueType outlines.
+// It returns false if the font has no 'bsln' table, or if the
+// baseline is not defined.
+func (face *Face) BaselineDelta(class uint16) (float32, bool) {
+	table, ok := face.Bsln()
+	if !ok || class >= 32 {
+		return 0, false
+	}
+	if table.Format <= 1 {
+		return float32(table.Deltas[class]), true
+	}
+	point, defaultPoint := table.ControlPoints[class], table.ControlPoints[table.DefaultBaseline]
+	if point == 0xFFFF {
+		return 0, false
+	}
+	p, ok := face.contourPoint(table.StandardGlyph, point)
+	if !ok {
+		return 0, false
+	}
+	if defaultPoint == 0xFFFF {
+		return p.y, true
+	}
+	origin, ok := face.contourPoint(table.StandardGlyph, defaultPoint)
+	if !ok {
+		return 0, false
+	}
+	return p.y - origin.y, true
+}
@@ -0,0 +1,36 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// cbdtGlyph decodes the image stored at `data`, for the formats 17, 18 and 19
+// of the 'CBDT' table, which all store PNG files.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cbdt
+func cbdtGlyph(data []byte, st *indexSubtable, strikeFlags uint8) (png []byte, metrics bitmapMetrics, err error) {
+	switch st.imageFormat {
+	case 17:
+		if len(data) < 9 {
+			return nil, metrics, errEOF
+		}
+		metrics, data = parseSmallGlyphMetrics(data, strikeFlags), data[5:]
+	case 18:
+		if len(data) < 12 {
+			return nil, metrics, errEOF
+		}
+		metrics, data = parseBigGlyphMetrics(data), data[8:]
+	case 19:
+		if st.indexFormat != 2 && st.indexFormat != 5 || len(data) < 4 {
+			return nil, metrics, errors.New("invalid bitmap format 19")
+		}
+		metrics = st.metrics
+	default:
+		return nil, metrics, errors.New("unsupported bitmap format")
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint64(len(data)) < 4+uint64(length) {
+		return nil, metrics, errEOF
+	}
+	return data[4 : 4+length], metrics, nil
+}
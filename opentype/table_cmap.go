@@ -0,0 +1,700 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/benoitkugler/textlayout/fonts"
+)
+
+type (
+	// Cmap stores a compact representation of a cmap,
+	// offering both on-demand rune lookup and full rune range.
+	Cmap = fonts.Cmap
+	// CmapIter is an interator over a Cmap.
+	CmapIter = fonts.CmapIter
+	// CmapEncoding identifies which system is used to describe characters.
+	CmapEncoding = fonts.CmapEncoding
+)
+
+// PlatformID represents the platform id for entries in the cmap and name tables.
+type PlatformID uint16
+
+const (
+	PlatformUnicode PlatformID = iota
+	PlatformMac
+	PlatformIso // deprecated
+	PlatformMicrosoft
+	PlatformCustom
+	_
+	_
+	PlatformAdobe // artificial
+)
+
+// PlatformEncodingID represents the platform specific id for entries in the cmap and name tables.
+type PlatformEncodingID uint16
+
+// Windows and Unicode platform encodings, as used in cmap subtables.
+const (
+	PEMicrosoftSymbolCs  PlatformEncodingID = 0
+	PEMicrosoftUnicodeCs PlatformEncodingID = 1
+	PEMicrosoftSjis      PlatformEncodingID = 2
+	PEMicrosoftPrc       PlatformEncodingID = 3
+	PEMicrosoftBig5      PlatformEncodingID = 4
+	PEMicrosoftWansung   PlatformEncodingID = 5
+	PEMicrosoftJohab     PlatformEncodingID = 6
+	PEMicrosoftUcs4      PlatformEncodingID = 10
+
+	PEUnicodeDefault   PlatformEncodingID = 0
+	PEUnicode11        PlatformEncodingID = 1
+	PEUnicodeIso10646  PlatformEncodingID = 2
+	PEUnicodeBMP       PlatformEncodingID = 3
+	PEUnicodeFull      PlatformEncodingID = 4
+	PEUnicodeVariation PlatformEncodingID = 5
+	PEUnicodeFull13    PlatformEncodingID = 6
+
+	PEMacRoman PlatformEncodingID = 0
+)
+
+// CmapID identifies a cmap subtable.
+type CmapID struct {
+	Platform PlatformID
+	Encoding PlatformEncodingID
+}
+
+// IsSymbolic returns true for the Microsoft Symbol encoding.
+func (c CmapID) IsSymbolic() bool {
+	return c.Platform == PlatformMicrosoft && c.Encoding == PEMicrosoftSymbolCs
+}
+
+// CmapSubtable is one of the mappings stored in the 'cmap' table.
+type CmapSubtable struct {
+	ID   CmapID
+	Cmap Cmap
+}
+
+// TableCmap defines the mapping of character codes to the glyph index values used in the font.
+// It may contain more than one subtable, in order to support more than one character encoding scheme.
+// Unsupported subtable formats are ignored.
+type TableCmap struct {
+	Cmaps []CmapSubtable
+
+	// Variations is the content of the format 14 subtable, if any.
+	Variations UnicodeVariations
+}
+
+// FindSubtable returns the cmap for the given platform and encoding, or nil if not present.
+func (t *TableCmap) FindSubtable(id CmapID) Cmap {
+	for _, cmap := range t.Cmaps {
+		if cmap.ID == id {
+			return cmap.Cmap
+		}
+	}
+	return nil
+}
+
+// BestEncoding returns the widest encoding supported. For valid fonts,
+// the returned cmap won't be nil.
+func (t TableCmap) BestEncoding() (Cmap, CmapEncoding) {
+	// direct adaptation from harfbuzz/src/hb-ot-cmap-table.hh
+
+	// Prefer symbol if available.
+	if subtable := t.FindSubtable(CmapID{PlatformMicrosoft, PEMicrosoftSymbolCs}); subtable != nil {
+		return subtable, fonts.EncSymbol
+	}
+
+	/* 32-bit subtables. */
+	if cmap := t.FindSubtable(CmapID{PlatformMicrosoft, PEMicrosoftUcs4}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+	if cmap := t.FindSubtable(CmapID{PlatformUnicode, PEUnicodeFull13}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+	if cmap := t.FindSubtable(CmapID{PlatformUnicode, PEUnicodeFull}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+
+	/* 16-bit subtables. */
+	if cmap := t.FindSubtable(CmapID{PlatformMicrosoft, PEMicrosoftUnicodeCs}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+	if cmap := t.FindSubtable(CmapID{PlatformUnicode, PEUnicodeBMP}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+	if cmap := t.FindSubtable(CmapID{PlatformUnicode, PEUnicodeIso10646}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+	if cmap := t.FindSubtable(CmapID{PlatformUnicode, PEUnicode11}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+	if cmap := t.FindSubtable(CmapID{PlatformUnicode, PEUnicodeDefault}); cmap != nil {
+		return cmap, fonts.EncUnicode
+	}
+
+	// Mac Roman, then any cmap
+	if cmap := t.FindSubtable(CmapID{PlatformMac, PEMacRoman}); cmap != nil {
+		return cmap, fonts.EncOther
+	}
+	if len(t.Cmaps) != 0 {
+		return t.Cmaps[0].Cmap, fonts.EncOther
+	}
+
+	return nil, fonts.EncOther
+}
+
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cmap
+// Unsupported subtables and recoverable errors are reported in the warnings
+// of the face. An invalid format 14 subtable is ignored, so that the
+// other subtables are still used.
+func (face *Face) parseTableCmap(input []byte) (TableCmap, error) {
+	w := &face.warnings
+	const headerSize = 4
+	if len(input) < headerSize {
+		return TableCmap{}, errEOF
+	}
+	numTables := int(binary.BigEndian.Uint16(input[2:]))
+	if len(input) < headerSize+8*numTables {
+		return TableCmap{}, errEOF
+	}
+
+	var out TableCmap
+	parsed := map[uint32]Cmap{} // subtables may be shared between encoding records
+	for i := 0; i < numTables; i++ {
+		record := input[headerSize+8*i:]
+		id := CmapID{
+			Platform: PlatformID(binary.BigEndian.Uint16(record)),
+			Encoding: PlatformEncodingID(binary.BigEndian.Uint16(record[2:])),
+		}
+		offset := binary.BigEndian.Uint32(record[4:])
+		if int(offset) < len(input) && len(input)-int(offset) >= 2 && binary.BigEndian.Uint16(input[offset:]) == 14 {
+			if out.Variations != nil { // only one format 14 subtable is expected
+				continue
+			}
+			uv, err := parseCmapFormat14(input[offset:])
+			if err != nil {
+				face.warnings.addError(face.tableError(tagCmap, fmt.Errorf("invalid format 14 subtable: %s", err)))
+				continue
+			}
+			out.Variations = uv
+			continue
+		}
+		if cmap, ok := parsed[offset]; ok {
+			out.Cmaps = append(out.Cmaps, CmapSubtable{ID: id, Cmap: cmap})
+			continue
+		}
+		if int(offset) >= len(input) || len(input)-int(offset) < 2 {
+			return out, errors.New("subtable offset (EOF)")
+		}
+
+		cmap, err := parseCmapSubtable(input[offset:], w)
+		if err != nil {
+			return out, err
+		}
+		if cmap == nil { // unsupported format
+			w.add("'cmap' table: unsupported subtable format %d (platform %d, encoding %d): ignored",
+				binary.BigEndian.Uint16(input[offset:]), id.Platform, id.Encoding)
+			continue
+		}
+		parsed[offset] = cmap
+		out.Cmaps = append(out.Cmaps, CmapSubtable{ID: id, Cmap: cmap})
+	}
+
+	return out, nil
+}
+
+// return nil, nil for unsupported formats
+func parseCmapSubtable(input []byte, w *warnings) (Cmap, error) {
+	format := binary.BigEndian.Uint16(input)
+	switch format {
+	case 0:
+		return parseCmapFormat0(input)
+	case 4:
+		return parseCmapFormat4(input, w)
+	case 6:
+		return parseCmapFormat6(input)
+	case 10:
+		return parseCmapFormat10(input)
+	case 12:
+		return parseCmapFormat12(input, false)
+	case 13:
+		return parseCmapFormat12(input, true)
+	default:
+		return nil, nil
+	}
+}
+
+// ---------------------------------- format 0 ----------------------------------
+
+type cmap0 [256]GID
+
+func parseCmapFormat0(input []byte) (*cmap0, error) {
+	if len(input) < 6+256 {
+		return nil, errors.New("subtable format 0 (EOF)")
+	}
+	out := new(cmap0)
+	for i, b := range input[6 : 6+256] {
+		out[i] = GID(b)
+	}
+	return out, nil
+}
+
+type cmap0Iter struct {
+	data *cmap0
+	pos  int
+}
+
+func (it *cmap0Iter) Next() bool {
+	for ; it.pos < len(it.data); it.pos++ {
+		if it.data[it.pos] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *cmap0Iter) Char() (rune, GID) {
+	r := rune(it.pos)
+	it.pos++
+	return r, it.data[r]
+}
+
+func (s *cmap0) Iter() CmapIter { return &cmap0Iter{data: s} }
+
+func (s *cmap0) Lookup(r rune) (GID, bool) {
+	if r < 0 || r >= 256 || s[r] == 0 {
+		return 0, false
+	}
+	return s[r], true
+}
+
+// ---------------------------------- format 4 ----------------------------------
+
+type cmap4Segment struct {
+	start, end, delta uint16
+	// if non zero, the index into glyphIDArray of the glyph
+	// for `start`, plus one
+	indexOffset int
+}
+
+type cmap4 struct {
+	segments     []cmap4Segment
+	glyphIDArray []uint16
+}
+
+func parseCmapFormat4(input []byte, w *warnings) (cmap4, error) {
+	if len(input) < 14 {
+		return cmap4{}, errors.New("subtable format 4 (EOF)")
+	}
+	length := int(binary.BigEndian.Uint16(input[2:]))
+	if length > len(input) {
+		// some fonts have a invalid length, ignore it
+		w.addError(fmt.Errorf("'cmap' table: invalid subtable format 4 length %d", length))
+		length = len(input)
+	}
+	input = input[:length]
+	segCount := int(binary.BigEndian.Uint16(input[6:]) / 2)
+	const headerSize = 14
+	eLength := 8*segCount + 2 // 2 for the reservedPad field
+	if len(input) < headerSize+eLength {
+		return cmap4{}, errors.New("subtable format 4 (EOF)")
+	}
+	endCodes := input[headerSize:]
+	startCodes := endCodes[2*segCount+2:]
+	deltas := startCodes[2*segCount:]
+	rangeOffsets := deltas[2*segCount:]
+	glyphIDArray := rangeOffsets[2*segCount:]
+
+	out := cmap4{
+		segments:     make([]cmap4Segment, segCount),
+		glyphIDArray: make([]uint16, len(glyphIDArray)/2),
+	}
+	for i := range out.glyphIDArray {
+		out.glyphIDArray[i] = binary.BigEndian.Uint16(glyphIDArray[2*i:])
+	}
+	for i := range out.segments {
+		seg := &out.segments[i]
+		seg.end = binary.BigEndian.Uint16(endCodes[2*i:])
+		seg.start = binary.BigEndian.Uint16(startCodes[2*i:])
+		seg.delta = binary.BigEndian.Uint16(deltas[2*i:])
+		if seg.start > seg.end {
+			return cmap4{}, fmt.Errorf("invalid subtable format 4 segment (%d > %d)", seg.start, seg.end)
+		}
+		if ro := int(binary.BigEndian.Uint16(rangeOffsets[2*i:])); ro != 0 {
+			// The offset is relative to its own position in the rangeOffsets array,
+			// we convert it to an index into glyphIDArray.
+			index := ro/2 - (segCount - i)
+			if index < 0 {
+				return cmap4{}, errors.New("invalid subtable format 4 range offset")
+			}
+			seg.indexOffset = index + 1
+		}
+	}
+	return out, nil
+}
+
+func (s cmap4) glyph(seg cmap4Segment, r uint16) (GID, bool) {
+	if seg.indexOffset == 0 {
+		g := r + seg.delta // wraps around 0xFFFF
+		return GID(g), g != 0
+	}
+	index := seg.indexOffset - 1 + int(r-seg.start)
+	if index >= len(s.glyphIDArray) {
+		return 0, false
+	}
+	g := s.glyphIDArray[index]
+	if g == 0 {
+		return 0, false
+	}
+	g += seg.delta
+	return GID(g), g != 0
+}
+
+func (s cmap4) Lookup(r rune) (GID, bool) {
+	if r < 0 || r > 0xFFFF {
+		return 0, false
+	}
+	u := uint16(r)
+	i := sort.Search(len(s.segments), func(i int) bool { return s.segments[i].end >= u })
+	if i == len(s.segments) || s.segments[i].start > u {
+		return 0, false
+	}
+	return s.glyph(s.segments[i], u)
+}
+
+type cmap4Iter struct {
+	data     *cmap4
+	segIndex int
+	r        uint32 // current rune in the segment, as uint32 to avoid overflow
+	started  bool
+
+	curRune  rune
+	curGlyph GID
+}
+
+func (it *cmap4Iter) Next() bool {
+	for it.segIndex < len(it.data.segments) {
+		seg := it.data.segments[it.segIndex]
+		if !it.started {
+			it.r = uint32(seg.start)
+			it.started = true
+		}
+		for ; it.r <= uint32(seg.end); it.r++ {
+			if it.r == 0xFFFF { // sentinel
+				break
+			}
+			if g, ok := it.data.glyph(seg, uint16(it.r)); ok {
+				it.curRune, it.curGlyph = rune(it.r), g
+				it.r++
+				return true
+			}
+		}
+		it.segIndex++
+		it.started = false
+	}
+	return false
+}
+
+func (it *cmap4Iter) Char() (rune, GID) { return it.curRune, it.curGlyph }
+
+func (s cmap4) Iter() CmapIter { return &cmap4Iter{data: &s} }
+
+// ---------------------------------- format 6 and 10 ----------------------------------
+
+// cmap6 is used for formats 6 and 10 (trimmed mappings).
+type cmap6 struct {
+	firstCode rune
+	entries   []GID
+}
+
+func parseCmapFormat6(input []byte) (cmap6, error) {
+	const headerSize = 10
+	if len(input) < headerSize {
+		return cmap6{}, errors.New("subtable format 6 (EOF)")
+	}
+	firstCode := rune(binary.BigEndian.Uint16(input[6:]))
+	entryCount := int(binary.BigEndian.Uint16(input[8:]))
+	if len(input) < headerSize+2*entryCount {
+		return cmap6{}, errors.New("subtable format 6 (EOF)")
+	}
+	out := cmap6{firstCode: firstCode, entries: make([]GID, entryCount)}
+	for i := range out.entries {
+		out.entries[i] = GID(binary.BigEndian.Uint16(input[headerSize+2*i:]))
+	}
+	return out, nil
+}
+
+func parseCmapFormat10(input []byte) (cmap6, error) {
+	const headerSize = 20
+	if len(input) < headerSize {
+		return cmap6{}, errors.New("subtable format 10 (EOF)")
+	}
+	firstCode := rune(binary.BigEndian.Uint32(input[12:]))
+	entryCount := binary.BigEndian.Uint32(input[16:])
+	if uint64(len(input)) < headerSize+2*uint64(entryCount) {
+		return cmap6{}, errors.New("subtable format 10 (EOF)")
+	}
+	out := cmap6{firstCode: firstCode, entries: make([]GID, entryCount)}
+	for i := range out.entries {
+		out.entries[i] = GID(binary.BigEndian.Uint16(input[headerSize+2*i:]))
+	}
+	return out, nil
+}
+
+func (s cmap6) Lookup(r rune) (GID, bool) {
+	if r < s.firstCode {
+		return 0, false
+	}
+	index := int(r - s.firstCode)
+	if index >= len(s.entries) || s.entries[index] == 0 {
+		return 0, false
+	}
+	return s.entries[index], true
+}
+
+type cmap6Iter struct {
+	data *cmap6
+	pos  int
+}
+
+func (it *cmap6Iter) Next() bool {
+	for ; it.pos < len(it.data.entries); it.pos++ {
+		if it.data.entries[it.pos] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *cmap6Iter) Char() (rune, GID) {
+	entry := it.data.entries[it.pos]
+	r := rune(it.pos) + it.data.firstCode
+	it.pos++
+	return r, entry
+}
+
+func (s cmap6) Iter() CmapIter { return &cmap6Iter{data: &s} }
+
+// ---------------------------------- format 12 and 13 ----------------------------------
+
+const maxRune = 0x10FFFF
+
+type cmap12Group struct {
+	start, end rune
+	glyph      GID
+}
+
+// cmap12 is used for formats 12 and 13 (segmented coverage).
+type cmap12 struct {
+	groups []cmap12Group
+	// true for format 13 : all runes in a group are mapped to the same glyph
+	manyToOne bool
+}
+
+func parseCmapFormat12(input []byte, manyToOne bool) (cmap12, error) {
+	const headerSize = 16
+	if len(input) < headerSize {
+		return cmap12{}, errors.New("subtable format 12/13 (EOF)")
+	}
+	numGroups := binary.BigEndian.Uint32(input[12:])
+	if uint64(len(input)) < headerSize+12*uint64(numGroups) {
+		return cmap12{}, errors.New("subtable format 12/13 (EOF)")
+	}
+
+	out := cmap12{groups: make([]cmap12Group, numGroups), manyToOne: manyToOne}
+	for i := range out.groups {
+		chunk := input[headerSize+12*i:]
+		g := cmap12Group{
+			start: rune(binary.BigEndian.Uint32(chunk)),
+			end:   rune(binary.BigEndian.Uint32(chunk[4:])),
+			glyph: GID(binary.BigEndian.Uint32(chunk[8:])),
+		}
+		if g.start > g.end || g.start < 0 {
+			return cmap12{}, fmt.Errorf("invalid subtable format 12/13 group (%d > %d)", g.start, g.end)
+		}
+		out.groups[i] = g
+	}
+	return out, nil
+}
+
+func (s cmap12) glyph(group cmap12Group, r rune) GID {
+	if s.manyToOne {
+		return group.glyph
+	}
+	return group.glyph + GID(r-group.start)
+}
+
+func (s cmap12) Lookup(r rune) (GID, bool) {
+	i := sort.Search(len(s.groups), func(i int) bool { return s.groups[i].end >= r })
+	if i == len(s.groups) || s.groups[i].start > r {
+		return 0, false
+	}
+	g := s.glyph(s.groups[i], r)
+	return g, g != 0
+}
+
+type cmap12Iter struct {
+	data     *cmap12
+	groupIdx int
+	r        rune
+	started  bool
+
+	curRune  rune
+	curGlyph GID
+}
+
+func (it *cmap12Iter) Next() bool {
+	for it.groupIdx < len(it.data.groups) {
+		group := it.data.groups[it.groupIdx]
+		if !it.started {
+			it.r = group.start
+			it.started = true
+		}
+		for ; it.r <= group.end && it.r <= maxRune; it.r++ {
+			if g := it.data.glyph(group, it.r); g != 0 {
+				it.curRune, it.curGlyph = it.r, g
+				it.r++
+				return true
+			}
+		}
+		it.groupIdx++
+		it.started = false
+	}
+	return false
+}
+
+func (it *cmap12Iter) Char() (rune, GID) { return it.curRune, it.curGlyph }
+
+func (s cmap12) Iter() CmapIter { return &cmap12Iter{data: &s} }
+
+// ---------------------------------- format 14 ----------------------------------
+
+// UnicodeVariations is the content of a cmap format 14 subtable, which maps
+// Unicode variation sequences (a base character followed by a variation selector)
+// to glyphs. It is sorted by selector.
+type UnicodeVariations []VariationSelector
+
+// VariationSelector stores the sequences using a variation selector.
+type VariationSelector struct {
+	Selector rune
+	// DefaultUVS lists the base characters whose sequence
+	// is rendered with the default glyph, as given by the regular cmap.
+	// It is sorted by start.
+	DefaultUVS []UnicodeRange
+	// NonDefaultUVS lists the base characters whose sequence
+	// is rendered with a specific glyph. It is sorted by rune.
+	NonDefaultUVS []UVSMapping
+}
+
+// UnicodeRange is an inclusive range of runes, from Start to Start+AdditionalCount.
+type UnicodeRange struct {
+	Start           rune
+	AdditionalCount uint8
+}
+
+// UVSMapping maps a base character to the glyph used for its variation sequence.
+type UVSMapping struct {
+	Unicode rune
+	Glyph   GID
+}
+
+// Variation status, as returned by UnicodeVariations.Lookup
+const (
+	UVSNotFound = iota // the sequence is not supported
+	UVSDefault         // the default glyph of the base character must be used
+	UVSSpecific        // a specific glyph is used
+)
+
+// Lookup returns the glyph to use for the sequence `r` followed by `selector`.
+// The returned status is one of UVSNotFound, UVSDefault (in which case the
+// returned glyph is not relevant, see TableCmap.Cmaps) or UVSSpecific.
+func (uv UnicodeVariations) Lookup(r, selector rune) (GID, int) {
+	i := sort.Search(len(uv), func(i int) bool { return uv[i].Selector >= selector })
+	if i == len(uv) || uv[i].Selector != selector {
+		return 0, UVSNotFound
+	}
+	vs := uv[i]
+
+	ranges := vs.DefaultUVS
+	j := sort.Search(len(ranges), func(j int) bool { return ranges[j].Start+rune(ranges[j].AdditionalCount) >= r })
+	if j < len(ranges) && ranges[j].Start <= r {
+		return 0, UVSDefault
+	}
+
+	mappings := vs.NonDefaultUVS
+	j = sort.Search(len(mappings), func(j int) bool { return mappings[j].Unicode >= r })
+	if j < len(mappings) && mappings[j].Unicode == r {
+		return mappings[j].Glyph, UVSSpecific
+	}
+	return 0, UVSNotFound
+}
+
+func uint24(b []byte) rune { return rune(b[0])<<16 | rune(b[1])<<8 | rune(b[2]) }
+
+func parseCmapFormat14(input []byte) (UnicodeVariations, error) {
+	const headerSize = 10
+	if len(input) < headerSize {
+		return nil, errors.New("subtable format 14 (EOF)")
+	}
+	count := binary.BigEndian.Uint32(input[6:])
+	if uint64(len(input)) < headerSize+11*uint64(count) {
+		return nil, errors.New("subtable format 14 (EOF)")
+	}
+	out := make(UnicodeVariations, count)
+	for i := range out {
+		record := input[headerSize+11*i:]
+		vs := VariationSelector{Selector: uint24(record)}
+		if i > 0 && vs.Selector <= out[i-1].Selector {
+			return nil, fmt.Errorf("invalid subtable format 14 selectors (%U <= %U)", vs.Selector, out[i-1].Selector)
+		}
+		var err error
+		if offset := binary.BigEndian.Uint32(record[3:]); offset != 0 {
+			vs.DefaultUVS, err = parseDefaultUVS(input, offset)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if offset := binary.BigEndian.Uint32(record[7:]); offset != 0 {
+			vs.NonDefaultUVS, err = parseNonDefaultUVS(input, offset)
+			if err != nil {
+				return nil, err
+			}
+		}
+		out[i] = vs
+	}
+	return out, nil
+}
+
+func parseDefaultUVS(input []byte, offset uint32) ([]UnicodeRange, error) {
+	if uint64(len(input)) < uint64(offset)+4 {
+		return nil, errors.New("subtable format 14 default UVS (EOF)")
+	}
+	input = input[offset:]
+	count := binary.BigEndian.Uint32(input)
+	if uint64(len(input)) < 4+4*uint64(count) {
+		return nil, errors.New("subtable format 14 default UVS (EOF)")
+	}
+	out := make([]UnicodeRange, count)
+	for i := range out {
+		out[i] = UnicodeRange{Start: uint24(input[4+4*i:]), AdditionalCount: input[4+4*i+3]}
+	}
+	return out, nil
+}
+
+func parseNonDefaultUVS(input []byte, offset uint32) ([]UVSMapping, error) {
+	if uint64(len(input)) < uint64(offset)+4 {
+		return nil, errors.New("subtable format 14 non default UVS (EOF)")
+	}
+	input = input[offset:]
+	count := binary.BigEndian.Uint32(input)
+	if uint64(len(input)) < 4+5*uint64(count) {
+		return nil, errors.New("subtable format 14 non default UVS (EOF)")
+	}
+	out := make([]UVSMapping, count)
+	for i := range out {
+		chunk := input[4+5*i:]
+		out[i] = UVSMapping{Unicode: uint24(chunk), Glyph: GID(binary.BigEndian.Uint16(chunk[3:]))}
+	}
+	return out, nil
+}
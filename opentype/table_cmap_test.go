@@ -0,0 +1,43 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestCmapFormat14(t *testing.T) {
+	face := loadFace(t, "cmap/CMAP14.otf")
+	data, err := face.GetRawTable(tagCmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := face.VariationGlyph(0x82a6, 0xe0100); !ok {
+		t.Fatal("missing variation glyph")
+	}
+
+	// break the format 14 subtable
+	data = append([]byte(nil), data...)
+	found := false
+	for i := 0; i < int(binary.BigEndian.Uint16(data[2:])); i++ {
+		offset := binary.BigEndian.Uint32(data[8+8*i:])
+		if binary.BigEndian.Uint16(data[offset:]) == 14 {
+			binary.BigEndian.PutUint32(data[offset+6:], 0xFFFFFF) // number of selector records
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("missing format 14 subtable")
+	}
+
+	broken := &Face{tables: face.tables}
+	cmap, err := broken.parseTableCmap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmap.Variations != nil || len(cmap.Cmaps) == 0 {
+		t.Errorf("expected the other subtables only, got %d subtables and variations %v", len(cmap.Cmaps), cmap.Variations)
+	}
+	if broken.warnings.firstError() == nil {
+		t.Error("the invalid subtable should be reported")
+	}
+}
@@ -0,0 +1,464 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-text/font"
+)
+
+// tableCOLR stores the color glyphs, defined as layers of other glyphs.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/colr
+type tableCOLR struct {
+	baseGlyphs []baseGlyphRecord // sorted by glyph
+	layers     []ColorLayer
+
+	// version 1 paint graphs, decoded on demand from data
+	data        []byte
+	basePaints  []basePaintRecord // sorted by glyph
+	layerPaints []uint32          // offsets of the paints, from the start of the table
+	clips       []clipRecord      // sorted by glyph range
+
+	varIndexMap    deltaSetIndexMap
+	hasVarIndexMap bool
+	store          itemVariationStore
+}
+
+type basePaintRecord struct {
+	glyph  GID
+	offset uint32 // from the start of the table
+}
+
+type clipRecord struct {
+	start, end GID
+	offset     uint32 // of the clip box, from the start of the table
+}
+
+type baseGlyphRecord struct {
+	glyph      GID
+	firstLayer int
+	numLayers  int
+}
+
+func parseTableCOLR(data []byte) (tableCOLR, error) {
+	const headerSize = 14
+	if len(data) < headerSize {
+		return tableCOLR{}, errEOF
+	}
+	numBaseGlyphs := int(binary.BigEndian.Uint16(data[2:]))
+	baseGlyphsOffset := int64(binary.BigEndian.Uint32(data[4:]))
+	layersOffset := int64(binary.BigEndian.Uint32(data[8:]))
+	numLayers := int(binary.BigEndian.Uint16(data[12:]))
+	if int64(len(data)) < baseGlyphsOffset+6*int64(numBaseGlyphs) || int64(len(data)) < layersOffset+4*int64(numLayers) {
+		return tableCOLR{}, errEOF
+	}
+
+	var out tableCOLR
+	out.baseGlyphs = make([]baseGlyphRecord, numBaseGlyphs)
+	for i := range out.baseGlyphs {
+		record := data[baseGlyphsOffset+6*int64(i):]
+		out.baseGlyphs[i] = baseGlyphRecord{
+			glyph:      GID(binary.BigEndian.Uint16(record)),
+			firstLayer: int(binary.BigEndian.Uint16(record[2:])),
+			numLayers:  int(binary.BigEndian.Uint16(record[4:])),
+		}
+	}
+	out.layers = make([]ColorLayer, numLayers)
+	for i := range out.layers {
+		record := data[layersOffset+4*int64(i):]
+		out.layers[i] = ColorLayer{
+			Glyph:        GID(binary.BigEndian.Uint16(record)),
+			PaletteIndex: binary.BigEndian.Uint16(record[2:]),
+		}
+	}
+
+	if version := binary.BigEndian.Uint16(data); version == 0 {
+		return out, nil
+	}
+	const headerSizeV1 = 34
+	if len(data) < headerSizeV1 {
+		return out, errEOF
+	}
+	out.data = data
+	var err error
+	if offset := binary.BigEndian.Uint32(data[14:]); offset != 0 {
+		if out.basePaints, err = parseBasePaints(data, offset); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint32(data[18:]); offset != 0 {
+		if out.layerPaints, err = parseLayerPaints(data, offset); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint32(data[22:]); offset != 0 {
+		if out.clips, err = parseClipList(data, offset); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint32(data[26:]); offset != 0 {
+		if out.varIndexMap, err = parseDeltaSetIndexMap(data, offset); err != nil {
+			return out, err
+		}
+		out.hasVarIndexMap = true
+	}
+	if offset := binary.BigEndian.Uint32(data[30:]); offset != 0 {
+		if out.store, err = parseItemVariationStore(data, offset); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseBasePaints(data []byte, offset uint32) ([]basePaintRecord, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return nil, errors.New("invalid base glyph list (EOF)")
+	}
+	count := int64(binary.BigEndian.Uint32(data[offset:]))
+	if int64(len(data)) < int64(offset)+4+6*count {
+		return nil, errors.New("invalid base glyph list (EOF)")
+	}
+	out := make([]basePaintRecord, count)
+	for i := range out {
+		record := data[int64(offset)+4+6*int64(i):]
+		out[i] = basePaintRecord{
+			glyph:  GID(binary.BigEndian.Uint16(record)),
+			offset: offset + binary.BigEndian.Uint32(record[2:]),
+		}
+	}
+	return out, nil
+}
+
+func parseLayerPaints(data []byte, offset uint32) ([]uint32, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return nil, errors.New("invalid layer list (EOF)")
+	}
+	count := int64(binary.BigEndian.Uint32(data[offset:]))
+	if int64(len(data)) < int64(offset)+4+4*count {
+		return nil, errors.New("invalid layer list (EOF)")
+	}
+	out := make([]uint32, count)
+	for i := range out {
+		out[i] = offset + binary.BigEndian.Uint32(data[int64(offset)+4+4*int64(i):])
+	}
+	return out, nil
+}
+
+func parseClipList(data []byte, offset uint32) ([]clipRecord, error) {
+	if int64(offset)+5 > int64(len(data)) {
+		return nil, errors.New("invalid clip list (EOF)")
+	}
+	count := int64(binary.BigEndian.Uint32(data[offset+1:]))
+	if int64(len(data)) < int64(offset)+5+7*count {
+		return nil, errors.New("invalid clip list (EOF)")
+	}
+	out := make([]clipRecord, count)
+	for i := range out {
+		record := data[int64(offset)+5+7*int64(i):]
+		out[i] = clipRecord{
+			start:  GID(binary.BigEndian.Uint16(record)),
+			end:    GID(binary.BigEndian.Uint16(record[2:])),
+			offset: offset + uint32(uint24(record[4:])),
+		}
+	}
+	return out, nil
+}
+
+// glyphLayers returns the layers of the color glyph (version 0), or nil
+// if the glyph is not a color glyph. Invalid layer ranges are ignored.
+func (t *tableCOLR) glyphLayers(gid GID) []ColorLayer {
+	i := sort.Search(len(t.baseGlyphs), func(i int) bool { return t.baseGlyphs[i].glyph >= gid })
+	if i == len(t.baseGlyphs) || t.baseGlyphs[i].glyph != gid {
+		return nil
+	}
+	record := t.baseGlyphs[i]
+	if record.firstLayer+record.numLayers > len(t.layers) {
+		return nil
+	}
+	return t.layers[record.firstLayer : record.firstLayer+record.numLayers]
+}
+
+// basePaint returns the offset of the root paint of the glyph (version 1).
+func (t *tableCOLR) basePaint(gid GID) (uint32, bool) {
+	i := sort.Search(len(t.basePaints), func(i int) bool { return t.basePaints[i].glyph >= gid })
+	if i == len(t.basePaints) || t.basePaints[i].glyph != gid {
+		return 0, false
+	}
+	return t.basePaints[i].offset, true
+}
+
+// varDelta returns the variation of the value at index `varIndexBase + i`,
+// or 0 for the default instance or if the value has no variations.
+func (t *tableCOLR) varDelta(varIndexBase uint32, i int, coords []float32) float32 {
+	if varIndexBase == 0xFFFFFFFF || coords == nil {
+		return 0
+	}
+	index := varIndexBase + uint32(i)
+	outer, inner := uint16(index>>16), uint16(index)
+	if t.hasVarIndexMap {
+		outer, inner = t.varIndexMap.index(index)
+	}
+	return t.store.delta(outer, inner, coords)
+}
+
+// clipBox returns the clip box of the glyph (version 1).
+func (t *tableCOLR) clipBox(gid GID, coords []float32) (ClipBox, bool) {
+	i := sort.Search(len(t.clips), func(i int) bool { return t.clips[i].end >= gid })
+	if i == len(t.clips) || t.clips[i].start > gid {
+		return ClipBox{}, false
+	}
+	offset := t.clips[i].offset
+	if int64(offset)+9 > int64(len(t.data)) {
+		return ClipBox{}, false
+	}
+	box := t.data[offset:]
+	format := box[0]
+	out := ClipBox{
+		XMin: float32(int16(binary.BigEndian.Uint16(box[1:]))),
+		YMin: float32(int16(binary.BigEndian.Uint16(box[3:]))),
+		XMax: float32(int16(binary.BigEndian.Uint16(box[5:]))),
+		YMax: float32(int16(binary.BigEndian.Uint16(box[7:]))),
+	}
+	if format == 2 && len(box) >= 13 {
+		base := binary.BigEndian.Uint32(box[9:])
+		out.XMin += t.varDelta(base, 0, coords)
+		out.YMin += t.varDelta(base, 1, coords)
+		out.XMax += t.varDelta(base, 2, coords)
+		out.YMax += t.varDelta(base, 3, coords)
+	}
+	return out, true
+}
+
+// paintBuilder resolves the paint graph of a glyph.
+type paintBuilder struct {
+	colr   *tableCOLR
+	coords []float32 // nil for the default instance
+
+	stack []uint32 // offsets of the paints being resolved, to detect cycles
+	count int      // total number of paints resolved
+}
+
+// paintSizes stores the minimum size of the paint tables, indexed by format.
+var paintSizes = [...]int{
+	1: 6, 2: 5, 3: 9, 4: 16, 5: 20, 6: 16, 7: 20, 8: 12, 9: 16, 10: 6, 11: 3,
+	12: 7, 13: 7, 14: 8, 15: 12, 16: 8, 17: 12, 18: 12, 19: 16, 20: 6, 21: 10,
+	22: 10, 23: 14, 24: 6, 25: 10, 26: 10, 27: 14, 28: 8, 29: 12, 30: 12, 31: 16, 32: 8,
+}
+
+// paint resolves the paint table at `offset`, from the start of the 'COLR' table.
+func (b *paintBuilder) paint(offset uint32) (Paint, error) {
+	for _, o := range b.stack {
+		if o == offset {
+			return nil, errPaintCycle
+		}
+	}
+	if b.count++; b.count > maxPaintCount || len(b.stack) >= maxPaintDepth {
+		return nil, errPaintLimit
+	}
+	b.stack = append(b.stack, offset)
+	defer func() { b.stack = b.stack[:len(b.stack)-1] }()
+
+	data := b.colr.data
+	if int64(offset) >= int64(len(data)) {
+		return nil, errors.New("invalid paint offset")
+	}
+	p := data[offset:]
+	format := int(p[0])
+	if format == 0 || format >= len(paintSizes) {
+		return nil, fmt.Errorf("unsupported paint format %d", format)
+	}
+	if len(p) < paintSizes[format] {
+		return nil, fmt.Errorf("invalid paint format %d (EOF)", format)
+	}
+
+	// readers for the fields of the table, and their variations
+	fword := func(pos int) float32 { return float32(int16(binary.BigEndian.Uint16(p[pos:]))) }
+	ufword := func(pos int) float32 { return float32(binary.BigEndian.Uint16(p[pos:])) }
+	f2dot14At := func(pos int) float32 { return f2dot14(binary.BigEndian.Uint16(p[pos:])) }
+	varBase := uint32(0xFFFFFFFF)
+	if format%2 == 1 && format >= 3 && format <= 31 && format != 13 { // variable formats, the one of PaintVarTransform is in its matrix
+		varBase = binary.BigEndian.Uint32(p[paintSizes[format]-4:])
+	}
+	delta := func(i int) float32 { return b.colr.varDelta(varBase, i, b.coords) }
+	child := func(pos int) (Paint, error) {
+		rel := uint32(uint24(p[pos:]))
+		if rel == 0 {
+			return nil, errors.New("invalid null paint offset")
+		}
+		return b.paint(offset + rel)
+	}
+	transformed := func(t Transform) (Paint, error) {
+		inner, err := child(1)
+		return PaintTransform{Transform: t, Paint: inner}, err
+	}
+
+	switch format {
+	case 1: // PaintColrLayers
+		count, first := int(p[1]), int64(binary.BigEndian.Uint32(p[2:]))
+		if first+int64(count) > int64(len(b.colr.layerPaints)) {
+			return nil, errors.New("invalid paint layers index")
+		}
+		out := PaintLayers{Layers: make([]Paint, count)}
+		for i := range out.Layers {
+			var err error
+			if out.Layers[i], err = b.paint(b.colr.layerPaints[first+int64(i)]); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case 2, 3: // PaintSolid, PaintVarSolid
+		return PaintSolid{PaletteIndex: binary.BigEndian.Uint16(p[1:]), Alpha: f2dot14At(3) + delta(0)/(1<<14)}, nil
+	case 4, 5: // PaintLinearGradient, PaintVarLinearGradient
+		line, err := b.colorLine(offset+uint32(uint24(p[1:])), format == 5)
+		if err != nil {
+			return nil, err
+		}
+		return PaintLinearGradient{
+			ColorLine: line,
+			P0:        point(fword(4)+delta(0), fword(6)+delta(1)),
+			P1:        point(fword(8)+delta(2), fword(10)+delta(3)),
+			P2:        point(fword(12)+delta(4), fword(14)+delta(5)),
+		}, nil
+	case 6, 7: // PaintRadialGradient, PaintVarRadialGradient
+		line, err := b.colorLine(offset+uint32(uint24(p[1:])), format == 7)
+		if err != nil {
+			return nil, err
+		}
+		return PaintRadialGradient{
+			ColorLine: line,
+			C0:        point(fword(4)+delta(0), fword(6)+delta(1)),
+			R0:        ufword(8) + delta(2),
+			C1:        point(fword(10)+delta(3), fword(12)+delta(4)),
+			R1:        ufword(14) + delta(5),
+		}, nil
+	case 8, 9: // PaintSweepGradient, PaintVarSweepGradient
+		line, err := b.colorLine(offset+uint32(uint24(p[1:])), format == 9)
+		if err != nil {
+			return nil, err
+		}
+		// the angles are biased by one half turn
+		return PaintSweepGradient{
+			ColorLine:  line,
+			Center:     point(fword(4)+delta(0), fword(6)+delta(1)),
+			StartAngle: (f2dot14At(8) + delta(2)/(1<<14) + 1) * 180,
+			EndAngle:   (f2dot14At(10) + delta(3)/(1<<14) + 1) * 180,
+		}, nil
+	case 10: // PaintGlyph
+		inner, err := child(1)
+		return PaintGlyph{Glyph: GID(binary.BigEndian.Uint16(p[4:])), Paint: inner}, err
+	case 11: // PaintColrGlyph
+		gid := GID(binary.BigEndian.Uint16(p[1:]))
+		base, ok := b.colr.basePaint(gid)
+		if !ok {
+			return nil, fmt.Errorf("invalid color glyph reference %d", gid)
+		}
+		inner, err := b.paint(base)
+		return PaintColrGlyph{Glyph: gid, Paint: inner}, err
+	case 12, 13: // PaintTransform, PaintVarTransform
+		t, err := b.affine(offset+uint32(uint24(p[4:])), format == 13)
+		if err != nil {
+			return nil, err
+		}
+		return transformed(t)
+	case 14, 15: // PaintTranslate, PaintVarTranslate
+		return transformed(translation(fword(4)+delta(0), fword(6)+delta(1)))
+	case 16, 17, 18, 19: // PaintScale, PaintScaleAroundCenter and their variable versions
+		sx, sy := f2dot14At(4)+delta(0)/(1<<14), f2dot14At(6)+delta(1)/(1<<14)
+		t := Transform{XX: sx, YY: sy}
+		if format >= 18 {
+			t = aroundCenter(t, fword(8)+delta(2), fword(10)+delta(3))
+		}
+		return transformed(t)
+	case 20, 21, 22, 23: // PaintScaleUniform, PaintScaleUniformAroundCenter and their variable versions
+		scale := f2dot14At(4) + delta(0)/(1<<14)
+		t := Transform{XX: scale, YY: scale}
+		if format >= 22 {
+			t = aroundCenter(t, fword(6)+delta(1), fword(8)+delta(2))
+		}
+		return transformed(t)
+	case 24, 25, 26, 27: // PaintRotate, PaintRotateAroundCenter and their variable versions
+		t := rotation(f2dot14At(4) + delta(0)/(1<<14))
+		if format >= 26 {
+			t = aroundCenter(t, fword(6)+delta(1), fword(8)+delta(2))
+		}
+		return transformed(t)
+	case 28, 29, 30, 31: // PaintSkew, PaintSkewAroundCenter and their variable versions
+		t := skew(f2dot14At(4)+delta(0)/(1<<14), f2dot14At(6)+delta(1)/(1<<14))
+		if format >= 30 {
+			t = aroundCenter(t, fword(8)+delta(2), fword(10)+delta(3))
+		}
+		return transformed(t)
+	default: // 32, PaintComposite
+		source, err := child(1)
+		if err != nil {
+			return nil, err
+		}
+		mode := CompositeMode(p[4])
+		if mode > CompositeLuminosity {
+			return nil, fmt.Errorf("invalid composite mode %d", mode)
+		}
+		backdrop, err := child(5)
+		return PaintComposite{Source: source, Backdrop: backdrop, Mode: mode}, err
+	}
+}
+
+func point(x, y float32) font.SegmentPoint { return font.SegmentPoint{X: x, Y: y} }
+
+// colorLine parses the (variable) color line at `offset`.
+func (b *paintBuilder) colorLine(offset uint32, variable bool) (ColorLine, error) {
+	data := b.colr.data
+	if int64(offset)+3 > int64(len(data)) {
+		return ColorLine{}, errors.New("invalid color line (EOF)")
+	}
+	line := data[offset:]
+	stopSize := 6
+	if variable {
+		stopSize = 10
+	}
+	count := int(binary.BigEndian.Uint16(line[1:]))
+	if len(line) < 3+stopSize*count {
+		return ColorLine{}, errors.New("invalid color line (EOF)")
+	}
+	out := ColorLine{Extend: Extend(line[0]), Stops: make([]ColorStop, count)}
+	if out.Extend > ExtendReflect {
+		out.Extend = ExtendPad // as required by the specification
+	}
+	for i := range out.Stops {
+		stop := line[3+stopSize*i:]
+		out.Stops[i] = ColorStop{
+			Offset:       f2dot14(binary.BigEndian.Uint16(stop)),
+			PaletteIndex: binary.BigEndian.Uint16(stop[2:]),
+			Alpha:        f2dot14(binary.BigEndian.Uint16(stop[4:])),
+		}
+		if variable {
+			base := binary.BigEndian.Uint32(stop[6:])
+			out.Stops[i].Offset += b.colr.varDelta(base, 0, b.coords) / (1 << 14)
+			out.Stops[i].Alpha += b.colr.varDelta(base, 1, b.coords) / (1 << 14)
+		}
+	}
+	return out, nil
+}
+
+// affine parses the (variable) affine transformation at `offset`.
+func (b *paintBuilder) affine(offset uint32, variable bool) (Transform, error) {
+	data := b.colr.data
+	size := int64(24)
+	if variable {
+		size = 28
+	}
+	if int64(offset)+size > int64(len(data)) {
+		return Transform{}, errors.New("invalid affine transformation (EOF)")
+	}
+	var values [6]float32
+	base := uint32(0xFFFFFFFF)
+	if variable {
+		base = binary.BigEndian.Uint32(data[offset+24:])
+	}
+	for i := range values {
+		fixed := int32(binary.BigEndian.Uint32(data[offset+4*uint32(i):]))
+		values[i] = (float32(fixed) + b.colr.varDelta(base, i, b.coords)) / (1 << 16)
+	}
+	return Transform{XX: values[0], YX: values[1], XY: values[2], YY: values[3], DX: values[4], DY: values[5]}, nil
+}
@@ -0,0 +1,86 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"image/color"
+)
+
+// tableCPAL stores the color palettes used by the 'COLR' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cpal
+type tableCPAL struct {
+	palettes [][]color.NRGBA // each with the same number of entries
+
+	// version 1, nil if missing
+	types       []PaletteFlags // indexed by palette
+	labels      []uint16       // indexed by palette
+	entryLabels []uint16       // indexed by palette entry
+}
+
+func parseTableCPAL(data []byte) (tableCPAL, error) {
+	const headerSize = 12
+	if len(data) < headerSize {
+		return tableCPAL{}, errEOF
+	}
+	numEntries := int(binary.BigEndian.Uint16(data[2:]))
+	numPalettes := int(binary.BigEndian.Uint16(data[4:]))
+	numColors := int(binary.BigEndian.Uint16(data[6:]))
+	colorsOffset := int64(binary.BigEndian.Uint32(data[8:]))
+	if len(data) < headerSize+2*numPalettes || int64(len(data)) < colorsOffset+4*int64(numColors) {
+		return tableCPAL{}, errEOF
+	}
+	colors := data[colorsOffset:]
+
+	out := tableCPAL{palettes: make([][]color.NRGBA, numPalettes)}
+	for i := range out.palettes {
+		first := int(binary.BigEndian.Uint16(data[headerSize+2*i:]))
+		if first+numEntries > numColors {
+			return tableCPAL{}, errors.New("invalid palette color index")
+		}
+		palette := make([]color.NRGBA, numEntries)
+		for j := range palette {
+			record := colors[4*(first+j):] // BGRA
+			palette[j] = color.NRGBA{B: record[0], G: record[1], R: record[2], A: record[3]}
+		}
+		out.palettes[i] = palette
+	}
+
+	if version := binary.BigEndian.Uint16(data); version == 0 {
+		return out, nil
+	}
+	offsets := data[headerSize+2*numPalettes:]
+	if len(offsets) < 12 {
+		return tableCPAL{}, errEOF
+	}
+	if offset := int64(binary.BigEndian.Uint32(offsets)); offset != 0 {
+		if int64(len(data)) < offset+4*int64(numPalettes) {
+			return tableCPAL{}, errors.New("invalid palette types array (EOF)")
+		}
+		out.types = make([]PaletteFlags, numPalettes)
+		for i := range out.types {
+			out.types[i] = PaletteFlags(binary.BigEndian.Uint32(data[offset+4*int64(i):]))
+		}
+	}
+	var err error
+	if out.labels, err = parseCPALLabels(data, binary.BigEndian.Uint32(offsets[4:]), numPalettes); err != nil {
+		return tableCPAL{}, err
+	}
+	if out.entryLabels, err = parseCPALLabels(data, binary.BigEndian.Uint32(offsets[8:]), numEntries); err != nil {
+		return tableCPAL{}, err
+	}
+	return out, nil
+}
+
+func parseCPALLabels(data []byte, offset uint32, count int) ([]uint16, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if int64(len(data)) < int64(offset)+2*int64(count) {
+		return nil, errors.New("invalid palette labels array (EOF)")
+	}
+	out := make([]uint16, count)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint16(data[int64(offset)+2*int64(i):])
+	}
+	return out, nil
+}
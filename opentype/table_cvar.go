@@ -0,0 +1,120 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// parseTableCvt returns the control values stored in the 'cvt ' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cvt
+func parseTableCvt(data []byte) []int16 {
+	out := make([]int16, len(data)/2)
+	for i := range out {
+		out[i] = int16(binary.BigEndian.Uint16(data[2*i:]))
+	}
+	return out
+}
+
+// tableCvar stores the variations of the control values.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cvar
+type tableCvar struct {
+	axisCount int
+	data      []byte // the whole table, whose tuple variation store starts at offset 4
+}
+
+func parseTableCvar(data []byte, axisCount int) (tableCvar, error) {
+	if len(data) < 8 {
+		return tableCvar{}, errEOF
+	}
+	if major := binary.BigEndian.Uint16(data); major != 1 {
+		return tableCvar{}, errors.New("unsupported version")
+	}
+	if axisCount > maxGvarAxes {
+		return tableCvar{}, errors.New("invalid axis count")
+	}
+	return tableCvar{axisCount: axisCount, data: data}, nil
+}
+
+// applyDeltas adds the variations of the control values for
+// the normalized coordinates `coords` to `values`.
+// The control values not referenced by a tuple variation are not modified.
+func (t *tableCvar) applyDeltas(coords []float32, values []float32) error {
+	// the peak tuples are always embedded, since there are no shared tuples
+	return applyTupleVariations(t.data, 4, t.axisCount, nil, coords, func(scalar float32, points []uint16, data []byte) error {
+		count := len(points)
+		if points == nil {
+			count = len(values)
+		}
+		deltas, _, err := parsePackedDeltas(data, count)
+		if err != nil {
+			return err
+		}
+		if points == nil { // all values
+			for i, d := range deltas {
+				values[i] += scalar * float32(d)
+			}
+			return nil
+		}
+		for i, index := range points {
+			if int(index) < len(values) {
+				values[index] += scalar * float32(deltas[i])
+			}
+		}
+		return nil
+	})
+}
+
+// cvt stores the 'cvt ' and 'cvar' tables, loaded on first use.
+type cvt struct {
+	once   sync.Once
+	values []int16    // nil if missing
+	cvar   *tableCvar // optional
+}
+
+// ControlValues returns the control values of the 'cvt ' table, in font units,
+// used by the TrueType hinting instructions. For variable fonts, they are adjusted
+// by the 'cvar' table (see SetVariations); invalid variations are ignored.
+// It returns false if the font has no 'cvt ' table.
+// The returned slice is a copy, owned by the caller.
+func (face *Face) ControlValues() ([]float32, bool) {
+	c := face.loadCvt()
+	if c.values == nil {
+		return nil, false
+	}
+	out := make([]float32, len(c.values))
+	for i, v := range c.values {
+		out[i] = float32(v)
+	}
+	if c.cvar == nil || !face.isVariable() {
+		return out, true
+	}
+	varied := append([]float32(nil), out...)
+	if err := c.cvar.applyDeltas(face.coords, varied); err != nil {
+		return out, true
+	}
+	return varied, true
+}
+
+func (face *Face) loadCvt() *cvt {
+	c := &face.cvt
+	c.once.Do(func() {
+		data, err := face.GetRawTable(tagCvt)
+		if err != nil {
+			return
+		}
+		c.values = parseTableCvt(data)
+		if data, err := face.GetRawTable(tagCvar); err == nil {
+			var axisCount int
+			if fvar, ok := face.Fvar(); ok {
+				axisCount = len(fvar.Axes)
+			}
+			if cvar, err := parseTableCvar(data, axisCount); err != nil {
+				face.warnings.addError(face.tableError(tagCvar, err))
+			} else {
+				c.cvar = &cvar
+			}
+		}
+	})
+	return c
+}
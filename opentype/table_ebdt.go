@@ -0,0 +1,130 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errNoBitmap = errors.New("no bitmap for glyph")
+
+// maxBitmapDepth limits the nesting of composite bitmaps.
+const maxBitmapDepth = 8
+
+// ebdtBitmap decodes the image of the glyph in the 'EBDT' table `ebdt`, for the
+// image formats 1, 2, 5, 6 and 7, and the composite formats 8 and 9.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/ebdt
+func (s *bitmapStrike) ebdtBitmap(ebdt []byte, gid GID, depth int) (Bitmap, error) {
+	if depth > maxBitmapDepth {
+		return Bitmap{}, errors.New("invalid composite bitmap: too many levels")
+	}
+	st, offset, length, ok := s.glyphImage(gid)
+	if !ok {
+		return Bitmap{}, errNoBitmap
+	}
+	if uint64(offset)+uint64(length) > uint64(len(ebdt)) {
+		return Bitmap{}, errEOF
+	}
+	data := ebdt[offset : offset+length]
+
+	var (
+		metrics    bitmapMetrics
+		bitAligned bool
+	)
+	switch st.imageFormat {
+	case 1, 2, 8:
+		if len(data) < 5 {
+			return Bitmap{}, errEOF
+		}
+		metrics, data = parseSmallGlyphMetrics(data, s.flags), data[5:]
+		bitAligned = st.imageFormat == 2
+	case 5:
+		if st.indexFormat != 2 && st.indexFormat != 5 {
+			return Bitmap{}, errors.New("invalid bitmap format 5")
+		}
+		metrics, bitAligned = st.metrics, true
+	case 6, 7, 9:
+		if len(data) < 8 {
+			return Bitmap{}, errEOF
+		}
+		metrics, data = parseBigGlyphMetrics(data), data[8:]
+		bitAligned = st.imageFormat == 7
+	default:
+		return Bitmap{}, errors.New("unsupported bitmap format")
+	}
+
+	bitDepth := int(s.bitDepth)
+	if bitDepth == 0 {
+		bitDepth = 1
+	}
+	out := Bitmap{
+		Width:    int(metrics.width),
+		Height:   int(metrics.height),
+		BitDepth: bitDepth,
+		Stride:   (int(metrics.width)*bitDepth + 7) / 8,
+		Metrics:  metrics.export(),
+	}
+
+	if st.imageFormat == 8 || st.imageFormat == 9 {
+		return s.composeBitmap(ebdt, out, data, st.imageFormat == 8, depth)
+	}
+
+	size := out.Stride * out.Height
+	if !bitAligned {
+		if len(data) < size {
+			return Bitmap{}, errEOF
+		}
+		out.Data = data[:size:size]
+		return out, nil
+	}
+
+	// convert to byte aligned rows
+	rowBits := out.Width * bitDepth
+	if len(data)*8 < rowBits*out.Height {
+		return Bitmap{}, errEOF
+	}
+	out.Data = make([]byte, size)
+	for y := 0; y < out.Height; y++ {
+		for i := 0; i < rowBits; i++ {
+			src := y*rowBits + i
+			if data[src/8]&(0x80>>(src%8)) != 0 {
+				out.Data[y*out.Stride+i/8] |= 0x80 >> (i % 8)
+			}
+		}
+	}
+	return out, nil
+}
+
+// composeBitmap draws the components stored in `data` in `out`.
+func (s *bitmapStrike) composeBitmap(ebdt []byte, out Bitmap, data []byte, isSmall bool, depth int) (Bitmap, error) {
+	if isSmall { // skip the pad byte
+		if len(data) < 1 {
+			return Bitmap{}, errEOF
+		}
+		data = data[1:]
+	}
+	if len(data) < 2 {
+		return Bitmap{}, errEOF
+	}
+	numComponents := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+4*numComponents {
+		return Bitmap{}, errEOF
+	}
+	out.Data = make([]byte, out.Stride*out.Height)
+	for i := 0; i < numComponents; i++ {
+		record := data[2+4*i:]
+		gid := GID(binary.BigEndian.Uint16(record))
+		dx, dy := int(int8(record[2])), int(int8(record[3]))
+		component, err := s.ebdtBitmap(ebdt, gid, depth+1)
+		if err != nil {
+			return Bitmap{}, err
+		}
+		for y := 0; y < component.Height; y++ {
+			for x := 0; x < component.Width; x++ {
+				if v := component.pixel(x, y); v != 0 {
+					out.setPixel(x+dx, y+dy, v)
+				}
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,221 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// tableBitmapLocation stores the strikes of the 'EBLC' and 'CBLC' tables,
+// which locate in the 'EBDT' and 'CBDT' tables the images of the glyphs.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/eblc
+type tableBitmapLocation []bitmapStrike
+
+type bitmapStrike struct {
+	ppemX, ppemY uint16
+	bitDepth     uint8
+	flags        uint8 // 1 for horizontal metrics, 2 for vertical metrics
+	subtables    []indexSubtable
+}
+
+// indexSubtable locates the images of the glyphs in [firstGlyph, lastGlyph].
+type indexSubtable struct {
+	firstGlyph, lastGlyph GID
+	indexFormat           uint16
+	imageFormat           uint16
+	imageOffset           uint32 // into the image data table
+	data                  []byte // the subtable, starting after its header
+
+	// for index formats 2 and 5
+	imageSize uint32
+	metrics   bitmapMetrics
+}
+
+// bitmapMetrics are the big glyph metrics of the bitmap tables.
+type bitmapMetrics struct {
+	height, width              uint8
+	horiBearingX, horiBearingY int8
+	horiAdvance                uint8
+	vertBearingX, vertBearingY int8
+	vertAdvance                uint8
+}
+
+func parseBigGlyphMetrics(data []byte) bitmapMetrics {
+	return bitmapMetrics{
+		height:       data[0],
+		width:        data[1],
+		horiBearingX: int8(data[2]),
+		horiBearingY: int8(data[3]),
+		horiAdvance:  data[4],
+		vertBearingX: int8(data[5]),
+		vertBearingY: int8(data[6]),
+		vertAdvance:  data[7],
+	}
+}
+
+// parseSmallGlyphMetrics returns the metrics, which are horizontal
+// unless the strike only provides vertical metrics.
+func parseSmallGlyphMetrics(data []byte, strikeFlags uint8) bitmapMetrics {
+	out := bitmapMetrics{height: data[0], width: data[1]}
+	if strikeFlags&3 == 2 {
+		out.vertBearingX, out.vertBearingY, out.vertAdvance = int8(data[2]), int8(data[3]), data[4]
+	} else {
+		out.horiBearingX, out.horiBearingY, out.horiAdvance = int8(data[2]), int8(data[3]), data[4]
+	}
+	return out
+}
+
+func parseTableBitmapLocation(data []byte) (tableBitmapLocation, error) {
+	const headerSize, recordSize = 8, 48
+	if len(data) < headerSize {
+		return nil, errEOF
+	}
+	numSizes := int64(binary.BigEndian.Uint32(data[4:]))
+	if int64(len(data)) < headerSize+recordSize*numSizes {
+		return nil, errEOF
+	}
+	out := make(tableBitmapLocation, numSizes)
+	for i := range out {
+		record := data[headerSize+recordSize*i:]
+		arrayOffset := int64(binary.BigEndian.Uint32(record))
+		numSubtables := int64(binary.BigEndian.Uint32(record[8:]))
+		out[i] = bitmapStrike{
+			ppemX:    uint16(record[44]),
+			ppemY:    uint16(record[45]),
+			bitDepth: record[46],
+			flags:    record[47],
+		}
+		if int64(len(data)) < arrayOffset+8*numSubtables {
+			return nil, errors.New("invalid index subtable array (EOF)")
+		}
+		out[i].subtables = make([]indexSubtable, numSubtables)
+		for j := range out[i].subtables {
+			entry := data[arrayOffset+8*int64(j):]
+			st, err := parseIndexSubtable(data, arrayOffset+int64(binary.BigEndian.Uint32(entry[4:])))
+			if err != nil {
+				return nil, err
+			}
+			st.firstGlyph = GID(binary.BigEndian.Uint16(entry))
+			st.lastGlyph = GID(binary.BigEndian.Uint16(entry[2:]))
+			if st.firstGlyph > st.lastGlyph {
+				return nil, errors.New("invalid index subtable glyph range")
+			}
+			out[i].subtables[j] = st
+		}
+	}
+	return out, nil
+}
+
+func parseIndexSubtable(data []byte, offset int64) (indexSubtable, error) {
+	if int64(len(data)) < offset+8 {
+		return indexSubtable{}, errors.New("invalid index subtable (EOF)")
+	}
+	st := indexSubtable{
+		indexFormat: binary.BigEndian.Uint16(data[offset:]),
+		imageFormat: binary.BigEndian.Uint16(data[offset+2:]),
+		imageOffset: binary.BigEndian.Uint32(data[offset+4:]),
+		data:        data[offset+8:],
+	}
+	switch st.indexFormat {
+	case 1, 3, 4: // the glyph offsets are checked on access
+	case 2, 5:
+		if len(st.data) < 12 {
+			return indexSubtable{}, errors.New("invalid index subtable (EOF)")
+		}
+		st.imageSize = binary.BigEndian.Uint32(st.data)
+		st.metrics = parseBigGlyphMetrics(st.data[4:])
+	default:
+		return indexSubtable{}, errors.New("unsupported index subtable format")
+	}
+	return st, nil
+}
+
+// glyphLocation returns the offset and length of the image of the glyph
+// (in the image data table), or false if the glyph has no image.
+func (st *indexSubtable) glyphLocation(gid GID) (offset, length uint32, ok bool) {
+	if gid < st.firstGlyph || gid > st.lastGlyph {
+		return 0, 0, false
+	}
+	index := int(gid - st.firstGlyph)
+	var start, end uint32
+	switch st.indexFormat {
+	case 1:
+		if len(st.data) < 4*index+8 {
+			return 0, 0, false
+		}
+		start, end = binary.BigEndian.Uint32(st.data[4*index:]), binary.BigEndian.Uint32(st.data[4*index+4:])
+	case 2:
+		start, end = st.imageSize*uint32(index), st.imageSize*uint32(index+1)
+	case 3:
+		if len(st.data) < 2*index+4 {
+			return 0, 0, false
+		}
+		start, end = uint32(binary.BigEndian.Uint16(st.data[2*index:])), uint32(binary.BigEndian.Uint16(st.data[2*index+2:]))
+	case 4:
+		if len(st.data) < 4 {
+			return 0, 0, false
+		}
+		numGlyphs := int(binary.BigEndian.Uint32(st.data))
+		if len(st.data) < 4+4*(numGlyphs+1) {
+			return 0, 0, false
+		}
+		pairs := st.data[4:]
+		// binary search on the sorted glyph array, whose last entry only provides an offset
+		lo, hi := 0, numGlyphs
+		for lo < hi {
+			mid := (lo + hi) / 2
+			g := GID(binary.BigEndian.Uint16(pairs[4*mid:]))
+			switch {
+			case gid < g:
+				hi = mid
+			case gid > g:
+				lo = mid + 1
+			default:
+				start = uint32(binary.BigEndian.Uint16(pairs[4*mid+2:]))
+				end = uint32(binary.BigEndian.Uint16(pairs[4*mid+6:]))
+				return st.imageOffset + start, end - start, start < end
+			}
+		}
+		return 0, 0, false
+	case 5:
+		if len(st.data) < 16 {
+			return 0, 0, false
+		}
+		numGlyphs := int(binary.BigEndian.Uint32(st.data[12:]))
+		if len(st.data) < 16+2*numGlyphs {
+			return 0, 0, false
+		}
+		ids := st.data[16:]
+		lo, hi := 0, numGlyphs
+		for lo < hi {
+			mid := (lo + hi) / 2
+			g := GID(binary.BigEndian.Uint16(ids[2*mid:]))
+			switch {
+			case gid < g:
+				hi = mid
+			case gid > g:
+				lo = mid + 1
+			default:
+				return st.imageOffset + st.imageSize*uint32(mid), st.imageSize, st.imageSize != 0
+			}
+		}
+		return 0, 0, false
+	}
+	if start >= end {
+		return 0, 0, false
+	}
+	return st.imageOffset + start, end - start, true
+}
+
+// glyphImage returns the subtable and the location of the image of the glyph,
+// or false if the glyph has no image in the strike.
+func (s *bitmapStrike) glyphImage(gid GID) (st *indexSubtable, offset, length uint32, ok bool) {
+	for i := range s.subtables {
+		st = &s.subtables[i]
+		if gid < st.firstGlyph || gid > st.lastGlyph {
+			continue
+		}
+		offset, length, ok = st.glyphLocation(gid)
+		return st, offset, length, ok
+	}
+	return nil, 0, 0, false
+}
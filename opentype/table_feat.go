@@ -0,0 +1,224 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableFeat describes the features of the AAT fonts, applied
+// by the 'morx' table, so that they can be exposed to the users.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6feat.html
+type TableFeat struct {
+	// Features are sorted by type.
+	Features []FeatFeature
+}
+
+// FeatFeature describes an AAT feature and its settings.
+type FeatFeature struct {
+	Type uint16
+	Name NameID
+	// Exclusive is true if only one of the settings may be selected at a time.
+	// Otherwise, the settings are enabled independently of each other, the
+	// settings with an even selector enabling an option, disabled by the setting
+	// whose selector follows.
+	Exclusive bool
+	// DefaultSetting is the index in Settings of the
+	// default setting of exclusive features.
+	DefaultSetting int
+	Settings       []FeatSetting
+}
+
+// FeatSetting is a setting of a FeatFeature.
+type FeatSetting struct {
+	Selector uint16
+	Name     NameID
+}
+
+// Feature returns the description of the feature `featureType`,
+// or false if it is not found.
+func (t *TableFeat) Feature(featureType uint16) (*FeatFeature, bool) {
+	for i := range t.Features {
+		if t.Features[i].Type == featureType {
+			return &t.Features[i], true
+		}
+	}
+	return nil, false
+}
+
+func parseTableFeat(data []byte) (TableFeat, error) {
+	if len(data) < 12 {
+		return TableFeat{}, errEOF
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 {
+		return TableFeat{}, fmt.Errorf("unsupported version %x", version)
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+12*count {
+		return TableFeat{}, errEOF
+	}
+	out := TableFeat{Features: make([]FeatFeature, count)}
+	for i := range out.Features {
+		record := data[12+12*i:]
+		settingCount := int64(binary.BigEndian.Uint16(record[2:]))
+		settingsOffset := int64(binary.BigEndian.Uint32(record[4:]))
+		flags := binary.BigEndian.Uint16(record[8:])
+		feature := FeatFeature{
+			Type:      binary.BigEndian.Uint16(record),
+			Name:      NameID(binary.BigEndian.Uint16(record[10:])),
+			Exclusive: flags&0x8000 != 0,
+			Settings:  make([]FeatSetting, settingCount),
+		}
+		if int64(len(data)) < settingsOffset+4*settingCount {
+			return out, errors.New("invalid feature settings (EOF)")
+		}
+		for j := range feature.Settings {
+			setting := data[settingsOffset+4*int64(j):]
+			feature.Settings[j] = FeatSetting{
+				Selector: binary.BigEndian.Uint16(setting),
+				Name:     NameID(binary.BigEndian.Uint16(setting[2:])),
+			}
+		}
+		if flags&0x4000 != 0 { // the default setting is not the first one
+			feature.DefaultSetting = int(flags & 0xFF)
+			if feature.DefaultSetting >= len(feature.Settings) {
+				return out, fmt.Errorf("invalid default setting %d for feature %d", feature.DefaultSetting, feature.Type)
+			}
+		}
+		out.Features[i] = feature
+	}
+	return out, nil
+}
+
+// feat is the lazily parsed 'feat' table.
+type feat struct {
+	once  sync.Once
+	table *TableFeat // nil if missing or invalid
+}
+
+// Feat returns the feature name table, parsed on first use,
+// or false if the font has no 'feat' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Feat() (*TableFeat, bool) {
+	face.feat.once.Do(func() {
+		if data, err := face.GetRawTable(tagFeat); err == nil {
+			if table, err := parseTableFeat(data); err != nil {
+				face.warnings.addError(face.tableError(tagFeat, err))
+			} else {
+				face.feat.table = &table
+			}
+		}
+	})
+	return face.feat.table, face.feat.table != nil
+}
+
+// AATFeatureForTag returns the AAT feature settings enabling and disabling
+// the equivalent of the OpenType feature `tag`, or false if there is none.
+func AATFeatureForTag(tag Tag) (enable, disable AATFeature, ok bool) {
+	for _, m := range aatFeatureMappings {
+		if m.tag == tag {
+			return m.enable, m.disable, true
+		}
+	}
+	return AATFeature{}, AATFeature{}, false
+}
+
+// OpenTypeFeature returns the OpenType feature enabled by the AAT feature setting
+// `setting`, or false if there is none. Some settings are the equivalent of
+// several OpenType features, such as 'vert' and 'vrt2', in which case
+// the first tag in alphabetical order is returned.
+func OpenTypeFeature(setting AATFeature) (Tag, bool) {
+	for _, m := range aatFeatureMappings {
+		if m.enable == setting {
+			return m.tag, true
+		}
+	}
+	return 0, false
+}
+
+// aatFeatureMappings are the equivalences between the OpenType features
+// and the AAT feature settings, sorted by tag, as published by Apple.
+var aatFeatureMappings = [...]struct {
+	tag             Tag
+	enable, disable AATFeature
+}{
+	{MustNewTag("afrc"), AATFeature{11, 1}, AATFeature{11, 0}},
+	{MustNewTag("c2pc"), AATFeature{38, 2}, AATFeature{38, 0}},
+	{MustNewTag("c2sc"), AATFeature{38, 1}, AATFeature{38, 0}},
+	{MustNewTag("calt"), AATFeature{36, 0}, AATFeature{36, 1}},
+	{MustNewTag("case"), AATFeature{33, 0}, AATFeature{33, 1}},
+	{MustNewTag("clig"), AATFeature{1, 18}, AATFeature{1, 19}},
+	{MustNewTag("cpsp"), AATFeature{33, 2}, AATFeature{33, 3}},
+	{MustNewTag("cswh"), AATFeature{36, 4}, AATFeature{36, 5}},
+	{MustNewTag("dlig"), AATFeature{1, 4}, AATFeature{1, 5}},
+	{MustNewTag("expt"), AATFeature{20, 10}, AATFeature{20, 16}},
+	{MustNewTag("frac"), AATFeature{11, 2}, AATFeature{11, 0}},
+	{MustNewTag("fwid"), AATFeature{22, 1}, AATFeature{22, 7}},
+	{MustNewTag("halt"), AATFeature{22, 6}, AATFeature{22, 7}},
+	{MustNewTag("hist"), AATFeature{40, 0}, AATFeature{40, 1}},
+	{MustNewTag("hkna"), AATFeature{34, 0}, AATFeature{34, 1}},
+	{MustNewTag("hlig"), AATFeature{1, 20}, AATFeature{1, 21}},
+	{MustNewTag("hngl"), AATFeature{23, 1}, AATFeature{23, 0}},
+	{MustNewTag("hojo"), AATFeature{20, 12}, AATFeature{20, 16}},
+	{MustNewTag("hwid"), AATFeature{22, 2}, AATFeature{22, 7}},
+	{MustNewTag("ital"), AATFeature{32, 2}, AATFeature{32, 3}},
+	{MustNewTag("jp04"), AATFeature{20, 11}, AATFeature{20, 16}},
+	{MustNewTag("jp78"), AATFeature{20, 2}, AATFeature{20, 16}},
+	{MustNewTag("jp83"), AATFeature{20, 3}, AATFeature{20, 16}},
+	{MustNewTag("jp90"), AATFeature{20, 4}, AATFeature{20, 16}},
+	{MustNewTag("liga"), AATFeature{1, 2}, AATFeature{1, 3}},
+	{MustNewTag("lnum"), AATFeature{21, 1}, AATFeature{21, 2}},
+	{MustNewTag("mgrk"), AATFeature{15, 10}, AATFeature{15, 11}},
+	{MustNewTag("nlck"), AATFeature{20, 13}, AATFeature{20, 16}},
+	{MustNewTag("onum"), AATFeature{21, 0}, AATFeature{21, 2}},
+	{MustNewTag("ordn"), AATFeature{10, 3}, AATFeature{10, 0}},
+	{MustNewTag("palt"), AATFeature{22, 5}, AATFeature{22, 7}},
+	{MustNewTag("pcap"), AATFeature{37, 2}, AATFeature{37, 0}},
+	{MustNewTag("pkna"), AATFeature{22, 0}, AATFeature{22, 7}},
+	{MustNewTag("pnum"), AATFeature{6, 1}, AATFeature{6, 4}},
+	{MustNewTag("pwid"), AATFeature{22, 0}, AATFeature{22, 7}},
+	{MustNewTag("qwid"), AATFeature{22, 4}, AATFeature{22, 7}},
+	{MustNewTag("rlig"), AATFeature{1, 0}, AATFeature{1, 1}},
+	{MustNewTag("ruby"), AATFeature{28, 2}, AATFeature{28, 3}},
+	{MustNewTag("sinf"), AATFeature{10, 4}, AATFeature{10, 0}},
+	{MustNewTag("smcp"), AATFeature{37, 1}, AATFeature{37, 0}},
+	{MustNewTag("smpl"), AATFeature{20, 1}, AATFeature{20, 16}},
+	{MustNewTag("ss01"), AATFeature{35, 2}, AATFeature{35, 3}},
+	{MustNewTag("ss02"), AATFeature{35, 4}, AATFeature{35, 5}},
+	{MustNewTag("ss03"), AATFeature{35, 6}, AATFeature{35, 7}},
+	{MustNewTag("ss04"), AATFeature{35, 8}, AATFeature{35, 9}},
+	{MustNewTag("ss05"), AATFeature{35, 10}, AATFeature{35, 11}},
+	{MustNewTag("ss06"), AATFeature{35, 12}, AATFeature{35, 13}},
+	{MustNewTag("ss07"), AATFeature{35, 14}, AATFeature{35, 15}},
+	{MustNewTag("ss08"), AATFeature{35, 16}, AATFeature{35, 17}},
+	{MustNewTag("ss09"), AATFeature{35, 18}, AATFeature{35, 19}},
+	{MustNewTag("ss10"), AATFeature{35, 20}, AATFeature{35, 21}},
+	{MustNewTag("ss11"), AATFeature{35, 22}, AATFeature{35, 23}},
+	{MustNewTag("ss12"), AATFeature{35, 24}, AATFeature{35, 25}},
+	{MustNewTag("ss13"), AATFeature{35, 26}, AATFeature{35, 27}},
+	{MustNewTag("ss14"), AATFeature{35, 28}, AATFeature{35, 29}},
+	{MustNewTag("ss15"), AATFeature{35, 30}, AATFeature{35, 31}},
+	{MustNewTag("ss16"), AATFeature{35, 32}, AATFeature{35, 33}},
+	{MustNewTag("ss17"), AATFeature{35, 34}, AATFeature{35, 35}},
+	{MustNewTag("ss18"), AATFeature{35, 36}, AATFeature{35, 37}},
+	{MustNewTag("ss19"), AATFeature{35, 38}, AATFeature{35, 39}},
+	{MustNewTag("ss20"), AATFeature{35, 40}, AATFeature{35, 41}},
+	{MustNewTag("subs"), AATFeature{10, 2}, AATFeature{10, 0}},
+	{MustNewTag("sups"), AATFeature{10, 1}, AATFeature{10, 0}},
+	{MustNewTag("swsh"), AATFeature{36, 2}, AATFeature{36, 3}},
+	{MustNewTag("titl"), AATFeature{19, 4}, AATFeature{19, 0}},
+	{MustNewTag("tnam"), AATFeature{20, 14}, AATFeature{20, 16}},
+	{MustNewTag("tnum"), AATFeature{6, 0}, AATFeature{6, 4}},
+	{MustNewTag("trad"), AATFeature{20, 0}, AATFeature{20, 16}},
+	{MustNewTag("twid"), AATFeature{22, 3}, AATFeature{22, 7}},
+	{MustNewTag("unic"), AATFeature{3, 14}, AATFeature{3, 15}},
+	{MustNewTag("valt"), AATFeature{22, 5}, AATFeature{22, 7}},
+	{MustNewTag("vert"), AATFeature{4, 0}, AATFeature{4, 1}},
+	{MustNewTag("vhal"), AATFeature{22, 6}, AATFeature{22, 7}},
+	{MustNewTag("vkna"), AATFeature{34, 2}, AATFeature{34, 3}},
+	{MustNewTag("vpal"), AATFeature{22, 5}, AATFeature{22, 7}},
+	{MustNewTag("vrt2"), AATFeature{4, 0}, AATFeature{4, 1}},
+	{MustNewTag("vrtr"), AATFeature{4, 2}, AATFeature{4, 3}},
+	{MustNewTag("zero"), AATFeature{14, 4}, AATFeature{14, 5}},
+}
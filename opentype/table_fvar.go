@@ -0,0 +1,163 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// TableFvar stores the variation axes and the named instances of a variable font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/fvar
+type TableFvar struct {
+	Axes      []VarAxis
+	Instances []VarInstance
+}
+
+// VarAxis is a variation axis, whose values are expressed in user space
+// (for instance, 100 to 900 for the weight axis).
+type VarAxis struct {
+	Tag                       Tag // such as 'wght' or 'wdth'
+	Minimum, Default, Maximum float32
+	Flags                     VarAxisFlags
+	// Name is the name ID of the display name of the axis, such as "Weight".
+	Name NameID
+}
+
+// VarAxisFlags are the flags of a variation axis.
+type VarAxisFlags uint16
+
+// VarAxisHidden indicates that the axis should not be exposed
+// directly in the user interfaces.
+const VarAxisHidden VarAxisFlags = 0x0001
+
+// Hidden returns true if the axis has the VarAxisHidden flag.
+func (axis VarAxis) Hidden() bool { return axis.Flags&VarAxisHidden != 0 }
+
+// VarInstance is a named instance of a variable font.
+type VarInstance struct {
+	// Coords are the user space coordinates of the instance, one per axis.
+	Coords []float32
+	// Subfamily is the name ID of the style of the instance, such as "Bold".
+	Subfamily NameID
+	// PostScript is the name ID of the PostScript name of the instance,
+	// or 0xFFFF if it is not provided.
+	PostScript NameID
+}
+
+// noNameID is the name ID used by the tables for missing names.
+const noNameID NameID = 0xFFFF
+
+func fixed1616(v uint32) float32 { return float32(int32(v)) / (1 << 16) }
+
+func parseTableFvar(data []byte) (TableFvar, error) {
+	if len(data) < 16 {
+		return TableFvar{}, errEOF
+	}
+	axesOffset := int(binary.BigEndian.Uint16(data[4:]))
+	axisCount := int(binary.BigEndian.Uint16(data[8:]))
+	axisSize := int(binary.BigEndian.Uint16(data[10:]))
+	instanceCount := int(binary.BigEndian.Uint16(data[12:]))
+	instanceSize := int(binary.BigEndian.Uint16(data[14:]))
+	if axisSize < 20 || instanceSize < 4+4*axisCount {
+		return TableFvar{}, errors.New("invalid fvar record sizes")
+	}
+	instancesOffset := axesOffset + axisCount*axisSize
+	if len(data) < instancesOffset+instanceCount*instanceSize {
+		return TableFvar{}, errEOF
+	}
+
+	out := TableFvar{Axes: make([]VarAxis, axisCount), Instances: make([]VarInstance, instanceCount)}
+	for i := range out.Axes {
+		record := data[axesOffset+i*axisSize:]
+		out.Axes[i] = VarAxis{
+			Tag:     Tag(binary.BigEndian.Uint32(record)),
+			Minimum: fixed1616(binary.BigEndian.Uint32(record[4:])),
+			Default: fixed1616(binary.BigEndian.Uint32(record[8:])),
+			Maximum: fixed1616(binary.BigEndian.Uint32(record[12:])),
+			Flags:   VarAxisFlags(binary.BigEndian.Uint16(record[16:])),
+			Name:    NameID(binary.BigEndian.Uint16(record[18:])),
+		}
+	}
+	for i := range out.Instances {
+		record := data[instancesOffset+i*instanceSize:]
+		instance := VarInstance{
+			Subfamily:  NameID(binary.BigEndian.Uint16(record)),
+			Coords:     make([]float32, axisCount),
+			PostScript: noNameID,
+		}
+		for j := range instance.Coords {
+			instance.Coords[j] = fixed1616(binary.BigEndian.Uint32(record[4+4*j:]))
+		}
+		if instanceSize >= 6+4*axisCount {
+			instance.PostScript = NameID(binary.BigEndian.Uint16(record[4+4*axisCount:]))
+		}
+		out.Instances[i] = instance
+	}
+	return out, nil
+}
+
+// FindAxis returns the index of the axis with the tag `tag`,
+// or false if there is no such axis.
+func (t *TableFvar) FindAxis(tag Tag) (int, bool) {
+	for i, axis := range t.Axes {
+		if axis.Tag == tag {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// fvar stores the 'fvar' table, loaded on first use.
+type fvar struct {
+	once  sync.Once
+	table *TableFvar // nil if missing or invalid
+}
+
+// Fvar returns the font variations table, parsed on first use,
+// or false if the font is not variable. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Fvar() (*TableFvar, bool) {
+	face.fvar.once.Do(func() {
+		if data, err := face.GetRawTable(tagFvar); err == nil {
+			if table, err := parseTableFvar(data); err != nil {
+				face.warnings.addError(face.tableError(tagFvar, err))
+			} else {
+				face.fvar.table = &table
+			}
+		}
+	})
+	return face.fvar.table, face.fvar.table != nil
+}
+
+// normalizedCoordinate returns the normalized value, in [-1, 1], of the user
+// space coordinate `coord` on the axis, ignoring the 'avar' table.
+func (axis VarAxis) normalizedCoordinate(coord float32) float32 {
+	switch {
+	case coord < axis.Default && axis.Default > axis.Minimum:
+		if coord < axis.Minimum {
+			coord = axis.Minimum
+		}
+		return (coord - axis.Default) / (axis.Default - axis.Minimum)
+	case coord > axis.Default && axis.Maximum > axis.Default:
+		if coord > axis.Maximum {
+			coord = axis.Maximum
+		}
+		return (coord - axis.Default) / (axis.Maximum - axis.Default)
+	default:
+		return 0
+	}
+}
+
+// userCoordinate returns the user space value of the normalized
+// coordinate `coord` on the axis, ignoring the 'avar' table.
+func (axis VarAxis) userCoordinate(coord float32) float32 {
+	if coord < -1 {
+		coord = -1
+	} else if coord > 1 {
+		coord = 1
+	}
+	if coord < 0 {
+		return axis.Default + coord*(axis.Default-axis.Minimum)
+	}
+	return axis.Default + coord*(axis.Maximum-axis.Default)
+}
@@ -0,0 +1,260 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// GlyphClass is the classification of a glyph given by the 'GDEF' table.
+type GlyphClass uint16
+
+const (
+	GlyphClassUnclassified GlyphClass = iota // the glyph is not classified
+	GlyphClassBase                           // single character, spacing glyph
+	GlyphClassLigature                       // multiple character, spacing glyph
+	GlyphClassMark                           // non-spacing combining glyph
+	GlyphClassComponent                      // part of single character, spacing glyph
+)
+
+// TableGDEF stores the glyph definitions used by the layout tables.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gdef
+type TableGDEF struct {
+	// GlyphClassDef assigns a GlyphClass to the glyphs. It is nil if not provided.
+	GlyphClassDef ClassDef
+	// MarkAttachClassDef assigns an attachment class to the marks, used by
+	// the lookups with a MarkAttachmentType flag. It is nil if not provided.
+	MarkAttachClassDef ClassDef
+	// MarkGlyphSets are the sets of marks used by the
+	// lookups with the UseMarkFilteringSet flag (version 1.2 and later).
+	MarkGlyphSets []Coverage
+
+	ligCarets ligCaretList
+	store     itemVariationStore // version 1.3 and later
+}
+
+// ligCaretList stores the caret positions of the ligatures.
+type ligCaretList struct {
+	coverage Coverage       // nil if missing
+	carets   [][]caretValue // indexed by coverage index
+}
+
+// caretValue is a caret position, whose format is 1 (coordinate),
+// 2 (contour point) or 3 (coordinate with adjustment).
+type caretValue struct {
+	format     uint16
+	coordinate int16       // format 1 and 3
+	pointIndex uint16      // format 2
+	device     deviceTable // format 3
+}
+
+func parseTableGDEF(data []byte) (TableGDEF, error) {
+	const headerSize = 12
+	if len(data) < headerSize {
+		return TableGDEF{}, errEOF
+	}
+	minor := binary.BigEndian.Uint16(data[2:])
+
+	var (
+		out TableGDEF
+		err error
+	)
+	if offset := binary.BigEndian.Uint16(data[4:]); offset != 0 {
+		if out.GlyphClassDef, err = parseClassDef(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint16(data[8:]); offset != 0 {
+		if out.ligCarets, err = parseLigCaretList(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint16(data[10:]); offset != 0 {
+		if out.MarkAttachClassDef, err = parseClassDef(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	if minor >= 2 && len(data) >= headerSize+2 {
+		if offset := binary.BigEndian.Uint16(data[12:]); offset != 0 {
+			if out.MarkGlyphSets, err = parseMarkGlyphSets(data, uint32(offset)); err != nil {
+				return out, err
+			}
+		}
+	}
+	if minor >= 3 && len(data) >= headerSize+6 {
+		if offset := binary.BigEndian.Uint32(data[14:]); offset != 0 {
+			if out.store, err = parseItemVariationStore(data, offset); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseLigCaretList(data []byte, offset uint32) (ligCaretList, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return ligCaretList{}, errors.New("invalid ligature caret list (EOF)")
+	}
+	data = data[offset:]
+	coverage, err := parseCoverage(data, uint32(binary.BigEndian.Uint16(data)))
+	if err != nil {
+		return ligCaretList{}, err
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+2*count {
+		return ligCaretList{}, errors.New("invalid ligature caret list (EOF)")
+	}
+	out := ligCaretList{coverage: coverage, carets: make([][]caretValue, count)}
+	for i := range out.carets {
+		out.carets[i], err = parseLigGlyph(data, uint32(binary.BigEndian.Uint16(data[4+2*i:])))
+		if err != nil {
+			return ligCaretList{}, err
+		}
+	}
+	return out, nil
+}
+
+func parseLigGlyph(data []byte, offset uint32) ([]caretValue, error) {
+	if int64(offset)+2 > int64(len(data)) {
+		return nil, errors.New("invalid ligature glyph table (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+2*count {
+		return nil, errors.New("invalid ligature glyph table (EOF)")
+	}
+	out := make([]caretValue, count)
+	for i := range out {
+		caretOffset := int(binary.BigEndian.Uint16(data[2+2*i:]))
+		if len(data) < caretOffset+4 {
+			return nil, errors.New("invalid caret value table (EOF)")
+		}
+		caret := data[caretOffset:]
+		out[i].format = binary.BigEndian.Uint16(caret)
+		switch out[i].format {
+		case 1:
+			out[i].coordinate = int16(binary.BigEndian.Uint16(caret[2:]))
+		case 2:
+			out[i].pointIndex = binary.BigEndian.Uint16(caret[2:])
+		case 3:
+			if len(caret) < 6 {
+				return nil, errors.New("invalid caret value table (EOF)")
+			}
+			out[i].coordinate = int16(binary.BigEndian.Uint16(caret[2:]))
+			if deviceOffset := binary.BigEndian.Uint16(caret[4:]); deviceOffset != 0 {
+				var err error
+				if out[i].device, err = parseDeviceTable(caret, uint32(deviceOffset)); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported caret value format %d", out[i].format)
+		}
+	}
+	return out, nil
+}
+
+func parseMarkGlyphSets(data []byte, offset uint32) ([]Coverage, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return nil, errEOF
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+4*count {
+		return nil, errEOF
+	}
+	out := make([]Coverage, count)
+	for i := range out {
+		var err error
+		out[i], err = parseCoverage(data, binary.BigEndian.Uint32(data[4+4*i:]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// GlyphClass returns the class of the glyph, which is
+// GlyphClassUnclassified if the font does not classify it.
+func (t *TableGDEF) GlyphClass(gid GID) GlyphClass {
+	if t.GlyphClassDef == nil {
+		return GlyphClassUnclassified
+	}
+	class, _ := t.GlyphClassDef.Class(gid)
+	return GlyphClass(class)
+}
+
+// IsInMarkGlyphSet returns true if the glyph belongs to the mark glyph set
+// at index `set`, and false if it does not, or if the set does not exist.
+func (t *TableGDEF) IsInMarkGlyphSet(set uint16, gid GID) bool {
+	if int(set) >= len(t.MarkGlyphSets) {
+		return false
+	}
+	_, ok := t.MarkGlyphSets[set].Index(gid)
+	return ok
+}
+
+// MarkAttachClass returns the mark attachment class of the glyph,
+// or 0 if the font does not define it.
+func (t *TableGDEF) MarkAttachClass(gid GID) uint16 {
+	if t.MarkAttachClassDef == nil {
+		return 0
+	}
+	class, _ := t.MarkAttachClassDef.Class(gid)
+	return class
+}
+
+// IgnoresGlyph returns true if the glyph must be skipped by a lookup using
+// the `flag` lookup flag, according to its class, and to its mark attachment class
+// or the mark filtering set at index `markFilteringSet` (only used if
+// the flag includes UseMarkFilteringSet) for marks.
+func (t *TableGDEF) IgnoresGlyph(gid GID, flag LookupFlag, markFilteringSet uint16) bool {
+	switch t.GlyphClass(gid) {
+	case GlyphClassBase:
+		return flag&IgnoreBaseGlyphs != 0
+	case GlyphClassLigature:
+		return flag&IgnoreLigatures != 0
+	case GlyphClassMark:
+		switch {
+		case flag&IgnoreMarks != 0:
+			return true
+		case flag&UseMarkFilteringSet != 0:
+			return !t.IsInMarkGlyphSet(markFilteringSet, gid)
+		case flag&MarkAttachmentType != 0:
+			return flag.MarkAttachClass() != t.MarkAttachClass(gid)
+		}
+	}
+	return false
+}
+
+// gdef is the lazily parsed 'GDEF' table.
+type gdef struct {
+	once  sync.Once
+	table *TableGDEF // nil if missing or invalid
+}
+
+// GDEF returns the glyph definition table, parsed on first use,
+// or false if the font has no 'GDEF' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) GDEF() (*TableGDEF, bool) {
+	face.gdef.once.Do(func() {
+		if data, err := face.GetRawTable(tagGDEF); err == nil {
+			if table, err := parseTableGDEF(data); err != nil {
+				face.warnings.addError(face.tableError(tagGDEF, err))
+			} else {
+				face.gdef.table = &table
+			}
+		}
+	})
+	return face.gdef.table, face.gdef.table != nil
+}
+
+// gdefStore returns the variation store of the 'GDEF' table,
+// or nil if the font has no 'GDEF' table.
+func (face *Face) gdefStore() *itemVariationStore {
+	if gdef, ok := face.GDEF(); ok {
+		return &gdef.store
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// This file parses the glyph descriptions of the 'glyf' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/glyf
+
+// maxCompositeDepth limits the nesting of composite glyphs.
+const maxCompositeDepth = 10
+
+type contourPoint struct {
+	x, y    float32
+	onCurve bool
+}
+
+// glyfPoints stores the points of a glyph,
+// as a list of contours.
+type glyfPoints struct {
+	points []contourPoint
+	ends   []int // index of the last point of each contour
+}
+
+// simple glyph flags
+const (
+	flagOnCurve = 1 << iota
+	flagXShort
+	flagYShort
+	flagRepeat
+	flagXSameOrPositive
+	flagYSameOrPositive
+)
+
+// composite glyph flags
+const (
+	flagArg1And2AreWords        = 0x0001
+	flagArgsAreXYValues         = 0x0002
+	flagWeHaveAScale            = 0x0008
+	flagMoreComponents          = 0x0020
+	flagWeHaveAnXAndYScale      = 0x0040
+	flagWeHaveATwoByTwo         = 0x0080
+	flagWeHaveInstructions      = 0x0100
+	flagUseMyMetrics            = 0x0200
+	flagScaledComponentOffset   = 0x0800
+	flagUnscaledComponentOffset = 0x1000
+)
+
+// phantomCount is the number of phantom points of a glyph, which are
+// its horizontal origin and advance, then its vertical origin and advance.
+const phantomCount = 4
+
+// appendGlyfPoints appends the points of the glyph (resolving the composite glyphs)
+// to `out`, and returns its phantom points. The variations of the 'gvar' table
+// are applied for variable fonts.
+func (face *Face) appendGlyfPoints(gid GID, depth int, out *glyfPoints) ([phantomCount]contourPoint, error) {
+	var phantoms [phantomCount]contourPoint
+	if depth > maxCompositeDepth {
+		return phantoms, errors.New("composite glyphs nesting limit reached")
+	}
+	data, ok := face.glyphData(gid)
+	if !ok {
+		return phantoms, fmt.Errorf("invalid glyph %d", gid)
+	}
+	if len(data) == 0 { // no outline
+		phantoms = face.phantomPoints(gid, 0, 0)
+		face.varyGlyfPoints(gid, nil, phantoms[:], nil)
+		return phantoms, nil
+	}
+	if len(data) < 10 {
+		return phantoms, fmt.Errorf("invalid glyph %d (EOF)", gid)
+	}
+	xMin, yMax := int16(binary.BigEndian.Uint16(data[2:])), int16(binary.BigEndian.Uint16(data[8:]))
+	phantoms = face.phantomPoints(gid, xMin, yMax)
+	numContours := int16(binary.BigEndian.Uint16(data))
+	if numContours < 0 {
+		err := face.appendCompositeGlyph(gid, data[10:], depth, out, &phantoms)
+		return phantoms, err
+	}
+
+	start, startEnds := len(out.points), len(out.ends)
+	if err := appendSimpleGlyph(data[10:], int(numContours), out); err != nil {
+		return phantoms, err
+	}
+	if face.gvarTable() != nil {
+		ends := make([]int, len(out.ends)-startEnds)
+		for i, end := range out.ends[startEnds:] {
+			ends[i] = end - start
+		}
+		face.varyGlyfPoints(gid, out.points[start:], phantoms[:], ends)
+	}
+	return phantoms, nil
+}
+
+// glyphPoints returns the points of the glyph, shifted horizontally so that its
+// horizontal origin (its first phantom point) is at x = 0 : as rasterizers do, the
+// left side bearing of the 'hmtx' table is used, even if it differs from
+// the minimum x of the bounding box.
+func (face *Face) glyphPoints(gid GID) (glyfPoints, error) {
+	var points glyfPoints
+	phantoms, err := face.appendGlyfPoints(gid, 0, &points)
+	if err != nil {
+		return points, err
+	}
+	if dx := phantoms[0].x; dx != 0 {
+		for i := range points.points {
+			points.points[i].x -= dx
+		}
+	}
+	return points, nil
+}
+
+// gvarTable returns the 'gvar' table if variations must be applied, or nil.
+func (face *Face) gvarTable() *tableGvar {
+	if face.outlines.gvar == nil || !face.isVariable() {
+		return nil
+	}
+	return face.outlines.gvar
+}
+
+// varyGlyfPoints applies the variations of the glyph, if any, to its points and
+// phantom points. Invalid variation data are ignored.
+func (face *Face) varyGlyfPoints(gid GID, points, phantoms []contourPoint, ends []int) {
+	gvar := face.gvarTable()
+	if gvar == nil {
+		return
+	}
+	all := make([]contourPoint, 0, len(points)+len(phantoms))
+	all = append(append(all, points...), phantoms...)
+	if err := gvar.applyDeltas(gid, face.coords, all, ends); err != nil {
+		return
+	}
+	copy(points, all)
+	copy(phantoms, all[len(points):])
+}
+
+// phantomPoints returns the phantom points of the glyph
+// whose bounding box has `xMin` and `yMax` for bounds.
+func (face *Face) phantomPoints(gid GID, xMin, yMax int16) [phantomCount]contourPoint {
+	m := face.loadMetrics()
+	lsb, ok := m.hmtx.sideBearing(gid)
+	if !ok {
+		lsb = xMin
+	}
+	left := float32(xMin) - float32(lsb)
+	advance := float32(face.Upem() / 2)
+	if len(m.hmtx.metrics) != 0 {
+		advance = float32(m.hmtx.advance(gid))
+	}
+	top, vAdvance := float32(yMax), float32(face.Upem())
+	if tsb, ok := m.vmtx.sideBearing(gid); ok {
+		top += float32(tsb)
+	}
+	if len(m.vmtx.metrics) != 0 {
+		vAdvance = float32(m.vmtx.advance(gid))
+	}
+	return [phantomCount]contourPoint{{x: left}, {x: left + advance}, {y: top}, {y: top - vAdvance}}
+}
+
+func appendSimpleGlyph(data []byte, numContours int, out *glyfPoints) error {
+	errTruncated := errors.New("invalid simple glyph (EOF)")
+	if len(data) < 2*numContours+2 {
+		return errTruncated
+	}
+	start := len(out.points)
+	numPoints := 0
+	for i := 0; i < numContours; i++ {
+		end := int(binary.BigEndian.Uint16(data[2*i:])) + 1
+		if end < numPoints {
+			return errors.New("invalid simple glyph contours")
+		}
+		numPoints = end
+		out.ends = append(out.ends, start+end-1)
+	}
+	instructionLength := int(binary.BigEndian.Uint16(data[2*numContours:]))
+	data = data[2*numContours+2:]
+	if len(data) < instructionLength {
+		return errTruncated
+	}
+	data = data[instructionLength:]
+
+	// flags
+	flags := make([]byte, numPoints)
+	for i := 0; i < numPoints; {
+		if len(data) == 0 {
+			return errTruncated
+		}
+		flag := data[0]
+		data = data[1:]
+		flags[i] = flag
+		i++
+		if flag&flagRepeat != 0 {
+			if len(data) == 0 {
+				return errTruncated
+			}
+			count := int(data[0])
+			data = data[1:]
+			for ; count > 0 && i < numPoints; count-- {
+				flags[i] = flag
+				i++
+			}
+		}
+	}
+
+	// coordinates
+	readCoords := func(short, sameOrPositive byte) ([]int16, error) {
+		coords := make([]int16, numPoints)
+		var v int16
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if len(data) < 1 {
+					return nil, errTruncated
+				}
+				if flag&sameOrPositive != 0 {
+					v += int16(data[0])
+				} else {
+					v -= int16(data[0])
+				}
+				data = data[1:]
+			case flag&sameOrPositive == 0:
+				if len(data) < 2 {
+					return nil, errTruncated
+				}
+				v += int16(binary.BigEndian.Uint16(data))
+				data = data[2:]
+			}
+			coords[i] = v
+		}
+		return coords, nil
+	}
+	xs, err := readCoords(flagXShort, flagXSameOrPositive)
+	if err != nil {
+		return err
+	}
+	ys, err := readCoords(flagYShort, flagYSameOrPositive)
+	if err != nil {
+		return err
+	}
+	for i, flag := range flags {
+		out.points = append(out.points, contourPoint{x: float32(xs[i]), y: float32(ys[i]), onCurve: flag&flagOnCurve != 0})
+	}
+	return nil
+}
+
+// glyfComponent is a component of a composite glyph.
+type glyfComponent struct {
+	flags      uint16
+	gid        GID
+	arg1, arg2 int32
+	// transform matrix [a b c d]: x' = a*x + c*y, y' = b*x + d*y
+	a, b, c, d float32
+}
+
+// parseGlyfComponents returns the components of a composite glyph,
+// and the data following them (the instructions of the glyph).
+func parseGlyfComponents(data []byte) ([]glyfComponent, []byte, error) {
+	errTruncated := errors.New("invalid composite glyph (EOF)")
+	var out []glyfComponent
+	for {
+		if len(data) < 4 {
+			return nil, nil, errTruncated
+		}
+		comp := glyfComponent{
+			flags: binary.BigEndian.Uint16(data),
+			gid:   GID(binary.BigEndian.Uint16(data[2:])),
+			a:     1,
+			d:     1,
+		}
+		flags := comp.flags
+		data = data[4:]
+
+		if flags&flagArg1And2AreWords != 0 {
+			if len(data) < 4 {
+				return nil, nil, errTruncated
+			}
+			comp.arg1, comp.arg2 = int32(binary.BigEndian.Uint16(data)), int32(binary.BigEndian.Uint16(data[2:]))
+			if flags&flagArgsAreXYValues != 0 {
+				comp.arg1, comp.arg2 = int32(int16(comp.arg1)), int32(int16(comp.arg2))
+			}
+			data = data[4:]
+		} else {
+			if len(data) < 2 {
+				return nil, nil, errTruncated
+			}
+			comp.arg1, comp.arg2 = int32(data[0]), int32(data[1])
+			if flags&flagArgsAreXYValues != 0 {
+				comp.arg1, comp.arg2 = int32(int8(comp.arg1)), int32(int8(comp.arg2))
+			}
+			data = data[2:]
+		}
+
+		switch {
+		case flags&flagWeHaveAScale != 0:
+			if len(data) < 2 {
+				return nil, nil, errTruncated
+			}
+			comp.a = f2dot14(binary.BigEndian.Uint16(data))
+			comp.d = comp.a
+			data = data[2:]
+		case flags&flagWeHaveAnXAndYScale != 0:
+			if len(data) < 4 {
+				return nil, nil, errTruncated
+			}
+			comp.a, comp.d = f2dot14(binary.BigEndian.Uint16(data)), f2dot14(binary.BigEndian.Uint16(data[2:]))
+			data = data[4:]
+		case flags&flagWeHaveATwoByTwo != 0:
+			if len(data) < 8 {
+				return nil, nil, errTruncated
+			}
+			comp.a, comp.b = f2dot14(binary.BigEndian.Uint16(data)), f2dot14(binary.BigEndian.Uint16(data[2:]))
+			comp.c, comp.d = f2dot14(binary.BigEndian.Uint16(data[4:])), f2dot14(binary.BigEndian.Uint16(data[6:]))
+			data = data[8:]
+		}
+		out = append(out, comp)
+
+		if flags&flagMoreComponents == 0 {
+			return out, data, nil
+		}
+	}
+}
+
+func (face *Face) appendCompositeGlyph(gid GID, data []byte, depth int, out *glyfPoints, phantoms *[phantomCount]contourPoint) error {
+	components, _, err := parseGlyfComponents(data)
+	if err != nil {
+		return err
+	}
+
+	// the variations of a composite glyph move the offsets of its components
+	offsets := make([]contourPoint, len(components))
+	for i, comp := range components {
+		if comp.flags&flagArgsAreXYValues != 0 {
+			offsets[i] = contourPoint{x: float32(comp.arg1), y: float32(comp.arg2)}
+		}
+	}
+	face.varyGlyfPoints(gid, offsets, phantoms[:], nil)
+
+	base := len(out.points) // the anchor points are relative to the composite glyph
+	for i, comp := range components {
+		start := len(out.points)
+		componentPhantoms, err := face.appendGlyfPoints(comp.gid, depth+1, out)
+		if err != nil {
+			return err
+		}
+		if comp.flags&flagUseMyMetrics != 0 {
+			*phantoms = componentPhantoms
+		}
+		a, b, c, d := comp.a, comp.b, comp.c, comp.d
+		component := out.points[start:]
+		for i, p := range component {
+			component[i].x, component[i].y = a*p.x+c*p.y, b*p.x+d*p.y
+		}
+
+		var dx, dy float32
+		if comp.flags&flagArgsAreXYValues != 0 {
+			dx, dy = offsets[i].x, offsets[i].y
+			if comp.flags&flagScaledComponentOffset != 0 && comp.flags&flagUnscaledComponentOffset == 0 {
+				dx, dy = a*dx+c*dy, b*dx+d*dy
+			}
+		} else { // align the point arg2 of the component on the point arg1 of the parent
+			if base+int(comp.arg1) >= start || int(comp.arg2) >= len(component) {
+				return errors.New("invalid composite glyph anchor points")
+			}
+			parent, child := out.points[base+int(comp.arg1)], component[comp.arg2]
+			dx, dy = parent.x-child.x, parent.y-child.y
+		}
+		for i := range component {
+			component[i].x += dx
+			component[i].y += dy
+		}
+	}
+	return nil
+}
@@ -0,0 +1,717 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+// TableGPOS stores the glyph positionings of the font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gpos
+type TableGPOS struct {
+	Layout
+	Lookups []GPOSLookup
+}
+
+// GPOS lookup types
+const (
+	GPOSSingle = 1 + iota
+	GPOSPair
+	GPOSCursive
+	GPOSMarkToBase
+	GPOSMarkToLigature
+	GPOSMarkToMark
+	GPOSContext
+	GPOSChainedContext
+	GPOSExtension // only used in the font files, see GPOSLookup.Type
+)
+
+// GPOSLookup is a positioning lookup, whose subtables are tried in order,
+// until one of them applies.
+type GPOSLookup struct {
+	// Type is the lookup type (GPOSSingle, ...), which is, for extension
+	// lookups, the type of the extension subtables.
+	Type uint16
+	Flag LookupFlag
+	// MarkFilteringSet is the index of the mark glyph set of the 'GDEF' table
+	// used by the lookup, if Flag includes UseMarkFilteringSet.
+	MarkFilteringSet uint16
+	Subtables        []GPOSSubtable
+}
+
+// GPOSSubtable is a subtable of a positioning lookup.
+// It is one of SinglePos, PairPos, CursivePos, MarkBasePos, MarkLigPos,
+// MarkMarkPos, ContextualPos or ChainedContextualPos.
+type GPOSSubtable interface {
+	isGPOSSubtable()
+}
+
+func (SinglePos) isGPOSSubtable()            {}
+func (PairPos) isGPOSSubtable()              {}
+func (CursivePos) isGPOSSubtable()           {}
+func (MarkBasePos) isGPOSSubtable()          {}
+func (MarkLigPos) isGPOSSubtable()           {}
+func (MarkMarkPos) isGPOSSubtable()          {}
+func (ContextualPos) isGPOSSubtable()        {}
+func (ChainedContextualPos) isGPOSSubtable() {}
+
+// ValueFormat lists the fields stored in the ValueRecords of a subtable.
+type ValueFormat uint16
+
+const (
+	ValueXPlacement ValueFormat = 1 << iota
+	ValueYPlacement
+	ValueXAdvance
+	ValueYAdvance
+	ValueXPlacementDevice
+	ValueYPlacementDevice
+	ValueXAdvanceDevice
+	ValueYAdvanceDevice
+)
+
+// size returns the size of a value record, in bytes.
+func (f ValueFormat) size() int { return 2 * bits.OnesCount16(uint16(f&0xFF)) }
+
+// ValueRecord adjusts the position and the advance of a glyph, in font units.
+// The fields not included in the ValueFormat of the subtable are 0.
+type ValueRecord struct {
+	XPlacement, YPlacement int16
+	XAdvance, YAdvance     int16
+
+	// adjustments of XPlacement, YPlacement, XAdvance and YAdvance
+	devices [4]deviceTable
+}
+
+// Anchor is an attachment point of a glyph, in font units.
+type Anchor struct {
+	// Format is 1 (coordinates), 2 (coordinates and contour point) or
+	// 3 (coordinates with adjustments).
+	Format uint16
+	X, Y   int16
+	// AnchorPoint is the contour point of the glyph giving,
+	// when available, the position of the anchor (format 2).
+	AnchorPoint uint16
+
+	xDevice, yDevice deviceTable // format 3
+}
+
+// SinglePos adjusts the position of a glyph, with the same value
+// for all the covered glyphs (format 1), or the value of Values
+// at the coverage index (format 2).
+type SinglePos struct {
+	Format      uint16
+	Coverage    Coverage
+	ValueFormat ValueFormat
+	Values      []ValueRecord // one value for format 1
+}
+
+// Value returns the adjustment of the glyph, or false if it is not covered.
+func (s SinglePos) Value(gid GID) (ValueRecord, bool) {
+	index, ok := s.Coverage.Index(gid)
+	if !ok {
+		return ValueRecord{}, false
+	}
+	if s.Format == 1 {
+		index = 0
+	}
+	if index >= len(s.Values) {
+		return ValueRecord{}, false
+	}
+	return s.Values[index], true
+}
+
+// PairPos adjusts the positions of pairs of glyphs, described by their
+// glyphs (format 1), or by the classes of the glyphs (format 2).
+type PairPos struct {
+	Format uint16
+	// Coverage is the coverage of the first glyphs.
+	Coverage Coverage
+	// ValueFormat1 and ValueFormat2 are the formats of the
+	// adjustments of the first and second glyphs.
+	ValueFormat1, ValueFormat2 ValueFormat
+
+	// PairSets are the pairs starting with the covered glyph,
+	// indexed by coverage index, sorted by second glyph (format 1).
+	PairSets [][]PairValue
+
+	// ClassDef1 and ClassDef2 are the classes of the first and second glyphs (format 2).
+	ClassDef1, ClassDef2 ClassDef
+	// ClassValues are the adjustments of the first and second glyphs,
+	// indexed by the class of the first glyph, then by the class of the
+	// second one (format 2).
+	ClassValues [][][2]ValueRecord
+}
+
+// PairValue is a pair of glyphs of a PairPos subtable of format 1.
+type PairValue struct {
+	SecondGlyph    GID
+	Value1, Value2 ValueRecord
+}
+
+// Values returns the adjustments of the glyphs of the pair,
+// or false if the pair is not covered.
+func (p PairPos) Values(first, second GID) (ValueRecord, ValueRecord, bool) {
+	index, ok := p.Coverage.Index(first)
+	if !ok {
+		return ValueRecord{}, ValueRecord{}, false
+	}
+	if p.Format == 1 {
+		if index >= len(p.PairSets) {
+			return ValueRecord{}, ValueRecord{}, false
+		}
+		set := p.PairSets[index]
+		low, high := 0, len(set)
+		for low < high {
+			mid := low + (high-low)/2
+			switch {
+			case second < set[mid].SecondGlyph:
+				high = mid
+			case second > set[mid].SecondGlyph:
+				low = mid + 1
+			default:
+				return set[mid].Value1, set[mid].Value2, true
+			}
+		}
+		return ValueRecord{}, ValueRecord{}, false
+	}
+	class1, _ := p.ClassDef1.Class(first)
+	class2, _ := p.ClassDef2.Class(second)
+	if int(class1) >= len(p.ClassValues) || int(class2) >= len(p.ClassValues[class1]) {
+		return ValueRecord{}, ValueRecord{}, false
+	}
+	values := p.ClassValues[class1][class2]
+	return values[0], values[1], true
+}
+
+// CursivePos connects the exit anchor of a glyph
+// to the entry anchor of the following one.
+type CursivePos struct {
+	Coverage   Coverage
+	EntryExits []EntryExit // indexed by coverage index
+}
+
+// EntryExit stores the cursive anchors of a glyph.
+type EntryExit struct {
+	Entry, Exit *Anchor // nil if missing
+}
+
+// MarkRecord is the class and the anchor of a mark.
+type MarkRecord struct {
+	Class  uint16
+	Anchor Anchor
+}
+
+// MarkBasePos attaches marks to base glyphs.
+type MarkBasePos struct {
+	MarkCoverage, BaseCoverage Coverage
+	Marks                      []MarkRecord // indexed by mark coverage index
+	// Bases are the anchors of the base glyphs, indexed by base coverage
+	// index, then by mark class. The missing anchors are nil.
+	Bases [][]*Anchor
+}
+
+// MarkLigPos attaches marks to the components of ligatures.
+type MarkLigPos struct {
+	MarkCoverage, LigatureCoverage Coverage
+	Marks                          []MarkRecord // indexed by mark coverage index
+	// Ligatures are the anchors of the ligatures, indexed by ligature coverage
+	// index, then by component, then by mark class. The missing anchors are nil.
+	Ligatures [][][]*Anchor
+}
+
+// MarkMarkPos attaches marks (mark1) to other marks (mark2).
+type MarkMarkPos struct {
+	MarkCoverage, Mark2Coverage Coverage
+	Marks                       []MarkRecord // indexed by mark coverage index
+	// Mark2s are the anchors of the mark2 glyphs, indexed by mark2 coverage
+	// index, then by mark class. The missing anchors are nil.
+	Mark2s [][]*Anchor
+}
+
+// ContextualPos applies positioning lookups to glyph sequences.
+type ContextualPos struct {
+	SequenceContext
+}
+
+// ChainedContextualPos applies positioning lookups to glyph
+// sequences, according to their surrounding glyphs.
+type ChainedContextualPos struct {
+	ChainedSequenceContext
+}
+
+func parseTableGPOS(data []byte) (TableGPOS, error) {
+	layout, err := parseLayout(data)
+	if err != nil {
+		return TableGPOS{}, err
+	}
+	lookups, err := parseLookupList(data, GPOSExtension)
+	if err != nil {
+		return TableGPOS{}, err
+	}
+	out := TableGPOS{Layout: layout, Lookups: make([]GPOSLookup, len(lookups))}
+	for i, lookup := range lookups {
+		if out.Lookups[i], err = parseGPOSLookup(lookup); err != nil {
+			return TableGPOS{}, fmt.Errorf("lookup %d: %s", i, err)
+		}
+	}
+	return out, nil
+}
+
+func parseGPOSLookup(lookup lookupTable) (GPOSLookup, error) {
+	out := GPOSLookup{
+		Type:             lookup.kind,
+		Flag:             lookup.flag,
+		MarkFilteringSet: lookup.markFilteringSet,
+		Subtables:        make([]GPOSSubtable, len(lookup.subtables)),
+	}
+	for j, subtable := range lookup.subtables {
+		var err error
+		if out.Subtables[j], err = parseGPOSSubtable(subtable, lookup.kind); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseGPOSSubtable(data []byte, kind uint16) (GPOSSubtable, error) {
+	switch kind {
+	case GPOSSingle:
+		return parseSinglePos(data)
+	case GPOSPair:
+		return parsePairPos(data)
+	case GPOSCursive:
+		return parseCursivePos(data)
+	case GPOSMarkToBase:
+		markCoverage, baseCoverage, marks, bases, err := parseMarkAttachment(data)
+		return MarkBasePos{MarkCoverage: markCoverage, BaseCoverage: baseCoverage, Marks: marks, Bases: bases}, err
+	case GPOSMarkToLigature:
+		return parseMarkLigPos(data)
+	case GPOSMarkToMark:
+		markCoverage, mark2Coverage, marks, mark2s, err := parseMarkAttachment(data)
+		return MarkMarkPos{MarkCoverage: markCoverage, Mark2Coverage: mark2Coverage, Marks: marks, Mark2s: mark2s}, err
+	case GPOSContext:
+		context, err := parseSequenceContext(data)
+		return ContextualPos{context}, err
+	case GPOSChainedContext:
+		context, err := parseChainedSequenceContext(data)
+		return ChainedContextualPos{context}, err
+	default:
+		return nil, fmt.Errorf("unsupported lookup type %d", kind)
+	}
+}
+
+// parseValueRecord parses the value record of format `format` starting
+// at `data[offset:]`, whose device tables are relative to `parent`.
+func parseValueRecord(parent, data []byte, offset int, format ValueFormat) (ValueRecord, error) {
+	if len(data) < offset+format.size() {
+		return ValueRecord{}, errors.New("invalid value record (EOF)")
+	}
+	var (
+		out    ValueRecord
+		values [8]uint16
+	)
+	for i := range values {
+		if format&(1<<i) != 0 {
+			values[i] = binary.BigEndian.Uint16(data[offset:])
+			offset += 2
+		}
+	}
+	out.XPlacement, out.YPlacement = int16(values[0]), int16(values[1])
+	out.XAdvance, out.YAdvance = int16(values[2]), int16(values[3])
+	for i, deviceOffset := range values[4:] {
+		if deviceOffset == 0 {
+			continue
+		}
+		var err error
+		if out.devices[i], err = parseDeviceTable(parent, uint32(deviceOffset)); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// parseAnchor parses the anchor at `data[offset:]`, or returns nil for null offsets.
+func parseAnchor(data []byte, offset uint16) (*Anchor, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if len(data) < int(offset)+6 {
+		return nil, errors.New("invalid anchor (EOF)")
+	}
+	data = data[offset:]
+	out := Anchor{
+		Format: binary.BigEndian.Uint16(data),
+		X:      int16(binary.BigEndian.Uint16(data[2:])),
+		Y:      int16(binary.BigEndian.Uint16(data[4:])),
+	}
+	switch out.Format {
+	case 1:
+	case 2:
+		if len(data) < 8 {
+			return nil, errors.New("invalid anchor (EOF)")
+		}
+		out.AnchorPoint = binary.BigEndian.Uint16(data[6:])
+	case 3:
+		if len(data) < 10 {
+			return nil, errors.New("invalid anchor (EOF)")
+		}
+		var err error
+		if offset := binary.BigEndian.Uint16(data[6:]); offset != 0 {
+			if out.xDevice, err = parseDeviceTable(data, uint32(offset)); err != nil {
+				return nil, err
+			}
+		}
+		if offset := binary.BigEndian.Uint16(data[8:]); offset != 0 {
+			if out.yDevice, err = parseDeviceTable(data, uint32(offset)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported anchor format %d", out.Format)
+	}
+	return &out, nil
+}
+
+func parseSinglePos(data []byte) (SinglePos, error) {
+	if len(data) < 6 {
+		return SinglePos{}, errors.New("invalid single positioning (EOF)")
+	}
+	out := SinglePos{
+		Format:      binary.BigEndian.Uint16(data),
+		ValueFormat: ValueFormat(binary.BigEndian.Uint16(data[4:])),
+	}
+	var err error
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+		return out, err
+	}
+	switch out.Format {
+	case 1:
+		out.Values = make([]ValueRecord, 1)
+		out.Values[0], err = parseValueRecord(data, data, 6, out.ValueFormat)
+	case 2:
+		if len(data) < 8 {
+			return out, errors.New("invalid single positioning (EOF)")
+		}
+		out.Values = make([]ValueRecord, binary.BigEndian.Uint16(data[6:]))
+		size := out.ValueFormat.size()
+		for i := range out.Values {
+			if out.Values[i], err = parseValueRecord(data, data, 8+i*size, out.ValueFormat); err != nil {
+				return out, err
+			}
+		}
+	default:
+		return out, fmt.Errorf("unsupported single positioning format %d", out.Format)
+	}
+	return out, err
+}
+
+func parsePairPos(data []byte) (PairPos, error) {
+	errTruncated := errors.New("invalid pair positioning (EOF)")
+	if len(data) < 10 {
+		return PairPos{}, errTruncated
+	}
+	out := PairPos{
+		Format:       binary.BigEndian.Uint16(data),
+		ValueFormat1: ValueFormat(binary.BigEndian.Uint16(data[4:])),
+		ValueFormat2: ValueFormat(binary.BigEndian.Uint16(data[6:])),
+	}
+	var err error
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+		return out, err
+	}
+	size1, size2 := out.ValueFormat1.size(), out.ValueFormat2.size()
+	switch out.Format {
+	case 1:
+		count := int(binary.BigEndian.Uint16(data[8:]))
+		if len(data) < 10+2*count {
+			return out, errTruncated
+		}
+		out.PairSets = make([][]PairValue, count)
+		for i := range out.PairSets {
+			offset := int(binary.BigEndian.Uint16(data[10+2*i:]))
+			if len(data) < offset+2 {
+				return out, errTruncated
+			}
+			set := data[offset:]
+			pairs := make([]PairValue, binary.BigEndian.Uint16(set))
+			recordSize := 2 + size1 + size2
+			if len(set) < 2+len(pairs)*recordSize {
+				return out, errTruncated
+			}
+			for j := range pairs {
+				record := 2 + j*recordSize
+				pairs[j].SecondGlyph = GID(binary.BigEndian.Uint16(set[record:]))
+				if pairs[j].Value1, err = parseValueRecord(set, set, record+2, out.ValueFormat1); err != nil {
+					return out, err
+				}
+				if pairs[j].Value2, err = parseValueRecord(set, set, record+2+size1, out.ValueFormat2); err != nil {
+					return out, err
+				}
+			}
+			out.PairSets[i] = pairs
+		}
+	case 2:
+		if len(data) < 16 {
+			return out, errTruncated
+		}
+		if out.ClassDef1, err = parseOptionalClassDef(data, binary.BigEndian.Uint16(data[8:])); err != nil {
+			return out, err
+		}
+		if out.ClassDef2, err = parseOptionalClassDef(data, binary.BigEndian.Uint16(data[10:])); err != nil {
+			return out, err
+		}
+		class1Count, class2Count := int(binary.BigEndian.Uint16(data[12:])), int(binary.BigEndian.Uint16(data[14:]))
+		if len(data) < 16+class1Count*class2Count*(size1+size2) {
+			return out, errTruncated
+		}
+		out.ClassValues = make([][][2]ValueRecord, class1Count)
+		offset := 16
+		for i := range out.ClassValues {
+			out.ClassValues[i] = make([][2]ValueRecord, class2Count)
+			for j := range out.ClassValues[i] {
+				if out.ClassValues[i][j][0], err = parseValueRecord(data, data, offset, out.ValueFormat1); err != nil {
+					return out, err
+				}
+				if out.ClassValues[i][j][1], err = parseValueRecord(data, data, offset+size1, out.ValueFormat2); err != nil {
+					return out, err
+				}
+				offset += size1 + size2
+			}
+		}
+	default:
+		return out, fmt.Errorf("unsupported pair positioning format %d", out.Format)
+	}
+	return out, nil
+}
+
+func parseCursivePos(data []byte) (CursivePos, error) {
+	if len(data) < 6 {
+		return CursivePos{}, errors.New("invalid cursive positioning (EOF)")
+	}
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return CursivePos{}, fmt.Errorf("unsupported cursive positioning format %d", format)
+	}
+	var (
+		out CursivePos
+		err error
+	)
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+		return out, err
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+4*count {
+		return out, errors.New("invalid cursive positioning (EOF)")
+	}
+	out.EntryExits = make([]EntryExit, count)
+	for i := range out.EntryExits {
+		if out.EntryExits[i].Entry, err = parseAnchor(data, binary.BigEndian.Uint16(data[6+4*i:])); err != nil {
+			return out, err
+		}
+		if out.EntryExits[i].Exit, err = parseAnchor(data, binary.BigEndian.Uint16(data[8+4*i:])); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// parseMarkArray parses the mark array at `data[offset:]`,
+// whose classes must be lower than `classCount`.
+func parseMarkArray(data []byte, offset uint16, classCount int) ([]MarkRecord, error) {
+	if len(data) < int(offset)+2 {
+		return nil, errors.New("invalid mark array (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+4*count {
+		return nil, errors.New("invalid mark array (EOF)")
+	}
+	out := make([]MarkRecord, count)
+	for i := range out {
+		out[i].Class = binary.BigEndian.Uint16(data[2+4*i:])
+		if int(out[i].Class) >= classCount {
+			return nil, fmt.Errorf("invalid mark class (%d >= %d)", out[i].Class, classCount)
+		}
+		anchor, err := parseAnchor(data, binary.BigEndian.Uint16(data[4+4*i:]))
+		if err != nil {
+			return nil, err
+		}
+		if anchor == nil {
+			return nil, errors.New("missing mark anchor")
+		}
+		out[i].Anchor = *anchor
+	}
+	return out, nil
+}
+
+// parseAnchorMatrix parses the array of `classCount` anchors per
+// row starting at `data[offset:]`, used by the base, ligature
+// component and mark2 arrays. Null offsets are empty arrays.
+func parseAnchorMatrix(data []byte, offset, classCount int) ([][]*Anchor, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if len(data) < offset+2 {
+		return nil, errors.New("invalid anchor array (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+2*count*classCount {
+		return nil, errors.New("invalid anchor array (EOF)")
+	}
+	out := make([][]*Anchor, count)
+	for i := range out {
+		out[i] = make([]*Anchor, classCount)
+		for j := range out[i] {
+			var err error
+			if out[i][j], err = parseAnchor(data, binary.BigEndian.Uint16(data[2+2*(i*classCount+j):])); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseMarkAttachment parses the mark-to-base and mark-to-mark
+// subtables, which share the same structure.
+func parseMarkAttachment(data []byte) (Coverage, Coverage, []MarkRecord, [][]*Anchor, error) {
+	if len(data) < 12 {
+		return nil, nil, nil, nil, errors.New("invalid mark attachment (EOF)")
+	}
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return nil, nil, nil, nil, fmt.Errorf("unsupported mark attachment format %d", format)
+	}
+	markCoverage, err := parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:])))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	baseCoverage, err := parseCoverage(data, uint32(binary.BigEndian.Uint16(data[4:])))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	classCount := int(binary.BigEndian.Uint16(data[6:]))
+	marks, err := parseMarkArray(data, binary.BigEndian.Uint16(data[8:]), classCount)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	bases, err := parseAnchorMatrix(data, int(binary.BigEndian.Uint16(data[10:])), classCount)
+	return markCoverage, baseCoverage, marks, bases, err
+}
+
+func parseMarkLigPos(data []byte) (MarkLigPos, error) {
+	errTruncated := errors.New("invalid mark to ligature attachment (EOF)")
+	if len(data) < 12 {
+		return MarkLigPos{}, errTruncated
+	}
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return MarkLigPos{}, fmt.Errorf("unsupported mark to ligature attachment format %d", format)
+	}
+	var (
+		out MarkLigPos
+		err error
+	)
+	if out.MarkCoverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+		return out, err
+	}
+	if out.LigatureCoverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[4:]))); err != nil {
+		return out, err
+	}
+	classCount := int(binary.BigEndian.Uint16(data[6:]))
+	if out.Marks, err = parseMarkArray(data, binary.BigEndian.Uint16(data[8:]), classCount); err != nil {
+		return out, err
+	}
+	arrayOffset := int(binary.BigEndian.Uint16(data[10:]))
+	if len(data) < arrayOffset+2 {
+		return out, errTruncated
+	}
+	ligatures := data[arrayOffset:]
+	out.Ligatures = make([][][]*Anchor, binary.BigEndian.Uint16(ligatures))
+	if len(ligatures) < 2+2*len(out.Ligatures) {
+		return out, errTruncated
+	}
+	for i := range out.Ligatures {
+		if out.Ligatures[i], err = parseAnchorMatrix(ligatures, int(binary.BigEndian.Uint16(ligatures[2+2*i:])), classCount); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// Positioning is the adjustment of the position and the advance of a glyph,
+// in font units.
+type Positioning struct {
+	XPlacement, YPlacement float32
+	XAdvance, YAdvance     float32
+}
+
+// ResolveValueRecord returns the adjustments of the value record, including the
+// deltas of its device tables : the variations of variable fonts (see SetVariations),
+// stored in the 'GDEF' table, and, when `ppem` is not zero, the hinting adjustments
+// for this size (expressed in pixels, and converted to font units).
+func (face *Face) ResolveValueRecord(v ValueRecord, ppem uint16) Positioning {
+	store := face.gdefStore()
+	return Positioning{
+		XPlacement: float32(v.XPlacement) + face.deviceDelta(store, v.devices[0], ppem),
+		YPlacement: float32(v.YPlacement) + face.deviceDelta(store, v.devices[1], ppem),
+		XAdvance:   float32(v.XAdvance) + face.deviceDelta(store, v.devices[2], ppem),
+		YAdvance:   float32(v.YAdvance) + face.deviceDelta(store, v.devices[3], ppem),
+	}
+}
+
+// ResolveAnchor returns the position of the anchor of the glyph `gid`, in font units.
+// The anchors of format 2 use the position of their contour point,
+// which is only supported for TrueType outlines, and fall back to their
+// coordinates otherwise. The anchors of format 3 are adjusted like
+// the value records (see ResolveValueRecord).
+func (face *Face) ResolveAnchor(a Anchor, gid GID, ppem uint16) (x, y float32) {
+	x, y = float32(a.X), float32(a.Y)
+	switch a.Format {
+	case 2:
+		if p, ok := face.contourPoint(gid, a.AnchorPoint); ok {
+			x, y = p.x, p.y
+		}
+	case 3:
+		store := face.gdefStore()
+		x += face.deviceDelta(store, a.xDevice, ppem)
+		y += face.deviceDelta(store, a.yDevice, ppem)
+	}
+	return x, y
+}
+
+// OpticalSize returns the parameters of the 'size' feature of the 'GPOS'
+// table, or false if the font has no such feature, or if it is invalid.
+func (face *Face) OpticalSize() (SizeParams, bool) {
+	gpos, ok := face.GPOS()
+	if !ok {
+		return SizeParams{}, false
+	}
+	for _, feature := range gpos.Features {
+		if params, ok := feature.Params.(SizeParams); ok {
+			return params, true
+		}
+	}
+	return SizeParams{}, false
+}
+
+// gpos is the lazily parsed 'GPOS' table.
+type gpos struct {
+	once  sync.Once
+	table *TableGPOS // nil if missing or invalid
+}
+
+// GPOS returns the glyph positioning table, parsed on first use,
+// or false if the font has no 'GPOS' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) GPOS() (*TableGPOS, bool) {
+	face.gpos.once.Do(func() {
+		if data, err := face.GetRawTable(tagGPOS); err == nil {
+			if table, err := parseTableGPOS(data); err != nil {
+				face.warnings.addError(face.tableError(tagGPOS, err))
+			} else {
+				face.gpos.table = &table
+			}
+		}
+	})
+	return face.gpos.table, face.gpos.table != nil
+}
@@ -0,0 +1,471 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableGSUB stores the glyph substitutions of the font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gsub
+type TableGSUB struct {
+	Layout
+	Lookups []GSUBLookup
+}
+
+// GSUB lookup types
+const (
+	GSUBSingle = 1 + iota
+	GSUBMultiple
+	GSUBAlternate
+	GSUBLigature
+	GSUBContext
+	GSUBChainedContext
+	GSUBExtension // only used in the font files, see GSUBLookup.Type
+	GSUBReverseChainedSingle
+)
+
+// GSUBLookup is a substitution lookup, whose subtables are tried in order,
+// until one of them applies.
+type GSUBLookup struct {
+	// Type is the lookup type (GSUBSingle, ...), which is, for extension
+	// lookups, the type of the extension subtables.
+	Type uint16
+	Flag LookupFlag
+	// MarkFilteringSet is the index of the mark glyph set of the 'GDEF' table
+	// used by the lookup, if Flag includes UseMarkFilteringSet.
+	MarkFilteringSet uint16
+	Subtables        []GSUBSubtable
+}
+
+// GSUBSubtable is a subtable of a substitution lookup.
+// It is one of SingleSubst, MultipleSubst, AlternateSubst, LigatureSubst,
+// ContextualSubst, ChainedContextualSubst or ReverseChainedSingleSubst.
+type GSUBSubtable interface {
+	isGSUBSubtable()
+}
+
+func (SingleSubst) isGSUBSubtable()               {}
+func (MultipleSubst) isGSUBSubtable()             {}
+func (AlternateSubst) isGSUBSubtable()            {}
+func (LigatureSubst) isGSUBSubtable()             {}
+func (ContextualSubst) isGSUBSubtable()           {}
+func (ChainedContextualSubst) isGSUBSubtable()    {}
+func (ReverseChainedSingleSubst) isGSUBSubtable() {}
+
+// SingleSubst replaces a glyph by another one, either by adding
+// a constant to the glyph index (format 1), or with the glyph
+// of Substitutes at the coverage index (format 2).
+type SingleSubst struct {
+	Format      uint16
+	Coverage    Coverage
+	Delta       int16 // format 1
+	Substitutes []GID // format 2, indexed by coverage index
+}
+
+// Substitute returns the substitute of the glyph,
+// or false if it is not covered.
+func (s SingleSubst) Substitute(gid GID) (GID, bool) {
+	index, ok := s.Coverage.Index(gid)
+	if !ok {
+		return 0, false
+	}
+	if s.Format == 1 {
+		return GID(uint16(int(gid) + int(s.Delta))), true // modulo 65536
+	}
+	if index >= len(s.Substitutes) {
+		return 0, false
+	}
+	return s.Substitutes[index], true
+}
+
+// MultipleSubst replaces a glyph by a sequence of glyphs.
+type MultipleSubst struct {
+	Coverage  Coverage
+	Sequences [][]GID // indexed by coverage index
+}
+
+// AlternateSubst replaces a glyph by one of its alternates.
+type AlternateSubst struct {
+	Coverage   Coverage
+	Alternates [][]GID // indexed by coverage index
+}
+
+// LigatureSubst replaces sequences of glyphs by ligatures.
+type LigatureSubst struct {
+	Coverage Coverage
+	// LigatureSets are the ligatures starting by the covered glyph, indexed
+	// by coverage index, in order of preference.
+	LigatureSets [][]Ligature
+}
+
+// Ligature replaces a sequence of glyphs by the ligature glyph.
+type Ligature struct {
+	Glyph GID
+	// Components are the glyphs of the sequence, after the first
+	// one, which is given by the coverage.
+	Components []GID
+}
+
+// ContextualSubst applies substitution lookups to glyph sequences.
+type ContextualSubst struct {
+	SequenceContext
+}
+
+// ChainedContextualSubst applies substitution lookups to glyph
+// sequences, according to their surrounding glyphs.
+type ChainedContextualSubst struct {
+	ChainedSequenceContext
+}
+
+// ReverseChainedSingleSubst replaces a glyph by another one, according to
+// the surrounding glyphs. It is applied from the end to the start of the text.
+type ReverseChainedSingleSubst struct {
+	Coverage Coverage
+	// BacktrackCoverages (in reverse order, with the closest glyph first)
+	// and LookaheadCoverages are the coverages of the surrounding glyphs.
+	BacktrackCoverages, LookaheadCoverages []Coverage
+	Substitutes                            []GID // indexed by coverage index
+}
+
+var (
+	tagAalt = MustNewTag("aalt")
+	tagSalt = MustNewTag("salt")
+)
+
+// Alternates returns the alternates of the glyph, without duplicates, given by
+// the lookups of the 'aalt' (Access All Alternates) and 'salt' (Stylistic
+// Alternates) features, in that order, followed by the other alternate
+// substitution lookups of the table. It returns nil if the glyph has no alternate.
+func (t *TableGSUB) Alternates(gid GID) []GID {
+	var (
+		lookups []uint16
+		seen    = map[uint16]bool{}
+	)
+	for _, tag := range [...]Tag{tagAalt, tagSalt} {
+		for _, feature := range t.Features {
+			if feature.Tag != tag {
+				continue
+			}
+			for _, index := range feature.LookupIndices {
+				if !seen[index] {
+					seen[index] = true
+					lookups = append(lookups, index)
+				}
+			}
+		}
+	}
+	for i, lookup := range t.Lookups {
+		if lookup.Type == GSUBAlternate && !seen[uint16(i)] {
+			lookups = append(lookups, uint16(i))
+		}
+	}
+
+	var out []GID
+	add := func(alternate GID) {
+		if alternate == gid {
+			return
+		}
+		for _, g := range out {
+			if g == alternate {
+				return
+			}
+		}
+		out = append(out, alternate)
+	}
+	for _, index := range lookups {
+		if int(index) >= len(t.Lookups) {
+			continue
+		}
+		for _, subtable := range t.Lookups[index].Subtables {
+			switch subtable := subtable.(type) {
+			case SingleSubst:
+				if substitute, ok := subtable.Substitute(gid); ok {
+					add(substitute)
+				}
+			case AlternateSubst:
+				if index, ok := subtable.Coverage.Index(gid); ok && index < len(subtable.Alternates) {
+					for _, alternate := range subtable.Alternates[index] {
+						add(alternate)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// StylisticSetName returns the name of the stylistic set `set`, from 1 to 20
+// (the feature 'ss01' to 'ss20'), given by the parameters of the feature,
+// in the first available of the `languages` (see LocalizedName).
+// It returns false if the font has no such stylistic set, or if it is not named.
+func (face *Face) StylisticSetName(set int, languages ...string) (string, bool) {
+	gsub, ok := face.GSUB()
+	if !ok || set < 1 || set > 20 {
+		return "", false
+	}
+	tag := MustNewTag(fmt.Sprintf("ss%02d", set))
+	for _, feature := range gsub.Features {
+		if params, ok := feature.Params.(StylisticSetParams); ok && feature.Tag == tag {
+			return face.LocalizedName(params.UINameID, languages...)
+		}
+	}
+	return "", false
+}
+
+// CharacterVariant describes a character variant feature,
+// with the names resolved from the 'name' table (empty if missing).
+type CharacterVariant struct {
+	Label, Tooltip, SampleText string
+	// ParamLabels are the labels of the named parameters
+	// of the feature, which may be selected by their index + 1.
+	ParamLabels []string
+	// Characters are the characters for which the feature
+	// provides glyph variants.
+	Characters []rune
+}
+
+// CharacterVariant returns the description of the character variant `variant`,
+// from 1 to 99 (the feature 'cv01' to 'cv99'), given by the parameters of the feature,
+// with its names in the first available of the `languages` (see LocalizedName).
+// It returns false if the font has no such character variant, or if it has no parameters.
+func (face *Face) CharacterVariant(variant int, languages ...string) (CharacterVariant, bool) {
+	gsub, ok := face.GSUB()
+	if !ok || variant < 1 || variant > 99 {
+		return CharacterVariant{}, false
+	}
+	tag := MustNewTag(fmt.Sprintf("cv%02d", variant))
+	for _, feature := range gsub.Features {
+		params, ok := feature.Params.(CharacterVariantParams)
+		if !ok || feature.Tag != tag {
+			continue
+		}
+		name := func(id NameID) string {
+			if id == 0 {
+				return ""
+			}
+			s, _ := face.LocalizedName(id, languages...)
+			return s
+		}
+		out := CharacterVariant{
+			Label:      name(params.LabelNameID),
+			Tooltip:    name(params.TooltipNameID),
+			SampleText: name(params.SampleTextNameID),
+			Characters: params.Characters,
+		}
+		if params.NumNamedParameters != 0 {
+			out.ParamLabels = make([]string, params.NumNamedParameters)
+			for i := range out.ParamLabels {
+				out.ParamLabels[i] = name(params.FirstParamLabelNameID + NameID(i))
+			}
+		}
+		return out, true
+	}
+	return CharacterVariant{}, false
+}
+
+func parseTableGSUB(data []byte) (TableGSUB, error) {
+	layout, err := parseLayout(data)
+	if err != nil {
+		return TableGSUB{}, err
+	}
+	lookups, err := parseLookupList(data, GSUBExtension)
+	if err != nil {
+		return TableGSUB{}, err
+	}
+	out := TableGSUB{Layout: layout, Lookups: make([]GSUBLookup, len(lookups))}
+	for i, lookup := range lookups {
+		out.Lookups[i] = GSUBLookup{
+			Type:             lookup.kind,
+			Flag:             lookup.flag,
+			MarkFilteringSet: lookup.markFilteringSet,
+			Subtables:        make([]GSUBSubtable, len(lookup.subtables)),
+		}
+		for j, subtable := range lookup.subtables {
+			if out.Lookups[i].Subtables[j], err = parseGSUBSubtable(subtable, lookup.kind); err != nil {
+				return TableGSUB{}, fmt.Errorf("lookup %d: %s", i, err)
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseGSUBSubtable(data []byte, kind uint16) (GSUBSubtable, error) {
+	switch kind {
+	case GSUBSingle:
+		return parseSingleSubst(data)
+	case GSUBMultiple, GSUBAlternate:
+		coverage, sequences, err := parseGlyphSequences(data)
+		if kind == GSUBMultiple {
+			return MultipleSubst{Coverage: coverage, Sequences: sequences}, err
+		}
+		return AlternateSubst{Coverage: coverage, Alternates: sequences}, err
+	case GSUBLigature:
+		return parseLigatureSubst(data)
+	case GSUBContext:
+		context, err := parseSequenceContext(data)
+		return ContextualSubst{context}, err
+	case GSUBChainedContext:
+		context, err := parseChainedSequenceContext(data)
+		return ChainedContextualSubst{context}, err
+	case GSUBReverseChainedSingle:
+		return parseReverseChainedSingleSubst(data)
+	default:
+		return nil, fmt.Errorf("unsupported lookup type %d", kind)
+	}
+}
+
+func parseSingleSubst(data []byte) (SingleSubst, error) {
+	if len(data) < 6 {
+		return SingleSubst{}, errors.New("invalid single substitution (EOF)")
+	}
+	out := SingleSubst{Format: binary.BigEndian.Uint16(data)}
+	var err error
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+		return out, err
+	}
+	switch out.Format {
+	case 1:
+		out.Delta = int16(binary.BigEndian.Uint16(data[4:]))
+	case 2:
+		if out.Substitutes, err = parseGlyphs(data, 6, int(binary.BigEndian.Uint16(data[4:]))); err != nil {
+			return out, errors.New("invalid single substitution (EOF)")
+		}
+	default:
+		return out, fmt.Errorf("unsupported single substitution format %d", out.Format)
+	}
+	return out, nil
+}
+
+// parseGlyphSequences parses the subtables of the multiple
+// and alternate substitutions, which share the same structure.
+func parseGlyphSequences(data []byte) (Coverage, [][]GID, error) {
+	errTruncated := errors.New("invalid glyph sequences (EOF)")
+	if len(data) < 6 {
+		return nil, nil, errTruncated
+	}
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return nil, nil, fmt.Errorf("unsupported substitution format %d", format)
+	}
+	coverage, err := parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:])))
+	if err != nil {
+		return nil, nil, err
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+2*count {
+		return nil, nil, errTruncated
+	}
+	out := make([][]GID, count)
+	for i := range out {
+		offset := int(binary.BigEndian.Uint16(data[6+2*i:]))
+		if len(data) < offset+2 {
+			return nil, nil, errTruncated
+		}
+		if out[i], err = parseGlyphs(data[offset:], 2, int(binary.BigEndian.Uint16(data[offset:]))); err != nil {
+			return nil, nil, errTruncated
+		}
+	}
+	return coverage, out, nil
+}
+
+func parseLigatureSubst(data []byte) (LigatureSubst, error) {
+	errTruncated := errors.New("invalid ligature substitution (EOF)")
+	if len(data) < 6 {
+		return LigatureSubst{}, errTruncated
+	}
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return LigatureSubst{}, fmt.Errorf("unsupported ligature substitution format %d", format)
+	}
+	var (
+		out LigatureSubst
+		err error
+	)
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+		return out, err
+	}
+	var ligatures []Ligature
+	sizes, err := parseRuleSets(data, 6, int(binary.BigEndian.Uint16(data[4:])), func(ligature []byte) error {
+		if len(ligature) < 4 {
+			return errTruncated
+		}
+		count := int(binary.BigEndian.Uint16(ligature[2:]))
+		if count == 0 {
+			return errors.New("invalid empty ligature")
+		}
+		components, err := parseGlyphs(ligature, 4, count-1)
+		if err != nil {
+			return errTruncated
+		}
+		ligatures = append(ligatures, Ligature{Glyph: GID(binary.BigEndian.Uint16(ligature)), Components: components})
+		return nil
+	})
+	if err != nil {
+		return out, err
+	}
+	out.LigatureSets = make([][]Ligature, len(sizes))
+	for i, size := range sizes {
+		out.LigatureSets[i], ligatures = ligatures[:size:size], ligatures[size:]
+	}
+	return out, nil
+}
+
+func parseReverseChainedSingleSubst(data []byte) (ReverseChainedSingleSubst, error) {
+	errTruncated := errors.New("invalid reverse chained substitution (EOF)")
+	if len(data) < 6 {
+		return ReverseChainedSingleSubst{}, errTruncated
+	}
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return ReverseChainedSingleSubst{}, fmt.Errorf("unsupported reverse chained substitution format %d", format)
+	}
+	var (
+		out ReverseChainedSingleSubst
+		err error
+	)
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+		return out, err
+	}
+	offset := 4
+	count := int(binary.BigEndian.Uint16(data[offset:]))
+	if out.BacktrackCoverages, err = parseCoverages(data, offset+2, count); err != nil {
+		return out, err
+	}
+	offset += 2 + 2*count
+	if len(data) < offset+2 {
+		return out, errTruncated
+	}
+	count = int(binary.BigEndian.Uint16(data[offset:]))
+	if out.LookaheadCoverages, err = parseCoverages(data, offset+2, count); err != nil {
+		return out, err
+	}
+	offset += 2 + 2*count
+	if len(data) < offset+2 {
+		return out, errTruncated
+	}
+	if out.Substitutes, err = parseGlyphs(data, offset+2, int(binary.BigEndian.Uint16(data[offset:]))); err != nil {
+		return out, errTruncated
+	}
+	return out, nil
+}
+
+// gsub is the lazily parsed 'GSUB' table.
+type gsub struct {
+	once  sync.Once
+	table *TableGSUB // nil if missing or invalid
+}
+
+// GSUB returns the glyph substitution table, parsed on first use,
+// or false if the font has no 'GSUB' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) GSUB() (*TableGSUB, bool) {
+	face.gsub.once.Do(func() {
+		if data, err := face.GetRawTable(tagGSUB); err == nil {
+			if table, err := parseTableGSUB(data); err != nil {
+				face.warnings.addError(face.tableError(tagGSUB, err))
+			} else {
+				face.gsub.table = &table
+			}
+		}
+	})
+	return face.gsub.table, face.gsub.table != nil
+}
@@ -0,0 +1,409 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// tableGvar stores the variations of the TrueType outlines.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gvar
+type tableGvar struct {
+	axisCount    int
+	sharedTuples [][]float32 // peak coordinates, indexed by tuple, then axis
+	offsets      []uint32    // into data, NumGlyphs + 1 glyphs at most
+	data         []byte      // glyph variation data array
+}
+
+// tuple variation flags
+const (
+	sharedPointNumbers  = 0x8000
+	tupleCountMask      = 0x0FFF
+	embeddedPeakTuple   = 0x8000
+	intermediateRegion  = 0x4000
+	privatePointNumbers = 0x2000
+	tupleIndexMask      = 0x0FFF
+)
+
+// packed point numbers and deltas flags
+const (
+	pointsAreWords    = 0x80
+	pointRunCountMask = 0x7F
+	deltasAreZero     = 0x80
+	deltasAreWords    = 0x40
+	deltaRunCountMask = 0x3F
+)
+
+// maxGvarAxes limits the number of axes of the 'gvar' table.
+const maxGvarAxes = 64
+
+var errGvarEOF = errors.New("invalid glyph variation data (EOF)")
+
+func parseTableGvar(data []byte, numGlyphs int) (tableGvar, error) {
+	if len(data) < 20 {
+		return tableGvar{}, errEOF
+	}
+	axisCount := int(binary.BigEndian.Uint16(data[4:]))
+	sharedCount := int(binary.BigEndian.Uint16(data[6:]))
+	sharedOffset := int64(binary.BigEndian.Uint32(data[8:]))
+	glyphCount := int(binary.BigEndian.Uint16(data[12:]))
+	longOffsets := binary.BigEndian.Uint16(data[14:])&1 != 0
+	dataOffset := int64(binary.BigEndian.Uint32(data[16:]))
+	if axisCount > maxGvarAxes {
+		return tableGvar{}, errors.New("invalid axis count")
+	}
+	if int64(len(data)) < sharedOffset+int64(2*axisCount*sharedCount) || int64(len(data)) < dataOffset {
+		return tableGvar{}, errEOF
+	}
+
+	out := tableGvar{axisCount: axisCount, sharedTuples: make([][]float32, sharedCount), data: data[dataOffset:]}
+	for i := range out.sharedTuples {
+		tuple := make([]float32, axisCount)
+		for j := range tuple {
+			tuple[j] = f2dot14(binary.BigEndian.Uint16(data[sharedOffset+int64(2*(i*axisCount+j)):]))
+		}
+		out.sharedTuples[i] = tuple
+	}
+
+	if glyphCount > numGlyphs {
+		glyphCount = numGlyphs
+	}
+	offsets := data[20:]
+	out.offsets = make([]uint32, glyphCount+1)
+	if longOffsets {
+		if len(offsets) < 4*len(out.offsets) {
+			return tableGvar{}, errors.New("invalid glyph variation data offsets (EOF)")
+		}
+		for i := range out.offsets {
+			out.offsets[i] = binary.BigEndian.Uint32(offsets[4*i:])
+		}
+	} else {
+		if len(offsets) < 2*len(out.offsets) {
+			return tableGvar{}, errors.New("invalid glyph variation data offsets (EOF)")
+		}
+		for i := range out.offsets {
+			out.offsets[i] = 2 * uint32(binary.BigEndian.Uint16(offsets[2*i:]))
+		}
+	}
+	return out, nil
+}
+
+// parsePackedPoints returns the point numbers, or nil if all the points are used.
+func parsePackedPoints(data []byte) ([]uint16, []byte, error) {
+	if len(data) < 1 {
+		return nil, nil, errGvarEOF
+	}
+	count := int(data[0])
+	data = data[1:]
+	if count == 0 {
+		return nil, data, nil
+	}
+	if count&0x80 != 0 {
+		if len(data) < 1 {
+			return nil, nil, errGvarEOF
+		}
+		count = (count&0x7F)<<8 | int(data[0])
+		data = data[1:]
+	}
+	out := make([]uint16, 0, count)
+	var point uint16
+	for len(out) < count {
+		if len(data) < 1 {
+			return nil, nil, errGvarEOF
+		}
+		control := data[0]
+		run := int(control&pointRunCountMask) + 1
+		data = data[1:]
+		size := 1
+		if control&pointsAreWords != 0 {
+			size = 2
+		}
+		if len(data) < size*run {
+			return nil, nil, errGvarEOF
+		}
+		for i := 0; i < run && len(out) < count; i++ {
+			if size == 2 {
+				point += binary.BigEndian.Uint16(data[2*i:])
+			} else {
+				point += uint16(data[i])
+			}
+			out = append(out, point)
+		}
+		data = data[size*run:]
+	}
+	return out, data, nil
+}
+
+// parsePackedDeltas returns `count` deltas.
+func parsePackedDeltas(data []byte, count int) ([]int16, []byte, error) {
+	out := make([]int16, 0, count)
+	for len(out) < count {
+		if len(data) < 1 {
+			return nil, nil, errGvarEOF
+		}
+		control := data[0]
+		run := int(control&deltaRunCountMask) + 1
+		data = data[1:]
+		switch {
+		case control&deltasAreZero != 0:
+			for i := 0; i < run && len(out) < count; i++ {
+				out = append(out, 0)
+			}
+		case control&deltasAreWords != 0:
+			if len(data) < 2*run {
+				return nil, nil, errGvarEOF
+			}
+			for i := 0; i < run && len(out) < count; i++ {
+				out = append(out, int16(binary.BigEndian.Uint16(data[2*i:])))
+			}
+			data = data[2*run:]
+		default:
+			if len(data) < run {
+				return nil, nil, errGvarEOF
+			}
+			for i := 0; i < run && len(out) < count; i++ {
+				out = append(out, int16(int8(data[i])))
+			}
+			data = data[run:]
+		}
+	}
+	return out, data, nil
+}
+
+// applyDeltas adds the variations of the glyph for the normalized coordinates
+// `coords` to `points`, which are the points of the glyph (one per component for
+// composite glyphs) followed by its phantom points.
+// The deltas of the points not referenced by a tuple variation are inferred from
+// the contours delimited by `ends` (which is empty for composite glyphs).
+func (t *tableGvar) applyDeltas(gid GID, coords []float32, points []contourPoint, ends []int) error {
+	if int(gid)+1 >= len(t.offsets) {
+		return nil
+	}
+	start, end := t.offsets[gid], t.offsets[gid+1]
+	if start >= end {
+		return nil // no variations
+	}
+	if int64(end) > int64(len(t.data)) {
+		return errGvarEOF
+	}
+
+	original := append([]contourPoint(nil), points...)
+	var tupleDeltas []contourPoint // deltas of one tuple, for inferred deltas
+	return applyTupleVariations(t.data[start:end], 0, t.axisCount, t.sharedTuples, coords, func(scalar float32, tuplePoints []uint16, tupleData []byte) error {
+		count := len(tuplePoints)
+		if tuplePoints == nil {
+			count = len(points)
+		}
+		xs, tupleData, err := parsePackedDeltas(tupleData, count)
+		if err != nil {
+			return err
+		}
+		ys, _, err := parsePackedDeltas(tupleData, count)
+		if err != nil {
+			return err
+		}
+
+		if tuplePoints == nil { // all points
+			for j := range points {
+				points[j].x += scalar * float32(xs[j])
+				points[j].y += scalar * float32(ys[j])
+			}
+			return nil
+		}
+		if cap(tupleDeltas) < len(points) {
+			tupleDeltas = make([]contourPoint, len(points))
+		}
+		tupleDeltas = tupleDeltas[:len(points)]
+		for j := range tupleDeltas {
+			tupleDeltas[j] = contourPoint{}
+		}
+		for j, point := range tuplePoints {
+			if int(point) < len(points) {
+				// onCurve marks the points with explicit deltas
+				tupleDeltas[point] = contourPoint{x: float32(xs[j]), y: float32(ys[j]), onCurve: true}
+			}
+		}
+		inferDeltas(original, tupleDeltas, ends)
+		for j, d := range tupleDeltas {
+			points[j].x += scalar * d.x
+			points[j].y += scalar * d.y
+		}
+		return nil
+	})
+}
+
+// applyTupleVariations walks the tuple variation store `data`, used by
+// the glyph variation data of the 'gvar' table and by the 'cvar' table,
+// whose header (the tuple count and the offset from the start of `data`
+// to the serialized data) starts at `header`.
+// `apply` is called, in order, for each tuple variation whose scalar is not zero
+// for the normalized coordinates `coords`, with its point numbers (nil for all
+// the points) and its serialized deltas.
+func applyTupleVariations(data []byte, header, axisCount int, sharedTuples [][]float32, coords []float32,
+	apply func(scalar float32, points []uint16, deltas []byte) error,
+) error {
+	if len(data) < header+4 {
+		return errGvarEOF
+	}
+	tupleCount := int(binary.BigEndian.Uint16(data[header:]) & tupleCountMask)
+	hasSharedPoints := binary.BigEndian.Uint16(data[header:])&sharedPointNumbers != 0
+	serializedOffset := int(binary.BigEndian.Uint16(data[header+2:]))
+	if len(data) < serializedOffset {
+		return errGvarEOF
+	}
+	headers, serialized := data[header+4:], data[serializedOffset:]
+
+	var (
+		sharedPoints []uint16
+		err          error
+	)
+	if hasSharedPoints {
+		if sharedPoints, serialized, err = parsePackedPoints(serialized); err != nil {
+			return err
+		}
+	}
+
+	region := make([]regionAxis, axisCount)
+	for i := 0; i < tupleCount; i++ {
+		if len(headers) < 4 {
+			return errGvarEOF
+		}
+		size := int(binary.BigEndian.Uint16(headers))
+		index := binary.BigEndian.Uint16(headers[2:])
+		headers = headers[4:]
+
+		// region of the tuple
+		var peak []float32
+		if index&embeddedPeakTuple != 0 {
+			if len(headers) < 2*axisCount {
+				return errGvarEOF
+			}
+			peak = make([]float32, axisCount)
+			for j := range peak {
+				peak[j] = f2dot14(binary.BigEndian.Uint16(headers[2*j:]))
+			}
+			headers = headers[2*axisCount:]
+		} else {
+			if int(index&tupleIndexMask) >= len(sharedTuples) {
+				return errors.New("invalid shared tuple index")
+			}
+			peak = sharedTuples[index&tupleIndexMask]
+		}
+		if index&intermediateRegion != 0 {
+			if len(headers) < 4*axisCount {
+				return errGvarEOF
+			}
+			for j := range region {
+				region[j] = regionAxis{
+					start: f2dot14(binary.BigEndian.Uint16(headers[2*j:])),
+					peak:  peak[j],
+					end:   f2dot14(binary.BigEndian.Uint16(headers[2*(axisCount+j):])),
+				}
+			}
+			headers = headers[4*axisCount:]
+		} else {
+			for j, p := range peak {
+				region[j] = regionAxis{start: min32(p, 0), peak: p, end: max32(p, 0)}
+			}
+		}
+
+		if len(serialized) < size {
+			return errGvarEOF
+		}
+		tupleData := serialized[:size]
+		serialized = serialized[size:]
+		scalar := regionScalar(region, coords)
+		if scalar == 0 {
+			continue
+		}
+
+		tuplePoints := sharedPoints
+		if index&privatePointNumbers != 0 {
+			if tuplePoints, tupleData, err = parsePackedPoints(tupleData); err != nil {
+				return err
+			}
+		}
+		if err = apply(scalar, tuplePoints, tupleData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inferDeltas interpolates the deltas of the points without explicit
+// deltas (whose onCurve field is false) in each contour, from the
+// explicit deltas of the nearest points of the contour.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gvar#inferred-deltas-for-un-referenced-point-numbers
+func inferDeltas(points, deltas []contourPoint, ends []int) {
+	start := 0
+	for _, end := range ends {
+		if end >= len(points) || end < start {
+			break
+		}
+		inferContourDeltas(points[start:end+1], deltas[start:end+1])
+		start = end + 1
+	}
+}
+
+func inferContourDeltas(points, deltas []contourPoint) {
+	first := -1
+	for i, d := range deltas {
+		if d.onCurve {
+			first = i
+			break
+		}
+	}
+	if first == -1 { // no explicit deltas : nothing to infer
+		return
+	}
+	n := len(points)
+	for i := first; i < first+n; {
+		// find the next explicit delta, after i
+		next := i + 1
+		for !deltas[next%n].onCurve {
+			next++
+		}
+		prev := i % n
+		for j := i + 1; j < next; j++ {
+			p := j % n
+			deltas[p].x = inferDelta(points[p].x, points[prev].x, points[next%n].x, deltas[prev].x, deltas[next%n].x)
+			deltas[p].y = inferDelta(points[p].y, points[prev].y, points[next%n].y, deltas[prev].y, deltas[next%n].y)
+		}
+		i = next
+	}
+}
+
+// inferDelta returns the delta of the coordinate `c`, given the coordinates
+// (`c1`, `c2`) and the deltas (`d1`, `d2`) of the two reference points.
+func inferDelta(c, c1, c2, d1, d2 float32) float32 {
+	if c1 == c2 {
+		if d1 == d2 {
+			return d1
+		}
+		return 0
+	}
+	if c1 > c2 {
+		c1, c2, d1, d2 = c2, c1, d2, d1
+	}
+	switch {
+	case c <= c1:
+		return d1
+	case c >= c2:
+		return d2
+	default:
+		return d1 + (c-c1)*(d2-d1)/(c2-c1)
+	}
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,88 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// TableHead contains critical information about the rest of the font.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6head.html
+// https://docs.microsoft.com/en-us/typography/opentype/spec/head
+type TableHead struct {
+	// FontRevision is the version of the font, set by the manufacturer.
+	FontRevision float32
+	Flags        HeadFlags
+	UnitsPerEm   uint16
+	// Created and Modified are the creation and modification dates of the font,
+	// in UTC.
+	Created, Modified time.Time
+
+	XMin int16
+	YMin int16
+	XMax int16
+	YMax int16
+
+	MacStyle MacStyle
+	// LowestRecPPEM is the smallest readable size, in pixels.
+	LowestRecPPEM uint16
+	// IndexToLocFormat is 0 for short offsets in the 'loca' table, 1 for long ones.
+	IndexToLocFormat int16
+}
+
+// HeadFlags are the flags of the 'head' table.
+type HeadFlags uint16
+
+const (
+	HeadBaselineAtZero           HeadFlags = 1 << 0  // the baseline is at y=0
+	HeadLeftSidebearingAtZero    HeadFlags = 1 << 1  // the left side bearing point is at x=0
+	HeadInstructionsDependOnSize HeadFlags = 1 << 2  // the instructions may depend on the point size
+	HeadForceIntegerPPEM         HeadFlags = 1 << 3  // the ppem must be rounded to an integer by the scaler
+	HeadInstructionsAlterAdvance HeadFlags = 1 << 4  // the instructions may alter the advance widths
+	HeadLossless                 HeadFlags = 1 << 11 // the font data have been compressed and decompressed losslessly
+	HeadConverted                HeadFlags = 1 << 12 // the font is converted, producing compatible metrics
+	HeadOptimizedForClearType    HeadFlags = 1 << 13 // the font is optimized for ClearType
+	HeadLastResort               HeadFlags = 1 << 14 // the glyphs are generic symbols for code point ranges
+)
+
+// MacStyle are the style flags of the 'head' table.
+type MacStyle uint16
+
+const (
+	MacStyleBold MacStyle = 1 << iota
+	MacStyleItalic
+	MacStyleUnderline
+	MacStyleOutline
+	MacStyleShadow
+	MacStyleCondensed
+	MacStyleExtended
+)
+
+// fontEpoch is the origin of the dates of the 'head' table.
+var fontEpoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// longDateTime converts a number of seconds since 1904 to a time.
+func longDateTime(seconds int64) time.Time {
+	// avoid the overflow of time.Duration (about 292 years)
+	days := seconds / (24 * 3600)
+	return fontEpoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds-days*24*3600) * time.Second)
+}
+
+func parseTableHead(data []byte) (out TableHead, err error) {
+	const headerSize = 54
+	if len(data) < headerSize {
+		return TableHead{}, errEOF
+	}
+	out.FontRevision = fixed1616(binary.BigEndian.Uint32(data[4:]))
+	out.Flags = HeadFlags(binary.BigEndian.Uint16(data[16:]))
+	out.UnitsPerEm = binary.BigEndian.Uint16(data[18:])
+	out.Created = longDateTime(int64(binary.BigEndian.Uint64(data[20:])))
+	out.Modified = longDateTime(int64(binary.BigEndian.Uint64(data[28:])))
+	out.XMin = int16(binary.BigEndian.Uint16(data[36:]))
+	out.YMin = int16(binary.BigEndian.Uint16(data[38:]))
+	out.XMax = int16(binary.BigEndian.Uint16(data[40:]))
+	out.YMax = int16(binary.BigEndian.Uint16(data[42:]))
+	out.MacStyle = MacStyle(binary.BigEndian.Uint16(data[44:]))
+	out.LowestRecPPEM = binary.BigEndian.Uint16(data[46:])
+	out.IndexToLocFormat = int16(binary.BigEndian.Uint16(data[50:]))
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package opentype
+
+import "encoding/binary"
+
+// tableHmtx stores the horizontal (or vertical) metrics of the glyphs.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/hmtx
+type tableHmtx struct {
+	metrics []longMetric
+	// side bearings of the glyphs after len(metrics), whose
+	// advance is the one of the last metric
+	sideBearings []int16
+}
+
+type longMetric struct {
+	advance     uint16
+	sideBearing int16
+}
+
+// tableHhea is the header of the 'hmtx' (or 'vmtx') table,
+// provided by the 'hhea' (or 'vhea') table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/hhea
+type tableHhea struct {
+	ascender, descender, lineGap int16
+
+	caretSlopeRise, caretSlopeRun, caretOffset int16
+
+	numMetrics int
+}
+
+func parseTableHhea(data []byte) (tableHhea, error) {
+	if len(data) < 36 {
+		return tableHhea{}, errEOF
+	}
+	return tableHhea{
+		ascender:  int16(binary.BigEndian.Uint16(data[4:])),
+		descender: int16(binary.BigEndian.Uint16(data[6:])),
+		lineGap:   int16(binary.BigEndian.Uint16(data[8:])),
+
+		caretSlopeRise: int16(binary.BigEndian.Uint16(data[18:])),
+		caretSlopeRun:  int16(binary.BigEndian.Uint16(data[20:])),
+		caretOffset:    int16(binary.BigEndian.Uint16(data[22:])),
+
+		numMetrics: int(binary.BigEndian.Uint16(data[34:])),
+	}, nil
+}
+
+// parseTableHmtx parses the 'hmtx' or 'vmtx' table. Truncated tables
+// are accepted, the missing glyphs having no metrics.
+func parseTableHmtx(data []byte, numMetrics, numGlyphs int) tableHmtx {
+	if numMetrics > numGlyphs {
+		numMetrics = numGlyphs
+	}
+	if len(data) < 4*numMetrics {
+		numMetrics = len(data) / 4
+	}
+	out := tableHmtx{metrics: make([]longMetric, numMetrics)}
+	for i := range out.metrics {
+		out.metrics[i] = longMetric{
+			advance:     binary.BigEndian.Uint16(data[4*i:]),
+			sideBearing: int16(binary.BigEndian.Uint16(data[4*i+2:])),
+		}
+	}
+	data = data[4*numMetrics:]
+	numBearings := numGlyphs - numMetrics
+	if len(data) < 2*numBearings {
+		numBearings = len(data) / 2
+	}
+	out.sideBearings = make([]int16, numBearings)
+	for i := range out.sideBearings {
+		out.sideBearings[i] = int16(binary.BigEndian.Uint16(data[2*i:]))
+	}
+	return out
+}
+
+// advance returns the advance of the glyph, which is the one of
+// the last metric for the glyphs after the end of the metrics.
+func (t tableHmtx) advance(gid GID) uint16 {
+	if int(gid) < len(t.metrics) {
+		return t.metrics[gid].advance
+	}
+	return t.metrics[len(t.metrics)-1].advance
+}
+
+// sideBearing returns the left (or top) side bearing of the glyph.
+func (t tableHmtx) sideBearing(gid GID) (int16, bool) {
+	if int(gid) < len(t.metrics) {
+		return t.metrics[gid].sideBearing, true
+	}
+	index := int(gid) - len(t.metrics)
+	if index < len(t.sideBearings) {
+		return t.sideBearings[index], true
+	}
+	return 0, false
+}
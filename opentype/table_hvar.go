@@ -0,0 +1,78 @@
+package opentype
+
+import "encoding/binary"
+
+// tableHVAR stores the variations of the horizontal (or vertical) metrics,
+// provided by the 'HVAR' (or 'VVAR') table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/hvar
+// https://docs.microsoft.com/en-us/typography/opentype/spec/vvar
+type tableHVAR struct {
+	store    itemVariationStore
+	advances deltaSetIndexMap // empty for the implicit mapping
+
+	// the left (or top) side bearings and the vertical
+	// origins (VVAR only) have no implicit mapping
+	sideBearings, origins       deltaSetIndexMap
+	hasSideBearings, hasOrigins bool
+}
+
+func parseTableHVAR(data []byte, vertical bool) (tableHVAR, error) {
+	if len(data) < 20 || (vertical && len(data) < 24) {
+		return tableHVAR{}, errEOF
+	}
+	var (
+		out tableHVAR
+		err error
+	)
+	out.store, err = parseItemVariationStore(data, binary.BigEndian.Uint32(data[4:]))
+	if err != nil {
+		return out, err
+	}
+	if offset := binary.BigEndian.Uint32(data[8:]); offset != 0 {
+		if out.advances, err = parseDeltaSetIndexMap(data, offset); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint32(data[12:]); offset != 0 {
+		if out.sideBearings, err = parseDeltaSetIndexMap(data, offset); err != nil {
+			return out, err
+		}
+		out.hasSideBearings = true
+	}
+	if !vertical {
+		return out, nil
+	}
+	if offset := binary.BigEndian.Uint32(data[20:]); offset != 0 {
+		if out.origins, err = parseDeltaSetIndexMap(data, offset); err != nil {
+			return out, err
+		}
+		out.hasOrigins = true
+	}
+	return out, nil
+}
+
+// advanceDelta returns the variation of the advance of the glyph.
+func (t *tableHVAR) advanceDelta(gid GID, coords []float32) float32 {
+	outer, inner := t.advances.index(uint32(gid))
+	return t.store.delta(outer, inner, coords)
+}
+
+// sideBearingDelta returns the variation of the left (or top)
+// side bearing of the glyph, or 0 if the table has no side bearing mapping.
+func (t *tableHVAR) sideBearingDelta(gid GID, coords []float32) float32 {
+	if !t.hasSideBearings {
+		return 0
+	}
+	outer, inner := t.sideBearings.index(uint32(gid))
+	return t.store.delta(outer, inner, coords)
+}
+
+// originDelta returns the variation of the vertical origin
+// of the glyph, or 0 if the table has no origin mapping.
+func (t *tableHVAR) originDelta(gid GID, coords []float32) float32 {
+	if !t.hasOrigins {
+		return 0
+	}
+	outer, inner := t.origins.index(uint32(gid))
+	return t.store.delta(outer, inner, coords)
+}
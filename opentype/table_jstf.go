@@ -0,0 +1,255 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableJSTF stores the justification data of the font, which
+// modifies the lookups of the 'GSUB' and 'GPOS' tables to shrink
+// or extend the lines of text.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/jstf
+type TableJSTF struct {
+	// Scripts are sorted by tag.
+	Scripts []JstfScript
+}
+
+// JstfScript is the justification data of a script.
+type JstfScript struct {
+	Tag Tag
+	// ExtenderGlyphs are the glyphs, such as the Arabic kashida,
+	// which may be inserted to extend the lines of text.
+	ExtenderGlyphs []GID
+	// DefaultLanguage is used for the languages not listed
+	// in Languages. It is nil if missing.
+	DefaultLanguage *JstfLangSys
+	// Languages are sorted by tag.
+	Languages []JstfLangSys
+}
+
+// JstfLangSys is the justification data of a language system.
+type JstfLangSys struct {
+	Tag Tag // 0 for the default language system of the script
+	// Priorities are the justification suggestions, in order of
+	// preference : the modifications of a priority level are applied
+	// until the line is justified, before trying the next level.
+	Priorities []JstfPriority
+}
+
+// JstfPriority is a level of justification suggestions.
+type JstfPriority struct {
+	Shrinkage, Extension JstfModifications
+}
+
+// JstfModifications are the lookups enabled and disabled to shrink
+// or extend a line of text.
+type JstfModifications struct {
+	// EnableGSUB, DisableGSUB, EnableGPOS and DisableGPOS are the
+	// indices of the lookups of the 'GSUB' and 'GPOS' tables to enable
+	// or disable.
+	EnableGSUB, DisableGSUB []uint16
+	EnableGPOS, DisableGPOS []uint16
+	// MaxLookups are positioning lookups, defined by the 'JSTF' table, giving
+	// the maximal adjustments allowed at this priority level.
+	MaxLookups []GPOSLookup
+}
+
+func parseTableJSTF(data []byte) (TableJSTF, error) {
+	if len(data) < 6 {
+		return TableJSTF{}, errEOF
+	}
+	if major := binary.BigEndian.Uint16(data); major != 1 {
+		return TableJSTF{}, fmt.Errorf("unsupported version %d", major)
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+6*count {
+		return TableJSTF{}, errEOF
+	}
+	out := TableJSTF{Scripts: make([]JstfScript, count)}
+	for i := range out.Scripts {
+		record := data[6+6*i:]
+		var err error
+		if out.Scripts[i], err = parseJstfScript(data, uint32(binary.BigEndian.Uint16(record[4:]))); err != nil {
+			return out, err
+		}
+		out.Scripts[i].Tag = Tag(binary.BigEndian.Uint32(record))
+	}
+	return out, nil
+}
+
+func parseJstfScript(data []byte, offset uint32) (JstfScript, error) {
+	if int64(offset)+6 > int64(len(data)) {
+		return JstfScript{}, errors.New("invalid justification script table (EOF)")
+	}
+	data = data[offset:]
+	var (
+		out JstfScript
+		err error
+	)
+	if extenderOffset := int(binary.BigEndian.Uint16(data)); extenderOffset != 0 {
+		if len(data) < extenderOffset+2 {
+			return out, errors.New("invalid extender glyph table (EOF)")
+		}
+		if out.ExtenderGlyphs, err = parseGlyphs(data, extenderOffset+2, int(binary.BigEndian.Uint16(data[extenderOffset:]))); err != nil {
+			return out, errors.New("invalid extender glyph table (EOF)")
+		}
+	}
+	if langSysOffset := binary.BigEndian.Uint16(data[2:]); langSysOffset != 0 {
+		langSys, err := parseJstfLangSys(data, uint32(langSysOffset))
+		if err != nil {
+			return out, err
+		}
+		out.DefaultLanguage = &langSys
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+6*count {
+		return out, errors.New("invalid justification script table (EOF)")
+	}
+	out.Languages = make([]JstfLangSys, count)
+	for i := range out.Languages {
+		record := data[6+6*i:]
+		if out.Languages[i], err = parseJstfLangSys(data, uint32(binary.BigEndian.Uint16(record[4:]))); err != nil {
+			return out, err
+		}
+		out.Languages[i].Tag = Tag(binary.BigEndian.Uint32(record))
+	}
+	return out, nil
+}
+
+func parseJstfLangSys(data []byte, offset uint32) (JstfLangSys, error) {
+	if int64(offset)+2 > int64(len(data)) {
+		return JstfLangSys{}, errors.New("invalid justification language system table (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+2*count {
+		return JstfLangSys{}, errors.New("invalid justification language system table (EOF)")
+	}
+	out := JstfLangSys{Priorities: make([]JstfPriority, count)}
+	for i := range out.Priorities {
+		var err error
+		if out.Priorities[i], err = parseJstfPriority(data, uint32(binary.BigEndian.Uint16(data[2+2*i:]))); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseJstfPriority(data []byte, offset uint32) (JstfPriority, error) {
+	if int64(offset)+20 > int64(len(data)) {
+		return JstfPriority{}, errors.New("invalid justification priority table (EOF)")
+	}
+	data = data[offset:]
+	var (
+		out JstfPriority
+		err error
+	)
+	for i, mods := range [2]*JstfModifications{&out.Shrinkage, &out.Extension} {
+		offsets := data[10*i:]
+		for j, list := range [4]*[]uint16{&mods.EnableGSUB, &mods.DisableGSUB, &mods.EnableGPOS, &mods.DisableGPOS} {
+			if *list, err = parseJstfModList(data, binary.BigEndian.Uint16(offsets[2*j:])); err != nil {
+				return out, err
+			}
+		}
+		if mods.MaxLookups, err = parseJstfMax(data, binary.BigEndian.Uint16(offsets[8:])); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// parseJstfModList returns the lookup indices of the list,
+// or nil for null offsets.
+func parseJstfModList(data []byte, offset uint16) ([]uint16, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if len(data) < int(offset)+2 {
+		return nil, errors.New("invalid justification lookup list (EOF)")
+	}
+	out, err := parseUint16s(data, int(offset)+2, int(binary.BigEndian.Uint16(data[offset:])))
+	if err != nil {
+		return nil, errors.New("invalid justification lookup list (EOF)")
+	}
+	return out, nil
+}
+
+// parseJstfMax returns the lookups of the table, or nil for null offsets.
+func parseJstfMax(data []byte, offset uint16) ([]GPOSLookup, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if len(data) < int(offset)+2 {
+		return nil, errors.New("invalid justification maximum table (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+2*count {
+		return nil, errors.New("invalid justification maximum table (EOF)")
+	}
+	out := make([]GPOSLookup, count)
+	for i := range out {
+		lookup, err := parseLookup(data, uint32(binary.BigEndian.Uint16(data[2+2*i:])), GPOSExtension)
+		if err != nil {
+			return nil, err
+		}
+		if out[i], err = parseGPOSLookup(lookup); err != nil {
+			return nil, fmt.Errorf("justification lookup %d: %s", i, err)
+		}
+	}
+	return out, nil
+}
+
+// FindScript returns the justification data of the script `tag`,
+// or false if it is not found.
+func (t *TableJSTF) FindScript(tag Tag) (*JstfScript, bool) {
+	for i := range t.Scripts {
+		if t.Scripts[i].Tag == tag {
+			return &t.Scripts[i], true
+		}
+	}
+	return nil, false
+}
+
+// FindLanguage returns the language system `language` of the script `script`,
+// or its default language system if `language` is DefaultLanguage.
+// It returns false if the script or the language system are not found.
+func (t *TableJSTF) FindLanguage(script, language Tag) (*JstfLangSys, bool) {
+	s, ok := t.FindScript(script)
+	if !ok {
+		return nil, false
+	}
+	if language == DefaultLanguage {
+		return s.DefaultLanguage, s.DefaultLanguage != nil
+	}
+	for i := range s.Languages {
+		if s.Languages[i].Tag == language {
+			return &s.Languages[i], true
+		}
+	}
+	return nil, false
+}
+
+// jstf is the lazily parsed 'JSTF' table.
+type jstf struct {
+	once  sync.Once
+	table *TableJSTF // nil if missing or invalid
+}
+
+// JSTF returns the justification table, parsed on first use,
+// or false if the font has no 'JSTF' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) JSTF() (*TableJSTF, bool) {
+	face.jstf.once.Do(func() {
+		if data, err := face.GetRawTable(tagJSTF); err == nil {
+			if table, err := parseTableJSTF(data); err != nil {
+				face.warnings.addError(face.tableError(tagJSTF, err))
+			} else {
+				face.jstf.table = &table
+			}
+		}
+	})
+	return face.jstf.table, face.jstf.table != nil
+}
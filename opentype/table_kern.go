@@ -0,0 +1,438 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TableKern stores the kerning of the font, either in the
+// OpenType or in the Apple variant of the 'kern' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/kern
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6kern.html
+type TableKern struct {
+	Subtables []KernSubtable
+}
+
+// KernSubtable is a subtable of the 'kern' table.
+type KernSubtable struct {
+	// Vertical is true if the values adjust the vertical layout.
+	Vertical bool
+	// CrossStream is true if the values adjust the glyphs perpendicularly
+	// to the layout direction, such as vertically in horizontal text.
+	CrossStream bool
+	// Minimum is true if the values are minimum kerning values
+	// (OpenType variant only).
+	Minimum bool
+	// Override is true if the values replace the ones accumulated by the
+	// previous subtables, instead of being added to them (OpenType variant only).
+	Override bool
+	// Variation is true if the values are variation values, used with
+	// the 'fvar' table (Apple variant only).
+	Variation bool
+	// TupleIndex is the index of the variation tuple of the values,
+	// for variation subtables (Apple variant only).
+	TupleIndex uint16
+
+	Data KernData
+}
+
+// KernData is the content of a kerning subtable. It is one of
+// KernPairs (format 0), KernStateMachine (format 1), KernClassMatrix (format 2)
+// or KernIndexArray (format 3).
+type KernData interface {
+	isKernData()
+}
+
+func (KernPairs) isKernData()        {}
+func (KernStateMachine) isKernData() {}
+func (KernClassMatrix) isKernData()  {}
+func (KernIndexArray) isKernData()   {}
+
+// PairKerning is implemented by the kerning subtables
+// storing the values of glyph pairs.
+type PairKerning interface {
+	// Kern returns the kerning value between the glyphs, in font units,
+	// or false if the pair is not kerned.
+	Kern(left, right GID) (int16, bool)
+}
+
+// KernPair is the kerning value of a glyph pair.
+type KernPair struct {
+	Left, Right GID
+	Value       int16
+}
+
+// KernPairs stores the kerning values of glyph pairs,
+// sorted by Left, then Right.
+type KernPairs []KernPair
+
+// Kern implements PairKerning.
+func (kp KernPairs) Kern(left, right GID) (int16, bool) {
+	i := sort.Search(len(kp), func(i int) bool {
+		return kp[i].Left > left || (kp[i].Left == left && kp[i].Right >= right)
+	})
+	if i < len(kp) && kp[i].Left == left && kp[i].Right == right {
+		return kp[i].Value, true
+	}
+	return 0, false
+}
+
+// KernStateMachine stores contextual kerning values, applied by a state machine
+// (Apple variant only). The flags of the entries are a combination of
+// KernPush, KernDontAdvance and of the offset of the values (KernValueOffset).
+type KernStateMachine struct {
+	Machine AATStateTable
+
+	data []byte // the state table, where the values are stored
+}
+
+// The flags of the entries of a KernStateMachine.
+const (
+	// KernPush pushes the current glyph on the kerning stack.
+	KernPush = 0x8000
+	// KernDontAdvance processes the current glyph again, in the new state.
+	KernDontAdvance = 0x4000
+	// KernValueOffset is the mask of the offset of the values applied to
+	// the glyphs of the stack (see KernStateMachine.Values), or 0 for no values.
+	KernValueOffset = 0x3FFF
+)
+
+// KernCrossStreamReset is the value of the cross-stream state machines
+// (KernStateMachine and KerxStateMachine) which resets the cross-stream
+// kerning of the glyph to 0, instead of being added to it.
+const KernCrossStreamReset = -0x8000
+
+// Values returns the list of kerning values starting at `offset`
+// (see KernValueOffset). The values are applied to the glyphs popped
+// from the kerning stack, and the end of the list is marked by an odd value,
+// whose lowest bit must be cleared before use.
+func (ksm *KernStateMachine) Values(offset uint16) []int16 {
+	var out []int16
+	for i := int(offset); i+2 <= len(ksm.data); i += 2 {
+		v := int16(binary.BigEndian.Uint16(ksm.data[i:]))
+		out = append(out, v)
+		if v&1 != 0 {
+			break
+		}
+	}
+	return out
+}
+
+// KernClassMatrix stores the kerning values in a matrix, indexed
+// by the classes of the left and right glyphs.
+type KernClassMatrix struct {
+	// left and right map the glyphs to the byte offsets, from the start
+	// of data, of the row and of the column of the matrix
+	left, right ClassDef
+	data        []byte // the kerning subtable
+	arrayOffset int    // start of the matrix in data
+}
+
+// Kern implements PairKerning.
+func (kc KernClassMatrix) Kern(left, right GID) (int16, bool) {
+	l, ok1 := kc.left.Class(left)
+	r, ok2 := kc.right.Class(right)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	offset := int(l) + int(r)
+	if offset < kc.arrayOffset || len(kc.data) < offset+2 {
+		return 0, false
+	}
+	return int16(binary.BigEndian.Uint16(kc.data[offset:])), true
+}
+
+// KernIndexArray stores the kerning values in a matrix of indices,
+// indexed by the classes of the left and right glyphs.
+type KernIndexArray struct {
+	Values []int16
+	// LeftClasses and RightClasses are the classes
+	// of the glyphs, indexed by glyph.
+	LeftClasses, RightClasses []uint8
+	// Indices are the indices in Values, for each left class
+	// (of size RightClassCount), then each right class.
+	Indices         []uint8
+	RightClassCount int
+}
+
+// Kern implements PairKerning.
+func (ki KernIndexArray) Kern(left, right GID) (int16, bool) {
+	if int(left) >= len(ki.LeftClasses) || int(right) >= len(ki.RightClasses) {
+		return 0, false
+	}
+	i := int(ki.LeftClasses[left])*ki.RightClassCount + int(ki.RightClasses[right])
+	if i >= len(ki.Indices) || int(ki.Indices[i]) >= len(ki.Values) {
+		return 0, false
+	}
+	return ki.Values[ki.Indices[i]], true
+}
+
+func parseTableKern(data []byte, numGlyphs int) (TableKern, error) {
+	if len(data) < 4 {
+		return TableKern{}, errEOF
+	}
+	switch version := binary.BigEndian.Uint16(data); version {
+	case 0:
+		return parseKernOpenType(data, numGlyphs)
+	case 1:
+		return parseKernApple(data, numGlyphs)
+	default:
+		return TableKern{}, fmt.Errorf("unsupported version %d", version)
+	}
+}
+
+func parseKernOpenType(data []byte, numGlyphs int) (TableKern, error) {
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	out := TableKern{Subtables: make([]KernSubtable, 0, count)}
+	data = data[4:]
+	for i := 0; i < count; i++ {
+		if len(data) < 6 {
+			return out, errors.New("invalid kerning subtable (EOF)")
+		}
+		length := int(binary.BigEndian.Uint16(data[2:]))
+		if i == count-1 {
+			// the length of large subtables overflows, so
+			// the last one is extended to the end of the table
+			length = len(data)
+		}
+		if length < 6 || len(data) < length {
+			return out, errors.New("invalid kerning subtable (EOF)")
+		}
+		coverage := binary.BigEndian.Uint16(data[4:])
+		subtable := KernSubtable{
+			Vertical:    coverage&0x01 == 0,
+			Minimum:     coverage&0x02 != 0,
+			CrossStream: coverage&0x04 != 0,
+			Override:    coverage&0x08 != 0,
+		}
+		var err error
+		if subtable.Data, err = parseKernData(data[:length], 6, coverage>>8, numGlyphs); err != nil {
+			return out, err
+		}
+		out.Subtables = append(out.Subtables, subtable)
+		data = data[length:]
+	}
+	return out, nil
+}
+
+// parseKernApple parses the Apple variant of the table, whose header is
+// either made of 32-bit values, or, in older fonts, of 16-bit values.
+func parseKernApple(data []byte, numGlyphs int) (TableKern, error) {
+	var count int
+	if binary.BigEndian.Uint16(data[2:]) == 0 {
+		if len(data) < 8 {
+			return TableKern{}, errEOF
+		}
+		count = int(binary.BigEndian.Uint32(data[4:]))
+		data = data[8:]
+	} else {
+		count = int(binary.BigEndian.Uint16(data[2:]))
+		data = data[4:]
+	}
+	var out TableKern
+	for i := 0; i < count; i++ {
+		if len(data) < 8 {
+			return out, errors.New("invalid kerning subtable (EOF)")
+		}
+		length := int64(binary.BigEndian.Uint32(data))
+		if length < 8 || int64(len(data)) < length {
+			return out, errors.New("invalid kerning subtable (EOF)")
+		}
+		coverage := binary.BigEndian.Uint16(data[4:])
+		subtable := KernSubtable{
+			Vertical:    coverage&0x8000 != 0,
+			CrossStream: coverage&0x4000 != 0,
+			Variation:   coverage&0x2000 != 0,
+			TupleIndex:  binary.BigEndian.Uint16(data[6:]),
+		}
+		var err error
+		if subtable.Data, err = parseKernData(data[:length], 8, coverage&0xFF, numGlyphs); err != nil {
+			return out, err
+		}
+		out.Subtables = append(out.Subtables, subtable)
+		data = data[length:]
+	}
+	return out, nil
+}
+
+// parseKernData parses the kerning subtable `data`, whose
+// header is `headerSize` long.
+func parseKernData(data []byte, headerSize int, format uint16, numGlyphs int) (KernData, error) {
+	switch format {
+	case 0:
+		return parseKernPairs(data[headerSize:])
+	case 1:
+		machine, err := parseAATStateTable(data[headerSize:], false, 0, numGlyphs)
+		return KernStateMachine{Machine: machine, data: data[headerSize:]}, err
+	case 2:
+		return parseKernClassMatrix(data, headerSize)
+	case 3:
+		return parseKernIndexArray(data[headerSize:])
+	default:
+		return nil, fmt.Errorf("unsupported kerning subtable format %d", format)
+	}
+}
+
+func parseKernPairs(data []byte) (KernPairs, error) {
+	if len(data) < 8 {
+		return nil, errors.New("invalid kerning pairs (EOF)")
+	}
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 8+6*count {
+		return nil, errors.New("invalid kerning pairs (EOF)")
+	}
+	out := make(KernPairs, count)
+	for i := range out {
+		pair := data[8+6*i:]
+		out[i] = KernPair{
+			Left:  GID(binary.BigEndian.Uint16(pair)),
+			Right: GID(binary.BigEndian.Uint16(pair[2:])),
+			Value: int16(binary.BigEndian.Uint16(pair[4:])),
+		}
+	}
+	less := func(i, j int) bool {
+		return out[i].Left < out[j].Left || (out[i].Left == out[j].Left && out[i].Right < out[j].Right)
+	}
+	if !sort.SliceIsSorted(out, less) { // some fonts have unsorted pairs
+		sort.SliceStable(out, less)
+	}
+	return out, nil
+}
+
+// parseKernClassMatrix parses the subtable `data`, whose offsets
+// are relative to the start of the subtable, header included.
+func parseKernClassMatrix(data []byte, headerSize int) (KernClassMatrix, error) {
+	if len(data) < headerSize+8 {
+		return KernClassMatrix{}, errors.New("invalid kerning class matrix (EOF)")
+	}
+	header := data[headerSize:]
+	out := KernClassMatrix{data: data, arrayOffset: int(binary.BigEndian.Uint16(header[6:]))}
+	var err error
+	if out.left, err = parseKernClassTable(data, binary.BigEndian.Uint16(header[2:])); err != nil {
+		return out, err
+	}
+	if out.right, err = parseKernClassTable(data, binary.BigEndian.Uint16(header[4:])); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// parseKernClassTable parses a class table of a class matrix, whose values
+// are offsets in the matrix.
+func parseKernClassTable(data []byte, offset uint16) (ClassDef, error) {
+	if len(data) < int(offset)+4 {
+		return nil, errors.New("invalid kerning class table (EOF)")
+	}
+	table := data[offset:]
+	out := classDefArray{startGlyph: GID(binary.BigEndian.Uint16(table))}
+	var err error
+	if out.classes, err = parseUint16s(table, 4, int(binary.BigEndian.Uint16(table[2:]))); err != nil {
+		return nil, errors.New("invalid kerning class table (EOF)")
+	}
+	return out, nil
+}
+
+func parseKernIndexArray(data []byte) (KernIndexArray, error) {
+	errTruncated := errors.New("invalid kerning index array (EOF)")
+	if len(data) < 6 {
+		return KernIndexArray{}, errTruncated
+	}
+	glyphCount := int(binary.BigEndian.Uint16(data))
+	valueCount, leftCount, rightCount := int(data[2]), int(data[3]), int(data[4])
+	if len(data) < 6+2*valueCount+2*glyphCount+leftCount*rightCount {
+		return KernIndexArray{}, errTruncated
+	}
+	out := KernIndexArray{Values: make([]int16, valueCount), RightClassCount: rightCount}
+	for i := range out.Values {
+		out.Values[i] = int16(binary.BigEndian.Uint16(data[6+2*i:]))
+	}
+	offset := 6 + 2*valueCount
+	out.LeftClasses = data[offset : offset+glyphCount]
+	out.RightClasses = data[offset+glyphCount : offset+2*glyphCount]
+	out.Indices = data[offset+2*glyphCount : offset+2*glyphCount+leftCount*rightCount]
+	return out, nil
+}
+
+// kern is the lazily parsed 'kern' table.
+type kern struct {
+	once  sync.Once
+	table *TableKern // nil if missing or invalid
+}
+
+// Kern returns the kerning table, parsed on first use,
+// or false if the font has no 'kern' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Kern() (*TableKern, bool) {
+	face.kern.once.Do(func() {
+		if data, err := face.GetRawTable(tagKern); err == nil {
+			if table, err := parseTableKern(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagKern, err))
+			} else {
+				face.kern.table = &table
+			}
+		}
+	})
+	return face.kern.table, face.kern.table != nil
+}
+
+// Kerning returns the horizontal kerning between the glyphs, in font units,
+// given by the pair kerning subtables (see PairKerning) of the 'kerx' table,
+// or of the 'kern' table if the font has no 'kerx' table.
+// The vertical, cross-stream (see CrossStreamKerning), minimum and variation
+// subtables are ignored, as well as the contextual ones (KernStateMachine, KerxStateMachine and
+// KerxAnchorMachine), which require the whole sequence of glyphs.
+// It returns 0 if the font has no kerning table.
+func (face *Face) Kerning(left, right GID) float32 {
+	return face.pairKerning(left, right, false)
+}
+
+// CrossStreamKerning is the same as Kerning, for the cross-stream subtables :
+// it returns the vertical shift of the right glyph, in font units, in horizontal
+// text. Apple fonts use it for instance to adjust the baseline of the glyphs
+// following some punctuation.
+func (face *Face) CrossStreamKerning(left, right GID) float32 {
+	return face.pairKerning(left, right, true)
+}
+
+// pairKerning returns the horizontal kerning, or the cross-stream kerning
+// if `crossStream` is true, of the pair kerning subtables.
+func (face *Face) pairKerning(left, right GID, crossStream bool) float32 {
+	if table, ok := face.Kerx(); ok {
+		var out int16
+		for _, subtable := range table.Subtables {
+			if subtable.Vertical || subtable.CrossStream != crossStream || subtable.Variation {
+				continue
+			}
+			if pairs, ok := subtable.Data.(PairKerning); ok {
+				v, _ := pairs.Kern(left, right)
+				out += v
+			}
+		}
+		return float32(out)
+	}
+	table, ok := face.Kern()
+	if !ok {
+		return 0
+	}
+	var out int16
+	for _, subtable := range table.Subtables {
+		if subtable.Vertical || subtable.CrossStream != crossStream || subtable.Minimum || subtable.Variation {
+			continue
+		}
+		pairs, ok := subtable.Data.(PairKerning)
+		if !ok {
+			continue
+		}
+		if v, ok := pairs.Kern(left, right); ok {
+			if subtable.Override {
+				out = v
+			} else {
+				out += v
+			}
+		}
+	}
+	return float32(out)
+}
@@ -0,0 +1,448 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableKerx stores the extended kerning of the AAT fonts, which
+// supersedes the Apple variant of the 'kern' table.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6kerx.html
+type TableKerx struct {
+	Subtables []KerxSubtable
+}
+
+// KerxSubtable is a subtable of the 'kerx' table.
+type KerxSubtable struct {
+	// Vertical is true if the values adjust the vertical layout.
+	Vertical bool
+	// CrossStream is true if the values adjust the glyphs perpendicularly
+	// to the layout direction, such as vertically in horizontal text.
+	CrossStream bool
+	// Variation is true if the values are variation values,
+	// used with the 'fvar' table.
+	Variation bool
+	// Backwards is true if the state machines process
+	// the glyphs from the end of the run.
+	Backwards bool
+	// TupleCount is the number of values of the kerning tuples of
+	// variation fonts, or 0 if the values are not tuples. Only the first
+	// value of the tuples is used, as in the other implementations.
+	TupleCount int
+
+	Data KerxData
+}
+
+// KerxData is the content of an extended kerning subtable. It is one of
+// KernPairs (format 0), KerxStateMachine (format 1), KerxClassMatrix
+// (formats 2 and 6) or KerxAnchorMachine (format 4).
+type KerxData interface {
+	isKerxData()
+}
+
+func (KernPairs) isKerxData()         {}
+func (KerxStateMachine) isKerxData()  {}
+func (KerxClassMatrix) isKerxData()   {}
+func (KerxAnchorMachine) isKerxData() {}
+
+// The flags of the entries of KerxStateMachine and KerxAnchorMachine.
+const (
+	// KerxPush pushes the current glyph on the kerning stack (KerxStateMachine).
+	KerxPush = 0x8000
+	// KerxMark marks the current glyph, whose point is attached
+	// by the following actions (KerxAnchorMachine).
+	KerxMark = 0x8000
+	// KerxDontAdvance processes the current glyph again, in the new state.
+	KerxDontAdvance = 0x4000
+	// KerxReset clears the kerning stack (KerxStateMachine).
+	KerxReset = 0x2000
+)
+
+// KerxNoAction is the index of the entries without
+// kerning values, or without anchor actions.
+const KerxNoAction = 0xFFFF
+
+// KerxStateMachine stores contextual kerning values, applied by a state machine.
+// The first value following the flags of the entries (AATStateEntry.Data) is the
+// index of their kerning values (see Values), or KerxNoAction.
+type KerxStateMachine struct {
+	Machine AATStateTable
+
+	values     []byte // starting at the value table
+	tupleCount int    // at least 1
+}
+
+// Values returns the list of kerning values of the entries with
+// value index `index`. The values are applied to the glyphs popped
+// from the kerning stack, and the end of the list is marked by an odd value,
+// whose lowest bit must be cleared before use.
+func (ksm *KerxStateMachine) Values(index uint16) []int16 {
+	var out []int16
+	for i := 2 * int(index) * ksm.tupleCount; i+2 <= len(ksm.values); i += 2 * ksm.tupleCount {
+		v := int16(binary.BigEndian.Uint16(ksm.values[i:]))
+		out = append(out, v)
+		if v&1 != 0 {
+			break
+		}
+	}
+	return out
+}
+
+// KerxClassMatrix stores the kerning values in a matrix, indexed by
+// the rows and columns of the left and right glyphs.
+type KerxClassMatrix struct {
+	// left and right map the glyphs to the indices, in the matrix,
+	// of their row (premultiplied by the row length) and of their column
+	left, right kerxLookup
+	data        []byte // the kerning subtable
+	array       int    // start of the matrix in data
+	valueSize   int    // 2 or 4
+	vectors     int    // start of the variation tuples in data, or -1
+}
+
+// Kern implements PairKerning.
+func (kc KerxClassMatrix) Kern(left, right GID) (int16, bool) {
+	l, ok1 := kc.left.value(left)
+	r, ok2 := kc.right.value(right)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	offset := int64(kc.array) + int64(kc.valueSize)*(int64(l)+int64(r))
+	if int64(len(kc.data)) < offset+int64(kc.valueSize) {
+		return 0, false
+	}
+	var v uint32
+	if kc.valueSize == 4 {
+		v = binary.BigEndian.Uint32(kc.data[offset:])
+	} else {
+		v = uint32(binary.BigEndian.Uint16(kc.data[offset:]))
+	}
+	if kc.vectors == -1 {
+		return int16(v), true
+	}
+	return kerxTupleValue(kc.data, kc.vectors, v)
+}
+
+// kerxTupleValue returns the first value of the tuple at offset `base`+`offset`.
+func kerxTupleValue(data []byte, base int, offset uint32) (int16, bool) {
+	if int64(base)+int64(offset)+2 > int64(len(data)) {
+		return 0, false
+	}
+	return int16(binary.BigEndian.Uint16(data[int64(base)+int64(offset):])), true
+}
+
+// kerxLookup maps the glyphs to the rows or columns of a KerxClassMatrix.
+type kerxLookup interface {
+	value(gid GID) (uint32, bool)
+}
+
+// kerxShortLookup is a kerxLookup with 16-bit values.
+type kerxShortLookup struct {
+	ClassDef
+}
+
+func (l kerxShortLookup) value(gid GID) (uint32, bool) {
+	v, ok := l.Class(gid)
+	return uint32(v), ok
+}
+
+// KerxAnchorMachine attaches the glyphs by aligning their points, according
+// to a state machine. The first value following the flags of the entries
+// (AATStateEntry.Data) is the index of their action in Actions, or KerxNoAction.
+// The actions align the point of the last marked glyph (see KerxMark)
+// with the one of the current glyph.
+type KerxAnchorMachine struct {
+	Machine AATStateTable
+	// ActionType is the type of the actions, one of KerxControlPointActions,
+	// KerxAnchorPointActions or KerxCoordinateActions.
+	ActionType uint8
+	Actions    []KerxAnchorAction
+}
+
+// The types of the actions of a KerxAnchorMachine.
+const (
+	// KerxControlPointActions align contour points of the glyphs.
+	KerxControlPointActions = iota
+	// KerxAnchorPointActions align anchor points defined by the 'ankr' table.
+	KerxAnchorPointActions
+	// KerxCoordinateActions align points given by their coordinates.
+	KerxCoordinateActions
+)
+
+// KerxAnchorAction is an action of a KerxAnchorMachine.
+type KerxAnchorAction struct {
+	// MarkPoint and CurrentPoint are the indices of the points of the marked
+	// and current glyphs, for KerxControlPointActions and KerxAnchorPointActions.
+	MarkPoint, CurrentPoint uint16
+	// MarkX, MarkY, CurrentX and CurrentY are the coordinates of the points,
+	// in font units, for KerxCoordinateActions.
+	MarkX, MarkY, CurrentX, CurrentY int16
+}
+
+func parseTableKerx(data []byte, numGlyphs int) (TableKerx, error) {
+	if len(data) < 8 {
+		return TableKerx{}, errEOF
+	}
+	version := binary.BigEndian.Uint16(data)
+	if version < 2 || version > 4 {
+		return TableKerx{}, fmt.Errorf("unsupported version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[4:]))
+	var out TableKerx
+	data = data[8:]
+	for i := 0; i < count; i++ {
+		if len(data) < 12 {
+			return out, errors.New("invalid extended kerning subtable (EOF)")
+		}
+		length := int64(binary.BigEndian.Uint32(data))
+		if length < 12 || int64(len(data)) < length {
+			return out, errors.New("invalid extended kerning subtable (EOF)")
+		}
+		coverage := binary.BigEndian.Uint32(data[4:])
+		subtable := KerxSubtable{
+			Vertical:    coverage&0x80000000 != 0,
+			CrossStream: coverage&0x40000000 != 0,
+			Variation:   coverage&0x20000000 != 0,
+			Backwards:   coverage&0x10000000 != 0,
+		}
+		if version >= 4 { // ignored by the previous versions
+			subtable.TupleCount = int(binary.BigEndian.Uint32(data[8:]))
+		}
+		var err error
+		if subtable.Data, err = parseKerxData(data[:length], uint8(coverage), subtable.TupleCount, numGlyphs); err != nil {
+			return out, err
+		}
+		out.Subtables = append(out.Subtables, subtable)
+		data = data[length:]
+	}
+	return out, nil
+}
+
+// parseKerxData parses the subtable `data`, header included.
+func parseKerxData(data []byte, format uint8, tupleCount, numGlyphs int) (KerxData, error) {
+	switch format {
+	case 0:
+		return parseKerxPairs(data, tupleCount)
+	case 1:
+		return parseKerxStateMachine(data[12:], tupleCount, numGlyphs)
+	case 2:
+		return parseKerxClassMatrix(data, tupleCount, numGlyphs)
+	case 4:
+		return parseKerxAnchorMachine(data[12:], numGlyphs)
+	case 6:
+		return parseKerxExtendedClassMatrix(data, tupleCount, numGlyphs)
+	default:
+		return nil, fmt.Errorf("unsupported extended kerning subtable format %d", format)
+	}
+}
+
+func parseKerxPairs(data []byte, tupleCount int) (KernPairs, error) {
+	errTruncated := errors.New("invalid extended kerning pairs (EOF)")
+	if len(data) < 12+16 {
+		return nil, errTruncated
+	}
+	count := int64(binary.BigEndian.Uint32(data[12:]))
+	if int64(len(data)) < 12+16+6*count {
+		return nil, errTruncated
+	}
+	out := make(KernPairs, count)
+	for i := range out {
+		pair := data[12+16+6*i:]
+		out[i] = KernPair{
+			Left:  GID(binary.BigEndian.Uint16(pair)),
+			Right: GID(binary.BigEndian.Uint16(pair[2:])),
+			Value: int16(binary.BigEndian.Uint16(pair[4:])),
+		}
+		if tupleCount != 0 { // the value is the offset of the tuple
+			var ok bool
+			if out[i].Value, ok = kerxTupleValue(data, 0, uint32(uint16(out[i].Value))); !ok {
+				return nil, errTruncated
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseKerxStateMachine parses the subtable `data`, starting at the state table.
+func parseKerxStateMachine(data []byte, tupleCount, numGlyphs int) (KerxStateMachine, error) {
+	machine, err := parseAATStateTable(data, true, 1, numGlyphs)
+	if err != nil {
+		return KerxStateMachine{}, err
+	}
+	if len(data) < 20 {
+		return KerxStateMachine{}, errors.New("invalid extended kerning state machine (EOF)")
+	}
+	valuesOffset := binary.BigEndian.Uint32(data[16:])
+	if int64(valuesOffset) > int64(len(data)) {
+		return KerxStateMachine{}, errors.New("invalid extended kerning values (EOF)")
+	}
+	if tupleCount == 0 {
+		tupleCount = 1
+	}
+	return KerxStateMachine{Machine: machine, values: data[valuesOffset:], tupleCount: tupleCount}, nil
+}
+
+func parseKerxClassMatrix(data []byte, tupleCount, numGlyphs int) (KerxClassMatrix, error) {
+	if len(data) < 12+16 {
+		return KerxClassMatrix{}, errors.New("invalid extended kerning class matrix (EOF)")
+	}
+	header := data[12:]
+	out := KerxClassMatrix{data: data, array: int(binary.BigEndian.Uint32(header[12:])), valueSize: 2, vectors: -1}
+	if tupleCount != 0 {
+		out.vectors = 0 // the offsets of the tuples are relative to the subtable
+	}
+	for i, lookup := range [2]*kerxLookup{&out.left, &out.right} {
+		classes, err := parseAATLookup(data, binary.BigEndian.Uint32(header[4+4*i:]), numGlyphs)
+		if err != nil {
+			return out, err
+		}
+		*lookup = kerxShortLookup{classes}
+	}
+	return out, nil
+}
+
+// parseKerxExtendedClassMatrix parses a subtable of format 6,
+// whose values and lookups may be made of 32-bit values.
+func parseKerxExtendedClassMatrix(data []byte, tupleCount, numGlyphs int) (KerxClassMatrix, error) {
+	if len(data) < 12+24 {
+		return KerxClassMatrix{}, errors.New("invalid extended kerning class matrix (EOF)")
+	}
+	header := data[12:]
+	isLong := binary.BigEndian.Uint32(header)&1 != 0
+	out := KerxClassMatrix{data: data, array: int(binary.BigEndian.Uint32(header[16:])), valueSize: 2, vectors: -1}
+	if isLong {
+		out.valueSize = 4
+	}
+	if tupleCount != 0 {
+		if len(data) < 12+28 {
+			return out, errors.New("invalid extended kerning class matrix (EOF)")
+		}
+		out.vectors = int(binary.BigEndian.Uint32(header[24:]))
+	}
+	for i, lookup := range [2]*kerxLookup{&out.left, &out.right} {
+		offset := binary.BigEndian.Uint32(header[8+4*i:])
+		if isLong {
+			values, err := parseAATLongLookup(data, offset, numGlyphs)
+			if err != nil {
+				return out, err
+			}
+			*lookup = values
+		} else {
+			classes, err := parseAATLookup(data, offset, numGlyphs)
+			if err != nil {
+				return out, err
+			}
+			*lookup = kerxShortLookup{classes}
+		}
+	}
+	return out, nil
+}
+
+// parseKerxAnchorMachine parses the subtable `data`, starting at the state table.
+func parseKerxAnchorMachine(data []byte, numGlyphs int) (KerxAnchorMachine, error) {
+	machine, err := parseAATStateTable(data, true, 1, numGlyphs)
+	if err != nil {
+		return KerxAnchorMachine{}, err
+	}
+	if len(data) < 20 {
+		return KerxAnchorMachine{}, errors.New("invalid extended kerning anchor machine (EOF)")
+	}
+	flags := binary.BigEndian.Uint32(data[16:])
+	out := KerxAnchorMachine{Machine: machine, ActionType: uint8(flags >> 30)}
+	if out.ActionType > KerxCoordinateActions {
+		return out, fmt.Errorf("unsupported extended kerning action type %d", out.ActionType)
+	}
+
+	// the number of actions is deduced from the entries
+	count := 0
+	for _, entry := range machine.Entries {
+		if index := entry.Data[0]; index != KerxNoAction && int(index) >= count {
+			count = int(index) + 1
+		}
+	}
+	actionSize := 4
+	if out.ActionType == KerxCoordinateActions {
+		actionSize = 8
+	}
+	offset := int(flags & 0x00FFFFFF)
+	if len(data) < offset+actionSize*count {
+		return out, errors.New("invalid extended kerning anchor actions (EOF)")
+	}
+	out.Actions = make([]KerxAnchorAction, count)
+	for i := range out.Actions {
+		action := data[offset+actionSize*i:]
+		if out.ActionType == KerxCoordinateActions {
+			out.Actions[i] = KerxAnchorAction{
+				MarkX:    int16(binary.BigEndian.Uint16(action)),
+				MarkY:    int16(binary.BigEndian.Uint16(action[2:])),
+				CurrentX: int16(binary.BigEndian.Uint16(action[4:])),
+				CurrentY: int16(binary.BigEndian.Uint16(action[6:])),
+			}
+		} else {
+			out.Actions[i] = KerxAnchorAction{
+				MarkPoint:    binary.BigEndian.Uint16(action),
+				CurrentPoint: binary.BigEndian.Uint16(action[2:]),
+			}
+		}
+	}
+	return out, nil
+}
+
+// kerx is the lazily parsed 'kerx' table.
+type kerx struct {
+	once  sync.Once
+	table *TableKerx // nil if missing or invalid
+}
+
+// Kerx returns the extended kerning table, parsed on first use,
+// or false if the font has no 'kerx' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Kerx() (*TableKerx, bool) {
+	face.kerx.once.Do(func() {
+		if data, err := face.GetRawTable(tagKerx); err == nil {
+			if table, err := parseTableKerx(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagKerx, err))
+			} else {
+				face.kerx.table = &table
+			}
+		}
+	})
+	return face.kerx.table, face.kerx.table != nil
+}
+
+// ResolveKerxAction returns the offset, in font units, to apply to the current
+// glyph `current` so that its point is aligned with the one of the marked glyph
+// `mark`, when applying the action `action` of `machine`.
+// The contour points are only supported for TrueType outlines, and the anchor
+// points are given by the 'ankr' table (see TableAnkr.AnchorPoints).
+// It returns false if the action or the points are not found.
+func (face *Face) ResolveKerxAction(machine *KerxAnchorMachine, action uint16, mark, current GID) (dx, dy float32, ok bool) {
+	if int(action) >= len(machine.Actions) {
+		return 0, 0, false
+	}
+	a := machine.Actions[action]
+	switch machine.ActionType {
+	case KerxControlPointActions:
+		markPoint, ok1 := face.contourPoint(mark, a.MarkPoint)
+		currentPoint, ok2 := face.contourPoint(current, a.CurrentPoint)
+		if !ok1 || !ok2 {
+			return 0, 0, false
+		}
+		return markPoint.x - currentPoint.x, markPoint.y - currentPoint.y, true
+	case KerxAnchorPointActions:
+		ankr, ok := face.Ankr()
+		if !ok {
+			return 0, 0, false
+		}
+		markPoints, currentPoints := ankr.AnchorPoints(mark), ankr.AnchorPoints(current)
+		if int(a.MarkPoint) >= len(markPoints) || int(a.CurrentPoint) >= len(currentPoints) {
+			return 0, 0, false
+		}
+		markPoint, currentPoint := markPoints[a.MarkPoint], currentPoints[a.CurrentPoint]
+		return float32(markPoint.X) - float32(currentPoint.X), float32(markPoint.Y) - float32(currentPoint.Y), true
+	default:
+		return float32(a.MarkX) - float32(a.CurrentX), float32(a.MarkY) - float32(a.CurrentY), true
+	}
+}
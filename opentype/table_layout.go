@@ -0,0 +1,1022 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// This file parses the structures shared by the 'GSUB' and 'GPOS' tables.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2
+
+// Layout stores the content common to the 'GSUB' and 'GPOS' tables.
+type Layout struct {
+	// Scripts are the writing systems supported by the table, sorted by tag.
+	Scripts []Script
+	// Features are the features of the table, referenced by index.
+	Features []Feature
+	// FeatureVariations replace some of the Features for variable
+	// fonts, according to the variation coordinates (version 1.1 and later).
+	FeatureVariations []FeatureVariation
+}
+
+var (
+	// DefaultScript is the tag of the script used for the
+	// writing systems not listed in the table.
+	DefaultScript = MustNewTag("DFLT")
+	// DefaultLanguage is the tag selecting the default
+	// language system of a script (see Layout.FindLanguage).
+	DefaultLanguage = MustNewTag("dflt")
+)
+
+// NoRequiredFeature is the value of LangSys.RequiredFeature
+// for the language systems without required feature.
+const NoRequiredFeature = 0xFFFF
+
+// Script lists the language systems of a writing system.
+type Script struct {
+	Tag Tag
+	// DefaultLanguage is the language system used for the languages
+	// not listed in Languages. It is nil if missing.
+	DefaultLanguage *LangSys
+	// Languages are the language systems specific to
+	// some languages, sorted by tag.
+	Languages []LangSys
+}
+
+// LangSys is a language system, selecting the features applied to a language.
+type LangSys struct {
+	Tag Tag // 0 for the default language system of the script
+	// RequiredFeature is the index of the feature which must always
+	// be applied, or NoRequiredFeature.
+	RequiredFeature uint16
+	// FeatureIndices are the indices of the other features,
+	// which may be enabled, in the features of the table.
+	FeatureIndices []uint16
+}
+
+// Feature is a typographic feature, implemented by a list of lookups.
+type Feature struct {
+	Tag Tag
+	// LookupIndices are the indices of the lookups of the feature,
+	// in the lookup list of the table.
+	LookupIndices []uint16
+	// Params are the parameters of the feature, or nil if
+	// it has none, or if they are not supported.
+	Params FeatureParams
+}
+
+// FeatureParams are the parameters of some features.
+// It is StylisticSetParams, CharacterVariantParams or SizeParams.
+type FeatureParams interface {
+	isFeatureParams()
+}
+
+func (StylisticSetParams) isFeatureParams()     {}
+func (CharacterVariantParams) isFeatureParams() {}
+func (SizeParams) isFeatureParams()             {}
+
+// StylisticSetParams are the parameters of the stylistic set
+// features ('ss01' to 'ss20').
+type StylisticSetParams struct {
+	// UINameID is the entry of the 'name' table naming
+	// the stylistic set in user interfaces.
+	UINameID NameID
+}
+
+// CharacterVariantParams are the parameters of the character variant
+// features ('cv01' to 'cv99'). The entries of the 'name' table are 0 if missing.
+type CharacterVariantParams struct {
+	// LabelNameID names the feature in user interfaces.
+	LabelNameID NameID
+	// TooltipNameID is a description of the feature, displayed as a tooltip.
+	TooltipNameID NameID
+	// SampleTextNameID is a sample text illustrating the feature.
+	SampleTextNameID NameID
+	// NumNamedParameters is the number of named parameters of the feature,
+	// whose labels are the consecutive entries starting at FirstParamLabelNameID.
+	NumNamedParameters    uint16
+	FirstParamLabelNameID NameID
+	// Characters are the characters for which the feature
+	// provides glyph variants.
+	Characters []rune
+}
+
+// SizeParams are the parameters of the 'size' feature of the 'GPOS' table,
+// describing the optical size of the font. The sizes are in decipoints.
+type SizeParams struct {
+	DesignSize uint16
+	// SubfamilyID identifies the fonts of the family which only differ by their
+	// optical size, sharing the same SubfamilyNameID. When it is 0, the other
+	// fields are 0, and the font has no recommended size range.
+	SubfamilyID     uint16
+	SubfamilyNameID NameID
+	// RangeStart (exclusive) and RangeEnd (inclusive) delimit the sizes
+	// for which the font is recommended.
+	RangeStart, RangeEnd uint16
+}
+
+// FeatureVariation replaces some features of the table when
+// the variation coordinates satisfy all its conditions.
+type FeatureVariation struct {
+	// Conditions are the ranges on the variation axes where
+	// the substitutions apply. All of them must be satisfied, and an empty
+	// list is always satisfied.
+	Conditions []FeatureCondition
+	// Substitutions are the alternate features, sorted by feature index.
+	Substitutions []FeatureSubstitution
+
+	// the conditions include an unsupported format, which never matches
+	unsupported bool
+}
+
+// FeatureCondition is satisfied when the normalized coordinate
+// of the axis Axis is in the range [Min, Max].
+type FeatureCondition struct {
+	Axis     uint16 // index in the 'fvar' axes
+	Min, Max float32
+}
+
+// FeatureSubstitution replaces the feature at index FeatureIndex by Feature,
+// whose tag is the one of the feature it replaces.
+type FeatureSubstitution struct {
+	FeatureIndex uint16
+	Feature      Feature
+}
+
+func parseLayout(data []byte) (Layout, error) {
+	if len(data) < 10 {
+		return Layout{}, errEOF
+	}
+	major, minor := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	if major != 1 {
+		return Layout{}, fmt.Errorf("unsupported version %d.%d", major, minor)
+	}
+
+	var (
+		out Layout
+		err error
+	)
+	if offset := binary.BigEndian.Uint16(data[6:]); offset != 0 {
+		if out.Features, err = parseFeatureList(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint16(data[4:]); offset != 0 {
+		if out.Scripts, err = parseScriptList(data, uint32(offset), len(out.Features)); err != nil {
+			return out, err
+		}
+	}
+	if minor >= 1 && len(data) >= 14 {
+		if offset := binary.BigEndian.Uint32(data[10:]); offset != 0 {
+			if out.FeatureVariations, err = parseFeatureVariations(data, offset, out.Features); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseScriptList(data []byte, offset uint32, numFeatures int) ([]Script, error) {
+	if int64(offset)+2 > int64(len(data)) {
+		return nil, errors.New("invalid script list (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+6*count {
+		return nil, errors.New("invalid script list (EOF)")
+	}
+	out := make([]Script, count)
+	for i := range out {
+		record := data[2+6*i:]
+		var err error
+		if out[i], err = parseScript(data, uint32(binary.BigEndian.Uint16(record[4:])), numFeatures); err != nil {
+			return nil, err
+		}
+		out[i].Tag = Tag(binary.BigEndian.Uint32(record))
+	}
+	return out, nil
+}
+
+func parseScript(data []byte, offset uint32, numFeatures int) (Script, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return Script{}, errors.New("invalid script table (EOF)")
+	}
+	data = data[offset:]
+	var out Script
+	if offset := binary.BigEndian.Uint16(data); offset != 0 {
+		langSys, err := parseLangSys(data, offset, numFeatures)
+		if err != nil {
+			return out, err
+		}
+		out.DefaultLanguage = &langSys
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+6*count {
+		return out, errors.New("invalid script table (EOF)")
+	}
+	out.Languages = make([]LangSys, count)
+	for i := range out.Languages {
+		record := data[4+6*i:]
+		var err error
+		if out.Languages[i], err = parseLangSys(data, binary.BigEndian.Uint16(record[4:]), numFeatures); err != nil {
+			return out, err
+		}
+		out.Languages[i].Tag = Tag(binary.BigEndian.Uint32(record))
+	}
+	return out, nil
+}
+
+// parseLangSys parses the language system at `data[offset:]`. The invalid
+// required feature indices are replaced by NoRequiredFeature.
+func parseLangSys(data []byte, offset uint16, numFeatures int) (LangSys, error) {
+	if len(data) < int(offset)+6 {
+		return LangSys{}, errors.New("invalid language system table (EOF)")
+	}
+	data = data[offset:]
+	out := LangSys{RequiredFeature: binary.BigEndian.Uint16(data[2:])}
+	if int(out.RequiredFeature) >= numFeatures {
+		out.RequiredFeature = NoRequiredFeature
+	}
+	var err error
+	if out.FeatureIndices, err = parseUint16s(data, 6, int(binary.BigEndian.Uint16(data[4:]))); err != nil {
+		return out, errors.New("invalid language system table (EOF)")
+	}
+	return out, nil
+}
+
+func parseFeatureList(data []byte, offset uint32) ([]Feature, error) {
+	if int64(offset)+2 > int64(len(data)) {
+		return nil, errors.New("invalid feature list (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+6*count {
+		return nil, errors.New("invalid feature list (EOF)")
+	}
+	out := make([]Feature, count)
+	for i := range out {
+		record := data[2+6*i:]
+		var err error
+		offset := uint32(binary.BigEndian.Uint16(record[4:]))
+		out[i], err = parseFeature(data, offset, Tag(binary.BigEndian.Uint32(record)))
+		if err != nil {
+			return nil, err
+		}
+		if out[i].Tag == tagSize && out[i].Params == nil {
+			// some old fonts use an offset relative to the feature list
+			if paramsOffset := int(binary.BigEndian.Uint16(data[offset:])); paramsOffset != 0 && paramsOffset < len(data) {
+				out[i].Params = parseFeatureParams(data[paramsOffset:], tagSize)
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseFeature parses the feature table `tag`. The parameters of the
+// feature are optional, and ignored if they are invalid.
+func parseFeature(data []byte, offset uint32, tag Tag) (Feature, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return Feature{}, errors.New("invalid feature table (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+2*count {
+		return Feature{}, errors.New("invalid feature table (EOF)")
+	}
+	out := Feature{Tag: tag, LookupIndices: make([]uint16, count)}
+	for i := range out.LookupIndices {
+		out.LookupIndices[i] = binary.BigEndian.Uint16(data[4+2*i:])
+	}
+	if paramsOffset := int(binary.BigEndian.Uint16(data)); paramsOffset != 0 && paramsOffset < len(data) {
+		out.Params = parseFeatureParams(data[paramsOffset:], tag)
+	}
+	return out, nil
+}
+
+// parseFeatureParams returns the parameters of the feature `tag`,
+// or nil if they are invalid or not supported.
+func parseFeatureParams(data []byte, tag Tag) FeatureParams {
+	switch {
+	case isStylisticSet(tag):
+		if len(data) < 4 || binary.BigEndian.Uint16(data) != 0 {
+			return nil
+		}
+		return StylisticSetParams{UINameID: NameID(binary.BigEndian.Uint16(data[2:]))}
+	case isCharacterVariant(tag):
+		if len(data) < 14 || binary.BigEndian.Uint16(data) != 0 {
+			return nil
+		}
+		count := int(binary.BigEndian.Uint16(data[12:]))
+		if len(data) < 14+3*count {
+			return nil
+		}
+		out := CharacterVariantParams{
+			LabelNameID:           NameID(binary.BigEndian.Uint16(data[2:])),
+			TooltipNameID:         NameID(binary.BigEndian.Uint16(data[4:])),
+			SampleTextNameID:      NameID(binary.BigEndian.Uint16(data[6:])),
+			NumNamedParameters:    binary.BigEndian.Uint16(data[8:]),
+			FirstParamLabelNameID: NameID(binary.BigEndian.Uint16(data[10:])),
+			Characters:            make([]rune, count),
+		}
+		for i := range out.Characters {
+			b := data[14+3*i:]
+			out.Characters[i] = rune(b[0])<<16 | rune(b[1])<<8 | rune(b[2])
+		}
+		return out
+	case tag == tagSize:
+		if len(data) < 10 {
+			return nil
+		}
+		out := SizeParams{
+			DesignSize:      binary.BigEndian.Uint16(data),
+			SubfamilyID:     binary.BigEndian.Uint16(data[2:]),
+			SubfamilyNameID: NameID(binary.BigEndian.Uint16(data[4:])),
+			RangeStart:      binary.BigEndian.Uint16(data[6:]),
+			RangeEnd:        binary.BigEndian.Uint16(data[8:]),
+		}
+		if out.DesignSize == 0 {
+			return nil
+		}
+		if out.SubfamilyID == 0 && out.SubfamilyNameID == 0 && out.RangeStart == 0 && out.RangeEnd == 0 {
+			return out
+		}
+		if out.DesignSize < out.RangeStart || out.DesignSize > out.RangeEnd ||
+			out.SubfamilyNameID < 256 || out.SubfamilyNameID > 32767 {
+			return nil
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+var tagSize = MustNewTag("size")
+
+// isStylisticSet returns true for the tags 'ss01' to 'ss20'.
+func isStylisticSet(tag Tag) bool {
+	set, ok := featureNumber(tag, "ss")
+	return ok && 1 <= set && set <= 20
+}
+
+// isCharacterVariant returns true for the tags 'cv01' to 'cv99'.
+func isCharacterVariant(tag Tag) bool {
+	variant, ok := featureNumber(tag, "cv")
+	return ok && variant >= 1
+}
+
+// featureNumber returns the number of the feature tags made of `prefix`
+// followed by two digits, such as 'ss01' or 'cv99'.
+func featureNumber(tag Tag, prefix string) (int, bool) {
+	s := tag.String()
+	if s[:2] != prefix || s[2] < '0' || s[2] > '9' || s[3] < '0' || s[3] > '9' {
+		return 0, false
+	}
+	return int(s[2]-'0')*10 + int(s[3]-'0'), true
+}
+
+func parseFeatureVariations(data []byte, offset uint32, features []Feature) ([]FeatureVariation, error) {
+	if int64(offset)+8 > int64(len(data)) {
+		return nil, errors.New("invalid feature variations table (EOF)")
+	}
+	data = data[offset:]
+	if major := binary.BigEndian.Uint16(data); major != 1 {
+		return nil, fmt.Errorf("unsupported feature variations version %d", major)
+	}
+	count := int64(binary.BigEndian.Uint32(data[4:]))
+	if int64(len(data)) < 8+8*count {
+		return nil, errors.New("invalid feature variations table (EOF)")
+	}
+	out := make([]FeatureVariation, count)
+	for i := range out {
+		record := data[8+8*i:]
+		var err error
+		if offset := binary.BigEndian.Uint32(record); offset != 0 {
+			if out[i].Conditions, out[i].unsupported, err = parseConditionSet(data, offset); err != nil {
+				return nil, err
+			}
+		}
+		if offset := binary.BigEndian.Uint32(record[4:]); offset != 0 {
+			if out[i].Substitutions, err = parseFeatureSubstitutions(data, offset, features); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseConditionSet returns the conditions of the set, and true
+// if some of them have an unsupported format.
+func parseConditionSet(data []byte, offset uint32) ([]FeatureCondition, bool, error) {
+	if int64(offset)+2 > int64(len(data)) {
+		return nil, false, errors.New("invalid condition set (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+4*count {
+		return nil, false, errors.New("invalid condition set (EOF)")
+	}
+	out := make([]FeatureCondition, 0, count)
+	unsupported := false
+	for i := 0; i < count; i++ {
+		conditionOffset := int64(binary.BigEndian.Uint32(data[2+4*i:]))
+		if conditionOffset+2 > int64(len(data)) {
+			return nil, false, errors.New("invalid condition table (EOF)")
+		}
+		condition := data[conditionOffset:]
+		if format := binary.BigEndian.Uint16(condition); format != 1 {
+			unsupported = true
+			continue
+		}
+		if len(condition) < 8 {
+			return nil, false, errors.New("invalid condition table (EOF)")
+		}
+		out = append(out, FeatureCondition{
+			Axis: binary.BigEndian.Uint16(condition[2:]),
+			Min:  f2dot14(binary.BigEndian.Uint16(condition[4:])),
+			Max:  f2dot14(binary.BigEndian.Uint16(condition[6:])),
+		})
+	}
+	return out, unsupported, nil
+}
+
+func parseFeatureSubstitutions(data []byte, offset uint32, features []Feature) ([]FeatureSubstitution, error) {
+	if int64(offset)+6 > int64(len(data)) {
+		return nil, errors.New("invalid feature table substitution (EOF)")
+	}
+	data = data[offset:]
+	if major := binary.BigEndian.Uint16(data); major != 1 {
+		return nil, fmt.Errorf("unsupported feature table substitution version %d", major)
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+6*count {
+		return nil, errors.New("invalid feature table substitution (EOF)")
+	}
+	out := make([]FeatureSubstitution, count)
+	for i := range out {
+		record := data[6+6*i:]
+		out[i].FeatureIndex = binary.BigEndian.Uint16(record)
+		if int(out[i].FeatureIndex) >= len(features) {
+			return nil, fmt.Errorf("invalid feature index in substitution (%d >= %d)", out[i].FeatureIndex, len(features))
+		}
+		var err error
+		if out[i].Feature, err = parseFeature(data, binary.BigEndian.Uint32(record[2:]), features[out[i].FeatureIndex].Tag); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Match returns true if the normalized variation coordinates `coords`
+// satisfy the conditions of the feature variation. The missing coordinates
+// are taken as 0 (the default value of the axis).
+func (fv *FeatureVariation) Match(coords []float32) bool {
+	if fv.unsupported {
+		return false
+	}
+	for _, condition := range fv.Conditions {
+		var coord float32
+		if int(condition.Axis) < len(coords) {
+			coord = coords[condition.Axis]
+		}
+		if coord < condition.Min || coord > condition.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// FeatureSubstitutions returns the substitutions of the first feature
+// variation matching the normalized variation coordinates `coords`
+// (see Face.Variations), or nil if none of them match.
+func (l *Layout) FeatureSubstitutions(coords []float32) []FeatureSubstitution {
+	for i := range l.FeatureVariations {
+		if l.FeatureVariations[i].Match(coords) {
+			return l.FeatureVariations[i].Substitutions
+		}
+	}
+	return nil
+}
+
+// ActiveFeatures returns the features of the table, after applying
+// the substitutions selected by the normalized variation coordinates
+// `coords` (see Face.Variations). Features is returned, and not a copy,
+// when no substitution apply.
+func (l *Layout) ActiveFeatures(coords []float32) []Feature {
+	substitutions := l.FeatureSubstitutions(coords)
+	if len(substitutions) == 0 {
+		return l.Features
+	}
+	out := append([]Feature(nil), l.Features...)
+	for _, sub := range substitutions {
+		out[sub.FeatureIndex].LookupIndices = sub.Feature.LookupIndices
+	}
+	return out
+}
+
+// FindScript returns the script `tag`, or false if it is not supported.
+// Note that DefaultScript may be used for unsupported writing systems.
+func (l *Layout) FindScript(tag Tag) (*Script, bool) {
+	for i := range l.Scripts {
+		if l.Scripts[i].Tag == tag {
+			return &l.Scripts[i], true
+		}
+	}
+	return nil, false
+}
+
+// FindLanguage returns the language system `language` of the script `script`,
+// or its default language system if `language` is DefaultLanguage.
+// It returns false if the script or the language system are not found.
+func (l *Layout) FindLanguage(script, language Tag) (*LangSys, bool) {
+	s, ok := l.FindScript(script)
+	if !ok {
+		return nil, false
+	}
+	if language == DefaultLanguage {
+		return s.DefaultLanguage, s.DefaultLanguage != nil
+	}
+	for i := range s.Languages {
+		if s.Languages[i].Tag == language {
+			return &s.Languages[i], true
+		}
+	}
+	return nil, false
+}
+
+// ScriptTags returns the tags of the scripts of the table.
+func (l *Layout) ScriptTags() []Tag {
+	out := make([]Tag, len(l.Scripts))
+	for i, s := range l.Scripts {
+		out[i] = s.Tag
+	}
+	return out
+}
+
+// LanguageTags returns the tags of the language systems of the script,
+// starting with DefaultLanguage if the script has a default language system.
+// It returns nil if the script is not found.
+func (l *Layout) LanguageTags(script Tag) []Tag {
+	s, ok := l.FindScript(script)
+	if !ok {
+		return nil
+	}
+	out := make([]Tag, 0, len(s.Languages)+1)
+	if s.DefaultLanguage != nil {
+		out = append(out, DefaultLanguage)
+	}
+	for _, langSys := range s.Languages {
+		out = append(out, langSys.Tag)
+	}
+	return out
+}
+
+// FeatureTags returns the tags of the features of the language system
+// `language` of the script `script` (see FindLanguage), in the order of the table,
+// and the tag of its required feature, or 0 if it has none.
+// The features may be repeated, with different lookups. It returns false if the
+// script or the language system are not found.
+func (l *Layout) FeatureTags(script, language Tag) (features []Tag, required Tag, ok bool) {
+	langSys, ok := l.FindLanguage(script, language)
+	if !ok {
+		return nil, 0, false
+	}
+	if langSys.RequiredFeature != NoRequiredFeature {
+		required = l.Features[langSys.RequiredFeature].Tag
+	}
+	features = make([]Tag, 0, len(langSys.FeatureIndices))
+	for _, index := range langSys.FeatureIndices {
+		if int(index) < len(l.Features) {
+			features = append(features, l.Features[index].Tag)
+		}
+	}
+	return features, required, true
+}
+
+// lookupTable is a lookup of the 'GSUB' or 'GPOS' table, whose
+// subtables are parsed by the caller, according to the lookup type.
+type lookupTable struct {
+	kind             uint16 // the type of the extension subtables for extension lookups
+	flag             LookupFlag
+	markFilteringSet uint16
+	subtables        [][]byte // starting at each subtable
+}
+
+// parseLookupList parses the lookup list of the table `data`, resolving the
+// extension subtables, whose lookup type is `extensionKind`.
+func parseLookupList(data []byte, extensionKind uint16) ([]lookupTable, error) {
+	offset := uint32(binary.BigEndian.Uint16(data[8:])) // data has been checked by parseLayout
+	if offset == 0 {
+		return nil, nil
+	}
+	if int64(offset)+2 > int64(len(data)) {
+		return nil, errors.New("invalid lookup list (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+2*count {
+		return nil, errors.New("invalid lookup list (EOF)")
+	}
+	out := make([]lookupTable, count)
+	for i := range out {
+		var err error
+		if out[i], err = parseLookup(data, uint32(binary.BigEndian.Uint16(data[2+2*i:])), extensionKind); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func parseLookup(data []byte, offset uint32, extensionKind uint16) (lookupTable, error) {
+	if int64(offset)+6 > int64(len(data)) {
+		return lookupTable{}, errors.New("invalid lookup table (EOF)")
+	}
+	data = data[offset:]
+	out := lookupTable{
+		kind: binary.BigEndian.Uint16(data),
+		flag: LookupFlag(binary.BigEndian.Uint16(data[2:])),
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+2*count {
+		return lookupTable{}, errors.New("invalid lookup table (EOF)")
+	}
+	if out.flag&UseMarkFilteringSet != 0 {
+		if len(data) < 8+2*count {
+			return lookupTable{}, errors.New("invalid lookup table (EOF)")
+		}
+		out.markFilteringSet = binary.BigEndian.Uint16(data[6+2*count:])
+	}
+	out.subtables = make([][]byte, count)
+	for i := range out.subtables {
+		subtableOffset := int(binary.BigEndian.Uint16(data[6+2*i:]))
+		if len(data) < subtableOffset {
+			return lookupTable{}, errors.New("invalid lookup subtable (EOF)")
+		}
+		out.subtables[i] = data[subtableOffset:]
+	}
+
+	if out.kind != extensionKind {
+		return out, nil
+	}
+	// resolve the extension subtables, which must all have the same type
+	for i, subtable := range out.subtables {
+		if len(subtable) < 8 {
+			return lookupTable{}, errors.New("invalid extension subtable (EOF)")
+		}
+		kind := binary.BigEndian.Uint16(subtable[2:])
+		if kind == extensionKind || (i != 0 && kind != out.kind) {
+			return lookupTable{}, fmt.Errorf("invalid extension lookup type %d", kind)
+		}
+		out.kind = kind
+		extensionOffset := int64(binary.BigEndian.Uint32(subtable[4:]))
+		if int64(len(subtable)) < extensionOffset {
+			return lookupTable{}, errors.New("invalid extension subtable (EOF)")
+		}
+		out.subtables[i] = subtable[extensionOffset:]
+	}
+	return out, nil
+}
+
+// SequenceLookup is a lookup applied to a glyph of the
+// input sequence matched by a contextual subtable.
+type SequenceLookup struct {
+	SequenceIndex uint16 // index of the glyph in the input sequence
+	LookupIndex   uint16 // index in the lookups of the table
+}
+
+// SequenceRule is an input sequence matched by a SequenceContext.
+type SequenceRule struct {
+	// Input are the glyphs (format 1) or the classes (format 2) of the
+	// input sequence, after the first glyph, which is given by the coverage.
+	Input   []uint16
+	Lookups []SequenceLookup
+}
+
+// SequenceContext is a contextual subtable, applying lookups to the glyph sequences
+// it matches, described by :
+//   - format 1 : the glyphs of the sequence
+//   - format 2 : the classes of the glyphs of the sequence
+//   - format 3 : a coverage for each glyph of the sequence
+type SequenceContext struct {
+	Format uint16
+	// Coverage is the coverage of the first glyph (formats 1 and 2).
+	Coverage Coverage
+	// RuleSets are the rules indexed by the coverage index (format 1) or the
+	// class (format 2) of the first glyph. They are tried in order, and
+	// the first matching rule applies. A rule set may be empty.
+	RuleSets [][]SequenceRule
+	// ClassDef assigns the classes used by the rules (format 2).
+	ClassDef ClassDef
+	// Coverages are the coverages of the glyphs of the sequence (format 3).
+	Coverages []Coverage
+	// Lookups are the lookups applied to the matched sequence (format 3).
+	Lookups []SequenceLookup
+}
+
+// ChainedSequenceRule is a sequence matched by a ChainedSequenceContext,
+// made of an input sequence, and of the glyphs before (backtrack) and after it (lookahead).
+type ChainedSequenceRule struct {
+	// Backtrack are the glyphs (format 1) or the classes (format 2) preceding
+	// the input sequence, in reverse order (with the closest one first).
+	Backtrack []uint16
+	// Input are the glyphs (format 1) or the classes (format 2) of the
+	// input sequence, after the first glyph, which is given by the coverage.
+	Input []uint16
+	// Lookahead are the glyphs (format 1) or the classes (format 2)
+	// following the input sequence.
+	Lookahead []uint16
+	Lookups   []SequenceLookup
+}
+
+// ChainedSequenceContext is a chained contextual subtable, applying lookups
+// to the input sequences it matches, when preceded by a backtrack sequence,
+// and followed by a lookahead sequence. The sequences are described by :
+//   - format 1 : the glyphs of the sequences
+//   - format 2 : the classes of the glyphs of the sequences
+//   - format 3 : a coverage for each glyph of the sequences
+type ChainedSequenceContext struct {
+	Format uint16
+	// Coverage is the coverage of the first glyph of the input sequence (formats 1 and 2).
+	Coverage Coverage
+	// RuleSets are the rules indexed by the coverage index (format 1) or the
+	// class (format 2) of the first input glyph. They are tried in order, and
+	// the first matching rule applies. A rule set may be empty.
+	RuleSets [][]ChainedSequenceRule
+	// BacktrackClassDef, InputClassDef and LookaheadClassDef assign
+	// the classes used by the rules (format 2).
+	BacktrackClassDef, InputClassDef, LookaheadClassDef ClassDef
+	// BacktrackCoverages (in reverse order, with the closest glyph first), InputCoverages
+	// and LookaheadCoverages are the coverages of the glyphs of the sequences (format 3).
+	BacktrackCoverages, InputCoverages, LookaheadCoverages []Coverage
+	// Lookups are the lookups applied to the matched input sequence (format 3).
+	Lookups []SequenceLookup
+}
+
+// parseUint16s returns the `count` values starting at `data[offset:]`.
+func parseUint16s(data []byte, offset, count int) ([]uint16, error) {
+	if len(data) < offset+2*count {
+		return nil, errEOF
+	}
+	out := make([]uint16, count)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint16(data[offset+2*i:])
+	}
+	return out, nil
+}
+
+// parseInt16s returns the `count` signed values starting at `data[offset:]`.
+func parseInt16s(data []byte, offset, count int) ([]int16, error) {
+	if len(data) < offset+2*count {
+		return nil, errEOF
+	}
+	out := make([]int16, count)
+	for i := range out {
+		out[i] = int16(binary.BigEndian.Uint16(data[offset+2*i:]))
+	}
+	return out, nil
+}
+
+// parseGlyphs returns the `count` glyphs starting at `data[offset:]`.
+func parseGlyphs(data []byte, offset, count int) ([]GID, error) {
+	if len(data) < offset+2*count {
+		return nil, errEOF
+	}
+	out := make([]GID, count)
+	for i := range out {
+		out[i] = GID(binary.BigEndian.Uint16(data[offset+2*i:]))
+	}
+	return out, nil
+}
+
+// parseCoverages returns the coverages whose `count` offsets,
+// relative to `data`, start at `data[offset:]`.
+func parseCoverages(data []byte, offset, count int) ([]Coverage, error) {
+	if len(data) < offset+2*count {
+		return nil, errors.New("invalid coverage array (EOF)")
+	}
+	out := make([]Coverage, count)
+	for i := range out {
+		var err error
+		if out[i], err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[offset+2*i:]))); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// parseOptionalClassDef is like parseClassDef, but returns
+// an empty class definition for null offsets.
+func parseOptionalClassDef(data []byte, offset uint16) (ClassDef, error) {
+	if offset == 0 {
+		return classDefRanges{}, nil
+	}
+	return parseClassDef(data, uint32(offset))
+}
+
+// parseSequenceLookups returns the `count` lookup records starting at `data[offset:]`.
+func parseSequenceLookups(data []byte, offset, count int) ([]SequenceLookup, error) {
+	if len(data) < offset+4*count {
+		return nil, errors.New("invalid sequence lookup records (EOF)")
+	}
+	out := make([]SequenceLookup, count)
+	for i := range out {
+		out[i].SequenceIndex = binary.BigEndian.Uint16(data[offset+4*i:])
+		out[i].LookupIndex = binary.BigEndian.Uint16(data[offset+4*i+2:])
+	}
+	return out, nil
+}
+
+// parseRuleSets calls `parseRule` for the rules of the rule sets, whose `count`
+// offsets start at `data[offset:]`. Null offsets are empty rule sets.
+func parseRuleSets(data []byte, offset, count int, parseRule func(rule []byte) error) ([]int, error) {
+	if len(data) < offset+2*count {
+		return nil, errors.New("invalid rule sets (EOF)")
+	}
+	sizes := make([]int, count) // number of rules of each set
+	for i := range sizes {
+		setOffset := int(binary.BigEndian.Uint16(data[offset+2*i:]))
+		if setOffset == 0 {
+			continue
+		}
+		if len(data) < setOffset+2 {
+			return nil, errors.New("invalid rule set (EOF)")
+		}
+		set := data[setOffset:]
+		sizes[i] = int(binary.BigEndian.Uint16(set))
+		if len(set) < 2+2*sizes[i] {
+			return nil, errors.New("invalid rule set (EOF)")
+		}
+		for j := 0; j < sizes[i]; j++ {
+			ruleOffset := int(binary.BigEndian.Uint16(set[2+2*j:]))
+			if len(set) < ruleOffset {
+				return nil, errors.New("invalid rule (EOF)")
+			}
+			if err := parseRule(set[ruleOffset:]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sizes, nil
+}
+
+func parseSequenceContext(data []byte) (SequenceContext, error) {
+	if len(data) < 6 {
+		return SequenceContext{}, errors.New("invalid contextual subtable (EOF)")
+	}
+	out := SequenceContext{Format: binary.BigEndian.Uint16(data)}
+	var err error
+	switch out.Format {
+	case 1, 2:
+		if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+			return out, err
+		}
+		setsStart := 4
+		if out.Format == 2 {
+			if len(data) < 8 {
+				return out, errors.New("invalid contextual subtable (EOF)")
+			}
+			if out.ClassDef, err = parseOptionalClassDef(data, binary.BigEndian.Uint16(data[4:])); err != nil {
+				return out, err
+			}
+			setsStart = 6
+		}
+		var rules []SequenceRule
+		sizes, err := parseRuleSets(data, setsStart+2, int(binary.BigEndian.Uint16(data[setsStart:])), func(rule []byte) error {
+			if len(rule) < 4 {
+				return errors.New("invalid sequence rule (EOF)")
+			}
+			glyphCount, lookupCount := int(binary.BigEndian.Uint16(rule)), int(binary.BigEndian.Uint16(rule[2:]))
+			if glyphCount == 0 {
+				return errors.New("invalid empty sequence rule")
+			}
+			input, err := parseUint16s(rule, 4, glyphCount-1)
+			if err != nil {
+				return errors.New("invalid sequence rule (EOF)")
+			}
+			lookups, err := parseSequenceLookups(rule, 4+2*(glyphCount-1), lookupCount)
+			rules = append(rules, SequenceRule{Input: input, Lookups: lookups})
+			return err
+		})
+		if err != nil {
+			return out, err
+		}
+		out.RuleSets = make([][]SequenceRule, len(sizes))
+		for i, size := range sizes {
+			out.RuleSets[i], rules = rules[:size:size], rules[size:]
+		}
+	case 3:
+		glyphCount, lookupCount := int(binary.BigEndian.Uint16(data[2:])), int(binary.BigEndian.Uint16(data[4:]))
+		if out.Coverages, err = parseCoverages(data, 6, glyphCount); err != nil {
+			return out, err
+		}
+		if out.Lookups, err = parseSequenceLookups(data, 6+2*glyphCount, lookupCount); err != nil {
+			return out, err
+		}
+	default:
+		return out, fmt.Errorf("unsupported contextual subtable format %d", out.Format)
+	}
+	return out, nil
+}
+
+func parseChainedSequenceContext(data []byte) (ChainedSequenceContext, error) {
+	errTruncated := errors.New("invalid chained contextual subtable (EOF)")
+	if len(data) < 6 {
+		return ChainedSequenceContext{}, errTruncated
+	}
+	out := ChainedSequenceContext{Format: binary.BigEndian.Uint16(data)}
+	var err error
+	switch out.Format {
+	case 1, 2:
+		if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data[2:]))); err != nil {
+			return out, err
+		}
+		setsStart := 4
+		if out.Format == 2 {
+			if len(data) < 12 {
+				return out, errTruncated
+			}
+			if out.BacktrackClassDef, err = parseOptionalClassDef(data, binary.BigEndian.Uint16(data[4:])); err != nil {
+				return out, err
+			}
+			if out.InputClassDef, err = parseOptionalClassDef(data, binary.BigEndian.Uint16(data[6:])); err != nil {
+				return out, err
+			}
+			if out.LookaheadClassDef, err = parseOptionalClassDef(data, binary.BigEndian.Uint16(data[8:])); err != nil {
+				return out, err
+			}
+			setsStart = 10
+		}
+		var rules []ChainedSequenceRule
+		sizes, err := parseRuleSets(data, setsStart+2, int(binary.BigEndian.Uint16(data[setsStart:])), func(rule []byte) error {
+			errRule := errors.New("invalid chained sequence rule (EOF)")
+			var (
+				out ChainedSequenceRule
+				err error
+			)
+			if len(rule) < 2 {
+				return errRule
+			}
+			count := int(binary.BigEndian.Uint16(rule))
+			if out.Backtrack, err = parseUint16s(rule, 2, count); err != nil {
+				return errRule
+			}
+			rule = rule[2+2*count:]
+			if len(rule) < 2 {
+				return errRule
+			}
+			if count = int(binary.BigEndian.Uint16(rule)); count == 0 {
+				return errors.New("invalid empty chained sequence rule")
+			}
+			if out.Input, err = parseUint16s(rule, 2, count-1); err != nil {
+				return errRule
+			}
+			rule = rule[2+2*(count-1):]
+			if len(rule) < 2 {
+				return errRule
+			}
+			count = int(binary.BigEndian.Uint16(rule))
+			if out.Lookahead, err = parseUint16s(rule, 2, count); err != nil {
+				return errRule
+			}
+			rule = rule[2+2*count:]
+			if len(rule) < 2 {
+				return errRule
+			}
+			out.Lookups, err = parseSequenceLookups(rule, 2, int(binary.BigEndian.Uint16(rule)))
+			rules = append(rules, out)
+			return err
+		})
+		if err != nil {
+			return out, err
+		}
+		out.RuleSets = make([][]ChainedSequenceRule, len(sizes))
+		for i, size := range sizes {
+			out.RuleSets[i], rules = rules[:size:size], rules[size:]
+		}
+	case 3:
+		subtable := data
+		data = data[2:]
+		var coverages [3][]Coverage // backtrack, input, lookahead
+		for i := range coverages {
+			if len(data) < 2 {
+				return out, errTruncated
+			}
+			count := int(binary.BigEndian.Uint16(data))
+			start := len(subtable) - len(data) + 2
+			if coverages[i], err = parseCoverages(subtable, start, count); err != nil {
+				return out, err
+			}
+			data = data[2+2*count:]
+		}
+		out.BacktrackCoverages, out.InputCoverages, out.LookaheadCoverages = coverages[0], coverages[1], coverages[2]
+		if len(data) < 2 {
+			return out, errTruncated
+		}
+		if out.Lookups, err = parseSequenceLookups(data, 2, int(binary.BigEndian.Uint16(data))); err != nil {
+			return out, err
+		}
+	default:
+		return out, fmt.Errorf("unsupported chained contextual subtable format %d", out.Format)
+	}
+	return out, nil
+}
@@ -0,0 +1,600 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TableMATH stores the data used to lay out mathematical formulas.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/math
+type TableMATH struct {
+	// Constants are the font wide constants, indexed by MathConstant.
+	Constants [mathConstantCount]MathValue
+	GlyphInfo MathGlyphInfo
+	Variants  MathVariants
+}
+
+// MathValue is a value of the 'MATH' table, in font units, possibly
+// adjusted by a device table (see Face.ResolveMathValue).
+type MathValue struct {
+	Value int16
+
+	device deviceTable
+}
+
+// MathConstant identifies a font wide constant of the 'MATH' table.
+// The constants are in font units, unless stated otherwise.
+type MathConstant uint8
+
+const (
+	// MathScriptPercentScaleDown is the percentage of scaling down
+	// for the level 1 superscripts and subscripts.
+	MathScriptPercentScaleDown MathConstant = iota
+	// MathScriptScriptPercentScaleDown is the percentage of scaling
+	// down for the level 2 superscripts and subscripts.
+	MathScriptScriptPercentScaleDown
+	MathDelimitedSubFormulaMinHeight
+	MathDisplayOperatorMinHeight
+	MathLeading
+	MathAxisHeight
+	MathAccentBaseHeight
+	MathFlattenedAccentBaseHeight
+	MathSubscriptShiftDown
+	MathSubscriptTopMax
+	MathSubscriptBaselineDropMin
+	MathSuperscriptShiftUp
+	MathSuperscriptShiftUpCramped
+	MathSuperscriptBottomMin
+	MathSuperscriptBaselineDropMax
+	MathSubSuperscriptGapMin
+	MathSuperscriptBottomMaxWithSubscript
+	MathSpaceAfterScript
+	MathUpperLimitGapMin
+	MathUpperLimitBaselineRiseMin
+	MathLowerLimitGapMin
+	MathLowerLimitBaselineDropMin
+	MathStackTopShiftUp
+	MathStackTopDisplayStyleShiftUp
+	MathStackBottomShiftDown
+	MathStackBottomDisplayStyleShiftDown
+	MathStackGapMin
+	MathStackDisplayStyleGapMin
+	MathStretchStackTopShiftUp
+	MathStretchStackBottomShiftDown
+	MathStretchStackGapAboveMin
+	MathStretchStackGapBelowMin
+	MathFractionNumeratorShiftUp
+	MathFractionNumeratorDisplayStyleShiftUp
+	MathFractionDenominatorShiftDown
+	MathFractionDenominatorDisplayStyleShiftDown
+	MathFractionNumeratorGapMin
+	MathFractionNumDisplayStyleGapMin
+	MathFractionRuleThickness
+	MathFractionDenominatorGapMin
+	MathFractionDenomDisplayStyleGapMin
+	MathSkewedFractionHorizontalGap
+	MathSkewedFractionVerticalGap
+	MathOverbarVerticalGap
+	MathOverbarRuleThickness
+	MathOverbarExtraAscender
+	MathUnderbarVerticalGap
+	MathUnderbarRuleThickness
+	MathUnderbarExtraDescender
+	MathRadicalVerticalGap
+	MathRadicalDisplayStyleVerticalGap
+	MathRadicalRuleThickness
+	MathRadicalExtraAscender
+	MathRadicalKernBeforeDegree
+	MathRadicalKernAfterDegree
+	// MathRadicalDegreeBottomRaisePercent is the height of the bottom
+	// of the radical degree, as a percentage of the height of the radical sign.
+	MathRadicalDegreeBottomRaisePercent
+
+	mathConstantCount
+)
+
+// MathGlyphInfo stores the glyph specific data of the 'MATH' table.
+type MathGlyphInfo struct {
+	// ItalicsCorrections are the italic corrections of the glyphs,
+	// used to position the scripts.
+	ItalicsCorrections MathValues
+	// TopAccentAttachments are the horizontal positions where the
+	// accents are attached above the glyphs.
+	TopAccentAttachments MathValues
+	// ExtendedShapes are the glyphs which are extended shapes, such as
+	// the large operators. It is nil if missing.
+	ExtendedShapes Coverage
+	// KernInfo are the kerning values of the corners of the glyphs,
+	// used to position the scripts.
+	KernInfo MathKernInfo
+}
+
+// MathKernCorner identifies a corner of a glyph, where a script is attached.
+type MathKernCorner uint8
+
+const (
+	MathKernTopRight MathKernCorner = iota
+	MathKernTopLeft
+	MathKernBottomRight
+	MathKernBottomLeft
+)
+
+// MathKernInfo associates kerning values to the corners of some glyphs.
+type MathKernInfo struct {
+	Coverage Coverage // nil if missing
+	// Kerns are indexed by coverage index, then by MathKernCorner.
+	// They are nil for the corners without kerning.
+	Kerns [][4]*MathKern
+}
+
+// MathKern gives the kerning of a corner of a glyph, which depends on
+// the height of the script (see Face.MathKern) : KernValues[i] is used for the
+// heights between CorrectionHeights[i-1] and CorrectionHeights[i].
+type MathKern struct {
+	CorrectionHeights []MathValue // in increasing order
+	KernValues        []MathValue // len(CorrectionHeights) + 1 values
+}
+
+// MathValues associates values to some glyphs.
+type MathValues struct {
+	Coverage Coverage    // nil if missing
+	Values   []MathValue // indexed by coverage index
+}
+
+// Value returns the value of the glyph, or false if it is not covered.
+func (mv MathValues) Value(gid GID) (MathValue, bool) {
+	if mv.Coverage == nil {
+		return MathValue{}, false
+	}
+	index, ok := mv.Coverage.Index(gid)
+	if !ok || index >= len(mv.Values) {
+		return MathValue{}, false
+	}
+	return mv.Values[index], true
+}
+
+// MathVariants stores the larger variants of the glyphs, and the
+// assemblies used to build the glyphs stretched beyond them, such as
+// the delimiters, radicals and braces.
+type MathVariants struct {
+	// MinConnectorOverlap is the minimal overlap of the connectors
+	// of the consecutive parts of the assemblies.
+	MinConnectorOverlap uint16
+	// Vertical and Horizontal are the constructions of the glyphs
+	// growing in the vertical and horizontal directions.
+	Vertical, Horizontal MathConstructions
+}
+
+// MathConstructions associates larger variants to some glyphs.
+type MathConstructions struct {
+	Coverage      Coverage                // nil if missing
+	Constructions []MathGlyphConstruction // indexed by coverage index
+}
+
+// MathGlyphConstruction describes how to build larger versions of a glyph.
+type MathGlyphConstruction struct {
+	// Assembly is used when the largest variant is
+	// not large enough. It is nil if missing.
+	Assembly *GlyphAssembly
+	// Variants are sorted by increasing size.
+	Variants []MathGlyphVariant
+}
+
+// MathGlyphVariant is a larger version of a glyph.
+type MathGlyphVariant struct {
+	Glyph GID
+	// AdvanceMeasurement is the size of the glyph, in font units,
+	// along the direction of the construction.
+	AdvanceMeasurement uint16
+}
+
+// GlyphAssembly builds a stretched glyph by assembling parts,
+// which overlap along the direction of the construction.
+type GlyphAssembly struct {
+	// ItalicsCorrection is the italic correction of the
+	// assembled glyph (see Face.ResolveMathValue).
+	ItalicsCorrection MathValue
+	// Parts are in bottom to top (or left to right) order.
+	Parts []GlyphPart
+}
+
+// GlyphPart is a part of a GlyphAssembly. The lengths are in font units,
+// along the direction of the construction.
+type GlyphPart struct {
+	Glyph GID
+	// StartConnectorLength and EndConnectorLength are the lengths of the
+	// connectors, which may overlap the adjacent parts.
+	StartConnectorLength, EndConnectorLength uint16
+	FullAdvance                              uint16
+	// Extender is true for the parts which may be skipped,
+	// or repeated, to build assemblies of various sizes.
+	Extender bool
+}
+
+// Construction returns the construction of the glyph, growing in the
+// vertical direction (or the horizontal one if `vertical` is false),
+// or false if the glyph has no such construction.
+func (mv *MathVariants) Construction(gid GID, vertical bool) (*MathGlyphConstruction, bool) {
+	c := &mv.Horizontal
+	if vertical {
+		c = &mv.Vertical
+	}
+	if c.Coverage == nil {
+		return nil, false
+	}
+	index, ok := c.Coverage.Index(gid)
+	if !ok || index >= len(c.Constructions) {
+		return nil, false
+	}
+	return &c.Constructions[index], true
+}
+
+func parseTableMATH(data []byte) (TableMATH, error) {
+	if len(data) < 10 {
+		return TableMATH{}, errEOF
+	}
+	if major := binary.BigEndian.Uint16(data); major != 1 {
+		return TableMATH{}, fmt.Errorf("unsupported version %d", major)
+	}
+	var (
+		out TableMATH
+		err error
+	)
+	if offset := binary.BigEndian.Uint16(data[4:]); offset != 0 {
+		if out.Constants, err = parseMathConstants(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint16(data[6:]); offset != 0 {
+		if out.GlyphInfo, err = parseMathGlyphInfo(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint16(data[8:]); offset != 0 {
+		if out.Variants, err = parseMathVariants(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseMathConstants(data []byte, offset uint32) ([mathConstantCount]MathValue, error) {
+	var out [mathConstantCount]MathValue
+	const size = 8 + 4*int(MathRadicalDegreeBottomRaisePercent-MathLeading) + 2
+	if int64(offset)+int64(size) > int64(len(data)) {
+		return out, errors.New("invalid math constants (EOF)")
+	}
+	data = data[offset:]
+	for c := MathScriptPercentScaleDown; c < MathLeading; c++ {
+		out[c].Value = int16(binary.BigEndian.Uint16(data[2*c:]))
+	}
+	for c := MathLeading; c < MathRadicalDegreeBottomRaisePercent; c++ {
+		var err error
+		if out[c], err = parseMathValue(data, 8+4*int(c-MathLeading)); err != nil {
+			return out, err
+		}
+	}
+	out[MathRadicalDegreeBottomRaisePercent].Value = int16(binary.BigEndian.Uint16(data[size-2:]))
+	return out, nil
+}
+
+// parseMathValue parses the value record at `data[offset:]`, whose
+// device offset is relative to `data`.
+func parseMathValue(data []byte, offset int) (MathValue, error) {
+	if len(data) < offset+4 {
+		return MathValue{}, errors.New("invalid math value record (EOF)")
+	}
+	out := MathValue{Value: int16(binary.BigEndian.Uint16(data[offset:]))}
+	if deviceOffset := binary.BigEndian.Uint16(data[offset+2:]); deviceOffset != 0 {
+		var err error
+		if out.device, err = parseDeviceTable(data, uint32(deviceOffset)); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseMathGlyphInfo(data []byte, offset uint32) (MathGlyphInfo, error) {
+	if int64(offset)+8 > int64(len(data)) {
+		return MathGlyphInfo{}, errors.New("invalid math glyph info (EOF)")
+	}
+	data = data[offset:]
+	var (
+		out MathGlyphInfo
+		err error
+	)
+	if out.ItalicsCorrections, err = parseMathValues(data, binary.BigEndian.Uint16(data)); err != nil {
+		return out, err
+	}
+	if out.TopAccentAttachments, err = parseMathValues(data, binary.BigEndian.Uint16(data[2:])); err != nil {
+		return out, err
+	}
+	if offset := binary.BigEndian.Uint16(data[4:]); offset != 0 {
+		if out.ExtendedShapes, err = parseCoverage(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	if offset := binary.BigEndian.Uint16(data[6:]); offset != 0 {
+		if out.KernInfo, err = parseMathKernInfo(data, uint32(offset)); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseMathKernInfo(data []byte, offset uint32) (MathKernInfo, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return MathKernInfo{}, errors.New("invalid math kern info (EOF)")
+	}
+	data = data[offset:]
+	var (
+		out MathKernInfo
+		err error
+	)
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data))); err != nil {
+		return out, err
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+8*count {
+		return out, errors.New("invalid math kern info (EOF)")
+	}
+	out.Kerns = make([][4]*MathKern, count)
+	for i := range out.Kerns {
+		for corner := range out.Kerns[i] {
+			kernOffset := int(binary.BigEndian.Uint16(data[4+8*i+2*corner:]))
+			if kernOffset == 0 {
+				continue
+			}
+			if out.Kerns[i][corner], err = parseMathKern(data, kernOffset); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseMathKern(data []byte, offset int) (*MathKern, error) {
+	if len(data) < offset+2 {
+		return nil, errors.New("invalid math kern table (EOF)")
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data))
+	out := MathKern{CorrectionHeights: make([]MathValue, count), KernValues: make([]MathValue, count+1)}
+	var err error
+	for i := range out.CorrectionHeights {
+		if out.CorrectionHeights[i], err = parseMathValue(data, 2+4*i); err != nil {
+			return nil, err
+		}
+	}
+	for i := range out.KernValues {
+		if out.KernValues[i], err = parseMathValue(data, 2+4*(count+i)); err != nil {
+			return nil, err
+		}
+	}
+	return &out, nil
+}
+
+// parseMathValues parses the italics correction and top accent attachment
+// tables, which share the same structure. Null offsets are empty tables.
+func parseMathValues(data []byte, offset uint16) (MathValues, error) {
+	if offset == 0 {
+		return MathValues{}, nil
+	}
+	if len(data) < int(offset)+4 {
+		return MathValues{}, errors.New("invalid math values (EOF)")
+	}
+	data = data[offset:]
+	var (
+		out MathValues
+		err error
+	)
+	if out.Coverage, err = parseCoverage(data, uint32(binary.BigEndian.Uint16(data))); err != nil {
+		return out, err
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	out.Values = make([]MathValue, count)
+	for i := range out.Values {
+		if out.Values[i], err = parseMathValue(data, 4+4*i); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseMathVariants(data []byte, offset uint32) (MathVariants, error) {
+	errTruncated := errors.New("invalid math variants (EOF)")
+	if int64(offset)+10 > int64(len(data)) {
+		return MathVariants{}, errTruncated
+	}
+	data = data[offset:]
+	out := MathVariants{MinConnectorOverlap: binary.BigEndian.Uint16(data)}
+	vertCount, horizCount := int(binary.BigEndian.Uint16(data[6:])), int(binary.BigEndian.Uint16(data[8:]))
+	if len(data) < 10+2*(vertCount+horizCount) {
+		return out, errTruncated
+	}
+	offsets := data[10:]
+	for i, c := range [2]*MathConstructions{&out.Vertical, &out.Horizontal} {
+		count := vertCount
+		if i == 1 {
+			count, offsets = horizCount, offsets[2*vertCount:]
+		}
+		coverageOffset := binary.BigEndian.Uint16(data[2+2*i:])
+		if coverageOffset == 0 {
+			continue
+		}
+		var err error
+		if c.Coverage, err = parseCoverage(data, uint32(coverageOffset)); err != nil {
+			return out, err
+		}
+		c.Constructions = make([]MathGlyphConstruction, count)
+		for j := range c.Constructions {
+			if c.Constructions[j], err = parseMathGlyphConstruction(data, binary.BigEndian.Uint16(offsets[2*j:])); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseMathGlyphConstruction(data []byte, offset uint16) (MathGlyphConstruction, error) {
+	errTruncated := errors.New("invalid math glyph construction (EOF)")
+	if len(data) < int(offset)+4 {
+		return MathGlyphConstruction{}, errTruncated
+	}
+	data = data[offset:]
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+4*count {
+		return MathGlyphConstruction{}, errTruncated
+	}
+	out := MathGlyphConstruction{Variants: make([]MathGlyphVariant, count)}
+	for i := range out.Variants {
+		record := data[4+4*i:]
+		out.Variants[i] = MathGlyphVariant{
+			Glyph:              GID(binary.BigEndian.Uint16(record)),
+			AdvanceMeasurement: binary.BigEndian.Uint16(record[2:]),
+		}
+	}
+	if assemblyOffset := int(binary.BigEndian.Uint16(data)); assemblyOffset != 0 {
+		if len(data) < assemblyOffset+6 {
+			return out, errors.New("invalid glyph assembly (EOF)")
+		}
+		assemblyData := data[assemblyOffset:]
+		var (
+			assembly GlyphAssembly
+			err      error
+		)
+		if assembly.ItalicsCorrection, err = parseMathValue(assemblyData, 0); err != nil {
+			return out, err
+		}
+		count := int(binary.BigEndian.Uint16(assemblyData[4:]))
+		if len(assemblyData) < 6+10*count {
+			return out, errors.New("invalid glyph assembly (EOF)")
+		}
+		assembly.Parts = make([]GlyphPart, count)
+		for i := range assembly.Parts {
+			record := assemblyData[6+10*i:]
+			assembly.Parts[i] = GlyphPart{
+				Glyph:                GID(binary.BigEndian.Uint16(record)),
+				StartConnectorLength: binary.BigEndian.Uint16(record[2:]),
+				EndConnectorLength:   binary.BigEndian.Uint16(record[4:]),
+				FullAdvance:          binary.BigEndian.Uint16(record[6:]),
+				Extender:             binary.BigEndian.Uint16(record[8:])&1 != 0,
+			}
+		}
+		out.Assembly = &assembly
+	}
+	return out, nil
+}
+
+// mathTable is the lazily parsed 'MATH' table.
+type mathTable struct {
+	once  sync.Once
+	table *TableMATH // nil if missing or invalid
+}
+
+// MATH returns the mathematical typesetting table, parsed on first use,
+// or false if the font has no 'MATH' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) MATH() (*TableMATH, bool) {
+	face.math.once.Do(func() {
+		if data, err := face.GetRawTable(tagMATH); err == nil {
+			if table, err := parseTableMATH(data); err != nil {
+				face.warnings.addError(face.tableError(tagMATH, err))
+			} else {
+				face.math.table = &table
+			}
+		}
+	})
+	return face.math.table, face.math.table != nil
+}
+
+// ResolveMathValue returns the value, in font units, adjusted by its device
+// table : the variations of variable fonts (see SetVariations), stored
+// in the 'GDEF' table, and, when `ppem` is not zero, the hinting adjustments
+// for this size.
+func (face *Face) ResolveMathValue(v MathValue, ppem uint16) float32 {
+	return float32(v.Value) + face.deviceDelta(face.gdefStore(), v.device, ppem)
+}
+
+// MathConstant returns the constant `c` of the 'MATH' table (see ResolveMathValue),
+// or 0 if the font has no 'MATH' table. The percentages are returned as is.
+func (face *Face) MathConstant(c MathConstant, ppem uint16) float32 {
+	table, ok := face.MATH()
+	if !ok || c >= mathConstantCount {
+		return 0
+	}
+	return face.ResolveMathValue(table.Constants[c], ppem)
+}
+
+// MathItalicCorrection returns the italic correction of the glyph
+// (see ResolveMathValue), or 0 if it has none.
+func (face *Face) MathItalicCorrection(gid GID, ppem uint16) float32 {
+	table, ok := face.MATH()
+	if !ok {
+		return 0
+	}
+	v, _ := table.GlyphInfo.ItalicsCorrections.Value(gid)
+	return face.ResolveMathValue(v, ppem)
+}
+
+// MathTopAccentAttachment returns the horizontal position where the
+// accents are attached above the glyph (see ResolveMathValue),
+// or false if it is not provided by the font, in which case the accents
+// are usually centered.
+func (face *Face) MathTopAccentAttachment(gid GID, ppem uint16) (float32, bool) {
+	table, ok := face.MATH()
+	if !ok {
+		return 0, false
+	}
+	v, ok := table.GlyphInfo.TopAccentAttachments.Value(gid)
+	if !ok {
+		return 0, false
+	}
+	return face.ResolveMathValue(v, ppem), true
+}
+
+// IsMathExtendedShape returns true if the glyph is an extended
+// shape, as given by the 'MATH' table.
+func (face *Face) IsMathExtendedShape(gid GID) bool {
+	table, ok := face.MATH()
+	if !ok || table.GlyphInfo.ExtendedShapes == nil {
+		return false
+	}
+	_, ok = table.GlyphInfo.ExtendedShapes.Index(gid)
+	return ok
+}
+
+// MathConstruction returns the larger variants of the glyph, and the assembly
+// used to stretch it further, growing in the vertical direction (or the
+// horizontal one if `vertical` is false), or false if the glyph can't be stretched.
+func (face *Face) MathConstruction(gid GID, vertical bool) (*MathGlyphConstruction, bool) {
+	table, ok := face.MATH()
+	if !ok {
+		return nil, false
+	}
+	return table.Variants.Construction(gid, vertical)
+}
+
+// MathKern returns the kerning of the corner `corner` of the glyph, in font
+// units, for a script placed at the height `correctionHeight` (see ResolveMathValue
+// for `ppem`), or 0 if the glyph has no kerning for this corner. The kerning value
+// used is the one following the correction heights strictly lower than `correctionHeight`.
+func (face *Face) MathKern(gid GID, corner MathKernCorner, correctionHeight float32, ppem uint16) float32 {
+	table, ok := face.MATH()
+	if !ok || table.GlyphInfo.KernInfo.Coverage == nil || corner > MathKernBottomLeft {
+		return 0
+	}
+	info := table.GlyphInfo.KernInfo
+	index, ok := info.Coverage.Index(gid)
+	if !ok || index >= len(info.Kerns) || info.Kerns[index][corner] == nil {
+		return 0
+	}
+	kern := info.Kerns[index][corner]
+	i := sort.Search(len(kern.CorrectionHeights), func(i int) bool {
+		return face.ResolveMathValue(kern.CorrectionHeights[i], ppem) >= correctionHeight
+	})
+	return face.ResolveMathValue(kern.KernValues[i], ppem)
+}
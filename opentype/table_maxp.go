@@ -0,0 +1,11 @@
+package opentype
+
+import "encoding/binary"
+
+// parseTableMaxp only returns the number of glyphs.
+func parseTableMaxp(data []byte) (int, error) {
+	if len(data) < 6 {
+		return 0, errEOF
+	}
+	return int(binary.BigEndian.Uint16(data[4:])), nil
+}
@@ -0,0 +1,94 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+)
+
+var (
+	// tagDlng is the 'meta' entry listing the languages the font is designed for
+	tagDlng = MustNewTag("dlng")
+	// tagSlng is the 'meta' entry listing the languages the font supports
+	tagSlng = MustNewTag("slng")
+)
+
+// TableMeta stores the metadata of the font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/meta
+type TableMeta struct {
+	// DesignLanguages are the ScriptLangTags (such as "Hant" or "zh-Hant-HK")
+	// of the languages the font is primarily designed for,
+	// from the 'dlng' entry. It is nil if not provided.
+	DesignLanguages []string
+	// SupportedLanguages are the ScriptLangTags of the languages the font is
+	// able to render, from the 'slng' entry. It is nil if not provided.
+	SupportedLanguages []string
+	// Entries stores the raw data of all the entries, by tag.
+	Entries map[Tag][]byte
+}
+
+func parseTableMeta(data []byte) (TableMeta, error) {
+	if len(data) < 16 {
+		return TableMeta{}, errEOF
+	}
+	if version := binary.BigEndian.Uint32(data); version != 1 {
+		return TableMeta{}, errors.New("unsupported version")
+	}
+	count := int(binary.BigEndian.Uint32(data[12:]))
+	if len(data) < 16+12*count {
+		return TableMeta{}, errors.New("invalid data maps (EOF)")
+	}
+	out := TableMeta{Entries: make(map[Tag][]byte, count)}
+	for i := 0; i < count; i++ {
+		record := data[16+12*i:]
+		tag := Tag(binary.BigEndian.Uint32(record))
+		offset, length := uint64(binary.BigEndian.Uint32(record[4:])), uint64(binary.BigEndian.Uint32(record[8:]))
+		if offset+length > uint64(len(data)) {
+			return TableMeta{}, errors.New("invalid data map (EOF)")
+		}
+		entry := data[offset : offset+length]
+		out.Entries[tag] = entry
+		switch tag {
+		case tagDlng:
+			out.DesignLanguages = parseScriptLangTags(entry)
+		case tagSlng:
+			out.SupportedLanguages = parseScriptLangTags(entry)
+		}
+	}
+	return out, nil
+}
+
+// parseScriptLangTags splits the comma separated list `data`,
+// trimming the white spaces and dropping the empty tags.
+func parseScriptLangTags(data []byte) []string {
+	out := []string{}
+	for _, tag := range strings.Split(string(data), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// meta stores the 'meta' table, loaded on first use.
+type meta struct {
+	once  sync.Once
+	table *TableMeta // nil if missing or invalid
+}
+
+// Meta returns the metadata table, parsed on first use,
+// or false if the font has no 'meta' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Meta() (*TableMeta, bool) {
+	face.meta.once.Do(func() {
+		if data, err := face.GetRawTable(tagMeta); err == nil {
+			if table, err := parseTableMeta(data); err != nil {
+				face.warnings.addError(face.tableError(tagMeta, err))
+			} else {
+				face.meta.table = &table
+			}
+		}
+	})
+	return face.meta.table, face.meta.table != nil
+}
@@ -0,0 +1,448 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TableMorx stores the glyph transformations of the AAT fonts,
+// which play the role of the 'GSUB' table.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6morx.html
+type TableMorx struct {
+	Chains []MorxChain
+}
+
+// MorxChain is a list of subtables, applied in order,
+// which are enabled by the selected features (see Flags).
+type MorxChain struct {
+	DefaultFlags uint32
+	Features     []MorxFeature
+	Subtables    []MorxSubtable
+}
+
+// AATFeature is a feature setting of the AAT tables, identified by the
+// type of the feature and the selector of the setting (see the 'feat' table).
+type AATFeature struct {
+	Type, Setting uint16
+}
+
+// MorxFeature gives the subtables enabled and disabled by a feature setting.
+type MorxFeature struct {
+	AATFeature
+	EnableFlags, DisableFlags uint32
+}
+
+// Flags returns the flags of the chain for the selected `features`, starting
+// from its default flags. The subtables to apply are the ones whose Flags
+// intersect the result. As in other implementations, the deprecated small caps
+// setting of the letter case feature (type 3, setting 3) is matched by the
+// small caps setting of the lower case feature (type 37, setting 1).
+func (c *MorxChain) Flags(features []AATFeature) uint32 {
+	selected := func(feature AATFeature) bool {
+		for _, f := range features {
+			if f == feature {
+				return true
+			}
+		}
+		return false
+	}
+	flags := c.DefaultFlags
+	for _, feature := range c.Features {
+		if selected(feature.AATFeature) ||
+			(feature.AATFeature == AATFeature{3, 3} && selected(AATFeature{37, 1})) {
+			flags &= feature.DisableFlags
+			flags |= feature.EnableFlags
+		}
+	}
+	return flags
+}
+
+// MorxSubtable is a subtable of a MorxChain.
+type MorxSubtable struct {
+	// Vertical is true if the subtable only applies to vertical text,
+	// and false if it only applies to horizontal text.
+	Vertical bool
+	// AllDirections is true if the subtable applies to both
+	// horizontal and vertical text, Vertical being then ignored.
+	AllDirections bool
+	// Backwards is true if the glyphs are processed in descending order, which is
+	// relative to the logical order if LogicalOrder is true, and to the layout
+	// order otherwise.
+	Backwards    bool
+	LogicalOrder bool
+	// Flags identifies the features enabling the subtable (see MorxChain.Flags).
+	Flags uint32
+
+	Data MorxData
+}
+
+// MorxData is the content of a glyph transformation subtable. It is one of
+// MorxRearrangement, MorxContextual, MorxLigature, MorxNoncontextual
+// or MorxInsertion.
+type MorxData interface {
+	isMorxData()
+}
+
+func (MorxRearrangement) isMorxData() {}
+func (MorxContextual) isMorxData()    {}
+func (MorxLigature) isMorxData()      {}
+func (MorxNoncontextual) isMorxData() {}
+func (MorxInsertion) isMorxData()     {}
+
+// The flags of the entries of the state machines of the 'morx' table.
+const (
+	// MorxDontAdvance processes the current glyph again, in the new state.
+	MorxDontAdvance = 0x4000
+	// MorxSetMark marks the current glyph (MorxContextual and MorxInsertion).
+	MorxSetMark = 0x8000
+	// MorxMarkFirst marks the current glyph as the
+	// first one of the range to rearrange (MorxRearrangement).
+	MorxMarkFirst = 0x8000
+	// MorxMarkLast marks the current glyph as the
+	// last one of the range to rearrange (MorxRearrangement).
+	MorxMarkLast = 0x2000
+	// MorxVerb is the mask of the rearrangement
+	// of the marked range (MorxRearrangement).
+	MorxVerb = 0x000F
+	// MorxSetComponent pushes the current glyph
+	// on the component stack (MorxLigature).
+	MorxSetComponent = 0x8000
+	// MorxPerformAction performs the ligature actions
+	// of the entry (MorxLigature).
+	MorxPerformAction = 0x2000
+	// MorxCurrentIsKashidaLike is set for kashida-like insertions at the current
+	// glyph, and cleared for split vowel-like insertions (MorxInsertion).
+	MorxCurrentIsKashidaLike = 0x2000
+	// MorxMarkedIsKashidaLike is the same as MorxCurrentIsKashidaLike,
+	// for the insertions at the marked glyph (MorxInsertion).
+	MorxMarkedIsKashidaLike = 0x1000
+	// MorxCurrentInsertBefore inserts the glyphs before
+	// the current glyph, instead of after it (MorxInsertion).
+	MorxCurrentInsertBefore = 0x0800
+	// MorxMarkedInsertBefore inserts the glyphs before
+	// the marked glyph, instead of after it (MorxInsertion).
+	MorxMarkedInsertBefore = 0x0400
+	// MorxCurrentInsertCount is the mask of the number of glyphs,
+	// shifted by 5 bits, inserted at the current glyph (MorxInsertion).
+	MorxCurrentInsertCount = 0x03E0
+	// MorxMarkedInsertCount is the mask of the number of glyphs
+	// inserted at the marked glyph (MorxInsertion).
+	MorxMarkedInsertCount = 0x001F
+)
+
+// MorxNoIndex is the index of the entries without substitution,
+// ligature action or insertion.
+const MorxNoIndex = 0xFFFF
+
+// MorxRearrangement reorders the glyphs of a range, marked by a state machine.
+// The verb of the entries (see MorxVerb) gives the rearrangement, applied to
+// the first (A, B) and last (C, D) glyphs of the range (x) :
+//
+//	0: no change	4: xA => Ax	8: AxCD => CDxA	12: ABxC => CxBA
+//	1: Ax => xA	5: ABx => xAB	9: AxCD => DCxA	13: ABxC => CxAB
+//	2: xD => Dx	6: xCD => CDx	10: ABxD => DxAB	14: ABxCD => CDxAB
+//	3: AxD => DxA	7: xCD => DCx	11: ABxD => DxBA	15: ABxCD => CDxBA
+type MorxRearrangement struct {
+	Machine AATStateTable
+}
+
+// MorxContextual substitutes the marked and current glyphs, according to a
+// state machine. The values following the flags of the entries (AATStateEntry.Data)
+// are the indices in Substitutions of the substitutions of the marked and current
+// glyphs, or MorxNoIndex.
+type MorxContextual struct {
+	Machine AATStateTable
+	// Substitutions map the glyphs to their substitute,
+	// as the classes of the ClassDef.
+	Substitutions []ClassDef
+}
+
+// Substitute returns the substitute of the glyph, given by the substitution
+// `index`, or false if the glyph is not substituted.
+func (mc *MorxContextual) Substitute(index uint16, gid GID) (GID, bool) {
+	if int(index) >= len(mc.Substitutions) {
+		return 0, false
+	}
+	substitute, ok := mc.Substitutions[index].Class(gid)
+	return GID(substitute), ok
+}
+
+// MorxLigature replaces sequences of glyphs by ligatures, according to a state
+// machine pushing the components on a stack. The value following the flags of
+// the entries performing actions (see MorxPerformAction) is the index of their
+// first action (see Actions).
+//
+// The actions are applied to the glyphs popped from the stack : the glyph plus
+// the offset of the action is the index of a value in the component table
+// (see Component). The sum of the values of the popped glyphs is the index of
+// the ligature (see Ligature), which is stored for the actions
+// with the Store or Last flags.
+type MorxLigature struct {
+	Machine AATStateTable
+
+	actions, components, ligatures []byte // up to the end of the subtable
+}
+
+// MorxLigatureAction is an action of a MorxLigature subtable.
+type MorxLigatureAction uint32
+
+// Last is true for the last action of the list,
+// which also stores the ligature.
+func (a MorxLigatureAction) Last() bool { return a&0x80000000 != 0 }
+
+// Store is true if the ligature is stored in
+// place of the popped glyph.
+func (a MorxLigatureAction) Store() bool { return a&0x40000000 != 0 }
+
+// Offset returns the offset added to the popped glyph,
+// giving an index in the component table.
+func (a MorxLigatureAction) Offset() int32 { return int32(a<<2) >> 2 }
+
+// Actions returns the list of actions starting at `index`,
+// up to the one marked as last.
+func (ml *MorxLigature) Actions(index uint16) []MorxLigatureAction {
+	var out []MorxLigatureAction
+	for i := 4 * int(index); i+4 <= len(ml.actions); i += 4 {
+		action := MorxLigatureAction(binary.BigEndian.Uint32(ml.actions[i:]))
+		out = append(out, action)
+		if action.Last() {
+			break
+		}
+	}
+	return out
+}
+
+// Component returns the value of the component table at `index`,
+// or false if it is out of bounds.
+func (ml *MorxLigature) Component(index int) (uint16, bool) {
+	if index < 0 || 2*index+2 > len(ml.components) {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(ml.components[2*index:]), true
+}
+
+// Ligature returns the ligature at `index`, or false if it is out of bounds.
+func (ml *MorxLigature) Ligature(index int) (GID, bool) {
+	if index < 0 || 2*index+2 > len(ml.ligatures) {
+		return 0, false
+	}
+	return GID(binary.BigEndian.Uint16(ml.ligatures[2*index:])), true
+}
+
+// MorxNoncontextual substitutes the glyphs, whatever their context.
+type MorxNoncontextual struct {
+	// Substitutions maps the glyphs to their substitute,
+	// as the classes of the ClassDef.
+	Substitutions ClassDef
+}
+
+// Substitute returns the substitute of the glyph,
+// or false if it is not substituted.
+func (mn *MorxNoncontextual) Substitute(gid GID) (GID, bool) {
+	substitute, ok := mn.Substitutions.Class(gid)
+	return GID(substitute), ok
+}
+
+// MorxInsertion inserts glyphs at the marked and current glyphs, according to
+// a state machine. The values following the flags of the entries (AATStateEntry.Data)
+// are the indices in Glyphs of the glyphs inserted at the current and marked
+// glyphs, or MorxNoIndex, their number being given by the flags (see
+// MorxCurrentInsertCount and MorxMarkedInsertCount).
+type MorxInsertion struct {
+	Machine AATStateTable
+	Glyphs  []GID
+}
+
+func parseTableMorx(data []byte, numGlyphs int) (TableMorx, error) {
+	if len(data) < 8 {
+		return TableMorx{}, errEOF
+	}
+	if version := binary.BigEndian.Uint16(data); version != 2 && version != 3 {
+		return TableMorx{}, fmt.Errorf("unsupported version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[4:]))
+	var out TableMorx
+	data = data[8:]
+	for i := 0; i < count; i++ {
+		if len(data) < 16 {
+			return out, errors.New("invalid transformation chain (EOF)")
+		}
+		length := int64(binary.BigEndian.Uint32(data[4:]))
+		if length < 16 || int64(len(data)) < length {
+			return out, errors.New("invalid transformation chain (EOF)")
+		}
+		chain, err := parseMorxChain(data[:length], numGlyphs)
+		if err != nil {
+			return out, err
+		}
+		out.Chains = append(out.Chains, chain)
+		data = data[length:]
+	}
+	return out, nil
+}
+
+func parseMorxChain(data []byte, numGlyphs int) (MorxChain, error) {
+	out := MorxChain{DefaultFlags: binary.BigEndian.Uint32(data)}
+	featureCount := int64(binary.BigEndian.Uint32(data[8:]))
+	count := int(binary.BigEndian.Uint32(data[12:]))
+	if int64(len(data)) < 16+12*featureCount {
+		return out, errors.New("invalid transformation chain (EOF)")
+	}
+	out.Features = make([]MorxFeature, featureCount)
+	for i := range out.Features {
+		feature := data[16+12*i:]
+		out.Features[i] = MorxFeature{
+			AATFeature:   AATFeature{Type: binary.BigEndian.Uint16(feature), Setting: binary.BigEndian.Uint16(feature[2:])},
+			EnableFlags:  binary.BigEndian.Uint32(feature[4:]),
+			DisableFlags: binary.BigEndian.Uint32(feature[8:]),
+		}
+	}
+	data = data[16+12*featureCount:]
+	for i := 0; i < count; i++ {
+		if len(data) < 12 {
+			return out, errors.New("invalid transformation subtable (EOF)")
+		}
+		length := int64(binary.BigEndian.Uint32(data))
+		if length < 12 || int64(len(data)) < length {
+			return out, errors.New("invalid transformation subtable (EOF)")
+		}
+		coverage := binary.BigEndian.Uint32(data[4:])
+		subtable := MorxSubtable{
+			Vertical:      coverage&0x80000000 != 0,
+			Backwards:     coverage&0x40000000 != 0,
+			AllDirections: coverage&0x20000000 != 0,
+			LogicalOrder:  coverage&0x10000000 != 0,
+			Flags:         binary.BigEndian.Uint32(data[8:]),
+		}
+		var err error
+		if subtable.Data, err = parseMorxData(data[12:length], uint8(coverage), numGlyphs); err != nil {
+			return out, fmt.Errorf("transformation subtable %d: %s", i, err)
+		}
+		out.Subtables = append(out.Subtables, subtable)
+		data = data[length:]
+	}
+	return out, nil
+}
+
+// parseMorxData parses the subtable `data`, starting after its header.
+func parseMorxData(data []byte, kind uint8, numGlyphs int) (MorxData, error) {
+	switch kind {
+	case 0:
+		machine, err := parseAATStateTable(data, true, 0, numGlyphs)
+		return MorxRearrangement{Machine: machine}, err
+	case 1:
+		return parseMorxContextual(data, numGlyphs)
+	case 2:
+		return parseMorxLigature(data, numGlyphs)
+	case 4:
+		substitutions, err := parseAATLookup(data, 0, numGlyphs)
+		return MorxNoncontextual{Substitutions: substitutions}, err
+	case 5:
+		return parseMorxInsertion(data, numGlyphs)
+	default:
+		return nil, fmt.Errorf("unsupported transformation subtable type %d", kind)
+	}
+}
+
+func parseMorxContextual(data []byte, numGlyphs int) (MorxContextual, error) {
+	machine, err := parseAATStateTable(data, true, 2, numGlyphs)
+	if err != nil {
+		return MorxContextual{}, err
+	}
+	if len(data) < 20 {
+		return MorxContextual{}, errors.New("invalid contextual substitution (EOF)")
+	}
+	// the number of substitutions is deduced from the entries
+	count := 0
+	for _, entry := range machine.Entries {
+		for _, index := range entry.Data {
+			if index != MorxNoIndex && int(index) >= count {
+				count = int(index) + 1
+			}
+		}
+	}
+	offset := int64(binary.BigEndian.Uint32(data[16:]))
+	if int64(len(data)) < offset+4*int64(count) {
+		return MorxContextual{}, errors.New("invalid contextual substitution (EOF)")
+	}
+	offsets := data[offset:]
+	out := MorxContextual{Machine: machine, Substitutions: make([]ClassDef, count)}
+	for i := range out.Substitutions {
+		if out.Substitutions[i], err = parseAATLookup(offsets, binary.BigEndian.Uint32(offsets[4*i:]), numGlyphs); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseMorxLigature(data []byte, numGlyphs int) (MorxLigature, error) {
+	machine, err := parseAATStateTable(data, true, 1, numGlyphs)
+	if err != nil {
+		return MorxLigature{}, err
+	}
+	if len(data) < 28 {
+		return MorxLigature{}, errors.New("invalid ligature subtable (EOF)")
+	}
+	out := MorxLigature{Machine: machine}
+	for i, array := range [3]*[]byte{&out.actions, &out.components, &out.ligatures} {
+		offset := binary.BigEndian.Uint32(data[16+4*i:])
+		if int64(offset) > int64(len(data)) {
+			return out, errors.New("invalid ligature subtable (EOF)")
+		}
+		*array = data[offset:]
+	}
+	return out, nil
+}
+
+func parseMorxInsertion(data []byte, numGlyphs int) (MorxInsertion, error) {
+	machine, err := parseAATStateTable(data, true, 2, numGlyphs)
+	if err != nil {
+		return MorxInsertion{}, err
+	}
+	if len(data) < 20 {
+		return MorxInsertion{}, errors.New("invalid insertion subtable (EOF)")
+	}
+	// the number of glyphs is deduced from the entries
+	count := 0
+	for _, entry := range machine.Entries {
+		if index := entry.Data[0]; index != MorxNoIndex {
+			if end := int(index) + int(entry.Flags&MorxCurrentInsertCount)>>5; end > count {
+				count = end
+			}
+		}
+		if index := entry.Data[1]; index != MorxNoIndex {
+			if end := int(index) + int(entry.Flags&MorxMarkedInsertCount); end > count {
+				count = end
+			}
+		}
+	}
+	out := MorxInsertion{Machine: machine}
+	if out.Glyphs, err = parseGlyphs(data, int(binary.BigEndian.Uint32(data[16:])), count); err != nil {
+		return out, errors.New("invalid insertion subtable (EOF)")
+	}
+	return out, nil
+}
+
+// morx is the lazily parsed 'morx' table.
+type morx struct {
+	once  sync.Once
+	table *TableMorx // nil if missing or invalid
+}
+
+// Morx returns the glyph transformation table, parsed on first use,
+// or false if the font has no 'morx' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Morx() (*TableMorx, bool) {
+	face.morx.once.Do(func() {
+		if data, err := face.GetRawTable(tagMorx); err == nil {
+			if table, err := parseTableMorx(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagMorx, err))
+			} else {
+				face.morx.table = &table
+			}
+		}
+	})
+	return face.morx.table, face.morx.table != nil
+}
@@ -0,0 +1,62 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// tableMVAR stores the variations of the font wide metrics.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/mvar
+type tableMVAR struct {
+	store   itemVariationStore
+	records []mvarRecord // sorted by tag
+}
+
+type mvarRecord struct {
+	tag          Tag
+	outer, inner uint16
+}
+
+func parseTableMVAR(data []byte) (tableMVAR, error) {
+	const headerSize = 12
+	if len(data) < headerSize {
+		return tableMVAR{}, errEOF
+	}
+	recordSize := int(binary.BigEndian.Uint16(data[6:]))
+	count := int(binary.BigEndian.Uint16(data[8:]))
+	storeOffset := binary.BigEndian.Uint16(data[10:])
+	if recordSize < 8 || len(data) < headerSize+recordSize*count {
+		return tableMVAR{}, errEOF
+	}
+	var (
+		out tableMVAR
+		err error
+	)
+	if storeOffset == 0 { // no variations
+		return out, nil
+	}
+	out.store, err = parseItemVariationStore(data, uint32(storeOffset))
+	if err != nil {
+		return out, err
+	}
+	out.records = make([]mvarRecord, count)
+	for i := range out.records {
+		record := data[headerSize+recordSize*i:]
+		out.records[i] = mvarRecord{
+			tag:   Tag(binary.BigEndian.Uint32(record)),
+			outer: binary.BigEndian.Uint16(record[4:]),
+			inner: binary.BigEndian.Uint16(record[6:]),
+		}
+	}
+	return out, nil
+}
+
+// delta returns the variation of the metric `tag`,
+// or 0 if it has no variations.
+func (t *tableMVAR) delta(tag Tag, coords []float32) float32 {
+	i := sort.Search(len(t.records), func(i int) bool { return t.records[i].tag >= tag })
+	if i == len(t.records) || t.records[i].tag != tag {
+		return 0
+	}
+	return t.store.delta(t.records[i].outer, t.records[i].inner, coords)
+}
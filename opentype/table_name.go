@@ -0,0 +1,354 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"unicode/utf16"
+)
+
+// NameID identifies the entries of the 'name' table.
+type NameID uint16
+
+const (
+	NameCopyrightNotice            NameID = 0
+	NameFamily                     NameID = 1
+	NameSubfamily                  NameID = 2
+	NameUniqueIdentifier           NameID = 3
+	NameFull                       NameID = 4
+	NameVersion                    NameID = 5
+	NamePostScript                 NameID = 6
+	NameTrademark                  NameID = 7
+	NameManufacturer               NameID = 8
+	NameDesigner                   NameID = 9
+	NameDescription                NameID = 10
+	NameVendorURL                  NameID = 11
+	NameDesignerURL                NameID = 12
+	NameLicenseDescription         NameID = 13
+	NameLicenseURL                 NameID = 14
+	NameTypographicFamily          NameID = 16
+	NameTypographicSubfamily       NameID = 17
+	NameCompatibleFull             NameID = 18 // Macintosh only
+	NameSampleText                 NameID = 19
+	NamePostScriptCID              NameID = 20
+	NameWWSFamily                  NameID = 21
+	NameWWSSubfamily               NameID = 22
+	NameLightBackgroundPalette     NameID = 23
+	NameDarkBackgroundPalette      NameID = 24
+	NameVariationsPostScriptPrefix NameID = 25
+)
+
+// LanguageID is the language of an entry of the 'name' table, whose meaning
+// depends on the platform : it is a Windows LCID for the Microsoft platform,
+// and a Macintosh language code for the Macintosh platform.
+// The values starting at 0x8000 refer to the language tags of the table
+// (see TableName.LanguageTags).
+type LanguageID uint16
+
+const (
+	LanguageMacEnglish       LanguageID = 0
+	LanguageMicrosoftEnglish LanguageID = 0x0409 // English (United States)
+)
+
+// NameRecord is an entry of the 'name' table.
+type NameRecord struct {
+	Platform PlatformID
+	Encoding PlatformEncodingID
+	Language LanguageID
+	Name     NameID
+	Value    []byte // the encoded string
+}
+
+// String returns the decoded value of the record, or false if its
+// encoding is not supported. UTF-16 (used by the Unicode and Microsoft
+// platforms) and Mac Roman are supported.
+func (r NameRecord) String() (string, bool) {
+	switch {
+	case r.isUTF16():
+		return decodeUTF16BE(r.Value), true
+	case r.Platform == PlatformMac && r.Encoding == PEMacRoman:
+		return decodeMacRoman(r.Value), true
+	case r.Platform == PlatformIso && r.Encoding == 0: // 7-bit ASCII
+		return decodeMacRoman(r.Value), true // ASCII is a subset of Mac Roman
+	default:
+		return "", false
+	}
+}
+
+func (r NameRecord) isUTF16() bool {
+	switch r.Platform {
+	case PlatformUnicode:
+		return true
+	case PlatformIso:
+		return r.Encoding == 1 // ISO 10646
+	case PlatformMicrosoft:
+		return r.Encoding == PEMicrosoftSymbolCs || r.Encoding == PEMicrosoftUnicodeCs || r.Encoding == PEMicrosoftUcs4
+	default:
+		return false
+	}
+}
+
+func decodeUTF16BE(b []byte) string {
+	chars := make([]uint16, len(b)/2)
+	for i := range chars {
+		chars[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(chars))
+}
+
+// macRoman maps the bytes 0x80 to 0xFF of the Mac Roman encoding to runes.
+var macRoman = [128]rune{
+	0x00C4, 0x00C5, 0x00C7, 0x00C9, 0x00D1, 0x00D6, 0x00DC, 0x00E1,
+	0x00E0, 0x00E2, 0x00E4, 0x00E3, 0x00E5, 0x00E7, 0x00E9, 0x00E8,
+	0x00EA, 0x00EB, 0x00ED, 0x00EC, 0x00EE, 0x00EF, 0x00F1, 0x00F3,
+	0x00F2, 0x00F4, 0x00F6, 0x00F5, 0x00FA, 0x00F9, 0x00FB, 0x00FC,
+	0x2020, 0x00B0, 0x00A2, 0x00A3, 0x00A7, 0x2022, 0x00B6, 0x00DF,
+	0x00AE, 0x00A9, 0x2122, 0x00B4, 0x00A8, 0x2260, 0x00C6, 0x00D8,
+	0x221E, 0x00B1, 0x2264, 0x2265, 0x00A5, 0x00B5, 0x2202, 0x2211,
+	0x220F, 0x03C0, 0x222B, 0x00AA, 0x00BA, 0x03A9, 0x00E6, 0x00F8,
+	0x00BF, 0x00A1, 0x00AC, 0x221A, 0x0192, 0x2248, 0x2206, 0x00AB,
+	0x00BB, 0x2026, 0x00A0, 0x00C0, 0x00C3, 0x00D5, 0x0152, 0x0153,
+	0x2013, 0x2014, 0x201C, 0x201D, 0x2018, 0x2019, 0x00F7, 0x25CA,
+	0x00FF, 0x0178, 0x2044, 0x20AC, 0x2039, 0x203A, 0xFB01, 0xFB02,
+	0x2021, 0x00B7, 0x201A, 0x201E, 0x2030, 0x00C2, 0x00CA, 0x00C1,
+	0x00CB, 0x00C8, 0x00CD, 0x00CE, 0x00CF, 0x00CC, 0x00D3, 0x00D4,
+	0xF8FF, 0x00D2, 0x00DA, 0x00DB, 0x00D9, 0x0131, 0x02C6, 0x02DC,
+	0x00AF, 0x02D8, 0x02D9, 0x02DA, 0x00B8, 0x02DD, 0x02DB, 0x02C7,
+}
+
+func decodeMacRoman(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		if c < 0x80 {
+			runes[i] = rune(c)
+		} else {
+			runes[i] = macRoman[c-0x80]
+		}
+	}
+	return string(runes)
+}
+
+// TableName stores the names of the font, such as its family
+// or its copyright notice, in several languages and encodings.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/name
+type TableName struct {
+	Records []NameRecord
+	// LanguageTags are the IETF BCP 47 language tags of the version 1
+	// of the table, used by the records whose language is 0x8000 + index.
+	LanguageTags []string
+}
+
+func parseTableName(data []byte) (TableName, error) {
+	if len(data) < 6 {
+		return TableName{}, errEOF
+	}
+	version := binary.BigEndian.Uint16(data)
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	storageOffset := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+12*count || storageOffset > len(data) {
+		return TableName{}, errEOF
+	}
+	storage := data[storageOffset:]
+	readString := func(length, offset uint16) ([]byte, error) {
+		if int(offset)+int(length) > len(storage) {
+			return nil, errors.New("invalid name record (EOF)")
+		}
+		return storage[offset : offset+length], nil
+	}
+
+	var out TableName
+	out.Records = make([]NameRecord, 0, count)
+	for i := 0; i < count; i++ {
+		record := data[6+12*i:]
+		value, err := readString(binary.BigEndian.Uint16(record[8:]), binary.BigEndian.Uint16(record[10:]))
+		if err != nil { // common in the wild : skip the record
+			continue
+		}
+		out.Records = append(out.Records, NameRecord{
+			Platform: PlatformID(binary.BigEndian.Uint16(record)),
+			Encoding: PlatformEncodingID(binary.BigEndian.Uint16(record[2:])),
+			Language: LanguageID(binary.BigEndian.Uint16(record[4:])),
+			Name:     NameID(binary.BigEndian.Uint16(record[6:])),
+			Value:    value,
+		})
+	}
+
+	if version == 0 {
+		return out, nil
+	}
+	tags := data[6+12*count:]
+	if len(tags) < 2 {
+		return TableName{}, errEOF
+	}
+	numTags := int(binary.BigEndian.Uint16(tags))
+	if len(tags) < 2+4*numTags {
+		return TableName{}, errEOF
+	}
+	out.LanguageTags = make([]string, numTags)
+	for i := range out.LanguageTags {
+		value, err := readString(binary.BigEndian.Uint16(tags[2+4*i:]), binary.BigEndian.Uint16(tags[4+4*i:]))
+		if err != nil {
+			return TableName{}, err
+		}
+		out.LanguageTags[i] = decodeUTF16BE(value)
+	}
+	return out, nil
+}
+
+// Name returns the (non empty) entry `name`, decoded, looking for the Windows
+// records in the `preferredLanguages`, in order, then falling back to English
+// (first on the Windows platform, then the Unicode and Macintosh ones),
+// and finally to any decodable record.
+// It returns false if the table has no such entry.
+func (t *TableName) Name(name NameID, preferredLanguages ...LanguageID) (string, bool) {
+	best, bestScore := "", -1
+	for _, r := range t.Records {
+		if r.Name != name || len(r.Value) == 0 {
+			continue
+		}
+		s, ok := r.String()
+		if !ok {
+			continue
+		}
+		score := r.selectionScore()
+		if r.Platform == PlatformMicrosoft {
+			for i, lang := range preferredLanguages {
+				if r.Language == lang {
+					score = 10 + len(preferredLanguages) - i
+					break
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// selectionScore ranks the English and Unicode records,
+// which are preferred when no preferred language matches.
+func (r NameRecord) selectionScore() int {
+	switch {
+	case r.Platform == PlatformMicrosoft && r.Language == LanguageMicrosoftEnglish:
+		return 5
+	case r.Platform == PlatformMicrosoft && r.Language&0x3FF == 0x009: // other English variants
+		return 4
+	case r.Platform == PlatformUnicode:
+		return 3
+	case r.Platform == PlatformMac && r.Language == LanguageMacEnglish:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// LanguageTag returns the BCP 47 language tag of the record, such as "en-US",
+// resolved from the Windows or Macintosh language IDs, or from the
+// language tags of the table. It returns an empty string for the unknown
+// languages, and for the Unicode platform records without language tag.
+func (t *TableName) LanguageTag(r NameRecord) string {
+	if r.Language >= 0x8000 {
+		if index := int(r.Language - 0x8000); index < len(t.LanguageTags) {
+			return t.LanguageTags[index]
+		}
+		return ""
+	}
+	switch r.Platform {
+	case PlatformMicrosoft:
+		return windowsLanguages[r.Language]
+	case PlatformMac:
+		return macLanguages[r.Language]
+	default:
+		return ""
+	}
+}
+
+// LocalizedName returns the (non empty) entry `name`, decoded, in the first of the
+// `languages` (given as BCP 47 tags) provided by the font.
+// Each tag is tried with its less specific fallbacks: for instance, "zh-Hant-TW" is
+// matched by the records in "zh-Hant-TW", then "zh-Hant" (such as "zh-HK"), then "zh".
+// Among the matching records, the closest to the requested language is preferred,
+// so that "zh-SG" selects "zh-CN" (with the same implicit script) over "zh-TW".
+// If none of the languages is found, the English name is returned, as for Name.
+// It returns false if the table has no such entry.
+func (t *TableName) LocalizedName(name NameID, languages ...string) (string, bool) {
+	for _, language := range languages {
+		fallbacks := languageFallbacks(language)
+		for _, requested := range fallbacks {
+			best, bestScore := "", -1
+			for _, r := range t.Records {
+				tag := t.LanguageTag(r)
+				if r.Name != name || len(r.Value) == 0 || !languageMatches(tag, requested) {
+					continue
+				}
+				s, ok := r.String()
+				if !ok {
+					continue
+				}
+				score := 4*commonSubtags(withImplicitScript(tag), withImplicitScript(fallbacks[0])) + r.platformScore()
+				if score > bestScore {
+					best, bestScore = s, score
+				}
+			}
+			if bestScore >= 0 {
+				return best, true
+			}
+		}
+	}
+	return t.Name(name)
+}
+
+func (r NameRecord) platformScore() int {
+	switch r.Platform {
+	case PlatformMicrosoft:
+		return 2
+	case PlatformUnicode:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// names stores the 'name' table, loaded on first use.
+type names struct {
+	once  sync.Once
+	table *TableName // nil if missing or invalid
+}
+
+// NameTable returns the naming table, parsed on first use,
+// or false if the font has no 'name' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) NameTable() (*TableName, bool) {
+	face.names.once.Do(func() {
+		if data, err := face.GetRawTable(tagName); err == nil {
+			if table, err := parseTableName(data); err != nil {
+				face.warnings.addError(face.tableError(tagName, err))
+			} else {
+				face.names.table = &table
+			}
+		}
+	})
+	return face.names.table, face.names.table != nil
+}
+
+// Name returns the entry `name` of the 'name' table (see TableName.Name),
+// or false if the font has no such entry.
+func (face *Face) Name(name NameID, preferredLanguages ...LanguageID) (string, bool) {
+	table, ok := face.NameTable()
+	if !ok {
+		return "", false
+	}
+	return table.Name(name, preferredLanguages...)
+}
+
+// LocalizedName returns the entry `name` of the 'name' table, in the first
+// available of the `languages` (see TableName.LocalizedName),
+// or false if the font has no such entry.
+func (face *Face) LocalizedName(name NameID, languages ...string) (string, bool) {
+	table, ok := face.NameTable()
+	if !ok {
+		return "", false
+	}
+	return table.LocalizedName(name, languages...)
+}
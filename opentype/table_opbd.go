@@ -0,0 +1,133 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// TableOpbd stores the optical bounds of the glyphs of the AAT fonts,
+// which are used for optical margin alignment, such as hanging punctuation.
+// The bounds are given by distances (format 0) or by
+// control points (format 1), see Face.OpticalBounds.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6opbd.html
+type TableOpbd struct {
+	Format uint16
+
+	glyphs ClassDef // the offsets of the bounds of the glyphs in data
+	data   []byte   // the whole table
+}
+
+// OpticalBounds stores the four values of the optical bounds of a glyph,
+// indexed by the Opbd constants : distances in font units (format 0),
+// or control point indices, 0xFFFF meaning no point (format 1).
+type OpticalBounds [4]int16
+
+// The indices of the values of OpticalBounds.
+const (
+	OpbdLeft = iota
+	OpbdTop
+	OpbdRight
+	OpbdBottom
+)
+
+// Bounds returns the optical bounds of the glyph,
+// or false if it has none (or if they are invalid).
+func (t *TableOpbd) Bounds(gid GID) (OpticalBounds, bool) {
+	offset, ok := t.glyphs.Class(gid)
+	if !ok || len(t.data) < int(offset)+8 {
+		return OpticalBounds{}, false
+	}
+	var out OpticalBounds
+	for i := range out {
+		out[i] = int16(binary.BigEndian.Uint16(t.data[int(offset)+2*i:]))
+	}
+	return out, true
+}
+
+func parseTableOpbd(data []byte, numGlyphs int) (TableOpbd, error) {
+	if len(data) < 8 {
+		return TableOpbd{}, errEOF
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 {
+		return TableOpbd{}, fmt.Errorf("unsupported version %x", version)
+	}
+	out := TableOpbd{Format: binary.BigEndian.Uint16(data[4:]), data: data}
+	if out.Format > 1 {
+		return out, fmt.Errorf("unsupported format %d", out.Format)
+	}
+	var err error
+	out.glyphs, err = parseAATLookup(data, 6, numGlyphs)
+	return out, err
+}
+
+// opbd is the lazily parsed 'opbd' table.
+type opbd struct {
+	once  sync.Once
+	table *TableOpbd // nil if missing or invalid
+}
+
+// Opbd returns the optical bounds table, parsed on first use,
+// or false if the font has no 'opbd' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Opbd() (*TableOpbd, bool) {
+	face.opbd.once.Do(func() {
+		if data, err := face.GetRawTable(tagOpbd); err == nil {
+			if table, err := parseTableOpbd(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagOpbd, err))
+			} else {
+				face.opbd.table = &table
+			}
+		}
+	})
+	return face.opbd.table, face.opbd.table != nil
+}
+
+// OpticalBounds returns the distances, in font units, from the edges of
+// the glyph to its optical edges, as defined by the 'opbd' table and indexed
+// by the Opbd constants : the optical left edge is at x = left, the optical right
+// edge at the horizontal advance plus right, and, in vertical layout, the optical
+// top and bottom edges are at the vertical origin plus top, and at the bottom
+// of the vertical advance plus bottom.
+// For format 1, the distances are given by the control points of the glyph,
+// which are only supported for TrueType outlines; the missing points, and
+// the top and bottom points of fonts without vertical metrics, give 0.
+// It returns false if the font has no 'opbd' table, or if the glyph
+// has no optical bounds.
+func (face *Face) OpticalBounds(gid GID) ([4]float32, bool) {
+	table, ok := face.Opbd()
+	if !ok {
+		return [4]float32{}, false
+	}
+	bounds, ok := table.Bounds(gid)
+	if !ok {
+		return [4]float32{}, false
+	}
+	var out [4]float32
+	if table.Format == 0 {
+		for i, v := range bounds {
+			out[i] = float32(v)
+		}
+		return out, true
+	}
+	_, originY, hasOrigin := face.VerticalOrigin(gid)
+	edges := [4]float32{0, originY, face.HorizontalAdvance(gid), originY + face.VerticalAdvance(gid)}
+	for i, point := range bounds {
+		if uint16(point) == 0xFFFF {
+			continue
+		}
+		p, ok := face.contourPoint(gid, uint16(point))
+		if !ok {
+			return [4]float32{}, false
+		}
+		switch i {
+		case OpbdLeft, OpbdRight:
+			out[i] = p.x - edges[i]
+		default:
+			if hasOrigin {
+				out[i] = p.y - edges[i]
+			}
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,203 @@
+package opentype
+
+import "encoding/binary"
+
+// TableOS2 stores the font wide metrics and the classification of the font given by the 'OS/2' table.
+// The fields which are not defined by the version of the table are set to
+// the defaults given by the specification.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/os2
+type TableOS2 struct {
+	Version       uint16
+	XAvgCharWidth int16
+	// WeightClass is the weight of the font, from 1 to 1000 (400 is regular, 700 is bold).
+	WeightClass uint16
+	// WidthClass is the width of the font, from 1 (ultra-condensed) to 9 (ultra-expanded).
+	WidthClass uint16
+	// FsType stores the embedding licensing rights of the font.
+	FsType uint16
+
+	SubscriptXSize     int16
+	SubscriptYSize     int16
+	SubscriptXOffset   int16
+	SubscriptYOffset   int16
+	SuperscriptXSize   int16
+	SuperscriptYSize   int16
+	SuperscriptXOffset int16
+	SuperscriptYOffset int16
+	StrikeoutSize      int16
+	StrikeoutPosition  int16
+
+	// FamilyClass is the IBM font family class (high byte) and subclass (low byte).
+	FamilyClass int16
+	Panose      Panose
+	// UnicodeRange is the bit field of the Unicode blocks supported by the font,
+	// the bit 0 being the lowest bit of UnicodeRange[0].
+	UnicodeRange [4]uint32
+	// VendorID identifies the vendor of the font.
+	VendorID Tag
+
+	FsSelection    SelectionFlags
+	FirstCharIndex uint16 // minimum BMP character, or 0xFFFF
+	LastCharIndex  uint16 // maximum BMP character, or 0xFFFF
+
+	TypoAscender  int16
+	TypoDescender int16
+	TypoLineGap   int16
+	WinAscent     uint16
+	WinDescent    uint16
+
+	// CodePageRange is the bit field of the code pages supported by the font
+	// (version 1 and later, 0 otherwise).
+	CodePageRange [2]uint32
+
+	// version 2 and later
+	XHeight     int16  // 0 if unknown
+	CapHeight   int16  // 0 if unknown
+	DefaultChar uint16 // default to 0, that is the .notdef glyph
+	BreakChar   uint16 // default to 0x20 (space)
+	MaxContext  uint16 // default to 0
+
+	// LowerOpticalPointSize and UpperOpticalPointSize are the range of sizes,
+	// in twentieths of a point, the font is designed for (version 5 and later,
+	// default to 0 and 0xFFFF).
+	LowerOpticalPointSize uint16
+	UpperOpticalPointSize uint16
+}
+
+// SelectionFlags are the style flags of the fsSelection field of the 'OS/2' table.
+type SelectionFlags uint16
+
+const (
+	SelectionItalic         SelectionFlags = 1 << iota // the font is italic
+	SelectionUnderscore                                // the glyphs are underscored
+	SelectionNegative                                  // the glyphs have their foreground and background reversed
+	SelectionOutlined                                  // the glyphs are outlined (hollow)
+	SelectionStrikeout                                 // the glyphs are overstruck
+	SelectionBold                                      // the font is bold
+	SelectionRegular                                   // the font is regular (neither bold nor italic)
+	SelectionUseTypoMetrics                            // the typographic metrics must be used for the line spacing
+	SelectionWWS                                       // the names follow the weight/width/slope model
+	SelectionOblique                                   // the font is oblique
+)
+
+func parseTableOS2(data []byte) (TableOS2, error) {
+	const headerSize = 78 // version 0
+	if len(data) < headerSize {
+		return TableOS2{}, errEOF
+	}
+	u16 := func(offset int) uint16 { return binary.BigEndian.Uint16(data[offset:]) }
+	i16 := func(offset int) int16 { return int16(binary.BigEndian.Uint16(data[offset:])) }
+	u32 := func(offset int) uint32 { return binary.BigEndian.Uint32(data[offset:]) }
+	out := TableOS2{
+		Version:            u16(0),
+		XAvgCharWidth:      i16(2),
+		WeightClass:        u16(4),
+		WidthClass:         u16(6),
+		FsType:             u16(8),
+		SubscriptXSize:     i16(10),
+		SubscriptYSize:     i16(12),
+		SubscriptXOffset:   i16(14),
+		SubscriptYOffset:   i16(16),
+		SuperscriptXSize:   i16(18),
+		SuperscriptYSize:   i16(20),
+		SuperscriptXOffset: i16(22),
+		SuperscriptYOffset: i16(24),
+		StrikeoutSize:      i16(26),
+		StrikeoutPosition:  i16(28),
+		FamilyClass:        i16(30),
+		UnicodeRange:       [4]uint32{u32(42), u32(46), u32(50), u32(54)},
+		VendorID:           Tag(u32(58)),
+		FsSelection:        SelectionFlags(u16(62)),
+		FirstCharIndex:     u16(64),
+		LastCharIndex:      u16(66),
+		TypoAscender:       i16(68),
+		TypoDescender:      i16(70),
+		TypoLineGap:        i16(72),
+		WinAscent:          u16(74),
+		WinDescent:         u16(76),
+
+		BreakChar:             0x20,
+		UpperOpticalPointSize: 0xFFFF,
+	}
+	copy(out.Panose[:], data[32:42])
+	if out.Version >= 1 && len(data) >= 86 {
+		out.CodePageRange = [2]uint32{u32(78), u32(82)}
+	}
+	if out.Version >= 2 && len(data) >= 96 {
+		out.XHeight = i16(86)
+		out.CapHeight = i16(88)
+		out.DefaultChar = u16(90)
+		out.BreakChar = u16(92)
+		out.MaxContext = u16(94)
+	}
+	if out.Version >= 5 && len(data) >= 100 {
+		out.LowerOpticalPointSize = u16(96)
+		out.UpperOpticalPointSize = u16(98)
+	}
+	return out, nil
+}
+
+// OS2 returns the 'OS/2' table, parsed on first use, or false if the
+// font has no 'OS/2' table. Invalid tables are ignored, and reported in the warnings.
+func (face *Face) OS2() (*TableOS2, bool) {
+	os2 := face.loadMetrics().os2
+	return os2, os2 != nil
+}
+
+// EmbeddingUsage is the usage permission given to an embedded font,
+// by increasing level of freedom (excepted for EmbeddingInstallable,
+// which imposes no restriction).
+type EmbeddingUsage uint8
+
+const (
+	// EmbeddingInstallable allows the font to be embedded, and
+	// permanently installed on the remote system.
+	EmbeddingInstallable EmbeddingUsage = iota
+	// EmbeddingRestricted forbids the font to be embedded without
+	// the permission of the legal owner.
+	EmbeddingRestricted
+	// EmbeddingPreviewPrint allows the font to be embedded, and temporarily
+	// loaded on the remote system, for documents which must be opened read-only.
+	EmbeddingPreviewPrint
+	// EmbeddingEditable allows the font to be embedded, and temporarily
+	// loaded on the remote system, for documents which may be edited.
+	EmbeddingEditable
+)
+
+// EmbeddingPermissions describes the licensing rights of the font,
+// as given by the fsType field of the 'OS/2' table.
+type EmbeddingPermissions struct {
+	Usage EmbeddingUsage
+	// NoSubsetting forbids the font to be subsetted before being embedded.
+	NoSubsetting bool
+	// BitmapOnly allows only the bitmaps of the font to be embedded, so
+	// that the font can't be embedded if it has no bitmaps.
+	BitmapOnly bool
+}
+
+// EmbeddingPermissions returns the licensing rights of the font, or false if
+// the font has no 'OS/2' table.
+// For fonts setting several usage bits, which is invalid from version 3,
+// the least restrictive usage is returned.
+func (face *Face) EmbeddingPermissions() (EmbeddingPermissions, bool) {
+	os2, ok := face.OS2()
+	if !ok {
+		return EmbeddingPermissions{}, false
+	}
+	fsType := os2.FsType
+	out := EmbeddingPermissions{
+		NoSubsetting: fsType&0x0100 != 0,
+		BitmapOnly:   fsType&0x0200 != 0,
+	}
+	switch {
+	case fsType&0x0008 != 0:
+		out.Usage = EmbeddingEditable
+	case fsType&0x0004 != 0:
+		out.Usage = EmbeddingPreviewPrint
+	case fsType&0x0002 != 0:
+		out.Usage = EmbeddingRestricted
+	default:
+		out.Usage = EmbeddingInstallable
+	}
+	return out, true
+}
@@ -0,0 +1,107 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// tablePost is the content of the 'post' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/post
+type tablePost struct {
+	version uint32
+
+	underlinePosition  int16
+	underlineThickness int16
+
+	// names is indexed by glyph, and is nil if
+	// the table does not provide glyph names (format 3.0)
+	names []string
+}
+
+func parseTablePost(data []byte, numGlyphs int) (tablePost, error) {
+	const headerSize = 32
+	if len(data) < headerSize {
+		return tablePost{}, errEOF
+	}
+	out := tablePost{
+		version:            binary.BigEndian.Uint32(data),
+		underlinePosition:  int16(binary.BigEndian.Uint16(data[8:])),
+		underlineThickness: int16(binary.BigEndian.Uint16(data[10:])),
+	}
+	var err error
+	switch out.version {
+	case 0x00010000:
+		n := numGlyphs
+		if n > len(macintoshGlyphNames) {
+			n = len(macintoshGlyphNames)
+		}
+		out.names = macintoshGlyphNames[:n]
+	case 0x00020000:
+		out.names, err = parsePostNames20(data[headerSize:], numGlyphs)
+	case 0x00025000:
+		out.names, err = parsePostNames25(data[headerSize:], numGlyphs)
+	case 0x00030000, 0x00040000: // no names
+	default:
+		err = fmt.Errorf("unsupported version 0x%08x", out.version)
+	}
+	return out, err
+}
+
+func parsePostNames20(data []byte, numGlyphs int) ([]string, error) {
+	if len(data) < 2 {
+		return nil, errEOF
+	}
+	count := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+2*count {
+		return nil, errors.New("invalid glyph name indices (EOF)")
+	}
+	indices := data[2 : 2+2*count]
+
+	// the custom names are stored as Pascal strings
+	var custom []string
+	for buf := data[2+2*count:]; len(buf) != 0; {
+		length := int(buf[0])
+		if len(buf) < 1+length {
+			break // tolerate a truncated last name
+		}
+		custom = append(custom, string(buf[1:1+length]))
+		buf = buf[1+length:]
+	}
+
+	if count > numGlyphs {
+		count = numGlyphs
+	}
+	out := make([]string, count)
+	for i := range out {
+		index := int(binary.BigEndian.Uint16(indices[2*i:]))
+		if index < len(macintoshGlyphNames) {
+			out[i] = macintoshGlyphNames[index]
+		} else if index -= len(macintoshGlyphNames); index < len(custom) {
+			out[i] = custom[index]
+		}
+	}
+	return out, nil
+}
+
+// parsePostNames25 parses the deprecated format 2.5, which stores an offset
+// into the standard names for each glyph.
+func parsePostNames25(data []byte, numGlyphs int) ([]string, error) {
+	if len(data) < 2 {
+		return nil, errEOF
+	}
+	count := int(binary.BigEndian.Uint16(data))
+	if count > numGlyphs {
+		count = numGlyphs
+	}
+	if len(data) < 2+count {
+		return nil, errors.New("invalid glyph name offsets (EOF)")
+	}
+	out := make([]string, count)
+	for i := range out {
+		if index := i + int(int8(data[2+i])); 0 <= index && index < len(macintoshGlyphNames) {
+			out[i] = macintoshGlyphNames[index]
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// TableProp stores the properties of the glyphs of the AAT fonts,
+// such as their directionality class, used for bidirectional layout.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6prop.html
+type TableProp struct {
+	Version uint16 // major version, 1 to 3
+	// DefaultProperties are the properties of the glyphs
+	// not covered by the table (see Properties).
+	DefaultProperties GlyphProperties
+
+	glyphs ClassDef // nil for format 0
+}
+
+// GlyphProperties stores the properties of a glyph, as defined by
+// the 'prop' table : flags and a directionality class.
+type GlyphProperties uint16
+
+// The flags of GlyphProperties.
+const (
+	// PropFloater is set for the glyphs which do not advance ("floating accents").
+	PropFloater GlyphProperties = 0x8000
+	// PropHangsLeft is set for the glyphs which may hang off
+	// the left side of a line (hanging punctuation).
+	PropHangsLeft GlyphProperties = 0x4000
+	// PropHangsRight is set for the glyphs which may hang off the right side of a line.
+	PropHangsRight GlyphProperties = 0x2000
+	// PropMirrored is set for the glyphs replaced by their complementary
+	// bracket in right-to-left text (see TableProp.ComplementaryBracket).
+	PropMirrored GlyphProperties = 0x1000
+	// PropAttachesOnRight is set for the glyphs attaching to the glyph
+	// on their right (version 2 and above).
+	PropAttachesOnRight GlyphProperties = 0x0080
+)
+
+// The directionality classes of GlyphProperties.
+const (
+	PropLeftToRight = iota
+	PropRightToLeft
+	PropArabicLetter
+	PropEuropeanNumber
+	PropEuropeanNumberSeparator
+	PropEuropeanNumberTerminator
+	PropArabicNumber
+	PropCommonNumberSeparator
+	PropParagraphSeparator
+	PropSegmentSeparator
+	PropWhitespace
+	PropOtherNeutral
+	PropLeftToRightEmbedding
+	PropLeftToRightOverride
+	PropRightToLeftEmbedding
+	PropRightToLeftOverride
+	PropPopDirectionalFormat
+	PropNonSpacingMark
+	PropBoundaryNeutral
+)
+
+// Direction returns the directionality class of the glyph,
+// one of the Prop directionality constants.
+func (p GlyphProperties) Direction() uint8 { return uint8(p & 0x1F) }
+
+// BracketOffset returns the offset from the glyph to
+// its complementary bracket (see PropMirrored).
+func (p GlyphProperties) BracketOffset() int8 { return int8(p>>4) >> 4 }
+
+// Properties returns the properties of the glyph.
+func (t *TableProp) Properties(gid GID) GlyphProperties {
+	if t.glyphs != nil {
+		if props, ok := t.glyphs.Class(gid); ok {
+			return GlyphProperties(props)
+		}
+	}
+	return t.DefaultProperties
+}
+
+// ComplementaryBracket returns the glyph replacing `gid` in right-to-left
+// text, or false if it is not mirrored (see PropMirrored).
+func (t *TableProp) ComplementaryBracket(gid GID) (GID, bool) {
+	props := t.Properties(gid)
+	if props&PropMirrored == 0 {
+		return 0, false
+	}
+	mirror := int(gid) + int(props.BracketOffset())
+	if mirror < 0 || mirror > 0xFFFF {
+		return 0, false
+	}
+	return GID(mirror), true
+}
+
+func parseTableProp(data []byte, numGlyphs int) (TableProp, error) {
+	if len(data) < 8 {
+		return TableProp{}, errEOF
+	}
+	version := binary.BigEndian.Uint32(data)
+	if version&0xFFFF != 0 || version < 0x00010000 || version > 0x00030000 {
+		return TableProp{}, fmt.Errorf("unsupported version %x", version)
+	}
+	out := TableProp{Version: uint16(version >> 16), DefaultProperties: GlyphProperties(binary.BigEndian.Uint16(data[6:]))}
+	switch format := binary.BigEndian.Uint16(data[4:]); format {
+	case 0:
+	case 1:
+		var err error
+		if out.glyphs, err = parseAATLookup(data, 8, numGlyphs); err != nil {
+			return out, err
+		}
+	default:
+		return out, fmt.Errorf("unsupported format %d", format)
+	}
+	return out, nil
+}
+
+// prop is the lazily parsed 'prop' table.
+type prop struct {
+	once  sync.Once
+	table *TableProp // nil if missing or invalid
+}
+
+// Prop returns the glyph properties table, parsed on first use,
+// or false if the font has no 'prop' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Prop() (*TableProp, bool) {
+	face.prop.once.Do(func() {
+		if data, err := face.GetRawTable(tagProp); err == nil {
+			if table, err := parseTableProp(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagProp, err))
+			} else {
+				face.prop.table = &table
+			}
+		}
+	})
+	return face.prop.table, face.prop.table != nil
+}
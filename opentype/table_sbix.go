@@ -0,0 +1,84 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// tableSbix stores the bitmap strikes of the 'sbix' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/sbix
+type tableSbix struct {
+	drawOutlines bool // flags bit 1
+	strikes      []sbixStrike
+}
+
+// graphic types of the 'sbix' glyph records
+var (
+	tagPNG  = MustNewTag("png ")
+	tagJPEG = MustNewTag("jpg ")
+	tagTIFF = MustNewTag("tiff")
+	tagDupe = MustNewTag("dupe") // the record stores the glyph to use instead
+)
+
+type sbixStrike struct {
+	ppem, ppi uint16
+	data      []byte   // the whole strike, starting with its header
+	offsets   []uint32 // into data, for NumGlyphs + 1 glyphs
+}
+
+func parseTableSbix(data []byte, numGlyphs int) (tableSbix, error) {
+	if len(data) < 8 {
+		return tableSbix{}, errEOF
+	}
+	flags := binary.BigEndian.Uint16(data[2:])
+	numStrikes := int64(binary.BigEndian.Uint32(data[4:]))
+	if int64(len(data)) < 8+4*numStrikes {
+		return tableSbix{}, errEOF
+	}
+	out := tableSbix{drawOutlines: flags&2 != 0, strikes: make([]sbixStrike, numStrikes)}
+	for i := range out.strikes {
+		offset := binary.BigEndian.Uint32(data[8+4*i:])
+		if int64(len(data)) < int64(offset)+4+4*int64(numGlyphs+1) {
+			return tableSbix{}, errors.New("invalid sbix strike (EOF)")
+		}
+		strike := data[offset:]
+		s := sbixStrike{
+			ppem:    binary.BigEndian.Uint16(strike),
+			ppi:     binary.BigEndian.Uint16(strike[2:]),
+			data:    strike,
+			offsets: make([]uint32, numGlyphs+1),
+		}
+		for j := range s.offsets {
+			s.offsets[j] = binary.BigEndian.Uint32(strike[4+4*j:])
+		}
+		out.strikes[i] = s
+	}
+	return out, nil
+}
+
+// glyphData returns the record of the glyph, resolving the 'dupe' graphics,
+// or false if the glyph has no image in the strike.
+func (s *sbixStrike) glyphData(gid GID) (originX, originY int16, graphicType Tag, data []byte, ok bool) {
+	for i := 0; i < 2; i++ { // at most one level of 'dupe'
+		if int(gid)+1 >= len(s.offsets) {
+			return
+		}
+		start, end := s.offsets[gid], s.offsets[gid+1]
+		if start > end || int64(end) > int64(len(s.data)) || end-start < 8 {
+			return // empty or invalid record
+		}
+		record := s.data[start:end]
+		graphicType = Tag(binary.BigEndian.Uint32(record[4:]))
+		if graphicType == tagDupe {
+			if len(record) < 10 {
+				return
+			}
+			gid = GID(binary.BigEndian.Uint16(record[8:]))
+			continue
+		}
+		originX = int16(binary.BigEndian.Uint16(record))
+		originY = int16(binary.BigEndian.Uint16(record[2:]))
+		return originX, originY, graphicType, record[8:], true
+	}
+	return
+}
@@ -0,0 +1,407 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TableSTAT stores the style attributes of the font, describing its position
+// in the font family along the design axes.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/stat
+type TableSTAT struct {
+	Axes   []StatAxis
+	Values []StatAxisValue
+	// ElidedFallbackName is the name ID used when all the axis values
+	// are elided, such as "Regular".
+	ElidedFallbackName NameID
+}
+
+// StatAxis is a design axis of the 'STAT' table.
+type StatAxis struct {
+	Tag  Tag
+	Name NameID
+	// Ordering is the position of the axis names in the style names.
+	Ordering uint16
+}
+
+// StatAxisValueFlags are the flags of a StatAxisValue.
+type StatAxisValueFlags uint16
+
+const (
+	// StatOlderSiblingFontAttribute indicates that the value applies
+	// to older fonts of the family, and not to this font.
+	StatOlderSiblingFontAttribute StatAxisValueFlags = 1 << iota
+	// StatElidableAxisValueName indicates that the name of the value
+	// may be omitted in the style names, such as "Regular" for the weight 400.
+	StatElidableAxisValueName
+)
+
+// StatAxisValue is a named value, or range of values, on one or several axes.
+type StatAxisValue struct {
+	Format uint16 // 1 to 4
+	Flags  StatAxisValueFlags
+	Name   NameID
+
+	// Locations are the positions on the design axes (the indices in TableSTAT.Axes)
+	// described by the value : the formats 1 to 3 describe one axis, and
+	// the format 4 describes several axes at once.
+	Locations []StatLocation
+
+	// RangeMin and RangeMax are the range of the value on its axis (format 2 only).
+	RangeMin, RangeMax float32
+	// LinkedValue is the value of the style linked to this one,
+	// such as the bold value of a regular weight (format 3 only).
+	LinkedValue float32
+}
+
+// StatLocation is a value on a design axis.
+type StatLocation struct {
+	Axis  int // index in TableSTAT.Axes
+	Value float32
+}
+
+func parseTableSTAT(data []byte) (TableSTAT, error) {
+	if len(data) < 18 {
+		return TableSTAT{}, errEOF
+	}
+	minorVersion := binary.BigEndian.Uint16(data[2:])
+	axisSize := int(binary.BigEndian.Uint16(data[4:]))
+	axisCount := int(binary.BigEndian.Uint16(data[6:]))
+	axesOffset := int64(binary.BigEndian.Uint32(data[8:]))
+	valueCount := int(binary.BigEndian.Uint16(data[12:]))
+	valuesOffset := int64(binary.BigEndian.Uint32(data[14:]))
+
+	out := TableSTAT{ElidedFallbackName: NameSubfamily} // default for version 1.0
+	if minorVersion >= 1 {
+		if len(data) < 20 {
+			return TableSTAT{}, errEOF
+		}
+		out.ElidedFallbackName = NameID(binary.BigEndian.Uint16(data[18:]))
+	}
+
+	if axisCount != 0 && (axisSize < 8 || int64(len(data)) < axesOffset+int64(axisCount*axisSize)) {
+		return TableSTAT{}, errors.New("invalid design axes array (EOF)")
+	}
+	out.Axes = make([]StatAxis, axisCount)
+	for i := range out.Axes {
+		record := data[axesOffset+int64(i*axisSize):]
+		out.Axes[i] = StatAxis{
+			Tag:      Tag(binary.BigEndian.Uint32(record)),
+			Name:     NameID(binary.BigEndian.Uint16(record[4:])),
+			Ordering: binary.BigEndian.Uint16(record[6:]),
+		}
+	}
+
+	if valueCount == 0 {
+		return out, nil
+	}
+	if int64(len(data)) < valuesOffset+2*int64(valueCount) {
+		return TableSTAT{}, errors.New("invalid axis values array (EOF)")
+	}
+	values := data[valuesOffset:]
+	out.Values = make([]StatAxisValue, 0, valueCount)
+	for i := 0; i < valueCount; i++ {
+		value, err := parseStatAxisValue(values, binary.BigEndian.Uint16(values[2*i:]), axisCount)
+		if err != nil {
+			return TableSTAT{}, err
+		}
+		if value.Format != 0 { // skip unknown formats
+			out.Values = append(out.Values, value)
+		}
+	}
+	return out, nil
+}
+
+func parseStatAxisValue(data []byte, offset uint16, axisCount int) (StatAxisValue, error) {
+	errValue := errors.New("invalid axis value (EOF)")
+	if len(data) < int(offset)+8 {
+		return StatAxisValue{}, errValue
+	}
+	data = data[offset:]
+	out := StatAxisValue{
+		Format: binary.BigEndian.Uint16(data),
+		Flags:  StatAxisValueFlags(binary.BigEndian.Uint16(data[4:])),
+		Name:   NameID(binary.BigEndian.Uint16(data[6:])),
+	}
+	location := func() (StatLocation, error) {
+		if len(data) < 12 {
+			return StatLocation{}, errValue
+		}
+		axis := int(binary.BigEndian.Uint16(data[2:]))
+		if axis >= axisCount {
+			return StatLocation{}, errors.New("invalid axis value index")
+		}
+		return StatLocation{Axis: axis, Value: fixed1616(binary.BigEndian.Uint32(data[8:]))}, nil
+	}
+	switch out.Format {
+	case 1, 2, 3:
+		loc, err := location()
+		if err != nil {
+			return StatAxisValue{}, err
+		}
+		out.Locations = []StatLocation{loc}
+		if out.Format == 2 {
+			if len(data) < 20 {
+				return StatAxisValue{}, errValue
+			}
+			out.RangeMin = fixed1616(binary.BigEndian.Uint32(data[12:]))
+			out.RangeMax = fixed1616(binary.BigEndian.Uint32(data[16:]))
+		} else if out.Format == 3 {
+			if len(data) < 16 {
+				return StatAxisValue{}, errValue
+			}
+			out.LinkedValue = fixed1616(binary.BigEndian.Uint32(data[12:]))
+		}
+	case 4:
+		count := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < 8+6*count {
+			return StatAxisValue{}, errValue
+		}
+		out.Locations = make([]StatLocation, count)
+		for i := range out.Locations {
+			record := data[8+6*i:]
+			axis := int(binary.BigEndian.Uint16(record))
+			if axis >= axisCount {
+				return StatAxisValue{}, errors.New("invalid axis value index")
+			}
+			out.Locations[i] = StatLocation{Axis: axis, Value: fixed1616(binary.BigEndian.Uint32(record[2:]))}
+		}
+	default:
+		out.Format = 0
+	}
+	return out, nil
+}
+
+// matches returns true if the value describes `coord` on the axis `axis`.
+func (v *StatAxisValue) matches(axis int, coord float32) bool {
+	if len(v.Locations) != 1 || v.Locations[0].Axis != axis {
+		return false
+	}
+	if v.Format == 2 {
+		return v.RangeMin <= coord && coord <= v.RangeMax
+	}
+	return v.Locations[0].Value == coord
+}
+
+// StyleValues returns the axis values describing the position `location`
+// (the user space coordinates, by axis tag), sorted by axis ordering.
+// The format 4 values matching all their axes are preferred, then,
+// for each remaining axis, the first matching value.
+// The axes missing in `location` are described by their value, if
+// the table provides only one, as it is typically the case for the
+// axes which are not variable in the font (such as 'ital').
+// Values with the StatOlderSiblingFontAttribute flag are ignored.
+func (t *TableSTAT) StyleValues(location map[Tag]float32) []StatAxisValue {
+	indices := t.styleValues(location)
+	out := make([]StatAxisValue, len(indices))
+	for i, index := range indices {
+		out[i] = t.Values[index]
+	}
+	return out
+}
+
+// styleValues implements StyleValues, returning indices in t.Values.
+func (t *TableSTAT) styleValues(location map[Tag]float32) []int {
+	type match struct {
+		value    int
+		ordering uint16
+	}
+	var (
+		matches []match
+		covered = make([]bool, len(t.Axes))
+	)
+	coordinate := func(axis int) (float32, bool) {
+		if v, ok := location[t.Axes[axis].Tag]; ok {
+			return v, true
+		}
+		// use the only value of the axis, if any
+		var (
+			value float32
+			n     int
+		)
+		for _, v := range t.Values {
+			if len(v.Locations) == 1 && v.Locations[0].Axis == axis && v.Flags&StatOlderSiblingFontAttribute == 0 {
+				value, n = v.Locations[0].Value, n+1
+			}
+		}
+		return value, n == 1
+	}
+
+	for index, v := range t.Values {
+		if v.Format != 4 || v.Flags&StatOlderSiblingFontAttribute != 0 {
+			continue
+		}
+		ok := true
+		for _, loc := range v.Locations {
+			if c, has := coordinate(loc.Axis); !has || c != loc.Value || covered[loc.Axis] {
+				ok = false
+				break
+			}
+		}
+		if !ok || len(v.Locations) == 0 {
+			continue
+		}
+		ordering := uint16(0xFFFF)
+		for _, loc := range v.Locations {
+			covered[loc.Axis] = true
+			if o := t.Axes[loc.Axis].Ordering; o < ordering {
+				ordering = o
+			}
+		}
+		matches = append(matches, match{index, ordering})
+	}
+
+	for axis := range t.Axes {
+		if covered[axis] {
+			continue
+		}
+		c, ok := coordinate(axis)
+		if !ok {
+			continue
+		}
+		for index, v := range t.Values {
+			if v.Flags&StatOlderSiblingFontAttribute == 0 && v.matches(axis, c) {
+				matches = append(matches, match{index, t.Axes[axis].Ordering})
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].ordering < matches[j].ordering })
+	out := make([]int, len(matches))
+	for i, m := range matches {
+		out[i] = m.value
+	}
+	return out
+}
+
+// stat stores the 'STAT' table, loaded on first use.
+type stat struct {
+	once  sync.Once
+	table *TableSTAT // nil if missing or invalid
+}
+
+// STAT returns the style attributes table, parsed on first use,
+// or false if the font has no 'STAT' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) STAT() (*TableSTAT, bool) {
+	face.stat.once.Do(func() {
+		if data, err := face.GetRawTable(tagSTAT); err == nil {
+			if table, err := parseTableSTAT(data); err != nil {
+				face.warnings.addError(face.tableError(tagSTAT, err))
+			} else {
+				face.stat.table = &table
+			}
+		}
+	})
+	return face.stat.table, face.stat.table != nil
+}
+
+// StyleName returns the name of the style of the instance selected by SetVariations,
+// such as "Semibold Condensed", built from the names of the axis values of the
+// 'STAT' table (see TableSTAT.StyleValues) in the first available of the `languages`
+// (see LocalizedName). The elidable names are omitted, and the elided fallback name
+// is returned if all of them are elided.
+// The values are compared to the coordinates in the normalized space, with the
+// precision of the F2Dot14 format.
+// It returns false if the font has no 'STAT' table or if the style can't be named,
+// that is if the position on some variable axis is not described by an axis value.
+func (face *Face) StyleName(languages ...string) (string, bool) {
+	return face.instanceStyleName(false, languages...)
+}
+
+// instanceStyleName implements StyleName. If `exact` is true, the ranges of
+// values (format 2) are ignored, so that only the instances at the nominal
+// values are named.
+func (face *Face) instanceStyleName(exact bool, languages ...string) (string, bool) {
+	stat, ok := face.STAT()
+	if !ok {
+		return "", false
+	}
+	values, described := face.instanceStyleValues(stat, exact)
+	if !described || len(values) == 0 {
+		return "", false
+	}
+	var names []string
+	for _, v := range values {
+		if v.Flags&StatElidableAxisValueName != 0 {
+			continue
+		}
+		if name, ok := face.LocalizedName(v.Name, languages...); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return face.LocalizedName(stat.ElidedFallbackName, languages...)
+	}
+	return strings.Join(names, " "), true
+}
+
+// instanceStyleValues returns the axis values describing the instance selected by
+// SetVariations (see TableSTAT.StyleValues), and true if the position on every
+// variable axis is described by one of them.
+// The values of the variation axes are normalized, and compared to the coordinates
+// rounded to the F2Dot14 format (see currentCoordinate), so that the approximations
+// of the normalization don't matter. If `exact` is true, the ranges of values
+// (format 2) are ignored.
+func (face *Face) instanceStyleValues(stat *TableSTAT, exact bool) ([]StatAxisValue, bool) {
+	fvar, _ := face.Fvar()
+	var axes []VarAxis
+	if fvar != nil {
+		axes = fvar.Axes
+	}
+	location := make(map[Tag]float32, len(axes))
+	for i, axis := range axes {
+		location[axis.Tag] = face.currentCoordinate(fvar, i)
+	}
+	// the axis values in the normalized space
+	normalize := func(statAxis int, value float32, clamp bool) float32 {
+		if fvar == nil {
+			return value
+		}
+		i, ok := fvar.FindAxis(stat.Axes[statAxis].Tag)
+		if !ok {
+			return value
+		}
+		if axis := axes[i]; !clamp && (value < axis.Minimum || value > axis.Maximum) {
+			return float32(math.NaN()) // out of the font design space : never matched
+		}
+		return face.normalizedCoordinate(fvar, i, value)
+	}
+	normalized := TableSTAT{Axes: stat.Axes, Values: make([]StatAxisValue, len(stat.Values))}
+	for i, v := range stat.Values {
+		v.Locations = append([]StatLocation(nil), v.Locations...)
+		for j, loc := range v.Locations {
+			v.Locations[j].Value = normalize(loc.Axis, loc.Value, false)
+		}
+		if v.Format == 2 {
+			if exact {
+				v.Format = 1
+			} else {
+				v.RangeMin = normalize(v.Locations[0].Axis, v.RangeMin, true)
+				v.RangeMax = normalize(v.Locations[0].Axis, v.RangeMax, true)
+			}
+		}
+		normalized.Values[i] = v
+	}
+
+	indices := normalized.styleValues(location)
+	out := make([]StatAxisValue, len(indices))
+	described := make(map[Tag]bool)
+	for i, index := range indices {
+		out[i] = stat.Values[index]
+		for _, loc := range out[i].Locations {
+			described[stat.Axes[loc.Axis].Tag] = true
+		}
+	}
+	for _, axis := range axes {
+		if axis.Minimum < axis.Maximum && !described[axis.Tag] {
+			return out, false
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,42 @@
+package opentype
+
+import (
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+func TestStyleNameNamedInstances(t *testing.T) {
+	for _, file := range td.WithFvar {
+		face := loadFace(t, file.Path)
+		fvar, _ := face.Fvar()
+		for i := range fvar.Instances {
+			named, subfamily, _ := face.NamedInstance(i, "en")
+			if got, ok := named.StyleName("en"); !ok || got != subfamily {
+				t.Errorf("%s: instance %d: expected style %q, got %q (%v)", file.Path, i, subfamily, got, ok)
+			}
+		}
+	}
+}
+
+func TestStyleName(t *testing.T) {
+	wght, slnt := MustNewTag("wght"), MustNewTag("slnt")
+	for _, test := range []struct {
+		file       string
+		variations []Variation
+		expected   string // empty if the style can't be named
+	}{
+		{"common/Commissioner-VF.ttf", []Variation{{wght, 600}, {slnt, -12}}, "SemiBold Italic"},
+		{"common/Commissioner-VF.ttf", []Variation{{wght, 650}}, "SemiBold"}, // value range
+		{"common/Commissioner-VF.ttf", []Variation{{wght, 550}, {slnt, -5}}, ""},
+		{"common/Selawik-VF.ttf", []Variation{{wght, 600}}, "Semibold"},
+		{"common/Selawik-VF.ttf", []Variation{{wght, 500}}, ""},
+		{"common/SourceSans-VF.ttf", []Variation{{wght, 400}}, "Regular"},
+	} {
+		face := loadFace(t, test.file).WithVariations(test.variations)
+		got, ok := face.StyleName()
+		if ok != (test.expected != "") || got != test.expected {
+			t.Errorf("%s %v: expected %q, got %q (%v)", test.file, test.variations, test.expected, got, ok)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package opentype
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+)
+
+// tableSVG stores the documents of the 'SVG ' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/svg
+type tableSVG struct {
+	documents []svgDocumentRecord // sorted by glyph range
+	data      []byte              // the document list
+}
+
+type svgDocumentRecord struct {
+	firstGlyph, lastGlyph GID
+	offset, length        uint32 // into data
+}
+
+func parseTableSVG(data []byte) (tableSVG, error) {
+	if len(data) < 10 {
+		return tableSVG{}, errEOF
+	}
+	offset := binary.BigEndian.Uint32(data[2:])
+	if uint64(len(data)) < uint64(offset)+2 {
+		return tableSVG{}, errors.New("invalid SVG document list (EOF)")
+	}
+	list := data[offset:]
+	count := int(binary.BigEndian.Uint16(list))
+	if len(list) < 2+12*count {
+		return tableSVG{}, errors.New("invalid SVG document list (EOF)")
+	}
+	out := tableSVG{documents: make([]svgDocumentRecord, count), data: list}
+	for i := range out.documents {
+		record := list[2+12*i:]
+		doc := svgDocumentRecord{
+			firstGlyph: GID(binary.BigEndian.Uint16(record)),
+			lastGlyph:  GID(binary.BigEndian.Uint16(record[2:])),
+			offset:     binary.BigEndian.Uint32(record[4:]),
+			length:     binary.BigEndian.Uint32(record[8:]),
+		}
+		if doc.firstGlyph > doc.lastGlyph || uint64(doc.offset)+uint64(doc.length) > uint64(len(list)) {
+			return tableSVG{}, errors.New("invalid SVG document record")
+		}
+		out.documents[i] = doc
+	}
+	return out, nil
+}
+
+// document returns the record of the document defining the glyph.
+func (t *tableSVG) document(gid GID) (svgDocumentRecord, bool) {
+	lo, hi := 0, len(t.documents)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		doc := t.documents[mid]
+		switch {
+		case gid < doc.firstGlyph:
+			hi = mid
+		case gid > doc.lastGlyph:
+			lo = mid + 1
+		default:
+			return doc, true
+		}
+	}
+	return svgDocumentRecord{}, false
+}
+
+// decompressSVG returns the content of the document,
+// decompressed if it is stored as a gzip file.
+func decompressSVG(doc []byte) ([]byte, error) {
+	if !bytes.HasPrefix(doc, []byte{0x1F, 0x8B, 0x08}) {
+		return doc, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,186 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TableTrak stores the tracking of the AAT fonts : the adjustments
+// of the advances of the glyphs, which depend on the point size.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6trak.html
+type TableTrak struct {
+	// Horizontal and Vertical are empty if missing.
+	Horizontal, Vertical TrackData
+}
+
+// TrackData stores the tracking values of a layout direction.
+type TrackData struct {
+	// Sizes are the point sizes of the values of the tracks.
+	Sizes []float32
+	// Tracks are sorted by Track, even if they are not in the font.
+	Tracks []TrackEntry
+}
+
+// TrackEntry stores the tracking values of a track, such as "tight" or "loose".
+type TrackEntry struct {
+	// Track is 0 for the normal track, negative for the tighter
+	// tracks, and positive for the looser ones.
+	Track float32
+	Name  NameID
+	// Values are the tracking values for each size
+	// of TrackData.Sizes, in font units.
+	Values []int16
+}
+
+// Tracking returns the tracking value, in font units, for the point size `ptSize`
+// and the track `track` (0 for the normal track). The values are interpolated
+// between the sizes, and between the tracks, and clamped outside of the sizes.
+// It returns 0 if there are no tracks.
+func (td *TrackData) Tracking(ptSize, track float32) float32 {
+	count := len(td.Tracks)
+	if count == 0 {
+		return 0
+	}
+	// find the tracks surrounding `track`
+	i, j := 0, count-1
+	for i+1 < count && td.Tracks[i+1].Track <= track {
+		i++
+	}
+	for j > 0 && td.Tracks[j-1].Track >= track {
+		j--
+	}
+	a := td.sizeTracking(td.Tracks[i].Values, ptSize)
+	if i == j {
+		return a
+	}
+	b := td.sizeTracking(td.Tracks[j].Values, ptSize)
+	t := (track - td.Tracks[i].Track) / (td.Tracks[j].Track - td.Tracks[i].Track)
+	return a + t*(b-a)
+}
+
+// sizeTracking interpolates the values of a track for `ptSize`.
+func (td *TrackData) sizeTracking(values []int16, ptSize float32) float32 {
+	count := len(td.Sizes)
+	if count == 0 {
+		return 0
+	}
+	i := 0
+	for i < count && td.Sizes[i] < ptSize {
+		i++
+	}
+	if i == 0 {
+		return float32(values[0])
+	}
+	if i == count {
+		return float32(values[count-1])
+	}
+	if td.Sizes[i] == ptSize {
+		return float32(values[i])
+	}
+
+	s0, s1 := td.Sizes[i-1], td.Sizes[i]
+	v0, v1 := float32(values[i-1]), float32(values[i])
+	if s1 < s0 { // unsorted sizes
+		s0, s1 = s1, s0
+		v0, v1 = v1, v0
+	}
+	switch {
+	case ptSize < s0:
+		return v0
+	case ptSize > s1:
+		return v1
+	case s0 == s1:
+		return (v0 + v1) / 2
+	}
+	t := (ptSize - s0) / (s1 - s0)
+	return v0 + t*(v1-v0)
+}
+
+// ApplyTracking returns the tracking values of the normal track, in font units,
+// for the point size `ptSize`, which are added to the advances of the glyphs
+// in horizontal and vertical layout (see TrackData.Tracking).
+func (t *TableTrak) ApplyTracking(ptSize float32) (horizontal, vertical float32) {
+	return t.Horizontal.Tracking(ptSize, 0), t.Vertical.Tracking(ptSize, 0)
+}
+
+func parseTableTrak(data []byte) (TableTrak, error) {
+	if len(data) < 12 {
+		return TableTrak{}, errEOF
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 {
+		return TableTrak{}, fmt.Errorf("unsupported version %x", version)
+	}
+	if format := binary.BigEndian.Uint16(data[4:]); format != 0 {
+		return TableTrak{}, fmt.Errorf("unsupported format %d", format)
+	}
+	var (
+		out TableTrak
+		err error
+	)
+	if out.Horizontal, err = parseTrackData(data, binary.BigEndian.Uint16(data[6:])); err != nil {
+		return out, err
+	}
+	if out.Vertical, err = parseTrackData(data, binary.BigEndian.Uint16(data[8:])); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// parseTrackData returns an empty TrackData for null offsets.
+func parseTrackData(data []byte, offset uint16) (TrackData, error) {
+	if offset == 0 {
+		return TrackData{}, nil
+	}
+	if len(data) < int(offset)+8 {
+		return TrackData{}, errors.New("invalid track data (EOF)")
+	}
+	header := data[offset:]
+	trackCount, sizeCount := int(binary.BigEndian.Uint16(header)), int(binary.BigEndian.Uint16(header[2:]))
+	sizesOffset := int64(binary.BigEndian.Uint32(header[4:]))
+	if len(header) < 8+8*trackCount || int64(len(data)) < sizesOffset+4*int64(sizeCount) {
+		return TrackData{}, errors.New("invalid track data (EOF)")
+	}
+	out := TrackData{Sizes: make([]float32, sizeCount), Tracks: make([]TrackEntry, trackCount)}
+	for i := range out.Sizes {
+		out.Sizes[i] = fixed1616(binary.BigEndian.Uint32(data[sizesOffset+4*int64(i):]))
+	}
+	for i := range out.Tracks {
+		entry := header[8+8*i:]
+		out.Tracks[i] = TrackEntry{
+			Track: fixed1616(binary.BigEndian.Uint32(entry)),
+			Name:  NameID(binary.BigEndian.Uint16(entry[4:])),
+		}
+		var err error
+		if out.Tracks[i].Values, err = parseInt16s(data, int(binary.BigEndian.Uint16(entry[6:])), sizeCount); err != nil {
+			return out, errors.New("invalid track values (EOF)")
+		}
+	}
+	// the tracks should be sorted, but some fonts don't
+	sort.SliceStable(out.Tracks, func(i, j int) bool { return out.Tracks[i].Track < out.Tracks[j].Track })
+	return out, nil
+}
+
+// trak is the lazily parsed 'trak' table.
+type trak struct {
+	once  sync.Once
+	table *TableTrak // nil if missing or invalid
+}
+
+// Trak returns the tracking table, parsed on first use,
+// or false if the font has no 'trak' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Trak() (*TableTrak, bool) {
+	face.trak.once.Do(func() {
+		if data, err := face.GetRawTable(tagTrak); err == nil {
+			if table, err := parseTableTrak(data); err != nil {
+				face.warnings.addError(face.tableError(tagTrak, err))
+			} else {
+				face.trak.table = &table
+			}
+		}
+	})
+	return face.trak.table, face.trak.table != nil
+}
@@ -0,0 +1,50 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// tableVORG stores the y coordinate of the vertical origin of the glyphs,
+// found in CFF fonts.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/vorg
+type tableVORG struct {
+	defaultOrigin int16
+	origins       []vertOrigin // sorted by glyph
+}
+
+type vertOrigin struct {
+	glyph  GID
+	origin int16
+}
+
+func parseTableVORG(data []byte) (tableVORG, error) {
+	const headerSize = 8
+	if len(data) < headerSize {
+		return tableVORG{}, errEOF
+	}
+	out := tableVORG{defaultOrigin: int16(binary.BigEndian.Uint16(data[4:]))}
+	count := int(binary.BigEndian.Uint16(data[6:]))
+	if len(data) < headerSize+4*count {
+		return tableVORG{}, errEOF
+	}
+	out.origins = make([]vertOrigin, count)
+	for i := range out.origins {
+		record := data[headerSize+4*i:]
+		out.origins[i] = vertOrigin{
+			glyph:  GID(binary.BigEndian.Uint16(record)),
+			origin: int16(binary.BigEndian.Uint16(record[2:])),
+		}
+	}
+	return out, nil
+}
+
+// origin returns the y coordinate of the vertical origin of the glyph,
+// which is the default one for the glyphs not listed.
+func (t *tableVORG) origin(gid GID) int16 {
+	i := sort.Search(len(t.origins), func(i int) bool { return t.origins[i].glyph >= gid })
+	if i < len(t.origins) && t.origins[i].glyph == gid {
+		return t.origins[i].origin
+	}
+	return t.defaultOrigin
+}
@@ -0,0 +1,209 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf16"
+)
+
+// TableZapf stores the glyph reference information of the AAT fonts :
+// the characters, names, groups and features of the glyphs.
+// The glyph information is parsed on demand, see GlyphInfo.
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6Zapf.html
+type TableZapf struct {
+	offsets []uint32 // the offsets of the glyph information in data, per glyph
+	data    []byte   // the whole table
+	extra   []byte   // the extra information space, holding the groups and features
+}
+
+// ZapfGlyphInfo stores the information of a glyph of the 'Zapf' table.
+type ZapfGlyphInfo struct {
+	// Text is the sequence of characters the glyph represents,
+	// which has several characters for ligatures.
+	Text []rune
+	// Names are the names and identifiers of the glyph.
+	Names []ZapfName
+	// Groups are the groups of glyphs the glyph belongs to,
+	// such as alternate forms, or nil.
+	Groups []ZapfGroup
+	// Features are the AAT features producing the glyph, or nil.
+	Features *ZapfFeatures
+}
+
+// ZapfName is a name or identifier of a glyph, whose meaning
+// is given by its kind (see the Zapf constants).
+type ZapfName struct {
+	Kind uint8
+	// Name is used for the kinds below 64.
+	Name string
+	// ID is used for the kinds 64 and above.
+	ID uint16
+}
+
+// Some of the kinds of ZapfName.
+const (
+	ZapfUniversalName = 0
+	ZapfAppleName     = 1
+	ZapfAdobeName     = 2
+	ZapfAFIIName      = 3
+	ZapfUnicodeName   = 4
+	ZapfCID           = 64
+)
+
+// ZapfGroup is a named group of glyphs.
+type ZapfGroup struct {
+	Name   NameID
+	Glyphs []GID
+}
+
+// ZapfFeatures stores the AAT features producing a glyph.
+type ZapfFeatures struct {
+	Context  uint16
+	Features []AATFeature
+}
+
+const (
+	zapfNone        = 0xFFFFFFFF // null offset of groups and features
+	zapfGroupsGroup = 0x4000     // flag of the groups of groups
+)
+
+// GlyphInfo returns the information of the glyph, or false
+// if the glyph has none, or if it is invalid.
+func (t *TableZapf) GlyphInfo(gid GID) (ZapfGlyphInfo, bool) {
+	if int(gid) >= len(t.offsets) {
+		return ZapfGlyphInfo{}, false
+	}
+	info, err := t.parseGlyphInfo(t.offsets[gid])
+	return info, err == nil
+}
+
+func (t *TableZapf) parseGlyphInfo(offset uint32) (ZapfGlyphInfo, error) {
+	if int64(len(t.data)) < int64(offset)+10 {
+		return ZapfGlyphInfo{}, errors.New("invalid glyph info (EOF)")
+	}
+	data := t.data[offset:]
+	groupOffset, featOffset := binary.BigEndian.Uint32(data), binary.BigEndian.Uint32(data[4:])
+	chars, err := parseUint16s(data, 10, int(binary.BigEndian.Uint16(data[8:])))
+	if err != nil {
+		return ZapfGlyphInfo{}, errors.New("invalid glyph characters (EOF)")
+	}
+	out := ZapfGlyphInfo{Text: utf16.Decode(chars)}
+	cursor := 10 + 2*len(chars)
+	if len(data) < cursor+2 {
+		return out, errors.New("invalid glyph names (EOF)")
+	}
+	out.Names = make([]ZapfName, binary.BigEndian.Uint16(data[cursor:]))
+	cursor += 2
+	for i := range out.Names {
+		if len(data) < cursor+3 {
+			return out, errors.New("invalid glyph name (EOF)")
+		}
+		name := ZapfName{Kind: data[cursor]}
+		if name.Kind >= 64 {
+			name.ID = binary.BigEndian.Uint16(data[cursor+1:])
+			cursor += 3
+		} else {
+			length := int(data[cursor+1])
+			if len(data) < cursor+2+length {
+				return out, errors.New("invalid glyph name (EOF)")
+			}
+			name.Name = string(data[cursor+2 : cursor+2+length])
+			cursor += 2 + length
+		}
+		out.Names[i] = name
+	}
+	if groupOffset != zapfNone {
+		if out.Groups, err = t.parseGroups(groupOffset, true); err != nil {
+			return out, err
+		}
+	}
+	if featOffset != zapfNone {
+		if int64(len(t.extra)) < int64(featOffset)+4 {
+			return out, errors.New("invalid glyph features (EOF)")
+		}
+		feat := t.extra[featOffset:]
+		count := int(binary.BigEndian.Uint16(feat[2:]))
+		if len(feat) < 4+4*count {
+			return out, errors.New("invalid glyph features (EOF)")
+		}
+		out.Features = &ZapfFeatures{Context: binary.BigEndian.Uint16(feat), Features: make([]AATFeature, count)}
+		for i := range out.Features.Features {
+			out.Features.Features[i] = AATFeature{Type: binary.BigEndian.Uint16(feat[4+4*i:]), Setting: binary.BigEndian.Uint16(feat[6+4*i:])}
+		}
+	}
+	return out, nil
+}
+
+// parseGroups parses a group, or a group of groups if `nested` is true.
+// The groups of groups may not be nested.
+func (t *TableZapf) parseGroups(offset uint32, nested bool) ([]ZapfGroup, error) {
+	if int64(len(t.extra)) < int64(offset)+4 {
+		return nil, errors.New("invalid glyph group (EOF)")
+	}
+	data := t.extra[offset:]
+	flags, count := binary.BigEndian.Uint16(data), int(binary.BigEndian.Uint16(data[2:]))
+	if flags&zapfGroupsGroup == 0 {
+		glyphs, err := parseGlyphs(data, 4, count)
+		if err != nil {
+			return nil, errors.New("invalid glyph group (EOF)")
+		}
+		return []ZapfGroup{{Name: NameID(flags), Glyphs: glyphs}}, nil
+	}
+	if !nested {
+		return nil, errors.New("invalid nested glyph groups")
+	}
+	if len(data) < 4+4*count {
+		return nil, errors.New("invalid glyph groups (EOF)")
+	}
+	out := make([]ZapfGroup, 0, count)
+	for i := 0; i < count; i++ {
+		groups, err := t.parseGroups(binary.BigEndian.Uint32(data[4+4*i:]), false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, groups...)
+	}
+	return out, nil
+}
+
+func parseTableZapf(data []byte, numGlyphs int) (TableZapf, error) {
+	if len(data) < 8+4*numGlyphs {
+		return TableZapf{}, errEOF
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x00020000 {
+		return TableZapf{}, fmt.Errorf("unsupported version %x", version)
+	}
+	extraInfo := binary.BigEndian.Uint32(data[4:])
+	if int64(extraInfo) > int64(len(data)) {
+		return TableZapf{}, errors.New("invalid extra info (EOF)")
+	}
+	out := TableZapf{offsets: make([]uint32, numGlyphs), data: data, extra: data[extraInfo:]}
+	for i := range out.offsets {
+		out.offsets[i] = binary.BigEndian.Uint32(data[8+4*i:])
+	}
+	return out, nil
+}
+
+// zapf is the lazily parsed 'Zapf' table.
+type zapf struct {
+	once  sync.Once
+	table *TableZapf // nil if missing or invalid
+}
+
+// Zapf returns the glyph reference table, parsed on first use,
+// or false if the font has no 'Zapf' table. Invalid tables
+// are ignored, and reported in the warnings.
+func (face *Face) Zapf() (*TableZapf, bool) {
+	face.zapf.once.Do(func() {
+		if data, err := face.GetRawTable(tagZapf); err == nil {
+			if table, err := parseTableZapf(data, face.NumGlyphs); err != nil {
+				face.warnings.addError(face.tableError(tagZapf, err))
+			} else {
+				face.zapf.table = &table
+			}
+		}
+	})
+	return face.zapf.table, face.zapf.table != nil
+}
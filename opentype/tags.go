@@ -0,0 +1,171 @@
+package opentype
+
+import (
+	"encoding/binary"
+
+	"github.com/go-text/font"
+)
+
+// GID is used to identify glyphs in a font.
+type GID = font.GID
+
+// Tag represents an open-type name.
+// These are technically uint32's, but are usually
+// displayed in ASCII as they are all acronyms.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/otff#data-types
+type Tag uint32
+
+// MustNewTag gives you the Tag corresponding to the acronym.
+// This function will panic if the string passed in is not 4 bytes long.
+func MustNewTag(str string) Tag {
+	bytes := []byte(str)
+
+	if len(bytes) != 4 {
+		panic("invalid tag: must be exactly 4 bytes")
+	}
+
+	return newTag(bytes)
+}
+
+func newTag(bytes []byte) Tag {
+	return Tag(binary.BigEndian.Uint32(bytes))
+}
+
+// String returns the ASCII representation of the tag.
+func (tag Tag) String() string {
+	return string([]byte{
+		byte(tag >> 24 & 0xFF),
+		byte(tag >> 16 & 0xFF),
+		byte(tag >> 8 & 0xFF),
+		byte(tag & 0xFF),
+	})
+}
+
+var (
+	// TypeTrueType is the first four bytes of an OpenType file containing a TrueType font
+	TypeTrueType = Tag(0x00010000)
+	// TypeAppleTrueType is the first four bytes of an OpenType file containing a TrueType font
+	// (specifically one designed for Apple products, it's recommended to use TypeTrueType instead)
+	TypeAppleTrueType = MustNewTag("true")
+	// TypePostScript1 is the first four bytes of an OpenType file containing a PostScript Type 1 font
+	TypePostScript1 = MustNewTag("typ1")
+	// TypeOpenType is the first four bytes of an OpenType file containing a PostScript Type 2 font
+	// as specified by OpenType
+	TypeOpenType = MustNewTag("OTTO")
+	// TypeCollection is the first four bytes of a font collection (.ttc, .otc)
+	TypeCollection = MustNewTag("ttcf")
+
+	// SignatureWOFF is the magic number at the start of a WOFF file.
+	SignatureWOFF = MustNewTag("wOFF")
+	// SignatureWOFF2 is the magic number at the start of a WOFF2 file.
+	SignatureWOFF2 = MustNewTag("wOF2")
+)
+
+var (
+	// tagHead represents the 'head' table, which contains the font header
+	tagHead = MustNewTag("head")
+	// tagBhed is the Apple replacement for 'head', found in bitmap only fonts
+	tagBhed = MustNewTag("bhed")
+	// tagMaxp represents the 'maxp' table, which contains the maximum profile
+	tagMaxp = MustNewTag("maxp")
+	// tagCmap represents the 'cmap' table, which contains the character to glyph mappings
+	tagCmap = MustNewTag("cmap")
+	// tagLoca represents the 'loca' table, which contains the offsets of the TrueType glyphs
+	tagLoca = MustNewTag("loca")
+	// tagGlyf represents the 'glyf' table, which contains the TrueType glyph outlines
+	tagGlyf = MustNewTag("glyf")
+	// tagHhea represents the 'hhea' table, which contains the horizontal header
+	tagHhea = MustNewTag("hhea")
+	// tagHmtx represents the 'hmtx' table, which contains the horizontal metrics
+	tagHmtx = MustNewTag("hmtx")
+	// tagVhea represents the 'vhea' table, which contains the vertical header
+	tagVhea = MustNewTag("vhea")
+	// tagVmtx represents the 'vmtx' table, which contains the vertical metrics
+	tagVmtx = MustNewTag("vmtx")
+	// tagVORG represents the 'VORG' table, which contains the vertical origins of CFF glyphs
+	tagVORG = MustNewTag("VORG")
+	// tagHVAR represents the 'HVAR' table, which contains the variations of the horizontal metrics
+	tagHVAR = MustNewTag("HVAR")
+	// tagVVAR represents the 'VVAR' table, which contains the variations of the vertical metrics
+	tagVVAR = MustNewTag("VVAR")
+	// tagOS2 represents the 'OS/2' table, which contains the font wide metrics required on Windows
+	tagOS2 = MustNewTag("OS/2")
+	// tagMVAR represents the 'MVAR' table, which contains the variations of the font wide metrics
+	tagMVAR = MustNewTag("MVAR")
+	// tagPost represents the 'post' table, which contains the PostScript information and glyph names
+	tagPost = MustNewTag("post")
+	// tagName represents the 'name' table, which contains the font names
+	tagName = MustNewTag("name")
+	// tagFvar represents the 'fvar' table, which contains the axes of variable fonts
+	tagFvar = MustNewTag("fvar")
+	// tagAvar represents the 'avar' table, which modifies the normalization of the variation coordinates
+	tagAvar = MustNewTag("avar")
+	// tagGvar represents the 'gvar' table, which contains the variations of the TrueType outlines
+	tagGvar = MustNewTag("gvar")
+	// tagCvt represents the 'cvt ' table, which contains the control values used by the TrueType instructions
+	tagCvt = MustNewTag("cvt ")
+	// tagCvar represents the 'cvar' table, which contains the variations of the control values
+	tagCvar = MustNewTag("cvar")
+	// tagMeta represents the 'meta' table, which contains the metadata of the font
+	tagMeta = MustNewTag("meta")
+	// tagSTAT represents the 'STAT' table, which contains the style attributes
+	tagSTAT = MustNewTag("STAT")
+	// tagGDEF represents the 'GDEF' table, which contains the glyph definitions used by the layout tables
+	tagGDEF = MustNewTag("GDEF")
+	// tagGSUB represents the 'GSUB' table, which contains the glyph substitutions
+	tagGSUB = MustNewTag("GSUB")
+	// tagGPOS represents the 'GPOS' table, which contains the glyph positionings
+	tagGPOS = MustNewTag("GPOS")
+	// tagBASE represents the 'BASE' table, which contains the baselines of the scripts
+	tagBASE = MustNewTag("BASE")
+	// tagJSTF represents the 'JSTF' table, which contains the justification data
+	tagJSTF = MustNewTag("JSTF")
+	// tagMATH represents the 'MATH' table, which contains the data used to lay out mathematical formulas
+	tagMATH = MustNewTag("MATH")
+	// tagKern represents the 'kern' table, which contains the kerning of the glyph pairs
+	tagKern = MustNewTag("kern")
+	// tagKerx represents the 'kerx' table, which contains the AAT extended kerning
+	tagKerx = MustNewTag("kerx")
+	// tagMorx represents the 'morx' table, which contains the AAT glyph transformations
+	tagMorx = MustNewTag("morx")
+	// tagTrak represents the 'trak' table, which contains the AAT tracking values
+	tagTrak = MustNewTag("trak")
+	// tagFeat represents the 'feat' table, which contains the names of the AAT features
+	tagFeat = MustNewTag("feat")
+	// tagAnkr represents the 'ankr' table, which contains the AAT anchor points
+	tagAnkr = MustNewTag("ankr")
+	// tagBsln represents the 'bsln' table, which contains the AAT baselines
+	tagBsln = MustNewTag("bsln")
+	// tagOpbd represents the 'opbd' table, which contains the AAT optical bounds
+	tagOpbd = MustNewTag("opbd")
+	// tagProp represents the 'prop' table, which contains the AAT glyph properties
+	tagProp = MustNewTag("prop")
+	// tagZapf represents the 'Zapf' table, which contains the AAT glyph reference information
+	tagZapf = MustNewTag("Zapf")
+	// tagLcar represents the 'lcar' table, which contains the AAT ligature caret positions
+	tagLcar = MustNewTag("lcar")
+	// tagCOLR represents the 'COLR' table, which contains the color glyphs
+	tagCOLR = MustNewTag("COLR")
+	// tagCPAL represents the 'CPAL' table, which contains the color palettes
+	tagCPAL = MustNewTag("CPAL")
+	// tagSVG represents the 'SVG ' table, which contains the SVG glyph documents
+	tagSVG = MustNewTag("SVG ")
+	// tagSbix represents the 'sbix' table, which contains the Apple bitmap strikes
+	tagSbix = MustNewTag("sbix")
+	// tagCBLC represents the 'CBLC' table, which locates the color bitmaps
+	tagCBLC = MustNewTag("CBLC")
+	// tagCBDT represents the 'CBDT' table, which contains the color bitmaps
+	tagCBDT = MustNewTag("CBDT")
+	// tagEBLC represents the 'EBLC' table, which locates the embedded bitmaps
+	tagEBLC = MustNewTag("EBLC")
+	// tagEBDT represents the 'EBDT' table, which contains the embedded bitmaps
+	tagEBDT = MustNewTag("EBDT")
+	// tagBloc represents the 'bloc' table, the Apple equivalent of 'EBLC'
+	tagBloc = MustNewTag("bloc")
+	// tagBdat represents the 'bdat' table, the Apple equivalent of 'EBDT'
+	tagBdat = MustNewTag("bdat")
+	// tagCFF represents the 'CFF ' table, which contains the PostScript glyph outlines
+	tagCFF = MustNewTag("CFF ")
+	// tagCFF2 represents the 'CFF2' table, which contains the PostScript glyph outlines of variable fonts
+	tagCFF2 = MustNewTag("CFF2")
+)
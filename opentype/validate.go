@@ -0,0 +1,234 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// validate performs the additional checks of the strict mode,
+// on the tables kept by the parse options (see ParseOptions.Strict).
+func (face *Face) validate(opts ParseOptions) error {
+	if err := face.validateDirectory(); err != nil {
+		return err
+	}
+	if err := face.validateRequired(opts); err != nil {
+		return err
+	}
+	if err := face.validateHead(); err != nil {
+		return err
+	}
+	if err := face.validateMaxp(); err != nil {
+		return err
+	}
+	if err := face.validateCmap(); err != nil {
+		return err
+	}
+	if err := face.validateTables(); err != nil {
+		return err
+	}
+	return face.validateOutlines()
+}
+
+// validateRequired checks that the tables required for all fonts are present,
+// unless skipped by `opts`. The 'OS/2' table is optional for Apple fonts.
+func (face *Face) validateRequired(opts ParseOptions) error {
+	for _, tag := range [...]Tag{tagHhea, tagHmtx, tagName, tagPost, tagOS2} {
+		if tag == tagOS2 && (face.Type == TypeAppleTrueType || face.HasTable(tagBhed)) {
+			continue
+		}
+		if !face.HasTable(tag) && !opts.skip(tag) {
+			return missingTable(tag)
+		}
+	}
+	return nil
+}
+
+// validateTables parses the tables which are otherwise loaded on demand,
+// and returns the first invalid one.
+func (face *Face) validateTables() error {
+	face.loadMetrics()
+	face.loadGlyphNames()
+	face.loadCvt()
+	face.loadColor()
+	face.loadBitmaps()
+	face.loadLcar()
+	face.NameTable()
+	face.Fvar()
+	face.Avar()
+	face.STAT()
+	face.Meta()
+	face.GDEF()
+	face.GSUB()
+	face.GPOS()
+	face.BASE()
+	face.JSTF()
+	face.MATH()
+	face.Kern()
+	face.Kerx()
+	face.Morx()
+	face.Trak()
+	face.Feat()
+	face.Ankr()
+	face.Bsln()
+	face.Opbd()
+	face.Prop()
+	face.Zapf()
+	if err := face.warnings.firstError(); err != nil {
+		return err
+	}
+	if err := face.validateMetrics(tagHhea, tagHmtx); err != nil {
+		return err
+	}
+	return face.validateMetrics(tagVhea, tagVmtx)
+}
+
+// validateMetrics checks that the number of metrics of the 'hhea' (or 'vhea')
+// table is valid, and that the 'hmtx' (or 'vmtx') table is long enough,
+// which are otherwise silently clamped.
+func (face *Face) validateMetrics(headerTag, metricsTag Tag) error {
+	data, err := face.GetRawTable(headerTag)
+	if err != nil {
+		return nil
+	}
+	header, err := parseTableHhea(data)
+	if err != nil {
+		return face.tableError(headerTag, err)
+	}
+	if header.numMetrics == 0 || header.numMetrics > face.NumGlyphs {
+		return face.tableError(headerTag, fmt.Errorf("invalid number of metrics %d for %d glyphs", header.numMetrics, face.NumGlyphs))
+	}
+	data, err = face.GetRawTable(metricsTag)
+	if err != nil {
+		return missingTable(metricsTag)
+	}
+	if minLength := 4*header.numMetrics + 2*(face.NumGlyphs-header.numMetrics); len(data) < minLength {
+		return face.tableError(metricsTag, fmt.Errorf("invalid length %d for %d glyphs (EOF)", len(data), face.NumGlyphs))
+	}
+	return nil
+}
+
+// validateOutlines checks that the outline of every glyph may be decoded.
+// Fonts without outline tables (such as bitmap only fonts) are accepted.
+func (face *Face) validateOutlines() error {
+	if !face.HasTable(tagGlyf) && !face.HasTable(tagCFF) && !face.HasTable(tagCFF2) {
+		return nil
+	}
+	if err := face.loadOutlines(); err != nil {
+		return err
+	}
+	for gid := GID(0); int(gid) < face.NumGlyphs; gid++ {
+		if cff := face.outlines.cff; cff != nil {
+			if _, ok := cff.GlyphOutline(gid); !ok {
+				return face.tableError(face.outlinesTag(), fmt.Errorf("invalid charstring for glyph %d", gid))
+			}
+			continue
+		}
+		var points glyfPoints
+		if _, err := face.appendGlyfPoints(gid, 0, &points); err != nil {
+			return face.tableError(tagGlyf, fmt.Errorf("glyph %d: %s", gid, err))
+		}
+	}
+	return nil
+}
+
+// outlinesTag returns the tag of the table storing the CFF outlines.
+func (face *Face) outlinesTag() Tag {
+	if face.HasTable(tagCFF2) {
+		return tagCFF2
+	}
+	return tagCFF
+}
+
+// validateDirectory checks that the tables don't overlap,
+// and that their checksums are correct.
+func (face *Face) validateDirectory() error {
+	tables, err := face.ListTables()
+	if err != nil {
+		return err
+	}
+	var prev TableRecord
+	for _, cur := range tables {
+		if cur.Length == 0 { // empty tables can't overlap
+			continue
+		}
+		if prev.Length != 0 && uint64(prev.Offset)+uint64(prev.Length) > uint64(cur.Offset) {
+			return &TableError{Tag: cur.Tag, Offset: cur.Offset, Err: fmt.Errorf("overlaps table '%s'", prev.Tag)}
+		}
+		prev = cur
+	}
+	for _, table := range tables {
+		if !table.ChecksumValid {
+			return &TableError{Tag: table.Tag, Offset: table.Offset,
+				Err: fmt.Errorf("invalid checksum: expected 0x%08x, got 0x%08x", table.Checksum, table.actualChecksum)}
+		}
+	}
+	return nil
+}
+
+func (face *Face) validateHead() error {
+	tag := tagBhed // see loadHeadTable
+	data, err := face.GetRawTable(tagBhed)
+	if err != nil {
+		tag = tagHead
+		data, err = face.GetRawTable(tagHead)
+		if err != nil {
+			return missingTable(tagHead)
+		}
+	}
+	if magic := binary.BigEndian.Uint32(data[12:]); magic != 0x5F0F3CF5 {
+		return face.tableError(tag, fmt.Errorf("invalid magic number 0x%08x", magic))
+	}
+	if upem := face.Head.UnitsPerEm; upem < 16 || upem > 16384 {
+		return face.tableError(tag, fmt.Errorf("invalid units per em %d", upem))
+	}
+	if format := face.Head.IndexToLocFormat; format != 0 && format != 1 {
+		return face.tableError(tag, fmt.Errorf("invalid index to loc format %d", format))
+	}
+	if face.Head.XMin > face.Head.XMax || face.Head.YMin > face.Head.YMax {
+		return face.tableError(tag, fmt.Errorf("invalid bounding box (%d, %d, %d, %d)",
+			face.Head.XMin, face.Head.YMin, face.Head.XMax, face.Head.YMax))
+	}
+	return nil
+}
+
+func (face *Face) validateMaxp() error {
+	data, err := face.GetRawTable(tagMaxp)
+	if err != nil {
+		return missingTable(tagMaxp)
+	}
+	switch version := binary.BigEndian.Uint32(data); version {
+	case 0x00005000:
+	case 0x00010000:
+		if len(data) < 32 {
+			return face.tableError(tagMaxp, fmt.Errorf("invalid length %d for version 1.0", len(data)))
+		}
+	default:
+		return face.tableError(tagMaxp, fmt.Errorf("invalid version 0x%08x", version))
+	}
+	if face.NumGlyphs == 0 {
+		return face.tableError(tagMaxp, errors.New("no glyphs"))
+	}
+	return nil
+}
+
+// validateCmap checks that the glyphs of the selected cmap,
+// and of the variation sequences, are valid.
+func (face *Face) validateCmap() error {
+	if face.cmap != nil {
+		for it := face.cmap.Iter(); it.Next(); {
+			r, gid := it.Char()
+			if int(gid) >= face.NumGlyphs {
+				return face.tableError(tagCmap, fmt.Errorf("glyph %d for rune %U is out of range", gid, r))
+			}
+		}
+	}
+	for _, vs := range face.cmapVar {
+		for _, m := range vs.NonDefaultUVS {
+			if int(m.Glyph) >= face.NumGlyphs {
+				return face.tableError(tagCmap, fmt.Errorf("glyph %d for sequence %U %U is out of range", m.Glyph, m.Unicode, vs.Selector))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+package opentype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// SetVariations sets the normalized variation coordinates (in [-1, 1], one per
+// axis of the 'fvar' table) used by the methods of the face supporting variable fonts.
+// Missing coordinates are treated as 0. Passing nil selects the default instance.
+func (face *Face) SetVariations(coords []float32) {
+	face.coords = append([]float32(nil), coords...)
+	if face.outlines.cff != nil {
+		face.outlines.cff.SetVariations(face.coords)
+	}
+}
+
+// Variations returns the normalized variation coordinates set by SetVariations,
+// or nil for the default instance.
+func (face *Face) Variations() []float32 { return face.coords }
+
+// Variation is a user space coordinate on a variation axis.
+type Variation struct {
+	Tag   Tag     // tag of the axis, such as 'wght'
+	Value float32 // user space value, such as 700
+}
+
+// WithVariations returns a copy of the face whose variation coordinates
+// are set to `variations` (see SetVariations), so that the metrics,
+// outlines and layout queries of the copy reflect the variations.
+// The axes not listed in `variations` use their default value,
+// the values are clamped to the range of their axis, and the unknown
+// axes are ignored.
+// The copy shares the font data with `face` : it is only valid as long
+// as `face` is, and its Close method does nothing.
+func (face *Face) WithVariations(variations []Variation) *Face {
+	var coords []float32
+	if fvar, ok := face.Fvar(); ok {
+		user := make([]float32, len(fvar.Axes))
+		for i, axis := range fvar.Axes {
+			user[i] = axis.Default
+		}
+		for _, v := range variations {
+			if i, ok := fvar.FindAxis(v.Tag); ok {
+				user[i] = v.Value
+			}
+		}
+		coords = face.NormalizeCoordinates(user)
+	}
+	return face.withCoordinates(coords)
+}
+
+// NamedInstance returns a copy of the face whose variation coordinates are the ones
+// of the named instance at `index` in the 'fvar' table (see WithVariations), along
+// with the name of its subfamily (such as "Bold Condensed"), in the first available
+// of the `languages` (see LocalizedName).
+// It returns false if the font is not variable, or if `index` is out of range.
+// The subfamily name is empty if the font does not provide it.
+func (face *Face) NamedInstance(index int, languages ...string) (*Face, string, bool) {
+	fvar, ok := face.Fvar()
+	if !ok || index < 0 || index >= len(fvar.Instances) {
+		return nil, "", false
+	}
+	instance := fvar.Instances[index]
+	subfamily, _ := face.LocalizedName(instance.Subfamily, languages...)
+	return face.withCoordinates(face.NormalizeCoordinates(instance.Coords)), subfamily, true
+}
+
+// InstanceMatch locates variation coordinates relatively
+// to the named instances of a font, see MatchNamedInstance.
+type InstanceMatch struct {
+	// Index is the index in TableFvar.Instances of the named instance closest
+	// to the coordinates, or -1 if the font has no named instances.
+	Index int
+	// Exact is true if the coordinates are the ones of this named instance.
+	Exact bool
+	// Default is true if the coordinates are the ones of the default instance.
+	Default bool
+}
+
+// MatchNamedInstance returns the named instance whose coordinates are the normalized
+// coordinates `coords` (see SetVariations), or the closest one, the distance being
+// measured in the normalized space. The missing coordinates are treated as 0, and
+// the coordinates are compared with the precision of the F2Dot14 format : the
+// coordinates of the instances are normalized by NormalizeCoordinates.
+// It returns false if the font is not variable.
+func (face *Face) MatchNamedInstance(coords []float32) (InstanceMatch, bool) {
+	const epsilon = 1. / (1 << 15) // half of the precision of the normalized coordinates
+	fvar, ok := face.Fvar()
+	if !ok {
+		return InstanceMatch{}, false
+	}
+	coord := func(i int) float32 {
+		if i < len(coords) {
+			return coords[i]
+		}
+		return 0
+	}
+
+	out := InstanceMatch{Index: -1, Default: true}
+	for i := range fvar.Axes {
+		if abs(coord(i)) >= epsilon {
+			out.Default = false
+		}
+	}
+	var minDistance float32
+	for index, instance := range fvar.Instances {
+		var distance float32
+		exact := len(instance.Coords) >= len(fvar.Axes)
+		normalized := face.NormalizeCoordinates(instance.Coords)
+		for i := range fvar.Axes {
+			diff := normalized[i] - coord(i)
+			if abs(diff) >= epsilon {
+				exact = false
+			}
+			distance += diff * diff
+		}
+		if exact {
+			out.Index, out.Exact = index, true
+			return out, true
+		}
+		if out.Index == -1 || distance < minDistance {
+			out.Index, minDistance = index, distance
+		}
+	}
+	return out, true
+}
+
+// withCoordinates returns a copy of the face, with the normalized
+// coordinates `coords`. The copy shares the font data and the tables
+// loaded by ParseFont, and parses the other tables on demand.
+func (face *Face) withCoordinates(coords []float32) *Face {
+	out := &Face{
+		src:                face.src,
+		tables:             face.tables,
+		cmap:               face.cmap,
+		cmapVar:            face.cmapVar,
+		Head:               face.Head,
+		NumGlyphs:          face.NumGlyphs,
+		Type:               face.Type,
+		synthesizeVertical: face.synthesizeVertical,
+		repairs:            face.repairs,
+	}
+	face.warnings.copyLoading(&out.warnings)
+	out.SetVariations(coords)
+	return out
+}
+
+// NormalizeCoordinates converts the user space coordinates `coords` (one per axis
+// of the 'fvar' table, such as 700 for a bold weight) to normalized coordinates,
+// suitable for SetVariations.
+// The coordinates are clamped to the range of their axis, the missing ones are
+// set to the default value of their axis, and the 'avar' mapping is applied.
+// As in the font tables, the result is rounded to the precision of the F2Dot14 format.
+// It returns nil if the font is not variable.
+func (face *Face) NormalizeCoordinates(coords []float32) []float32 {
+	fvar, ok := face.Fvar()
+	if !ok {
+		return nil
+	}
+	out := make([]float32, len(fvar.Axes))
+	for i, axis := range fvar.Axes {
+		coord := axis.Default
+		if i < len(coords) {
+			coord = coords[i]
+		}
+		out[i] = face.normalizedCoordinate(fvar, i, coord)
+	}
+	return out
+}
+
+// DenormalizeCoordinates is the inverse of NormalizeCoordinates, converting the
+// normalized coordinates `coords` to user space. The missing coordinates are
+// treated as 0.
+// It returns nil if the font is not variable.
+func (face *Face) DenormalizeCoordinates(coords []float32) []float32 {
+	fvar, ok := face.Fvar()
+	if !ok {
+		return nil
+	}
+	out := make([]float32, len(fvar.Axes))
+	for i := range fvar.Axes {
+		var coord float32
+		if i < len(coords) {
+			coord = coords[i]
+		}
+		out[i] = face.userCoordinate(fvar, i, coord)
+	}
+	return out
+}
+
+// roundF2dot14 rounds `v` to the nearest multiple of 1/16384.
+func roundF2dot14(v float32) float32 { return float32(math.Round(float64(v)*(1<<14))) / (1 << 14) }
+
+// isVariable returns true if non default coordinates are set
+func (face *Face) isVariable() bool {
+	for _, c := range face.coords {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// itemVariationStore stores the variation deltas of
+// the HVAR, VVAR, MVAR and GDEF tables.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#item-variation-store
+type itemVariationStore struct {
+	regions [][]regionAxis // indexed by region, then axis
+	data    []itemVariationData
+}
+
+type regionAxis struct{ start, peak, end float32 }
+
+type itemVariationData struct {
+	regionIndexes []uint16
+	deltas        [][]int32 // indexed by item, then region
+}
+
+func parseItemVariationStore(data []byte, offset uint32) (itemVariationStore, error) {
+	if int64(offset)+8 > int64(len(data)) {
+		return itemVariationStore{}, errors.New("invalid item variation store (EOF)")
+	}
+	data = data[offset:]
+	if format := binary.BigEndian.Uint16(data); format != 1 {
+		return itemVariationStore{}, fmt.Errorf("unsupported item variation store format %d", format)
+	}
+	regionsOffset := binary.BigEndian.Uint32(data[2:])
+	count := int(binary.BigEndian.Uint16(data[6:]))
+	if len(data) < 8+4*count {
+		return itemVariationStore{}, errors.New("invalid item variation store (EOF)")
+	}
+
+	var (
+		out itemVariationStore
+		err error
+	)
+	out.regions, err = parseVariationRegions(data, regionsOffset)
+	if err != nil {
+		return out, err
+	}
+	out.data = make([]itemVariationData, count)
+	for i := range out.data {
+		out.data[i], err = parseItemVariationData(data, binary.BigEndian.Uint32(data[8+4*i:]), len(out.regions))
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func parseVariationRegions(data []byte, offset uint32) ([][]regionAxis, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return nil, errors.New("invalid variation region list (EOF)")
+	}
+	data = data[offset:]
+	axisCount := int(binary.BigEndian.Uint16(data))
+	regionCount := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+6*axisCount*regionCount {
+		return nil, errors.New("invalid variation region list (EOF)")
+	}
+	out := make([][]regionAxis, regionCount)
+	for i := range out {
+		out[i] = make([]regionAxis, axisCount)
+		for j := range out[i] {
+			record := data[4+6*(i*axisCount+j):]
+			out[i][j] = regionAxis{
+				start: f2dot14(binary.BigEndian.Uint16(record)),
+				peak:  f2dot14(binary.BigEndian.Uint16(record[2:])),
+				end:   f2dot14(binary.BigEndian.Uint16(record[4:])),
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseItemVariationData(data []byte, offset uint32, numRegions int) (itemVariationData, error) {
+	if int64(offset)+6 > int64(len(data)) {
+		return itemVariationData{}, errors.New("invalid item variation data (EOF)")
+	}
+	data = data[offset:]
+	itemCount := int(binary.BigEndian.Uint16(data))
+	wordCount := int(binary.BigEndian.Uint16(data[2:]))
+	regionCount := int(binary.BigEndian.Uint16(data[4:]))
+	longWords := wordCount&0x8000 != 0
+	wordCount &= 0x7FFF
+	if wordCount > regionCount {
+		return itemVariationData{}, errors.New("invalid item variation data word count")
+	}
+	if len(data) < 6+2*regionCount {
+		return itemVariationData{}, errors.New("invalid item variation data (EOF)")
+	}
+
+	var out itemVariationData
+	out.regionIndexes = make([]uint16, regionCount)
+	for i := range out.regionIndexes {
+		out.regionIndexes[i] = binary.BigEndian.Uint16(data[6+2*i:])
+		if int(out.regionIndexes[i]) >= numRegions {
+			return out, fmt.Errorf("invalid item variation data region index %d", out.regionIndexes[i])
+		}
+	}
+
+	// words are int16 (or int32 for long words), the other deltas int8 (or int16)
+	wordSize, smallSize := 2, 1
+	if longWords {
+		wordSize, smallSize = 4, 2
+	}
+	rowSize := wordSize*wordCount + smallSize*(regionCount-wordCount)
+	rows := data[6+2*regionCount:]
+	if len(rows) < itemCount*rowSize {
+		return out, errors.New("invalid item variation data (EOF)")
+	}
+	out.deltas = make([][]int32, itemCount)
+	for i := range out.deltas {
+		row := rows[i*rowSize:]
+		deltas := make([]int32, regionCount)
+		for j := range deltas {
+			switch {
+			case j < wordCount && longWords:
+				deltas[j] = int32(binary.BigEndian.Uint32(row))
+			case j < wordCount, longWords:
+				deltas[j] = int32(int16(binary.BigEndian.Uint16(row)))
+			default:
+				deltas[j] = int32(int8(row[0]))
+			}
+			if j < wordCount {
+				row = row[wordSize:]
+			} else {
+				row = row[smallSize:]
+			}
+		}
+		out.deltas[i] = deltas
+	}
+	return out, nil
+}
+
+func f2dot14(v uint16) float32 { return float32(int16(v)) / (1 << 14) }
+
+// scalar returns the contribution of the region for the given coordinates.
+func regionScalar(region []regionAxis, coords []float32) float32 {
+	out := float32(1)
+	for i, axis := range region {
+		var coord float32
+		if i < len(coords) {
+			coord = coords[i]
+		}
+		switch {
+		case axis.start > axis.peak || axis.peak > axis.end,
+			axis.start < 0 && axis.end > 0 && axis.peak != 0,
+			axis.peak == 0, coord == axis.peak:
+			continue // no effect
+		case coord <= axis.start || coord >= axis.end:
+			return 0
+		case coord < axis.peak:
+			out *= (coord - axis.start) / (axis.peak - axis.start)
+		default:
+			out *= (axis.end - coord) / (axis.end - axis.peak)
+		}
+	}
+	return out
+}
+
+// delta returns the interpolated delta of the item (`outer`, `inner`),
+// or 0 if the indexes are invalid.
+func (store itemVariationStore) delta(outer, inner uint16, coords []float32) float32 {
+	if int(outer) >= len(store.data) {
+		return 0
+	}
+	data := store.data[outer]
+	if int(inner) >= len(data.deltas) {
+		return 0
+	}
+	var out float32
+	for i, d := range data.deltas[inner] {
+		if d == 0 {
+			continue
+		}
+		out += float32(d) * regionScalar(store.regions[data.regionIndexes[i]], coords)
+	}
+	return out
+}
+
+// deltaSetIndexMap maps glyphs (or other items) to variation data,
+// packed as outer<<16 | inner.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#associating-target-items-to-variation-data
+type deltaSetIndexMap []uint32
+
+func parseDeltaSetIndexMap(data []byte, offset uint32) (deltaSetIndexMap, error) {
+	if int64(offset)+4 > int64(len(data)) {
+		return nil, errors.New("invalid delta set index map (EOF)")
+	}
+	data = data[offset:]
+	format, entryFormat := data[0], data[1]
+	var count int
+	switch format {
+	case 0:
+		count, data = int(binary.BigEndian.Uint16(data[2:])), data[4:]
+	case 1:
+		if len(data) < 6 {
+			return nil, errors.New("invalid delta set index map (EOF)")
+		}
+		count, data = int(binary.BigEndian.Uint32(data[2:])), data[6:]
+	default:
+		return nil, fmt.Errorf("unsupported delta set index map format %d", format)
+	}
+	entrySize := int((entryFormat&0x30)>>4) + 1
+	innerBits := uint32(entryFormat&0x0F) + 1
+	if len(data) < entrySize*count {
+		return nil, errors.New("invalid delta set index map (EOF)")
+	}
+	out := make(deltaSetIndexMap, count)
+	for i := range out {
+		var entry uint32
+		for _, b := range data[entrySize*i : entrySize*(i+1)] {
+			entry = entry<<8 | uint32(b)
+		}
+		out[i] = (entry>>innerBits)<<16 | entry&(1<<innerBits-1)
+	}
+	return out, nil
+}
+
+// index returns the outer and inner indexes for the item `i`.
+// Items after the end of the map use the last entry, and an empty map
+// is the implicit mapping (0, i).
+func (m deltaSetIndexMap) index(i uint32) (outer, inner uint16) {
+	if len(m) == 0 {
+		return 0, uint16(i)
+	}
+	if int(i) >= len(m) {
+		i = uint32(len(m) - 1)
+	}
+	return uint16(m[i] >> 16), uint16(m[i])
+}
@@ -0,0 +1,98 @@
+package opentype
+
+import (
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+func TestFvar(t *testing.T) {
+	for _, file := range td.WithFvar {
+		fvar, ok := loadFace(t, file.Path).Fvar()
+		if !ok {
+			t.Fatalf("%s: missing 'fvar' table", file.Path)
+		}
+		if len(fvar.Axes) != file.AxisCount || len(fvar.Instances) != file.InstancesCount {
+			t.Errorf("%s: expected %d axes and %d instances, got %d and %d", file.Path,
+				file.AxisCount, file.InstancesCount, len(fvar.Axes), len(fvar.Instances))
+		}
+	}
+}
+
+func TestNormalizeCoordinates(t *testing.T) {
+	for _, file := range td.WithFvar {
+		face := loadFace(t, file.Path)
+		fvar, _ := face.Fvar()
+
+		var defaults, minimums, maximums []float32
+		for _, axis := range fvar.Axes {
+			defaults = append(defaults, axis.Default)
+			minimums = append(minimums, axis.Minimum)
+			maximums = append(maximums, axis.Maximum)
+		}
+		for i, axis := range fvar.Axes {
+			expectedMin, expectedMax := float32(-1), float32(1)
+			if axis.Minimum == axis.Default {
+				expectedMin = 0
+			}
+			if axis.Maximum == axis.Default {
+				expectedMax = 0
+			}
+			if got := face.NormalizeCoordinates(defaults)[i]; got != 0 {
+				t.Errorf("%s: default of axis %s normalized to %g", file.Path, axis.Tag, got)
+			}
+			if got := face.NormalizeCoordinates(minimums)[i]; got != expectedMin {
+				t.Errorf("%s: minimum of axis %s normalized to %g", file.Path, axis.Tag, got)
+			}
+			if got := face.NormalizeCoordinates(maximums)[i]; got != expectedMax {
+				t.Errorf("%s: maximum of axis %s normalized to %g", file.Path, axis.Tag, got)
+			}
+		}
+
+		for i, instance := range fvar.Instances {
+			normalized := face.NormalizeCoordinates(instance.Coords)
+			// the round trip is exact in the normalized space
+			roundTrip := face.NormalizeCoordinates(face.DenormalizeCoordinates(normalized))
+			for j := range normalized {
+				if roundTrip[j] != normalized[j] {
+					t.Errorf("%s: instance %d: coordinate %d normalized to %g, then to %g", file.Path, i, j, normalized[j], roundTrip[j])
+				}
+			}
+
+			match, ok := face.MatchNamedInstance(normalized)
+			if !ok || !match.Exact {
+				t.Errorf("%s: instance %d not matched: %v", file.Path, i, match)
+				continue
+			}
+			for j, coord := range fvar.Instances[match.Index].Coords {
+				if coord != instance.Coords[j] {
+					t.Errorf("%s: instance %d matched with instance %d", file.Path, i, match.Index)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestWithVariations(t *testing.T) {
+	face := loadFace(t, "common/Commissioner-VF.ttf")
+	gid, ok := face.NominalGlyph('A')
+	if !ok {
+		t.Fatal("missing glyph for 'A'")
+	}
+	advance := face.HorizontalAdvance(gid)
+
+	bold := face.WithVariations([]Variation{{Tag: MustNewTag("wght"), Value: 700}})
+	if face.isVariable() {
+		t.Fatal("WithVariations modified the original face")
+	}
+	if got := face.HorizontalAdvance(gid); got != advance {
+		t.Errorf("default advance changed from %g to %g", advance, got)
+	}
+	if expected := face.NormalizeCoordinates([]float32{700, 0, 0, 0}); bold.Variations()[0] != expected[0] {
+		t.Errorf("expected coordinates %v, got %v", expected, bold.Variations())
+	}
+	if got := bold.HorizontalAdvance(gid); got <= advance {
+		t.Errorf("bold advance %g should be larger than %g", got, advance)
+	}
+}
@@ -0,0 +1,139 @@
+// Package woff implements a decoder for the WOFF 1.0 web font format,
+// as defined in https://www.w3.org/TR/WOFF/.
+//
+// A WOFF file is a simple wrapper around the sfnt tables, which may be
+// individually compressed with zlib. Decoding it produces a regular
+// sfnt binary which may then be parsed by the opentype package.
+package woff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	signature      = 0x774F4646 // wOFF
+	headerSize     = 44
+	entrySize      = 20
+	maxNumTables   = 4096
+	sfntHeaderSize = 12
+	sfntEntrySize  = 16
+)
+
+var errEOF = errors.New("invalid WOFF file (EOF)")
+
+type tableEntry struct {
+	tag          uint32
+	offset       uint32
+	compLength   uint32
+	origLength   uint32
+	origChecksum uint32
+}
+
+// IsWOFF returns true if `data` starts with the WOFF signature.
+func IsWOFF(data []byte) bool {
+	return len(data) >= 4 && binary.BigEndian.Uint32(data) == signature
+}
+
+// Decode uncompresses the given WOFF file, returning
+// the content of the wrapped OpenType font file.
+func Decode(data []byte) ([]byte, error) {
+	if len(data) < headerSize {
+		return nil, errEOF
+	}
+	if binary.BigEndian.Uint32(data) != signature {
+		return nil, errors.New("invalid WOFF signature")
+	}
+	flavor := binary.BigEndian.Uint32(data[4:])
+	numTables := int(binary.BigEndian.Uint16(data[12:]))
+	if numTables == 0 || numTables > maxNumTables {
+		return nil, fmt.Errorf("invalid WOFF number of tables: %d", numTables)
+	}
+	if len(data) < headerSize+entrySize*numTables {
+		return nil, errEOF
+	}
+
+	entries := make([]tableEntry, numTables)
+	var sfntSize uint64 = sfntHeaderSize + sfntEntrySize*uint64(numTables)
+	for i := range entries {
+		record := data[headerSize+entrySize*i:]
+		e := tableEntry{
+			tag:          binary.BigEndian.Uint32(record),
+			offset:       binary.BigEndian.Uint32(record[4:]),
+			compLength:   binary.BigEndian.Uint32(record[8:]),
+			origLength:   binary.BigEndian.Uint32(record[12:]),
+			origChecksum: binary.BigEndian.Uint32(record[16:]),
+		}
+		if uint64(e.offset)+uint64(e.compLength) > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid WOFF table %s offset or length", tagString(e.tag))
+		}
+		if e.compLength > e.origLength {
+			return nil, fmt.Errorf("invalid WOFF table %s compressed length", tagString(e.tag))
+		}
+		sfntSize += uint64(e.origLength+3) &^ 3
+		entries[i] = e
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	out := make([]byte, sfntHeaderSize+sfntEntrySize*numTables, sfntSize)
+	writeOffsetTable(out, flavor, numTables)
+	for i, e := range entries {
+		table, err := decompressTable(data, e)
+		if err != nil {
+			return nil, err
+		}
+		record := out[sfntHeaderSize+sfntEntrySize*i:]
+		binary.BigEndian.PutUint32(record, e.tag)
+		binary.BigEndian.PutUint32(record[4:], e.origChecksum)
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[12:], e.origLength)
+
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+
+	return out, nil
+}
+
+func decompressTable(data []byte, e tableEntry) ([]byte, error) {
+	raw := data[e.offset : e.offset+e.compLength]
+	if e.compLength == e.origLength { // not compressed
+		return raw, nil
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WOFF compressed table %s: %s", tagString(e.tag), err)
+	}
+	defer r.Close()
+
+	table := make([]byte, e.origLength)
+	if _, err := io.ReadFull(r, table); err != nil {
+		return nil, fmt.Errorf("invalid WOFF compressed table %s: %s", tagString(e.tag), err)
+	}
+	return table, nil
+}
+
+func writeOffsetTable(out []byte, flavor uint32, numTables int) {
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+	binary.BigEndian.PutUint32(out, flavor)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(numTables*16-searchRange))
+}
+
+func tagString(t uint32) string {
+	return string([]byte{byte(t >> 24), byte(t >> 16), byte(t >> 8), byte(t)})
+}
@@ -0,0 +1,177 @@
+package woff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+)
+
+func readFile(t *testing.T, file string) []byte {
+	t.Helper()
+	data, err := td.Files.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// sfntTables returns the tables of an OpenType font, indexed by tag.
+func sfntTables(t *testing.T, data []byte) map[uint32][]byte {
+	t.Helper()
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	tables := make(map[uint32][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		record := data[sfntHeaderSize+sfntEntrySize*i:]
+		offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			t.Fatalf("invalid table record %d", i)
+		}
+		tables[binary.BigEndian.Uint32(record)] = data[offset : offset+length]
+	}
+	return tables
+}
+
+func checksum(table []byte, isHead bool) uint32 {
+	var sum uint32
+	for i := 0; i < len(table); i += 4 {
+		var word [4]byte
+		copy(word[:], table[i:])
+		if isHead && i == 8 { // checksum adjustment
+			continue
+		}
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// encode wraps the tables of the given OpenType font in a WOFF file,
+// compressing the tables if `compress` is true.
+func encode(t *testing.T, sfnt []byte, compress bool) []byte {
+	numTables := int(binary.BigEndian.Uint16(sfnt[4:]))
+	out := make([]byte, headerSize+entrySize*numTables)
+	binary.BigEndian.PutUint32(out, signature)
+	binary.BigEndian.PutUint32(out[4:], binary.BigEndian.Uint32(sfnt))
+	binary.BigEndian.PutUint16(out[12:], uint16(numTables))
+	for i := 0; i < numTables; i++ {
+		record := sfnt[sfntHeaderSize+sfntEntrySize*i:]
+		offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+		table := sfnt[offset : offset+length]
+		if compress {
+			var buf bytes.Buffer
+			w := zlib.NewWriter(&buf)
+			if _, err := w.Write(table); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() < len(table) {
+				table = buf.Bytes()
+			}
+		}
+
+		entry := out[headerSize+entrySize*i:]
+		binary.BigEndian.PutUint32(entry, binary.BigEndian.Uint32(record))
+		binary.BigEndian.PutUint32(entry[4:], uint32(len(out)))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(table)))
+		binary.BigEndian.PutUint32(entry[12:], length)
+		binary.BigEndian.PutUint32(entry[16:], binary.BigEndian.Uint32(record[4:]))
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(out[8:], uint32(len(out)))
+	return out
+}
+
+func TestDecode(t *testing.T) {
+	data := readFile(t, "common/open-sans-v15-latin-regular.woff")
+	if !IsWOFF(data) {
+		t.Fatal("expected a WOFF file")
+	}
+	sfnt, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flavor := binary.BigEndian.Uint32(sfnt); flavor != 0x00010000 {
+		t.Errorf("unexpected flavor %x", flavor)
+	}
+	tables := sfntTables(t, sfnt)
+	if len(tables) != int(binary.BigEndian.Uint16(data[12:])) {
+		t.Errorf("expected %d tables, got %d", binary.BigEndian.Uint16(data[12:]), len(tables))
+	}
+	for i := 0; i < len(tables); i++ {
+		record := sfnt[sfntHeaderSize+sfntEntrySize*i:]
+		tag := binary.BigEndian.Uint32(record)
+		if sum := checksum(tables[tag], tagString(tag) == "head"); sum != binary.BigEndian.Uint32(record[4:]) {
+			t.Errorf("table %s: invalid checksum", tagString(tag))
+		}
+	}
+	if glyf := tables[binary.BigEndian.Uint32([]byte("glyf"))]; len(glyf) == 0 {
+		t.Error("missing 'glyf' table")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, file := range []string{
+		"common/Roboto-BoldItalic.ttf",
+		"common/Raleway-v4020-Regular.otf",
+		"common/Commissioner-VF.ttf",
+	} {
+		sfnt := readFile(t, file)
+		expected := sfntTables(t, sfnt)
+		for _, compress := range []bool{false, true} {
+			decoded, err := Decode(encode(t, sfnt, compress))
+			if err != nil {
+				t.Fatalf("%s: %s", file, err)
+			}
+			if binary.BigEndian.Uint32(decoded) != binary.BigEndian.Uint32(sfnt) {
+				t.Errorf("%s: invalid flavor", file)
+			}
+			got := sfntTables(t, decoded)
+			if len(got) != len(expected) {
+				t.Fatalf("%s: expected %d tables, got %d", file, len(expected), len(got))
+			}
+			for tag, table := range expected {
+				if !bytes.Equal(got[tag], table) {
+					t.Errorf("%s (compressed: %v): table %s differs", file, compress, tagString(tag))
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	data := encode(t, readFile(t, "common/Roboto-BoldItalic.ttf"), true)
+	for _, length := range []int{0, 10, headerSize, headerSize + entrySize, len(data) / 2} {
+		if _, err := Decode(data[:length]); err == nil {
+			t.Errorf("expected an error for %d bytes", length)
+		}
+	}
+
+	corrupt := func(modify func(data []byte)) []byte {
+		out := append([]byte(nil), data...)
+		modify(out)
+		return out
+	}
+	for i, input := range [][]byte{
+		corrupt(func(data []byte) { data[0] = 'x' }),                                          // signature
+		corrupt(func(data []byte) { binary.BigEndian.PutUint16(data[12:], 0) }),               // number of tables
+		corrupt(func(data []byte) { binary.BigEndian.PutUint32(data[headerSize+4:], 1<<30) }), // table offset
+		corrupt(func(data []byte) { // compressed length larger than the original one
+			binary.BigEndian.PutUint32(data[headerSize+12:], 1)
+		}),
+		corrupt(func(data []byte) { // zlib stream
+			offset := binary.BigEndian.Uint32(data[headerSize+4:])
+			data[offset], data[offset+1] = 0, 0
+		}),
+	} {
+		if _, err := Decode(input); err == nil {
+			t.Errorf("input %d: expected an error", i)
+		}
+	}
+}
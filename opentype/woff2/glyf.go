@@ -0,0 +1,476 @@
+package woff2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// composite glyph flags
+const (
+	arg1And2AreWords  = 0x0001
+	weHaveAScale      = 0x0008
+	moreComponents    = 0x0020
+	weHaveXYScale     = 0x0040
+	weHaveATwoByTwo   = 0x0080
+	weHaveInstruction = 0x0100
+)
+
+// simple glyph flags
+const (
+	flagOnCurve      = 0x01
+	flagXShort       = 0x02
+	flagYShort       = 0x04
+	flagXSame        = 0x10 // or positive, for short vectors
+	flagYSame        = 0x20 // or positive, for short vectors
+	flagOverlapSimpl = 0x40
+)
+
+var errGlyfEOF = errors.New("invalid WOFF2 transformed 'glyf' table (EOF)")
+
+type point struct {
+	x, y    int32
+	onCurve bool
+}
+
+// reconstructGlyf decodes the transformed 'glyf' table, returning the
+// standard 'glyf' and 'loca' tables, the xMin value of each glyph
+// (needed to reconstruct 'hmtx') and the loca index format.
+func reconstructGlyf(data []byte) (glyf, loca []byte, xMins []int16, indexFormat uint16, err error) {
+	const headerSize = 36
+	if len(data) < headerSize {
+		return nil, nil, nil, 0, errGlyfEOF
+	}
+	optionFlags := binary.BigEndian.Uint16(data[2:])
+	numGlyphs := int(binary.BigEndian.Uint16(data[4:]))
+	indexFormat = binary.BigEndian.Uint16(data[6:])
+
+	// the seven sub streams follow the header
+	var streams [7][]byte
+	offset := uint64(headerSize)
+	for i := range streams {
+		size := uint64(binary.BigEndian.Uint32(data[8+4*i:]))
+		if offset+size > uint64(len(data)) {
+			return nil, nil, nil, 0, errGlyfEOF
+		}
+		streams[i] = data[offset : offset+size]
+		offset += size
+	}
+	var overlapBitmap []byte
+	if optionFlags&1 != 0 {
+		size := uint64((numGlyphs + 7) >> 3)
+		if offset+size > uint64(len(data)) {
+			return nil, nil, nil, 0, errGlyfEOF
+		}
+		overlapBitmap = data[offset : offset+size]
+	}
+
+	nContourStream := reader{data: streams[0]}
+	nPointsStream := reader{data: streams[1]}
+	flagStream := reader{data: streams[2]}
+	glyphStream := reader{data: streams[3]}
+	compositeStream := reader{data: streams[4]}
+	bboxStream := reader{data: streams[5]}
+	instructionStream := reader{data: streams[6]}
+
+	bboxBitmapLength := ((numGlyphs + 31) >> 5) << 2
+	bboxBitmap, err := bboxStream.bytes(bboxBitmapLength)
+	if err != nil {
+		return nil, nil, nil, 0, errGlyfEOF
+	}
+
+	locaOffsets := make([]uint32, numGlyphs+1)
+	xMins = make([]int16, numGlyphs)
+	var points []point // buffer reused accross glyphs
+	for gid := 0; gid < numGlyphs; gid++ {
+		locaOffsets[gid] = uint32(len(glyf))
+
+		nContours, err := nContourStream.uint16()
+		if err != nil {
+			return nil, nil, nil, 0, errGlyfEOF
+		}
+		hasBbox := bboxBitmap[gid>>3]&(0x80>>(gid&7)) != 0
+
+		switch numberOfContours := int16(nContours); {
+		case numberOfContours == 0: // empty glyph
+			if hasBbox {
+				return nil, nil, nil, 0, fmt.Errorf("invalid WOFF2 'glyf' table: empty glyph %d has a bounding box", gid)
+			}
+		case numberOfContours == -1: // composite glyph
+			if !hasBbox {
+				return nil, nil, nil, 0, fmt.Errorf("invalid WOFF2 'glyf' table: composite glyph %d has no bounding box", gid)
+			}
+			components, haveInstructions, err := readComposite(&compositeStream)
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+			bbox, err := bboxStream.bytes(8)
+			if err != nil {
+				return nil, nil, nil, 0, errGlyfEOF
+			}
+			xMins[gid] = int16(binary.BigEndian.Uint16(bbox))
+
+			glyf = appendUint16(glyf, nContours)
+			glyf = append(glyf, bbox...)
+			glyf = append(glyf, components...)
+			if haveInstructions {
+				instructions, err := readInstructions(&glyphStream, &instructionStream)
+				if err != nil {
+					return nil, nil, nil, 0, err
+				}
+				glyf = appendUint16(glyf, uint16(len(instructions)))
+				glyf = append(glyf, instructions...)
+			}
+		case numberOfContours > 0: // simple glyph
+			endPoints := make([]uint16, numberOfContours)
+			totalPoints := 0
+			for i := range endPoints {
+				nPoints, err := nPointsStream.uint255()
+				if err != nil {
+					return nil, nil, nil, 0, errGlyfEOF
+				}
+				totalPoints += int(nPoints)
+				if totalPoints > 0xFFFF {
+					return nil, nil, nil, 0, fmt.Errorf("invalid WOFF2 'glyf' table: too many points in glyph %d", gid)
+				}
+				endPoints[i] = uint16(totalPoints - 1)
+			}
+
+			points, err = decodeTriplets(&flagStream, &glyphStream, totalPoints, points[:0])
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+			instructions, err := readInstructions(&glyphStream, &instructionStream)
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+
+			var xMin, yMin, xMax, yMax int16
+			if hasBbox {
+				bbox, err := bboxStream.bytes(8)
+				if err != nil {
+					return nil, nil, nil, 0, errGlyfEOF
+				}
+				xMin = int16(binary.BigEndian.Uint16(bbox))
+				yMin = int16(binary.BigEndian.Uint16(bbox[2:]))
+				xMax = int16(binary.BigEndian.Uint16(bbox[4:]))
+				yMax = int16(binary.BigEndian.Uint16(bbox[6:]))
+			} else if len(points) != 0 {
+				xMin, yMin, xMax, yMax = pointsBounds(points)
+			}
+			xMins[gid] = xMin
+
+			hasOverlap := overlapBitmap != nil && overlapBitmap[gid>>3]&(0x80>>(gid&7)) != 0
+			glyf = appendUint16(glyf, nContours)
+			glyf = appendUint16(glyf, uint16(xMin))
+			glyf = appendUint16(glyf, uint16(yMin))
+			glyf = appendUint16(glyf, uint16(xMax))
+			glyf = appendUint16(glyf, uint16(yMax))
+			for _, e := range endPoints {
+				glyf = appendUint16(glyf, e)
+			}
+			glyf = appendUint16(glyf, uint16(len(instructions)))
+			glyf = append(glyf, instructions...)
+			glyf = appendPoints(glyf, points, hasOverlap)
+		default:
+			return nil, nil, nil, 0, fmt.Errorf("invalid WOFF2 'glyf' table: number of contours %d", numberOfContours)
+		}
+
+		// glyphs are padded to 4 bytes, which is compatible with both loca formats
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	locaOffsets[numGlyphs] = uint32(len(glyf))
+
+	if indexFormat == 0 {
+		if len(glyf) > 0x1FFFF {
+			return nil, nil, nil, 0, errors.New("invalid WOFF2 'glyf' table: too large for short loca format")
+		}
+		loca = make([]byte, 2*len(locaOffsets))
+		for i, o := range locaOffsets {
+			binary.BigEndian.PutUint16(loca[2*i:], uint16(o/2))
+		}
+	} else {
+		loca = make([]byte, 4*len(locaOffsets))
+		for i, o := range locaOffsets {
+			binary.BigEndian.PutUint32(loca[4*i:], o)
+		}
+	}
+
+	return glyf, loca, xMins, indexFormat, nil
+}
+
+// readComposite returns the raw components data, which is
+// stored as in the 'glyf' table.
+func readComposite(stream *reader) ([]byte, bool, error) {
+	start := stream.pos
+	haveInstructions := false
+	for {
+		flags, err := stream.uint16()
+		if err != nil {
+			return nil, false, errGlyfEOF
+		}
+		haveInstructions = haveInstructions || flags&weHaveInstruction != 0
+		size := 2 // glyph index
+		if flags&arg1And2AreWords != 0 {
+			size += 4
+		} else {
+			size += 2
+		}
+		if flags&weHaveAScale != 0 {
+			size += 2
+		} else if flags&weHaveXYScale != 0 {
+			size += 4
+		} else if flags&weHaveATwoByTwo != 0 {
+			size += 8
+		}
+		if _, err := stream.bytes(size); err != nil {
+			return nil, false, errGlyfEOF
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return stream.data[start:stream.pos], haveInstructions, nil
+}
+
+func readInstructions(glyphStream, instructionStream *reader) ([]byte, error) {
+	length, err := glyphStream.uint255()
+	if err != nil {
+		return nil, errGlyfEOF
+	}
+	instructions, err := instructionStream.bytes(int(length))
+	if err != nil {
+		return nil, errGlyfEOF
+	}
+	return instructions, nil
+}
+
+func withSign(flag byte, baseval int32) int32 {
+	// Precondition: 0 <= baseval < 65536 (to avoid integer overflow)
+	if flag&1 != 0 {
+		return baseval
+	}
+	return -baseval
+}
+
+// decodeTriplets reads `nPoints` points, accumulating the deltas
+// to produce absolute coordinates.
+func decodeTriplets(flagStream, glyphStream *reader, nPoints int, out []point) ([]point, error) {
+	var x, y int32
+	for i := 0; i < nPoints; i++ {
+		flag, err := flagStream.byte()
+		if err != nil {
+			return nil, errGlyfEOF
+		}
+		onCurve := flag>>7 == 0
+		flag &= 0x7f
+
+		var nDataBytes int
+		switch {
+		case flag < 84:
+			nDataBytes = 1
+		case flag < 120:
+			nDataBytes = 2
+		case flag < 124:
+			nDataBytes = 3
+		default:
+			nDataBytes = 4
+		}
+		in, err := glyphStream.bytes(nDataBytes)
+		if err != nil {
+			return nil, errGlyfEOF
+		}
+
+		var dx, dy int32
+		switch {
+		case flag < 10:
+			dx = 0
+			dy = withSign(flag, int32(flag&14)<<7+int32(in[0]))
+		case flag < 20:
+			dx = withSign(flag, int32((flag-10)&14)<<7+int32(in[0]))
+			dy = 0
+		case flag < 84:
+			b0 := int32(flag - 20)
+			b1 := int32(in[0])
+			dx = withSign(flag, 1+(b0&0x30)+(b1>>4))
+			dy = withSign(flag>>1, 1+((b0&0x0c)<<2)+(b1&0x0f))
+		case flag < 120:
+			b0 := int32(flag - 84)
+			dx = withSign(flag, 1+((b0/12)<<8)+int32(in[0]))
+			dy = withSign(flag>>1, 1+(((b0%12)>>2)<<8)+int32(in[1]))
+		case flag < 124:
+			b2 := int32(in[1])
+			dx = withSign(flag, (int32(in[0])<<4)+(b2>>4))
+			dy = withSign(flag>>1, ((b2&0x0f)<<8)+int32(in[2]))
+		default:
+			dx = withSign(flag, (int32(in[0])<<8)+int32(in[1]))
+			dy = withSign(flag>>1, (int32(in[2])<<8)+int32(in[3]))
+		}
+		x += dx
+		y += dy
+		out = append(out, point{x: x, y: y, onCurve: onCurve})
+	}
+	return out, nil
+}
+
+func pointsBounds(points []point) (xMin, yMin, xMax, yMax int16) {
+	x0, y0, x1, y1 := points[0].x, points[0].y, points[0].x, points[0].y
+	for _, p := range points[1:] {
+		if p.x < x0 {
+			x0 = p.x
+		}
+		if p.x > x1 {
+			x1 = p.x
+		}
+		if p.y < y0 {
+			y0 = p.y
+		}
+		if p.y > y1 {
+			y1 = p.y
+		}
+	}
+	return int16(x0), int16(y0), int16(x1), int16(y1)
+}
+
+// appendPoints writes the flags and coordinates arrays of a simple glyph,
+// using the compact short vector encoding when possible.
+func appendPoints(glyf []byte, points []point, hasOverlap bool) []byte {
+	flags := make([]byte, len(points))
+	var xs, ys []byte
+	var lastX, lastY int32
+	for i, p := range points {
+		var flag byte
+		if p.onCurve {
+			flag |= flagOnCurve
+		}
+		if i == 0 && hasOverlap {
+			flag |= flagOverlapSimpl
+		}
+
+		dx := p.x - lastX
+		switch {
+		case dx == 0:
+			flag |= flagXSame
+		case -256 < dx && dx < 256:
+			flag |= flagXShort
+			if dx > 0 {
+				flag |= flagXSame
+			} else {
+				dx = -dx
+			}
+			xs = append(xs, byte(dx))
+		default:
+			xs = appendUint16(xs, uint16(int16(dx)))
+		}
+
+		dy := p.y - lastY
+		switch {
+		case dy == 0:
+			flag |= flagYSame
+		case -256 < dy && dy < 256:
+			flag |= flagYShort
+			if dy > 0 {
+				flag |= flagYSame
+			} else {
+				dy = -dy
+			}
+			ys = append(ys, byte(dy))
+		default:
+			ys = appendUint16(ys, uint16(int16(dy)))
+		}
+
+		flags[i] = flag
+		lastX, lastY = p.x, p.y
+	}
+	glyf = append(glyf, flags...)
+	glyf = append(glyf, xs...)
+	glyf = append(glyf, ys...)
+	return glyf
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// glyphXMins reads the xMin field of each glyph from an untransformed 'glyf' table.
+func glyphXMins(glyf, loca, head []byte) ([]int16, error) {
+	if len(head) < 54 {
+		return nil, errors.New("invalid WOFF2 file: missing 'head' table")
+	}
+	isLong := binary.BigEndian.Uint16(head[50:]) == 1
+	var offsets []uint32
+	if isLong {
+		for i := 0; i+4 <= len(loca); i += 4 {
+			offsets = append(offsets, binary.BigEndian.Uint32(loca[i:]))
+		}
+	} else {
+		for i := 0; i+2 <= len(loca); i += 2 {
+			offsets = append(offsets, 2*uint32(binary.BigEndian.Uint16(loca[i:])))
+		}
+	}
+	if len(offsets) == 0 {
+		return nil, errors.New("invalid WOFF2 file: invalid 'loca' table")
+	}
+	out := make([]int16, len(offsets)-1)
+	for i := range out {
+		start, end := offsets[i], offsets[i+1]
+		if start == end {
+			continue
+		}
+		if uint64(start)+4 > uint64(len(glyf)) {
+			return nil, errors.New("invalid WOFF2 file: invalid 'glyf' table (EOF)")
+		}
+		out[i] = int16(binary.BigEndian.Uint16(glyf[start+2:]))
+	}
+	return out, nil
+}
+
+// reconstructHmtx decodes the transformed 'hmtx' table,
+// using `xMins` for the omitted left side bearings.
+func reconstructHmtx(data, hhea []byte, xMins []int16) ([]byte, error) {
+	if len(hhea) < 36 {
+		return nil, errors.New("invalid WOFF2 file: invalid 'hhea' table (EOF)")
+	}
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	numGlyphs := len(xMins)
+	if numHMetrics < 1 || numHMetrics > numGlyphs {
+		return nil, fmt.Errorf("invalid WOFF2 file: invalid number of horizontal metrics %d", numHMetrics)
+	}
+
+	r := reader{data: data}
+	flags, err := r.byte()
+	if err != nil {
+		return nil, errors.New("invalid WOFF2 transformed 'hmtx' table (EOF)")
+	}
+	hasProportionalLsbs := flags&1 == 0
+	hasMonospaceLsbs := flags&2 == 0
+
+	advances, err := r.bytes(2 * numHMetrics)
+	if err != nil {
+		return nil, errors.New("invalid WOFF2 transformed 'hmtx' table (EOF)")
+	}
+	lsbs := make([]int16, numGlyphs)
+	for i := range lsbs {
+		if explicit := (i < numHMetrics && hasProportionalLsbs) || (i >= numHMetrics && hasMonospaceLsbs); explicit {
+			v, err := r.uint16()
+			if err != nil {
+				return nil, errors.New("invalid WOFF2 transformed 'hmtx' table (EOF)")
+			}
+			lsbs[i] = int16(v)
+		} else {
+			lsbs[i] = xMins[i]
+		}
+	}
+
+	out := make([]byte, 0, 4*numHMetrics+2*(numGlyphs-numHMetrics))
+	for i := 0; i < numHMetrics; i++ {
+		out = append(out, advances[2*i], advances[2*i+1])
+		out = appendUint16(out, uint16(lsbs[i]))
+	}
+	for _, lsb := range lsbs[numHMetrics:] {
+		out = appendUint16(out, uint16(lsb))
+	}
+	return out, nil
+}
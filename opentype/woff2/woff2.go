@@ -0,0 +1,591 @@
+// Package woff2 implements a decoder for the WOFF2 web font format,
+// as defined in https://www.w3.org/TR/WOFF2/.
+//
+// The decoder uncompresses the Brotli font data stream and reconstructs
+// the transformed 'glyf', 'loca' and 'hmtx' tables, producing a
+// regular sfnt binary (or a font collection) which may then be
+// parsed by the opentype package.
+package woff2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	signature      = 0x774F4632 // wOF2
+	ttcTag         = 0x74746366 // ttcf
+	headerSize     = 48
+	maxUncompSize  = 256 << 20 // security implementation limit
+	maxNumTables   = 4096
+	sfntHeaderSize = 12
+	sfntEntrySize  = 16
+)
+
+var (
+	tagGlyf = tag("glyf")
+	tagLoca = tag("loca")
+	tagHmtx = tag("hmtx")
+	tagHhea = tag("hhea")
+	tagHead = tag("head")
+)
+
+func tag(s string) uint32 { return binary.BigEndian.Uint32([]byte(s)) }
+
+// knownTags is the table of tags indexed by the 6 lower bits
+// of the table directory entries flags.
+var knownTags = [63]uint32{
+	tag("cmap"), tag("head"), tag("hhea"), tag("hmtx"), tag("maxp"), tag("name"), tag("OS/2"), tag("post"),
+	tag("cvt "), tag("fpgm"), tag("glyf"), tag("loca"), tag("prep"), tag("CFF "), tag("VORG"), tag("EBDT"),
+	tag("EBLC"), tag("gasp"), tag("hdmx"), tag("kern"), tag("LTSH"), tag("PCLT"), tag("VDMX"), tag("vhea"),
+	tag("vmtx"), tag("BASE"), tag("GDEF"), tag("GPOS"), tag("GSUB"), tag("EBSC"), tag("JSTF"), tag("MATH"),
+	tag("CBDT"), tag("CBLC"), tag("COLR"), tag("CPAL"), tag("SVG "), tag("sbix"), tag("acnt"), tag("avar"),
+	tag("bdat"), tag("bloc"), tag("bsln"), tag("cvar"), tag("fdsc"), tag("feat"), tag("fmtx"), tag("fvar"),
+	tag("gvar"), tag("hsty"), tag("just"), tag("lcar"), tag("mort"), tag("morx"), tag("opbd"), tag("prop"),
+	tag("trak"), tag("Zapf"), tag("Silf"), tag("Glat"), tag("Gloc"), tag("Feat"), tag("Sill"),
+}
+
+var errEOF = errors.New("invalid WOFF2 file (EOF)")
+
+type header struct {
+	flavor              uint32
+	length              uint32
+	numTables           uint16
+	totalSfntSize       uint32
+	totalCompressedSize uint32
+}
+
+type tableEntry struct {
+	tag             uint32
+	transformed     bool
+	origLength      uint32
+	transformLength uint32 // only valid if transformed
+
+	// offset and length in the uncompressed stream
+	srcOffset, srcLength uint32
+}
+
+type collectionFont struct {
+	flavor  uint32
+	indices []uint16 // into the table directory
+}
+
+// IsWOFF2 returns true if `data` starts with the WOFF2 signature.
+func IsWOFF2(data []byte) bool {
+	return len(data) >= 4 && binary.BigEndian.Uint32(data) == signature
+}
+
+// Decode decompresses the given WOFF2 file.
+// The returned bytes are a regular OpenType font file, or
+// an OpenType font collection when the WOFF2 file contains a collection.
+func Decode(data []byte) ([]byte, error) {
+	h, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	r := reader{data: data, pos: headerSize}
+
+	entries, err := parseTableDirectory(&r, h.numTables)
+	if err != nil {
+		return nil, err
+	}
+
+	var collection []collectionFont
+	var collectionVersion uint32
+	if h.flavor == ttcTag {
+		collectionVersion, collection, err = parseCollectionDirectory(&r, len(entries))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if uint64(r.pos)+uint64(h.totalCompressedSize) > uint64(len(data)) {
+		return nil, errEOF
+	}
+	compressed := data[r.pos : r.pos+int(h.totalCompressedSize)]
+
+	var uncompSize uint64
+	for i := range entries {
+		e := &entries[i]
+		e.srcOffset = uint32(uncompSize)
+		if e.transformed {
+			e.srcLength = e.transformLength
+		} else {
+			e.srcLength = e.origLength
+		}
+		uncompSize += uint64(e.srcLength)
+	}
+	if uncompSize > maxUncompSize {
+		return nil, fmt.Errorf("invalid WOFF2 file: uncompressed size %d exceeds implementation limit", uncompSize)
+	}
+
+	stream, err := decompress(compressed, int(uncompSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if collection == nil {
+		indices := make([]uint16, len(entries))
+		for i := range indices {
+			indices[i] = uint16(i)
+		}
+		fonts := []collectionFont{{flavor: h.flavor, indices: indices}}
+		tables, err := reconstructTables(stream, entries, fonts)
+		if err != nil {
+			return nil, err
+		}
+		return writeSfnt(h.flavor, indices, entries, tables), nil
+	}
+
+	tables, err := reconstructTables(stream, entries, collection)
+	if err != nil {
+		return nil, err
+	}
+	return writeCollection(collectionVersion, collection, entries, tables), nil
+}
+
+func parseHeader(data []byte) (header, error) {
+	if len(data) < headerSize {
+		return header{}, errEOF
+	}
+	if binary.BigEndian.Uint32(data) != signature {
+		return header{}, errors.New("invalid WOFF2 signature")
+	}
+	h := header{
+		flavor:              binary.BigEndian.Uint32(data[4:]),
+		length:              binary.BigEndian.Uint32(data[8:]),
+		numTables:           binary.BigEndian.Uint16(data[12:]),
+		totalSfntSize:       binary.BigEndian.Uint32(data[16:]),
+		totalCompressedSize: binary.BigEndian.Uint32(data[20:]),
+	}
+	if h.numTables == 0 || h.numTables > maxNumTables {
+		return header{}, fmt.Errorf("invalid WOFF2 number of tables: %d", h.numTables)
+	}
+	return h, nil
+}
+
+func parseTableDirectory(r *reader, numTables uint16) ([]tableEntry, error) {
+	entries := make([]tableEntry, numTables)
+	for i := range entries {
+		flags, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		var t uint32
+		if index := flags & 0x3f; index == 0x3f {
+			t, err = r.uint32()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			t = knownTags[index]
+		}
+		version := flags >> 6
+
+		e := tableEntry{tag: t}
+		e.origLength, err = r.base128()
+		if err != nil {
+			return nil, err
+		}
+
+		// for glyf and loca, version 0 is the transform, 3 is the null transform;
+		// for the other tables, version 0 is the null transform
+		if t == tagGlyf || t == tagLoca {
+			e.transformed = version == 0
+		} else {
+			e.transformed = version != 0
+		}
+		if e.transformed {
+			if t != tagGlyf && t != tagLoca && !(t == tagHmtx && version == 1) {
+				return nil, fmt.Errorf("unsupported WOFF2 transformation %d for table %s", version, tagString(t))
+			}
+			e.transformLength, err = r.base128()
+			if err != nil {
+				return nil, err
+			}
+			if t == tagLoca && e.transformLength != 0 {
+				return nil, errors.New("invalid WOFF2 transformed 'loca' table length")
+			}
+		}
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+func parseCollectionDirectory(r *reader, numTables int) (uint32, []collectionFont, error) {
+	version, err := r.uint32()
+	if err != nil {
+		return 0, nil, err
+	}
+	numFonts, err := r.uint255()
+	if err != nil {
+		return 0, nil, err
+	}
+	if numFonts == 0 {
+		return 0, nil, errors.New("invalid WOFF2 empty collection")
+	}
+	fonts := make([]collectionFont, numFonts)
+	for i := range fonts {
+		numTables16, err := r.uint255()
+		if err != nil {
+			return 0, nil, err
+		}
+		fonts[i].flavor, err = r.uint32()
+		if err != nil {
+			return 0, nil, err
+		}
+		fonts[i].indices = make([]uint16, numTables16)
+		for j := range fonts[i].indices {
+			index, err := r.uint255()
+			if err != nil {
+				return 0, nil, err
+			}
+			if int(index) >= numTables {
+				return 0, nil, fmt.Errorf("invalid WOFF2 collection table index %d", index)
+			}
+			fonts[i].indices[j] = index
+		}
+	}
+	return version, fonts, nil
+}
+
+func decompress(compressed []byte, uncompSize int) ([]byte, error) {
+	out := make([]byte, uncompSize)
+	br := brotli.NewReader(bytes.NewReader(compressed))
+	if _, err := io.ReadFull(br, out); err != nil {
+		return nil, fmt.Errorf("invalid WOFF2 compressed data: %s", err)
+	}
+	return out, nil
+}
+
+// reconstructTables returns the final content of each table of the directory.
+// Transformed tables are reconstructed, the other ones are slices into `stream`.
+func reconstructTables(stream []byte, entries []tableEntry, fonts []collectionFont) ([][]byte, error) {
+	tables := make([][]byte, len(entries))
+	for i, e := range entries {
+		if uint64(e.srcOffset)+uint64(e.srcLength) > uint64(len(stream)) {
+			return nil, errEOF
+		}
+		if !e.transformed {
+			tables[i] = stream[e.srcOffset : e.srcOffset+e.srcLength]
+		}
+	}
+
+	// transformed tables are resolved font by font, since
+	// hmtx depends on glyf, hhea and maxp
+	for _, font := range fonts {
+		var glyf, loca, hmtx, hhea, head = -1, -1, -1, -1, -1
+		for _, index := range font.indices {
+			switch entries[index].tag {
+			case tagGlyf:
+				glyf = int(index)
+			case tagLoca:
+				loca = int(index)
+			case tagHmtx:
+				hmtx = int(index)
+			case tagHhea:
+				hhea = int(index)
+			case tagHead:
+				head = int(index)
+			}
+		}
+
+		var xMins []int16 // for each glyph, after glyf reconstruction
+		if glyf != -1 && entries[glyf].transformed {
+			if loca == -1 || !entries[loca].transformed {
+				return nil, errors.New("invalid WOFF2 file: transformed 'glyf' table without transformed 'loca'")
+			}
+			if tables[glyf] == nil { // not already done by a previous font in the collection
+				e := entries[glyf]
+				var (
+					err         error
+					indexFormat uint16
+				)
+				tables[glyf], tables[loca], xMins, indexFormat, err = reconstructGlyf(stream[e.srcOffset : e.srcOffset+e.srcLength])
+				if err != nil {
+					return nil, err
+				}
+				// make sure the head table is in sync with the loca format
+				if head != -1 && len(tables[head]) >= 54 && binary.BigEndian.Uint16(tables[head][50:]) != indexFormat {
+					fixed := append([]byte(nil), tables[head]...)
+					binary.BigEndian.PutUint16(fixed[50:], indexFormat)
+					tables[head] = fixed
+				}
+			}
+		} else if loca != -1 && entries[loca].transformed {
+			return nil, errors.New("invalid WOFF2 file: transformed 'loca' table without transformed 'glyf'")
+		}
+
+		if hmtx != -1 && entries[hmtx].transformed && tables[hmtx] == nil {
+			if glyf == -1 || hhea == -1 {
+				return nil, errors.New("invalid WOFF2 file: transformed 'hmtx' table requires 'glyf' and 'hhea'")
+			}
+			if xMins == nil {
+				var err error
+				xMins, err = glyphXMins(tables[glyf], tables[loca], tables[head])
+				if err != nil {
+					return nil, err
+				}
+			}
+			e := entries[hmtx]
+			var err error
+			tables[hmtx], err = reconstructHmtx(stream[e.srcOffset:e.srcOffset+e.srcLength], tables[hhea], xMins)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i, t := range tables {
+		if t == nil {
+			return nil, fmt.Errorf("invalid WOFF2 file: table %s is not referenced by any font", tagString(entries[i].tag))
+		}
+	}
+	return tables, nil
+}
+
+// writeSfnt serializes one font, sorting its tables by tag.
+func writeSfnt(flavor uint32, indices []uint16, entries []tableEntry, tables [][]byte) []byte {
+	headerLength := sfntHeaderSize + sfntEntrySize*len(indices)
+	size := headerLength
+	for _, index := range indices {
+		size += pad4(len(tables[index]))
+	}
+	out := make([]byte, headerLength, size)
+	writeOffsetTable(out, flavor, len(indices))
+
+	sorted := sortedIndices(indices, entries)
+	offsets := make(map[uint16]uint32, len(sorted))
+	for _, index := range sorted {
+		offsets[index] = uint32(len(out))
+		out = appendPadded(out, tables[index])
+	}
+	writeTableRecords(out[sfntHeaderSize:], sorted, entries, tables, offsets)
+
+	fixHeadChecksum(out, sorted, entries, offsets)
+	return out
+}
+
+// writeCollection serializes a font collection, sharing the tables
+// referenced by several fonts.
+func writeCollection(version uint32, fonts []collectionFont, entries []tableEntry, tables [][]byte) []byte {
+	ttcHeaderLength := 12 + 4*len(fonts)
+	if version == 0x00020000 {
+		ttcHeaderLength += 12 // DSIG fields, set to zero
+	}
+	out := make([]byte, ttcHeaderLength)
+	binary.BigEndian.PutUint32(out, ttcTag)
+	binary.BigEndian.PutUint32(out[4:], version)
+	binary.BigEndian.PutUint32(out[8:], uint32(len(fonts)))
+
+	// write the offset tables, and reserve room for the table records
+	fontOffsets := make([]int, len(fonts))
+	for i, font := range fonts {
+		fontOffsets[i] = len(out)
+		binary.BigEndian.PutUint32(out[12+4*i:], uint32(len(out)))
+		out = append(out, make([]byte, sfntHeaderSize+sfntEntrySize*len(font.indices))...)
+		writeOffsetTable(out[fontOffsets[i]:], font.flavor, len(font.indices))
+	}
+
+	// write the table data, once per table
+	offsets := make(map[uint16]uint32, len(entries))
+	for _, font := range fonts {
+		for _, index := range font.indices {
+			if _, done := offsets[index]; done {
+				continue
+			}
+			offsets[index] = uint32(len(out))
+			out = appendPadded(out, tables[index])
+		}
+	}
+
+	for i, font := range fonts {
+		sorted := sortedIndices(font.indices, entries)
+		writeTableRecords(out[fontOffsets[i]+sfntHeaderSize:], sorted, entries, tables, offsets)
+	}
+	return out
+}
+
+func sortedIndices(indices []uint16, entries []tableEntry) []uint16 {
+	sorted := append([]uint16(nil), indices...)
+	sort.Slice(sorted, func(i, j int) bool { return entries[sorted[i]].tag < entries[sorted[j]].tag })
+	return sorted
+}
+
+func writeOffsetTable(out []byte, flavor uint32, numTables int) {
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+	binary.BigEndian.PutUint32(out, flavor)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(numTables*16-searchRange))
+}
+
+func writeTableRecords(out []byte, sorted []uint16, entries []tableEntry, tables [][]byte, offsets map[uint16]uint32) {
+	for i, index := range sorted {
+		record := out[sfntEntrySize*i:]
+		binary.BigEndian.PutUint32(record, entries[index].tag)
+		binary.BigEndian.PutUint32(record[4:], tableChecksum(entries[index].tag, tables[index]))
+		binary.BigEndian.PutUint32(record[8:], offsets[index])
+		binary.BigEndian.PutUint32(record[12:], uint32(len(tables[index])))
+	}
+}
+
+// fixHeadChecksum updates the 'head' table checkSumAdjustment.
+func fixHeadChecksum(out []byte, sorted []uint16, entries []tableEntry, offsets map[uint16]uint32) {
+	for i, index := range sorted {
+		if entries[index].tag != tagHead {
+			continue
+		}
+		offset := offsets[index]
+		record := out[sfntHeaderSize+sfntEntrySize*i:]
+		length := binary.BigEndian.Uint32(record[12:])
+		if length < 12 {
+			return
+		}
+		binary.BigEndian.PutUint32(out[offset+8:], 0)
+		binary.BigEndian.PutUint32(out[offset+8:], 0xB1B0AFBA-checksum(out))
+		return
+	}
+}
+
+// tableChecksum returns the checksum of the table record, which
+// excludes the checkSumAdjustment of the 'head' table.
+func tableChecksum(tag uint32, table []byte) uint32 {
+	sum := checksum(table)
+	if tag == tagHead && len(table) >= 12 {
+		sum -= binary.BigEndian.Uint32(table[8:])
+	}
+	return sum
+}
+
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for len(data) >= 4 {
+		sum += binary.BigEndian.Uint32(data)
+		data = data[4:]
+	}
+	if len(data) != 0 {
+		var last [4]byte
+		copy(last[:], data)
+		sum += binary.BigEndian.Uint32(last[:])
+	}
+	return sum
+}
+
+func pad4(n int) int { return (n + 3) &^ 3 }
+
+func appendPadded(out, table []byte) []byte {
+	out = append(out, table...)
+	for len(out)%4 != 0 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func tagString(t uint32) string {
+	return string([]byte{byte(t >> 24), byte(t >> 16), byte(t >> 8), byte(t)})
+}
+
+// reader is a cursor over a byte slice, supporting the
+// variable length encodings used by WOFF2.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) uint16() (uint16, error) {
+	if r.pos+2 > len(r.data) {
+		return 0, errEOF
+	}
+	v := binary.BigEndian.Uint16(r.data[r.pos:])
+	r.pos += 2
+	return v, nil
+}
+
+func (r *reader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errEOF
+	}
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errEOF
+	}
+	v := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return v, nil
+}
+
+// base128 reads an UIntBase128 value.
+func (r *reader) base128() (uint32, error) {
+	var accum uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		// no leading zeros
+		if i == 0 && b == 0x80 {
+			return 0, errors.New("invalid WOFF2 UIntBase128 value (leading zeros)")
+		}
+		// if any of top 7 bits are set then << 7 would overflow
+		if accum&0xFE000000 != 0 {
+			return 0, errors.New("invalid WOFF2 UIntBase128 value (overflow)")
+		}
+		accum = accum<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return accum, nil
+		}
+	}
+	return 0, errors.New("invalid WOFF2 UIntBase128 value (too long)")
+}
+
+// uint255 reads an 255UInt16 value.
+func (r *reader) uint255() (uint16, error) {
+	const (
+		oneMoreByteCode1 = 255
+		oneMoreByteCode2 = 254
+		wordCode         = 253
+		lowestUCode      = 253
+	)
+	code, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch code {
+	case wordCode:
+		return r.uint16()
+	case oneMoreByteCode1:
+		b, err := r.byte()
+		return uint16(b) + lowestUCode, err
+	case oneMoreByteCode2:
+		b, err := r.byte()
+		return uint16(b) + lowestUCode*2, err
+	default:
+		return uint16(code), nil
+	}
+}
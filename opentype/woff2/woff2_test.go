@@ -0,0 +1,184 @@
+package woff2_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-text/font/opentype"
+	"github.com/go-text/font/opentype/woff2"
+)
+
+func decode(t *testing.T, file string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile("testdata/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !woff2.IsWOFF2(data) {
+		t.Fatalf("%s: expected a WOFF2 file", file)
+	}
+	out, err := woff2.Decode(data)
+	if err != nil {
+		t.Fatalf("%s: %s", file, err)
+	}
+	return out
+}
+
+func parseCollection(t *testing.T, data []byte) []*opentype.Face {
+	t.Helper()
+	collection, err := opentype.ParseCollectionBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	faces := make([]*opentype.Face, collection.NumFaces())
+	for i := range faces {
+		faces[i], err = collection.FaceWithOptions(i, opentype.ParseOptions{Strict: true})
+		if err != nil {
+			t.Fatalf("face %d: %s", i, err)
+		}
+	}
+	return faces
+}
+
+// checkTables checks the table checksums, and the 'head' checksum adjustment.
+func checkTables(t *testing.T, file string, data []byte, faces []*opentype.Face) {
+	t.Helper()
+	for i, face := range faces {
+		tables, err := face.ListTables()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range tables {
+			if !table.ChecksumValid {
+				t.Errorf("%s (face %d): table %s: invalid checksum", file, i, table.Tag)
+			}
+		}
+	}
+	if len(faces) == 1 {
+		var sum uint32
+		for i := 0; i < len(data); i += 4 {
+			var word [4]byte
+			copy(word[:], data[i:])
+			sum += binary.BigEndian.Uint32(word[:])
+		}
+		if sum != 0xB1B0AFBA {
+			t.Errorf("%s: invalid checksum adjustment", file)
+		}
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	glyf, loca, hmtx, head := opentype.MustNewTag("glyf"), opentype.MustNewTag("loca"),
+		opentype.MustNewTag("hmtx"), opentype.MustNewTag("head")
+	for _, file := range []string{
+		"roundtrip-collection-dsig-001",
+		"roundtrip-collection-order-001",
+		"roundtrip-hmtx-lsb-001",
+		"roundtrip-offset-tables-001",
+	} {
+		original, err := ioutil.ReadFile("testdata/" + file + ".ttf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := decode(t, file+".woff2")
+		expectedFaces, faces := parseCollection(t, original), parseCollection(t, decoded)
+		if len(faces) != len(expectedFaces) {
+			t.Fatalf("%s: expected %d faces, got %d", file, len(expectedFaces), len(faces))
+		}
+		checkTables(t, file, decoded, faces)
+
+		for i, expected := range expectedFaces {
+			face := faces[i]
+			records, err := expected.ListTables()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, record := range records {
+				expectedTable, _ := expected.GetRawTable(record.Tag)
+				table, err := face.GetRawTable(record.Tag)
+				if err != nil {
+					t.Errorf("%s (face %d): %s", file, i, err)
+					continue
+				}
+				switch record.Tag {
+				case glyf, loca, hmtx: // the glyphs are compared below
+				case head:
+					// ignore the checksum adjustment, and the flag 11 set by the encoder
+					table, expectedTable = append([]byte(nil), table...), append([]byte(nil), expectedTable...)
+					copy(table[8:12], expectedTable[8:12])
+					table[16] &^= 0x08
+					expectedTable[16] &^= 0x08
+					if !bytes.Equal(table, expectedTable) {
+						t.Errorf("%s (face %d): table 'head' differs", file, i)
+					}
+				default:
+					if !bytes.Equal(table, expectedTable) {
+						t.Errorf("%s (face %d): table %s differs", file, i, record.Tag)
+					}
+				}
+			}
+
+			if face.GlyphCount() != expected.GlyphCount() {
+				t.Fatalf("%s (face %d): expected %d glyphs, got %d", file, i, expected.GlyphCount(), face.GlyphCount())
+			}
+			for gid := opentype.GID(0); int(gid) < face.GlyphCount(); gid++ {
+				expectedOutline, _ := expected.GlyphOutline(gid)
+				outline, _ := face.GlyphOutline(gid)
+				if outline.SVGPath(1, false) != expectedOutline.SVGPath(1, false) {
+					t.Errorf("%s (face %d): glyph %d: outlines differ", file, i, gid)
+				}
+				expectedExtents, _ := expected.GlyphExtents(gid)
+				if extents, _ := face.GlyphExtents(gid); extents != expectedExtents {
+					t.Errorf("%s (face %d): glyph %d: expected extents %v, got %v", file, i, gid, expectedExtents, extents)
+				}
+				expectedLSB, _ := expected.LeftSideBearing(gid)
+				if lsb, _ := face.LeftSideBearing(gid); lsb != expectedLSB {
+					t.Errorf("%s (face %d): glyph %d: expected side bearing %g, got %g", file, i, gid, expectedLSB, lsb)
+				}
+				// the advances are not compared, since the WOFF2 files
+				// of the test suite store them in reverse order
+			}
+		}
+	}
+}
+
+func TestDecodeValidation(t *testing.T) {
+	for _, file := range []string{
+		"validation-checksum-001.woff2",    // CFF flavored
+		"validation-checksum-002.woff2",    // CFF flavored
+		"validation-loca-format-001.woff2", // short 'loca' and composite glyphs
+		"validation-loca-format-002.woff2", // long 'loca' and composite glyphs
+	} {
+		decoded := decode(t, file)
+		faces := parseCollection(t, decoded)
+		checkTables(t, file, decoded, faces)
+		for gid := opentype.GID(0); int(gid) < faces[0].GlyphCount(); gid++ {
+			if _, ok := faces[0].GlyphOutline(gid); !ok {
+				t.Errorf("%s: glyph %d: invalid outline", file, gid)
+			}
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/roundtrip-hmtx-lsb-001.woff2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, length := range []int{0, 10, 48, 100, len(data) / 2} {
+		if _, err := woff2.Decode(data[:length]); err == nil {
+			t.Errorf("expected an error for %d bytes", length)
+		}
+	}
+
+	corrupted := append([]byte(nil), data...)
+	corrupted[0] = 'x'
+	if woff2.IsWOFF2(corrupted) {
+		t.Error("invalid signature accepted")
+	}
+	if _, err := woff2.Decode(corrupted); err == nil {
+		t.Error("expected an error for an invalid signature")
+	}
+}
@@ -0,0 +1,209 @@
+package font
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SegmentOp identifies the kind of a Segment.
+type SegmentOp uint8
+
+const (
+	SegmentOpMoveTo SegmentOp = iota // starts a new contour
+	SegmentOpLineTo                  // straight line
+	SegmentOpQuadTo                  // quadratic Bézier curve
+	SegmentOpCubeTo                  // cubic Bézier curve
+)
+
+// SegmentPoint is a point of a glyph outline, in font units.
+type SegmentPoint struct {
+	X, Y float32
+}
+
+// Segment is one element of a glyph outline.
+// Only the first point is used for move and line segments,
+// the first two points (control and end points) for quadratic curves,
+// and the three points for cubic curves.
+type Segment struct {
+	Op   SegmentOp
+	Args [3]SegmentPoint
+}
+
+// ArgsSlice returns the points used by the segment.
+func (s *Segment) ArgsSlice() []SegmentPoint {
+	switch s.Op {
+	case SegmentOpQuadTo:
+		return s.Args[:2]
+	case SegmentOpCubeTo:
+		return s.Args[:3]
+	default:
+		return s.Args[:1]
+	}
+}
+
+// GlyphOutline is the path of a glyph, in font units, with the y axis growing up.
+// Each contour starts with a move segment, and is implicitly closed.
+type GlyphOutline struct {
+	Segments []Segment
+}
+
+// SVGPath returns the outline as the content of the "d" attribute of an SVG path,
+// with the coordinates multiplied by `scale`. If `flipY` is true, the y axis is
+// reversed to match the SVG coordinate system, which grows down.
+// Each contour is explicitly closed with a "Z" command.
+func (o GlyphOutline) SVGPath(scale float32, flipY bool) string {
+	var b strings.Builder
+	writeCoord := func(v float32) {
+		if v == 0 {
+			v = 0 // avoid printing -0
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	yScale := scale
+	if flipY {
+		yScale = -scale
+	}
+	for i, s := range o.Segments {
+		switch s.Op {
+		case SegmentOpMoveTo:
+			if i != 0 {
+				b.WriteString("Z ")
+			}
+			b.WriteByte('M')
+		case SegmentOpLineTo:
+			b.WriteByte('L')
+		case SegmentOpQuadTo:
+			b.WriteByte('Q')
+		case SegmentOpCubeTo:
+			b.WriteByte('C')
+		default:
+			continue
+		}
+		for _, p := range s.ArgsSlice() {
+			writeCoord(p.X * scale)
+			writeCoord(p.Y * yScale)
+		}
+		b.WriteByte(' ')
+	}
+	if len(o.Segments) != 0 {
+		b.WriteByte('Z')
+	}
+	return b.String()
+}
+
+// TightExtents returns the exact extents of the area covered by the outline,
+// taking into account the extrema of the curves, whereas the bounding box
+// stored in fonts usually encloses all the control points.
+// Move segments not followed by a drawing segment are ignored.
+// An empty outline returns zero extents.
+func (o GlyphOutline) TightExtents() GlyphExtents {
+	b := bounds{xMin: math.Inf(+1), yMin: math.Inf(+1), xMax: math.Inf(-1), yMax: math.Inf(-1)}
+	var current SegmentPoint
+	for i, s := range o.Segments {
+		switch s.Op {
+		case SegmentOpMoveTo:
+			if i+1 < len(o.Segments) && o.Segments[i+1].Op != SegmentOpMoveTo {
+				b.add(float64(s.Args[0].X), float64(s.Args[0].Y))
+			}
+		case SegmentOpLineTo:
+			b.add(float64(s.Args[0].X), float64(s.Args[0].Y))
+		case SegmentOpQuadTo:
+			b.add(float64(s.Args[1].X), float64(s.Args[1].Y))
+			for _, t := range quadExtrema(current, s.Args[0], s.Args[1]) {
+				b.add(quadAt(current.X, s.Args[0].X, s.Args[1].X, t), quadAt(current.Y, s.Args[0].Y, s.Args[1].Y, t))
+			}
+		case SegmentOpCubeTo:
+			b.add(float64(s.Args[2].X), float64(s.Args[2].Y))
+			for _, t := range cubeExtrema(current, s.Args[0], s.Args[1], s.Args[2]) {
+				b.add(cubeAt(current.X, s.Args[0].X, s.Args[1].X, s.Args[2].X, t), cubeAt(current.Y, s.Args[0].Y, s.Args[1].Y, s.Args[2].Y, t))
+			}
+		default:
+			continue
+		}
+		current = s.ArgsSlice()[len(s.ArgsSlice())-1]
+	}
+	if b.xMin > b.xMax { // no outline
+		return GlyphExtents{}
+	}
+	return GlyphExtents{
+		XBearing: float32(b.xMin),
+		YBearing: float32(b.yMax),
+		Width:    float32(b.xMax - b.xMin),
+		Height:   float32(b.yMin - b.yMax),
+	}
+}
+
+type bounds struct{ xMin, yMin, xMax, yMax float64 }
+
+func (b *bounds) add(x, y float64) {
+	b.xMin, b.xMax = math.Min(b.xMin, x), math.Max(b.xMax, x)
+	b.yMin, b.yMax = math.Min(b.yMin, y), math.Max(b.yMax, y)
+}
+
+func quadAt(p0, p1, p2 float32, t float64) float64 {
+	u := 1 - t
+	return u*u*float64(p0) + 2*u*t*float64(p1) + t*t*float64(p2)
+}
+
+func cubeAt(p0, p1, p2, p3 float32, t float64) float64 {
+	u := 1 - t
+	return u*u*u*float64(p0) + 3*u*u*t*float64(p1) + 3*u*t*t*float64(p2) + t*t*t*float64(p3)
+}
+
+// quadExtrema returns the parameters in ]0, 1[ where the
+// derivative of the curve vanishes, on each axis.
+func quadExtrema(p0, p1, p2 SegmentPoint) []float64 {
+	var out []float64
+	for _, c := range [2][3]float32{{p0.X, p1.X, p2.X}, {p0.Y, p1.Y, p2.Y}} {
+		// B'(t) = 2 * ((p1 - p0) + t * (p0 - 2p1 + p2))
+		if d := float64(c[0]) - 2*float64(c[1]) + float64(c[2]); d != 0 {
+			out = appendParameter(out, (float64(c[0])-float64(c[1]))/d)
+		}
+	}
+	return out
+}
+
+// cubeExtrema returns the parameters in ]0, 1[ where the
+// derivative of the curve vanishes, on each axis.
+func cubeExtrema(p0, p1, p2, p3 SegmentPoint) []float64 {
+	var out []float64
+	for _, c := range [2][4]float32{{p0.X, p1.X, p2.X, p3.X}, {p0.Y, p1.Y, p2.Y, p3.Y}} {
+		// B'(t) = 3 * (a t² + b t + c)
+		q0, q1, q2, q3 := float64(c[0]), float64(c[1]), float64(c[2]), float64(c[3])
+		a := -q0 + 3*q1 - 3*q2 + q3
+		b := 2 * (q0 - 2*q1 + q2)
+		c := q1 - q0
+		if a == 0 { // degenerated to a quadratic derivative
+			if b != 0 {
+				out = appendParameter(out, -c/b)
+			}
+			continue
+		}
+		delta := b*b - 4*a*c
+		if delta < 0 {
+			continue
+		}
+		sq := math.Sqrt(delta)
+		out = appendParameter(out, (-b+sq)/(2*a))
+		out = appendParameter(out, (-b-sq)/(2*a))
+	}
+	return out
+}
+
+func appendParameter(params []float64, t float64) []float64 {
+	if t > 0 && t < 1 {
+		params = append(params, t)
+	}
+	return params
+}
+
+// GlyphOutliner is implemented by the faces providing
+// the outlines of their glyphs.
+type GlyphOutliner interface {
+	// GlyphOutline returns the outline of the given glyph, or false
+	// if the glyph is invalid or has no outline data (such as bitmap glyphs).
+	// Glyphs without contours, such as spaces, return an empty outline.
+	GlyphOutline(gid GID) (GlyphOutline, bool)
+}
@@ -0,0 +1,432 @@
+// Package pcf provides support for the X11 Portable Compiled Format (.pcf)
+// bitmap fonts, possibly gzip compressed (.pcf.gz).
+//
+// The metrics of bitmap fonts are expressed in pixels.
+// See https://fontforge.org/docs/techref/pcf-format.html for the format.
+package pcf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/benoitkugler/textlayout/fonts/glyphsnames"
+	"github.com/go-text/font"
+)
+
+var (
+	_ font.Face          = (*Face)(nil)
+	_ font.GlyphExtenter = (*Face)(nil)
+	_ font.GlyphCounter  = (*Face)(nil)
+)
+
+// GID is used to identify glyphs in a font.
+type GID = font.GID
+
+const magic = "\x01fcp"
+
+// Property is an entry of the font properties,
+// which is either a string (atom) or an integer.
+type Property struct {
+	Atom   string // only valid if IsAtom is true
+	Value  int32  // only valid if IsAtom is false
+	IsAtom bool
+}
+
+// Metrics are the metrics of one glyph, in pixels.
+// The bitmap of the glyph spans horizontally from LeftSideBearing
+// to RightSideBearing, and vertically from Ascent (above the baseline)
+// to Descent (below the baseline).
+type Metrics struct {
+	LeftSideBearing  int16
+	RightSideBearing int16
+	Width            int16 // the advance
+	Ascent           int16
+	Descent          int16
+	Attributes       uint16
+}
+
+// Bitmap is the monochrome image of a glyph, stored row by row,
+// from top to bottom. The leftmost pixel of a row is the most significant bit
+// of its first byte, and each row is padded to a whole number of bytes.
+type Bitmap struct {
+	Width, Height int
+	Stride        int // number of bytes per row
+	Data          []byte
+
+	// Left and Top are the position of the top-left pixel,
+	// relative to the glyph origin, with Top increasing upwards.
+	Left, Top int
+}
+
+// At returns true if the pixel at column `x` and row `y` is set.
+func (b Bitmap) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return false
+	}
+	return b.Data[y*b.Stride+x/8]&(0x80>>(x%8)) != 0
+}
+
+// Face is a font parsed from a PCF file.
+type Face struct {
+	// Properties stores the font properties, such as FONT (the XLFD name),
+	// FAMILY_NAME, PIXEL_SIZE, CHARSET_REGISTRY or CHARSET_ENCODING.
+	Properties map[string]Property
+
+	// Ascent and Descent are the logical extents of the font,
+	// above and below the baseline, in pixels.
+	Ascent, Descent int32
+
+	metrics    []Metrics
+	inkMetrics []Metrics // optional
+	bitmaps    bitmaps
+	names      []string // optional
+	encoding   encoding
+
+	cmap map[rune]GID
+}
+
+// ParseFont reads a PCF font, which may be gzip compressed.
+func ParseFont(file font.Resource) (*Face, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil { // file might have been used before
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b { // gzip header
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+	return Parse(data)
+}
+
+// Parse parses an uncompressed PCF font.
+// The returned Face references `data`, which must not be modified.
+func Parse(data []byte) (*Face, error) {
+	if len(data) < 8 || string(data[:4]) != magic {
+		return nil, errors.New("not a PCF font")
+	}
+	count := binary.LittleEndian.Uint32(data[4:])
+	if uint64(8+16*count) > uint64(len(data)) {
+		return nil, errEOF
+	}
+	tables := make(map[uint32]tocEntry, count)
+	for i := 0; i < int(count); i++ {
+		b := data[8+16*i:]
+		entry := tocEntry{
+			kind:   binary.LittleEndian.Uint32(b),
+			format: binary.LittleEndian.Uint32(b[4:]),
+			size:   binary.LittleEndian.Uint32(b[8:]),
+			offset: binary.LittleEndian.Uint32(b[12:]),
+		}
+		if _, has := tables[entry.kind]; !has { // only use the first table of each kind
+			tables[entry.kind] = entry
+		}
+	}
+
+	var out Face
+	for _, kind := range [...]uint32{
+		tableProperties, tableAccelerators, tableBDFAccelerators, tableMetrics,
+		tableInkMetrics, tableBitmaps, tableBDFEncodings, tableGlyphNames,
+	} {
+		entry, has := tables[kind]
+		if !has {
+			continue
+		}
+		r, err := newReader(data, entry)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case tableProperties:
+			out.Properties, err = r.properties()
+		case tableAccelerators, tableBDFAccelerators: // the BDF accelerators are preferred
+			var acc accelerators
+			acc, err = r.accelerators()
+			out.Ascent, out.Descent = acc.ascent, acc.descent
+		case tableMetrics:
+			out.metrics, err = r.metrics()
+		case tableInkMetrics:
+			out.inkMetrics, err = r.metrics()
+		case tableBitmaps:
+			out.bitmaps, err = r.bitmaps()
+		case tableBDFEncodings:
+			out.encoding, err = r.encoding()
+		case tableGlyphNames:
+			out.names, err = r.glyphNames()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := out.validate(); err != nil {
+		return nil, err
+	}
+	out.buildCmap()
+	return &out, nil
+}
+
+// validate checks the consistency of the tables
+func (f *Face) validate() error {
+	n := len(f.metrics)
+	if n == 0 {
+		return errors.New("invalid PCF font: missing metrics")
+	}
+	if len(f.bitmaps.offsets) != n {
+		return fmt.Errorf("invalid PCF font: %d bitmaps for %d glyphs", len(f.bitmaps.offsets), n)
+	}
+	if f.inkMetrics != nil && len(f.inkMetrics) != n {
+		return fmt.Errorf("invalid PCF font: %d ink metrics for %d glyphs", len(f.inkMetrics), n)
+	}
+	if f.names != nil && len(f.names) != n {
+		return fmt.Errorf("invalid PCF font: %d glyph names for %d glyphs", len(f.names), n)
+	}
+	for _, gid := range f.encoding.glyphs {
+		if gid != 0xFFFF && int(gid) >= n {
+			return fmt.Errorf("invalid PCF encoding: glyph index %d", gid)
+		}
+	}
+	return nil
+}
+
+// isUnicode returns true if the character codes of the font
+// are Unicode code points, as indicated by its charset properties.
+func (f *Face) isUnicode() bool {
+	registry := strings.ToLower(f.Properties["CHARSET_REGISTRY"].Atom)
+	encoding := f.Properties["CHARSET_ENCODING"].Atom
+	switch registry {
+	case "iso10646":
+		return true
+	case "iso8859":
+		return encoding == "1"
+	case "iso646.1991":
+		return encoding == "IRV" // ASCII
+	}
+	return false
+}
+
+// buildCmap maps the codes of the encoding to runes if the font
+// uses an Unicode compatible charset, or uses the glyph names otherwise.
+func (f *Face) buildCmap() {
+	f.cmap = make(map[rune]GID)
+	if f.isUnicode() {
+		enc := f.encoding
+		for byte1 := enc.minByte1; byte1 <= enc.maxByte1; byte1++ {
+			for byte2 := enc.minByte2; byte2 <= enc.maxByte2; byte2++ {
+				code := byte1<<8 | byte2
+				if gid, ok := enc.lookup(code); ok {
+					f.cmap[rune(code)] = gid
+				}
+			}
+		}
+		return
+	}
+	for i, name := range f.names {
+		if r, ok := glyphsnames.GlyphToRune(name); ok {
+			if _, has := f.cmap[r]; !has {
+				f.cmap[r] = GID(i)
+			}
+		}
+	}
+}
+
+// NumGlyphs returns the number of glyphs in the font.
+func (f *Face) NumGlyphs() int { return len(f.metrics) }
+
+// GlyphCount implements font.GlyphCounter, and is the same as NumGlyphs.
+func (f *Face) GlyphCount() int { return f.NumGlyphs() }
+
+// Upem returns the PIXEL_SIZE property, or the
+// font height (its ascent plus its descent) if it is missing.
+// The metrics of the font are expressed in pixels,
+// so that dividing them by Upem gives values relative to the em.
+func (f *Face) Upem() uint16 {
+	if p, ok := f.Properties["PIXEL_SIZE"]; ok && !p.IsAtom && p.Value > 0 {
+		return uint16(p.Value)
+	}
+	return uint16(f.Ascent + f.Descent)
+}
+
+// NominalGlyph returns the glyph for the given rune.
+// The character codes of the font are used if its charset is
+// compatible with Unicode (ISO10646, ISO8859-1 or ASCII), and
+// the glyph names are interpreted otherwise.
+func (f *Face) NominalGlyph(r rune) (GID, bool) {
+	gid, ok := f.cmap[r]
+	return gid, ok
+}
+
+// GlyphForCode returns the glyph used for the (one or two bytes) character code `code`,
+// according to the font encoding, whose charset is given by the properties.
+func (f *Face) GlyphForCode(code uint16) (GID, bool) { return f.encoding.lookup(code) }
+
+// DefaultGlyph returns the glyph to use for the codes not
+// supported by the font, or 0 if the font does not specify it.
+func (f *Face) DefaultGlyph() GID {
+	if gid, ok := f.encoding.lookup(f.encoding.defaultChar); ok {
+		return gid
+	}
+	return 0
+}
+
+// GlyphName returns the name of the given glyph, or an empty
+// string if the glyph is invalid or if the font has no glyph names.
+func (f *Face) GlyphName(gid GID) string {
+	if int(gid) >= len(f.names) {
+		return ""
+	}
+	return f.names[gid]
+}
+
+// HorizontalAdvance returns the advance of the glyph, in pixels,
+// or 0 if the glyph is invalid.
+func (f *Face) HorizontalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.metrics) {
+		return 0
+	}
+	return float32(f.metrics[gid].Width)
+}
+
+// VerticalAdvance returns the opposite of the font height (its ascent
+// plus its descent), since the font has no vertical metrics,
+// or 0 if the glyph is invalid.
+func (f *Face) VerticalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.metrics) {
+		return 0
+	}
+	return -float32(f.Ascent + f.Descent)
+}
+
+// FontHExtents returns the ascent and descent of the font,
+// in pixels, with no line gap.
+func (f *Face) FontHExtents() (font.FontExtents, bool) {
+	return font.FontExtents{Ascender: float32(f.Ascent), Descender: -float32(f.Descent)}, true
+}
+
+// LineMetric returns the underline and strikethrough metrics, in pixels, given by the
+// UNDERLINE_POSITION, UNDERLINE_THICKNESS, STRIKEOUT_ASCENT and STRIKEOUT_DESCENT
+// properties, or false if the required properties are missing.
+func (f *Face) LineMetric(metric font.LineMetric) (float32, bool) {
+	value := func(name string) (float32, bool) {
+		p, ok := f.Properties[name]
+		return float32(p.Value), ok && !p.IsAtom
+	}
+	switch metric {
+	case font.UnderlinePosition: // XLFD values are positive below the baseline
+		pos, ok := value("UNDERLINE_POSITION")
+		return -pos, ok
+	case font.UnderlineThickness:
+		return value("UNDERLINE_THICKNESS")
+	case font.StrikethroughPosition:
+		return value("STRIKEOUT_ASCENT")
+	case font.StrikethroughThickness:
+		ascent, ok1 := value("STRIKEOUT_ASCENT")
+		descent, ok2 := value("STRIKEOUT_DESCENT")
+		return ascent + descent, ok1 && ok2
+	default:
+		return 0, false
+	}
+}
+
+// GlyphMetrics returns the metrics of the glyph, which also describe its bitmap.
+func (f *Face) GlyphMetrics(gid GID) (Metrics, bool) {
+	if int(gid) >= len(f.metrics) {
+		return Metrics{}, false
+	}
+	return f.metrics[gid], true
+}
+
+// InkMetrics returns the metrics of the inked pixels of the glyph, which
+// may be tighter than its GlyphMetrics.
+func (f *Face) InkMetrics(gid GID) (Metrics, bool) {
+	if int(gid) >= len(f.metrics) {
+		return Metrics{}, false
+	}
+	if f.inkMetrics == nil {
+		return f.metrics[gid], true
+	}
+	return f.inkMetrics[gid], true
+}
+
+// GlyphExtents returns the extents of the inked pixels of the glyph,
+// in pixels, or false if the glyph is invalid.
+func (f *Face) GlyphExtents(gid GID) (font.GlyphExtents, bool) {
+	m, ok := f.InkMetrics(gid)
+	if !ok {
+		return font.GlyphExtents{}, false
+	}
+	return font.GlyphExtents{
+		XBearing: float32(m.LeftSideBearing),
+		YBearing: float32(m.Ascent),
+		Width:    float32(m.RightSideBearing) - float32(m.LeftSideBearing),
+		Height:   -float32(m.Ascent) - float32(m.Descent),
+	}, true
+}
+
+// GlyphBitmap returns the image of the glyph, or false if the glyph is
+// invalid or its bitmap data is missing.
+func (f *Face) GlyphBitmap(gid GID) (Bitmap, bool) {
+	if int(gid) >= len(f.metrics) {
+		return Bitmap{}, false
+	}
+	m := f.metrics[gid]
+	out := Bitmap{
+		Width:  int(m.RightSideBearing) - int(m.LeftSideBearing),
+		Height: int(m.Ascent) + int(m.Descent),
+		Left:   int(m.LeftSideBearing),
+		Top:    int(m.Ascent),
+	}
+	if out.Width <= 0 || out.Height <= 0 {
+		return Bitmap{Left: out.Left, Top: out.Top}, true
+	}
+	out.Stride = (out.Width + 7) / 8
+
+	format := f.bitmaps.format
+	pad := 1 << (format & formatGlyphPad)
+	rowSize := (out.Stride + pad - 1) / pad * pad
+	start := uint64(f.bitmaps.offsets[gid])
+	if start+uint64(rowSize*out.Height) > uint64(len(f.bitmaps.data)) {
+		return Bitmap{}, false
+	}
+	src := f.bitmaps.data[start : start+uint64(rowSize*out.Height)]
+
+	// normalize to MSB first bits, and unswap the scan units
+	unit := 1 << ((format & formatScanUnit) >> 4)
+	swap := unit > 1 && (format&formatByteOrder != 0) != (format&formatBitOrder != 0)
+	reverse := format&formatBitOrder == 0
+	out.Data = make([]byte, out.Stride*out.Height)
+	for y := 0; y < out.Height; y++ {
+		row := src[y*rowSize : (y+1)*rowSize]
+		for x := range out.Data[y*out.Stride : (y+1)*out.Stride] {
+			i := x
+			if swap && rowSize%unit == 0 {
+				i = x - x%unit + unit - 1 - x%unit
+			}
+			b := row[i]
+			if reverse {
+				b = reverseBits(b)
+			}
+			out.Data[y*out.Stride+x] = b
+		}
+	}
+	return out, true
+}
+
+func reverseBits(b byte) byte {
+	b = b>>4 | b<<4
+	b = (b&0xCC)>>2 | (b&0x33)<<2
+	return (b&0xAA)>>1 | (b&0x55)<<1
+}
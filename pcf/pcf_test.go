@@ -0,0 +1,138 @@
+package pcf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func loadFont(t *testing.T) *Face {
+	t.Helper()
+	f, err := os.Open("testdata/8x16.pcf.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	face, err := ParseFont(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return face
+}
+
+// readFile returns the compressed and uncompressed content of the test font.
+func readFile(t *testing.T) (compressed, data []byte) {
+	t.Helper()
+	compressed, err := ioutil.ReadFile("testdata/8x16.pcf.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compressed, data
+}
+
+func TestParse(t *testing.T) {
+	face := loadFont(t)
+	if p := face.Properties["FONT"]; !p.IsAtom || p.Atom != "-Sony-Fixed-Medium-R-Normal--16-120-100-100-C-80-ISO8859-1" {
+		t.Errorf("unexpected FONT property %v", p)
+	}
+	if p := face.Properties["PIXEL_SIZE"]; p.IsAtom || p.Value != 16 {
+		t.Errorf("unexpected PIXEL_SIZE property %v", p)
+	}
+	if face.Ascent != 14 || face.Descent != 2 || face.Upem() != 16 || face.NumGlyphs() != 221 {
+		t.Errorf("unexpected font metrics: %d %d %d, %d glyphs", face.Ascent, face.Descent, face.Upem(), face.NumGlyphs())
+	}
+
+	// the uncompressed font gives the same result
+	_, data := readFile(t)
+	uncompressed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uncompressed.NumGlyphs() != face.NumGlyphs() || len(uncompressed.Properties) != len(face.Properties) {
+		t.Error("invalid uncompressed font")
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	face := loadFont(t)
+	gid, ok := face.NominalGlyph('A')
+	if !ok || face.GlyphName(gid) != "A" {
+		t.Fatalf("invalid glyph %d for 'A'", gid)
+	}
+	if code, _ := face.GlyphForCode('A'); code != gid {
+		t.Errorf("expected glyph %d for code 0x41, got %d", gid, code)
+	}
+	if advance := face.HorizontalAdvance(gid); advance != 8 {
+		t.Errorf("expected advance 8, got %g", advance)
+	}
+	if m, _ := face.GlyphMetrics(gid); m != (Metrics{LeftSideBearing: 0, RightSideBearing: 8, Width: 8, Ascent: 14, Descent: 2}) {
+		t.Errorf("unexpected metrics %v", m)
+	}
+
+	bitmap, ok := face.GlyphBitmap(gid)
+	if !ok || bitmap.Width != 8 || bitmap.Height != 16 || bitmap.Left != 0 || bitmap.Top != 14 {
+		t.Fatalf("unexpected bitmap %v", bitmap)
+	}
+	expected := []string{
+		"........",
+		"...#....",
+		"..#.#...",
+		"..#.#...",
+		"..#.#...",
+		".#...#..",
+		".#...#..",
+		".#...#..",
+		".#...#..",
+		".#####..",
+		"#.....#.",
+		"#.....#.",
+		"#.....#.",
+		"##...##.",
+		"........",
+		"........",
+	}
+	for y, row := range expected {
+		for x, c := range row {
+			if bitmap.At(x, y) != (c == '#') {
+				t.Errorf("invalid pixel (%d, %d)", x, y)
+			}
+		}
+	}
+	// the extents are given by the ink metrics
+	if extents, _ := face.GlyphExtents(gid); extents.XBearing != 0 || extents.YBearing != 13 || extents.Width != 7 || extents.Height != -13 {
+		t.Errorf("unexpected extents %v", extents)
+	}
+
+	for gid := GID(0); int(gid) < face.NumGlyphs(); gid++ {
+		m, _ := face.GlyphMetrics(gid)
+		bitmap, ok := face.GlyphBitmap(gid)
+		if !ok || bitmap.Width != int(m.RightSideBearing-m.LeftSideBearing) || bitmap.Height != int(m.Ascent+m.Descent) {
+			t.Errorf("glyph %d: bitmap %dx%d does not match metrics %v", gid, bitmap.Width, bitmap.Height, m)
+		}
+	}
+	if _, ok := face.GlyphBitmap(GID(face.NumGlyphs())); ok {
+		t.Error("expected no bitmap for an invalid glyph")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	compressed, data := readFile(t)
+	if _, err := Parse(compressed); err == nil {
+		t.Error("Parse expects uncompressed fonts")
+	}
+	for _, length := range []int{0, 4, 8, 64, len(data) / 2} {
+		if _, err := Parse(data[:length]); err == nil {
+			t.Errorf("expected an error for %d bytes", length)
+		}
+	}
+}
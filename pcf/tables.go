@@ -0,0 +1,338 @@
+package pcf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var errEOF = errors.New("invalid PCF font (EOF)")
+
+// table types, as found in the table of contents
+const (
+	tableProperties = 1 << iota
+	tableAccelerators
+	tableMetrics
+	tableBitmaps
+	tableInkMetrics
+	tableBDFEncodings
+	tableScalableWidths
+	tableGlyphNames
+	tableBDFAccelerators
+)
+
+// format flags
+const (
+	formatMask             = 0xFFFFFF00
+	formatDefault          = 0x00000000
+	formatCompressedMetric = 0x00000100
+
+	formatGlyphPad  = 3 << 0 // padding of the bitmap rows: 1 << (format & 3) bytes
+	formatByteOrder = 1 << 2 // most significant byte first
+	formatBitOrder  = 1 << 3 // most significant bit first
+	formatScanUnit  = 3 << 4 // 1 << ((format >> 4) & 3) bytes
+)
+
+type tocEntry struct {
+	kind, format, size, offset uint32
+}
+
+// reader is a cursor over one table, whose byte order is
+// given by its format (the format itself is always little endian).
+type reader struct {
+	data   []byte
+	pos    int
+	format uint32
+	order  binary.ByteOrder
+}
+
+// newReader reads the format of the table described by `entry`.
+func newReader(data []byte, entry tocEntry) (*reader, error) {
+	if uint64(entry.offset)+4 > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid PCF table offset %d", entry.offset)
+	}
+	end := uint64(entry.offset) + uint64(entry.size)
+	if end > uint64(len(data)) { // some fonts store a padded size for the last table
+		end = uint64(len(data))
+	}
+	r := reader{data: data[entry.offset:end]}
+	r.format = binary.LittleEndian.Uint32(r.data)
+	r.pos = 4
+	r.order = binary.LittleEndian
+	if r.format&formatByteOrder != 0 {
+		r.order = binary.BigEndian
+	}
+	return &r, nil
+}
+
+func (r *reader) u8() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errEOF
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+func (r *reader) u16() (uint16, error) {
+	if r.pos+2 > len(r.data) {
+		return 0, errEOF
+	}
+	r.pos += 2
+	return r.order.Uint16(r.data[r.pos-2:]), nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errEOF
+	}
+	r.pos += 4
+	return r.order.Uint32(r.data[r.pos-4:]), nil
+}
+
+// count reads a number of elements of `size` bytes and
+// checks that the table is large enough to store them.
+func (r *reader) count(size int) (int, error) {
+	c, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(c)*uint64(size) > uint64(len(r.data)-r.pos) {
+		return 0, errEOF
+	}
+	return int(c), nil
+}
+
+// cString returns the zero terminated string starting at `offset`.
+func cString(data []byte, offset uint32) (string, error) {
+	if uint64(offset) >= uint64(len(data)) {
+		return "", fmt.Errorf("invalid PCF string offset %d", offset)
+	}
+	end := bytes.IndexByte(data[offset:], 0)
+	if end == -1 {
+		return "", errors.New("invalid PCF string (missing terminator)")
+	}
+	return string(data[offset : int(offset)+end]), nil
+}
+
+func (r *reader) properties() (map[string]Property, error) {
+	n, err := r.count(9)
+	if err != nil {
+		return nil, err
+	}
+	type rawProp struct {
+		name, value uint32
+		isString    bool
+	}
+	props := make([]rawProp, n)
+	for i := range props {
+		props[i].name, _ = r.u32()
+		isString, _ := r.u8()
+		props[i].isString = isString != 0
+		props[i].value, _ = r.u32()
+	}
+	if pad := n & 3; pad != 0 {
+		r.pos += 4 - pad
+	}
+	size, err := r.count(1)
+	if err != nil {
+		return nil, err
+	}
+	strs := r.data[r.pos : r.pos+size]
+
+	out := make(map[string]Property, n)
+	for _, prop := range props {
+		name, err := cString(strs, prop.name)
+		if err != nil {
+			return nil, err
+		}
+		p := Property{Value: int32(prop.value)}
+		if prop.isString {
+			p.IsAtom = true
+			p.Value = 0
+			p.Atom, err = cString(strs, prop.value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		out[name] = p
+	}
+	return out, nil
+}
+
+func (r *reader) metric(compressed bool) (Metrics, error) {
+	if compressed {
+		if r.pos+5 > len(r.data) {
+			return Metrics{}, errEOF
+		}
+		b := r.data[r.pos : r.pos+5]
+		r.pos += 5
+		return Metrics{
+			LeftSideBearing:  int16(b[0]) - 0x80,
+			RightSideBearing: int16(b[1]) - 0x80,
+			Width:            int16(b[2]) - 0x80,
+			Ascent:           int16(b[3]) - 0x80,
+			Descent:          int16(b[4]) - 0x80,
+		}, nil
+	}
+	if r.pos+12 > len(r.data) {
+		return Metrics{}, errEOF
+	}
+	var v [6]uint16
+	for i := range v {
+		v[i], _ = r.u16()
+	}
+	return Metrics{
+		LeftSideBearing:  int16(v[0]),
+		RightSideBearing: int16(v[1]),
+		Width:            int16(v[2]),
+		Ascent:           int16(v[3]),
+		Descent:          int16(v[4]),
+		Attributes:       v[5],
+	}, nil
+}
+
+func (r *reader) metrics() ([]Metrics, error) {
+	compressed := r.format&formatMask == formatCompressedMetric
+	var (
+		n   int
+		err error
+	)
+	if compressed {
+		var c uint16
+		c, err = r.u16()
+		n = int(c)
+	} else {
+		n, err = r.count(12)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Metrics, n)
+	for i := range out {
+		out[i], err = r.metric(compressed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+type accelerators struct {
+	ascent, descent int32
+}
+
+// accelerators only reads the font ascent and descent,
+// the other fields may be computed from the glyph metrics.
+func (r *reader) accelerators() (accelerators, error) {
+	r.pos += 8 // flags
+	ascent, _ := r.u32()
+	descent, err := r.u32()
+	if err != nil {
+		return accelerators{}, err
+	}
+	return accelerators{ascent: int32(ascent), descent: int32(descent)}, nil
+}
+
+type bitmaps struct {
+	format  uint32
+	offsets []uint32
+	data    []byte
+}
+
+func (r *reader) bitmaps() (bitmaps, error) {
+	if r.format&formatMask != formatDefault {
+		return bitmaps{}, fmt.Errorf("unsupported PCF bitmap format %d", r.format)
+	}
+	n, err := r.count(4)
+	if err != nil {
+		return bitmaps{}, err
+	}
+	out := bitmaps{format: r.format, offsets: make([]uint32, n)}
+	for i := range out.offsets {
+		out.offsets[i], _ = r.u32()
+	}
+	var sizes [4]uint32
+	for i := range sizes {
+		sizes[i], err = r.u32()
+		if err != nil {
+			return bitmaps{}, err
+		}
+	}
+	size := int(sizes[r.format&formatGlyphPad])
+	if r.pos+size > len(r.data) {
+		return bitmaps{}, errEOF
+	}
+	out.data = r.data[r.pos : r.pos+size]
+	return out, nil
+}
+
+type encoding struct {
+	minByte2, maxByte2 uint16
+	minByte1, maxByte1 uint16
+	defaultChar        uint16
+	glyphs             []uint16 // 0xFFFF for missing glyphs
+}
+
+func (r *reader) encoding() (encoding, error) {
+	if r.format&formatMask != formatDefault {
+		return encoding{}, fmt.Errorf("unsupported PCF encoding format %d", r.format)
+	}
+	var out encoding
+	out.minByte2, _ = r.u16()
+	out.maxByte2, _ = r.u16()
+	out.minByte1, _ = r.u16()
+	out.maxByte1, _ = r.u16()
+	var err error
+	out.defaultChar, err = r.u16()
+	if err != nil {
+		return out, err
+	}
+	if out.minByte2 > out.maxByte2 || out.maxByte2 > 0xFF || out.minByte1 > out.maxByte1 || out.maxByte1 > 0xFF {
+		return out, fmt.Errorf("invalid PCF encoding range %d-%d, %d-%d",
+			out.minByte1, out.maxByte1, out.minByte2, out.maxByte2)
+	}
+	n := int(out.maxByte2-out.minByte2+1) * int(out.maxByte1-out.minByte1+1)
+	if r.pos+2*n > len(r.data) {
+		return out, errEOF
+	}
+	out.glyphs = make([]uint16, n)
+	for i := range out.glyphs {
+		out.glyphs[i], _ = r.u16()
+	}
+	return out, nil
+}
+
+// lookup returns the glyph for the (possibly two bytes) code.
+func (enc encoding) lookup(code uint16) (GID, bool) {
+	byte1, byte2 := code>>8, code&0xFF
+	if byte1 < enc.minByte1 || byte1 > enc.maxByte1 || byte2 < enc.minByte2 || byte2 > enc.maxByte2 {
+		return 0, false
+	}
+	index := int(byte1-enc.minByte1)*int(enc.maxByte2-enc.minByte2+1) + int(byte2-enc.minByte2)
+	gid := enc.glyphs[index]
+	return GID(gid), gid != 0xFFFF
+}
+
+func (r *reader) glyphNames() ([]string, error) {
+	n, err := r.count(4)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]uint32, n)
+	for i := range offsets {
+		offsets[i], _ = r.u32()
+	}
+	size, err := r.count(1)
+	if err != nil {
+		return nil, err
+	}
+	strs := r.data[r.pos : r.pos+size]
+	out := make([]string, n)
+	for i, offset := range offsets {
+		if out[i], err = cString(strs, offset); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
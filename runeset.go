@@ -0,0 +1,90 @@
+package font
+
+import "math/bits"
+
+const (
+	runePageSize  = 256 // runes per page
+	runePlaneSize = 256 // pages per plane
+	numPlanes     = 17  // up to U+10FFFF
+)
+
+// runePage is a bitset of 256 runes.
+type runePage [runePageSize / 64]uint64
+
+type runePlane [runePlaneSize]*runePage
+
+// RuneSet is an immutable set of runes, such as the coverage of a font,
+// stored as a bitset over the Unicode planes, so that Contains
+// runs in constant time.
+// The zero value is an empty set.
+type RuneSet struct {
+	planes [numPlanes]*runePlane
+	length int
+}
+
+// NewRuneSet returns the set of the given runes.
+// Runes outside of the Unicode range are ignored.
+func NewRuneSet(runes ...rune) *RuneSet {
+	var out RuneSet
+	for _, r := range runes {
+		if r < 0 || r > 0x10FFFF {
+			continue
+		}
+		plane := out.planes[r>>16]
+		if plane == nil {
+			plane = new(runePlane)
+			out.planes[r>>16] = plane
+		}
+		page := plane[(r>>8)&0xFF]
+		if page == nil {
+			page = new(runePage)
+			plane[(r>>8)&0xFF] = page
+		}
+		word, bit := &page[(r>>6)&3], uint64(1)<<(r&63)
+		if *word&bit == 0 {
+			*word |= bit
+			out.length++
+		}
+	}
+	return &out
+}
+
+// Contains returns true if `r` is in the set.
+func (rs *RuneSet) Contains(r rune) bool {
+	if r < 0 || r > 0x10FFFF {
+		return false
+	}
+	plane := rs.planes[r>>16]
+	if plane == nil {
+		return false
+	}
+	page := plane[(r>>8)&0xFF]
+	return page != nil && page[(r>>6)&3]&(1<<(r&63)) != 0
+}
+
+// Len returns the number of runes in the set.
+func (rs *RuneSet) Len() int { return rs.length }
+
+// Iterate calls `fn` for each rune of the set, in increasing order,
+// until `fn` returns false.
+func (rs *RuneSet) Iterate(fn func(r rune) bool) {
+	for p, plane := range rs.planes {
+		if plane == nil {
+			continue
+		}
+		for q, page := range plane {
+			if page == nil {
+				continue
+			}
+			for w, word := range page {
+				for word != 0 {
+					bit := bits.TrailingZeros64(word)
+					word &^= 1 << bit
+					if !fn(rune(p<<16 | q<<8 | w<<6 | bit)) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package font
+
+import "math"
+
+// Rounding specifies how the metrics returned by a Scaler
+// are adjusted to whole pixels.
+type Rounding uint8
+
+const (
+	// RoundNone keeps the fractional pixel values.
+	RoundNone Rounding = iota
+	// RoundNearest rounds each value to the nearest pixel.
+	RoundNearest
+	// RoundOutward rounds the advances and the line metrics to the nearest
+	// pixel, and the extents away from the origin of the glyph (or from
+	// the baseline), so that the rounded boxes enclose the exact ones.
+	RoundOutward
+)
+
+// Scaler converts the metrics of a Face, expressed in font units,
+// to pixels, for a given point size and device resolution.
+// The y axis grows up, as for the font units.
+type Scaler struct {
+	Face Face
+	// Size is the font size, in points (1/72 of an inch).
+	Size float32
+	// DPI is the resolution of the device, in dots per inch.
+	// The zero value is interpreted as 72, so that one point is one pixel.
+	DPI      float32
+	Rounding Rounding
+}
+
+// NewScaler returns a Scaler for `face` at `size` points, on
+// a device of resolution `dpi`, without rounding.
+func NewScaler(face Face, size, dpi float32) Scaler {
+	return Scaler{Face: face, Size: size, DPI: dpi}
+}
+
+// PPEM returns the number of pixels per em.
+func (s Scaler) PPEM() float32 {
+	dpi := s.DPI
+	if dpi == 0 {
+		dpi = 72
+	}
+	return s.Size * dpi / 72
+}
+
+// Scale returns the number of pixels per font unit.
+func (s Scaler) Scale() float32 {
+	upem := s.Face.Upem()
+	if upem == 0 {
+		return 0
+	}
+	return s.PPEM() / float32(upem)
+}
+
+func (s Scaler) round(v float32) float32 {
+	if s.Rounding == RoundNone {
+		return v
+	}
+	return float32(math.Round(float64(v)))
+}
+
+// outward rounds `v` away from zero if the rounding is RoundOutward.
+func (s Scaler) outward(v float32) float32 {
+	if s.Rounding != RoundOutward {
+		return s.round(v)
+	}
+	if v < 0 {
+		return float32(math.Floor(float64(v)))
+	}
+	return float32(math.Ceil(float64(v)))
+}
+
+// HorizontalAdvance returns the horizontal advance of the glyph, in pixels.
+func (s Scaler) HorizontalAdvance(gid GID) float32 {
+	return s.round(s.Face.HorizontalAdvance(gid) * s.Scale())
+}
+
+// VerticalAdvance returns the vertical advance of the glyph, in pixels,
+// which is negative for top to bottom text.
+func (s Scaler) VerticalAdvance(gid GID) float32 {
+	return s.round(s.Face.VerticalAdvance(gid) * s.Scale())
+}
+
+// FontHExtents returns the extents of the font for horizontal text, in pixels.
+// With RoundOutward, the ascender and the descender are rounded away from the baseline.
+func (s Scaler) FontHExtents() (FontExtents, bool) {
+	ext, ok := s.Face.FontHExtents()
+	if !ok {
+		return FontExtents{}, false
+	}
+	scale := s.Scale()
+	return FontExtents{
+		Ascender:  s.outward(ext.Ascender * scale),
+		Descender: s.outward(ext.Descender * scale),
+		LineGap:   s.round(ext.LineGap * scale),
+	}, true
+}
+
+// LineMetric returns the metric identified by `metric`, in pixels.
+func (s Scaler) LineMetric(metric LineMetric) (float32, bool) {
+	v, ok := s.Face.LineMetric(metric)
+	if !ok {
+		return 0, false
+	}
+	return s.round(v * s.Scale()), true
+}
+
+// GlyphExtents returns the extents of the glyph, in pixels, or false
+// if the face does not implement GlyphExtenter, or if the glyph extents
+// are not available.
+// With RoundOutward, the rounded box encloses the exact one.
+func (s Scaler) GlyphExtents(gid GID) (GlyphExtents, bool) {
+	extenter, ok := s.Face.(GlyphExtenter)
+	if !ok {
+		return GlyphExtents{}, false
+	}
+	ext, ok := extenter.GlyphExtents(gid)
+	if !ok {
+		return GlyphExtents{}, false
+	}
+	scale := s.Scale()
+	xMin, yMax := ext.XBearing*scale, ext.YBearing*scale
+	xMax, yMin := xMin+ext.Width*scale, yMax+ext.Height*scale
+	switch s.Rounding {
+	case RoundNearest:
+		xMin, yMax = s.round(xMin), s.round(yMax)
+		xMax, yMin = s.round(xMax), s.round(yMin)
+	case RoundOutward:
+		xMin, yMax = float32(math.Floor(float64(xMin))), float32(math.Ceil(float64(yMax)))
+		xMax, yMin = float32(math.Ceil(float64(xMax))), float32(math.Floor(float64(yMin)))
+	}
+	return GlyphExtents{XBearing: xMin, YBearing: yMax, Width: xMax - xMin, Height: yMin - yMax}, true
+}
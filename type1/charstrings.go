@@ -0,0 +1,427 @@
+package type1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/benoitkugler/textlayout/fonts/simpleencodings"
+	"github.com/go-text/font"
+)
+
+// This file implements an interpreter for Type1 charstrings,
+// as defined in chapter 6 of the Adobe Type 1 Font Format specification.
+
+const (
+	maxStackSize  = 24
+	maxSubrsDepth = 10
+)
+
+type point struct{ x, y float64 }
+
+func (p point) add(q point) point { return point{p.x + q.x, p.y + q.y} }
+
+func (p point) toSegmentPoint() font.SegmentPoint {
+	return font.SegmentPoint{X: float32(p.x), Y: float32(p.y)}
+}
+
+// glyphData is the result of the interpretation of a charstring
+type glyphData struct {
+	lsb, advance point
+	segments     []font.Segment
+}
+
+// extents returns the bounding box of the points of the outline,
+// including the control points of the curves.
+// Move points not followed by a line or a curve are ignored.
+func (g glyphData) extents() font.GlyphExtents {
+	xMin, yMin := float32(math.Inf(+1)), float32(math.Inf(+1))
+	xMax, yMax := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for i, s := range g.segments {
+		args := s.ArgsSlice()
+		if s.Op == font.SegmentOpMoveTo && (i+1 == len(g.segments) || g.segments[i+1].Op == font.SegmentOpMoveTo) {
+			args = nil
+		}
+		for _, p := range args {
+			if p.X < xMin {
+				xMin = p.X
+			}
+			if p.X > xMax {
+				xMax = p.X
+			}
+			if p.Y < yMin {
+				yMin = p.Y
+			}
+			if p.Y > yMax {
+				yMax = p.Y
+			}
+		}
+	}
+	if xMin > xMax { // no outline
+		return font.GlyphExtents{}
+	}
+	return font.GlyphExtents{
+		XBearing: xMin,
+		YBearing: yMax,
+		Width:    xMax - xMin,
+		Height:   yMin - yMax,
+	}
+}
+
+type interpreter struct {
+	face *Face
+
+	stack   []float64
+	psStack []float64 // results of the othersubrs
+
+	glyph glyphData
+
+	current point
+	offset  point // used to position seac accents
+
+	flex       bool
+	flexPoints []point
+
+	depth     int
+	seac      bool // nested charstrings may not use seac
+	widthOnly bool
+	done      bool
+}
+
+// runCharstring interprets the given charstring. If `widthOnly` is true,
+// the execution stops as soon as the advance is known.
+func (f *Face) runCharstring(data []byte, widthOnly bool) (glyphData, error) {
+	ip := interpreter{face: f, widthOnly: widthOnly, stack: make([]float64, 0, maxStackSize)}
+	err := ip.run(data)
+	return ip.glyph, err
+}
+
+func (ip *interpreter) push(v float64) error {
+	if len(ip.stack) >= maxStackSize {
+		return errors.New("invalid Type1 charstring (stack overflow)")
+	}
+	ip.stack = append(ip.stack, v)
+	return nil
+}
+
+func (ip *interpreter) clear() { ip.stack = ip.stack[:0] }
+
+func (ip *interpreter) moveTo(p point) {
+	ip.current = p
+	ip.glyph.segments = append(ip.glyph.segments, font.Segment{Op: font.SegmentOpMoveTo, Args: [3]font.SegmentPoint{p.toSegmentPoint()}})
+}
+
+func (ip *interpreter) lineTo(p point) {
+	ip.current = p
+	ip.glyph.segments = append(ip.glyph.segments, font.Segment{Op: font.SegmentOpLineTo, Args: [3]font.SegmentPoint{p.toSegmentPoint()}})
+}
+
+func (ip *interpreter) cubeTo(p1, p2, p3 point) {
+	ip.current = p3
+	ip.glyph.segments = append(ip.glyph.segments, font.Segment{Op: font.SegmentOpCubeTo, Args: [3]font.SegmentPoint{p1.toSegmentPoint(), p2.toSegmentPoint(), p3.toSegmentPoint()}})
+}
+
+// relCubeTo adds a curve using three relative displacements.
+func (ip *interpreter) relCubeTo(d1, d2, d3 point) {
+	p1 := ip.current.add(d1)
+	p2 := p1.add(d2)
+	ip.cubeTo(p1, p2, p2.add(d3))
+}
+
+// rmoveTo handles the special case of flex sequences, where
+// rmoveto only record the points.
+func (ip *interpreter) rmoveTo(d point) {
+	if ip.flex {
+		ip.current = ip.current.add(d)
+		ip.flexPoints = append(ip.flexPoints, ip.current)
+		return
+	}
+	ip.moveTo(ip.current.add(d))
+}
+
+// args checks that at least `n` arguments are on the stack
+func (ip *interpreter) args(n int, op string) ([]float64, error) {
+	if len(ip.stack) < n {
+		return nil, fmt.Errorf("invalid Type1 charstring (missing arguments for %s)", op)
+	}
+	return ip.stack[len(ip.stack)-n:], nil
+}
+
+func (ip *interpreter) run(code []byte) error {
+	if ip.depth > maxSubrsDepth {
+		return errors.New("invalid Type1 charstring (subroutines nesting limit reached)")
+	}
+	ip.depth++
+	defer func() { ip.depth-- }()
+
+	for pos := 0; pos < len(code) && !ip.done; {
+		b := int(code[pos])
+		pos++
+
+		// numbers
+		switch {
+		case b >= 32 && b <= 246:
+			if err := ip.push(float64(b - 139)); err != nil {
+				return err
+			}
+			continue
+		case b >= 247 && b <= 254:
+			if pos >= len(code) {
+				return errors.New("invalid Type1 charstring (EOF)")
+			}
+			w := int(code[pos])
+			pos++
+			v := (b-247)*256 + w + 108
+			if b >= 251 {
+				v = -(b-251)*256 - w - 108
+			}
+			if err := ip.push(float64(v)); err != nil {
+				return err
+			}
+			continue
+		case b == 255:
+			if pos+4 > len(code) {
+				return errors.New("invalid Type1 charstring (EOF)")
+			}
+			v := int32(uint32(code[pos])<<24 | uint32(code[pos+1])<<16 | uint32(code[pos+2])<<8 | uint32(code[pos+3]))
+			pos += 4
+			if err := ip.push(float64(v)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// operators
+		if b == 12 {
+			if pos >= len(code) {
+				return errors.New("invalid Type1 charstring (EOF)")
+			}
+			b = 256 + int(code[pos])
+			pos++
+		}
+		if err := ip.operator(b); err != nil {
+			return err
+		}
+		if b == 11 { // return
+			return nil
+		}
+	}
+	return nil
+}
+
+func (ip *interpreter) operator(op int) error {
+	switch op {
+	case 13: // hsbw
+		a, err := ip.args(2, "hsbw")
+		if err != nil {
+			return err
+		}
+		ip.setWidth(point{a[0], 0}, point{a[1], 0})
+	case 256 + 7: // sbw
+		a, err := ip.args(4, "sbw")
+		if err != nil {
+			return err
+		}
+		ip.setWidth(point{a[0], a[1]}, point{a[2], a[3]})
+	case 1, 3, 256 + 0, 256 + 1, 256 + 2, 9: // hstem, vstem, dotsection, vstem3, hstem3, closepath
+		// hints are ignored, closepath does not move the current point
+	case 21: // rmoveto
+		a, err := ip.args(2, "rmoveto")
+		if err != nil {
+			return err
+		}
+		ip.rmoveTo(point{a[0], a[1]})
+	case 22: // hmoveto
+		a, err := ip.args(1, "hmoveto")
+		if err != nil {
+			return err
+		}
+		ip.rmoveTo(point{a[0], 0})
+	case 4: // vmoveto
+		a, err := ip.args(1, "vmoveto")
+		if err != nil {
+			return err
+		}
+		ip.rmoveTo(point{0, a[0]})
+	case 5: // rlineto
+		a, err := ip.args(2, "rlineto")
+		if err != nil {
+			return err
+		}
+		ip.lineTo(ip.current.add(point{a[0], a[1]}))
+	case 6: // hlineto
+		a, err := ip.args(1, "hlineto")
+		if err != nil {
+			return err
+		}
+		ip.lineTo(ip.current.add(point{a[0], 0}))
+	case 7: // vlineto
+		a, err := ip.args(1, "vlineto")
+		if err != nil {
+			return err
+		}
+		ip.lineTo(ip.current.add(point{0, a[0]}))
+	case 8: // rrcurveto
+		a, err := ip.args(6, "rrcurveto")
+		if err != nil {
+			return err
+		}
+		ip.relCubeTo(point{a[0], a[1]}, point{a[2], a[3]}, point{a[4], a[5]})
+	case 30: // vhcurveto
+		a, err := ip.args(4, "vhcurveto")
+		if err != nil {
+			return err
+		}
+		ip.relCubeTo(point{0, a[0]}, point{a[1], a[2]}, point{a[3], 0})
+	case 31: // hvcurveto
+		a, err := ip.args(4, "hvcurveto")
+		if err != nil {
+			return err
+		}
+		ip.relCubeTo(point{a[0], 0}, point{a[1], a[2]}, point{0, a[3]})
+	case 10: // callsubr
+		a, err := ip.args(1, "callsubr")
+		if err != nil {
+			return err
+		}
+		index := int(a[0])
+		ip.stack = ip.stack[:len(ip.stack)-1]
+		if index < 0 || index >= len(ip.face.subrs) {
+			return fmt.Errorf("invalid Type1 charstring (subroutine %d out of range)", index)
+		}
+		return ip.run(ip.face.subrs[index]) // the arguments are kept on the stack
+	case 11: // return
+		return nil
+	case 14: // endchar
+		ip.done = true
+	case 256 + 6: // seac
+		a, err := ip.args(5, "seac")
+		if err != nil {
+			return err
+		}
+		return ip.doSeac(a[0], a[1], a[2], a[3], a[4])
+	case 256 + 12: // div
+		a, err := ip.args(2, "div")
+		if err != nil {
+			return err
+		}
+		ip.stack = ip.stack[:len(ip.stack)-2]
+		if a[1] == 0 {
+			return errors.New("invalid Type1 charstring (division by zero)")
+		}
+		return ip.push(a[0] / a[1]) // arguments are not cleared
+	case 256 + 16: // callothersubr
+		return ip.callOtherSubr()
+	case 256 + 17: // pop
+		if len(ip.psStack) == 0 {
+			return errors.New("invalid Type1 charstring (empty PostScript stack)")
+		}
+		v := ip.psStack[len(ip.psStack)-1]
+		ip.psStack = ip.psStack[:len(ip.psStack)-1]
+		return ip.push(v) // arguments are not cleared
+	case 256 + 33: // setcurrentpoint
+		a, err := ip.args(2, "setcurrentpoint")
+		if err != nil {
+			return err
+		}
+		ip.current = point{a[0], a[1]}
+	default:
+		// unknown operators are ignored
+	}
+	ip.clear()
+	return nil
+}
+
+func (ip *interpreter) setWidth(lsb, advance point) {
+	ip.glyph.lsb, ip.glyph.advance = lsb, advance
+	ip.current = lsb.add(ip.offset)
+	if ip.widthOnly {
+		ip.done = true
+	}
+}
+
+// callOtherSubr implements the standard OtherSubrs 0 to 3 (flex and hints replacement) :
+// the other ones only copy their arguments to the PostScript stack.
+func (ip *interpreter) callOtherSubr() error {
+	a, err := ip.args(2, "callothersubr")
+	if err != nil {
+		return err
+	}
+	index, n := int(a[1]), int(a[0])
+	ip.stack = ip.stack[:len(ip.stack)-2]
+	args, err := ip.args(n, "callothersubr")
+	if err != nil {
+		return err
+	}
+	ip.stack = ip.stack[:len(ip.stack)-n]
+
+	ip.psStack = ip.psStack[:0]
+	switch index {
+	case 0: // end flex
+		if !ip.flex || len(ip.flexPoints) != 7 {
+			return errors.New("invalid Type1 charstring (invalid flex)")
+		}
+		ip.flex = false
+		// the first point is the reference point, not drawn
+		p := ip.flexPoints
+		ip.cubeTo(p[1], p[2], p[3])
+		ip.cubeTo(p[4], p[5], p[6])
+		// the following pop pop setcurrentpoint sequence expects the end point
+		ip.psStack = append(ip.psStack, p[6].y, p[6].x)
+	case 1: // start flex
+		ip.flex = true
+		ip.flexPoints = ip.flexPoints[:0]
+	case 2: // add flex point : handled by rmoveto
+	default:
+		for i := len(args) - 1; i >= 0; i-- {
+			ip.psStack = append(ip.psStack, args[i])
+		}
+	}
+	return nil
+}
+
+// doSeac builds an accented character from two glyphs
+// of the standard encoding, and ends the charstring.
+func (ip *interpreter) doSeac(asb, adx, ady, bchar, achar float64) error {
+	if ip.seac {
+		return errors.New("invalid Type1 charstring (nested seac)")
+	}
+	ip.done = true
+	if ip.widthOnly {
+		return nil
+	}
+
+	base, err := ip.standardGlyph(bchar)
+	if err != nil {
+		return err
+	}
+	accent, err := ip.standardGlyph(achar)
+	if err != nil {
+		return err
+	}
+
+	sub := interpreter{face: ip.face, seac: true, stack: make([]float64, 0, maxStackSize)}
+	if err = sub.run(base); err != nil {
+		return err
+	}
+	sub.done = false
+	// the accent origin is relative to the composite glyph sidebearing
+	sub.offset = point{adx - asb + ip.glyph.lsb.x, ady}
+	if err = sub.run(accent); err != nil {
+		return err
+	}
+	ip.glyph.segments = append(ip.glyph.segments, sub.glyph.segments...)
+	return nil
+}
+
+func (ip *interpreter) standardGlyph(code float64) ([]byte, error) {
+	if code < 0 || code > 255 {
+		return nil, errors.New("invalid Type1 charstring (invalid seac code)")
+	}
+	name := simpleencodings.AdobeStandard[int(code)]
+	gid, ok := ip.face.nameToGID[name]
+	if name == "" || !ok {
+		return nil, fmt.Errorf("invalid Type1 charstring (missing seac glyph %s)", name)
+	}
+	return ip.face.charstrings[gid].data, nil
+}
@@ -0,0 +1,251 @@
+package type1
+
+import (
+	"errors"
+	"strconv"
+)
+
+// This file implements a minimal PostScript tokenizer,
+// sufficient to extract the content of the font dictionaries.
+
+type tokenKind uint8
+
+const (
+	tokEOF      tokenKind = iota
+	tokNumber             // integer or real
+	tokName               // literal name ("/name"), without the slash
+	tokOperator           // executable name, such as def or RD
+	tokString             // literal or hex string, decoded
+	tokOpenArray
+	tokCloseArray
+	tokOpenProc
+	tokCloseProc
+	tokOpenDict
+	tokCloseDict
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	num   float64 // for tokNumber
+}
+
+func (tk token) isOperator(name string) bool { return tk.kind == tokOperator && tk.value == name }
+
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func isWhitespace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0:
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isWhitespace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// regular reads a regular (non delimited) sequence of characters.
+func (l *lexer) regular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// next returns the next token, or a tokEOF token
+// at the end of the input.
+func (l *lexer) next() token {
+	l.skipSpaceAndComments()
+	if l.pos >= len(l.data) {
+		return token{kind: tokEOF}
+	}
+	switch c := l.data[l.pos]; c {
+	case '/':
+		l.pos++
+		return token{kind: tokName, value: l.regular()}
+	case '(':
+		l.pos++
+		return token{kind: tokString, value: l.literalString()}
+	case '<':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '<' {
+			l.pos++
+			return token{kind: tokOpenDict}
+		}
+		return token{kind: tokString, value: l.hexString()}
+	case '>':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+		}
+		return token{kind: tokCloseDict}
+	case '[':
+		l.pos++
+		return token{kind: tokOpenArray}
+	case ']':
+		l.pos++
+		return token{kind: tokCloseArray}
+	case '{':
+		l.pos++
+		return token{kind: tokOpenProc}
+	case '}':
+		l.pos++
+		return token{kind: tokCloseProc}
+	case ')':
+		// unbalanced : ignore it
+		l.pos++
+		return l.next()
+	default:
+		s := l.regular()
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return token{kind: tokNumber, value: s, num: f}
+		}
+		return token{kind: tokOperator, value: s}
+	}
+}
+
+// literalString reads a string after the opening parenthesis,
+// handling nested parenthesis and escape sequences.
+func (l *lexer) literalString() string {
+	var (
+		out   []byte
+		depth = 1
+	)
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(out)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return string(out)
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n': // line continuation
+				continue
+			default:
+				if '0' <= c && c <= '7' {
+					v := c - '0'
+					for i := 0; i < 2 && l.pos < len(l.data) && '0' <= l.data[l.pos] && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + l.data[l.pos] - '0'
+						l.pos++
+					}
+					c = v
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+// hexString reads a string after the opening '<'
+func (l *lexer) hexString() string {
+	var (
+		out  []byte
+		half = -1
+	)
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		v, ok := fromHex(c)
+		if !ok {
+			continue
+		}
+		if half == -1 {
+			half = int(v)
+		} else {
+			out = append(out, byte(half)<<4|v)
+			half = -1
+		}
+	}
+	if half != -1 {
+		out = append(out, byte(half)<<4)
+	}
+	return string(out)
+}
+
+func fromHex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// readBinary returns the `n` bytes following the current token,
+// which must be the RD (or -|) operator, separated from the data by exactly one space.
+func (l *lexer) readBinary(n int) ([]byte, error) {
+	start := l.pos + 1
+	if n < 0 || start+n > len(l.data) {
+		return nil, errors.New("invalid binary section in Type1 font (EOF)")
+	}
+	l.pos = start + n
+	return l.data[start:l.pos], nil
+}
+
+// numbers reads an array or procedure of numbers, such as [0.001 0 0 0.001 0 0]
+func (l *lexer) numbers() []float64 {
+	open := l.next()
+	if open.kind != tokOpenArray && open.kind != tokOpenProc {
+		return nil
+	}
+	var out []float64
+	for {
+		tk := l.next()
+		if tk.kind != tokNumber {
+			return out
+		}
+		out = append(out, tk.num)
+	}
+}
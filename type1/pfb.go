@@ -0,0 +1,131 @@
+package type1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	eexecKey      = 55665
+	charstringKey = 4330
+)
+
+// splitSegments returns the cleartext and the (still encrypted) private part
+// of a Type1 font, given in either binary (.pfb) or ASCII (.pfa) form.
+func splitSegments(data []byte) (clear, private []byte, err error) {
+	if len(data) >= 2 && data[0] == 0x80 {
+		return splitPFB(data)
+	}
+	return splitPFA(data)
+}
+
+// splitPFB reads the segments of a .pfb file, made of
+// 0x80, a segment type, and a little endian 32-bit length.
+func splitPFB(data []byte) (clear, private []byte, err error) {
+	for len(data) != 0 {
+		if len(data) < 2 || data[0] != 0x80 {
+			return nil, nil, errors.New("invalid PFB segment header")
+		}
+		kind := data[1]
+		if kind == 3 { // EOF
+			break
+		}
+		if len(data) < 6 {
+			return nil, nil, errors.New("invalid PFB segment header (EOF)")
+		}
+		length := binary.LittleEndian.Uint32(data[2:])
+		data = data[6:]
+		if uint64(length) > uint64(len(data)) {
+			return nil, nil, errors.New("invalid PFB segment length")
+		}
+		segment := data[:length]
+		data = data[length:]
+
+		switch kind {
+		case 1: // ASCII : only the first one is relevant, the last one being the trailer
+			if private == nil {
+				clear = append(clear, segment...)
+			}
+		case 2: // binary
+			private = append(private, segment...)
+		default:
+			return nil, nil, errors.New("invalid PFB segment type")
+		}
+	}
+	if clear == nil {
+		return nil, nil, errors.New("missing cleartext segment in PFB file")
+	}
+	return clear, private, nil
+}
+
+// splitPFA uses the eexec operator to delimit the encrypted part of a .pfa file,
+// which may be stored in hexadecimal or binary form.
+func splitPFA(data []byte) (clear, private []byte, err error) {
+	index := bytes.Index(data, []byte("eexec"))
+	if index == -1 {
+		return nil, nil, errors.New("invalid Type1 font: missing eexec operator")
+	}
+	clear = data[:index+len("eexec")]
+	private = data[index+len("eexec"):]
+	for len(private) != 0 && isWhitespace(private[0]) {
+		private = private[1:]
+	}
+
+	if isHexStart(private) {
+		private = decodeHex(private)
+	}
+	return clear, private, nil
+}
+
+// isHexStart applies the heuristic suggested by the specification :
+// the first 4 bytes of hexadecimal encrypted data are hex digits.
+func isHexStart(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	for _, c := range data[:4] {
+		if _, ok := fromHex(c); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeHex decodes the hexadecimal data, ignoring whitespaces
+// and stopping at the first invalid character.
+func decodeHex(data []byte) []byte {
+	out := make([]byte, 0, len(data)/2)
+	half := -1
+	for _, c := range data {
+		if isWhitespace(c) {
+			continue
+		}
+		v, ok := fromHex(c)
+		if !ok {
+			break
+		}
+		if half == -1 {
+			half = int(v)
+		} else {
+			out = append(out, byte(half)<<4|v)
+			half = -1
+		}
+	}
+	return out
+}
+
+// decrypt applies the Type1 decryption algorithm,
+// and removes the `skip` first random bytes.
+func decrypt(data []byte, key uint16, skip int) []byte {
+	const c1, c2 = 52845, 22719
+	out := make([]byte, len(data))
+	for i, c := range data {
+		out[i] = c ^ byte(key>>8)
+		key = (uint16(c)+key)*c1 + c2
+	}
+	if skip > len(out) {
+		return nil
+	}
+	return out[skip:]
+}
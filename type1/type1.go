@@ -0,0 +1,472 @@
+// Package type1 provides support for PostScript Type1 fonts,
+// stored in binary (.pfb) or ASCII (.pfa) form, as found in PDF files.
+//
+// The glyph names are mapped to Unicode using the Adobe Glyph List,
+// and the charstrings are decrypted and interpreted to provide the glyph metrics.
+package type1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/benoitkugler/textlayout/fonts/glyphsnames"
+	"github.com/benoitkugler/textlayout/fonts/simpleencodings"
+	"github.com/go-text/font"
+	"github.com/go-text/font/afm"
+)
+
+var (
+	_ font.Face          = (*Face)(nil)
+	_ font.GlyphExtenter = (*Face)(nil)
+	_ font.GlyphCounter  = (*Face)(nil)
+	_ font.GlyphOutliner = (*Face)(nil)
+)
+
+// GID is used to identify glyphs in a font.
+type GID = font.GID
+
+const notdef = ".notdef"
+
+// FontInfo stores the optional entries of the FontInfo dictionary.
+type FontInfo struct {
+	Version    string
+	Notice     string
+	FullName   string
+	FamilyName string
+	Weight     string
+
+	ItalicAngle  float64
+	IsFixedPitch bool
+
+	UnderlinePosition  float64
+	UnderlineThickness float64
+}
+
+// Face is a Type1 font.
+// The glyph indices are the order of the glyphs in the CharStrings dictionary,
+// with the .notdef glyph moved to index 0.
+type Face struct {
+	FontName string
+	Info     FontInfo
+
+	// FontMatrix maps glyph space to text space,
+	// and is usually [0.001 0 0 0.001 0 0]
+	FontMatrix [6]float64
+	FontBBox   [4]float64 // xMin, yMin, xMax, yMax
+
+	// Encoding maps character codes to glyph names.
+	// Unused codes are mapped to an empty string.
+	Encoding [256]string
+
+	charstrings []charstring
+	subrs       [][]byte
+
+	nameToGID map[string]GID
+	cmap      map[rune]GID
+
+	metrics *afm.Metrics // optional, see AttachMetrics
+}
+
+type charstring struct {
+	name string
+	data []byte // decrypted
+}
+
+// ParseFont reads a Type1 font, in binary (.pfb) or ASCII (.pfa) format.
+func ParseFont(file font.Resource) (*Face, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil { // file might have been used before
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	clear, private, err := splitSegments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := Face{FontMatrix: [6]float64{0.001, 0, 0, 0.001, 0, 0}}
+	out.parseCleartext(clear)
+	if out.FontName == "" {
+		return nil, errors.New("invalid Type1 font: missing FontName")
+	}
+
+	if err = out.parsePrivate(decrypt(private, eexecKey, 4)); err != nil {
+		return nil, err
+	}
+	if len(out.charstrings) == 0 {
+		return nil, errors.New("invalid Type1 font: missing CharStrings")
+	}
+
+	out.buildIndex()
+	return &out, nil
+}
+
+// parseCleartext reads the public font dictionary, ignoring unknown entries.
+func (f *Face) parseCleartext(data []byte) {
+	l := lexer{data: data}
+	for {
+		tk := l.next()
+		if tk.kind == tokEOF {
+			return
+		}
+		if tk.kind != tokName {
+			continue
+		}
+		switch tk.value {
+		case "FontName":
+			if v := l.next(); v.kind == tokName {
+				f.FontName = v.value
+			}
+		case "version":
+			f.Info.Version = l.next().value
+		case "Notice":
+			f.Info.Notice = l.next().value
+		case "FullName":
+			f.Info.FullName = l.next().value
+		case "FamilyName":
+			f.Info.FamilyName = l.next().value
+		case "Weight":
+			f.Info.Weight = l.next().value
+		case "ItalicAngle":
+			f.Info.ItalicAngle = l.next().num
+		case "isFixedPitch":
+			f.Info.IsFixedPitch = l.next().isOperator("true")
+		case "UnderlinePosition":
+			f.Info.UnderlinePosition = l.next().num
+		case "UnderlineThickness":
+			f.Info.UnderlineThickness = l.next().num
+		case "FontMatrix":
+			if m := l.numbers(); len(m) == 6 {
+				copy(f.FontMatrix[:], m)
+			}
+		case "FontBBox":
+			if m := l.numbers(); len(m) == 4 {
+				copy(f.FontBBox[:], m)
+			}
+		case "Encoding":
+			f.parseEncoding(&l)
+		}
+	}
+}
+
+// parseEncoding reads either a predefined encoding name,
+// or a sequence of 'dup <code> /<name> put', ended by 'def'.
+func (f *Face) parseEncoding(l *lexer) {
+	tk := l.next()
+	if tk.isOperator("StandardEncoding") {
+		f.Encoding = simpleencodings.AdobeStandard
+		return
+	}
+
+	for {
+		switch tk = l.next(); {
+		case tk.kind == tokEOF, tk.isOperator("def"):
+			return
+		case tk.isOperator("dup"):
+			code, name := l.next(), l.next()
+			if code.kind != tokNumber || name.kind != tokName {
+				continue
+			}
+			if l.next().isOperator("put") && 0 <= code.num && code.num < 256 {
+				f.Encoding[int(code.num)] = name.value
+			}
+		}
+	}
+}
+
+// parsePrivate reads the decrypted Private dictionary,
+// extracting the subroutines and the charstrings.
+func (f *Face) parsePrivate(data []byte) error {
+	var (
+		l           = lexer{data: data}
+		lenIV       = 4
+		subrs       [][]byte
+		charstrings []charstring
+	)
+	for {
+		tk := l.next()
+		if tk.kind == tokEOF {
+			break
+		}
+		if tk.kind != tokName {
+			continue
+		}
+		switch tk.value {
+		case "lenIV":
+			if v := l.next(); v.kind == tokNumber {
+				lenIV = int(v.num)
+			}
+		case "Subrs":
+			if subrs != nil {
+				continue
+			}
+			var err error
+			subrs, err = parseSubrs(&l)
+			if err != nil {
+				return err
+			}
+		case "CharStrings":
+			if charstrings != nil { // only use the first dictionary
+				continue
+			}
+			var err error
+			charstrings, err = parseCharStrings(&l)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	decryptCharstring := func(data []byte) []byte {
+		if lenIV < 0 { // no encryption
+			return data
+		}
+		return decrypt(data, charstringKey, lenIV)
+	}
+	f.subrs = make([][]byte, len(subrs))
+	for i, s := range subrs {
+		f.subrs[i] = decryptCharstring(s)
+	}
+	f.charstrings = charstrings
+	for i, cs := range charstrings {
+		f.charstrings[i].data = decryptCharstring(cs.data)
+	}
+	return nil
+}
+
+// parseSubrs reads '<count> array' followed by
+// '<count>' entries 'dup <index> <length> RD <binary> NP'
+func parseSubrs(l *lexer) ([][]byte, error) {
+	count := l.next()
+	if count.kind != tokNumber || count.num < 0 {
+		return nil, errors.New("invalid Type1 Subrs array")
+	}
+	out := make([][]byte, int(count.num))
+	for {
+		tk := l.next()
+		switch {
+		case tk.isOperator("array"), tk.isOperator("NP"), tk.isOperator("|"),
+			tk.isOperator("noaccess"), tk.isOperator("put"):
+			continue
+		case tk.isOperator("dup"):
+		default:
+			return out, nil
+		}
+
+		index, length, rd := l.next(), l.next(), l.next()
+		if index.kind != tokNumber || length.kind != tokNumber || rd.kind != tokOperator {
+			return nil, errors.New("invalid Type1 Subrs entry")
+		}
+		bin, err := l.readBinary(int(length.num))
+		if err != nil {
+			return nil, err
+		}
+		if i := int(index.num); 0 <= i && i < len(out) {
+			out[i] = bin
+		}
+	}
+}
+
+// parseCharStrings reads '<count> dict dup begin' followed by
+// entries '/<name> <length> RD <binary> ND', ended by 'end'
+func parseCharStrings(l *lexer) ([]charstring, error) {
+	var out []charstring
+	for {
+		tk := l.next()
+		switch {
+		case tk.kind == tokEOF, tk.isOperator("end"):
+			return out, nil
+		case tk.kind != tokName:
+			continue
+		}
+
+		length, rd := l.next(), l.next()
+		if length.kind != tokNumber || rd.kind != tokOperator {
+			return nil, fmt.Errorf("invalid Type1 charstring for glyph %s", tk.value)
+		}
+		bin, err := l.readBinary(int(length.num))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, charstring{name: tk.value, data: bin})
+	}
+}
+
+// buildIndex moves .notdef to index 0 and
+// builds the lookup tables by glyph name and by rune.
+func (f *Face) buildIndex() {
+	for i, cs := range f.charstrings {
+		if cs.name == notdef {
+			f.charstrings[0], f.charstrings[i] = f.charstrings[i], f.charstrings[0]
+			break
+		}
+	}
+
+	f.nameToGID = make(map[string]GID, len(f.charstrings))
+	for i, cs := range f.charstrings {
+		if _, has := f.nameToGID[cs.name]; !has {
+			f.nameToGID[cs.name] = GID(i)
+		}
+	}
+
+	f.cmap = make(map[rune]GID, len(f.charstrings))
+	addRune := func(name string) {
+		gid, ok := f.nameToGID[name]
+		if !ok || name == notdef {
+			return
+		}
+		if r, ok := glyphsnames.GlyphToRune(name); ok {
+			if _, has := f.cmap[r]; !has {
+				f.cmap[r] = gid
+			}
+		}
+	}
+	// give priority to the glyphs referenced by the encoding
+	for _, name := range f.Encoding {
+		addRune(name)
+	}
+	for _, cs := range f.charstrings {
+		addRune(cs.name)
+	}
+}
+
+// NumGlyphs returns the number of glyphs in the font.
+func (f *Face) NumGlyphs() int { return len(f.charstrings) }
+
+// GlyphCount implements font.GlyphCounter, and is the same as NumGlyphs.
+func (f *Face) GlyphCount() int { return f.NumGlyphs() }
+
+// Upem returns the units per em, deduced from the font matrix.
+func (f *Face) Upem() uint16 {
+	if f.FontMatrix[0] <= 0 {
+		return 1000
+	}
+	return uint16(1/f.FontMatrix[0] + 0.5)
+}
+
+// NominalGlyph returns the glyph for the given rune, using the
+// Adobe Glyph List to interpret the glyph names.
+func (f *Face) NominalGlyph(r rune) (GID, bool) {
+	gid, ok := f.cmap[r]
+	return gid, ok
+}
+
+// GlyphName returns the name of the given glyph, or an empty
+// string if the glyph is invalid.
+func (f *Face) GlyphName(gid GID) string {
+	if int(gid) >= len(f.charstrings) {
+		return ""
+	}
+	return f.charstrings[gid].name
+}
+
+// GlyphForCode returns the glyph used for the character code `code`,
+// according to the font built-in encoding.
+func (f *Face) GlyphForCode(code byte) (GID, bool) {
+	gid, ok := f.nameToGID[f.Encoding[code]]
+	return gid, ok
+}
+
+// AttachMetrics uses the given AFM metrics to provide kerning,
+// and advances for the glyphs whose charstrings are invalid.
+// Passing nil removes the metrics.
+func (f *Face) AttachMetrics(metrics *afm.Metrics) { f.metrics = metrics }
+
+// HorizontalAdvance returns the advance of the glyph, in font units,
+// or 0 if the glyph is invalid.
+// If the advance can't be computed from the charstring, the attached AFM metrics are used.
+func (f *Face) HorizontalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.charstrings) {
+		return 0
+	}
+	m, err := f.runCharstring(f.charstrings[gid].data, true)
+	if err != nil {
+		if f.metrics != nil {
+			if cm, ok := f.metrics.CharMetric(f.charstrings[gid].name); ok {
+				return float32(cm.WX)
+			}
+		}
+		return 0
+	}
+	return float32(m.advance.x)
+}
+
+// VerticalAdvance returns the opposite of the units per em, since
+// the font has no vertical metrics, or 0 if the glyph is invalid.
+func (f *Face) VerticalAdvance(gid GID) float32 {
+	if int(gid) >= len(f.charstrings) {
+		return 0
+	}
+	return -float32(f.Upem())
+}
+
+// GlyphExtents returns the bounding box of the glyph outline, in font units,
+// or false if the glyph is invalid. Since the control points of the curves
+// are included, the box may be larger than the exact bounds.
+func (f *Face) GlyphExtents(gid GID) (font.GlyphExtents, bool) {
+	if int(gid) >= len(f.charstrings) {
+		return font.GlyphExtents{}, false
+	}
+	g, err := f.runCharstring(f.charstrings[gid].data, false)
+	if err != nil {
+		return font.GlyphExtents{}, false
+	}
+	return g.extents(), true
+}
+
+// GlyphOutline returns the outline of the glyph, made of cubic Bézier curves,
+// or false if the glyph is invalid.
+func (f *Face) GlyphOutline(gid GID) (font.GlyphOutline, bool) {
+	if int(gid) >= len(f.charstrings) {
+		return font.GlyphOutline{}, false
+	}
+	g, err := f.runCharstring(f.charstrings[gid].data, false)
+	if err != nil {
+		return font.GlyphOutline{}, false
+	}
+	return font.GlyphOutline{Segments: g.segments}, true
+}
+
+// FontHExtents returns the ascender and descender of the attached AFM metrics,
+// or of the font bounding box, and a line gap such that the line height is
+// at least 1.2 times the units per em. It returns false if the font has no bounding box.
+func (f *Face) FontHExtents() (font.FontExtents, bool) {
+	ascender, descender := f.FontBBox[3], f.FontBBox[1]
+	if f.metrics != nil && (f.metrics.Ascender != 0 || f.metrics.Descender != 0) {
+		ascender, descender = f.metrics.Ascender, f.metrics.Descender
+	} else if f.FontBBox == [4]float64{} {
+		return font.FontExtents{}, false
+	}
+	out := font.FontExtents{Ascender: float32(ascender), Descender: float32(descender)}
+	if height := 1.2 * float32(f.Upem()); height > out.Ascender-out.Descender {
+		out.LineGap = height - (out.Ascender - out.Descender)
+	}
+	return out, true
+}
+
+// LineMetric returns the underline position and thickness
+// of the font information, and false for the other metrics.
+func (f *Face) LineMetric(metric font.LineMetric) (float32, bool) {
+	switch metric {
+	case font.UnderlinePosition:
+		return float32(f.Info.UnderlinePosition), true
+	case font.UnderlineThickness:
+		return float32(f.Info.UnderlineThickness), true
+	default:
+		return 0, false
+	}
+}
+
+// Kerning returns the horizontal kerning adjustment (in font units) between
+// the glyphs `left` and `right`, as provided by the attached AFM metrics, or 0.
+func (f *Face) Kerning(left, right GID) float32 {
+	if f.metrics == nil || int(left) >= len(f.charstrings) || int(right) >= len(f.charstrings) {
+		return 0
+	}
+	return float32(f.metrics.Kerning(f.charstrings[left].name, f.charstrings[right].name))
+}
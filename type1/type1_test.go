@@ -0,0 +1,284 @@
+package type1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/go-text/font"
+)
+
+// op is a charstring operator, two bytes long for the escaped ones.
+type op int
+
+const (
+	opRlineto   op = 5
+	opRrcurveto op = 8
+	opClosepath op = 9
+	opCallsubr  op = 10
+	opReturn    op = 11
+	opHsbw      op = 13
+	opEndchar   op = 14
+	opRmoveto   op = 21
+	opSeac      op = 12<<8 | 6
+)
+
+// encodeCharstring encodes a sequence of integers and operators.
+func encodeCharstring(items ...interface{}) []byte {
+	var out []byte
+	for _, item := range items {
+		switch item := item.(type) {
+		case int:
+			switch {
+			case -107 <= item && item <= 107:
+				out = append(out, byte(item+139))
+			case 108 <= item && item <= 1131:
+				item -= 108
+				out = append(out, byte(item>>8+247), byte(item))
+			case -1131 <= item && item <= -108:
+				item = -item - 108
+				out = append(out, byte(item>>8+251), byte(item))
+			default:
+				out = append(out, 255, 0, 0, 0, 0)
+				binary.BigEndian.PutUint32(out[len(out)-4:], uint32(int32(item)))
+			}
+		case op:
+			if item > 0xFF {
+				out = append(out, byte(item>>8))
+			}
+			out = append(out, byte(item))
+		}
+	}
+	return out
+}
+
+// encrypt applies the Type1 encryption algorithm,
+// after adding 4 bytes at the start of the data.
+func encrypt(data []byte, key uint16) []byte {
+	const c1, c2 = 52845, 22719
+	out := make([]byte, 0, len(data)+4)
+	for _, p := range append([]byte{1, 2, 3, 4}, data...) {
+		c := p ^ byte(key>>8)
+		key = (uint16(c)+key)*c1 + c2
+		out = append(out, c)
+	}
+	return out
+}
+
+const cleartext = `%!PS-AdobeFont-1.0: TestFont-Bold 001.000
+%%Title: TestFont-Bold
+11 dict begin
+/FontInfo 9 dict dup begin
+/version (001.000) readonly def
+/Notice (A test font \(generated\)) readonly def
+/FullName (Test Font Bold) readonly def
+/FamilyName (Test Font) readonly def
+/Weight (Bold) readonly def
+/ItalicAngle -12 def
+/isFixedPitch false def
+/UnderlinePosition -100 def
+/UnderlineThickness 50 def
+end readonly def
+/FontName /TestFont-Bold def
+/Encoding 256 array
+0 1 255 {1 index exch /.notdef put} for
+dup 32 /space put
+dup 65 /A put
+dup 66 /B put
+readonly def
+/PaintType 0 def
+/FontType 1 def
+/FontMatrix [0.001 0 0 0.001 0 0] readonly def
+/FontBBox {0 -10 600 750} readonly def
+currentdict end
+currentfile eexec
+`
+
+const trailer = "0000000000000000000000000000000000000000000000000000000000000000\ncleartomark\n"
+
+// privateDict returns the decrypted Private dictionary of the test font.
+func privateDict() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("dup /Private 8 dict dup begin\n" +
+		"/RD{string currentfile exch readstring pop}executeonly def\n" +
+		"/ND{noaccess def}executeonly def\n" +
+		"/NP{noaccess put}executeonly def\n" +
+		"/lenIV 4 def\n")
+
+	subrs := [][]byte{
+		encodeCharstring(opReturn),
+		encodeCharstring(opReturn),
+		encodeCharstring(opReturn),
+		encodeCharstring(opReturn),
+		encodeCharstring(100, 0, opRlineto, opReturn),
+	}
+	buf.WriteString("/Subrs " + strconv.Itoa(len(subrs)) + " array\n")
+	for i, subr := range subrs {
+		subr = encrypt(subr, charstringKey)
+		buf.WriteString("dup " + strconv.Itoa(i) + " " + strconv.Itoa(len(subr)) + " RD ")
+		buf.Write(subr)
+		buf.WriteString(" NP\n")
+	}
+	buf.WriteString("ND\n")
+
+	glyphs := []struct {
+		name string
+		data []byte
+	}{
+		{"space", encodeCharstring(0, 250, opHsbw, opEndchar)},
+		{"A", encodeCharstring(10, 600, opHsbw, 0, 0, opRmoveto,
+			500, 0, opRlineto, -250, 700, opRlineto, opClosepath, opEndchar)},
+		{".notdef", encodeCharstring(0, 500, opHsbw, opEndchar)},
+		{"B", encodeCharstring(20, 700, opHsbw, 0, 0, opRmoveto, 4, opCallsubr,
+			0, 300, 200, 0, 0, -300, opRrcurveto, opClosepath, opEndchar)},
+		{"dieresis", encodeCharstring(0, 300, opHsbw, 100, 650, opRmoveto,
+			50, 0, opRlineto, 0, 50, opRlineto, opClosepath, opEndchar)},
+		// the accent is shifted by 100 units
+		{"Adieresis", encodeCharstring(10, 600, opHsbw, 10, 100, 0, 65, 200, opSeac)},
+	}
+	buf.WriteString("2 index /CharStrings " + strconv.Itoa(len(glyphs)) + " dict dup begin\n")
+	for _, g := range glyphs {
+		data := encrypt(g.data, charstringKey)
+		buf.WriteString("/" + g.name + " " + strconv.Itoa(len(data)) + " RD ")
+		buf.Write(data)
+		buf.WriteString(" ND\n")
+	}
+	buf.WriteString("end\nend\nreadonly put\nnoaccess put\n" +
+		"dup /FontName get exch definefont pop\nmark currentfile closefile\n")
+	return buf.Bytes()
+}
+
+// pfa returns the test font in ASCII form, with an hexadecimal encrypted part.
+func pfa() []byte {
+	private := hex.EncodeToString(encrypt(privateDict(), eexecKey))
+	var buf bytes.Buffer
+	buf.WriteString(cleartext)
+	for len(private) > 64 {
+		buf.WriteString(private[:64] + "\n")
+		private = private[64:]
+	}
+	buf.WriteString(private + "\n" + trailer)
+	return buf.Bytes()
+}
+
+// pfb returns the test font in binary form.
+func pfb() []byte {
+	var out []byte
+	segment := func(kind byte, data []byte) {
+		out = append(out, 0x80, kind, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(out[len(out)-4:], uint32(len(data)))
+		out = append(out, data...)
+	}
+	segment(1, []byte(cleartext))
+	segment(2, encrypt(privateDict(), eexecKey))
+	segment(1, []byte(trailer))
+	return append(out, 0x80, 3)
+}
+
+func TestParse(t *testing.T) {
+	for _, data := range [][]byte{pfa(), pfb()} {
+		face, err := ParseFont(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedInfo := FontInfo{
+			Version: "001.000", Notice: "A test font (generated)", FullName: "Test Font Bold", FamilyName: "Test Font",
+			Weight: "Bold", ItalicAngle: -12, UnderlinePosition: -100, UnderlineThickness: 50,
+		}
+		if face.FontName != "TestFont-Bold" || face.Info != expectedInfo {
+			t.Errorf("unexpected font info %s %v", face.FontName, face.Info)
+		}
+		if face.FontBBox != [4]float64{0, -10, 600, 750} || face.Upem() != 1000 {
+			t.Errorf("unexpected bounding box %v", face.FontBBox)
+		}
+		if face.NumGlyphs() != 6 || face.GlyphName(0) != ".notdef" {
+			t.Fatalf("expected 6 glyphs starting with .notdef, got %d", face.NumGlyphs())
+		}
+
+		for _, test := range []struct {
+			name    string
+			code    byte // 0 if not encoded
+			advance float32
+		}{
+			{".notdef", 0, 500},
+			{"space", ' ', 250},
+			{"A", 'A', 600},
+			{"B", 'B', 700},
+			{"dieresis", 0, 300},
+			{"Adieresis", 0, 600},
+		} {
+			gid, ok := face.nameToGID[test.name]
+			if !ok {
+				t.Fatalf("missing glyph %s", test.name)
+			}
+			if test.code != 0 {
+				if code, _ := face.GlyphForCode(test.code); code != gid {
+					t.Errorf("expected glyph %d for code %d, got %d", gid, test.code, code)
+				}
+			}
+			if advance := face.HorizontalAdvance(gid); advance != test.advance {
+				t.Errorf("glyph %s: expected advance %g, got %g", test.name, test.advance, advance)
+			}
+		}
+		// the glyph names are used for the runes
+		if gid, _ := face.NominalGlyph('Ä'); face.GlyphName(gid) != "Adieresis" {
+			t.Errorf("expected Adieresis for 'Ä', got %s", face.GlyphName(gid))
+		}
+	}
+}
+
+func TestGlyphOutline(t *testing.T) {
+	face, err := ParseFont(bytes.NewReader(pfb()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moveTo := func(x, y float32) font.Segment {
+		return font.Segment{Op: font.SegmentOpMoveTo, Args: [3]font.SegmentPoint{{X: x, Y: y}}}
+	}
+	lineTo := func(x, y float32) font.Segment {
+		return font.Segment{Op: font.SegmentOpLineTo, Args: [3]font.SegmentPoint{{X: x, Y: y}}}
+	}
+	triangle := []font.Segment{moveTo(10, 0), lineTo(510, 0), lineTo(260, 700)}
+	for _, test := range []struct {
+		name     string
+		expected []font.Segment
+		extents  font.GlyphExtents
+	}{
+		{"space", nil, font.GlyphExtents{}},
+		{"A", triangle, font.GlyphExtents{XBearing: 10, YBearing: 700, Width: 500, Height: -700}},
+		{"B", []font.Segment{ // with a subroutine
+			moveTo(20, 0), lineTo(120, 0),
+			{Op: font.SegmentOpCubeTo, Args: [3]font.SegmentPoint{{X: 120, Y: 300}, {X: 320, Y: 300}, {X: 320, Y: 0}}},
+		}, font.GlyphExtents{XBearing: 20, YBearing: 300, Width: 300, Height: -300}},
+		{"Adieresis", append(triangle, moveTo(200, 650), lineTo(250, 650), lineTo(250, 700)),
+			font.GlyphExtents{XBearing: 10, YBearing: 700, Width: 500, Height: -700}},
+	} {
+		gid := face.nameToGID[test.name]
+		outline, ok := face.GlyphOutline(gid)
+		if !ok {
+			t.Fatalf("glyph %s: invalid outline", test.name)
+		}
+		if !reflect.DeepEqual(outline.Segments, test.expected) {
+			t.Errorf("glyph %s: expected outline %v, got %v", test.name, test.expected, outline.Segments)
+		}
+		if extents, _ := face.GlyphExtents(gid); extents != test.extents {
+			t.Errorf("glyph %s: expected extents %v, got %v", test.name, test.extents, extents)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	valid := pfa()
+	noEexec := bytes.Replace(valid, []byte("eexec"), []byte("exec"), 1)
+	noName := bytes.Replace(valid, []byte("/FontName /TestFont-Bold def"), nil, 1)
+	truncated := pfb()
+	truncated = truncated[:len(truncated)/2]
+	for i, data := range [][]byte{nil, noEexec, noName, truncated, {0x80, 4, 0, 0, 0, 0}} {
+		if _, err := ParseFont(bytes.NewReader(data)); err == nil {
+			t.Errorf("input %d: expected an error", i)
+		}
+	}
+}